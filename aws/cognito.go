@@ -99,6 +99,24 @@ func (c CognitoAuth) SetPassword(ctx context.Context, user, old, new string) err
 	return nil
 }
 
+// AdminSetPassword sets a permanent password without the old one. Permanent, so
+// a member resetting a forgotten password is not then asked to choose another.
+func (c CognitoAuth) AdminSetPassword(ctx context.Context, user, password string) error {
+	_, err := c.awsCognito.AdminSetUserPassword(ctx, &cognito.AdminSetUserPasswordInput{
+		UserPoolId: &c.userPoolID,
+		Username:   &user,
+		Password:   &password,
+		Permanent:  true,
+	})
+	if err != nil {
+		c.logger.ErrorContext(ctx, "failed to set password", slog.String("error", err.Error()))
+
+		return handleCognitoError(err, auth.ErrAuthenticateOther)
+	}
+
+	return nil
+}
+
 func (c CognitoAuth) CreateUser(ctx context.Context, username, email string, memberID uuid.UUID) (string, error) {
 	resp, err := c.awsCognito.AdminCreateUser(ctx, &cognito.AdminCreateUserInput{
 		UserPoolId: &c.userPoolID,
@@ -6,6 +6,7 @@ import (
 	donationsaudit "boardfund/cmd/root/audit/donations"
 	"boardfund/cmd/root/fundcmd"
 	"boardfund/cmd/root/payout"
+	"boardfund/cmd/root/webhookscmd"
 	"context"
 	"log"
 	"os"
@@ -35,9 +36,18 @@ func loadRunConfig() (*root.RunConfig, error) {
 		}
 	}
 
+	// Cognito's settings are only required of a deployment that uses it.
+	authProvider := getEnvOrDefault("AUTH_PROVIDER", root.AuthProviderCognito)
+	useCognito := authProvider == root.AuthProviderCognito
+
 	// General configurations
 	config := &root.RunConfig{
 		PayPal: payPalConfig,
+		Stripe: root.StripeConfig{
+			SecretKey:     getEnvOrDefault("STRIPE_SECRET_KEY", ""),
+			WebhookSecret: getEnvOrDefault("STRIPE_WEBHOOK_SECRET", ""),
+			BaseURL:       getEnvOrDefault("STRIPE_BASE_URL", "https://api.stripe.com"),
+		},
 		IsLive: isLive,
 
 		PGUser: getEnvOrError("PG_USER", true),
@@ -47,9 +57,12 @@ func loadRunConfig() (*root.RunConfig, error) {
 		PGDB:   getEnvOrError("PG_DB", true),
 		Host:   getEnvOrDefault("HOST", "localhost"),
 
-		JWKURL:            getEnvOrError("JWK_URL", true),
-		CognitoClientID:   getEnvOrError("COGNITO_CLIENT_ID", true),
-		CognitoUserPoolID: getEnvOrError("COGNITO_USER_POOL_ID", true),
+		AuthProvider:      authProvider,
+		JWKURL:            getEnvOrError("JWK_URL", useCognito),
+		CognitoClientID:   getEnvOrError("COGNITO_CLIENT_ID", useCognito),
+		CognitoUserPoolID: getEnvOrError("COGNITO_USER_POOL_ID", useCognito),
+		TwoFactorGroups:   getEnvAsSlice("TWO_FACTOR_GROUPS"),
+		TrustedProxies:    getEnvAsSlice("TRUSTED_PROXIES"),
 
 		EnableNATSLogging: getEnvAsBool("ENABLE_NATS_LOGGING", false),
 		NATSStoreDir:      getEnvOrDefault("NATS_STORE_DIR", ""),
@@ -59,6 +72,27 @@ func loadRunConfig() (*root.RunConfig, error) {
 
 		PayoutApprovalWindow: getEnvAsDuration("PAYOUT_APPROVAL_WINDOW", 72*time.Hour),
 		PayoutReminderWindow: getEnvAsDuration("PAYOUT_REMINDER_WINDOW", 24*time.Hour),
+
+		PayoutSecondApprovalAboveCents: int32(getEnvAsInt("PAYOUT_SECOND_APPROVAL_ABOVE_CENTS", 0)),
+
+		PublicURL:        getEnvOrDefault("PUBLIC_URL", "http://localhost:8080"),
+		InviteSigningKey: getEnvOrDefault("INVITE_SIGNING_KEY", ""),
+
+		SMTP: root.SMTPConfig{
+			Host:     getEnvOrDefault("SMTP_HOST", ""),
+			Port:     getEnvAsInt("SMTP_PORT", 587),
+			Username: getEnvOrDefault("SMTP_USERNAME", ""),
+			Password: getEnvOrDefault("SMTP_PASSWORD", ""),
+			From:     getEnvOrDefault("SMTP_FROM", ""),
+		},
+		PayoutApprovers:   getEnvAsSlice("PAYOUT_APPROVER_EMAILS"),
+		PayoutWebhookURLs: getEnvAsSlice("PAYOUT_WEBHOOK_URLS"),
+
+		Notify: root.NotifyConfig{
+			Email:   getNotifyChannel("EMAIL", true, 2, 5*time.Second),
+			Webhook: getNotifyChannel("WEBHOOK", true, 4, 2*time.Second),
+			Notice:  getNotifyChannel("NOTICE", false, 1, 0),
+		},
 	}
 
 	return config, nil
@@ -102,6 +136,31 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return value
 }
 
+func getEnvAsInt(key string, defaultValue int) int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		log.Fatalf("%s is not a valid number: %v", key, err)
+	}
+
+	return value
+}
+
+// getNotifyChannel reads NOTIFY_<name>_ENABLED, _ATTEMPTS and _BACKOFF.
+func getNotifyChannel(name string, enabled bool, attempts int, backoff time.Duration) root.NotifyChannel {
+	prefix := "NOTIFY_" + name + "_"
+
+	return root.NotifyChannel{
+		Enabled:  getEnvAsBool(prefix+"ENABLED", enabled),
+		Attempts: getEnvAsInt(prefix+"ATTEMPTS", attempts),
+		Backoff:  getEnvAsDuration(prefix+"BACKOFF", backoff),
+	}
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -127,9 +186,11 @@ func main() {
 	donationsAuditCmd := donationsaudit.DonationsAuditCmd(runConfig)
 
 	auditCmd.AddCommand(donationsAuditCmd)
+	auditCmd.AddCommand(donationsaudit.ImportAuditCmd(runConfig))
 	rootCmd.AddCommand(auditCmd)
 	rootCmd.AddCommand(payout.PayoutCmd(runConfig))
 	rootCmd.AddCommand(fundcmd.FundCmd(runConfig))
+	rootCmd.AddCommand(webhookscmd.WebhooksCmd(runConfig))
 
 	// Surface a non-zero exit code: scheduled audit runs are otherwise
 	// indistinguishable from successful ones.
@@ -11,6 +11,7 @@ import (
 	"boardfund/paypal"
 	"boardfund/paypal/token"
 	"boardfund/pg"
+	"boardfund/providers"
 	donationstore "boardfund/service/donations/store"
 	"boardfund/service/finance"
 	"boardfund/service/fundevents"
@@ -43,6 +44,26 @@ func DonationsAuditCmd(runConfig *root.RunConfig) *cobra.Command {
 // is indistinguishable from the schedule not having fired -- and telling those
 // two apart is the whole reason the started line exists.
 func reconcile(ctx context.Context, runConfig *root.RunConfig, logger *slog.Logger) error {
+	financeService, err := build(ctx, runConfig, logger)
+	if err != nil {
+		return err
+	}
+
+	err = financeService.RunRecurringDonationReconciliation(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile recurring donations: %w", err)
+	}
+
+	err = financeService.RunOneTimeDonationReconciliation(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile one-time donations: %w", err)
+	}
+
+	return nil
+}
+
+// build wires the finance service for one run of an audit command.
+func build(ctx context.Context, runConfig *root.RunConfig, logger *slog.Logger) (*finance.FinanceService, error) {
 	dbURI := fmt.Sprintf(
 		"postgresql://%s:%s@%s:%s/%s",
 		runConfig.PGUser, runConfig.PGPass, runConfig.PGHost, runConfig.PGPort, runConfig.PGDB,
@@ -59,12 +80,12 @@ func reconcile(ctx context.Context, runConfig *root.RunConfig, logger *slog.Logg
 
 	pool, err := pg.GetDBPool(dbURI)
 	if err != nil {
-		return fmt.Errorf("failed to create pgx pool: %w", err)
+		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
 	}
 
 	defaultConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion("us-west-2"))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	s3Client := s3.NewFromConfig(defaultConfig)
@@ -77,15 +98,9 @@ func reconcile(ctx context.Context, runConfig *root.RunConfig, logger *slog.Logg
 		donationStore, paypalService, donationsPaymentsS3, fundEvents, runConfig.ReportTypes, logger,
 	)
 
-	err = financeService.RunRecurringDonationReconciliation(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to reconcile recurring donations: %w", err)
+	if stripeService := root.NewStripe(*runConfig, logger); stripeService != nil {
+		financeService.AddProvider(providers.Stripe, stripeService)
 	}
 
-	err = financeService.RunOneTimeDonationReconciliation(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to reconcile one-time donations: %w", err)
-	}
-
-	return nil
+	return financeService, nil
 }
@@ -0,0 +1,67 @@
+package donations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"boardfund/cmd/root"
+	"boardfund/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// ImportAuditCmd records one-time payments the providers took and the site never
+// heard about: captured at PayPal, and then the donor's browser closed before it
+// came back to complete the donation. What cannot be tied to a fund and a member
+// is kept for an admin to assign on the unmatched payments page.
+//
+// Safe to run over the same window as often as it likes; it is meant to run
+// daily over a window reaching back past the last run.
+func ImportAuditCmd(runConfig *root.RunConfig) *cobra.Command {
+	var days int
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "record one-time payments the providers have and we do not",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if days < 1 {
+				return errors.New("--days must be at least 1")
+			}
+
+			logger := logging.New("import-payments")
+
+			return logging.Job(cmd.Context(), logger, "import-payments",
+				func(ctx context.Context) ([]slog.Attr, error) {
+					financeService, err := build(ctx, runConfig, logger)
+					if err != nil {
+						return nil, err
+					}
+
+					end := time.Now()
+
+					result, err := financeService.ImportMissedPayments(ctx, end.AddDate(0, 0, -days), end)
+					if err != nil {
+						return nil, fmt.Errorf("failed to import payments: %w", err)
+					}
+
+					fmt.Printf("searched %d payment(s): %d already recorded, %d recorded now, %d waiting on an admin\n",
+						result.Searched, result.Known, result.Recorded, result.Unmatched)
+
+					return []slog.Attr{
+						slog.Int("days", days),
+						slog.Int("searched", result.Searched),
+						slog.Int("known", result.Known),
+						slog.Int("recorded", result.Recorded),
+						slog.Int("unmatched", result.Unmatched),
+					}, nil
+				})
+		},
+	}
+
+	cmd.Flags().IntVar(&days, "days", 7, "how many days back to search")
+
+	return cmd
+}
@@ -0,0 +1,120 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"boardfund/aws"
+	"boardfund/jwtauth"
+	"boardfund/jwtauth/keyset"
+	"boardfund/localauth"
+	localauthstore "boardfund/localauth/store"
+	"boardfund/mailer"
+	"boardfund/service/auth"
+
+	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The values RunConfig.AuthProvider may take.
+const (
+	AuthProviderCognito = "cognito"
+	AuthProviderLocal   = "local"
+)
+
+// authorizer is auth.AuthService's, restated because that one is unexported.
+type authorizer interface {
+	Authorize(ctx context.Context, user, pass string) (*auth.AuthResponse, error)
+	SetPassword(ctx context.Context, user, old, new string) error
+	AdminSetPassword(ctx context.Context, user, password string) error
+	CreateUser(ctx context.Context, username, email string, memberID uuid.UUID) (string, error)
+	AddToGroup(ctx context.Context, username, group string) error
+	RemoveFromGroup(ctx context.Context, username, group string) error
+	ListGroups(ctx context.Context, username string) ([]string, error)
+}
+
+// newAuthorizer builds the authorizer RunConfig.AuthProvider names, and the
+// verifier for the tokens it issues.
+//
+// With Cognito the verifier fetches Cognito's keys from JWKURL. With the local
+// authorizer it is handed the issuer's own key set directly rather than being
+// pointed at the JWKS endpoint: that endpoint is this process, which is not
+// listening yet. The issuer is returned so run can serve that endpoint and keep
+// the keys rotating; it is nil with Cognito.
+func newAuthorizer(
+	ctx context.Context,
+	runConfig RunConfig,
+	cognitoClient *cognito.Client,
+	pool *pgxpool.Pool,
+	logger *slog.Logger,
+) (authorizer, *jwtauth.Token, *localauth.Issuer, error) {
+	switch runConfig.AuthProvider {
+	case AuthProviderCognito, "":
+		ksetCache := keyset.NewKeySetWithCache(runConfig.JWKURL, 15)
+		kset, err := ksetCache.NewKeySet()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		cognitoAuth := aws.NewCognitoAuth(cognitoClient, logger, runConfig.CognitoClientID, runConfig.CognitoUserPoolID)
+
+		return cognitoAuth, jwtauth.NewToken(kset), nil, nil
+	case AuthProviderLocal:
+		store := localauthstore.NewLocalAuthStore(pool)
+
+		issuer, err := localauth.NewIssuer(ctx, store, runConfig.PublicURL, logger)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		// Without a relay the temporary password goes to the log. Acceptable where
+		// the only people reading the log are the people registering, which is
+		// never production. Declared as the interface, not *mailer.Mailer, so
+		// that "no relay" reaches the authorizer as nil rather than as a nil
+		// pointer it would call.
+		var sender interface {
+			Send(ctx context.Context, msg mailer.Message) error
+		}
+
+		switch {
+		case runConfig.SMTP.Host != "":
+			sender, err = newMailer(runConfig)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		case runConfig.IsLive:
+			return nil, nil, nil, errors.New("local auth in production needs SMTP_HOST to send temporary passwords")
+		}
+
+		localAuth, err := localauth.NewAuthorizer(store, issuer, sender, logger, runConfig.PublicURL)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return localAuth, jwtauth.NewToken(issuer.PublicKeys()), issuer, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown AUTH_PROVIDER %q: want %q or %q",
+			runConfig.AuthProvider, AuthProviderCognito, AuthProviderLocal)
+	}
+}
+
+// twoFactorGroups is the configured list with every role's group added if it
+// was left out, so that no setting can make the admin pages single-factor for
+// any of the roles let into them.
+func twoFactorGroups(configured []string) []string {
+	groups := jwtauth.RoleGroups()
+
+	for _, group := range configured {
+		group = strings.TrimSpace(group)
+		if group != "" && !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
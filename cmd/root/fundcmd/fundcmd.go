@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"boardfund/aws"
 	"boardfund/cmd/root"
@@ -13,6 +14,7 @@ import (
 	"boardfund/paypal"
 	"boardfund/paypal/token"
 	"boardfund/pg"
+	"boardfund/providers"
 	"boardfund/service/donations"
 	donationstore "boardfund/service/donations/store"
 	"boardfund/service/fundevents"
@@ -30,6 +32,8 @@ func FundCmd(runConfig *root.RunConfig) *cobra.Command {
 	}
 
 	cmd.AddCommand(closeExpiredCmd(runConfig))
+	cmd.AddCommand(closeReachedCmd(runConfig))
+	cmd.AddCommand(prunePlansCmd(runConfig))
 
 	return cmd
 }
@@ -101,6 +105,138 @@ func closeExpiredCmd(runConfig *root.RunConfig) *cobra.Command {
 	return cmd
 }
 
+// closeReachedCmd closes the funds set to close at their goal once they have
+// collected it. Like close-expired it must run after the payout planner, and for
+// the same reason: a closed fund is not planned.
+func closeReachedCmd(runConfig *root.RunConfig) *cobra.Command {
+	var confirm bool
+
+	cmd := &cobra.Command{
+		Use:   "close-reached",
+		Short: "close every fund set to close at its goal that has reached it",
+		Long: "Deactivates funds that close on reaching their goal and have, and " +
+			"cancels their recurring subscriptions at the provider. Without " +
+			"--confirm the command only reports what it would close.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return logging.Job(cmd.Context(), logging.New("funds"), "close-reached",
+				func(ctx context.Context) ([]slog.Attr, error) {
+					service, err := build(runConfig)
+					if err != nil {
+						return nil, err
+					}
+
+					if !confirm {
+						reached, errList := service.ListGoalReachedOpenFunds(ctx)
+						if errList != nil {
+							return nil, errList
+						}
+
+						if len(reached) == 0 {
+							fmt.Println("no open fund has reached a goal it closes on")
+
+							return []slog.Attr{slog.Bool("dry_run", true), slog.Int("would_close", 0)}, nil
+						}
+
+						fmt.Printf("would close %d fund(s):\n", len(reached))
+						for _, fund := range reached {
+							fmt.Printf("  %s  %s  %d of %d cents\n",
+								fund.ID, fund.Name, fund.Stats.TotalDonated, fund.GoalCents)
+						}
+
+						fmt.Println("\nre-run with --confirm to close them")
+
+						return []slog.Attr{
+							slog.Bool("dry_run", true),
+							slog.Int("would_close", len(reached)),
+						}, nil
+					}
+
+					closed, err := service.CloseReachedFunds(ctx)
+					if err != nil {
+						return nil, err
+					}
+
+					fmt.Printf("closed %d fund(s)\n", closed)
+
+					return []slog.Attr{slog.Int("closed", closed)}, nil
+				})
+		},
+	}
+
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "actually close the funds; without this the command only reports what would be closed")
+
+	return cmd
+}
+
+// prunePlansCmd retires the PayPal plans nobody subscribed to. Every amount and
+// cycle a donor opens the form with becomes a plan, so without this the
+// catalogue only ever grows. The grace period is measured from the last time a
+// donor was offered the plan: someone who opened the PayPal popup a minute ago
+// still needs it to exist when they click subscribe.
+func prunePlansCmd(runConfig *root.RunConfig) *cobra.Command {
+	var (
+		confirm bool
+		grace   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune-plans",
+		Short: "deactivate donation plans nobody subscribed to",
+		Long: "Deactivates, at the provider and here, every plan with no donations " +
+			"that has not been offered for the grace period, and every plan left " +
+			"active on a closed fund. Without --confirm the command only reports " +
+			"what it would deactivate.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return logging.Job(cmd.Context(), logging.New("funds"), "prune-plans",
+				func(ctx context.Context) ([]slog.Attr, error) {
+					service, err := build(runConfig)
+					if err != nil {
+						return nil, err
+					}
+
+					if !confirm {
+						plans, errList := service.ListAbandonedPlans(ctx, grace)
+						if errList != nil {
+							return nil, errList
+						}
+
+						if len(plans) == 0 {
+							fmt.Println("no abandoned plans")
+
+							return []slog.Attr{slog.Bool("dry_run", true), slog.Int("would_prune", 0)}, nil
+						}
+
+						fmt.Printf("would deactivate %d plan(s):\n", len(plans))
+						for _, plan := range plans {
+							fmt.Printf("  %s  %s  %s\n", plan.ProviderPlanID, plan.FundID, plan.Name)
+						}
+
+						fmt.Println("\nre-run with --confirm to deactivate them")
+
+						return []slog.Attr{
+							slog.Bool("dry_run", true),
+							slog.Int("would_prune", len(plans)),
+						}, nil
+					}
+
+					pruned, err := service.PruneAbandonedPlans(ctx, grace)
+					if err != nil {
+						return nil, err
+					}
+
+					fmt.Printf("deactivated %d plan(s)\n", pruned)
+
+					return []slog.Attr{slog.Int("pruned", pruned)}, nil
+				})
+		},
+	}
+
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "actually deactivate the plans; without this the command only reports what would be deactivated")
+	cmd.Flags().DurationVar(&grace, "grace", 72*time.Hour, "how long a plan with no subscriptions is kept after it was last offered")
+
+	return cmd
+}
+
 // build wires the donation service for a one-shot CLI run, matching how the
 // payout commands construct theirs.
 func build(runConfig *root.RunConfig) (*donations.DonationService, error) {
@@ -142,7 +278,13 @@ func build(runConfig *root.RunConfig) (*donations.DonationService, error) {
 	store := donationstore.NewDonationStore(pool)
 	fundEvents := fundevents.NewService(fundeventstore.NewEventStore(pool), logger)
 
-	return donations.NewDonationService(
+	donationService := donations.NewDonationService(
 		store, documentStorage, fundImages, paypalService, fundEvents, runConfig.ReportTypes, logger,
-	), nil
+	)
+
+	if stripeService := root.NewStripe(*runConfig, logger); stripeService != nil {
+		donationService.AddProvider(providers.Stripe, stripeService)
+	}
+
+	return donationService, nil
 }
@@ -0,0 +1,91 @@
+package root
+
+import (
+	"log/slog"
+
+	"boardfund/mailer"
+	"boardfund/service/notices"
+	"boardfund/service/notifications"
+	notificationstore "boardfund/service/notifications/store"
+	payoutstore "boardfund/service/payouts/store"
+)
+
+// NewApprovalNotifier builds whatever is configured to tell treasurers about
+// batches, for the web process and the payout commands alike -- the sweep runs
+// from both, and a reminder should not depend on which one got there first.
+//
+// Every enabled channel that has somewhere to send goes into one fan-out. A
+// channel switched on with nothing to send to is logged and left out rather
+// than failing boot: the payouts still work without it, and the log says why
+// nobody heard.
+//
+// Returns a nil interface, not a nil pointer in one, when nothing is
+// configured: the payout service checks for nil, and a typed nil would pass that
+// check and then panic on the first batch.
+func NewApprovalNotifier(
+	runConfig RunConfig,
+	payoutStore payoutstore.PayoutStore,
+	notificationStore notificationstore.NotificationStore,
+	noticeService *notices.Service,
+	logger *slog.Logger,
+) (notifications.ApprovalNotifier, error) {
+	var channels []notifications.ApprovalNotifier
+
+	if email := runConfig.Notify.Email; email.Enabled {
+		if runConfig.SMTP.Host == "" || len(runConfig.PayoutApprovers) == 0 {
+			logger.Warn("email approval notifications are enabled but SMTP_HOST or PAYOUT_APPROVER_EMAILS is unset")
+		} else {
+			m, err := newMailer(runConfig)
+			if err != nil {
+				return nil, err
+			}
+
+			channels = append(channels, notifications.NewEmailNotifier(
+				m, payoutStore, notificationStore, runConfig.PayoutApprovers, runConfig.PublicURL,
+				retryFor(email), logger,
+			))
+		}
+	}
+
+	if webhook := runConfig.Notify.Webhook; webhook.Enabled {
+		if len(runConfig.PayoutWebhookURLs) == 0 {
+			logger.Warn("webhook approval notifications are enabled but PAYOUT_WEBHOOK_URLS is unset")
+		} else {
+			channels = append(channels, notifications.NewWebhookNotifier(
+				payoutStore, notificationStore, runConfig.PayoutWebhookURLs, runConfig.PublicURL,
+				retryFor(webhook), logger,
+			))
+		}
+	}
+
+	if notice := runConfig.Notify.Notice; notice.Enabled {
+		channels = append(channels, notifications.NewNoticeNotifier(
+			noticeService, payoutStore, notificationStore, runConfig.PublicURL,
+			retryFor(notice), logger,
+		))
+	}
+
+	if len(channels) == 0 {
+		logger.Warn("no approval notifier configured: batches awaiting approval will only be logged")
+
+		return nil, nil
+	}
+
+	return notifications.NewFanout(logger, channels...), nil
+}
+
+func retryFor(channel NotifyChannel) notifications.Retry {
+	return notifications.Retry{Attempts: channel.Attempts, Backoff: channel.Backoff}
+}
+
+// newMailer is the relay SMTPConfig describes. Callers check SMTP.Host first:
+// each has its own idea of what no relay means.
+func newMailer(runConfig RunConfig) (*mailer.Mailer, error) {
+	return mailer.NewMailer(mailer.Config{
+		Host:     runConfig.SMTP.Host,
+		Port:     runConfig.SMTP.Port,
+		Username: runConfig.SMTP.Username,
+		Password: runConfig.SMTP.Password,
+		From:     runConfig.SMTP.From,
+	})
+}
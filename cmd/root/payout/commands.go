@@ -178,6 +178,30 @@ func showCmd(runConfig *root.RunConfig) *cobra.Command {
 
 			printBatch(*batch)
 
+			approvals, err := d.service.GetApprovalsForBatch(cmd.Context(), batchID)
+			if err != nil {
+				return err
+			}
+
+			if len(approvals) > 0 {
+				fmt.Println()
+
+				aw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(aw, "APPROVED AT\tAPPROVER ID\tAPPROVER")
+
+				for _, approval := range approvals {
+					fmt.Fprintf(aw, "%s\t%s\t%s\n",
+						approval.Created.Format(time.RFC3339),
+						approval.ApproverID,
+						approval.ApproverName,
+					)
+				}
+
+				if err = aw.Flush(); err != nil {
+					return err
+				}
+			}
+
 			items, err := d.service.GetPayoutsForBatch(cmd.Context(), batchID)
 			if err != nil {
 				return err
@@ -279,6 +303,55 @@ func rejectCmd(runConfig *root.RunConfig) *cobra.Command {
 	return cmd
 }
 
+func strikeCmd(runConfig *root.RunConfig) *cobra.Command {
+	var (
+		byStr  string
+		reason string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "strike <batch-id> <payout-id>",
+		Short: "take one payout out of a batch awaiting approval, leaving the rest",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid batch id: %w", err)
+			}
+
+			payoutID, err := uuid.Parse(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid payout id: %w", err)
+			}
+
+			by, err := uuid.Parse(byStr)
+			if err != nil {
+				return fmt.Errorf("invalid --by, want a member UUID: %w", err)
+			}
+
+			d, err := build(runConfig)
+			if err != nil {
+				return err
+			}
+
+			batch, err := d.service.StrikePayout(cmd.Context(), batchID, payoutID, by, reason)
+			if err != nil {
+				return err
+			}
+
+			printBatch(*batch)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&byStr, "by", "", "member UUID of the treasurer striking the payout (required)")
+	cmd.Flags().StringVar(&reason, "reason", "", "why the payout was struck")
+	_ = cmd.MarkFlagRequired("by")
+
+	return cmd
+}
+
 func submitCmd(runConfig *root.RunConfig) *cobra.Command {
 	var confirm bool
 
@@ -400,6 +473,10 @@ func printBatch(b payouts.Batch) {
 		fmt.Fprintf(w, "approval deadline\t%s\n", deadline(b))
 	}
 
+	if b.ApprovalsRequired > 1 {
+		fmt.Fprintf(w, "approvals\t%d of %d\n", b.ApprovalsGiven, b.ApprovalsRequired)
+	}
+
 	if b.ApprovedBy != nil && b.ApprovedAt != nil {
 		fmt.Fprintf(w, "approved\t%s by %s\n", b.ApprovedAt.Format(time.RFC3339), b.ApprovedBy)
 	}
@@ -563,3 +640,179 @@ func reconcilePendingCmd(runConfig *root.RunConfig) *cobra.Command {
 		},
 	}
 }
+
+func allocationCmd(runConfig *root.RunConfig) *cobra.Command {
+	var (
+		strategy string
+		capCents int32
+	)
+
+	cmd := &cobra.Command{
+		Use:   "allocation <fund-id>",
+		Short: "choose how a fund divides its payouts: even, fixed or weighted",
+		Long: "Choose how a fund divides its payouts between its payees.\n\n" +
+			"even splits what is available equally. fixed pays each enrollment the amount\n" +
+			"set with 'payout share --fixed-cents', and pays nobody if the fund cannot\n" +
+			"cover everyone. weighted splits what is available by each enrollment's\n" +
+			"--weight. --cap-cents limits what any one payee gets; the rest stays in the\n" +
+			"fund for the next payout. Batches already planned are not changed.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fundID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid fund id: %w", err)
+			}
+
+			allocation := payouts.Allocation{Strategy: payouts.AllocationStrategy(strategy)}
+			if !allocation.Strategy.Valid() {
+				return fmt.Errorf("invalid --strategy %q, want one of %v", strategy, payouts.AllocationStrategies)
+			}
+
+			if cmd.Flags().Changed("cap-cents") {
+				if capCents <= 0 {
+					return errors.New("--cap-cents must be greater than zero")
+				}
+
+				allocation.CapCents = &capCents
+			}
+
+			d, err := build(runConfig)
+			if err != nil {
+				return err
+			}
+
+			stored, err := d.service.SetFundAllocation(cmd.Context(), fundID, allocation)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("fund %s now allocates payouts %s\n", fundID, stored)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&strategy, "strategy", string(payouts.AllocationEven), "even, fixed or weighted")
+	cmd.Flags().Int32Var(&capCents, "cap-cents", 0, "most any one payee gets from one payout; omit for no cap")
+
+	return cmd
+}
+
+func shareCmd(runConfig *root.RunConfig) *cobra.Command {
+	var (
+		weight     int32
+		fixedCents int32
+	)
+
+	cmd := &cobra.Command{
+		Use:   "share <enrollment-id>",
+		Short: "set an enrollment's weight and fixed amount for weighted and fixed funds",
+		Long: "Set what one enrollment is owed under a weighted or fixed allocation.\n\n" +
+			"Both are replaced together, so give both: an omitted --weight goes back to 1\n" +
+			"and an omitted --fixed-cents clears the fixed amount. A weight of 0 leaves the\n" +
+			"enrollment out of weighted payouts; no fixed amount leaves it out of fixed ones.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			enrollmentID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid enrollment id: %w", err)
+			}
+
+			if weight < 0 {
+				return errors.New("--weight cannot be negative")
+			}
+
+			share := payouts.EnrollmentShare{Weight: weight}
+			if cmd.Flags().Changed("fixed-cents") {
+				if fixedCents <= 0 {
+					return errors.New("--fixed-cents must be greater than zero")
+				}
+
+				share.FixedAmountCents = &fixedCents
+			}
+
+			d, err := build(runConfig)
+			if err != nil {
+				return err
+			}
+
+			enrollment, err := d.service.SetEnrollmentShare(cmd.Context(), enrollmentID, share)
+			if err != nil {
+				return err
+			}
+
+			fixed := "none"
+			if enrollment.FixedAmountCents != nil {
+				fixed = dollars(*enrollment.FixedAmountCents)
+			}
+
+			fmt.Printf("enrollment %s: weight %d, fixed amount %s\n", enrollment.ID, enrollment.Weight, fixed)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int32Var(&weight, "weight", 1, "share of a weighted payout; 0 leaves the enrollment out")
+	cmd.Flags().Int32Var(&fixedCents, "fixed-cents", 0, "amount paid each period under a fixed allocation; omit to clear")
+
+	return cmd
+}
+
+func secondApprovalCmd(runConfig *root.RunConfig) *cobra.Command {
+	var (
+		aboveCents int32
+		clear      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "second-approval <fund-id>",
+		Short: "require two admins to approve a fund's batches above an amount",
+		Long: "Require two distinct admins to approve any of this fund's batches whose total\n" +
+			"is above --above-cents. --clear goes back to the site-wide threshold set by\n" +
+			"PAYOUT_SECOND_APPROVAL_ABOVE_CENTS, if there is one.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fundID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid fund id: %w", err)
+			}
+
+			changed := cmd.Flags().Changed("above-cents")
+			if changed == clear {
+				return errors.New("give exactly one of --above-cents or --clear")
+			}
+
+			var threshold *int32
+			if changed {
+				if aboveCents <= 0 {
+					return errors.New("--above-cents must be greater than zero")
+				}
+
+				threshold = &aboveCents
+			}
+
+			d, err := build(runConfig)
+			if err != nil {
+				return err
+			}
+
+			err = d.service.SetFundSecondApproval(cmd.Context(), fundID, threshold)
+			if err != nil {
+				return err
+			}
+
+			if threshold == nil {
+				fmt.Printf("fund %s now follows the site-wide second-approval threshold\n", fundID)
+			} else {
+				fmt.Printf("fund %s batches above %s now need two approvals\n", fundID, dollars(*threshold))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int32Var(&aboveCents, "above-cents", 0, "batch total in cents above which two admins must approve")
+	cmd.Flags().BoolVar(&clear, "clear", false, "remove the fund's own threshold")
+
+	return cmd
+}
@@ -11,6 +11,9 @@ import (
 	"boardfund/pg"
 	"boardfund/service/fundevents"
 	fundeventstore "boardfund/service/fundevents/store"
+	"boardfund/service/notices"
+	noticestore "boardfund/service/notices/store"
+	notificationstore "boardfund/service/notifications/store"
 	"boardfund/service/payouts"
 	payoutstore "boardfund/service/payouts/store"
 
@@ -33,12 +36,16 @@ func PayoutCmd(runConfig *root.RunConfig) *cobra.Command {
 		showCmd(runConfig),
 		approveCmd(runConfig),
 		rejectCmd(runConfig),
+		strikeCmd(runConfig),
 		submitCmd(runConfig),
 		sweepCmd(runConfig),
 		reconcileCmd(runConfig),
 		planDueCmd(runConfig),
 		submitApprovedCmd(runConfig),
 		reconcilePendingCmd(runConfig),
+		allocationCmd(runConfig),
+		shareCmd(runConfig),
+		secondApprovalCmd(runConfig),
 	)
 
 	return cmd
@@ -77,15 +84,25 @@ func build(runConfig *root.RunConfig) (*deps, error) {
 	store := payoutstore.NewPayoutStore(pool)
 	fundEvents := fundevents.NewService(fundeventstore.NewEventStore(pool), logger)
 
-	// No notifier wired yet: reminders are logged by the sweep until a delivery
-	// channel exists. A nil notifier is handled by the service.
+	notifier, err := root.NewApprovalNotifier(
+		*runConfig,
+		store,
+		notificationstore.NewNotificationStore(pool),
+		notices.NewService(noticestore.NewNoticeStore(pool), logger),
+		logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	service := payouts.NewPayoutService(
 		store,
 		paypalService,
-		nil,
+		notifier,
 		fundEvents,
 		runConfig.PayoutApprovalWindow,
 		runConfig.PayoutReminderWindow,
+		runConfig.PayoutSecondApprovalAboveCents,
 		logger,
 	)
 
@@ -2,13 +2,12 @@ package root
 
 import (
 	"boardfund/aws"
-	"boardfund/jwtauth"
-	"boardfund/jwtauth/keyset"
 	"boardfund/logging"
 	"boardfund/messaging"
 	"boardfund/paypal"
 	"boardfund/paypal/token"
 	"boardfund/pg"
+	"boardfund/providers"
 	"boardfund/service/adminevents"
 	admineventstore "boardfund/service/adminevents/store"
 	"boardfund/service/auth"
@@ -24,9 +23,12 @@ import (
 	memberstore "boardfund/service/members/store"
 	"boardfund/service/notices"
 	noticestore "boardfund/service/notices/store"
+	"boardfund/service/notifications"
+	notificationstore "boardfund/service/notifications/store"
 	"boardfund/service/payouts"
 	payoutstore "boardfund/service/payouts/store"
 	"boardfund/web/adminweb"
+	"boardfund/web/apiweb"
 	"boardfund/web/authweb"
 	"boardfund/web/common"
 	"boardfund/web/homeweb"
@@ -68,6 +70,7 @@ type PayPalConfig struct {
 
 type RunConfig struct {
 	PayPal PayPalConfig
+	Stripe StripeConfig
 	IsLive bool
 
 	Host string
@@ -78,10 +81,25 @@ type RunConfig struct {
 	PGPort string
 	PGDB   string
 
+	// AuthProvider is who checks passwords and issues tokens: "cognito", the
+	// default, or "local" for an environment with no AWS to reach. The Cognito
+	// settings below are only read with Cognito.
+	AuthProvider      string
 	JWKURL            string
 	CognitoClientID   string
 	CognitoUserPoolID string
 
+	// TwoFactorGroups are the groups whose members must give a second factor
+	// before the admin pages will serve them. The admin group is always one of
+	// them, whatever this says: admins approve payouts that move real money.
+	TwoFactorGroups []string
+
+	// TrustedProxies are the addresses or CIDR ranges of the proxies in front of
+	// the site, whose forwarding headers say who a request is from. Unset, every
+	// request is counted against the address that connected, which behind a proxy
+	// is the proxy's.
+	TrustedProxies []string
+
 	EnableNATSLogging bool
 
 	// NATSStoreDir is where JetStream keeps the webhook stream. Must be on a
@@ -100,6 +118,58 @@ type RunConfig struct {
 	// be before a reminder is sent. Both fall back to service defaults when zero.
 	PayoutApprovalWindow time.Duration
 	PayoutReminderWindow time.Duration
+
+	// PayoutSecondApprovalAboveCents is the batch total above which two admins
+	// must approve, for funds that set no threshold of their own. Zero is none.
+	PayoutSecondApprovalAboveCents int32
+
+	// PublicURL is where the site is reached from outside, scheme included. Used
+	// for links in anything sent away from the site, where a bare path is no use.
+	PublicURL string
+
+	// InviteSigningKey signs invite links. Empty turns invites off, leaving the
+	// approved email list as the only way to register; changing it voids every
+	// link outstanding.
+	InviteSigningKey string
+
+	SMTP SMTPConfig
+
+	// PayoutApprovers are the addresses told when a batch needs approving.
+	PayoutApprovers []string
+
+	// PayoutWebhookURLs are chat webhooks (Slack or Discord) posted to when a
+	// batch needs approving.
+	PayoutWebhookURLs []string
+
+	// Notify turns each approval channel on or off and says how hard it tries.
+	// A channel that is enabled but has nothing to send to is skipped; with no
+	// channel left the payout service runs without a notifier, as it did before
+	// there was one.
+	Notify NotifyConfig
+}
+
+type NotifyConfig struct {
+	Email   NotifyChannel
+	Webhook NotifyChannel
+	Notice  NotifyChannel
+}
+
+// NotifyChannel is one channel's switch and retry policy. Enabled exists apart
+// from the channel's own settings so a noisy channel can be silenced for a while
+// without losing the addresses or URLs it was configured with.
+type NotifyChannel struct {
+	Enabled  bool
+	Attempts int
+	Backoff  time.Duration
+}
+
+// SMTPConfig is the relay approval emails go through. No host means no email.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
 }
 
 type ChildDeps struct {
@@ -209,17 +279,17 @@ func run(ctx context.Context, runConfig RunConfig) error {
 	s3Client := s3.NewFromConfig(defaultConfig)
 	cognitoClient := cognito.NewFromConfig(defaultConfig)
 
-	authorizer := aws.NewCognitoAuth(cognitoClient, logger, runConfig.CognitoClientID, runConfig.CognitoUserPoolID)
-
 	documentStorage := aws.NewAWSS3(s3Client, logger, "")
 	fundImages := aws.NewFundImages(s3Client, runConfig.FundImagesS3Bucket, logger)
 
-	ksetCache := keyset.NewKeySetWithCache(runConfig.JWKURL, 15)
-	kset, err := ksetCache.NewKeySet()
+	authorizer, verifier, issuer, err := newAuthorizer(ctx, runConfig, cognitoClient, pool, logger)
 	if err != nil {
 		return err
 	}
-	verifier := jwtauth.NewToken(kset)
+
+	if issuer != nil {
+		go issuer.Run(ctx)
+	}
 
 	messageBroker, err := messaging.NewBroker(ctx, nc, logger)
 	if err != nil {
@@ -227,6 +297,11 @@ func run(ctx context.Context, runConfig RunConfig) error {
 	}
 	defer messageBroker.Close()
 
+	// Before anything subscribes, so every consumer reports what it made of each
+	// webhook against the archived copy.
+	webhookArchive := hooksstore.NewDeliveryStore(pool)
+	messageBroker.RecordOutcomes(webhookArchive)
+
 	fundEvents := fundevents.NewService(eventStore, logger)
 	adminEvents := adminevents.NewService(adminEventStore, logger)
 	noticeService := notices.NewService(noticestore.NewNoticeStore(pool), logger)
@@ -234,14 +309,47 @@ func run(ctx context.Context, runConfig RunConfig) error {
 	donationService := donations.NewDonationService(donationStore, documentStorage, fundImages, paypalService, fundEvents, runConfig.ReportTypes, logger)
 	memberService := members.NewMemberService(memberStore, donationStore, paypalService, fundEvents, logger)
 	authService := auth.NewAuthService(memberStore, authStore, authorizer, adminEvents, logger)
+
+	// Forgotten passwords are reset by emailed link, so without a relay there is
+	// no reset: the form says so rather than promising an email nobody sends.
+	if runConfig.SMTP.Host != "" {
+		resetMailer, errMailer := newMailer(runConfig)
+		if errMailer != nil {
+			return errMailer
+		}
+
+		authService.SendPasswordResetsWith(resetMailer, runConfig.PublicURL)
+	}
+
+	// A key too short to sign with is refused at startup rather than left to
+	// surface as every invite failing.
+	if runConfig.InviteSigningKey != "" {
+		if err = authService.SignInvitesWith([]byte(runConfig.InviteSigningKey), runConfig.PublicURL); err != nil {
+			return fmt.Errorf("INVITE_SIGNING_KEY: %w", err)
+		}
+	}
+
 	financeService := finance.NewFinanceService(donationStore, paypalService, documentStorage, fundEvents, runConfig.ReportTypes, logger)
 	enrollmentService := enrollments.NewEnrollmentsService(enrollmentStore, donationStore, fundEvents, logger)
 
-	// No notifier yet: approval reminders are logged by the sweep until a delivery
-	// channel exists. The service tolerates a nil notifier.
+	if stripeService := NewStripe(runConfig, logger); stripeService != nil {
+		donationService.AddProvider(providers.Stripe, stripeService)
+		memberService.AddProvider(providers.Stripe, stripeService)
+		financeService.AddProvider(providers.Stripe, stripeService)
+	}
+
+	notificationStore := notificationstore.NewNotificationStore(pool)
+	notificationService := notifications.NewService(notificationStore, logger)
+
+	notifier, err := NewApprovalNotifier(runConfig, payoutStore, notificationStore, noticeService, logger)
+	if err != nil {
+		return err
+	}
+
 	payoutService := payouts.NewPayoutService(
-		payoutStore, paypalService, nil, fundEvents,
-		runConfig.PayoutApprovalWindow, runConfig.PayoutReminderWindow, logger,
+		payoutStore, paypalService, notifier, fundEvents,
+		runConfig.PayoutApprovalWindow, runConfig.PayoutReminderWindow,
+		runConfig.PayoutSecondApprovalAboveCents, logger,
 	)
 
 	authMiddleware := middlewares.Verify(
@@ -249,24 +357,57 @@ func run(ctx context.Context, runConfig RunConfig) error {
 		middlewares.TokenFromCookie,
 		middlewares.TokenFromHeader,
 	)
-	adminAuthMiddleware := middlewares.Verify(
+	verifyAdmin := middlewares.Verify(
 		verifier.VerifyAdmin,
 		middlewares.TokenFromCookie,
 		middlewares.TokenFromHeader,
 	)
 
+	// Inside the verify, which is what puts the token it reads on the context.
+	secondFactorGroups := twoFactorGroups(runConfig.TwoFactorGroups)
+	requireTwoFactor := middlewares.RequireTwoFactor(sessionManager, secondFactorGroups)
+	adminAuthMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
+		return verifyAdmin(requireTwoFactor(next))
+	}
+
+	// The API takes its token from the header only. A cookie is sent by the
+	// browser on its own, and accepting one here would make every JSON route
+	// reachable cross-site by anyone who can get a member to load a page.
+	apiAuthMiddleware := middlewares.VerifyOr(
+		apiweb.Unauthorized,
+		verifier.Verify,
+		middlewares.TokenFromHeader,
+	)
+	// The admin routes ask for the same second factor the admin pages do. A token
+	// from the password alone carries the same roles, so without this the API is
+	// a way round the pages.
+	apiTwoFactorMiddleware := middlewares.RequireTwoFactorOr(apiweb.SecondFactorRequired, sessionManager, secondFactorGroups)
+
 	// Handlers setup
 	donationHandlers := homeweb.NewFundHandlers(
 		donationService, fundEvents, noticeService, enrollmentService, sessionManager, authMiddleware, logger,
-		runConfig.PayPal.ClientID,
+		runConfig.PayPal.ClientID, runConfig.PublicURL,
 	)
-	authHandlers := authweb.NewAuthHandlers(authService, memberService, sessionManager, runConfig.PayPal.ClientID, runConfig.IsLive)
+	authHandlers := authweb.NewAuthHandlers(authService, memberService, enrollmentService, sessionManager, authMiddleware, logger, runConfig.PayPal.ClientID, runConfig.IsLive)
+	if issuer != nil {
+		authHandlers.PublishKeysWith(issuer.ServeJWKS)
+	}
+	if err = authHandlers.TrustProxies(runConfig.TrustedProxies); err != nil {
+		return fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
 	adminHandlers := adminweb.NewAdminHandlers(
-		adminAuthMiddleware, memberService, donationService, authService, financeService, enrollmentService, payoutService, fundEvents, adminEvents, noticeService, sessionManager, logger, messageBroker, runConfig.PayPal.ClientID,
+		adminAuthMiddleware, memberService, donationService, authService, financeService, enrollmentService, payoutService, fundEvents, adminEvents, noticeService, notificationService, sessionManager, logger, messageBroker, webhookArchive, runConfig.PayPal.ClientID,
+	)
+	adminHandlers.WatchPayPal(paypalClient)
+	apiHandlers := apiweb.NewAPIHandlers(
+		apiAuthMiddleware, apiTwoFactorMiddleware, donationService, fundEvents, payoutService, memberService, logger,
 	)
 	webhooksHandlers := hooksweb.NewWebhooksHandlers(
-		donationService, memberService, messageBroker, hooksstore.NewDeliveryStore(pool), logger, runConfig.PayPal.WebhookID,
+		donationService, memberService, messageBroker, webhookArchive, logger, runConfig.PayPal.WebhookID,
 	)
+	if runConfig.Stripe.WebhookSecret != "" {
+		webhooksHandlers.AcceptStripeWebhooks(runConfig.Stripe.WebhookSecret)
+	}
 
 	donationWebhookHandlers := donations.NewHandlers(donationStore, fundEvents, logger)
 	err = donationWebhookHandlers.Subscribe(messageBroker)
@@ -320,6 +461,7 @@ func run(ctx context.Context, runConfig RunConfig) error {
 	donationHandlers.Register(router)
 	adminHandlers.Register(router)
 	webhooksHandlers.Register(router)
+	apiHandlers.Register(router)
 
 	server := &http.Server{
 		Addr:    ":8080",
@@ -388,7 +530,7 @@ func resolveStoreDir(configured string, isLive bool, logger *slog.Logger) (strin
 				"without one, webhook events do not survive a deploy")
 		}
 
-		fallback := filepath.Join(os.TempDir(), "fund-jetstream")
+		fallback := fallbackStoreDir()
 
 		logger.Warn("NATS_STORE_DIR is not set, so webhook events will not survive a restart",
 			slog.String("falling_back_to", fallback),
@@ -432,13 +574,24 @@ func checkWritable(dir string) error {
 // a process restart and nothing else -- which is the failure mode this change
 // exists to remove, wearing a persistence badge.
 //
-// DontListen stays: the server has never opened a port, and nothing outside this
-// process connects to it.
+// It listens on loopback, on a port picked at boot, and only for a client with
+// the token generated alongside it. That is for `fund webhooks`, run in this
+// container, which cannot open a second server over the same store without
+// corrupting it; messaging.Dial finds the port and token in the control file.
+// This process still connects in-process, and nothing off the host can reach
+// the port at all.
 func runNATS(enableLogging bool, storeDir string) (*nats.Conn, *server.Server, error) {
+	token, err := messaging.NewControlToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	opts := server.Options{
-		DontListen: true,
-		JetStream:  true,
-		StoreDir:   storeDir,
+		Host:          "127.0.0.1",
+		Port:          server.RANDOM_PORT,
+		Authorization: token,
+		JetStream:     true,
+		StoreDir:      storeDir,
 	}
 
 	ns, err := server.NewServer(&opts)
@@ -456,11 +609,30 @@ func runNATS(enableLogging bool, storeDir string) (*nats.Conn, *server.Server, e
 		return nil, nil, errors.New("nats server not ready")
 	}
 
-	clientOpts := []nats.Option{nats.InProcessServer(ns)}
-	nc, err := nats.Connect(nats.DefaultURL, clientOpts...)
+	clientOpts := []nats.Option{nats.InProcessServer(ns), nats.Token(token)}
+	nc, err := nats.Connect(ns.ClientURL(), clientOpts...)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if err = messaging.WriteControl(storeDir, messaging.Control{URL: ns.ClientURL(), Token: token}); err != nil {
+		return nil, nil, err
+	}
+
 	return nc, ns, nil
 }
+
+// StoreDir is where the running service keeps its stream, for a command that
+// needs to find it: the configured directory, or the fallback resolveStoreDir
+// warns about outside production.
+func StoreDir(runConfig RunConfig) string {
+	if runConfig.NATSStoreDir == "" {
+		return fallbackStoreDir()
+	}
+
+	return runConfig.NATSStoreDir
+}
+
+func fallbackStoreDir() string {
+	return filepath.Join(os.TempDir(), "fund-jetstream")
+}
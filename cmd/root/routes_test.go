@@ -1,12 +1,16 @@
 package root
 
 import (
+	"encoding/json"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 	"testing"
 
 	"boardfund/web/adminweb"
+	"boardfund/web/apiweb"
 	"boardfund/web/authweb"
 	"boardfund/web/homeweb"
 	"boardfund/web/hooksweb"
@@ -26,6 +30,20 @@ import (
 // took production down: registration happens inside run(), so nothing had ever
 // called it outside of a live boot.
 func TestAllRoutesRegisterWithoutConflict(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("routes conflict: %v", r)
+		}
+	}()
+
+	registerAll(t)
+}
+
+// registerAll registers every handler group the way run() does and returns the
+// router, for the tests that need to know what is actually served.
+func registerAll(t *testing.T) *mux.Router {
+	t.Helper()
+
 	// Register only takes method values off each handler, so the services behind
 	// them are never dereferenced here and can stay nil. The middleware arguments
 	// are the exception -- they are called during registration, not per request.
@@ -34,12 +52,15 @@ func TestAllRoutesRegisterWithoutConflict(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 	sessions := scs.New()
 
-	authHandlers := authweb.NewAuthHandlers(nil, nil, nil, "", true)
-	donationHandlers := homeweb.NewFundHandlers(nil, nil, nil, nil, nil, passthrough, nil, "")
+	authHandlers := authweb.NewAuthHandlers(nil, nil, nil, nil, passthrough, logger, "", true)
+	// As run() does with a local issuer, so the optional routes are checked too.
+	authHandlers.PublishKeysWith(func(http.ResponseWriter, *http.Request) {})
+	donationHandlers := homeweb.NewFundHandlers(nil, nil, nil, nil, nil, passthrough, nil, "", "")
 	adminHandlers := adminweb.NewAdminHandlers(
-		passthrough, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger, nil, "",
+		passthrough, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger, nil, nil, "",
 	)
 	webhooksHandlers := hooksweb.NewWebhooksHandlers(nil, nil, nil, nil, nil, "")
+	apiHandlers := apiweb.NewAPIHandlers(passthrough, passthrough, nil, nil, nil, nil, logger)
 
 	router := mux.NewRouter(http.NewServeMux())
 
@@ -54,14 +75,90 @@ func TestAllRoutesRegisterWithoutConflict(t *testing.T) {
 	// The static handler is registered before the groups.
 	router.HandleFunc("/static/", func(http.ResponseWriter, *http.Request) {})
 
-	defer func() {
-		if r := recover(); r != nil {
-			t.Fatalf("routes conflict: %v", r)
-		}
-	}()
-
 	authHandlers.Register(router)
 	donationHandlers.Register(router)
 	adminHandlers.Register(router)
 	webhooksHandlers.Register(router)
+	apiHandlers.Register(router)
+
+	return router
+}
+
+// The OpenAPI document is what a client is generated from, so a route it does
+// not name is one nobody's client can call, and a route it names that is not
+// served is one every generated client will call and get a 404 from.
+//
+// Everything under /api/v1 or /.well-known is the JSON surface, whichever
+// package registers it. What answers in JSON and is not in the spec is listed in
+// notInTheSpec, with the reason.
+func TestTheSpecMatchesTheRoutes(t *testing.T) {
+	var served []string
+	for _, pattern := range registerAll(t).Patterns() {
+		if _, excluded := notInTheSpec[pattern]; excluded {
+			continue
+		}
+
+		_, path, _ := strings.Cut(pattern, " ")
+		if strings.HasPrefix(path, apiweb.Prefix+"/") || strings.HasPrefix(path, "/.well-known/") {
+			served = append(served, pattern)
+		}
+	}
+
+	spec, err := apiweb.Spec()
+	if err != nil {
+		t.Fatalf("building the spec: %v", err)
+	}
+
+	var document struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err = json.Unmarshal(spec, &document); err != nil {
+		t.Fatalf("the spec is not JSON: %v", err)
+	}
+
+	var described []string
+	for path, methods := range document.Paths {
+		for method := range methods {
+			described = append(described, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	for _, pattern := range served {
+		if !strings.Contains(pattern, " ") {
+			t.Errorf("%q takes any method; an API route should say which it answers", pattern)
+
+			continue
+		}
+
+		if !slices.Contains(described, pattern) {
+			t.Errorf("%q is served but missing from the spec", pattern)
+		}
+	}
+
+	for _, operation := range described {
+		if !slices.Contains(served, operation) {
+			t.Errorf("the spec describes %q, which is not served", operation)
+		}
+	}
+}
+
+// notInTheSpec is the JSON served outside the API, each with why.
+var notInTheSpec = map[string]string{
+	// The document, not something in it.
+	"GET " + apiweb.SpecPath: "the spec itself",
+	// A standard JWK set, read by JWT libraries rather than by a generated
+	// client. Its shape is RFC 7517's, which an operation here would only restate.
+	"GET " + authweb.JWKSPath: "the token signing keys",
+}
+
+// An exclusion for a route that is no longer served would hide the next route
+// to take its path.
+func TestEveryExclusionFromTheSpecIsServed(t *testing.T) {
+	patterns := registerAll(t).Patterns()
+
+	for pattern := range notInTheSpec {
+		if !slices.Contains(patterns, pattern) {
+			t.Errorf("%q is excluded from the spec but not served", pattern)
+		}
+	}
 }
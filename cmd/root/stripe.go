@@ -0,0 +1,31 @@
+package root
+
+import (
+	"log/slog"
+
+	"boardfund/stripe"
+)
+
+// StripeConfig is the Stripe account donations may be taken through. No secret
+// key means no Stripe: funds take PayPal alone, as they always did.
+type StripeConfig struct {
+	SecretKey string
+	// WebhookSecret is the signing secret of the webhook endpoint registered in
+	// Stripe's dashboard. Without it there is no endpoint, and Stripe's refunds
+	// and disputes are not heard of until reconciliation.
+	WebhookSecret string
+	BaseURL       string
+}
+
+// NewStripe is Stripe as configured, or nil when it is not.
+//
+// Every command that builds a donation, member or finance service adds it, not
+// only the web server: closing a fund cancels its subscriptions and the audit
+// reconciles its payments, wherever they were taken.
+func NewStripe(runConfig RunConfig, logger *slog.Logger) *stripe.Stripe {
+	if runConfig.Stripe.SecretKey == "" {
+		return nil
+	}
+
+	return stripe.NewStripe(stripe.NewClient(runConfig.Stripe.SecretKey, logger, runConfig.Stripe.BaseURL))
+}
@@ -0,0 +1,191 @@
+package webhookscmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"boardfund/cmd/root"
+	"boardfund/messaging"
+	"boardfund/service/adminevents"
+	"boardfund/service/donations"
+	donationstore "boardfund/service/donations/store"
+	"boardfund/service/fundevents"
+	fundeventstore "boardfund/service/fundevents/store"
+	"boardfund/service/payouts"
+	payoutstore "boardfund/service/payouts/store"
+
+	"github.com/google/uuid"
+)
+
+// window is the range of arrival times a replay reads, since inclusive.
+type window struct {
+	since time.Time
+	until time.Time
+}
+
+// parseWindow reads --since and --until. A bare date is midnight UTC, which is
+// how the rest of the commands read one; until defaults to now.
+func parseWindow(sinceStr, untilStr string, now time.Time) (window, error) {
+	since, err := parseTime(sinceStr)
+	if err != nil {
+		return window{}, fmt.Errorf("invalid --since: %w", err)
+	}
+
+	until := now
+	if untilStr != "" {
+		if until, err = parseTime(untilStr); err != nil {
+			return window{}, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	if !since.Before(until) {
+		return window{}, fmt.Errorf("--since %s is not before --until %s",
+			since.Format(time.RFC3339), until.Format(time.RFC3339))
+	}
+
+	return window{since: since, until: until}, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("want RFC 3339 or YYYY-MM-DD, got %q", value)
+	}
+
+	return parsed, nil
+}
+
+// callbacks collects what the handlers subscribe, by event, so a replay calls
+// exactly what a delivery from the broker would.
+type callbacks map[string][]func(data []byte) error
+
+func (c callbacks) Subscribe(event string, cb func(data []byte) error) error {
+	c[event] = append(c[event], cb)
+
+	return nil
+}
+
+// replayTally is what a replay did, for the summary and the job's log line.
+type replayTally struct {
+	read      int
+	unhandled int
+	failed    int
+	changes   int
+}
+
+// replayStored runs a range of the stored stream through the handlers.
+//
+// In this process, not the service's: the durables have already acknowledged
+// these messages, and republishing a week of them would hand every one to the
+// live consumers as if PayPal had sent it again. The service keeps running
+// meanwhile. If it is handed the same event while this runs, the handlers are
+// idempotent, which is what they are already relying on for redeliveries.
+//
+// A handler that fails is reported and the replay goes on. The live consumer
+// would have retried it; here there is nobody to retry for, and one bad payload
+// should not hide what the rest of the week would do.
+func replayStored(ctx context.Context, runConfig *root.RunConfig, window window, subject string, dryRun bool, actor *uuid.UUID) ([]slog.Attr, error) {
+	d, err := build(ctx, runConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	var tally replayTally
+
+	verb := "changed"
+	if dryRun {
+		verb = "would change"
+	}
+
+	// A message is named once, above its first change, so a replay over a quiet
+	// week prints nothing between the header and the summary.
+	var current messaging.StoredMessage
+	var named bool
+
+	report := func(change string) {
+		if !named {
+			fmt.Printf("#%d %s, received %s\n", current.Seq, current.Subject, current.Received.UTC().Format(time.RFC3339))
+			named = true
+		}
+
+		fmt.Printf("  %s: %s\n", verb, change)
+		tally.changes++
+	}
+
+	handlers := callbacks{}
+
+	fundEvents := fundevents.NewService(fundeventstore.NewEventStore(d.pool), d.logger)
+
+	donationHandlers := donations.NewReplayHandlers(donationstore.NewDonationStore(d.pool), fundEvents, dryRun, report, d.logger)
+	if err = donationHandlers.Subscribe(handlers); err != nil {
+		return nil, err
+	}
+
+	payoutHandlers := payouts.NewReplayHandlers(payoutstore.NewPayoutStore(d.pool), dryRun, report, d.logger)
+	if err = payoutHandlers.Subscribe(handlers); err != nil {
+		return nil, err
+	}
+
+	if subject != "" && len(handlers[subject]) == 0 {
+		return nil, fmt.Errorf("nothing handles %s, so there is nothing to replay it through", subject)
+	}
+
+	fmt.Printf("replaying webhooks received %s to %s\n",
+		window.since.UTC().Format(time.RFC3339), window.until.UTC().Format(time.RFC3339))
+
+	err = d.broker.Stored(ctx, window.since, window.until, subject, func(msg messaging.StoredMessage) error {
+		tally.read++
+
+		current, named = msg, false
+
+		cbs := handlers[msg.Subject]
+		if len(cbs) == 0 {
+			tally.unhandled++
+
+			return nil
+		}
+
+		for _, cb := range cbs {
+			if errCb := cb(msg.Data); errCb != nil {
+				tally.failed++
+				fmt.Printf("#%d %s failed: %v\n", msg.Seq, msg.Subject, errCb)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("\nread %d webhook(s): %d change(s), %d failed, %d with no handler\n",
+		tally.read, tally.changes, tally.failed, tally.unhandled)
+
+	attrs := []slog.Attr{
+		slog.Bool("dry_run", dryRun),
+		slog.Int("read", tally.read),
+		slog.Int("changes", tally.changes),
+		slog.Int("failed", tally.failed),
+	}
+
+	if dryRun {
+		if tally.changes > 0 {
+			fmt.Println("re-run with --confirm to apply them")
+		}
+	} else {
+		d.events.Record(ctx, adminevents.StoredWebhooksReplayed(actor, subject, window.since, window.until, tally.changes))
+	}
+
+	if tally.failed > 0 {
+		return attrs, fmt.Errorf("%d webhook(s) failed to replay", tally.failed)
+	}
+
+	return attrs, nil
+}
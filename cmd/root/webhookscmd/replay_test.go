@@ -0,0 +1,57 @@
+package webhookscmd
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"boardfund/messaging"
+	"boardfund/service/donations"
+	"boardfund/service/payouts"
+)
+
+func TestParseWindow(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseWindow("2026-10-11", "", now)
+	if err != nil {
+		t.Fatalf("parseWindow: %v", err)
+	}
+
+	if !got.since.Equal(time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)) || !got.until.Equal(now) {
+		t.Errorf("window = %v to %v", got.since, got.until)
+	}
+
+	if _, err = parseWindow("2026-10-11T09:30:00+02:00", "2026-10-12", now); err != nil {
+		t.Errorf("an RFC 3339 start: %v", err)
+	}
+
+	for _, bad := range [][2]string{{"last week", ""}, {"2026-10-12", "2026-10-11"}, {"2026-10-11", "2026-10-11"}} {
+		if _, err = parseWindow(bad[0], bad[1], now); err == nil {
+			t.Errorf("parseWindow(%q, %q) should fail", bad[0], bad[1])
+		}
+	}
+}
+
+// The replay finds its callbacks the way the broker does, through Subscribe, so
+// an event the handlers take on later is replayable without touching this
+// package.
+func TestTheCollectorHasEveryHandledEvent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handlers := callbacks{}
+
+	if err := donations.NewReplayHandlers(nil, nil, true, func(string) {}, logger).Subscribe(handlers); err != nil {
+		t.Fatalf("donations: %v", err)
+	}
+
+	if err := payouts.NewReplayHandlers(nil, true, func(string) {}, logger).Subscribe(handlers); err != nil {
+		t.Fatalf("payouts: %v", err)
+	}
+
+	for _, event := range []string{messaging.PaymentCompleted, messaging.PaymentRefunded, messaging.SubscriptionSuspended, messaging.PayoutsItemSucceeded, messaging.PayoutsBatchSuccess} {
+		if len(handlers[event]) != 1 {
+			t.Errorf("%s has %d callback(s), want 1", event, len(handlers[event]))
+		}
+	}
+}
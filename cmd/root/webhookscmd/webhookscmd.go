@@ -0,0 +1,227 @@
+// Package webhookscmd reaches the webhook stream of the running service from the
+// command line. Unlike the other commands these need that service up: the
+// stream lives in its embedded server, and they connect to it through the
+// control file rather than opening the store themselves.
+package webhookscmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"boardfund/cmd/root"
+	"boardfund/logging"
+	"boardfund/messaging"
+	"boardfund/pg"
+	"boardfund/service/adminevents"
+	admineventstore "boardfund/service/adminevents/store"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"github.com/spf13/cobra"
+)
+
+func WebhooksCmd(runConfig *root.RunConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "inspect and replay webhook messages the service received",
+		Long: "Run inside the service's container: the commands connect to the " +
+			"running service through the control file in NATS_STORE_DIR.",
+	}
+
+	cmd.AddCommand(listCmd(runConfig))
+	cmd.AddCommand(replayCmd(runConfig))
+
+	return cmd
+}
+
+type deps struct {
+	nc     *nats.Conn
+	pool   *pgxpool.Pool
+	broker *messaging.Broker
+	events *adminevents.Service
+	logger *slog.Logger
+}
+
+// build attaches to the running service's streams and opens the admin log.
+// Constructed per command, like the others: these are one-shot invocations.
+func build(ctx context.Context, runConfig *root.RunConfig) (*deps, error) {
+	logger := logging.New("webhooks")
+
+	nc, err := messaging.Dial(root.StoreDir(*runConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	broker, err := messaging.Attach(ctx, nc, logger)
+	if err != nil {
+		nc.Close()
+
+		return nil, err
+	}
+
+	dbURI := fmt.Sprintf(
+		"postgresql://%s:%s@%s:%s/%s",
+		runConfig.PGUser, runConfig.PGPass, runConfig.PGHost, runConfig.PGPort, runConfig.PGDB,
+	)
+
+	pool, err := pg.GetDBPool(dbURI)
+	if err != nil {
+		nc.Close()
+
+		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
+	}
+
+	return &deps{
+		nc:     nc,
+		pool:   pool,
+		broker: broker,
+		events: adminevents.NewService(admineventstore.NewEventStore(pool), logger),
+		logger: logger,
+	}, nil
+}
+
+// Close lets go of everything build opened. One call for both, so a command
+// cannot remember the one and forget the other.
+func (d *deps) Close() {
+	d.nc.Close()
+	d.pool.Close()
+}
+
+func listCmd(runConfig *root.RunConfig) *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list dead letters, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := build(cmd.Context(), runConfig)
+			if err != nil {
+				return err
+			}
+			defer d.Close()
+
+			letters, err := d.broker.DeadLetters(cmd.Context(), limit)
+			if err != nil {
+				return err
+			}
+
+			if len(letters) == 0 {
+				fmt.Println("no dead letters")
+
+				return nil
+			}
+
+			for _, letter := range letters {
+				fmt.Printf("%6d  %-40s  %d attempts  given up %s\n",
+					letter.Seq, letter.Subject, letter.Deliveries, letter.At.Format("2006-01-02 15:04:05"))
+				fmt.Printf("        %s\n", letter.Data)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 50, "how many to list")
+
+	return cmd
+}
+
+// replayCmd replays either one dead letter or a range of the stored stream.
+//
+// Given a sequence it is the admin page's replay button, for when the page is
+// not to hand: the letter goes back onto its subject and the running service's
+// consumer takes it from there.
+//
+// Given --since instead it reads WEBHOOKS itself and runs each message through
+// the donations and payouts callbacks in this process, against the real
+// stores. That is for the other failure: a handler that acknowledged what it
+// should not have, fixed since, leaving a week of webhooks handled wrongly and
+// nothing dead-lettered to show for it. A dry run unless --confirm is passed.
+//
+// Either way it is recorded in the admin log; --actor names the member running
+// it, since the command line has no session to take one from.
+func replayCmd(runConfig *root.RunConfig) *cobra.Command {
+	var actorStr, sinceStr, untilStr, subject string
+	var confirm bool
+
+	cmd := &cobra.Command{
+		Use:   "replay [<seq>]",
+		Short: "send a dead letter back to its consumer, or run stored webhooks through the handlers again",
+		Long: "With a dead-letter sequence, sends that letter back to the consumer that " +
+			"gave up on it. With --since, runs every stored webhook received in the " +
+			"range through the donation and payout handlers, and reports the payments, " +
+			"refunds and statuses that change; without --confirm it only reports what " +
+			"would change.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var actor *uuid.UUID
+			if actorStr != "" {
+				parsed, errParse := uuid.Parse(actorStr)
+				if errParse != nil {
+					return fmt.Errorf("invalid --actor, want a member UUID: %w", errParse)
+				}
+
+				actor = &parsed
+			}
+
+			if len(args) == 1 {
+				if sinceStr != "" || untilStr != "" || subject != "" || confirm {
+					return fmt.Errorf("a dead-letter sequence takes no --since, --until, --subject or --confirm")
+				}
+
+				return replayDeadLetter(cmd.Context(), runConfig, args[0], actor)
+			}
+
+			if sinceStr == "" {
+				return fmt.Errorf("give a dead-letter sequence, or --since to replay stored webhooks")
+			}
+
+			window, err := parseWindow(sinceStr, untilStr, time.Now())
+			if err != nil {
+				return err
+			}
+
+			return logging.Job(cmd.Context(), logging.New("webhooks"), "replay-stored",
+				func(ctx context.Context) ([]slog.Attr, error) {
+					return replayStored(ctx, runConfig, window, subject, !confirm, actor)
+				})
+		},
+	}
+
+	cmd.Flags().StringVar(&actorStr, "actor", "", "member UUID of whoever is replaying, for the admin log")
+	cmd.Flags().StringVar(&sinceStr, "since", "", "replay stored webhooks received from this time, RFC 3339 or YYYY-MM-DD")
+	cmd.Flags().StringVar(&untilStr, "until", "", "and before this time; defaults to now")
+	cmd.Flags().StringVar(&subject, "subject", "", "only this event type, such as PAYMENT.SALE.COMPLETED")
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "actually apply the changes; without this the command only reports what would change")
+
+	return cmd
+}
+
+func replayDeadLetter(ctx context.Context, runConfig *root.RunConfig, seqStr string, actor *uuid.UUID) error {
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || seq == 0 {
+		return fmt.Errorf("invalid dead-letter sequence %q", seqStr)
+	}
+
+	d, err := build(ctx, runConfig)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	letter, err := d.broker.ReplayDeadLetter(ctx, seq)
+	if err != nil {
+		return err
+	}
+
+	d.events.Record(ctx, adminevents.WebhookReplayed(actor, letter.Subject, letter.Seq, "from the command line"))
+
+	fmt.Printf("replayed %s #%d to its consumer\n", letter.Subject, letter.Seq)
+
+	return nil
+}
@@ -13,7 +13,7 @@ import (
 )
 
 const getAdminEvents = `-- name: GetAdminEvents :many
-SELECT admin_event.id, admin_event.kind, admin_event.occurred_at, admin_event.actor_member_id, admin_event.subject_member_id, admin_event.detail, admin_event.created, admin_event.subject_label,
+SELECT admin_event.id, admin_event.kind, admin_event.occurred_at, admin_event.actor_member_id, admin_event.subject_member_id, admin_event.detail, admin_event.created, admin_event.subject_label, admin_event.role,
        actor.bco_name   AS actor_name,
        subject.bco_name AS subject_name
 FROM admin_event
@@ -52,6 +52,7 @@ func (q *Queries) GetAdminEvents(ctx context.Context, limit int32) ([]GetAdminEv
 			&i.AdminEvent.Detail,
 			&i.AdminEvent.Created,
 			&i.AdminEvent.SubjectLabel,
+			&i.AdminEvent.Role,
 			&i.ActorName,
 			&i.SubjectName,
 		); err != nil {
@@ -67,9 +68,9 @@ func (q *Queries) GetAdminEvents(ctx context.Context, limit int32) ([]GetAdminEv
 
 const insertAdminEvent = `-- name: InsertAdminEvent :one
 INSERT INTO admin_event (id, kind, occurred_at, actor_member_id, subject_member_id, subject_label,
-                         detail)
-VALUES ($1, $2, COALESCE($7::timestamptz, now()), $3, $4, $5, $6)
-RETURNING id, kind, occurred_at, actor_member_id, subject_member_id, detail, created, subject_label
+                         detail, role)
+VALUES ($1, $2, COALESCE($8::timestamptz, now()), $3, $4, $5, $6, $7)
+RETURNING id, kind, occurred_at, actor_member_id, subject_member_id, detail, created, subject_label, role
 `
 
 type InsertAdminEventParams struct {
@@ -79,6 +80,7 @@ type InsertAdminEventParams struct {
 	SubjectMemberID uuid.NullUUID
 	SubjectLabel    pgtype.Text
 	Detail          pgtype.Text
+	Role            pgtype.Text
 	OccurredAt      pgtype.Timestamptz
 }
 
@@ -90,6 +92,7 @@ func (q *Queries) InsertAdminEvent(ctx context.Context, arg InsertAdminEventPara
 		arg.SubjectMemberID,
 		arg.SubjectLabel,
 		arg.Detail,
+		arg.Role,
 		arg.OccurredAt,
 	)
 	var i AdminEvent
@@ -102,6 +105,7 @@ func (q *Queries) InsertAdminEvent(ctx context.Context, arg InsertAdminEventPara
 		&i.Detail,
 		&i.Created,
 		&i.SubjectLabel,
+		&i.Role,
 	)
 	return i, err
 }
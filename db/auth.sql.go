@@ -7,8 +7,192 @@ package db
 
 import (
 	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const assignFundManager = `-- name: AssignFundManager :execrows
+INSERT INTO fund_manager (member_id, fund_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type AssignFundManagerParams struct {
+	MemberID uuid.UUID
+	FundID   uuid.UUID
+}
+
+func (q *Queries) AssignFundManager(ctx context.Context, arg AssignFundManagerParams) (int64, error) {
+	result, err := q.db.Exec(ctx, assignFundManager, arg.MemberID, arg.FundID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const claimInvite = `-- name: ClaimInvite :one
+UPDATE invite
+SET used_at = now()
+WHERE id = $1
+  AND used_at IS NULL
+  AND revoked_at IS NULL
+  AND expires_at > now()
+RETURNING id, email, fund_id, invited_by, member_id, expires_at, used_at, revoked_at, created
+`
+
+func (q *Queries) ClaimInvite(ctx context.Context, id uuid.UUID) (Invite, error) {
+	row := q.db.QueryRow(ctx, claimInvite, id)
+	var i Invite
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.FundID,
+		&i.InvitedBy,
+		&i.MemberID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.RevokedAt,
+		&i.Created,
+	)
+	return i, err
+}
+
+const claimMemberTOTPAttempt = `-- name: ClaimMemberTOTPAttempt :one
+UPDATE member_totp
+SET failed_attempts = failed_attempts + 1,
+    locked_until    = CASE
+                          WHEN failed_attempts + 1 >= $1::int THEN $2::timestamptz
+                          ELSE locked_until END
+WHERE member_id = $3
+  AND confirmed_at IS NOT NULL
+  AND (locked_until IS NULL OR locked_until <= $4::timestamptz)
+RETURNING member_id, secret, confirmed_at, last_used_step, created, failed_attempts, locked_until
+`
+
+type ClaimMemberTOTPAttemptParams struct {
+	MaxAttempts int32
+	LockUntil   pgtype.Timestamptz
+	MemberID    uuid.UUID
+	Now         pgtype.Timestamptz
+}
+
+// Takes one attempt from the member's allowance before the code is checked, so
+// guesses sent all at once are each counted rather than all checked against the
+// count as it stood. No row means the member is locked out.
+func (q *Queries) ClaimMemberTOTPAttempt(ctx context.Context, arg ClaimMemberTOTPAttemptParams) (MemberTotp, error) {
+	row := q.db.QueryRow(ctx, claimMemberTOTPAttempt,
+		arg.MaxAttempts,
+		arg.LockUntil,
+		arg.MemberID,
+		arg.Now,
+	)
+	var i MemberTotp
+	err := row.Scan(
+		&i.MemberID,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.Created,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const clearMemberTOTPAttempts = `-- name: ClearMemberTOTPAttempts :exec
+UPDATE member_totp
+SET failed_attempts = 0,
+    locked_until    = NULL
+WHERE member_id = $1
+`
+
+func (q *Queries) ClearMemberTOTPAttempts(ctx context.Context, memberID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, clearMemberTOTPAttempts, memberID)
+	return err
+}
+
+const confirmMemberTOTP = `-- name: ConfirmMemberTOTP :one
+UPDATE member_totp
+SET confirmed_at   = now(),
+    last_used_step = $2
+WHERE member_id = $1
+  AND confirmed_at IS NULL
+RETURNING member_id, secret, confirmed_at, last_used_step, created, failed_attempts, locked_until
+`
+
+type ConfirmMemberTOTPParams struct {
+	MemberID     uuid.UUID
+	LastUsedStep int64
+}
+
+func (q *Queries) ConfirmMemberTOTP(ctx context.Context, arg ConfirmMemberTOTPParams) (MemberTotp, error) {
+	row := q.db.QueryRow(ctx, confirmMemberTOTP, arg.MemberID, arg.LastUsedStep)
+	var i MemberTotp
+	err := row.Scan(
+		&i.MemberID,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.Created,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const countPasswordResetsByEmail = `-- name: CountPasswordResetsByEmail :one
+SELECT count(*)
+FROM password_reset
+WHERE email = $1
+  AND created > $2
+`
+
+type CountPasswordResetsByEmailParams struct {
+	Email   string
+	Created pgtype.Timestamptz
+}
+
+func (q *Queries) CountPasswordResetsByEmail(ctx context.Context, arg CountPasswordResetsByEmailParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPasswordResetsByEmail, arg.Email, arg.Created)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPasswordResetsByIP = `-- name: CountPasswordResetsByIP :one
+SELECT count(*)
+FROM password_reset
+WHERE ip_address = $1
+  AND created > $2
+`
+
+type CountPasswordResetsByIPParams struct {
+	IpAddress string
+	Created   pgtype.Timestamptz
+}
+
+func (q *Queries) CountPasswordResetsByIP(ctx context.Context, arg CountPasswordResetsByIPParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPasswordResetsByIP, arg.IpAddress, arg.Created)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUnusedRecoveryCodes = `-- name: CountUnusedRecoveryCodes :one
+SELECT count(*)
+FROM member_recovery_code
+WHERE member_id = $1
+  AND used_at IS NULL
+`
+
+func (q *Queries) CountUnusedRecoveryCodes(ctx context.Context, memberID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnusedRecoveryCodes, memberID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deleteApprovedEmail = `-- name: DeleteApprovedEmail :one
 DELETE FROM approved_email
 WHERE email = $1
@@ -28,6 +212,47 @@ func (q *Queries) DeleteApprovedEmail(ctx context.Context, email string) (Approv
 	return i, err
 }
 
+const deleteMemberTOTP = `-- name: DeleteMemberTOTP :exec
+DELETE FROM member_totp
+WHERE member_id = $1
+`
+
+func (q *Queries) DeleteMemberTOTP(ctx context.Context, memberID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteMemberTOTP, memberID)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM member_recovery_code
+WHERE member_id = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, memberID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteRecoveryCodes, memberID)
+	return err
+}
+
+const getActiveMemberByEmail = `-- name: GetActiveMemberByEmail :one
+SELECT id, bco_name
+FROM member
+WHERE active
+  AND lower(email) = lower($1)
+ORDER BY created
+LIMIT 1
+`
+
+type GetActiveMemberByEmailRow struct {
+	ID      uuid.UUID
+	BcoName pgtype.Text
+}
+
+func (q *Queries) GetActiveMemberByEmail(ctx context.Context, lower string) (GetActiveMemberByEmailRow, error) {
+	row := q.db.QueryRow(ctx, getActiveMemberByEmail, lower)
+	var i GetActiveMemberByEmailRow
+	err := row.Scan(&i.ID, &i.BcoName)
+	return i, err
+}
+
 const getApprovedEmail = `-- name: GetApprovedEmail :one
 SELECT email, used, used_at, created, updated
 FROM approved_email
@@ -79,6 +304,168 @@ func (q *Queries) GetApprovedEmails(ctx context.Context) ([]ApprovedEmail, error
 	return items, nil
 }
 
+const getInvite = `-- name: GetInvite :one
+SELECT invite.id, invite.email, invite.fund_id, invite.invited_by, invite.member_id, invite.expires_at, invite.used_at, invite.revoked_at, invite.created,
+       fund.name        AS fund_name,
+       inviter.bco_name AS inviter_name,
+       invitee.bco_name AS member_name
+FROM invite
+         LEFT JOIN fund ON fund.id = invite.fund_id
+         JOIN member inviter ON inviter.id = invite.invited_by
+         LEFT JOIN member invitee ON invitee.id = invite.member_id
+WHERE invite.id = $1
+`
+
+type GetInviteRow struct {
+	ID          uuid.UUID
+	Email       pgtype.Text
+	FundID      uuid.NullUUID
+	InvitedBy   uuid.UUID
+	MemberID    uuid.NullUUID
+	ExpiresAt   pgtype.Timestamptz
+	UsedAt      pgtype.Timestamptz
+	RevokedAt   pgtype.Timestamptz
+	Created     pgtype.Timestamptz
+	FundName    pgtype.Text
+	InviterName pgtype.Text
+	MemberName  pgtype.Text
+}
+
+func (q *Queries) GetInvite(ctx context.Context, id uuid.UUID) (GetInviteRow, error) {
+	row := q.db.QueryRow(ctx, getInvite, id)
+	var i GetInviteRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.FundID,
+		&i.InvitedBy,
+		&i.MemberID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.RevokedAt,
+		&i.Created,
+		&i.FundName,
+		&i.InviterName,
+		&i.MemberName,
+	)
+	return i, err
+}
+
+const getInvites = `-- name: GetInvites :many
+SELECT invite.id, invite.email, invite.fund_id, invite.invited_by, invite.member_id, invite.expires_at, invite.used_at, invite.revoked_at, invite.created,
+       fund.name        AS fund_name,
+       inviter.bco_name AS inviter_name,
+       invitee.bco_name AS member_name
+FROM invite
+         LEFT JOIN fund ON fund.id = invite.fund_id
+         JOIN member inviter ON inviter.id = invite.invited_by
+         LEFT JOIN member invitee ON invitee.id = invite.member_id
+ORDER BY invite.created DESC
+LIMIT $1
+`
+
+type GetInvitesRow struct {
+	ID          uuid.UUID
+	Email       pgtype.Text
+	FundID      uuid.NullUUID
+	InvitedBy   uuid.UUID
+	MemberID    uuid.NullUUID
+	ExpiresAt   pgtype.Timestamptz
+	UsedAt      pgtype.Timestamptz
+	RevokedAt   pgtype.Timestamptz
+	Created     pgtype.Timestamptz
+	FundName    pgtype.Text
+	InviterName pgtype.Text
+	MemberName  pgtype.Text
+}
+
+func (q *Queries) GetInvites(ctx context.Context, limit int32) ([]GetInvitesRow, error) {
+	rows, err := q.db.Query(ctx, getInvites, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetInvitesRow
+	for rows.Next() {
+		var i GetInvitesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.FundID,
+			&i.InvitedBy,
+			&i.MemberID,
+			&i.ExpiresAt,
+			&i.UsedAt,
+			&i.RevokedAt,
+			&i.Created,
+			&i.FundName,
+			&i.InviterName,
+			&i.MemberName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getManagedFunds = `-- name: GetManagedFunds :many
+SELECT fund.id, fund.name
+FROM fund_manager
+         JOIN fund ON fund.id = fund_manager.fund_id
+WHERE fund_manager.member_id = $1
+ORDER BY fund.name
+`
+
+type GetManagedFundsRow struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (q *Queries) GetManagedFunds(ctx context.Context, memberID uuid.UUID) ([]GetManagedFundsRow, error) {
+	rows, err := q.db.Query(ctx, getManagedFunds, memberID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetManagedFundsRow
+	for rows.Next() {
+		var i GetManagedFundsRow
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMemberTOTP = `-- name: GetMemberTOTP :one
+SELECT member_id, secret, confirmed_at, last_used_step, created, failed_attempts, locked_until
+FROM member_totp
+WHERE member_id = $1
+`
+
+func (q *Queries) GetMemberTOTP(ctx context.Context, memberID uuid.UUID) (MemberTotp, error) {
+	row := q.db.QueryRow(ctx, getMemberTOTP, memberID)
+	var i MemberTotp
+	err := row.Scan(
+		&i.MemberID,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.Created,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
 const insertApprovedEmail = `-- name: InsertApprovedEmail :one
 INSERT INTO approved_email (email)
 VALUES ($1)
@@ -98,6 +485,116 @@ func (q *Queries) InsertApprovedEmail(ctx context.Context, email string) (Approv
 	return i, err
 }
 
+const insertInvite = `-- name: InsertInvite :one
+INSERT INTO invite (id, email, fund_id, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, email, fund_id, invited_by, member_id, expires_at, used_at, revoked_at, created
+`
+
+type InsertInviteParams struct {
+	ID        uuid.UUID
+	Email     pgtype.Text
+	FundID    uuid.NullUUID
+	InvitedBy uuid.UUID
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) InsertInvite(ctx context.Context, arg InsertInviteParams) (Invite, error) {
+	row := q.db.QueryRow(ctx, insertInvite,
+		arg.ID,
+		arg.Email,
+		arg.FundID,
+		arg.InvitedBy,
+		arg.ExpiresAt,
+	)
+	var i Invite
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.FundID,
+		&i.InvitedBy,
+		&i.MemberID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.RevokedAt,
+		&i.Created,
+	)
+	return i, err
+}
+
+const insertPasswordReset = `-- name: InsertPasswordReset :one
+INSERT INTO password_reset (id, email, ip_address, member_id, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, email, ip_address, member_id, token_hash, expires_at, used_at, created
+`
+
+type InsertPasswordResetParams struct {
+	ID        uuid.UUID
+	Email     string
+	IpAddress string
+	MemberID  uuid.NullUUID
+	TokenHash []byte
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) InsertPasswordReset(ctx context.Context, arg InsertPasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, insertPasswordReset,
+		arg.ID,
+		arg.Email,
+		arg.IpAddress,
+		arg.MemberID,
+		arg.TokenHash,
+		arg.ExpiresAt,
+	)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.IpAddress,
+		&i.MemberID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.Created,
+	)
+	return i, err
+}
+
+const insertRecoveryCode = `-- name: InsertRecoveryCode :exec
+INSERT INTO member_recovery_code (id, member_id, code_hash)
+VALUES ($1, $2, $3)
+`
+
+type InsertRecoveryCodeParams struct {
+	ID       uuid.UUID
+	MemberID uuid.UUID
+	CodeHash []byte
+}
+
+func (q *Queries) InsertRecoveryCode(ctx context.Context, arg InsertRecoveryCodeParams) error {
+	_, err := q.db.Exec(ctx, insertRecoveryCode, arg.ID, arg.MemberID, arg.CodeHash)
+	return err
+}
+
+const isFundManager = `-- name: IsFundManager :one
+SELECT EXISTS (SELECT 1
+               FROM fund_manager
+               WHERE member_id = $1
+                 AND fund_id = $2)
+`
+
+type IsFundManagerParams struct {
+	MemberID uuid.UUID
+	FundID   uuid.UUID
+}
+
+func (q *Queries) IsFundManager(ctx context.Context, arg IsFundManagerParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isFundManager, arg.MemberID, arg.FundID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const markApprovedEmailUsed = `-- name: MarkApprovedEmailUsed :one
 UPDATE approved_email
 SET used    = true,
@@ -118,3 +615,217 @@ func (q *Queries) MarkApprovedEmailUsed(ctx context.Context, email string) (Appr
 	)
 	return i, err
 }
+
+const redeemPasswordReset = `-- name: RedeemPasswordReset :one
+UPDATE password_reset
+SET used_at = now()
+WHERE token_hash = $1
+  AND member_id IS NOT NULL
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING id, email, ip_address, member_id, token_hash, expires_at, used_at, created
+`
+
+func (q *Queries) RedeemPasswordReset(ctx context.Context, tokenHash []byte) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, redeemPasswordReset, tokenHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.IpAddress,
+		&i.MemberID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.Created,
+	)
+	return i, err
+}
+
+const releaseInvite = `-- name: ReleaseInvite :exec
+UPDATE invite
+SET used_at = NULL
+WHERE id = $1
+  AND member_id IS NULL
+`
+
+func (q *Queries) ReleaseInvite(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, releaseInvite, id)
+	return err
+}
+
+const releasePasswordReset = `-- name: ReleasePasswordReset :exec
+UPDATE password_reset
+SET used_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ReleasePasswordReset(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, releasePasswordReset, id)
+	return err
+}
+
+const removeFundManager = `-- name: RemoveFundManager :execrows
+DELETE FROM fund_manager
+WHERE member_id = $1
+  AND fund_id = $2
+`
+
+type RemoveFundManagerParams struct {
+	MemberID uuid.UUID
+	FundID   uuid.UUID
+}
+
+func (q *Queries) RemoveFundManager(ctx context.Context, arg RemoveFundManagerParams) (int64, error) {
+	result, err := q.db.Exec(ctx, removeFundManager, arg.MemberID, arg.FundID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const retireOtherPasswordResets = `-- name: RetireOtherPasswordResets :exec
+UPDATE password_reset
+SET used_at = now()
+WHERE member_id = $1
+  AND id <> $2
+  AND used_at IS NULL
+`
+
+type RetireOtherPasswordResetsParams struct {
+	MemberID uuid.NullUUID
+	ID       uuid.UUID
+}
+
+func (q *Queries) RetireOtherPasswordResets(ctx context.Context, arg RetireOtherPasswordResetsParams) error {
+	_, err := q.db.Exec(ctx, retireOtherPasswordResets, arg.MemberID, arg.ID)
+	return err
+}
+
+const revokeInvite = `-- name: RevokeInvite :one
+UPDATE invite
+SET revoked_at = now()
+WHERE id = $1
+  AND used_at IS NULL
+  AND revoked_at IS NULL
+RETURNING id, email, fund_id, invited_by, member_id, expires_at, used_at, revoked_at, created
+`
+
+func (q *Queries) RevokeInvite(ctx context.Context, id uuid.UUID) (Invite, error) {
+	row := q.db.QueryRow(ctx, revokeInvite, id)
+	var i Invite
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.FundID,
+		&i.InvitedBy,
+		&i.MemberID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.RevokedAt,
+		&i.Created,
+	)
+	return i, err
+}
+
+const setInviteMember = `-- name: SetInviteMember :exec
+UPDATE invite
+SET member_id = $2
+WHERE id = $1
+`
+
+type SetInviteMemberParams struct {
+	ID       uuid.UUID
+	MemberID uuid.NullUUID
+}
+
+func (q *Queries) SetInviteMember(ctx context.Context, arg SetInviteMemberParams) error {
+	_, err := q.db.Exec(ctx, setInviteMember, arg.ID, arg.MemberID)
+	return err
+}
+
+const upsertPendingMemberTOTP = `-- name: UpsertPendingMemberTOTP :one
+INSERT INTO member_totp (member_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (member_id) DO UPDATE
+    SET secret  = excluded.secret,
+        created = now()
+WHERE member_totp.confirmed_at IS NULL
+RETURNING member_id, secret, confirmed_at, last_used_step, created, failed_attempts, locked_until
+`
+
+type UpsertPendingMemberTOTPParams struct {
+	MemberID uuid.UUID
+	Secret   string
+}
+
+func (q *Queries) UpsertPendingMemberTOTP(ctx context.Context, arg UpsertPendingMemberTOTPParams) (MemberTotp, error) {
+	row := q.db.QueryRow(ctx, upsertPendingMemberTOTP, arg.MemberID, arg.Secret)
+	var i MemberTotp
+	err := row.Scan(
+		&i.MemberID,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.Created,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const useMemberTOTPStep = `-- name: UseMemberTOTPStep :one
+UPDATE member_totp
+SET last_used_step = $2
+WHERE member_id = $1
+  AND confirmed_at IS NOT NULL
+  AND last_used_step < $2
+RETURNING member_id, secret, confirmed_at, last_used_step, created, failed_attempts, locked_until
+`
+
+type UseMemberTOTPStepParams struct {
+	MemberID     uuid.UUID
+	LastUsedStep int64
+}
+
+func (q *Queries) UseMemberTOTPStep(ctx context.Context, arg UseMemberTOTPStepParams) (MemberTotp, error) {
+	row := q.db.QueryRow(ctx, useMemberTOTPStep, arg.MemberID, arg.LastUsedStep)
+	var i MemberTotp
+	err := row.Scan(
+		&i.MemberID,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.Created,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :one
+UPDATE member_recovery_code
+SET used_at = now()
+WHERE member_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL
+RETURNING id, member_id, code_hash, used_at, created
+`
+
+type UseRecoveryCodeParams struct {
+	MemberID uuid.UUID
+	CodeHash []byte
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (MemberRecoveryCode, error) {
+	row := q.db.QueryRow(ctx, useRecoveryCode, arg.MemberID, arg.CodeHash)
+	var i MemberRecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.MemberID,
+		&i.CodeHash,
+		&i.UsedAt,
+		&i.Created,
+	)
+	return i, err
+}
@@ -12,6 +12,96 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const catalogueDonationPlan = `-- name: CatalogueDonationPlan :many
+INSERT INTO donation_plan (id, name, amount_cents, interval_unit, interval_count, active, paypal_plan_id, fund_id,
+                           provider_name, updated)
+VALUES ($1, $2, $3, $4, $5, true, $6, $7, $8, now())
+ON CONFLICT (fund_id, provider_name, interval_unit, interval_count, amount_cents) DO UPDATE
+    SET (name, active, paypal_plan_id, updated) = (EXCLUDED.name, true, EXCLUDED.paypal_plan_id, now())
+    WHERE donation_plan.active = false
+RETURNING id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
+`
+
+type CatalogueDonationPlanParams struct {
+	ID            uuid.UUID
+	Name          string
+	AmountCents   int32
+	IntervalUnit  IntervalUnit
+	IntervalCount int32
+	PaypalPlanID  pgtype.Text
+	FundID        uuid.UUID
+	ProviderName  string
+}
+
+// Adds a plan to the catalogue, or brings back one that was pruned with the
+// provider plan just created for it. A pruned plan had no subscriptions, so
+// nothing refers to the provider id being replaced.
+//
+// Returns nothing when a live plan is already there: somebody else asked for
+// the same amount at the same moment and theirs won. The caller deactivates the
+// plan it made rather than leaving it behind at PayPal.
+func (q *Queries) CatalogueDonationPlan(ctx context.Context, arg CatalogueDonationPlanParams) ([]DonationPlan, error) {
+	rows, err := q.db.Query(ctx, catalogueDonationPlan,
+		arg.ID,
+		arg.Name,
+		arg.AmountCents,
+		arg.IntervalUnit,
+		arg.IntervalCount,
+		arg.PaypalPlanID,
+		arg.FundID,
+		arg.ProviderName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DonationPlan
+	for rows.Next() {
+		var i DonationPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.PaypalPlanID,
+			&i.AmountCents,
+			&i.IntervalUnit,
+			&i.IntervalCount,
+			&i.Active,
+			&i.Created,
+			&i.Updated,
+			&i.FundID,
+			&i.ProviderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const closeUnmatchedPayment = `-- name: CloseUnmatchedPayment :exec
+UPDATE unmatched_payment
+SET donation_id = $2,
+    assigned_at = now()
+WHERE provider_payment_id = $1
+  AND assigned_at IS NULL
+`
+
+type CloseUnmatchedPaymentParams struct {
+	ProviderPaymentID string
+	DonationID        uuid.NullUUID
+}
+
+// Closes the unmatched row for a payment that has since been recorded some other
+// way -- a webhook that arrived after the import gave up on it. Nobody assigned
+// it, so assigned_by stays empty; the donation says where it went.
+func (q *Queries) CloseUnmatchedPayment(ctx context.Context, arg CloseUnmatchedPaymentParams) error {
+	_, err := q.db.Exec(ctx, closeUnmatchedPayment, arg.ProviderPaymentID, arg.DonationID)
+	return err
+}
+
 const deleteFundImage = `-- name: DeleteFundImage :exec
 DELETE
 FROM fund_image
@@ -23,6 +113,137 @@ func (q *Queries) DeleteFundImage(ctx context.Context, fundID uuid.UUID) error {
 	return err
 }
 
+const donationPaymentExists = `-- name: DonationPaymentExists :one
+SELECT EXISTS (SELECT 1 FROM donation_payment WHERE paypal_payment_id = $1)
+`
+
+// Whether a provider payment is already on record, so the import can skip it
+// without starting a donation it would only roll back.
+func (q *Queries) DonationPaymentExists(ctx context.Context, paypalPaymentID string) (bool, error) {
+	row := q.db.QueryRow(ctx, donationPaymentExists, paypalPaymentID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const findMemberByPayer = `-- name: FindMemberByPayer :one
+SELECT id
+FROM member
+WHERE active
+  AND (($1::text <> '' AND provider_payer_id = $1::text)
+    OR ($2::text <> '' AND (lower(email) = lower($2::text)
+        OR lower(paypal_email) = lower($2::text))))
+ORDER BY (provider_payer_id IS NOT DISTINCT FROM $1::text) DESC, created
+LIMIT 1
+`
+
+type FindMemberByPayerParams struct {
+	PayerID string
+	Email   string
+}
+
+// The member a provider's payer is, for a payment found without the browser
+// that would have said. The payer id is what PayPal's login recorded and is
+// certain; an email is a guess worth taking only when nothing else matches, and
+// never when it is empty, since an empty email matches every member without one.
+func (q *Queries) FindMemberByPayer(ctx context.Context, arg FindMemberByPayerParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, findMemberByPayer, arg.PayerID, arg.Email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getAbandonedDonationPlans = `-- name: GetAbandonedDonationPlans :many
+SELECT p.id, p.name, p.paypal_plan_id, p.amount_cents, p.interval_unit, p.interval_count, p.active, p.created, p.updated, p.fund_id, p.provider_name
+FROM donation_plan p
+         JOIN fund f ON f.id = p.fund_id
+WHERE p.active = true
+  AND (f.active = false
+    OR (p.updated < $1::timestamptz
+        AND NOT EXISTS (SELECT 1 FROM donation d WHERE d.donation_plan_id = p.id)))
+ORDER BY p.created
+`
+
+// Plans that are only costing clutter at PayPal: nobody has subscribed to them
+// and nobody has been handed one since the cutoff. Every opening of the
+// recurring form used to create one, so most were made for a donor who then
+// closed the popup.
+//
+// A closed fund's plans are included whether or not anyone subscribed. Closing
+// cancelled the subscriptions and deactivates its plans; any it could not are
+// picked up here.
+func (q *Queries) GetAbandonedDonationPlans(ctx context.Context, unusedSince pgtype.Timestamptz) ([]DonationPlan, error) {
+	rows, err := q.db.Query(ctx, getAbandonedDonationPlans, unusedSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DonationPlan
+	for rows.Next() {
+		var i DonationPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.PaypalPlanID,
+			&i.AmountCents,
+			&i.IntervalUnit,
+			&i.IntervalCount,
+			&i.Active,
+			&i.Created,
+			&i.Updated,
+			&i.FundID,
+			&i.ProviderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getActiveDonationPlansForFund = `-- name: GetActiveDonationPlansForFund :many
+SELECT id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
+FROM donation_plan
+WHERE fund_id = $1
+  AND active = true
+ORDER BY created
+`
+
+func (q *Queries) GetActiveDonationPlansForFund(ctx context.Context, fundID uuid.UUID) ([]DonationPlan, error) {
+	rows, err := q.db.Query(ctx, getActiveDonationPlansForFund, fundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DonationPlan
+	for rows.Next() {
+		var i DonationPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.PaypalPlanID,
+			&i.AmountCents,
+			&i.IntervalUnit,
+			&i.IntervalCount,
+			&i.Active,
+			&i.Created,
+			&i.Updated,
+			&i.FundID,
+			&i.ProviderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getActiveFunds = `-- name: GetActiveFunds :many
 WITH FundStats AS (SELECT fund_id,
                           COALESCE(SUM(amount_cents - refunded_cents), 0)::INTEGER AS total_donated,
@@ -36,7 +257,7 @@ WITH FundStats AS (SELECT fund_id,
                             JOIN member m ON donation.donor_id = m.id
                             LEFT JOIN donation_payment dp ON donation.id = dp.donation_id
                    GROUP BY fund_id)
-SELECT f.id, f.name, f.description, f.provider_id, f.provider_name, f.goal_cents, f.payout_frequency, f.active, f.principal, f.expires, f.next_payment, f.created, f.updated, f.enrollees_visible,
+SELECT f.id, f.name, f.description, f.provider_id, f.provider_name, f.goal_cents, f.payout_frequency, f.active, f.principal, f.expires, f.next_payment, f.created, f.updated, f.enrollees_visible, f.payout_allocation, f.payout_cap_cents, f.second_approval_above_cents, f.close_on_goal,
        fs.total_donated,
        fs.total_donations,
        fs.average_donation,
@@ -51,24 +272,28 @@ GROUP BY f.id, f.name, f.active, f.expires, f.created, fs.total_donated, fs.tota
 `
 
 type GetActiveFundsRow struct {
-	ID               uuid.UUID
-	Name             string
-	Description      string
-	ProviderID       string
-	ProviderName     string
-	GoalCents        pgtype.Int4
-	PayoutFrequency  PayoutFrequency
-	Active           bool
-	Principal        uuid.NullUUID
-	Expires          NullDBTime
-	NextPayment      DBTime
-	Created          pgtype.Timestamptz
-	Updated          pgtype.Timestamptz
-	EnrolleesVisible bool
-	TotalDonated     pgtype.Int4
-	TotalDonations   pgtype.Int8
-	AverageDonation  pgtype.Int4
-	TotalDonors      pgtype.Int8
+	ID                       uuid.UUID
+	Name                     string
+	Description              string
+	ProviderID               string
+	ProviderName             string
+	GoalCents                pgtype.Int4
+	PayoutFrequency          PayoutFrequency
+	Active                   bool
+	Principal                uuid.NullUUID
+	Expires                  NullDBTime
+	NextPayment              DBTime
+	Created                  pgtype.Timestamptz
+	Updated                  pgtype.Timestamptz
+	EnrolleesVisible         bool
+	PayoutAllocation         PayoutAllocation
+	PayoutCapCents           pgtype.Int4
+	SecondApprovalAboveCents pgtype.Int4
+	CloseOnGoal              bool
+	TotalDonated             pgtype.Int4
+	TotalDonations           pgtype.Int8
+	AverageDonation          pgtype.Int4
+	TotalDonors              pgtype.Int8
 }
 
 func (q *Queries) GetActiveFunds(ctx context.Context, payoutFrequency PayoutFrequency) ([]GetActiveFundsRow, error) {
@@ -95,6 +320,10 @@ func (q *Queries) GetActiveFunds(ctx context.Context, payoutFrequency PayoutFreq
 			&i.Created,
 			&i.Updated,
 			&i.EnrolleesVisible,
+			&i.PayoutAllocation,
+			&i.PayoutCapCents,
+			&i.SecondApprovalAboveCents,
+			&i.CloseOnGoal,
 			&i.TotalDonated,
 			&i.TotalDonations,
 			&i.AverageDonation,
@@ -123,7 +352,7 @@ WITH FundStats AS (SELECT fund_id,
                             JOIN member m ON donation.donor_id = m.id
                             LEFT JOIN donation_payment dp ON donation.id = dp.donation_id
                    GROUP BY fund_id)
-SELECT f.id, f.name, f.description, f.provider_id, f.provider_name, f.goal_cents, f.payout_frequency, f.active, f.principal, f.expires, f.next_payment, f.created, f.updated, f.enrollees_visible,
+SELECT f.id, f.name, f.description, f.provider_id, f.provider_name, f.goal_cents, f.payout_frequency, f.active, f.principal, f.expires, f.next_payment, f.created, f.updated, f.enrollees_visible, f.payout_allocation, f.payout_cap_cents, f.second_approval_above_cents, f.close_on_goal,
        fs.total_donated,
        fs.total_donations,
        fs.average_donation,
@@ -135,24 +364,28 @@ ORDER BY (f.active = false OR (f.expires IS NOT NULL AND f.expires <= NOW())),
 `
 
 type GetAllFundsWithStatsRow struct {
-	ID               uuid.UUID
-	Name             string
-	Description      string
-	ProviderID       string
-	ProviderName     string
-	GoalCents        pgtype.Int4
-	PayoutFrequency  PayoutFrequency
-	Active           bool
-	Principal        uuid.NullUUID
-	Expires          NullDBTime
-	NextPayment      DBTime
-	Created          pgtype.Timestamptz
-	Updated          pgtype.Timestamptz
-	EnrolleesVisible bool
-	TotalDonated     pgtype.Int4
-	TotalDonations   pgtype.Int8
-	AverageDonation  pgtype.Int4
-	TotalDonors      pgtype.Int8
+	ID                       uuid.UUID
+	Name                     string
+	Description              string
+	ProviderID               string
+	ProviderName             string
+	GoalCents                pgtype.Int4
+	PayoutFrequency          PayoutFrequency
+	Active                   bool
+	Principal                uuid.NullUUID
+	Expires                  NullDBTime
+	NextPayment              DBTime
+	Created                  pgtype.Timestamptz
+	Updated                  pgtype.Timestamptz
+	EnrolleesVisible         bool
+	PayoutAllocation         PayoutAllocation
+	PayoutCapCents           pgtype.Int4
+	SecondApprovalAboveCents pgtype.Int4
+	CloseOnGoal              bool
+	TotalDonated             pgtype.Int4
+	TotalDonations           pgtype.Int8
+	AverageDonation          pgtype.Int4
+	TotalDonors              pgtype.Int8
 }
 
 // The admin listing, deliberately unfiltered.
@@ -188,6 +421,10 @@ func (q *Queries) GetAllFundsWithStats(ctx context.Context) ([]GetAllFundsWithSt
 			&i.Created,
 			&i.Updated,
 			&i.EnrolleesVisible,
+			&i.PayoutAllocation,
+			&i.PayoutCapCents,
+			&i.SecondApprovalAboveCents,
+			&i.CloseOnGoal,
 			&i.TotalDonated,
 			&i.TotalDonations,
 			&i.AverageDonation,
@@ -229,7 +466,7 @@ WITH FundStats AS (SELECT fund_id,
                               JOIN fund_enrollment fe ON fe.id = p.fund_enrollment_id
                      WHERE p.status = 'paid'
                      GROUP BY bp.fund_id)
-SELECT f.id, f.name, f.description, f.provider_id, f.provider_name, f.goal_cents, f.payout_frequency, f.active, f.principal, f.expires, f.next_payment, f.created, f.updated, f.enrollees_visible,
+SELECT f.id, f.name, f.description, f.provider_id, f.provider_name, f.goal_cents, f.payout_frequency, f.active, f.principal, f.expires, f.next_payment, f.created, f.updated, f.enrollees_visible, f.payout_allocation, f.payout_cap_cents, f.second_approval_above_cents, f.close_on_goal,
        fs.total_donated,
        fs.total_donations,
        fs.average_donation,
@@ -251,28 +488,32 @@ ORDER BY COALESCE(f.expires, f.updated) DESC
 `
 
 type GetClosedFundsWithStatsRow struct {
-	ID               uuid.UUID
-	Name             string
-	Description      string
-	ProviderID       string
-	ProviderName     string
-	GoalCents        pgtype.Int4
-	PayoutFrequency  PayoutFrequency
-	Active           bool
-	Principal        uuid.NullUUID
-	Expires          NullDBTime
-	NextPayment      DBTime
-	Created          pgtype.Timestamptz
-	Updated          pgtype.Timestamptz
-	EnrolleesVisible bool
-	TotalDonated     pgtype.Int4
-	TotalDonations   pgtype.Int8
-	AverageDonation  pgtype.Int4
-	TotalDonors      pgtype.Int8
-	TotalPaidCents   int64
-	TotalRecipients  int64
-	TotalPayouts     int64
-	LastPayoutDate   pgtype.Timestamptz
+	ID                       uuid.UUID
+	Name                     string
+	Description              string
+	ProviderID               string
+	ProviderName             string
+	GoalCents                pgtype.Int4
+	PayoutFrequency          PayoutFrequency
+	Active                   bool
+	Principal                uuid.NullUUID
+	Expires                  NullDBTime
+	NextPayment              DBTime
+	Created                  pgtype.Timestamptz
+	Updated                  pgtype.Timestamptz
+	EnrolleesVisible         bool
+	PayoutAllocation         PayoutAllocation
+	PayoutCapCents           pgtype.Int4
+	SecondApprovalAboveCents pgtype.Int4
+	CloseOnGoal              bool
+	TotalDonated             pgtype.Int4
+	TotalDonations           pgtype.Int8
+	AverageDonation          pgtype.Int4
+	TotalDonors              pgtype.Int8
+	TotalPaidCents           int64
+	TotalRecipients          int64
+	TotalPayouts             int64
+	LastPayoutDate           pgtype.Timestamptz
 }
 
 // The public archive: funds that have ended, newest first.
@@ -306,6 +547,10 @@ func (q *Queries) GetClosedFundsWithStats(ctx context.Context) ([]GetClosedFunds
 			&i.Created,
 			&i.Updated,
 			&i.EnrolleesVisible,
+			&i.PayoutAllocation,
+			&i.PayoutCapCents,
+			&i.SecondApprovalAboveCents,
+			&i.CloseOnGoal,
 			&i.TotalDonated,
 			&i.TotalDonations,
 			&i.AverageDonation,
@@ -326,7 +571,7 @@ func (q *Queries) GetClosedFundsWithStats(ctx context.Context) ([]GetClosedFunds
 }
 
 const getDonationById = `-- name: GetDonationById :one
-SELECT id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+SELECT id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 FROM donation
 WHERE id = $1
 `
@@ -346,12 +591,13 @@ func (q *Queries) GetDonationById(ctx context.Context, id uuid.UUID) (Donation,
 		&i.Active,
 		&i.ProviderSubscriptionID,
 		&i.InactiveReason,
+		&i.ProviderName,
 	)
 	return i, err
 }
 
 const getDonationByProviderSubscriptionId = `-- name: GetDonationByProviderSubscriptionId :one
-SELECT id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+SELECT id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 FROM donation
 WHERE provider_subscription_id = $1
 `
@@ -371,12 +617,13 @@ func (q *Queries) GetDonationByProviderSubscriptionId(ctx context.Context, provi
 		&i.Active,
 		&i.ProviderSubscriptionID,
 		&i.InactiveReason,
+		&i.ProviderName,
 	)
 	return i, err
 }
 
 const getDonationPaymentById = `-- name: GetDonationPaymentById :one
-SELECT id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at
+SELECT id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at, dispute_id, dispute_state, disputed_cents, dispute_reason, dispute_updated_at
 FROM donation_payment
 WHERE id = $1
 `
@@ -396,12 +643,117 @@ func (q *Queries) GetDonationPaymentById(ctx context.Context, id uuid.UUID) (Don
 		&i.ProviderStatus,
 		&i.ProviderAmountCents,
 		&i.ReconciledAt,
+		&i.DisputeID,
+		&i.DisputeState,
+		&i.DisputedCents,
+		&i.DisputeReason,
+		&i.DisputeUpdatedAt,
 	)
 	return i, err
 }
 
+const getDonationPaymentByProviderPaymentId = `-- name: GetDonationPaymentByProviderPaymentId :many
+SELECT dp.id AS payment_id, d.id AS donation_id, d.fund_id, d.donor_id,
+       dp.amount_cents, dp.refunded_cents
+FROM donation_payment dp
+         JOIN donation d ON d.id = dp.donation_id
+WHERE dp.paypal_payment_id = $1
+`
+
+type GetDonationPaymentByProviderPaymentIdRow struct {
+	PaymentID     uuid.UUID
+	DonationID    uuid.UUID
+	FundID        uuid.UUID
+	DonorID       uuid.UUID
+	AmountCents   int32
+	RefundedCents int32
+}
+
+// A payment by the provider's id, with what a refund against it needs: the
+// donation it belongs to and how much of it has come back so far. :many so an
+// unknown payment is no rows rather than ErrNoRows, as with the refund itself.
+func (q *Queries) GetDonationPaymentByProviderPaymentId(ctx context.Context, paypalPaymentID string) ([]GetDonationPaymentByProviderPaymentIdRow, error) {
+	rows, err := q.db.Query(ctx, getDonationPaymentByProviderPaymentId, paypalPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDonationPaymentByProviderPaymentIdRow
+	for rows.Next() {
+		var i GetDonationPaymentByProviderPaymentIdRow
+		if err := rows.Scan(
+			&i.PaymentID,
+			&i.DonationID,
+			&i.FundID,
+			&i.DonorID,
+			&i.AmountCents,
+			&i.RefundedCents,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDonationPaymentDispute = `-- name: GetDonationPaymentDispute :many
+SELECT dp.id AS payment_id, d.id AS donation_id, d.fund_id, d.donor_id,
+       dp.amount_cents, dp.dispute_id, dp.dispute_state, dp.disputed_cents,
+       dp.dispute_updated_at
+FROM donation_payment dp
+         JOIN donation d ON d.id = dp.donation_id
+WHERE dp.paypal_payment_id = $1
+`
+
+type GetDonationPaymentDisputeRow struct {
+	PaymentID        uuid.UUID
+	DonationID       uuid.UUID
+	FundID           uuid.UUID
+	DonorID          uuid.UUID
+	AmountCents      int32
+	DisputeID        pgtype.Text
+	DisputeState     NullPaymentDisputeState
+	DisputedCents    int32
+	DisputeUpdatedAt pgtype.Timestamptz
+}
+
+// A payment's dispute as it stands, for predicting what the update above would
+// do without making it. :many for the same reason as the lookup below.
+func (q *Queries) GetDonationPaymentDispute(ctx context.Context, paypalPaymentID string) ([]GetDonationPaymentDisputeRow, error) {
+	rows, err := q.db.Query(ctx, getDonationPaymentDispute, paypalPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDonationPaymentDisputeRow
+	for rows.Next() {
+		var i GetDonationPaymentDisputeRow
+		if err := rows.Scan(
+			&i.PaymentID,
+			&i.DonationID,
+			&i.FundID,
+			&i.DonorID,
+			&i.AmountCents,
+			&i.DisputeID,
+			&i.DisputeState,
+			&i.DisputedCents,
+			&i.DisputeUpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getDonationPaymentsByDonationId = `-- name: GetDonationPaymentsByDonationId :many
-SELECT id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at
+SELECT id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at, dispute_id, dispute_state, disputed_cents, dispute_reason, dispute_updated_at
 FROM donation_payment
 WHERE donation_id = $1
 `
@@ -427,6 +779,11 @@ func (q *Queries) GetDonationPaymentsByDonationId(ctx context.Context, donationI
 			&i.ProviderStatus,
 			&i.ProviderAmountCents,
 			&i.ReconciledAt,
+			&i.DisputeID,
+			&i.DisputeState,
+			&i.DisputedCents,
+			&i.DisputeReason,
+			&i.DisputeUpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -439,7 +796,7 @@ func (q *Queries) GetDonationPaymentsByDonationId(ctx context.Context, donationI
 }
 
 const getDonationPaymentsByMemberPaypalEmail = `-- name: GetDonationPaymentsByMemberPaypalEmail :many
-SELECT donation_payment.id, donation_payment.donation_id, donation_payment.paypal_payment_id, donation_payment.amount_cents, donation_payment.created, donation_payment.updated, donation_payment.provider_fee_cents, donation_payment.refunded_cents, donation_payment.provider_status, donation_payment.provider_amount_cents, donation_payment.reconciled_at
+SELECT donation_payment.id, donation_payment.donation_id, donation_payment.paypal_payment_id, donation_payment.amount_cents, donation_payment.created, donation_payment.updated, donation_payment.provider_fee_cents, donation_payment.refunded_cents, donation_payment.provider_status, donation_payment.provider_amount_cents, donation_payment.reconciled_at, donation_payment.dispute_id, donation_payment.dispute_state, donation_payment.disputed_cents, donation_payment.dispute_reason, donation_payment.dispute_updated_at
 FROM donation_payment
          JOIN donation ON donation.id = donation_payment.donation_id
          JOIN member ON member.id = donation.donor_id
@@ -467,6 +824,11 @@ func (q *Queries) GetDonationPaymentsByMemberPaypalEmail(ctx context.Context, pa
 			&i.ProviderStatus,
 			&i.ProviderAmountCents,
 			&i.ReconciledAt,
+			&i.DisputeID,
+			&i.DisputeState,
+			&i.DisputedCents,
+			&i.DisputeReason,
+			&i.DisputeUpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -479,7 +841,7 @@ func (q *Queries) GetDonationPaymentsByMemberPaypalEmail(ctx context.Context, pa
 }
 
 const getDonationPlanById = `-- name: GetDonationPlanById :one
-SELECT id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id
+SELECT id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
 FROM donation_plan
 WHERE id = $1
 `
@@ -498,12 +860,53 @@ func (q *Queries) GetDonationPlanById(ctx context.Context, id uuid.UUID) (Donati
 		&i.Created,
 		&i.Updated,
 		&i.FundID,
+		&i.ProviderName,
 	)
 	return i, err
 }
 
+const getDonationPlanByProviderPlanId = `-- name: GetDonationPlanByProviderPlanId :many
+SELECT id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
+FROM donation_plan
+WHERE paypal_plan_id = $1
+`
+
+// The plan a subscription reports paying into, by the provider's id for it. :many
+// so a plan that is not ours is no rows rather than ErrNoRows.
+func (q *Queries) GetDonationPlanByProviderPlanId(ctx context.Context, paypalPlanID pgtype.Text) ([]DonationPlan, error) {
+	rows, err := q.db.Query(ctx, getDonationPlanByProviderPlanId, paypalPlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DonationPlan
+	for rows.Next() {
+		var i DonationPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.PaypalPlanID,
+			&i.AmountCents,
+			&i.IntervalUnit,
+			&i.IntervalCount,
+			&i.Active,
+			&i.Created,
+			&i.Updated,
+			&i.FundID,
+			&i.ProviderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getDonationPlans = `-- name: GetDonationPlans :many
-SELECT id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id
+SELECT id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
 FROM donation_plan
 ORDER BY created
 `
@@ -528,6 +931,7 @@ func (q *Queries) GetDonationPlans(ctx context.Context) ([]DonationPlan, error)
 			&i.Created,
 			&i.Updated,
 			&i.FundID,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -540,7 +944,7 @@ func (q *Queries) GetDonationPlans(ctx context.Context) ([]DonationPlan, error)
 }
 
 const getDonationsByDonorId = `-- name: GetDonationsByDonorId :many
-SELECT id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+SELECT id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 FROM donation
 WHERE donor_id = $1
 `
@@ -566,6 +970,7 @@ func (q *Queries) GetDonationsByDonorId(ctx context.Context, donorID uuid.UUID)
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -578,7 +983,7 @@ func (q *Queries) GetDonationsByDonorId(ctx context.Context, donorID uuid.UUID)
 }
 
 const getDonationsByMemberPaypalEmail = `-- name: GetDonationsByMemberPaypalEmail :many
-SELECT donation.id, donation.recurring, donation.donor_id, donation.donation_plan_id, donation.provider_order_id, donation.created, donation.updated, donation.fund_id, donation.active, donation.provider_subscription_id, donation.inactive_reason
+SELECT donation.id, donation.recurring, donation.donor_id, donation.donation_plan_id, donation.provider_order_id, donation.created, donation.updated, donation.fund_id, donation.active, donation.provider_subscription_id, donation.inactive_reason, donation.provider_name
 FROM donation
          JOIN member ON member.id = donation.donor_id
 WHERE member.paypal_email = $1
@@ -605,6 +1010,7 @@ func (q *Queries) GetDonationsByMemberPaypalEmail(ctx context.Context, paypalEma
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -629,13 +1035,14 @@ SELECT d.id,
        COALESCE(SUM(dp.amount_cents - dp.refunded_cents), 0)::bigint AS total_given_cents,
        MAX(dp.created)::timestamptz                                  AS last_payment_at,
        p.amount_cents                                                AS plan_amount_cents,
-       p.interval_unit                                               AS plan_interval_unit
+       p.interval_unit                                               AS plan_interval_unit,
+       p.interval_count                                              AS plan_interval_count
 FROM donation d
          JOIN fund f ON f.id = d.fund_id
          LEFT JOIN donation_payment dp ON dp.donation_id = d.id
          LEFT JOIN donation_plan p ON p.id = d.donation_plan_id
 WHERE d.donor_id = $1
-GROUP BY d.id, f.name, f.active, p.amount_cents, p.interval_unit
+GROUP BY d.id, f.name, f.active, p.amount_cents, p.interval_unit, p.interval_count
 ORDER BY d.active DESC, d.created DESC
 `
 
@@ -653,6 +1060,7 @@ type GetDonationsForDonorRow struct {
 	LastPaymentAt          pgtype.Timestamptz
 	PlanAmountCents        pgtype.Int4
 	PlanIntervalUnit       NullIntervalUnit
+	PlanIntervalCount      pgtype.Int4
 }
 
 // What a donor sees on their own donations page: one row per donation, with the
@@ -684,6 +1092,7 @@ func (q *Queries) GetDonationsForDonor(ctx context.Context, donorID uuid.UUID) (
 			&i.LastPaymentAt,
 			&i.PlanAmountCents,
 			&i.PlanIntervalUnit,
+			&i.PlanIntervalCount,
 		); err != nil {
 			return nil, err
 		}
@@ -762,7 +1171,7 @@ WITH FundStats AS (SELECT fund_id,
                             JOIN member m ON donation.donor_id = m.id
                             LEFT JOIN donation_payment dp ON donation.id = dp.donation_id
                    GROUP BY fund_id)
-SELECT f.id, f.name, f.description, f.provider_id, f.provider_name, f.goal_cents, f.payout_frequency, f.active, f.principal, f.expires, f.next_payment, f.created, f.updated, f.enrollees_visible,
+SELECT f.id, f.name, f.description, f.provider_id, f.provider_name, f.goal_cents, f.payout_frequency, f.active, f.principal, f.expires, f.next_payment, f.created, f.updated, f.enrollees_visible, f.payout_allocation, f.payout_cap_cents, f.second_approval_above_cents, f.close_on_goal,
        fs.total_donated,
        fs.total_donations,
        fs.average_donation,
@@ -773,24 +1182,28 @@ WHERE f.id = $1
 `
 
 type GetFundByIdRow struct {
-	ID               uuid.UUID
-	Name             string
-	Description      string
-	ProviderID       string
-	ProviderName     string
-	GoalCents        pgtype.Int4
-	PayoutFrequency  PayoutFrequency
-	Active           bool
-	Principal        uuid.NullUUID
-	Expires          NullDBTime
-	NextPayment      DBTime
-	Created          pgtype.Timestamptz
-	Updated          pgtype.Timestamptz
-	EnrolleesVisible bool
-	TotalDonated     pgtype.Int4
-	TotalDonations   pgtype.Int8
-	AverageDonation  pgtype.Int4
-	TotalDonors      pgtype.Int8
+	ID                       uuid.UUID
+	Name                     string
+	Description              string
+	ProviderID               string
+	ProviderName             string
+	GoalCents                pgtype.Int4
+	PayoutFrequency          PayoutFrequency
+	Active                   bool
+	Principal                uuid.NullUUID
+	Expires                  NullDBTime
+	NextPayment              DBTime
+	Created                  pgtype.Timestamptz
+	Updated                  pgtype.Timestamptz
+	EnrolleesVisible         bool
+	PayoutAllocation         PayoutAllocation
+	PayoutCapCents           pgtype.Int4
+	SecondApprovalAboveCents pgtype.Int4
+	CloseOnGoal              bool
+	TotalDonated             pgtype.Int4
+	TotalDonations           pgtype.Int8
+	AverageDonation          pgtype.Int4
+	TotalDonors              pgtype.Int8
 }
 
 func (q *Queries) GetFundById(ctx context.Context, id uuid.UUID) (GetFundByIdRow, error) {
@@ -811,6 +1224,10 @@ func (q *Queries) GetFundById(ctx context.Context, id uuid.UUID) (GetFundByIdRow
 		&i.Created,
 		&i.Updated,
 		&i.EnrolleesVisible,
+		&i.PayoutAllocation,
+		&i.PayoutCapCents,
+		&i.SecondApprovalAboveCents,
+		&i.CloseOnGoal,
 		&i.TotalDonated,
 		&i.TotalDonations,
 		&i.AverageDonation,
@@ -1065,6 +1482,9 @@ SELECT dp.id,
        dp.provider_status,
        dp.provider_amount_cents,
        dp.reconciled_at,
+       dp.dispute_state,
+       dp.disputed_cents,
+       dp.dispute_reason,
        dp.created,
        d.recurring,
        m.bco_name AS donor_name
@@ -1085,6 +1505,9 @@ type GetFundPaymentsForAuditRow struct {
 	ProviderStatus      pgtype.Text
 	ProviderAmountCents pgtype.Int4
 	ReconciledAt        NullDBTime
+	DisputeState        NullPaymentDisputeState
+	DisputedCents       int32
+	DisputeReason       pgtype.Text
 	Created             pgtype.Timestamptz
 	Recurring           bool
 	DonorName           pgtype.Text
@@ -1113,6 +1536,9 @@ func (q *Queries) GetFundPaymentsForAudit(ctx context.Context, fundID uuid.UUID)
 			&i.ProviderStatus,
 			&i.ProviderAmountCents,
 			&i.ReconciledAt,
+			&i.DisputeState,
+			&i.DisputedCents,
+			&i.DisputeReason,
 			&i.Created,
 			&i.Recurring,
 			&i.DonorName,
@@ -1187,8 +1613,41 @@ func (q *Queries) GetFundPayoutStats(ctx context.Context, fundID uuid.UUID) (Get
 	return i, err
 }
 
+const getFundProviderId = `-- name: GetFundProviderId :many
+SELECT provider_id
+FROM fund_provider
+WHERE fund_id = $1
+  AND provider_name = $2
+`
+
+type GetFundProviderIdParams struct {
+	FundID       uuid.UUID
+	ProviderName string
+}
+
+// The fund's product at a provider other than its own, if a donor has used one.
+func (q *Queries) GetFundProviderId(ctx context.Context, arg GetFundProviderIdParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, getFundProviderId, arg.FundID, arg.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var provider_id string
+		if err := rows.Scan(&provider_id); err != nil {
+			return nil, err
+		}
+		items = append(items, provider_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFunds = `-- name: GetFunds :many
-SELECT id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible
+SELECT id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible, payout_allocation, payout_cap_cents, second_approval_above_cents, close_on_goal
 FROM fund
 ORDER BY created
 `
@@ -1217,6 +1676,77 @@ func (q *Queries) GetFunds(ctx context.Context) ([]Fund, error) {
 			&i.Created,
 			&i.Updated,
 			&i.EnrolleesVisible,
+			&i.PayoutAllocation,
+			&i.PayoutCapCents,
+			&i.SecondApprovalAboveCents,
+			&i.CloseOnGoal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGoalReachedActiveFunds = `-- name: GetGoalReachedActiveFunds :many
+SELECT f.id, f.name, f.goal_cents, c.collected_cents
+FROM fund f
+         JOIN (SELECT d.fund_id,
+                      SUM(dp.amount_cents
+                          - GREATEST(dp.refunded_cents,
+                                     CASE
+                                         WHEN dp.dispute_state IN ('open', 'lost') THEN dp.disputed_cents
+                                         ELSE 0 END)
+                          - dp.provider_fee_cents)::integer AS collected_cents
+               FROM donation d
+                        JOIN donation_payment dp ON dp.donation_id = d.id
+               GROUP BY d.fund_id) c ON c.fund_id = f.id
+WHERE f.active = true
+  AND f.close_on_goal = true
+  AND f.goal_cents > 0
+  AND c.collected_cents >= f.goal_cents
+  AND (f.payout_frequency <> 'once' OR f.next_payment IS NULL)
+ORDER BY f.name
+`
+
+type GetGoalReachedActiveFundsRow struct {
+	ID             uuid.UUID
+	Name           string
+	GoalCents      pgtype.Int4
+	CollectedCents int32
+}
+
+// Funds set to close at their goal that have collected it. The goal closer walks
+// these and runs the same deactivation as the expiry job.
+//
+// Collected is counted as GetFundBalanceCents counts a donation: less refunds,
+// less money held by an open or lost dispute, and less the provider's fee. A
+// goal reached on gross receipts closed funds that had not got the money --
+// the fees were never in the account, and a chargeback can take the rest back
+// after the fund has stopped taking donations to replace it. The larger of the
+// refund and the dispute, for the reason given there.
+//
+// The same guard as GetExpiredActiveFunds for a one-off fund, for the same
+// reason: closed funds are skipped by the planner, so one closed before its
+// single payout has been planned would strand everything it collected. It
+// closes on the first run after the payout is planned instead.
+func (q *Queries) GetGoalReachedActiveFunds(ctx context.Context) ([]GetGoalReachedActiveFundsRow, error) {
+	rows, err := q.db.Query(ctx, getGoalReachedActiveFunds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGoalReachedActiveFundsRow
+	for rows.Next() {
+		var i GetGoalReachedActiveFundsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.GoalCents,
+			&i.CollectedCents,
 		); err != nil {
 			return nil, err
 		}
@@ -1313,7 +1843,7 @@ func (q *Queries) GetMonthlyTotalsByFund(ctx context.Context, id uuid.UUID) ([]G
 }
 
 const getOneTimeDonationsForFund = `-- name: GetOneTimeDonationsForFund :many
-SELECT d.id, d.recurring, d.donor_id, d.donation_plan_id, d.provider_order_id, d.created, d.updated, d.fund_id, d.active, d.provider_subscription_id, d.inactive_reason
+SELECT d.id, d.recurring, d.donor_id, d.donation_plan_id, d.provider_order_id, d.created, d.updated, d.fund_id, d.active, d.provider_subscription_id, d.inactive_reason, d.provider_name
 FROM donation d
          JOIN fund f ON d.fund_id = f.id
 WHERE d.active = $1
@@ -1347,6 +1877,73 @@ func (q *Queries) GetOneTimeDonationsForFund(ctx context.Context, arg GetOneTime
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOpenUnmatchedPayments = `-- name: GetOpenUnmatchedPayments :many
+SELECT up.id, up.provider_name, up.provider_payment_id, up.provider_order_id, up.amount_cents, up.provider_fee_cents, up.payer_email, up.payer_name, up.fund_id, up.reason, up.occurred_at, up.donation_id, up.assigned_by, up.assigned_at, up.created, f.name AS fund_name
+FROM unmatched_payment up
+         LEFT JOIN fund f ON f.id = up.fund_id
+WHERE up.assigned_at IS NULL
+ORDER BY up.occurred_at DESC
+`
+
+type GetOpenUnmatchedPaymentsRow struct {
+	ID                uuid.UUID
+	ProviderName      string
+	ProviderPaymentID string
+	ProviderOrderID   pgtype.Text
+	AmountCents       int32
+	ProviderFeeCents  int32
+	PayerEmail        pgtype.Text
+	PayerName         pgtype.Text
+	FundID            uuid.NullUUID
+	Reason            string
+	OccurredAt        pgtype.Timestamptz
+	DonationID        uuid.NullUUID
+	AssignedBy        uuid.NullUUID
+	AssignedAt        pgtype.Timestamptz
+	Created           pgtype.Timestamptz
+	FundName          pgtype.Text
+}
+
+// The fund's name is joined for the page, which is about what to do with the
+// money and where it may have been meant to go.
+func (q *Queries) GetOpenUnmatchedPayments(ctx context.Context) ([]GetOpenUnmatchedPaymentsRow, error) {
+	rows, err := q.db.Query(ctx, getOpenUnmatchedPayments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOpenUnmatchedPaymentsRow
+	for rows.Next() {
+		var i GetOpenUnmatchedPaymentsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProviderName,
+			&i.ProviderPaymentID,
+			&i.ProviderOrderID,
+			&i.AmountCents,
+			&i.ProviderFeeCents,
+			&i.PayerEmail,
+			&i.PayerName,
+			&i.FundID,
+			&i.Reason,
+			&i.OccurredAt,
+			&i.DonationID,
+			&i.AssignedBy,
+			&i.AssignedAt,
+			&i.Created,
+			&i.FundName,
 		); err != nil {
 			return nil, err
 		}
@@ -1359,7 +1956,7 @@ func (q *Queries) GetOneTimeDonationsForFund(ctx context.Context, arg GetOneTime
 }
 
 const getPaymentsForDonation = `-- name: GetPaymentsForDonation :many
-SELECT dp.id, dp.donation_id, dp.paypal_payment_id, dp.amount_cents, dp.created, dp.updated, dp.provider_fee_cents, dp.refunded_cents, dp.provider_status, dp.provider_amount_cents, dp.reconciled_at
+SELECT dp.id, dp.donation_id, dp.paypal_payment_id, dp.amount_cents, dp.created, dp.updated, dp.provider_fee_cents, dp.refunded_cents, dp.provider_status, dp.provider_amount_cents, dp.reconciled_at, dp.dispute_id, dp.dispute_state, dp.disputed_cents, dp.dispute_reason, dp.dispute_updated_at
 FROM donation_payment dp
 WHERE dp.donation_id = $1
 `
@@ -1385,6 +1982,11 @@ func (q *Queries) GetPaymentsForDonation(ctx context.Context, donationID uuid.UU
 			&i.ProviderStatus,
 			&i.ProviderAmountCents,
 			&i.ReconciledAt,
+			&i.DisputeID,
+			&i.DisputeState,
+			&i.DisputedCents,
+			&i.DisputeReason,
+			&i.DisputeUpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1397,7 +1999,7 @@ func (q *Queries) GetPaymentsForDonation(ctx context.Context, donationID uuid.UU
 }
 
 const getRecurringDonationsForFund = `-- name: GetRecurringDonationsForFund :many
-SELECT d.id, d.recurring, d.donor_id, d.donation_plan_id, d.provider_order_id, d.created, d.updated, d.fund_id, d.active, d.provider_subscription_id, d.inactive_reason
+SELECT d.id, d.recurring, d.donor_id, d.donation_plan_id, d.provider_order_id, d.created, d.updated, d.fund_id, d.active, d.provider_subscription_id, d.inactive_reason, d.provider_name
 FROM donation d
          JOIN fund f ON d.fund_id = f.id
 WHERE d.active = $1
@@ -1431,6 +2033,53 @@ func (q *Queries) GetRecurringDonationsForFund(ctx context.Context, arg GetRecur
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSuspendedDonationBySubscriptionId = `-- name: GetSuspendedDonationBySubscriptionId :many
+SELECT donation.id, donation.recurring, donation.donor_id, donation.donation_plan_id, donation.provider_order_id, donation.created, donation.updated, donation.fund_id, donation.active, donation.provider_subscription_id, donation.inactive_reason, donation.provider_name
+FROM donation
+         JOIN fund ON fund.id = donation.fund_id
+WHERE donation.provider_subscription_id = $1
+  AND donation.active = false
+  AND donation.inactive_reason = 'SUSPENDED'
+  AND fund.active = true
+`
+
+// The donation ReactivateSuspendedDonationBySubscriptionId would bring back,
+// read without bringing it back. The same predicate, so a dry run of a webhook
+// replay predicts what the live one does.
+func (q *Queries) GetSuspendedDonationBySubscriptionId(ctx context.Context, providerSubscriptionID pgtype.Text) ([]Donation, error) {
+	rows, err := q.db.Query(ctx, getSuspendedDonationBySubscriptionId, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Donation
+	for rows.Next() {
+		var i Donation
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recurring,
+			&i.DonorID,
+			&i.DonationPlanID,
+			&i.ProviderOrderID,
+			&i.Created,
+			&i.Updated,
+			&i.FundID,
+			&i.Active,
+			&i.ProviderSubscriptionID,
+			&i.InactiveReason,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -1470,10 +2119,61 @@ func (q *Queries) GetTotalDonatedByMember(ctx context.Context, donorID uuid.UUID
 	return sum, err
 }
 
+const getUnmatchedPaymentById = `-- name: GetUnmatchedPaymentById :one
+SELECT up.id, up.provider_name, up.provider_payment_id, up.provider_order_id, up.amount_cents, up.provider_fee_cents, up.payer_email, up.payer_name, up.fund_id, up.reason, up.occurred_at, up.donation_id, up.assigned_by, up.assigned_at, up.created, f.name AS fund_name
+FROM unmatched_payment up
+         LEFT JOIN fund f ON f.id = up.fund_id
+WHERE up.id = $1
+`
+
+type GetUnmatchedPaymentByIdRow struct {
+	ID                uuid.UUID
+	ProviderName      string
+	ProviderPaymentID string
+	ProviderOrderID   pgtype.Text
+	AmountCents       int32
+	ProviderFeeCents  int32
+	PayerEmail        pgtype.Text
+	PayerName         pgtype.Text
+	FundID            uuid.NullUUID
+	Reason            string
+	OccurredAt        pgtype.Timestamptz
+	DonationID        uuid.NullUUID
+	AssignedBy        uuid.NullUUID
+	AssignedAt        pgtype.Timestamptz
+	Created           pgtype.Timestamptz
+	FundName          pgtype.Text
+}
+
+func (q *Queries) GetUnmatchedPaymentById(ctx context.Context, id uuid.UUID) (GetUnmatchedPaymentByIdRow, error) {
+	row := q.db.QueryRow(ctx, getUnmatchedPaymentById, id)
+	var i GetUnmatchedPaymentByIdRow
+	err := row.Scan(
+		&i.ID,
+		&i.ProviderName,
+		&i.ProviderPaymentID,
+		&i.ProviderOrderID,
+		&i.AmountCents,
+		&i.ProviderFeeCents,
+		&i.PayerEmail,
+		&i.PayerName,
+		&i.FundID,
+		&i.Reason,
+		&i.OccurredAt,
+		&i.DonationID,
+		&i.AssignedBy,
+		&i.AssignedAt,
+		&i.Created,
+		&i.FundName,
+	)
+	return i, err
+}
+
 const insertDonation = `-- name: InsertDonation :one
-INSERT INTO donation (id, donor_id, fund_id, recurring, donation_plan_id, provider_order_id, provider_subscription_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+INSERT INTO donation (id, donor_id, fund_id, recurring, donation_plan_id, provider_order_id, provider_subscription_id,
+                      provider_name)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 type InsertDonationParams struct {
@@ -1484,6 +2184,7 @@ type InsertDonationParams struct {
 	DonationPlanID         uuid.NullUUID
 	ProviderOrderID        string
 	ProviderSubscriptionID pgtype.Text
+	ProviderName           string
 }
 
 func (q *Queries) InsertDonation(ctx context.Context, arg InsertDonationParams) (Donation, error) {
@@ -1495,6 +2196,7 @@ func (q *Queries) InsertDonation(ctx context.Context, arg InsertDonationParams)
 		arg.DonationPlanID,
 		arg.ProviderOrderID,
 		arg.ProviderSubscriptionID,
+		arg.ProviderName,
 	)
 	var i Donation
 	err := row.Scan(
@@ -1509,6 +2211,7 @@ func (q *Queries) InsertDonation(ctx context.Context, arg InsertDonationParams)
 		&i.Active,
 		&i.ProviderSubscriptionID,
 		&i.InactiveReason,
+		&i.ProviderName,
 	)
 	return i, err
 }
@@ -1517,7 +2220,7 @@ const insertDonationPayment = `-- name: InsertDonationPayment :many
 INSERT INTO donation_payment (id, donation_id, paypal_payment_id, amount_cents, provider_fee_cents)
 VALUES ($1, $2, $3, $4, $5)
 ON CONFLICT (paypal_payment_id) DO NOTHING
-RETURNING id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at
+RETURNING id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at, dispute_id, dispute_state, disputed_cents, dispute_reason, dispute_updated_at
 `
 
 type InsertDonationPaymentParams struct {
@@ -1563,6 +2266,11 @@ func (q *Queries) InsertDonationPayment(ctx context.Context, arg InsertDonationP
 			&i.ProviderStatus,
 			&i.ProviderAmountCents,
 			&i.ReconciledAt,
+			&i.DisputeID,
+			&i.DisputeState,
+			&i.DisputedCents,
+			&i.DisputeReason,
+			&i.DisputeUpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1577,7 +2285,7 @@ func (q *Queries) InsertDonationPayment(ctx context.Context, arg InsertDonationP
 const insertDonationPlan = `-- name: InsertDonationPlan :one
 INSERT INTO donation_plan (id, name, amount_cents, interval_unit, interval_count, active, paypal_plan_id, fund_id)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id
+RETURNING id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
 `
 
 type InsertDonationPlanParams struct {
@@ -1614,14 +2322,15 @@ func (q *Queries) InsertDonationPlan(ctx context.Context, arg InsertDonationPlan
 		&i.Created,
 		&i.Updated,
 		&i.FundID,
+		&i.ProviderName,
 	)
 	return i, err
 }
 
 const insertFund = `-- name: InsertFund :one
 INSERT INTO fund (id, name, description, provider_id, provider_name, active, payout_frequency, goal_cents, expires,
-                  principal, enrollees_visible, next_payment)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+                  principal, enrollees_visible, close_on_goal, next_payment)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
         -- Anchored to midnight UTC rather than the moment of creation.
         --
         -- A fund becomes due on the first cron run after its anchor, and the
@@ -1638,8 +2347,14 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
                  THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '1 month') AT TIME ZONE 'UTC'
              WHEN $7::payout_frequency = 'daily'
                  THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '1 day') AT TIME ZONE 'UTC'
+             WHEN $7::payout_frequency = 'weekly'
+                 THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '7 days') AT TIME ZONE 'UTC'
+             WHEN $7::payout_frequency = 'biweekly'
+                 THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '14 days') AT TIME ZONE 'UTC'
+             WHEN $7::payout_frequency = 'quarterly'
+                 THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '3 months') AT TIME ZONE 'UTC'
              ELSE $9::timestamptz END))
-RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible
+RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible, payout_allocation, payout_cap_cents, second_approval_above_cents, close_on_goal
 `
 
 type InsertFundParams struct {
@@ -1654,6 +2369,7 @@ type InsertFundParams struct {
 	Expires          NullDBTime
 	Principal        uuid.NullUUID
 	EnrolleesVisible bool
+	CloseOnGoal      bool
 }
 
 func (q *Queries) InsertFund(ctx context.Context, arg InsertFundParams) (Fund, error) {
@@ -1669,6 +2385,7 @@ func (q *Queries) InsertFund(ctx context.Context, arg InsertFundParams) (Fund, e
 		arg.Expires,
 		arg.Principal,
 		arg.EnrolleesVisible,
+		arg.CloseOnGoal,
 	)
 	var i Fund
 	err := row.Scan(
@@ -1686,10 +2403,119 @@ func (q *Queries) InsertFund(ctx context.Context, arg InsertFundParams) (Fund, e
 		&i.Created,
 		&i.Updated,
 		&i.EnrolleesVisible,
+		&i.PayoutAllocation,
+		&i.PayoutCapCents,
+		&i.SecondApprovalAboveCents,
+		&i.CloseOnGoal,
 	)
 	return i, err
 }
 
+const insertFundProvider = `-- name: InsertFundProvider :many
+INSERT INTO fund_provider (fund_id, provider_name, provider_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (fund_id, provider_name) DO NOTHING
+RETURNING provider_id
+`
+
+type InsertFundProviderParams struct {
+	FundID       uuid.UUID
+	ProviderName string
+	ProviderID   string
+}
+
+// Returns nothing when another request recorded the fund at this provider first;
+// the caller reads theirs back and leaves its own product unused.
+func (q *Queries) InsertFundProvider(ctx context.Context, arg InsertFundProviderParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, insertFundProvider, arg.FundID, arg.ProviderName, arg.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var provider_id string
+		if err := rows.Scan(&provider_id); err != nil {
+			return nil, err
+		}
+		items = append(items, provider_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertUnmatchedPayment = `-- name: InsertUnmatchedPayment :many
+INSERT INTO unmatched_payment (id, provider_name, provider_payment_id, provider_order_id, amount_cents,
+                               provider_fee_cents, payer_email, payer_name, fund_id, reason, occurred_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (provider_name, provider_payment_id) DO NOTHING
+RETURNING id, provider_name, provider_payment_id, provider_order_id, amount_cents, provider_fee_cents, payer_email, payer_name, fund_id, reason, occurred_at, donation_id, assigned_by, assigned_at, created
+`
+
+type InsertUnmatchedPaymentParams struct {
+	ID                uuid.UUID
+	ProviderName      string
+	ProviderPaymentID string
+	ProviderOrderID   pgtype.Text
+	AmountCents       int32
+	ProviderFeeCents  int32
+	PayerEmail        pgtype.Text
+	PayerName         pgtype.Text
+	FundID            uuid.NullUUID
+	Reason            string
+	OccurredAt        pgtype.Timestamptz
+}
+
+func (q *Queries) InsertUnmatchedPayment(ctx context.Context, arg InsertUnmatchedPaymentParams) ([]UnmatchedPayment, error) {
+	rows, err := q.db.Query(ctx, insertUnmatchedPayment,
+		arg.ID,
+		arg.ProviderName,
+		arg.ProviderPaymentID,
+		arg.ProviderOrderID,
+		arg.AmountCents,
+		arg.ProviderFeeCents,
+		arg.PayerEmail,
+		arg.PayerName,
+		arg.FundID,
+		arg.Reason,
+		arg.OccurredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UnmatchedPayment
+	for rows.Next() {
+		var i UnmatchedPayment
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProviderName,
+			&i.ProviderPaymentID,
+			&i.ProviderOrderID,
+			&i.AmountCents,
+			&i.ProviderFeeCents,
+			&i.PayerEmail,
+			&i.PayerName,
+			&i.FundID,
+			&i.Reason,
+			&i.OccurredAt,
+			&i.DonationID,
+			&i.AssignedBy,
+			&i.AssignedAt,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const memberHasGivenToFund = `-- name: MemberHasGivenToFund :one
 SELECT EXISTS (SELECT 1
                FROM donation d
@@ -1739,7 +2565,7 @@ WHERE donation.fund_id = fund.id
   AND donation.active = false
   AND donation.inactive_reason = 'SUSPENDED'
   AND fund.active = true
-RETURNING donation.id, donation.recurring, donation.donor_id, donation.donation_plan_id, donation.provider_order_id, donation.created, donation.updated, donation.fund_id, donation.active, donation.provider_subscription_id, donation.inactive_reason
+RETURNING donation.id, donation.recurring, donation.donor_id, donation.donation_plan_id, donation.provider_order_id, donation.created, donation.updated, donation.fund_id, donation.active, donation.provider_subscription_id, donation.inactive_reason, donation.provider_name
 `
 
 // Brings back a donation that suspension deactivated, and only that.
@@ -1772,6 +2598,7 @@ func (q *Queries) ReactivateSuspendedDonationBySubscriptionId(ctx context.Contex
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -1854,6 +2681,146 @@ func (q *Queries) RemoveOwnFundNote(ctx context.Context, arg RemoveOwnFundNotePa
 	return i, err
 }
 
+const reuseDonationPlan = `-- name: ReuseDonationPlan :one
+UPDATE donation_plan
+SET updated = now()
+WHERE fund_id = $1
+  AND amount_cents = $2
+  AND interval_unit = $3
+  AND interval_count = $4
+  AND provider_name = $5
+  AND active = true
+RETURNING id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
+`
+
+type ReuseDonationPlanParams struct {
+	FundID        uuid.UUID
+	AmountCents   int32
+	IntervalUnit  IntervalUnit
+	IntervalCount int32
+	ProviderName  string
+}
+
+// The fund's plan for this amount and billing cycle, if it has a live one.
+//
+// Touching updated is the point of doing this as an UPDATE: a plan is abandoned
+// when nobody has subscribed to it and nobody has asked for it lately, and a
+// donor who has just been handed this plan may be in PayPal's popup with it now.
+// Pruning measures its grace period from here.
+func (q *Queries) ReuseDonationPlan(ctx context.Context, arg ReuseDonationPlanParams) (DonationPlan, error) {
+	row := q.db.QueryRow(ctx, reuseDonationPlan,
+		arg.FundID,
+		arg.AmountCents,
+		arg.IntervalUnit,
+		arg.IntervalCount,
+		arg.ProviderName,
+	)
+	var i DonationPlan
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.PaypalPlanID,
+		&i.AmountCents,
+		&i.IntervalUnit,
+		&i.IntervalCount,
+		&i.Active,
+		&i.Created,
+		&i.Updated,
+		&i.FundID,
+		&i.ProviderName,
+	)
+	return i, err
+}
+
+const setDonationPaymentDispute = `-- name: SetDonationPaymentDispute :many
+WITH previous AS (SELECT prev.id, prev.dispute_state
+                  FROM donation_payment prev
+                  WHERE prev.paypal_payment_id = $6)
+UPDATE donation_payment dp
+SET dispute_id         = $1,
+    dispute_state      = $2::payment_dispute_state,
+    disputed_cents     = LEAST($3::int, dp.amount_cents),
+    dispute_reason     = $4::text,
+    dispute_updated_at = $5,
+    updated            = now()
+FROM donation d, previous p
+WHERE dp.id = p.id
+  AND dp.donation_id = d.id
+  AND (dp.dispute_updated_at IS NULL OR dp.dispute_updated_at < $5)
+RETURNING dp.id AS payment_id, d.id AS donation_id, d.fund_id, d.donor_id,
+    dp.amount_cents, dp.dispute_id, dp.dispute_state, dp.disputed_cents,
+    p.dispute_state AS previous_dispute_state
+`
+
+type SetDonationPaymentDisputeParams struct {
+	DisputeID       pgtype.Text
+	DisputeState    PaymentDisputeState
+	DisputedCents   int32
+	DisputeReason   pgtype.Text
+	UpdateTime      pgtype.Timestamptz
+	PaypalPaymentID string
+}
+
+type SetDonationPaymentDisputeRow struct {
+	PaymentID            uuid.UUID
+	DonationID           uuid.UUID
+	FundID               uuid.UUID
+	DonorID              uuid.UUID
+	AmountCents          int32
+	DisputeID            pgtype.Text
+	DisputeState         NullPaymentDisputeState
+	DisputedCents        int32
+	PreviousDisputeState NullPaymentDisputeState
+}
+
+// Records where a dispute against a payment stands.
+//
+// The three dispute webhooks carry the whole dispute each time, so this sets
+// rather than steps: whichever arrives, the row ends up as the provider last
+// described it. What keeps them in order is the provider's update_time. A
+// redelivery carries the same one and matches nothing, and a CREATED arriving
+// after its RESOLVED carries an older one and cannot reopen what was settled.
+//
+// The state before the update is returned beside the new one, as with refunds,
+// so the caller can tell a dispute opening or closing from one merely updated --
+// only the first two belong in the feed.
+func (q *Queries) SetDonationPaymentDispute(ctx context.Context, arg SetDonationPaymentDisputeParams) ([]SetDonationPaymentDisputeRow, error) {
+	rows, err := q.db.Query(ctx, setDonationPaymentDispute,
+		arg.DisputeID,
+		arg.DisputeState,
+		arg.DisputedCents,
+		arg.DisputeReason,
+		arg.UpdateTime,
+		arg.PaypalPaymentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SetDonationPaymentDisputeRow
+	for rows.Next() {
+		var i SetDonationPaymentDisputeRow
+		if err := rows.Scan(
+			&i.PaymentID,
+			&i.DonationID,
+			&i.FundID,
+			&i.DonorID,
+			&i.AmountCents,
+			&i.DisputeID,
+			&i.DisputeState,
+			&i.DisputedCents,
+			&i.PreviousDisputeState,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const setDonationPaymentRefunded = `-- name: SetDonationPaymentRefunded :many
 WITH previous AS (SELECT prev.id, prev.refunded_cents
                   FROM donation_payment prev
@@ -1927,12 +2894,91 @@ func (q *Queries) SetDonationPaymentRefunded(ctx context.Context, arg SetDonatio
 	return items, nil
 }
 
+const setDonationPlanForDonation = `-- name: SetDonationPlanForDonation :many
+UPDATE donation
+SET donation_plan_id = $2,
+    updated          = now()
+WHERE id = $1
+  AND donation_plan_id IS DISTINCT FROM $2
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
+`
+
+type SetDonationPlanForDonationParams struct {
+	ID             uuid.UUID
+	DonationPlanID uuid.NullUUID
+}
+
+// Moves a donation onto another plan of its fund, once the provider has moved the
+// subscription. A compare-and-set: the donor coming back from PayPal and the
+// provider's webhook both apply the same change, and whichever is second finds
+// the donation already on the plan and returns no row, so the feed records the
+// change once.
+func (q *Queries) SetDonationPlanForDonation(ctx context.Context, arg SetDonationPlanForDonationParams) ([]Donation, error) {
+	rows, err := q.db.Query(ctx, setDonationPlanForDonation, arg.ID, arg.DonationPlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Donation
+	for rows.Next() {
+		var i Donation
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recurring,
+			&i.DonorID,
+			&i.DonationPlanID,
+			&i.ProviderOrderID,
+			&i.Created,
+			&i.Updated,
+			&i.FundID,
+			&i.Active,
+			&i.ProviderSubscriptionID,
+			&i.InactiveReason,
+			&i.ProviderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setDonationPlanInactive = `-- name: SetDonationPlanInactive :one
+UPDATE donation_plan
+SET active  = false,
+    updated = now()
+WHERE id = $1
+RETURNING id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
+`
+
+func (q *Queries) SetDonationPlanInactive(ctx context.Context, id uuid.UUID) (DonationPlan, error) {
+	row := q.db.QueryRow(ctx, setDonationPlanInactive, id)
+	var i DonationPlan
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.PaypalPlanID,
+		&i.AmountCents,
+		&i.IntervalUnit,
+		&i.IntervalCount,
+		&i.Active,
+		&i.Created,
+		&i.Updated,
+		&i.FundID,
+		&i.ProviderName,
+	)
+	return i, err
+}
+
 const setDonationToInactive = `-- name: SetDonationToInactive :one
 UPDATE donation
 SET active          = false,
     inactive_reason = $2
 WHERE id = $1
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 type SetDonationToInactiveParams struct {
@@ -1955,6 +3001,7 @@ func (q *Queries) SetDonationToInactive(ctx context.Context, arg SetDonationToIn
 		&i.Active,
 		&i.ProviderSubscriptionID,
 		&i.InactiveReason,
+		&i.ProviderName,
 	)
 	return i, err
 }
@@ -1964,7 +3011,7 @@ UPDATE donation
 SET active          = false,
     inactive_reason = $2
 WHERE provider_subscription_id = $1
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 type SetDonationToInactiveBySubscriptionIdParams struct {
@@ -1987,6 +3034,7 @@ func (q *Queries) SetDonationToInactiveBySubscriptionId(ctx context.Context, arg
 		&i.Active,
 		&i.ProviderSubscriptionID,
 		&i.InactiveReason,
+		&i.ProviderName,
 	)
 	return i, err
 }
@@ -1995,7 +3043,7 @@ const setDonationsToActive = `-- name: SetDonationsToActive :many
 UPDATE donation
 SET active = true
 WHERE id = ANY ($1::uuid[])
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 func (q *Queries) SetDonationsToActive(ctx context.Context, ids []uuid.UUID) ([]Donation, error) {
@@ -2019,6 +3067,7 @@ func (q *Queries) SetDonationsToActive(ctx context.Context, ids []uuid.UUID) ([]
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -2035,7 +3084,7 @@ UPDATE donation
 SET active = true
 WHERE fund_id = $1
   AND active = false
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 func (q *Queries) SetDonationsToActiveByFundId(ctx context.Context, fundID uuid.UUID) ([]Donation, error) {
@@ -2059,6 +3108,7 @@ func (q *Queries) SetDonationsToActiveByFundId(ctx context.Context, fundID uuid.
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -2074,7 +3124,7 @@ const setDonationsToActiveBySubscriptionId = `-- name: SetDonationsToActiveBySub
 UPDATE donation
 SET active = true
 WHERE provider_subscription_id = $1
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 func (q *Queries) SetDonationsToActiveBySubscriptionId(ctx context.Context, providerSubscriptionID pgtype.Text) (Donation, error) {
@@ -2092,6 +3142,7 @@ func (q *Queries) SetDonationsToActiveBySubscriptionId(ctx context.Context, prov
 		&i.Active,
 		&i.ProviderSubscriptionID,
 		&i.InactiveReason,
+		&i.ProviderName,
 	)
 	return i, err
 }
@@ -2101,7 +3152,7 @@ UPDATE donation
 SET active = false
 WHERE donor_id = $1
   AND active = true
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 func (q *Queries) SetDonationsToInactiveByDonorId(ctx context.Context, donorID uuid.UUID) ([]Donation, error) {
@@ -2125,6 +3176,7 @@ func (q *Queries) SetDonationsToInactiveByDonorId(ctx context.Context, donorID u
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -2141,7 +3193,7 @@ UPDATE donation
 SET active = false
 WHERE fund_id = $1
   AND active = true
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 func (q *Queries) SetDonationsToInactiveByFundId(ctx context.Context, fundID uuid.UUID) ([]Donation, error) {
@@ -2165,6 +3217,7 @@ func (q *Queries) SetDonationsToInactiveByFundId(ctx context.Context, fundID uui
 			&i.Active,
 			&i.ProviderSubscriptionID,
 			&i.InactiveReason,
+			&i.ProviderName,
 		); err != nil {
 			return nil, err
 		}
@@ -2180,7 +3233,7 @@ const setFundToActive = `-- name: SetFundToActive :one
 UPDATE fund
 SET active = true
 WHERE id = $1
-RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible
+RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible, payout_allocation, payout_cap_cents, second_approval_above_cents, close_on_goal
 `
 
 func (q *Queries) SetFundToActive(ctx context.Context, id uuid.UUID) (Fund, error) {
@@ -2201,6 +3254,10 @@ func (q *Queries) SetFundToActive(ctx context.Context, id uuid.UUID) (Fund, erro
 		&i.Created,
 		&i.Updated,
 		&i.EnrolleesVisible,
+		&i.PayoutAllocation,
+		&i.PayoutCapCents,
+		&i.SecondApprovalAboveCents,
+		&i.CloseOnGoal,
 	)
 	return i, err
 }
@@ -2209,7 +3266,7 @@ const setFundToInactive = `-- name: SetFundToInactive :one
 UPDATE fund
 SET active = false
 WHERE id = $1
-RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible
+RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible, payout_allocation, payout_cap_cents, second_approval_above_cents, close_on_goal
 `
 
 func (q *Queries) SetFundToInactive(ctx context.Context, id uuid.UUID) (Fund, error) {
@@ -2230,6 +3287,10 @@ func (q *Queries) SetFundToInactive(ctx context.Context, id uuid.UUID) (Fund, er
 		&i.Created,
 		&i.Updated,
 		&i.EnrolleesVisible,
+		&i.PayoutAllocation,
+		&i.PayoutCapCents,
+		&i.SecondApprovalAboveCents,
+		&i.CloseOnGoal,
 	)
 	return i, err
 }
@@ -2242,7 +3303,7 @@ SET provider_status       = $2::text,
     reconciled_at         = now(),
     updated               = now()
 WHERE id = $1
-RETURNING id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at
+RETURNING id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at, dispute_id, dispute_state, disputed_cents, dispute_reason, dispute_updated_at
 `
 
 type SetPaymentReconciliationParams struct {
@@ -2277,6 +3338,52 @@ func (q *Queries) SetPaymentReconciliation(ctx context.Context, arg SetPaymentRe
 		&i.ProviderStatus,
 		&i.ProviderAmountCents,
 		&i.ReconciledAt,
+		&i.DisputeID,
+		&i.DisputeState,
+		&i.DisputedCents,
+		&i.DisputeReason,
+		&i.DisputeUpdatedAt,
+	)
+	return i, err
+}
+
+const setUnmatchedPaymentAssigned = `-- name: SetUnmatchedPaymentAssigned :one
+UPDATE unmatched_payment
+SET donation_id = $2,
+    assigned_by = $3,
+    assigned_at = now()
+WHERE id = $1
+  AND assigned_at IS NULL
+RETURNING id, provider_name, provider_payment_id, provider_order_id, amount_cents, provider_fee_cents, payer_email, payer_name, fund_id, reason, occurred_at, donation_id, assigned_by, assigned_at, created
+`
+
+type SetUnmatchedPaymentAssignedParams struct {
+	ID         uuid.UUID
+	DonationID uuid.NullUUID
+	AssignedBy uuid.NullUUID
+}
+
+// Guarded on assigned_at, so two admins assigning the same payment at once
+// cannot both record it.
+func (q *Queries) SetUnmatchedPaymentAssigned(ctx context.Context, arg SetUnmatchedPaymentAssignedParams) (UnmatchedPayment, error) {
+	row := q.db.QueryRow(ctx, setUnmatchedPaymentAssigned, arg.ID, arg.DonationID, arg.AssignedBy)
+	var i UnmatchedPayment
+	err := row.Scan(
+		&i.ID,
+		&i.ProviderName,
+		&i.ProviderPaymentID,
+		&i.ProviderOrderID,
+		&i.AmountCents,
+		&i.ProviderFeeCents,
+		&i.PayerEmail,
+		&i.PayerName,
+		&i.FundID,
+		&i.Reason,
+		&i.OccurredAt,
+		&i.DonationID,
+		&i.AssignedBy,
+		&i.AssignedAt,
+		&i.Created,
 	)
 	return i, err
 }
@@ -2285,7 +3392,7 @@ const updateDonation = `-- name: UpdateDonation :one
 UPDATE donation
 SET (donor_id, donation_plan_id, provider_order_id, updated) = ($2, $3, $4, now())
 WHERE id = $1
-RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason
+RETURNING id, recurring, donor_id, donation_plan_id, provider_order_id, created, updated, fund_id, active, provider_subscription_id, inactive_reason, provider_name
 `
 
 type UpdateDonationParams struct {
@@ -2315,6 +3422,7 @@ func (q *Queries) UpdateDonation(ctx context.Context, arg UpdateDonationParams)
 		&i.Active,
 		&i.ProviderSubscriptionID,
 		&i.InactiveReason,
+		&i.ProviderName,
 	)
 	return i, err
 }
@@ -2323,7 +3431,7 @@ const updateDonationPaymentPaypalFee = `-- name: UpdateDonationPaymentPaypalFee
 UPDATE donation_payment
 SET provider_fee_cents = $2
 WHERE id = $1
-RETURNING id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at
+RETURNING id, donation_id, paypal_payment_id, amount_cents, created, updated, provider_fee_cents, refunded_cents, provider_status, provider_amount_cents, reconciled_at, dispute_id, dispute_state, disputed_cents, dispute_reason, dispute_updated_at
 `
 
 type UpdateDonationPaymentPaypalFeeParams struct {
@@ -2346,6 +3454,11 @@ func (q *Queries) UpdateDonationPaymentPaypalFee(ctx context.Context, arg Update
 		&i.ProviderStatus,
 		&i.ProviderAmountCents,
 		&i.ReconciledAt,
+		&i.DisputeID,
+		&i.DisputeState,
+		&i.DisputedCents,
+		&i.DisputeReason,
+		&i.DisputeUpdatedAt,
 	)
 	return i, err
 }
@@ -2355,7 +3468,7 @@ UPDATE donation_plan
 SET (name, amount_cents, interval_unit, interval_count, active, paypal_plan_id, fund_id,
      updated) = ($2, $3, $4, $5, $6, $7, $8, now())
 WHERE id = $1
-RETURNING id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id
+RETURNING id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id, provider_name
 `
 
 type UpdateDonationPlanParams struct {
@@ -2392,6 +3505,7 @@ func (q *Queries) UpdateDonationPlan(ctx context.Context, arg UpdateDonationPlan
 		&i.Created,
 		&i.Updated,
 		&i.FundID,
+		&i.ProviderName,
 	)
 	return i, err
 }
@@ -2399,13 +3513,13 @@ func (q *Queries) UpdateDonationPlan(ctx context.Context, arg UpdateDonationPlan
 const updateFund = `-- name: UpdateFund :one
 UPDATE fund
 SET (name, description, active, payout_frequency, goal_cents, expires, principal,
-     enrollees_visible, updated) = ($2, $3, $4, $5, $6, $7, $8, $9, now()),
+     enrollees_visible, close_on_goal, updated) = ($2, $3, $4, $5, $6, $7, $8, $9, $10, now()),
     next_payment = CASE
                        WHEN $5::payout_frequency = 'once' AND next_payment IS NOT NULL
                            THEN $7::timestamptz
                        ELSE next_payment END
 WHERE id = $1
-RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible
+RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible, payout_allocation, payout_cap_cents, second_approval_above_cents, close_on_goal
 `
 
 type UpdateFundParams struct {
@@ -2418,6 +3532,7 @@ type UpdateFundParams struct {
 	Expires          NullDBTime
 	Principal        uuid.NullUUID
 	EnrolleesVisible bool
+	CloseOnGoal      bool
 }
 
 // next_payment follows expires on a one-off fund, because for that frequency
@@ -2445,6 +3560,7 @@ func (q *Queries) UpdateFund(ctx context.Context, arg UpdateFundParams) (Fund, e
 		arg.Expires,
 		arg.Principal,
 		arg.EnrolleesVisible,
+		arg.CloseOnGoal,
 	)
 	var i Fund
 	err := row.Scan(
@@ -2462,53 +3578,10 @@ func (q *Queries) UpdateFund(ctx context.Context, arg UpdateFundParams) (Fund, e
 		&i.Created,
 		&i.Updated,
 		&i.EnrolleesVisible,
-	)
-	return i, err
-}
-
-const upsertDonationPlan = `-- name: UpsertDonationPlan :one
-INSERT INTO donation_plan (id, name, amount_cents, interval_unit, interval_count, active, paypal_plan_id, fund_id,
-                           updated)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now())
-ON CONFLICT (interval_unit, amount_cents) DO UPDATE
-    SET (name, active, paypal_plan_id, fund_id) = ($2, $6, $7, $8)
-RETURNING id, name, paypal_plan_id, amount_cents, interval_unit, interval_count, active, created, updated, fund_id
-`
-
-type UpsertDonationPlanParams struct {
-	ID            uuid.UUID
-	Name          string
-	AmountCents   int32
-	IntervalUnit  IntervalUnit
-	IntervalCount int32
-	Active        bool
-	PaypalPlanID  pgtype.Text
-	FundID        uuid.UUID
-}
-
-func (q *Queries) UpsertDonationPlan(ctx context.Context, arg UpsertDonationPlanParams) (DonationPlan, error) {
-	row := q.db.QueryRow(ctx, upsertDonationPlan,
-		arg.ID,
-		arg.Name,
-		arg.AmountCents,
-		arg.IntervalUnit,
-		arg.IntervalCount,
-		arg.Active,
-		arg.PaypalPlanID,
-		arg.FundID,
-	)
-	var i DonationPlan
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.PaypalPlanID,
-		&i.AmountCents,
-		&i.IntervalUnit,
-		&i.IntervalCount,
-		&i.Active,
-		&i.Created,
-		&i.Updated,
-		&i.FundID,
+		&i.PayoutAllocation,
+		&i.PayoutCapCents,
+		&i.SecondApprovalAboveCents,
+		&i.CloseOnGoal,
 	)
 	return i, err
 }
@@ -16,7 +16,7 @@ const deactivateEnrollment = `-- name: DeactivateEnrollment :one
 UPDATE fund_enrollment
 SET active = false
 WHERE id = $1
-RETURNING id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email
+RETURNING id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email, payout_amount_cents, payout_weight
 `
 
 func (q *Queries) DeactivateEnrollment(ctx context.Context, id uuid.UUID) (FundEnrollment, error) {
@@ -32,6 +32,8 @@ func (q *Queries) DeactivateEnrollment(ctx context.Context, id uuid.UUID) (FundE
 		&i.Created,
 		&i.Updated,
 		&i.PaypalEmail,
+		&i.PayoutAmountCents,
+		&i.PayoutWeight,
 	)
 	return i, err
 }
@@ -57,7 +59,7 @@ func (q *Queries) FundEnrollmentExists(ctx context.Context, arg FundEnrollmentEx
 }
 
 const getActiveEnrollmentsByFundId = `-- name: GetActiveEnrollmentsByFundId :many
-SELECT id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email
+SELECT id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email, payout_amount_cents, payout_weight
 FROM fund_enrollment
 WHERE fund_id = $1
   AND active = true
@@ -82,6 +84,8 @@ func (q *Queries) GetActiveEnrollmentsByFundId(ctx context.Context, fundID uuid.
 			&i.Created,
 			&i.Updated,
 			&i.PaypalEmail,
+			&i.PayoutAmountCents,
+			&i.PayoutWeight,
 		); err != nil {
 			return nil, err
 		}
@@ -93,8 +97,33 @@ func (q *Queries) GetActiveEnrollmentsByFundId(ctx context.Context, fundID uuid.
 	return items, nil
 }
 
+const getEnrollmentById = `-- name: GetEnrollmentById :one
+SELECT id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email, payout_amount_cents, payout_weight
+FROM fund_enrollment
+WHERE id = $1
+`
+
+func (q *Queries) GetEnrollmentById(ctx context.Context, id uuid.UUID) (FundEnrollment, error) {
+	row := q.db.QueryRow(ctx, getEnrollmentById, id)
+	var i FundEnrollment
+	err := row.Scan(
+		&i.ID,
+		&i.FundID,
+		&i.MemberID,
+		&i.MemberBcoName,
+		&i.FirstPayoutDate,
+		&i.Active,
+		&i.Created,
+		&i.Updated,
+		&i.PaypalEmail,
+		&i.PayoutAmountCents,
+		&i.PayoutWeight,
+	)
+	return i, err
+}
+
 const getEnrollmentForFundByMemberId = `-- name: GetEnrollmentForFundByMemberId :one
-SELECT id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email
+SELECT id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email, payout_amount_cents, payout_weight
 FROM fund_enrollment
 WHERE member_id = $1
   AND fund_id = $2
@@ -118,6 +147,8 @@ func (q *Queries) GetEnrollmentForFundByMemberId(ctx context.Context, arg GetEnr
 		&i.Created,
 		&i.Updated,
 		&i.PaypalEmail,
+		&i.PayoutAmountCents,
+		&i.PayoutWeight,
 	)
 	return i, err
 }
@@ -131,7 +162,7 @@ ON CONFLICT (fund_id, member_id) DO UPDATE
     SET active          = true,
         member_bco_name = EXCLUDED.member_bco_name,
         paypal_email    = EXCLUDED.paypal_email
-RETURNING id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email
+RETURNING id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email, payout_amount_cents, payout_weight
 `
 
 type InsertEnrollmentParams struct {
@@ -173,6 +204,8 @@ func (q *Queries) InsertEnrollment(ctx context.Context, arg InsertEnrollmentPara
 		&i.Created,
 		&i.Updated,
 		&i.PaypalEmail,
+		&i.PayoutAmountCents,
+		&i.PayoutWeight,
 	)
 	return i, err
 }
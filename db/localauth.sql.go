@@ -0,0 +1,228 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: localauth.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const addLocalUserToGroup = `-- name: AddLocalUserToGroup :exec
+INSERT INTO local_user_group (username, group_name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type AddLocalUserToGroupParams struct {
+	Username  string
+	GroupName string
+}
+
+func (q *Queries) AddLocalUserToGroup(ctx context.Context, arg AddLocalUserToGroupParams) error {
+	_, err := q.db.Exec(ctx, addLocalUserToGroup, arg.Username, arg.GroupName)
+	return err
+}
+
+const deleteLocalUser = `-- name: DeleteLocalUser :exec
+DELETE FROM local_user
+WHERE username = $1
+`
+
+func (q *Queries) DeleteLocalUser(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, deleteLocalUser, username)
+	return err
+}
+
+const deleteSigningKeysBefore = `-- name: DeleteSigningKeysBefore :exec
+DELETE FROM signing_key
+WHERE created < $1
+`
+
+func (q *Queries) DeleteSigningKeysBefore(ctx context.Context, created pgtype.Timestamptz) error {
+	_, err := q.db.Exec(ctx, deleteSigningKeysBefore, created)
+	return err
+}
+
+const getLocalUserByUsername = `-- name: GetLocalUserByUsername :one
+SELECT id, username, email, member_id, password_hash, must_reset, created, updated
+FROM local_user
+WHERE username = $1
+`
+
+func (q *Queries) GetLocalUserByUsername(ctx context.Context, username string) (LocalUser, error) {
+	row := q.db.QueryRow(ctx, getLocalUserByUsername, username)
+	var i LocalUser
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.MemberID,
+		&i.PasswordHash,
+		&i.MustReset,
+		&i.Created,
+		&i.Updated,
+	)
+	return i, err
+}
+
+const getLocalUserGroups = `-- name: GetLocalUserGroups :many
+SELECT group_name
+FROM local_user_group
+WHERE username = $1
+ORDER BY group_name
+`
+
+func (q *Queries) GetLocalUserGroups(ctx context.Context, username string) ([]string, error) {
+	rows, err := q.db.Query(ctx, getLocalUserGroups, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var group_name string
+		if err := rows.Scan(&group_name); err != nil {
+			return nil, err
+		}
+		items = append(items, group_name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSigningKeys = `-- name: GetSigningKeys :many
+SELECT kid, private_jwk, created
+FROM signing_key
+ORDER BY created DESC
+`
+
+func (q *Queries) GetSigningKeys(ctx context.Context) ([]SigningKey, error) {
+	rows, err := q.db.Query(ctx, getSigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SigningKey
+	for rows.Next() {
+		var i SigningKey
+		if err := rows.Scan(&i.Kid, &i.PrivateJwk, &i.Created); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertLocalUser = `-- name: InsertLocalUser :one
+INSERT INTO local_user (id, username, email, member_id, password_hash, must_reset)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, username, email, member_id, password_hash, must_reset, created, updated
+`
+
+type InsertLocalUserParams struct {
+	ID           uuid.UUID
+	Username     string
+	Email        string
+	MemberID     uuid.UUID
+	PasswordHash string
+	MustReset    bool
+}
+
+func (q *Queries) InsertLocalUser(ctx context.Context, arg InsertLocalUserParams) (LocalUser, error) {
+	row := q.db.QueryRow(ctx, insertLocalUser,
+		arg.ID,
+		arg.Username,
+		arg.Email,
+		arg.MemberID,
+		arg.PasswordHash,
+		arg.MustReset,
+	)
+	var i LocalUser
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.MemberID,
+		&i.PasswordHash,
+		&i.MustReset,
+		&i.Created,
+		&i.Updated,
+	)
+	return i, err
+}
+
+const insertSigningKey = `-- name: InsertSigningKey :one
+INSERT INTO signing_key (kid, private_jwk, created)
+VALUES ($1, $2, $3)
+RETURNING kid, private_jwk, created
+`
+
+type InsertSigningKeyParams struct {
+	Kid        string
+	PrivateJwk []byte
+	Created    pgtype.Timestamptz
+}
+
+func (q *Queries) InsertSigningKey(ctx context.Context, arg InsertSigningKeyParams) (SigningKey, error) {
+	row := q.db.QueryRow(ctx, insertSigningKey, arg.Kid, arg.PrivateJwk, arg.Created)
+	var i SigningKey
+	err := row.Scan(&i.Kid, &i.PrivateJwk, &i.Created)
+	return i, err
+}
+
+const removeLocalUserFromGroup = `-- name: RemoveLocalUserFromGroup :exec
+DELETE FROM local_user_group
+WHERE username = $1
+  AND group_name = $2
+`
+
+type RemoveLocalUserFromGroupParams struct {
+	Username  string
+	GroupName string
+}
+
+func (q *Queries) RemoveLocalUserFromGroup(ctx context.Context, arg RemoveLocalUserFromGroupParams) error {
+	_, err := q.db.Exec(ctx, removeLocalUserFromGroup, arg.Username, arg.GroupName)
+	return err
+}
+
+const setLocalUserPassword = `-- name: SetLocalUserPassword :one
+UPDATE local_user
+SET password_hash = $2,
+    must_reset    = $3,
+    updated       = now()
+WHERE username = $1
+RETURNING id, username, email, member_id, password_hash, must_reset, created, updated
+`
+
+type SetLocalUserPasswordParams struct {
+	Username     string
+	PasswordHash string
+	MustReset    bool
+}
+
+func (q *Queries) SetLocalUserPassword(ctx context.Context, arg SetLocalUserPasswordParams) (LocalUser, error) {
+	row := q.db.QueryRow(ctx, setLocalUserPassword, arg.Username, arg.PasswordHash, arg.MustReset)
+	var i LocalUser
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.MemberID,
+		&i.PasswordHash,
+		&i.MustReset,
+		&i.Created,
+		&i.Updated,
+	)
+	return i, err
+}
@@ -16,10 +16,24 @@ import (
 type AdminEventKind string
 
 const (
-	AdminEventKindAdminGranted         AdminEventKind = "admin_granted"
-	AdminEventKindAdminRevoked         AdminEventKind = "admin_revoked"
-	AdminEventKindEmailApproved        AdminEventKind = "email_approved"
-	AdminEventKindEmailApprovalRemoved AdminEventKind = "email_approval_removed"
+	AdminEventKindAdminGranted           AdminEventKind = "admin_granted"
+	AdminEventKindAdminRevoked           AdminEventKind = "admin_revoked"
+	AdminEventKindEmailApproved          AdminEventKind = "email_approved"
+	AdminEventKindEmailApprovalRemoved   AdminEventKind = "email_approval_removed"
+	AdminEventKindWebhookReplayed        AdminEventKind = "webhook_replayed"
+	AdminEventKindPaymentAssigned        AdminEventKind = "payment_assigned"
+	AdminEventKindPasswordResetRequested AdminEventKind = "password_reset_requested"
+	AdminEventKindPasswordResetCompleted AdminEventKind = "password_reset_completed"
+	AdminEventKindTwoFactorEnabled       AdminEventKind = "two_factor_enabled"
+	AdminEventKindTwoFactorDisabled      AdminEventKind = "two_factor_disabled"
+	AdminEventKindRecoveryCodeUsed       AdminEventKind = "recovery_code_used"
+	AdminEventKindRoleGranted            AdminEventKind = "role_granted"
+	AdminEventKindRoleRevoked            AdminEventKind = "role_revoked"
+	AdminEventKindFundManagerAssigned    AdminEventKind = "fund_manager_assigned"
+	AdminEventKindFundManagerRemoved     AdminEventKind = "fund_manager_removed"
+	AdminEventKindInviteCreated          AdminEventKind = "invite_created"
+	AdminEventKindInviteRevoked          AdminEventKind = "invite_revoked"
+	AdminEventKindInviteRedeemed         AdminEventKind = "invite_redeemed"
 )
 
 func (e *AdminEventKind) Scan(src interface{}) error {
@@ -60,24 +74,29 @@ func (ns NullAdminEventKind) Value() (driver.Value, error) {
 type FundEventKind string
 
 const (
-	FundEventKindDonationStarted      FundEventKind = "donation_started"
-	FundEventKindDonationCancelled    FundEventKind = "donation_cancelled"
-	FundEventKindPaymentReceived      FundEventKind = "payment_received"
-	FundEventKindMemberEnrolled       FundEventKind = "member_enrolled"
-	FundEventKindEnrollmentCancelled  FundEventKind = "enrollment_cancelled"
-	FundEventKindPayoutBatchPlanned   FundEventKind = "payout_batch_planned"
-	FundEventKindPayoutBatchApproved  FundEventKind = "payout_batch_approved"
-	FundEventKindPayoutBatchRejected  FundEventKind = "payout_batch_rejected"
-	FundEventKindPayoutBatchSubmitted FundEventKind = "payout_batch_submitted"
-	FundEventKindPayoutBatchSettled   FundEventKind = "payout_batch_settled"
-	FundEventKindPayoutBatchExpired   FundEventKind = "payout_batch_expired"
-	FundEventKindFundClosed           FundEventKind = "fund_closed"
-	FundEventKindPaymentFailed        FundEventKind = "payment_failed"
-	FundEventKindDonationResumed      FundEventKind = "donation_resumed"
-	FundEventKindPaymentRefunded      FundEventKind = "payment_refunded"
-	FundEventKindFundUpdated          FundEventKind = "fund_updated"
-	FundEventKindFundCreated          FundEventKind = "fund_created"
-	FundEventKindFundNoteRemoved      FundEventKind = "fund_note_removed"
+	FundEventKindDonationStarted          FundEventKind = "donation_started"
+	FundEventKindDonationCancelled        FundEventKind = "donation_cancelled"
+	FundEventKindPaymentReceived          FundEventKind = "payment_received"
+	FundEventKindMemberEnrolled           FundEventKind = "member_enrolled"
+	FundEventKindEnrollmentCancelled      FundEventKind = "enrollment_cancelled"
+	FundEventKindPayoutBatchPlanned       FundEventKind = "payout_batch_planned"
+	FundEventKindPayoutBatchApproved      FundEventKind = "payout_batch_approved"
+	FundEventKindPayoutBatchRejected      FundEventKind = "payout_batch_rejected"
+	FundEventKindPayoutBatchSubmitted     FundEventKind = "payout_batch_submitted"
+	FundEventKindPayoutBatchSettled       FundEventKind = "payout_batch_settled"
+	FundEventKindPayoutBatchExpired       FundEventKind = "payout_batch_expired"
+	FundEventKindFundClosed               FundEventKind = "fund_closed"
+	FundEventKindPaymentFailed            FundEventKind = "payment_failed"
+	FundEventKindDonationResumed          FundEventKind = "donation_resumed"
+	FundEventKindPaymentRefunded          FundEventKind = "payment_refunded"
+	FundEventKindFundUpdated              FundEventKind = "fund_updated"
+	FundEventKindFundCreated              FundEventKind = "fund_created"
+	FundEventKindFundNoteRemoved          FundEventKind = "fund_note_removed"
+	FundEventKindPayoutStruck             FundEventKind = "payout_struck"
+	FundEventKindPayoutBatchFirstApproval FundEventKind = "payout_batch_first_approval"
+	FundEventKindDonationAmountChanged    FundEventKind = "donation_amount_changed"
+	FundEventKindPaymentDisputed          FundEventKind = "payment_disputed"
+	FundEventKindPaymentDisputeResolved   FundEventKind = "payment_dispute_resolved"
 )
 
 func (e *FundEventKind) Scan(src interface{}) error {
@@ -120,6 +139,7 @@ type IntervalUnit string
 const (
 	IntervalUnitWEEK  IntervalUnit = "WEEK"
 	IntervalUnitMONTH IntervalUnit = "MONTH"
+	IntervalUnitYEAR  IntervalUnit = "YEAR"
 )
 
 func (e *IntervalUnit) Scan(src interface{}) error {
@@ -157,12 +177,186 @@ func (ns NullIntervalUnit) Value() (driver.Value, error) {
 	return string(ns.IntervalUnit), nil
 }
 
+type NotificationChannel string
+
+const (
+	NotificationChannelEmail   NotificationChannel = "email"
+	NotificationChannelWebhook NotificationChannel = "webhook"
+	NotificationChannelNotice  NotificationChannel = "notice"
+)
+
+func (e *NotificationChannel) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationChannel(s)
+	case string:
+		*e = NotificationChannel(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationChannel: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationChannel struct {
+	NotificationChannel NotificationChannel
+	Valid               bool // Valid is true if NotificationChannel is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationChannel) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationChannel, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationChannel.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationChannel) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationChannel), nil
+}
+
+type NotificationKind string
+
+const (
+	NotificationKindApprovalRequired NotificationKind = "approval_required"
+	NotificationKindApprovalExpiring NotificationKind = "approval_expiring"
+)
+
+func (e *NotificationKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationKind(s)
+	case string:
+		*e = NotificationKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationKind: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationKind struct {
+	NotificationKind NotificationKind
+	Valid            bool // Valid is true if NotificationKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationKind), nil
+}
+
+type PaymentDisputeState string
+
+const (
+	PaymentDisputeStateOpen PaymentDisputeState = "open"
+	PaymentDisputeStateWon  PaymentDisputeState = "won"
+	PaymentDisputeStateLost PaymentDisputeState = "lost"
+)
+
+func (e *PaymentDisputeState) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PaymentDisputeState(s)
+	case string:
+		*e = PaymentDisputeState(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PaymentDisputeState: %T", src)
+	}
+	return nil
+}
+
+type NullPaymentDisputeState struct {
+	PaymentDisputeState PaymentDisputeState
+	Valid               bool // Valid is true if PaymentDisputeState is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPaymentDisputeState) Scan(value interface{}) error {
+	if value == nil {
+		ns.PaymentDisputeState, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PaymentDisputeState.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPaymentDisputeState) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PaymentDisputeState), nil
+}
+
+type PayoutAllocation string
+
+const (
+	PayoutAllocationEven     PayoutAllocation = "even"
+	PayoutAllocationFixed    PayoutAllocation = "fixed"
+	PayoutAllocationWeighted PayoutAllocation = "weighted"
+)
+
+func (e *PayoutAllocation) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PayoutAllocation(s)
+	case string:
+		*e = PayoutAllocation(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PayoutAllocation: %T", src)
+	}
+	return nil
+}
+
+type NullPayoutAllocation struct {
+	PayoutAllocation PayoutAllocation
+	Valid            bool // Valid is true if PayoutAllocation is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPayoutAllocation) Scan(value interface{}) error {
+	if value == nil {
+		ns.PayoutAllocation, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PayoutAllocation.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPayoutAllocation) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PayoutAllocation), nil
+}
+
 type PayoutFrequency string
 
 const (
-	PayoutFrequencyMonthly PayoutFrequency = "monthly"
-	PayoutFrequencyOnce    PayoutFrequency = "once"
-	PayoutFrequencyDaily   PayoutFrequency = "daily"
+	PayoutFrequencyMonthly   PayoutFrequency = "monthly"
+	PayoutFrequencyOnce      PayoutFrequency = "once"
+	PayoutFrequencyDaily     PayoutFrequency = "daily"
+	PayoutFrequencyWeekly    PayoutFrequency = "weekly"
+	PayoutFrequencyBiweekly  PayoutFrequency = "biweekly"
+	PayoutFrequencyQuarterly PayoutFrequency = "quarterly"
 )
 
 func (e *PayoutFrequency) Scan(src interface{}) error {
@@ -214,6 +408,7 @@ const (
 	PayoutStatusOnhold           PayoutStatus = "onhold"
 	PayoutStatusCancelled        PayoutStatus = "cancelled"
 	PayoutStatusAwaitingApproval PayoutStatus = "awaiting_approval"
+	PayoutStatusStruck           PayoutStatus = "struck"
 )
 
 func (e *PayoutStatus) Scan(src interface{}) error {
@@ -294,6 +489,49 @@ func (ns NullRole) Value() (driver.Value, error) {
 	return string(ns.Role), nil
 }
 
+type WebhookOutcome string
+
+const (
+	WebhookOutcomeHandled   WebhookOutcome = "handled"
+	WebhookOutcomeFailed    WebhookOutcome = "failed"
+	WebhookOutcomeAbandoned WebhookOutcome = "abandoned"
+)
+
+func (e *WebhookOutcome) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookOutcome(s)
+	case string:
+		*e = WebhookOutcome(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookOutcome: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookOutcome struct {
+	WebhookOutcome WebhookOutcome
+	Valid          bool // Valid is true if WebhookOutcome is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookOutcome) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookOutcome, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookOutcome.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookOutcome) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookOutcome), nil
+}
+
 type AdminEvent struct {
 	ID              uuid.UUID
 	Kind            AdminEventKind
@@ -303,6 +541,7 @@ type AdminEvent struct {
 	Detail          pgtype.Text
 	Created         pgtype.Timestamptz
 	SubjectLabel    pgtype.Text
+	Role            pgtype.Text
 }
 
 type ApprovedEmail struct {
@@ -313,24 +552,33 @@ type ApprovedEmail struct {
 	Updated pgtype.Timestamptz
 }
 
+type BatchApproval struct {
+	ID         uuid.UUID
+	BatchID    uuid.UUID
+	ApproverID uuid.UUID
+	Created    pgtype.Timestamptz
+}
+
 type BatchPayout struct {
-	ID               uuid.UUID
-	FundID           uuid.UUID
-	AmountCents      int32
-	NumEnrollments   int32
-	Status           PayoutStatus
-	FailureReason    pgtype.Text
-	Notes            pgtype.Text
-	Description      pgtype.Text
-	ProviderBatchID  pgtype.Text
-	PayoutDate       pgtype.Timestamptz
-	Created          pgtype.Timestamptz
-	Updated          pgtype.Timestamptz
-	SenderBatchID    uuid.UUID
-	ApprovalDeadline NullDBTime
-	ApprovedBy       uuid.NullUUID
-	ApprovedAt       NullDBTime
-	ReminderSentAt   NullDBTime
+	ID                uuid.UUID
+	FundID            uuid.UUID
+	AmountCents       int32
+	NumEnrollments    int32
+	Status            PayoutStatus
+	FailureReason     pgtype.Text
+	Notes             pgtype.Text
+	Description       pgtype.Text
+	ProviderBatchID   pgtype.Text
+	PayoutDate        pgtype.Timestamptz
+	Created           pgtype.Timestamptz
+	Updated           pgtype.Timestamptz
+	SenderBatchID     uuid.UUID
+	ApprovalDeadline  NullDBTime
+	ApprovedBy        uuid.NullUUID
+	ApprovedAt        NullDBTime
+	ReminderSentAt    NullDBTime
+	ApprovalsRequired int32
+	ApprovalsGiven    int32
 }
 
 type Donation struct {
@@ -345,6 +593,7 @@ type Donation struct {
 	Active                 bool
 	ProviderSubscriptionID pgtype.Text
 	InactiveReason         pgtype.Text
+	ProviderName           string
 }
 
 type DonationPayment struct {
@@ -359,6 +608,11 @@ type DonationPayment struct {
 	ProviderStatus      pgtype.Text
 	ProviderAmountCents pgtype.Int4
 	ReconciledAt        NullDBTime
+	DisputeID           pgtype.Text
+	DisputeState        NullPaymentDisputeState
+	DisputedCents       int32
+	DisputeReason       pgtype.Text
+	DisputeUpdatedAt    pgtype.Timestamptz
 }
 
 type DonationPlan struct {
@@ -372,35 +626,42 @@ type DonationPlan struct {
 	Created       pgtype.Timestamptz
 	Updated       pgtype.Timestamptz
 	FundID        uuid.UUID
+	ProviderName  string
 }
 
 type Fund struct {
-	ID               uuid.UUID
-	Name             string
-	Description      string
-	ProviderID       string
-	ProviderName     string
-	GoalCents        pgtype.Int4
-	PayoutFrequency  PayoutFrequency
-	Active           bool
-	Principal        uuid.NullUUID
-	Expires          NullDBTime
-	NextPayment      DBTime
-	Created          pgtype.Timestamptz
-	Updated          pgtype.Timestamptz
-	EnrolleesVisible bool
+	ID                       uuid.UUID
+	Name                     string
+	Description              string
+	ProviderID               string
+	ProviderName             string
+	GoalCents                pgtype.Int4
+	PayoutFrequency          PayoutFrequency
+	Active                   bool
+	Principal                uuid.NullUUID
+	Expires                  NullDBTime
+	NextPayment              DBTime
+	Created                  pgtype.Timestamptz
+	Updated                  pgtype.Timestamptz
+	EnrolleesVisible         bool
+	PayoutAllocation         PayoutAllocation
+	PayoutCapCents           pgtype.Int4
+	SecondApprovalAboveCents pgtype.Int4
+	CloseOnGoal              bool
 }
 
 type FundEnrollment struct {
-	ID              uuid.UUID
-	FundID          uuid.UUID
-	MemberID        uuid.UUID
-	MemberBcoName   pgtype.Text
-	FirstPayoutDate pgtype.Timestamptz
-	Active          bool
-	Created         pgtype.Timestamptz
-	Updated         pgtype.Timestamptz
-	PaypalEmail     string
+	ID                uuid.UUID
+	FundID            uuid.UUID
+	MemberID          uuid.UUID
+	MemberBcoName     pgtype.Text
+	FirstPayoutDate   pgtype.Timestamptz
+	Active            bool
+	Created           pgtype.Timestamptz
+	Updated           pgtype.Timestamptz
+	PaypalEmail       string
+	PayoutAmountCents pgtype.Int4
+	PayoutWeight      int32
 }
 
 type FundEvent struct {
@@ -428,6 +689,12 @@ type FundImage struct {
 	Updated     pgtype.Timestamptz
 }
 
+type FundManager struct {
+	MemberID uuid.UUID
+	FundID   uuid.UUID
+	Created  pgtype.Timestamptz
+}
+
 type FundNote struct {
 	ID        uuid.UUID
 	FundID    uuid.UUID
@@ -440,6 +707,42 @@ type FundNote struct {
 	Updated   pgtype.Timestamptz
 }
 
+type FundProvider struct {
+	FundID       uuid.UUID
+	ProviderName string
+	ProviderID   string
+	Created      pgtype.Timestamptz
+}
+
+type Invite struct {
+	ID        uuid.UUID
+	Email     pgtype.Text
+	FundID    uuid.NullUUID
+	InvitedBy uuid.UUID
+	MemberID  uuid.NullUUID
+	ExpiresAt pgtype.Timestamptz
+	UsedAt    pgtype.Timestamptz
+	RevokedAt pgtype.Timestamptz
+	Created   pgtype.Timestamptz
+}
+
+type LocalUser struct {
+	ID           uuid.UUID
+	Username     string
+	Email        string
+	MemberID     uuid.UUID
+	PasswordHash string
+	MustReset    bool
+	Created      pgtype.Timestamptz
+	Updated      pgtype.Timestamptz
+}
+
+type LocalUserGroup struct {
+	Username  string
+	GroupName string
+	Created   pgtype.Timestamptz
+}
+
 type Member struct {
 	ID              uuid.UUID
 	FirstName       pgtype.Text
@@ -458,6 +761,24 @@ type Member struct {
 	Active          bool
 }
 
+type MemberRecoveryCode struct {
+	ID       uuid.UUID
+	MemberID uuid.UUID
+	CodeHash []byte
+	UsedAt   pgtype.Timestamptz
+	Created  pgtype.Timestamptz
+}
+
+type MemberTotp struct {
+	MemberID       uuid.UUID
+	Secret         string
+	ConfirmedAt    pgtype.Timestamptz
+	LastUsedStep   int64
+	Created        pgtype.Timestamptz
+	FailedAttempts int32
+	LockedUntil    pgtype.Timestamptz
+}
+
 type Notice struct {
 	ID        uuid.UUID
 	Body      string
@@ -468,6 +789,16 @@ type Notice struct {
 	Updated   pgtype.Timestamptz
 }
 
+type NotificationDelivery struct {
+	ID        uuid.UUID
+	BatchID   uuid.UUID
+	Kind      NotificationKind
+	Channel   NotificationChannel
+	Recipient string
+	Error     pgtype.Text
+	Created   pgtype.Timestamptz
+}
+
 type PasskeyUser struct {
 	ID      []byte
 	Email   string
@@ -477,6 +808,17 @@ type PasskeyUser struct {
 	Updated pgtype.Timestamptz
 }
 
+type PasswordReset struct {
+	ID        uuid.UUID
+	Email     string
+	IpAddress string
+	MemberID  uuid.NullUUID
+	TokenHash []byte
+	ExpiresAt pgtype.Timestamptz
+	UsedAt    pgtype.Timestamptz
+	Created   pgtype.Timestamptz
+}
+
 type Payout struct {
 	ID                   uuid.UUID
 	FundEnrollmentID     uuid.UUID
@@ -492,6 +834,8 @@ type Payout struct {
 	ProviderPayoutItemID pgtype.Text
 	DestinationEmail     string
 	ProviderFeeCents     int32
+	StruckBy             uuid.NullUUID
+	StruckAt             NullDBTime
 }
 
 type Session struct {
@@ -500,8 +844,58 @@ type Session struct {
 	Expiry pgtype.Timestamptz
 }
 
+type SigningKey struct {
+	Kid        string
+	PrivateJwk []byte
+	Created    pgtype.Timestamptz
+}
+
+type UnmatchedPayment struct {
+	ID                uuid.UUID
+	ProviderName      string
+	ProviderPaymentID string
+	ProviderOrderID   pgtype.Text
+	AmountCents       int32
+	ProviderFeeCents  int32
+	PayerEmail        pgtype.Text
+	PayerName         pgtype.Text
+	FundID            uuid.NullUUID
+	Reason            string
+	OccurredAt        pgtype.Timestamptz
+	DonationID        uuid.NullUUID
+	AssignedBy        uuid.NullUUID
+	AssignedAt        pgtype.Timestamptz
+	Created           pgtype.Timestamptz
+}
+
 type WebhookDelivery struct {
 	TransmissionID string
 	EventType      string
 	ReceivedAt     pgtype.Timestamptz
 }
+
+type WebhookEvent struct {
+	TransmissionID     string
+	EventID            string
+	EventType          string
+	ResourceType       string
+	ResourceID         string
+	Summary            string
+	Payload            []byte
+	CertUrl            string
+	CertificateChained bool
+	ChainError         pgtype.Text
+	CreatedAt          NullDBTime
+	ReceivedAt         pgtype.Timestamptz
+}
+
+type WebhookEventOutcome struct {
+	ID             uuid.UUID
+	TransmissionID string
+	Consumer       string
+	StreamSeq      int64
+	Delivery       int32
+	Outcome        WebhookOutcome
+	Error          pgtype.Text
+	OccurredAt     pgtype.Timestamptz
+}
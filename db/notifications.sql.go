@@ -0,0 +1,87 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: notifications.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const getNotificationDeliveriesByBatchId = `-- name: GetNotificationDeliveriesByBatchId :many
+SELECT id, batch_id, kind, channel, recipient, error, created
+FROM notification_delivery
+WHERE batch_id = $1
+ORDER BY created DESC
+`
+
+// Newest first: the question on the batch page is whether the last attempt
+// worked.
+func (q *Queries) GetNotificationDeliveriesByBatchId(ctx context.Context, batchID uuid.UUID) ([]NotificationDelivery, error) {
+	rows, err := q.db.Query(ctx, getNotificationDeliveriesByBatchId, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationDelivery
+	for rows.Next() {
+		var i NotificationDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.Kind,
+			&i.Channel,
+			&i.Recipient,
+			&i.Error,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertNotificationDelivery = `-- name: InsertNotificationDelivery :one
+INSERT INTO notification_delivery (id, batch_id, kind, channel, recipient, error)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, batch_id, kind, channel, recipient, error, created
+`
+
+type InsertNotificationDeliveryParams struct {
+	ID        uuid.UUID
+	BatchID   uuid.UUID
+	Kind      NotificationKind
+	Channel   NotificationChannel
+	Recipient string
+	Error     pgtype.Text
+}
+
+func (q *Queries) InsertNotificationDelivery(ctx context.Context, arg InsertNotificationDeliveryParams) (NotificationDelivery, error) {
+	row := q.db.QueryRow(ctx, insertNotificationDelivery,
+		arg.ID,
+		arg.BatchID,
+		arg.Kind,
+		arg.Channel,
+		arg.Recipient,
+		arg.Error,
+	)
+	var i NotificationDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.Kind,
+		&i.Channel,
+		&i.Recipient,
+		&i.Error,
+		&i.Created,
+	)
+	return i, err
+}
@@ -19,13 +19,22 @@ SET next_payment = CASE
                        WHEN payout_frequency = 'daily' THEN next_payment + (interval '1 day' * (SELECT MIN(n)
                                                                                                 FROM generate_series(1, 3650) n
                                                                                                 WHERE next_payment + (interval '1 day' * n) > now()))
+                       WHEN payout_frequency = 'weekly' THEN next_payment + (interval '7 days' * (SELECT MIN(n)
+                                                                                                  FROM generate_series(1, 60) n
+                                                                                                  WHERE next_payment + (interval '7 days' * n) > now()))
+                       WHEN payout_frequency = 'biweekly' THEN next_payment + (interval '14 days' * (SELECT MIN(n)
+                                                                                                     FROM generate_series(1, 60) n
+                                                                                                     WHERE next_payment + (interval '14 days' * n) > now()))
+                       WHEN payout_frequency = 'quarterly' THEN next_payment + (interval '3 months' * (SELECT MIN(n)
+                                                                                                       FROM generate_series(1, 60) n
+                                                                                                       WHERE next_payment + (interval '3 months' * n) > now()))
                        ELSE next_payment + (interval '1 month' * (SELECT MIN(n)
                                                                   FROM generate_series(1, 60) n
                                                                   WHERE next_payment + (interval '1 month' * n) > now()))
     END,
     updated      = now()
 WHERE id = $1
-RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible
+RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_frequency, active, principal, expires, next_payment, created, updated, enrollees_visible, payout_allocation, payout_cap_cents, second_approval_above_cents, close_on_goal
 `
 
 // Moves the fund to its next scheduled payout, anchored on the existing date
@@ -43,6 +52,11 @@ RETURNING id, name, description, provider_id, provider_name, goal_cents, payout_
 // every month has a tomorrow -- but it does need a wider search, since 60 of
 // anything is two months of days. Ten years of them keeps the bound honest
 // against a test fund left running.
+//
+// 'weekly' and 'biweekly' step by seven and fourteen days, so a fund paying on
+// a Friday keeps paying on a Friday; 'quarterly' steps by three months and
+// clamps the way monthly does. The same rules as Go's Fund.NextPaymentAfter,
+// and the same bound of 60 periods.
 func (q *Queries) AdvanceFundNextPayment(ctx context.Context, id uuid.UUID) (Fund, error) {
 	row := q.db.QueryRow(ctx, advanceFundNextPayment, id)
 	var i Fund
@@ -61,6 +75,10 @@ func (q *Queries) AdvanceFundNextPayment(ctx context.Context, id uuid.UUID) (Fun
 		&i.Created,
 		&i.Updated,
 		&i.EnrolleesVisible,
+		&i.PayoutAllocation,
+		&i.PayoutCapCents,
+		&i.SecondApprovalAboveCents,
+		&i.CloseOnGoal,
 	)
 	return i, err
 }
@@ -73,7 +91,7 @@ SET status      = 'ready',
     updated     = now()
 WHERE id = $1
   AND status = 'awaiting_approval'
-RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 `
 
 type ApproveBatchPayoutParams struct {
@@ -105,6 +123,8 @@ func (q *Queries) ApproveBatchPayout(ctx context.Context, arg ApproveBatchPayout
 		&i.ApprovedBy,
 		&i.ApprovedAt,
 		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
 	)
 	return i, err
 }
@@ -117,7 +137,7 @@ SET status         = 'cancelled',
 WHERE status = 'awaiting_approval'
   AND approval_deadline IS NOT NULL
   AND approval_deadline <= now()
-RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 `
 
 // Sweeps batches whose approval window closed. Bounded by status so an approved
@@ -150,6 +170,8 @@ func (q *Queries) CancelExpiredBatchPayouts(ctx context.Context) ([]BatchPayout,
 			&i.ApprovedBy,
 			&i.ApprovedAt,
 			&i.ReminderSentAt,
+			&i.ApprovalsRequired,
+			&i.ApprovalsGiven,
 		); err != nil {
 			return nil, err
 		}
@@ -161,8 +183,55 @@ func (q *Queries) CancelExpiredBatchPayouts(ctx context.Context) ([]BatchPayout,
 	return items, nil
 }
 
+const countBatchApproval = `-- name: CountBatchApproval :one
+UPDATE batch_payout
+SET approvals_given    = approvals_given + 1,
+    approvals_required = CASE
+           WHEN batch_payout.amount_cents > COALESCE((SELECT f.second_approval_above_cents FROM fund f WHERE f.id = batch_payout.fund_id),
+                                    $2::int) THEN 2
+           ELSE 1
+    END,
+    updated            = now()
+WHERE batch_payout.id = $1
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
+`
+
+type CountBatchApprovalParams struct {
+	ID                              uuid.UUID
+	DefaultSecondApprovalAboveCents pgtype.Int4
+}
+
+// Counts the approval and re-decides how many are needed, in case the fund's
+// threshold or the site's changed since the batch was planned.
+func (q *Queries) CountBatchApproval(ctx context.Context, arg CountBatchApprovalParams) (BatchPayout, error) {
+	row := q.db.QueryRow(ctx, countBatchApproval, arg.ID, arg.DefaultSecondApprovalAboveCents)
+	var i BatchPayout
+	err := row.Scan(
+		&i.ID,
+		&i.FundID,
+		&i.AmountCents,
+		&i.NumEnrollments,
+		&i.Status,
+		&i.FailureReason,
+		&i.Notes,
+		&i.Description,
+		&i.ProviderBatchID,
+		&i.PayoutDate,
+		&i.Created,
+		&i.Updated,
+		&i.SenderBatchID,
+		&i.ApprovalDeadline,
+		&i.ApprovedBy,
+		&i.ApprovedAt,
+		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
+	)
+	return i, err
+}
+
 const getActiveEnrollmentsForPayout = `-- name: GetActiveEnrollmentsForPayout :many
-SELECT fund_enrollment.id, fund_enrollment.fund_id, fund_enrollment.member_id, fund_enrollment.member_bco_name, fund_enrollment.first_payout_date, fund_enrollment.active, fund_enrollment.created, fund_enrollment.updated, fund_enrollment.paypal_email
+SELECT fund_enrollment.id, fund_enrollment.fund_id, fund_enrollment.member_id, fund_enrollment.member_bco_name, fund_enrollment.first_payout_date, fund_enrollment.active, fund_enrollment.created, fund_enrollment.updated, fund_enrollment.paypal_email, fund_enrollment.payout_amount_cents, fund_enrollment.payout_weight
 FROM fund_enrollment
          JOIN member ON member.id = fund_enrollment.member_id
          JOIN fund ON fund.id = fund_enrollment.fund_id
@@ -196,6 +265,56 @@ func (q *Queries) GetActiveEnrollmentsForPayout(ctx context.Context, fundID uuid
 			&i.Created,
 			&i.Updated,
 			&i.PaypalEmail,
+			&i.PayoutAmountCents,
+			&i.PayoutWeight,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBatchApprovals = `-- name: GetBatchApprovals :many
+SELECT ba.id,
+       ba.batch_id,
+       ba.approver_id,
+       m.bco_name AS approver_name,
+       ba.created
+FROM batch_approval ba
+         JOIN member m ON m.id = ba.approver_id
+WHERE ba.batch_id = $1
+ORDER BY ba.created
+`
+
+type GetBatchApprovalsRow struct {
+	ID           uuid.UUID
+	BatchID      uuid.UUID
+	ApproverID   uuid.UUID
+	ApproverName pgtype.Text
+	Created      pgtype.Timestamptz
+}
+
+// Who approved a batch, in order, named the way the rest of the page names
+// people.
+func (q *Queries) GetBatchApprovals(ctx context.Context, batchID uuid.UUID) ([]GetBatchApprovalsRow, error) {
+	rows, err := q.db.Query(ctx, getBatchApprovals, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBatchApprovalsRow
+	for rows.Next() {
+		var i GetBatchApprovalsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.ApproverID,
+			&i.ApproverName,
+			&i.Created,
 		); err != nil {
 			return nil, err
 		}
@@ -208,7 +327,7 @@ func (q *Queries) GetActiveEnrollmentsForPayout(ctx context.Context, fundID uuid
 }
 
 const getBatchPayoutById = `-- name: GetBatchPayoutById :one
-SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 FROM batch_payout
 WHERE id = $1
 `
@@ -234,12 +353,14 @@ func (q *Queries) GetBatchPayoutById(ctx context.Context, id uuid.UUID) (BatchPa
 		&i.ApprovedBy,
 		&i.ApprovedAt,
 		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
 	)
 	return i, err
 }
 
 const getBatchPayoutBySenderBatchId = `-- name: GetBatchPayoutBySenderBatchId :one
-SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 FROM batch_payout
 WHERE sender_batch_id = $1
 `
@@ -265,12 +386,14 @@ func (q *Queries) GetBatchPayoutBySenderBatchId(ctx context.Context, senderBatch
 		&i.ApprovedBy,
 		&i.ApprovedAt,
 		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
 	)
 	return i, err
 }
 
 const getBatchPayoutsByFundId = `-- name: GetBatchPayoutsByFundId :many
-SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 FROM batch_payout
 WHERE fund_id = $1
 ORDER BY payout_date DESC
@@ -303,6 +426,8 @@ func (q *Queries) GetBatchPayoutsByFundId(ctx context.Context, fundID uuid.UUID)
 			&i.ApprovedBy,
 			&i.ApprovedAt,
 			&i.ReminderSentAt,
+			&i.ApprovalsRequired,
+			&i.ApprovalsGiven,
 		); err != nil {
 			return nil, err
 		}
@@ -315,7 +440,7 @@ func (q *Queries) GetBatchPayoutsByFundId(ctx context.Context, fundID uuid.UUID)
 }
 
 const getBatchPayoutsByStatus = `-- name: GetBatchPayoutsByStatus :many
-SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 FROM batch_payout
 WHERE status = $1
 ORDER BY payout_date
@@ -348,6 +473,8 @@ func (q *Queries) GetBatchPayoutsByStatus(ctx context.Context, status PayoutStat
 			&i.ApprovedBy,
 			&i.ApprovedAt,
 			&i.ReminderSentAt,
+			&i.ApprovalsRequired,
+			&i.ApprovalsGiven,
 		); err != nil {
 			return nil, err
 		}
@@ -360,7 +487,7 @@ func (q *Queries) GetBatchPayoutsByStatus(ctx context.Context, status PayoutStat
 }
 
 const getBatchPayoutsNeedingReminder = `-- name: GetBatchPayoutsNeedingReminder :many
-SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 FROM batch_payout
 WHERE status = 'awaiting_approval'
   AND reminder_sent_at IS NULL
@@ -398,6 +525,8 @@ func (q *Queries) GetBatchPayoutsNeedingReminder(ctx context.Context, remindWith
 			&i.ApprovedBy,
 			&i.ApprovedAt,
 			&i.ReminderSentAt,
+			&i.ApprovalsRequired,
+			&i.ApprovalsGiven,
 		); err != nil {
 			return nil, err
 		}
@@ -409,6 +538,105 @@ func (q *Queries) GetBatchPayoutsNeedingReminder(ctx context.Context, remindWith
 	return items, nil
 }
 
+const getDetailedBatchPayoutById = `-- name: GetDetailedBatchPayoutById :one
+SELECT bp.id,
+       bp.fund_id,
+       bp.amount_cents,
+       bp.num_enrollments,
+       bp.status,
+       bp.failure_reason,
+       bp.notes,
+       bp.description,
+       bp.provider_batch_id,
+       bp.payout_date,
+       bp.sender_batch_id,
+       bp.approval_deadline,
+       bp.approved_by,
+       bp.approved_at,
+       bp.reminder_sent_at,
+       bp.approvals_required,
+       bp.approvals_given,
+       bp.created,
+       bp.updated,
+       f.name                                                          AS fund_name,
+       COALESCE(
+               array_agg(COALESCE(m.bco_name, fe.member_bco_name)
+                         ORDER BY COALESCE(m.bco_name, fe.member_bco_name))
+               FILTER (WHERE COALESCE(m.bco_name, fe.member_bco_name) IS NOT NULL),
+               '{}'
+       )::text[]                                                       AS payee_names,
+       COALESCE(
+               array_agg(COALESCE(m.id, '00000000-0000-0000-0000-000000000000'::uuid)
+                         ORDER BY COALESCE(m.bco_name, fe.member_bco_name))
+               FILTER (WHERE COALESCE(m.bco_name, fe.member_bco_name) IS NOT NULL),
+               '{}'
+       )::uuid[]                                                       AS payee_ids
+FROM batch_payout bp
+         JOIN fund f ON f.id = bp.fund_id
+         LEFT JOIN payout p ON p.batch_id = bp.id AND p.status <> 'struck'
+         LEFT JOIN fund_enrollment fe ON fe.id = p.fund_enrollment_id
+         LEFT JOIN member m ON m.id = fe.member_id
+WHERE bp.id = $1
+GROUP BY bp.id, f.name
+`
+
+type GetDetailedBatchPayoutByIdRow struct {
+	ID                uuid.UUID
+	FundID            uuid.UUID
+	AmountCents       int32
+	NumEnrollments    int32
+	Status            PayoutStatus
+	FailureReason     pgtype.Text
+	Notes             pgtype.Text
+	Description       pgtype.Text
+	ProviderBatchID   pgtype.Text
+	PayoutDate        pgtype.Timestamptz
+	SenderBatchID     uuid.UUID
+	ApprovalDeadline  NullDBTime
+	ApprovedBy        uuid.NullUUID
+	ApprovedAt        NullDBTime
+	ReminderSentAt    NullDBTime
+	ApprovalsRequired int32
+	ApprovalsGiven    int32
+	Created           pgtype.Timestamptz
+	Updated           pgtype.Timestamptz
+	FundName          string
+	PayeeNames        []string
+	PayeeIds          []uuid.UUID
+}
+
+// One batch, read the same way. What a message about a batch needs to say:
+// whose money, and to whom.
+func (q *Queries) GetDetailedBatchPayoutById(ctx context.Context, id uuid.UUID) (GetDetailedBatchPayoutByIdRow, error) {
+	row := q.db.QueryRow(ctx, getDetailedBatchPayoutById, id)
+	var i GetDetailedBatchPayoutByIdRow
+	err := row.Scan(
+		&i.ID,
+		&i.FundID,
+		&i.AmountCents,
+		&i.NumEnrollments,
+		&i.Status,
+		&i.FailureReason,
+		&i.Notes,
+		&i.Description,
+		&i.ProviderBatchID,
+		&i.PayoutDate,
+		&i.SenderBatchID,
+		&i.ApprovalDeadline,
+		&i.ApprovedBy,
+		&i.ApprovedAt,
+		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
+		&i.Created,
+		&i.Updated,
+		&i.FundName,
+		&i.PayeeNames,
+		&i.PayeeIds,
+	)
+	return i, err
+}
+
 const getDetailedBatchPayoutsByStatus = `-- name: GetDetailedBatchPayoutsByStatus :many
 SELECT bp.id,
        bp.fund_id,
@@ -425,6 +653,8 @@ SELECT bp.id,
        bp.approved_by,
        bp.approved_at,
        bp.reminder_sent_at,
+       bp.approvals_required,
+       bp.approvals_given,
        bp.created,
        bp.updated,
        f.name                                                          AS fund_name,
@@ -448,7 +678,7 @@ SELECT bp.id,
        )::uuid[]                                                       AS payee_ids
 FROM batch_payout bp
          JOIN fund f ON f.id = bp.fund_id
-         LEFT JOIN payout p ON p.batch_id = bp.id
+         LEFT JOIN payout p ON p.batch_id = bp.id AND p.status <> 'struck'
          LEFT JOIN fund_enrollment fe ON fe.id = p.fund_enrollment_id
          LEFT JOIN member m ON m.id = fe.member_id
 WHERE bp.status = $1
@@ -457,26 +687,28 @@ ORDER BY bp.payout_date
 `
 
 type GetDetailedBatchPayoutsByStatusRow struct {
-	ID               uuid.UUID
-	FundID           uuid.UUID
-	AmountCents      int32
-	NumEnrollments   int32
-	Status           PayoutStatus
-	FailureReason    pgtype.Text
-	Notes            pgtype.Text
-	Description      pgtype.Text
-	ProviderBatchID  pgtype.Text
-	PayoutDate       pgtype.Timestamptz
-	SenderBatchID    uuid.UUID
-	ApprovalDeadline NullDBTime
-	ApprovedBy       uuid.NullUUID
-	ApprovedAt       NullDBTime
-	ReminderSentAt   NullDBTime
-	Created          pgtype.Timestamptz
-	Updated          pgtype.Timestamptz
-	FundName         string
-	PayeeNames       []string
-	PayeeIds         []uuid.UUID
+	ID                uuid.UUID
+	FundID            uuid.UUID
+	AmountCents       int32
+	NumEnrollments    int32
+	Status            PayoutStatus
+	FailureReason     pgtype.Text
+	Notes             pgtype.Text
+	Description       pgtype.Text
+	ProviderBatchID   pgtype.Text
+	PayoutDate        pgtype.Timestamptz
+	SenderBatchID     uuid.UUID
+	ApprovalDeadline  NullDBTime
+	ApprovedBy        uuid.NullUUID
+	ApprovedAt        NullDBTime
+	ReminderSentAt    NullDBTime
+	ApprovalsRequired int32
+	ApprovalsGiven    int32
+	Created           pgtype.Timestamptz
+	Updated           pgtype.Timestamptz
+	FundName          string
+	PayeeNames        []string
+	PayeeIds          []uuid.UUID
 }
 
 // What the approval page needs to show a batch, rather than what the jobs need to
@@ -496,6 +728,9 @@ type GetDetailedBatchPayoutsByStatusRow struct {
 //
 // LEFT JOIN throughout, so a batch with no payouts recorded yet still lists,
 // rather than disappearing from the page that exists to approve it.
+//
+// Struck payouts are left out of the join: "who is being paid" does not include
+// somebody a treasurer has already taken out.
 func (q *Queries) GetDetailedBatchPayoutsByStatus(ctx context.Context, status PayoutStatus) ([]GetDetailedBatchPayoutsByStatusRow, error) {
 	rows, err := q.db.Query(ctx, getDetailedBatchPayoutsByStatus, status)
 	if err != nil {
@@ -521,6 +756,8 @@ func (q *Queries) GetDetailedBatchPayoutsByStatus(ctx context.Context, status Pa
 			&i.ApprovedBy,
 			&i.ApprovedAt,
 			&i.ReminderSentAt,
+			&i.ApprovalsRequired,
+			&i.ApprovalsGiven,
 			&i.Created,
 			&i.Updated,
 			&i.FundName,
@@ -537,12 +774,26 @@ func (q *Queries) GetDetailedBatchPayoutsByStatus(ctx context.Context, status Pa
 	return items, nil
 }
 
+const getEnrollmentMemberId = `-- name: GetEnrollmentMemberId :one
+SELECT member_id
+FROM fund_enrollment
+WHERE id = $1
+`
+
+func (q *Queries) GetEnrollmentMemberId(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, getEnrollmentMemberId, id)
+	var member_id uuid.UUID
+	err := row.Scan(&member_id)
+	return member_id, err
+}
+
 const getEnrollmentsInUnsentBatches = `-- name: GetEnrollmentsInUnsentBatches :many
 SELECT DISTINCT payout.fund_enrollment_id
 FROM payout
          JOIN batch_payout ON batch_payout.id = payout.batch_id
 WHERE batch_payout.fund_id = $1
   AND batch_payout.status IN ('awaiting_approval', 'ready')
+  AND payout.status <> 'struck'
 `
 
 // Enrollments named by a batch that has been planned but not yet sent.
@@ -577,7 +828,12 @@ func (q *Queries) GetEnrollmentsInUnsentBatches(ctx context.Context, fundID uuid
 }
 
 const getFundBalanceCents = `-- name: GetFundBalanceCents :one
-SELECT (COALESCE((SELECT SUM(dp.amount_cents - dp.refunded_cents - dp.provider_fee_cents)
+SELECT (COALESCE((SELECT SUM(dp.amount_cents
+                                 - GREATEST(dp.refunded_cents,
+                                            CASE
+                                                WHEN dp.dispute_state IN ('open', 'lost') THEN dp.disputed_cents
+                                                ELSE 0 END)
+                                 - dp.provider_fee_cents)
                   FROM donation
                            JOIN donation_payment dp ON donation.id = dp.donation_id
                   WHERE donation.fund_id = $1), 0)
@@ -593,7 +849,9 @@ SELECT (COALESCE((SELECT SUM(dp.amount_cents - dp.refunded_cents - dp.provider_f
                   -- of every batch that came to nothing, permanently, and the
                   -- money could never be paid out by a later one.
                   AND batch_payout.status <> 'cancelled'
-                  AND payout.status NOT IN ('failed', 'cancelled', 'returned')), 0))::bigint
+                  -- A struck payout was taken out before approval and will
+                  -- never be sent, so its money is free again too.
+                  AND payout.status NOT IN ('failed', 'cancelled', 'returned', 'struck')), 0))::bigint
            AS available_cents
 `
 
@@ -623,6 +881,15 @@ SELECT (COALESCE((SELECT SUM(dp.amount_cents - dp.refunded_cents - dp.provider_f
 //
 // A refunded payment keeps its fee subtracted: PayPal retains the fee on a
 // refund, so the money is gone whether or not the donation stayed.
+//
+// Disputed money is held out while the dispute is open, and stays out if it is
+// lost. PayPal takes it from the account the moment a dispute opens, so a fund
+// counting it until the resolution was planning payouts from money that was not
+// there and, half the time, never would be again. A won dispute gives it back.
+//
+// The larger of the two, not their sum: a lost chargeback is often followed by
+// PAYMENT.SALE.REVERSED for the same money, and subtracting both would take it
+// out twice.
 func (q *Queries) GetFundBalanceCents(ctx context.Context, fundID uuid.UUID) (int64, error) {
 	row := q.db.QueryRow(ctx, getFundBalanceCents, fundID)
 	var available_cents int64
@@ -631,7 +898,7 @@ func (q *Queries) GetFundBalanceCents(ctx context.Context, fundID uuid.UUID) (in
 }
 
 const getFundsDueForPayout = `-- name: GetFundsDueForPayout :many
-SELECT id, name, payout_frequency, next_payment
+SELECT id, name, payout_frequency, next_payment, payout_allocation, payout_cap_cents
 FROM fund
 WHERE active = true
   AND next_payment IS NOT NULL
@@ -640,10 +907,12 @@ ORDER BY next_payment
 `
 
 type GetFundsDueForPayoutRow struct {
-	ID              uuid.UUID
-	Name            string
-	PayoutFrequency PayoutFrequency
-	NextPayment     DBTime
+	ID               uuid.UUID
+	Name             string
+	PayoutFrequency  PayoutFrequency
+	NextPayment      DBTime
+	PayoutAllocation PayoutAllocation
+	PayoutCapCents   pgtype.Int4
 }
 
 // The planner runs daily and asks "what is due today", so a fund whose date has
@@ -664,6 +933,8 @@ func (q *Queries) GetFundsDueForPayout(ctx context.Context) ([]GetFundsDueForPay
 			&i.Name,
 			&i.PayoutFrequency,
 			&i.NextPayment,
+			&i.PayoutAllocation,
+			&i.PayoutCapCents,
 		); err != nil {
 			return nil, err
 		}
@@ -675,37 +946,110 @@ func (q *Queries) GetFundsDueForPayout(ctx context.Context) ([]GetFundsDueForPay
 	return items, nil
 }
 
+const getPayoutById = `-- name: GetPayoutById :one
+SELECT id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents, struck_by, struck_at
+FROM payout
+WHERE id = $1
+`
+
+func (q *Queries) GetPayoutById(ctx context.Context, id uuid.UUID) (Payout, error) {
+	row := q.db.QueryRow(ctx, getPayoutById, id)
+	var i Payout
+	err := row.Scan(
+		&i.ID,
+		&i.FundEnrollmentID,
+		&i.BatchID,
+		&i.AmountCents,
+		&i.Status,
+		&i.FailureReason,
+		&i.Notes,
+		&i.Description,
+		&i.PayoutDate,
+		&i.Created,
+		&i.Updated,
+		&i.ProviderPayoutItemID,
+		&i.DestinationEmail,
+		&i.ProviderFeeCents,
+		&i.StruckBy,
+		&i.StruckAt,
+	)
+	return i, err
+}
+
+const getPayoutByProviderItemId = `-- name: GetPayoutByProviderItemId :one
+SELECT id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents, struck_by, struck_at
+FROM payout
+WHERE provider_payout_item_id = $1
+`
+
+func (q *Queries) GetPayoutByProviderItemId(ctx context.Context, providerPayoutItemID pgtype.Text) (Payout, error) {
+	row := q.db.QueryRow(ctx, getPayoutByProviderItemId, providerPayoutItemID)
+	var i Payout
+	err := row.Scan(
+		&i.ID,
+		&i.FundEnrollmentID,
+		&i.BatchID,
+		&i.AmountCents,
+		&i.Status,
+		&i.FailureReason,
+		&i.Notes,
+		&i.Description,
+		&i.PayoutDate,
+		&i.Created,
+		&i.Updated,
+		&i.ProviderPayoutItemID,
+		&i.DestinationEmail,
+		&i.ProviderFeeCents,
+		&i.StruckBy,
+		&i.StruckAt,
+	)
+	return i, err
+}
+
 const getPayoutsByBatchId = `-- name: GetPayoutsByBatchId :many
-SELECT id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents
+SELECT payout.id, payout.fund_enrollment_id, payout.batch_id, payout.amount_cents, payout.status, payout.failure_reason, payout.notes, payout.description, payout.payout_date, payout.created, payout.updated, payout.provider_payout_item_id, payout.destination_email, payout.provider_fee_cents, payout.struck_by, payout.struck_at,
+       struck.bco_name AS struck_by_name
 FROM payout
-WHERE batch_id = $1
-ORDER BY created
+         LEFT JOIN member struck ON struck.id = payout.struck_by
+WHERE payout.batch_id = $1
+ORDER BY payout.created
 `
 
-func (q *Queries) GetPayoutsByBatchId(ctx context.Context, batchID uuid.UUID) ([]Payout, error) {
+type GetPayoutsByBatchIdRow struct {
+	Payout       Payout
+	StruckByName pgtype.Text
+}
+
+// The name of whoever struck a payout comes with it, so the batch page can say
+// who took it out without a lookup per row. LEFT, because most payouts were
+// never struck.
+func (q *Queries) GetPayoutsByBatchId(ctx context.Context, batchID uuid.UUID) ([]GetPayoutsByBatchIdRow, error) {
 	rows, err := q.db.Query(ctx, getPayoutsByBatchId, batchID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Payout
+	var items []GetPayoutsByBatchIdRow
 	for rows.Next() {
-		var i Payout
+		var i GetPayoutsByBatchIdRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.FundEnrollmentID,
-			&i.BatchID,
-			&i.AmountCents,
-			&i.Status,
-			&i.FailureReason,
-			&i.Notes,
-			&i.Description,
-			&i.PayoutDate,
-			&i.Created,
-			&i.Updated,
-			&i.ProviderPayoutItemID,
-			&i.DestinationEmail,
-			&i.ProviderFeeCents,
+			&i.Payout.ID,
+			&i.Payout.FundEnrollmentID,
+			&i.Payout.BatchID,
+			&i.Payout.AmountCents,
+			&i.Payout.Status,
+			&i.Payout.FailureReason,
+			&i.Payout.Notes,
+			&i.Payout.Description,
+			&i.Payout.PayoutDate,
+			&i.Payout.Created,
+			&i.Payout.Updated,
+			&i.Payout.ProviderPayoutItemID,
+			&i.Payout.DestinationEmail,
+			&i.Payout.ProviderFeeCents,
+			&i.Payout.StruckBy,
+			&i.Payout.StruckAt,
+			&i.StruckByName,
 		); err != nil {
 			return nil, err
 		}
@@ -717,26 +1061,61 @@ func (q *Queries) GetPayoutsByBatchId(ctx context.Context, batchID uuid.UUID) ([
 	return items, nil
 }
 
+const insertBatchApproval = `-- name: InsertBatchApproval :one
+INSERT INTO batch_approval (id, batch_id, approver_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (batch_id, approver_id) DO NOTHING
+RETURNING id, batch_id, approver_id, created
+`
+
+type InsertBatchApprovalParams struct {
+	ID         uuid.UUID
+	BatchID    uuid.UUID
+	ApproverID uuid.UUID
+}
+
+// No row back when this admin has already approved the batch.
+func (q *Queries) InsertBatchApproval(ctx context.Context, arg InsertBatchApprovalParams) (BatchApproval, error) {
+	row := q.db.QueryRow(ctx, insertBatchApproval, arg.ID, arg.BatchID, arg.ApproverID)
+	var i BatchApproval
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.ApproverID,
+		&i.Created,
+	)
+	return i, err
+}
+
 const insertBatchPayout = `-- name: InsertBatchPayout :one
 INSERT INTO batch_payout (id, fund_id, amount_cents, num_enrollments, status, description, notes,
-                          payout_date, sender_batch_id, approval_deadline)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+                          payout_date, sender_batch_id, approval_deadline, approvals_required)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+        CASE
+           WHEN $3::int > COALESCE((SELECT f.second_approval_above_cents FROM fund f WHERE f.id = $2),
+                                    $11::int) THEN 2
+           ELSE 1
+    END)
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 `
 
 type InsertBatchPayoutParams struct {
-	ID               uuid.UUID
-	FundID           uuid.UUID
-	AmountCents      int32
-	NumEnrollments   int32
-	Status           PayoutStatus
-	Description      pgtype.Text
-	Notes            pgtype.Text
-	PayoutDate       pgtype.Timestamptz
-	SenderBatchID    uuid.UUID
-	ApprovalDeadline NullDBTime
+	ID                              uuid.UUID
+	FundID                          uuid.UUID
+	AmountCents                     int32
+	NumEnrollments                  int32
+	Status                          PayoutStatus
+	Description                     pgtype.Text
+	Notes                           pgtype.Text
+	PayoutDate                      pgtype.Timestamptz
+	SenderBatchID                   uuid.UUID
+	ApprovalDeadline                NullDBTime
+	DefaultSecondApprovalAboveCents pgtype.Int4
 }
 
+// approvals_required is decided here rather than by the caller, from the same
+// expression the recount and the approval use, so there is one definition of
+// "large" and the fund's own threshold always beats the site's.
 func (q *Queries) InsertBatchPayout(ctx context.Context, arg InsertBatchPayoutParams) (BatchPayout, error) {
 	row := q.db.QueryRow(ctx, insertBatchPayout,
 		arg.ID,
@@ -749,6 +1128,7 @@ func (q *Queries) InsertBatchPayout(ctx context.Context, arg InsertBatchPayoutPa
 		arg.PayoutDate,
 		arg.SenderBatchID,
 		arg.ApprovalDeadline,
+		arg.DefaultSecondApprovalAboveCents,
 	)
 	var i BatchPayout
 	err := row.Scan(
@@ -769,6 +1149,8 @@ func (q *Queries) InsertBatchPayout(ctx context.Context, arg InsertBatchPayoutPa
 		&i.ApprovedBy,
 		&i.ApprovedAt,
 		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
 	)
 	return i, err
 }
@@ -777,7 +1159,7 @@ const insertPayout = `-- name: InsertPayout :one
 INSERT INTO payout (id, fund_enrollment_id, batch_id, amount_cents, status, description, notes,
                     payout_date, destination_email)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents
+RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents, struck_by, struck_at
 `
 
 type InsertPayoutParams struct {
@@ -820,6 +1202,8 @@ func (q *Queries) InsertPayout(ctx context.Context, arg InsertPayoutParams) (Pay
 		&i.ProviderPayoutItemID,
 		&i.DestinationEmail,
 		&i.ProviderFeeCents,
+		&i.StruckBy,
+		&i.StruckAt,
 	)
 	return i, err
 }
@@ -839,12 +1223,72 @@ func (q *Queries) IsFundActive(ctx context.Context, id uuid.UUID) (bool, error)
 	return active, err
 }
 
+const isMemberEnrolledInFund = `-- name: IsMemberEnrolledInFund :one
+SELECT EXISTS (SELECT 1
+               FROM fund_enrollment
+               WHERE fund_id = $1
+                 AND member_id = $2) AS enrolled
+`
+
+type IsMemberEnrolledInFundParams struct {
+	FundID   uuid.UUID
+	MemberID uuid.UUID
+}
+
+// Whether a member has ever been enrolled in a fund, active or not. An admin
+// who is or was paid by a fund does not approve its payouts: the rule is about
+// interest, and a cancelled enrollment can be re-activated.
+func (q *Queries) IsMemberEnrolledInFund(ctx context.Context, arg IsMemberEnrolledInFundParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isMemberEnrolledInFund, arg.FundID, arg.MemberID)
+	var enrolled bool
+	err := row.Scan(&enrolled)
+	return enrolled, err
+}
+
+const lockBatchPayoutAwaitingApproval = `-- name: LockBatchPayoutAwaitingApproval :one
+SELECT id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
+FROM batch_payout
+WHERE id = $1
+  AND status = 'awaiting_approval'
+FOR UPDATE
+`
+
+// Holds a batch still awaiting approval while payouts are struck from it. The
+// row lock is what keeps an approval from landing between the strike and the
+// recount: approved, the batch would be cleared for the old total.
+func (q *Queries) LockBatchPayoutAwaitingApproval(ctx context.Context, id uuid.UUID) (BatchPayout, error) {
+	row := q.db.QueryRow(ctx, lockBatchPayoutAwaitingApproval, id)
+	var i BatchPayout
+	err := row.Scan(
+		&i.ID,
+		&i.FundID,
+		&i.AmountCents,
+		&i.NumEnrollments,
+		&i.Status,
+		&i.FailureReason,
+		&i.Notes,
+		&i.Description,
+		&i.ProviderBatchID,
+		&i.PayoutDate,
+		&i.Created,
+		&i.Updated,
+		&i.SenderBatchID,
+		&i.ApprovalDeadline,
+		&i.ApprovedBy,
+		&i.ApprovedAt,
+		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
+	)
+	return i, err
+}
+
 const markBatchPayoutReminderSent = `-- name: MarkBatchPayoutReminderSent :one
 UPDATE batch_payout
 SET reminder_sent_at = now(),
     updated          = now()
 WHERE id = $1
-RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 `
 
 func (q *Queries) MarkBatchPayoutReminderSent(ctx context.Context, id uuid.UUID) (BatchPayout, error) {
@@ -868,6 +1312,52 @@ func (q *Queries) MarkBatchPayoutReminderSent(ctx context.Context, id uuid.UUID)
 		&i.ApprovedBy,
 		&i.ApprovedAt,
 		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
+	)
+	return i, err
+}
+
+const recountBatchPayout = `-- name: RecountBatchPayout :one
+UPDATE batch_payout
+SET amount_cents    = (SELECT COALESCE(SUM(p.amount_cents), 0)
+                       FROM payout p
+                       WHERE p.batch_id = batch_payout.id
+                         AND p.status <> 'struck')::int,
+    num_enrollments = (SELECT COUNT(*)
+                       FROM payout p
+                       WHERE p.batch_id = batch_payout.id
+                         AND p.status <> 'struck')::int,
+    updated         = now()
+WHERE batch_payout.id = $1
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
+`
+
+// Recomputed from what is left rather than decremented, so the header cannot
+// drift from its rows whatever happened before.
+func (q *Queries) RecountBatchPayout(ctx context.Context, id uuid.UUID) (BatchPayout, error) {
+	row := q.db.QueryRow(ctx, recountBatchPayout, id)
+	var i BatchPayout
+	err := row.Scan(
+		&i.ID,
+		&i.FundID,
+		&i.AmountCents,
+		&i.NumEnrollments,
+		&i.Status,
+		&i.FailureReason,
+		&i.Notes,
+		&i.Description,
+		&i.ProviderBatchID,
+		&i.PayoutDate,
+		&i.Created,
+		&i.Updated,
+		&i.SenderBatchID,
+		&i.ApprovalDeadline,
+		&i.ApprovedBy,
+		&i.ApprovedAt,
+		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
 	)
 	return i, err
 }
@@ -879,7 +1369,7 @@ SET status         = 'cancelled',
     updated        = now()
 WHERE id = $1
   AND status = 'awaiting_approval'
-RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 `
 
 type RejectBatchPayoutParams struct {
@@ -908,6 +1398,8 @@ func (q *Queries) RejectBatchPayout(ctx context.Context, arg RejectBatchPayoutPa
 		&i.ApprovedBy,
 		&i.ApprovedAt,
 		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
 	)
 	return i, err
 }
@@ -946,13 +1438,58 @@ func (q *Queries) RequeueOneTimeFundPayout(ctx context.Context, id uuid.UUID) (i
 	return result.RowsAffected(), nil
 }
 
+const setBatchApprovalsRequired = `-- name: SetBatchApprovalsRequired :one
+UPDATE batch_payout
+SET approvals_required = CASE
+           WHEN batch_payout.amount_cents > COALESCE((SELECT f.second_approval_above_cents FROM fund f WHERE f.id = batch_payout.fund_id),
+                                    $2::int) THEN 2
+           ELSE 1
+    END,
+    updated            = now()
+WHERE batch_payout.id = $1
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
+`
+
+type SetBatchApprovalsRequiredParams struct {
+	ID                              uuid.UUID
+	DefaultSecondApprovalAboveCents pgtype.Int4
+}
+
+// After the recount, because the threshold compares against the new total.
+func (q *Queries) SetBatchApprovalsRequired(ctx context.Context, arg SetBatchApprovalsRequiredParams) (BatchPayout, error) {
+	row := q.db.QueryRow(ctx, setBatchApprovalsRequired, arg.ID, arg.DefaultSecondApprovalAboveCents)
+	var i BatchPayout
+	err := row.Scan(
+		&i.ID,
+		&i.FundID,
+		&i.AmountCents,
+		&i.NumEnrollments,
+		&i.Status,
+		&i.FailureReason,
+		&i.Notes,
+		&i.Description,
+		&i.ProviderBatchID,
+		&i.PayoutDate,
+		&i.Created,
+		&i.Updated,
+		&i.SenderBatchID,
+		&i.ApprovalDeadline,
+		&i.ApprovedBy,
+		&i.ApprovedAt,
+		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
+	)
+	return i, err
+}
+
 const setBatchPayoutStatus = `-- name: SetBatchPayoutStatus :one
 UPDATE batch_payout
 SET status         = $2,
     failure_reason = $3,
     updated        = now()
 WHERE id = $1
-RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 `
 
 type SetBatchPayoutStatusParams struct {
@@ -982,6 +1519,8 @@ func (q *Queries) SetBatchPayoutStatus(ctx context.Context, arg SetBatchPayoutSt
 		&i.ApprovedBy,
 		&i.ApprovedAt,
 		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
 	)
 	return i, err
 }
@@ -990,10 +1529,11 @@ const setBatchPayoutSubmitted = `-- name: SetBatchPayoutSubmitted :one
 UPDATE batch_payout
 SET provider_batch_id = $2,
     status            = 'pending',
+    failure_reason    = NULL,
     updated           = now()
 WHERE id = $1
   AND status = 'ready'
-RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at
+RETURNING id, fund_id, amount_cents, num_enrollments, status, failure_reason, notes, description, provider_batch_id, payout_date, created, updated, sender_batch_id, approval_deadline, approved_by, approved_at, reminder_sent_at, approvals_required, approvals_given
 `
 
 type SetBatchPayoutSubmittedParams struct {
@@ -1002,7 +1542,8 @@ type SetBatchPayoutSubmittedParams struct {
 }
 
 // Records the provider's batch ID and moves the batch to 'pending'. Constrained to
-// 'ready' so a submission can only ever happen once per approved batch.
+// 'ready' so a submission can only ever happen once per approved batch. Clears
+// the reason a refused attempt left, which no longer applies once it is taken.
 func (q *Queries) SetBatchPayoutSubmitted(ctx context.Context, arg SetBatchPayoutSubmittedParams) (BatchPayout, error) {
 	row := q.db.QueryRow(ctx, setBatchPayoutSubmitted, arg.ID, arg.ProviderBatchID)
 	var i BatchPayout
@@ -1024,16 +1565,113 @@ func (q *Queries) SetBatchPayoutSubmitted(ctx context.Context, arg SetBatchPayou
 		&i.ApprovedBy,
 		&i.ApprovedAt,
 		&i.ReminderSentAt,
+		&i.ApprovalsRequired,
+		&i.ApprovalsGiven,
+	)
+	return i, err
+}
+
+const setEnrollmentPayoutShare = `-- name: SetEnrollmentPayoutShare :one
+UPDATE fund_enrollment
+SET payout_amount_cents = $2,
+    payout_weight       = $3,
+    updated             = now()
+WHERE id = $1
+RETURNING id, fund_id, member_id, member_bco_name, first_payout_date, active, created, updated, paypal_email, payout_amount_cents, payout_weight
+`
+
+type SetEnrollmentPayoutShareParams struct {
+	ID                uuid.UUID
+	PayoutAmountCents pgtype.Int4
+	PayoutWeight      int32
+}
+
+func (q *Queries) SetEnrollmentPayoutShare(ctx context.Context, arg SetEnrollmentPayoutShareParams) (FundEnrollment, error) {
+	row := q.db.QueryRow(ctx, setEnrollmentPayoutShare, arg.ID, arg.PayoutAmountCents, arg.PayoutWeight)
+	var i FundEnrollment
+	err := row.Scan(
+		&i.ID,
+		&i.FundID,
+		&i.MemberID,
+		&i.MemberBcoName,
+		&i.FirstPayoutDate,
+		&i.Active,
+		&i.Created,
+		&i.Updated,
+		&i.PaypalEmail,
+		&i.PayoutAmountCents,
+		&i.PayoutWeight,
+	)
+	return i, err
+}
+
+const setFundPayoutAllocation = `-- name: SetFundPayoutAllocation :one
+UPDATE fund
+SET payout_allocation = $2,
+    payout_cap_cents  = $3,
+    updated           = now()
+WHERE id = $1
+RETURNING id, name, payout_allocation, payout_cap_cents
+`
+
+type SetFundPayoutAllocationParams struct {
+	ID               uuid.UUID
+	PayoutAllocation PayoutAllocation
+	PayoutCapCents   pgtype.Int4
+}
+
+type SetFundPayoutAllocationRow struct {
+	ID               uuid.UUID
+	Name             string
+	PayoutAllocation PayoutAllocation
+	PayoutCapCents   pgtype.Int4
+}
+
+// How the fund divides its payouts. Returns what was stored so the caller shows
+// the setting as it now is, not as it was asked for.
+func (q *Queries) SetFundPayoutAllocation(ctx context.Context, arg SetFundPayoutAllocationParams) (SetFundPayoutAllocationRow, error) {
+	row := q.db.QueryRow(ctx, setFundPayoutAllocation, arg.ID, arg.PayoutAllocation, arg.PayoutCapCents)
+	var i SetFundPayoutAllocationRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.PayoutAllocation,
+		&i.PayoutCapCents,
 	)
 	return i, err
 }
 
+const setFundSecondApprovalAbove = `-- name: SetFundSecondApprovalAbove :one
+UPDATE fund
+SET second_approval_above_cents = $2,
+    updated                     = now()
+WHERE id = $1
+RETURNING id, second_approval_above_cents
+`
+
+type SetFundSecondApprovalAboveParams struct {
+	ID                       uuid.UUID
+	SecondApprovalAboveCents pgtype.Int4
+}
+
+type SetFundSecondApprovalAboveRow struct {
+	ID                       uuid.UUID
+	SecondApprovalAboveCents pgtype.Int4
+}
+
+func (q *Queries) SetFundSecondApprovalAbove(ctx context.Context, arg SetFundSecondApprovalAboveParams) (SetFundSecondApprovalAboveRow, error) {
+	row := q.db.QueryRow(ctx, setFundSecondApprovalAbove, arg.ID, arg.SecondApprovalAboveCents)
+	var i SetFundSecondApprovalAboveRow
+	err := row.Scan(&i.ID, &i.SecondApprovalAboveCents)
+	return i, err
+}
+
 const setPayoutProviderItemId = `-- name: SetPayoutProviderItemId :one
 UPDATE payout
 SET provider_payout_item_id = $2,
     updated                 = now()
 WHERE id = $1
-RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents
+RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents, struck_by, struck_at
 `
 
 type SetPayoutProviderItemIdParams struct {
@@ -1059,6 +1697,8 @@ func (q *Queries) SetPayoutProviderItemId(ctx context.Context, arg SetPayoutProv
 		&i.ProviderPayoutItemID,
 		&i.DestinationEmail,
 		&i.ProviderFeeCents,
+		&i.StruckBy,
+		&i.StruckAt,
 	)
 	return i, err
 }
@@ -1071,7 +1711,7 @@ SET provider_payout_item_id = $2,
     provider_fee_cents      = $5,
     updated                 = now()
 WHERE id = $1
-RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents
+RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents, struck_by, struck_at
 `
 
 type SetPayoutResultByIdParams struct {
@@ -1110,6 +1750,8 @@ func (q *Queries) SetPayoutResultById(ctx context.Context, arg SetPayoutResultBy
 		&i.ProviderPayoutItemID,
 		&i.DestinationEmail,
 		&i.ProviderFeeCents,
+		&i.StruckBy,
+		&i.StruckAt,
 	)
 	return i, err
 }
@@ -1121,7 +1763,7 @@ SET status           = $2,
     provider_fee_cents = $4,
     updated          = now()
 WHERE provider_payout_item_id = $1
-RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents
+RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents, struck_by, struck_at
 `
 
 type SetPayoutStatusByProviderItemIdParams struct {
@@ -1155,6 +1797,59 @@ func (q *Queries) SetPayoutStatusByProviderItemId(ctx context.Context, arg SetPa
 		&i.ProviderPayoutItemID,
 		&i.DestinationEmail,
 		&i.ProviderFeeCents,
+		&i.StruckBy,
+		&i.StruckAt,
+	)
+	return i, err
+}
+
+const strikePayout = `-- name: StrikePayout :one
+UPDATE payout
+SET status         = 'struck',
+    failure_reason = $3,
+    struck_by      = $4,
+    struck_at      = now(),
+    updated        = now()
+WHERE id = $1
+  AND batch_id = $2
+  AND status <> 'struck'
+RETURNING id, fund_enrollment_id, batch_id, amount_cents, status, failure_reason, notes, description, payout_date, created, updated, provider_payout_item_id, destination_email, provider_fee_cents, struck_by, struck_at
+`
+
+type StrikePayoutParams struct {
+	ID            uuid.UUID
+	BatchID       uuid.UUID
+	FailureReason pgtype.Text
+	StruckBy      uuid.NullUUID
+}
+
+// Bounded by batch so a payout id from another batch strikes nothing, and by
+// status so striking twice does not overwrite who did it first.
+func (q *Queries) StrikePayout(ctx context.Context, arg StrikePayoutParams) (Payout, error) {
+	row := q.db.QueryRow(ctx, strikePayout,
+		arg.ID,
+		arg.BatchID,
+		arg.FailureReason,
+		arg.StruckBy,
+	)
+	var i Payout
+	err := row.Scan(
+		&i.ID,
+		&i.FundEnrollmentID,
+		&i.BatchID,
+		&i.AmountCents,
+		&i.Status,
+		&i.FailureReason,
+		&i.Notes,
+		&i.Description,
+		&i.PayoutDate,
+		&i.Created,
+		&i.Updated,
+		&i.ProviderPayoutItemID,
+		&i.DestinationEmail,
+		&i.ProviderFeeCents,
+		&i.StruckBy,
+		&i.StruckAt,
 	)
 	return i, err
 }
@@ -7,8 +7,141 @@ package db
 
 import (
 	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const archiveWebhookEvent = `-- name: ArchiveWebhookEvent :exec
+INSERT INTO webhook_event (transmission_id, event_id, event_type, resource_type, resource_id, summary, payload,
+                           cert_url, certificate_chained, chain_error, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::text, $11::timestamptz)
+`
+
+type ArchiveWebhookEventParams struct {
+	TransmissionID     string
+	EventID            string
+	EventType          string
+	ResourceType       string
+	ResourceID         string
+	Summary            string
+	Payload            []byte
+	CertUrl            string
+	CertificateChained bool
+	ChainError         pgtype.Text
+	CreatedAt          pgtype.Timestamptz
+}
+
+// Keeps what a newly accepted webhook carried. Called only for a transmission
+// RecordWebhookDelivery has just reported new, in the same transaction, so there
+// is no conflict to handle: a replay never gets this far.
+func (q *Queries) ArchiveWebhookEvent(ctx context.Context, arg ArchiveWebhookEventParams) error {
+	_, err := q.db.Exec(ctx, archiveWebhookEvent,
+		arg.TransmissionID,
+		arg.EventID,
+		arg.EventType,
+		arg.ResourceType,
+		arg.ResourceID,
+		arg.Summary,
+		arg.Payload,
+		arg.CertUrl,
+		arg.CertificateChained,
+		arg.ChainError,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const getWebhookEvent = `-- name: GetWebhookEvent :one
+SELECT transmission_id, event_id, event_type, resource_type, resource_id, summary, payload, cert_url, certificate_chained, chain_error, created_at, received_at
+FROM webhook_event
+WHERE transmission_id = $1
+`
+
+func (q *Queries) GetWebhookEvent(ctx context.Context, transmissionID string) (WebhookEvent, error) {
+	row := q.db.QueryRow(ctx, getWebhookEvent, transmissionID)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.TransmissionID,
+		&i.EventID,
+		&i.EventType,
+		&i.ResourceType,
+		&i.ResourceID,
+		&i.Summary,
+		&i.Payload,
+		&i.CertUrl,
+		&i.CertificateChained,
+		&i.ChainError,
+		&i.CreatedAt,
+		&i.ReceivedAt,
+	)
+	return i, err
+}
+
+const getWebhookEventOutcomes = `-- name: GetWebhookEventOutcomes :many
+SELECT id, transmission_id, consumer, stream_seq, delivery, outcome, error, occurred_at
+FROM webhook_event_outcome
+WHERE transmission_id = $1
+ORDER BY occurred_at, delivery
+`
+
+func (q *Queries) GetWebhookEventOutcomes(ctx context.Context, transmissionID string) ([]WebhookEventOutcome, error) {
+	rows, err := q.db.Query(ctx, getWebhookEventOutcomes, transmissionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEventOutcome
+	for rows.Next() {
+		var i WebhookEventOutcome
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransmissionID,
+			&i.Consumer,
+			&i.StreamSeq,
+			&i.Delivery,
+			&i.Outcome,
+			&i.Error,
+			&i.OccurredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookEventTypes = `-- name: GetWebhookEventTypes :many
+SELECT DISTINCT event_type
+FROM webhook_event
+ORDER BY event_type
+`
+
+// What the type filter offers: every type that has been archived, rather than a
+// list kept in code that falls behind the first time PayPal sends something new.
+func (q *Queries) GetWebhookEventTypes(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, getWebhookEventTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var event_type string
+		if err := rows.Scan(&event_type); err != nil {
+			return nil, err
+		}
+		items = append(items, event_type)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const recordWebhookDelivery = `-- name: RecordWebhookDelivery :many
 INSERT INTO webhook_delivery (transmission_id, event_type)
 VALUES ($1, $2)
@@ -44,3 +177,120 @@ func (q *Queries) RecordWebhookDelivery(ctx context.Context, arg RecordWebhookDe
 	}
 	return items, nil
 }
+
+const recordWebhookOutcome = `-- name: RecordWebhookOutcome :exec
+INSERT INTO webhook_event_outcome (id, transmission_id, consumer, stream_seq, delivery, outcome, error)
+SELECT $1, $2, $3, $4, $5, $6, $7::text
+WHERE EXISTS (SELECT 1 FROM webhook_event WHERE transmission_id = $2)
+ON CONFLICT (transmission_id, consumer, stream_seq, delivery) DO NOTHING
+`
+
+type RecordWebhookOutcomeParams struct {
+	ID             uuid.UUID
+	TransmissionID string
+	Consumer       string
+	StreamSeq      int64
+	Delivery       int32
+	Outcome        WebhookOutcome
+	Error          pgtype.Text
+}
+
+// One attempt by one consumer. DO NOTHING, because a report is made after the
+// handler runs and before the ack, and a lost ack can make it twice.
+//
+// Only for an archived event. Messages published before the archive existed
+// are still in the stream, and a dead letter from then can still be replayed;
+// their attempts have nothing to be filed against and are left out rather than
+// failing the foreign key.
+func (q *Queries) RecordWebhookOutcome(ctx context.Context, arg RecordWebhookOutcomeParams) error {
+	_, err := q.db.Exec(ctx, recordWebhookOutcome,
+		arg.ID,
+		arg.TransmissionID,
+		arg.Consumer,
+		arg.StreamSeq,
+		arg.Delivery,
+		arg.Outcome,
+		arg.Error,
+	)
+	return err
+}
+
+const searchWebhookEvents = `-- name: SearchWebhookEvents :many
+SELECT e.transmission_id,
+       e.event_type,
+       e.resource_type,
+       e.resource_id,
+       e.summary,
+       e.certificate_chained,
+       e.received_at,
+       (SELECT count(*) FROM webhook_event_outcome o
+        WHERE o.transmission_id = e.transmission_id AND o.outcome = 'handled')::int AS handled,
+       (SELECT count(*) FROM webhook_event_outcome o
+        WHERE o.transmission_id = e.transmission_id AND o.outcome <> 'handled')::int AS failed
+FROM webhook_event e
+WHERE ($1::text IS NULL OR e.event_type = $1::text)
+  AND ($2::text IS NULL OR e.resource_id = $2::text)
+  AND ($3::timestamptz IS NULL OR e.received_at >= $3::timestamptz)
+  AND ($4::timestamptz IS NULL OR e.received_at < $4::timestamptz)
+ORDER BY e.received_at DESC
+LIMIT $5
+`
+
+type SearchWebhookEventsParams struct {
+	EventType     pgtype.Text
+	ResourceID    pgtype.Text
+	ReceivedFrom  pgtype.Timestamptz
+	ReceivedUntil pgtype.Timestamptz
+	RowLimit      int32
+}
+
+type SearchWebhookEventsRow struct {
+	TransmissionID     string
+	EventType          string
+	ResourceType       string
+	ResourceID         string
+	Summary            string
+	CertificateChained bool
+	ReceivedAt         pgtype.Timestamptz
+	Handled            int32
+	Failed             int32
+}
+
+// The admin viewer's list. Each filter is skipped when null, and the counts are
+// of attempts, so an event that failed twice and then went through reads as
+// such without opening it.
+func (q *Queries) SearchWebhookEvents(ctx context.Context, arg SearchWebhookEventsParams) ([]SearchWebhookEventsRow, error) {
+	rows, err := q.db.Query(ctx, searchWebhookEvents,
+		arg.EventType,
+		arg.ResourceID,
+		arg.ReceivedFrom,
+		arg.ReceivedUntil,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchWebhookEventsRow
+	for rows.Next() {
+		var i SearchWebhookEventsRow
+		if err := rows.Scan(
+			&i.TransmissionID,
+			&i.EventType,
+			&i.ResourceType,
+			&i.ResourceID,
+			&i.Summary,
+			&i.CertificateChained,
+			&i.ReceivedAt,
+			&i.Handled,
+			&i.Failed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,14 +7,19 @@ import (
 	"time"
 )
 
-// AdminGroup is the Cognito group that grants admin access. It is the single
-// source of truth for that decision: the member.roles column plays no part, and
+// AdminGroup is the Cognito group that grants admin access: all of it, where
+// the other roles in roles.go grant part. Group membership is the single source
+// of truth for that decision: the member.roles column plays no part, and
 // nothing in the application ever writes ADMIN to it.
 const AdminGroup = "bco-admin-group"
 
 // GroupsClaim is where Cognito puts group membership on the ID token.
 const GroupsClaim = "cognito:groups"
 
+// UsernameClaim is where Cognito puts the username on the ID token, which is
+// the member's bco name.
+const UsernameClaim = "cognito:username"
+
 type Token struct {
 	keyset jwk.Set
 }
@@ -40,6 +45,14 @@ func HasGroup(claims map[string]any, group string) bool {
 	return false
 }
 
+// Username is the username a parsed token was issued to, or empty when it
+// carries none.
+func Username(claims map[string]any) string {
+	username, _ := claims[UsernameClaim].(string)
+
+	return username
+}
+
 func NewToken(keyset jwk.Set) *Token {
 	return &Token{keyset: keyset}
 }
@@ -58,6 +71,8 @@ func (t *Token) Verify(tokenStr string) (jwt.Token, error) {
 	return parsedToken, nil
 }
 
+// VerifyAdmin is Verify for the admin pages, which any role is let into. What
+// each role may do once there is for the route to check; see Allows.
 func (t *Token) VerifyAdmin(tokenStr string) (jwt.Token, error) {
 	parsedToken, err := jwt.ParseString(
 		tokenStr,
@@ -69,7 +84,7 @@ func (t *Token) VerifyAdmin(tokenStr string) (jwt.Token, error) {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
-	if !HasGroup(parsedToken.PrivateClaims(), AdminGroup) {
+	if !HasAnyRole(parsedToken.PrivateClaims()) {
 		return nil, fmt.Errorf("not an admin")
 	}
 
@@ -0,0 +1,160 @@
+package jwtauth
+
+// The groups below AdminGroup each grant part of what it does. A member may be
+// in any number of them; what they may do is everything any of their groups
+// allows.
+const (
+	TreasurerGroup   = "bco-treasurer-group"
+	ModeratorGroup   = "bco-moderator-group"
+	FundManagerGroup = "bco-fund-manager-group"
+)
+
+// Permission is something an admin page does. Routes ask for one; roles are
+// the lists of them a group grants.
+type Permission string
+
+const (
+	// ViewAdmin is the admin pages themselves: reading funds, members, payouts and
+	// the audit log. Every role has it, because every role needs somewhere to do
+	// its part from.
+	ViewAdmin Permission = "view_admin"
+
+	// ApprovePayouts is approving, rejecting and striking payouts, which is what
+	// sends an approved batch to PayPal, and crediting unmatched payments.
+	ApprovePayouts Permission = "approve_payouts"
+
+	// ModerateNotes is removing notes and posting notices.
+	ModerateNotes Permission = "moderate_notes"
+
+	// ManageFunds is editing a fund and its enrollments. A fund manager has it only
+	// for the funds they are assigned; see Role.PerFund.
+	ManageFunds Permission = "manage_funds"
+
+	// CreateFunds is opening a fund, and closing one. Separate from ManageFunds
+	// because a new fund has nobody assigned to it yet, and because closing a
+	// fund cancels every donor's subscription to it: that is not the day-to-day
+	// running a fund manager is trusted with.
+	CreateFunds Permission = "create_funds"
+
+	// ManageMembers is granting and revoking roles, deactivating members,
+	// approving addresses to register and resetting two-factor. Whoever has it
+	// can give themselves everything else, so only AdminGroup does.
+	ManageMembers Permission = "manage_members"
+
+	// OperateWebhooks is the webhook bus and the PayPal health page, and replaying
+	// a dead letter -- which redelivers a payment to whatever consumes it.
+	OperateWebhooks Permission = "operate_webhooks"
+)
+
+// Role is a group and what it grants.
+type Role struct {
+	Group string
+
+	// Name is what the pages and the audit log call it.
+	Name string
+
+	Permissions []Permission
+
+	// PerFund means the role's ManageFunds holds only for the funds its member is
+	// assigned, which the token cannot say: it is looked up per request.
+	PerFund bool
+}
+
+// Roles are every role there is, AdminGroup first.
+var Roles = []Role{
+	{
+		Group: AdminGroup,
+		Name:  "admin",
+		Permissions: []Permission{
+			ViewAdmin, ApprovePayouts, ModerateNotes, ManageFunds, CreateFunds, ManageMembers, OperateWebhooks,
+		},
+	},
+	{
+		Group:       TreasurerGroup,
+		Name:        "treasurer",
+		Permissions: []Permission{ViewAdmin, ApprovePayouts},
+	},
+	{
+		Group:       ModeratorGroup,
+		Name:        "moderator",
+		Permissions: []Permission{ViewAdmin, ModerateNotes},
+	},
+	{
+		Group:       FundManagerGroup,
+		Name:        "fund manager",
+		Permissions: []Permission{ViewAdmin, ManageFunds},
+		PerFund:     true,
+	},
+}
+
+// RoleByGroup is the role for group, and whether there is one.
+func RoleByGroup(group string) (Role, bool) {
+	for _, role := range Roles {
+		if role.Group == group {
+			return role, true
+		}
+	}
+
+	return Role{}, false
+}
+
+// RoleGroups are the groups of every role, for whoever must treat them alike:
+// the admin pages let any of them in, and two-factor is asked of all of them.
+func RoleGroups() []string {
+	groups := make([]string, len(Roles))
+	for i, role := range Roles {
+		groups[i] = role.Group
+	}
+
+	return groups
+}
+
+// Grant is what a token's groups allow of one permission.
+type Grant struct {
+	Allowed bool
+
+	// PerFund means it is allowed only through a per-fund role, so the caller
+	// must still check the fund in question is one of the member's.
+	PerFund bool
+}
+
+// Allows reports what a parsed token's groups allow of permission. A role that
+// grants it everywhere wins over one that grants it per fund.
+func Allows(claims map[string]any, permission Permission) Grant {
+	var grant Grant
+
+	for _, role := range Roles {
+		if !HasGroup(claims, role.Group) || !role.has(permission) {
+			continue
+		}
+
+		if !role.PerFund {
+			return Grant{Allowed: true}
+		}
+
+		grant = Grant{Allowed: true, PerFund: true}
+	}
+
+	return grant
+}
+
+// HasAnyRole reports whether a parsed token carries any role's group.
+func HasAnyRole(claims map[string]any) bool {
+	for _, role := range Roles {
+		if HasGroup(claims, role.Group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Role) has(permission Permission) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
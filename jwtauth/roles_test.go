@@ -0,0 +1,65 @@
+package jwtauth
+
+import "testing"
+
+func groups(names ...string) map[string]any {
+	list := make([]any, len(names))
+	for i, name := range names {
+		list[i] = name
+	}
+
+	return map[string]any{GroupsClaim: list}
+}
+
+func TestAllows(t *testing.T) {
+	cases := []struct {
+		name       string
+		claims     map[string]any
+		permission Permission
+		want       Grant
+	}{
+		{"admin has everything", groups(AdminGroup), ManageMembers, Grant{Allowed: true}},
+		{"treasurer approves payouts", groups(TreasurerGroup), ApprovePayouts, Grant{Allowed: true}},
+		{"treasurer does not moderate", groups(TreasurerGroup), ModerateNotes, Grant{}},
+		{"moderator does not approve payouts", groups(ModeratorGroup), ApprovePayouts, Grant{}},
+		{"no role, no grant", groups("donors"), ViewAdmin, Grant{}},
+		// A fund manager's grant is only as good as the fund it is used on, which
+		// the caller has to check.
+		{"fund manager is per fund", groups(FundManagerGroup), ManageFunds, Grant{Allowed: true, PerFund: true}},
+		{"fund manager cannot open or close funds", groups(FundManagerGroup), CreateFunds, Grant{}},
+		// Otherwise an admin who also manages a fund would lose every other fund.
+		{"an unscoped role wins", groups(FundManagerGroup, AdminGroup), ManageFunds, Grant{Allowed: true}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Allows(c.claims, c.permission); got != c.want {
+				t.Errorf("Allows = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+// ManageMembers is granting roles. Any role with it can make itself admin, so
+// anything but the admin role having it would make the split pointless.
+func TestOnlyAdminManagesMembers(t *testing.T) {
+	for _, role := range Roles {
+		if role.Group != AdminGroup && role.has(ManageMembers) {
+			t.Errorf("%s can manage members", role.Name)
+		}
+	}
+}
+
+// Every role is let into the admin pages by VerifyAdmin, so every role must be
+// able to see them.
+func TestEveryRoleViewsAdmin(t *testing.T) {
+	for _, role := range Roles {
+		if !role.has(ViewAdmin) {
+			t.Errorf("%s cannot view the admin pages", role.Name)
+		}
+
+		if !HasAnyRole(groups(role.Group)) {
+			t.Errorf("%s is not a role to HasAnyRole", role.Name)
+		}
+	}
+}
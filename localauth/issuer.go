@@ -0,0 +1,304 @@
+package localauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// TokenLifetime is how long an issued token is good for. Cognito's default for
+// an ID token, so a session lasts as long under either authorizer.
+const TokenLifetime = time.Hour
+
+// RotateEvery is how long one key signs before the next takes over.
+const RotateEvery = 30 * 24 * time.Hour
+
+// SigningKey is one key as kept in signing_key: the private JWK, serialised.
+type SigningKey struct {
+	KID        string
+	PrivateJWK []byte
+	Created    time.Time
+}
+
+type keyStore interface {
+	GetSigningKeys(ctx context.Context) ([]SigningKey, error)
+	InsertSigningKey(ctx context.Context, key SigningKey) (*SigningKey, error)
+	DeleteSigningKeysBefore(ctx context.Context, before time.Time) error
+}
+
+// Issuer signs the tokens the local authorizer hands out, and publishes the keys
+// that check them.
+//
+// The newest key signs. The one before it is still published until every token
+// it signed has expired, which is TokenLifetime after its successor took over;
+// after that it is deleted. A verifier holding PublicKeys therefore accepts
+// every live token across a rotation, and nothing older.
+//
+// The web server is one process -- it embeds its own NATS -- so rotation is
+// decided here and reloaded from the table, with no second instance to agree
+// with. The table is what makes a restart harmless.
+type Issuer struct {
+	keys   keyStore
+	logger *slog.Logger
+
+	issuer string
+	now    func() time.Time
+
+	mu      sync.RWMutex
+	signing jwk.Key
+
+	// public is handed to jwtauth.NewToken once and changed in place, so the
+	// verifier sees a rotation without being rebuilt. jwk.Set locks itself.
+	public jwk.Set
+}
+
+// NewIssuer loads the stored keys, making the first one if there are none.
+// issuer is the iss claim, the site's public URL.
+func NewIssuer(ctx context.Context, keys keyStore, issuer string, logger *slog.Logger) (*Issuer, error) {
+	i := &Issuer{
+		keys:   keys,
+		logger: logger,
+		issuer: issuer,
+		now:    time.Now,
+		public: jwk.NewSet(),
+	}
+
+	if err := i.Rotate(ctx); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// PublicKeys is the set a jwtauth.Token verifies local tokens against. It is
+// the same set the JWKS endpoint serves.
+func (i *Issuer) PublicKeys() jwk.Set {
+	return i.public
+}
+
+// Rotate makes a new signing key when the current one is RotateEvery old, or
+// when there is none, and drops keys nothing live was signed with. Safe to call
+// as often as you like; most calls change nothing.
+func (i *Issuer) Rotate(ctx context.Context) error {
+	stored, err := i.keys.GetSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get signing keys: %w", err)
+	}
+
+	// Newest first, so the head is the key that signs.
+	if len(stored) == 0 || i.now().Sub(stored[0].Created) >= RotateEvery {
+		fresh, errNew := newSigningKey(i.now())
+		if errNew != nil {
+			return errNew
+		}
+
+		inserted, errInsert := i.keys.InsertSigningKey(ctx, *fresh)
+		if errInsert != nil {
+			return fmt.Errorf("failed to store signing key: %w", errInsert)
+		}
+
+		i.logger.InfoContext(ctx, "rotated the token signing key", slog.String("kid", inserted.KID))
+
+		stored = append([]SigningKey{*inserted}, stored...)
+	}
+
+	current := stored[0]
+
+	// Everything older than the current key signed its last token when the
+	// current key was made. Once those tokens have expired it has nothing left
+	// to verify.
+	if len(stored) > 1 && i.now().Sub(current.Created) > TokenLifetime {
+		if err = i.keys.DeleteSigningKeysBefore(ctx, current.Created); err != nil {
+			return fmt.Errorf("failed to delete retired signing keys: %w", err)
+		}
+
+		stored = stored[:1]
+	}
+
+	return i.load(stored)
+}
+
+// load makes stored the keys in use, the first of them signing.
+func (i *Issuer) load(stored []SigningKey) error {
+	keys := make([]jwk.Key, 0, len(stored))
+
+	for _, s := range stored {
+		key, err := jwk.ParseKey(s.PrivateJWK)
+		if err != nil {
+			return fmt.Errorf("failed to parse signing key %s: %w", s.KID, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	public := make([]jwk.Key, 0, len(keys))
+
+	for _, key := range keys {
+		pub, err := key.PublicKey()
+		if err != nil {
+			return fmt.Errorf("failed to derive public key: %w", err)
+		}
+
+		public = append(public, pub)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.signing = keys[0]
+
+	// Added before anything is removed, so a token signed by either key checks
+	// throughout.
+	for _, pub := range public {
+		if _, ok := i.public.LookupKeyID(pub.KeyID()); !ok {
+			if err := i.public.AddKey(pub); err != nil {
+				return err
+			}
+		}
+	}
+
+	for n := i.public.Len() - 1; n >= 0; n-- {
+		key, _ := i.public.Key(n)
+		if !containsKID(public, key.KeyID()) {
+			_ = i.public.RemoveKey(key)
+		}
+	}
+
+	return nil
+}
+
+// Run rotates on a schedule until ctx is done. Hourly is far more often than
+// RotateEvery needs, and is what keeps a retired key from outliving
+// TokenLifetime by much.
+func (i *Issuer) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := i.Rotate(ctx); err != nil {
+				// The current key goes on signing, so this is late rather than
+				// broken. The next tick tries again.
+				i.logger.ErrorContext(ctx, "failed to rotate signing keys", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Issue signs a token carrying claims, valid from now for TokenLifetime.
+func (i *Issuer) Issue(subject string, claims map[string]any) (string, time.Time, error) {
+	i.mu.RLock()
+	key := i.signing
+	i.mu.RUnlock()
+
+	if key == nil {
+		return "", time.Time{}, errors.New("no signing key")
+	}
+
+	now := i.now()
+	expires := now.Add(TokenLifetime)
+
+	token := jwt.New()
+	for name, value := range map[string]any{
+		jwt.SubjectKey:    subject,
+		jwt.IssuerKey:     i.issuer,
+		jwt.IssuedAtKey:   now,
+		jwt.NotBeforeKey:  now,
+		jwt.ExpirationKey: expires,
+		jwt.JwtIDKey:      uuid.NewString(),
+	} {
+		if err := token.Set(name, value); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+
+	for name, value := range claims {
+		if err := token.Set(name, value); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.RS256, key))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return string(signed), expires, nil
+}
+
+// ServeJWKS publishes the public keys, in the same shape Cognito serves at
+// /.well-known/jwks.json, so anything that can verify a Cognito token can verify
+// a local one by pointing at this instead.
+func (i *Issuer) ServeJWKS(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(i.public)
+	if err != nil {
+		i.logger.ErrorContext(r.Context(), "failed to marshal key set", slog.String("error", err.Error()))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Short, because a rotation publishes a key that signs at once. A reader that
+	// cached for longer would refuse tokens signed by it until its copy expired.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_, _ = w.Write(body)
+}
+
+func newSigningKey(now time.Time) (*SigningKey, error) {
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := uuid.NewString()
+
+	// The algorithm is on the key because jwtauth verifies with WithKeySet, which
+	// will not guess one.
+	for name, value := range map[string]any{
+		jwk.KeyIDKey:     kid,
+		jwk.AlgorithmKey: jwa.RS256,
+		jwk.KeyUsageKey:  jwk.ForSignature,
+	} {
+		if err = key.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	serialised, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{KID: kid, PrivateJWK: serialised, Created: now}, nil
+}
+
+func containsKID(keys []jwk.Key, kid string) bool {
+	for _, key := range keys {
+		if key.KeyID() == kid {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+package localauth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"boardfund/jwtauth"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+type fakeKeys struct {
+	keys []SigningKey
+}
+
+func (f *fakeKeys) GetSigningKeys(context.Context) ([]SigningKey, error) {
+	// Newest first, as the query orders them.
+	keys := make([]SigningKey, len(f.keys))
+	for n, key := range f.keys {
+		keys[len(f.keys)-1-n] = key
+	}
+
+	return keys, nil
+}
+
+func (f *fakeKeys) InsertSigningKey(_ context.Context, key SigningKey) (*SigningKey, error) {
+	f.keys = append(f.keys, key)
+
+	return &key, nil
+}
+
+func (f *fakeKeys) DeleteSigningKeysBefore(_ context.Context, before time.Time) error {
+	var kept []SigningKey
+	for _, key := range f.keys {
+		if !key.Created.Before(before) {
+			kept = append(kept, key)
+		}
+	}
+
+	f.keys = kept
+
+	return nil
+}
+
+// A rotation must not sign anybody out: a token signed by the old key is good
+// until it expires, and the old key is only dropped once that has happened.
+func TestRotationKeepsLiveTokensValid(t *testing.T) {
+	ctx := context.Background()
+	keys := &fakeKeys{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	issuer, err := NewIssuer(ctx, keys, "http://localhost:8080", logger)
+	if err != nil {
+		t.Fatalf("issuer: %v", err)
+	}
+
+	// Read after the first key is made, so it is never younger than the clock.
+	now := time.Now()
+	issuer.now = func() time.Time { return now }
+
+	verifier := jwtauth.NewToken(issuer.PublicKeys())
+
+	old, _, err := issuer.Issue("sub", nil)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	now = now.Add(RotateEvery)
+	if err = issuer.Rotate(ctx); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	if len(keys.keys) != 2 || issuer.PublicKeys().Len() != 2 {
+		t.Fatalf("%d stored, %d published; want both keys while the old one may have live tokens",
+			len(keys.keys), issuer.PublicKeys().Len())
+	}
+
+	fresh, _, err := issuer.Issue("sub", nil)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	if kid(t, fresh) == kid(t, old) {
+		t.Fatal("the new token is signed by the old key")
+	}
+
+	// Checked by signature alone: the old token's own clock has long run out.
+	if _, err = jwt.ParseString(old, jwt.WithKeySet(issuer.PublicKeys()), jwt.WithValidate(false)); err != nil {
+		t.Errorf("old token after rotation: %v", err)
+	}
+
+	if _, err = jwt.ParseString(fresh, jwt.WithKeySet(issuer.PublicKeys()), jwt.WithValidate(false)); err != nil {
+		t.Errorf("new token: %v", err)
+	}
+
+	now = now.Add(TokenLifetime + time.Minute)
+	if err = issuer.Rotate(ctx); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	if len(keys.keys) != 1 || issuer.PublicKeys().Len() != 1 {
+		t.Errorf("%d stored, %d published; want the old key gone once its tokens have expired",
+			len(keys.keys), issuer.PublicKeys().Len())
+	}
+
+	if _, err = verifier.Verify(old); err == nil {
+		t.Error("a token signed by a retired key still verifies")
+	}
+}
+
+func kid(t *testing.T, token string) string {
+	t.Helper()
+
+	msg, err := jws.Parse([]byte(token))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	return msg.Signatures()[0].ProtectedHeaders().KeyID()
+}
@@ -0,0 +1,360 @@
+// Package localauth is an authorizer that needs nothing but the database: the
+// stand-in for Cognito in development and staging, where there is no AWS.
+//
+// It satisfies the same interface aws.CognitoAuth does, and issues tokens with
+// the claims Cognito's carry -- custom:member_id, cognito:username,
+// cognito:groups -- so auth.AuthService, jwtauth and middlewares.Verify cannot
+// tell which one is in use. Only the keys they are checked against differ.
+package localauth
+
+import (
+	"boardfund/jwtauth"
+	"boardfund/mailer"
+	"boardfund/service/auth"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MinPasswordLength is the shortest password accepted, which is Cognito's
+// default. Longer is better; anything past that is for the member to decide.
+const MinPasswordLength = 8
+
+// User is an account as local_user holds it.
+type User struct {
+	ID           uuid.UUID
+	Username     string
+	Email        string
+	MemberID     uuid.UUID
+	PasswordHash string
+	MustReset    bool
+	Created      time.Time
+}
+
+type InsertUser struct {
+	ID           uuid.UUID
+	Username     string
+	Email        string
+	MemberID     uuid.UUID
+	PasswordHash string
+	MustReset    bool
+}
+
+type SetPassword struct {
+	Username     string
+	PasswordHash string
+	MustReset    bool
+}
+
+type userStore interface {
+	GetLocalUser(ctx context.Context, username string) (*User, error)
+	InsertLocalUser(ctx context.Context, user InsertUser) (*User, error)
+	DeleteLocalUser(ctx context.Context, username string) error
+	SetLocalUserPassword(ctx context.Context, set SetPassword) (*User, error)
+	AddLocalUserToGroup(ctx context.Context, username, group string) error
+	RemoveLocalUserFromGroup(ctx context.Context, username, group string) error
+	GetLocalUserGroups(ctx context.Context, username string) ([]string, error)
+}
+
+type tokenIssuer interface {
+	Issue(subject string, claims map[string]any) (string, time.Time, error)
+}
+
+// passwordSender delivers a new account's temporary password, as Cognito emails
+// it. Narrowed from mailer.Mailer.
+type passwordSender interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+type Authorizer struct {
+	users  userStore
+	tokens tokenIssuer
+	sender passwordSender
+
+	logger *slog.Logger
+
+	loginURL string
+
+	// dummyHash is checked against when the username is unknown, so a wrong
+	// username takes as long to refuse as a wrong password and the login form
+	// cannot be used to find out who has an account.
+	dummyHash string
+}
+
+// NewAuthorizer builds the authorizer. sender may be nil, in which case a new
+// account's temporary password is written to the log instead of being emailed:
+// fine on a laptop, and refused by the server in production.
+func NewAuthorizer(users userStore, tokens tokenIssuer, sender passwordSender, logger *slog.Logger, publicURL string) (*Authorizer, error) {
+	dummyHash, err := hashPassword(uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authorizer{
+		users:     users,
+		tokens:    tokens,
+		sender:    sender,
+		logger:    logger,
+		loginURL:  strings.TrimSuffix(publicURL, "/") + "/login",
+		dummyHash: dummyHash,
+	}, nil
+}
+
+// Authorize checks the password and issues tokens. An account still on its
+// temporary password gets ResetPassword instead, as Cognito answers with its
+// NEW_PASSWORD_REQUIRED challenge, and only once the password is shown to be
+// right.
+func (a Authorizer) Authorize(ctx context.Context, user, pass string) (*auth.AuthResponse, error) {
+	account, err := a.checkCredentials(ctx, user, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.MustReset {
+		return &auth.AuthResponse{ResetPassword: true}, nil
+	}
+
+	groups, err := a.users.GetLocalUserGroups(ctx, account.Username)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to list groups for user", slog.String("error", err.Error()))
+
+		return nil, auth.ErrAuthenticateOther
+	}
+
+	// Cognito leaves the claim off rather than sending an empty list.
+	claims := map[string]any{
+		"token_use":           "id",
+		"email":               account.Email,
+		"custom:member_id":    account.MemberID.String(),
+		jwtauth.UsernameClaim: account.Username,
+	}
+	if len(groups) > 0 {
+		claims[jwtauth.GroupsClaim] = groups
+	}
+
+	idToken, expires, err := a.tokens.Issue(account.ID.String(), claims)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to issue id token", slog.String("error", err.Error()))
+
+		return nil, auth.ErrAuthenticateOther
+	}
+
+	accessClaims := map[string]any{
+		"token_use": "access",
+		"username":  account.Username,
+	}
+	if len(groups) > 0 {
+		accessClaims[jwtauth.GroupsClaim] = groups
+	}
+
+	accessToken, _, err := a.tokens.Issue(account.ID.String(), accessClaims)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to issue access token", slog.String("error", err.Error()))
+
+		return nil, auth.ErrAuthenticateOther
+	}
+
+	return &auth.AuthResponse{
+		Token: &auth.Token{
+			AccessTokenStr: accessToken,
+			IDTokenStr:     idToken,
+			Expires:        expires,
+		},
+	}, nil
+}
+
+// SetPassword replaces the password, given the current one. It is also how a
+// temporary password is replaced, which is why it does not care whether the
+// account must reset.
+func (a Authorizer) SetPassword(ctx context.Context, user, old, new string) error {
+	if _, err := a.checkCredentials(ctx, user, old); err != nil {
+		return err
+	}
+
+	return a.AdminSetPassword(ctx, user, new)
+}
+
+// AdminSetPassword replaces the password without the current one, and clears
+// any reset the account was waiting on.
+func (a Authorizer) AdminSetPassword(ctx context.Context, user, password string) error {
+	if len(password) < MinPasswordLength {
+		return auth.ErrInvalidPassword
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.users.SetLocalUserPassword(ctx, SetPassword{Username: user, PasswordHash: hash})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return auth.ErrUserNotFound
+	}
+
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to set password", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	return nil
+}
+
+// CreateUser makes an account with a temporary password and sends it to email.
+// The id returned is the account's, kept as member.cognito_id.
+//
+// An account whose password could not be sent is removed again. Left in place it
+// could never be logged in to, and its username could never be registered.
+func (a Authorizer) CreateUser(ctx context.Context, username, email string, memberID uuid.UUID) (string, error) {
+	password, err := temporaryPassword()
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := a.users.InsertLocalUser(ctx, InsertUser{
+		ID:           uuid.New(),
+		Username:     username,
+		Email:        email,
+		MemberID:     memberID,
+		PasswordHash: hash,
+		MustReset:    true,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return "", auth.ErrUsernameExists
+		}
+
+		a.logger.ErrorContext(ctx, "failed to insert user", slog.String("error", err.Error()))
+
+		return "", auth.ErrNewUserOther
+	}
+
+	if err = a.sendTemporaryPassword(ctx, user, password); err != nil {
+		a.logger.ErrorContext(ctx, "failed to send temporary password", slog.String("error", err.Error()))
+
+		if errDelete := a.users.DeleteLocalUser(ctx, username); errDelete != nil {
+			a.logger.ErrorContext(ctx, "failed to remove user whose password was not sent",
+				slog.String("username", username),
+				slog.String("error", errDelete.Error()),
+			)
+		}
+
+		return "", auth.ErrNewUserOther
+	}
+
+	return user.ID.String(), nil
+}
+
+func (a Authorizer) sendTemporaryPassword(ctx context.Context, user *User, password string) error {
+	if a.sender == nil {
+		a.logger.WarnContext(ctx, "no mailer configured; temporary password written to the log instead",
+			slog.String("username", user.Username),
+			slog.String("temporary_password", password),
+		)
+
+		return nil
+	}
+
+	return a.sender.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: "your temporary password",
+		Body: fmt.Sprintf(
+			"an account has been made for you.\n\nusername: %s\ntemporary password: %s\n\n"+
+				"log in at %s and you will be asked to choose a password of your own.\n",
+			user.Username, password, a.loginURL,
+		),
+	})
+}
+
+// AddToGroup is idempotent, as Cognito's is.
+func (a Authorizer) AddToGroup(ctx context.Context, username, group string) error {
+	err := a.users.AddLocalUserToGroup(ctx, username, group)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			return auth.ErrUserNotFound
+		}
+
+		a.logger.ErrorContext(ctx, "failed to add user to group",
+			slog.String("group", group),
+			slog.String("error", err.Error()),
+		)
+
+		return auth.ErrGroupOther
+	}
+
+	return nil
+}
+
+// RemoveFromGroup is idempotent too.
+func (a Authorizer) RemoveFromGroup(ctx context.Context, username, group string) error {
+	err := a.users.RemoveLocalUserFromGroup(ctx, username, group)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to remove user from group",
+			slog.String("group", group),
+			slog.String("error", err.Error()),
+		)
+
+		return auth.ErrGroupOther
+	}
+
+	return nil
+}
+
+func (a Authorizer) ListGroups(ctx context.Context, username string) ([]string, error) {
+	groups, err := a.users.GetLocalUserGroups(ctx, username)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to list groups for user", slog.String("error", err.Error()))
+
+		return nil, auth.ErrGroupOther
+	}
+
+	return groups, nil
+}
+
+// checkCredentials is the account, if pass is its password. An unknown username
+// and a wrong password are the same answer.
+func (a Authorizer) checkCredentials(ctx context.Context, user, pass string) (*User, error) {
+	account, err := a.users.GetLocalUser(ctx, user)
+	if errors.Is(err, pgx.ErrNoRows) {
+		_, _ = checkPassword(a.dummyHash, pass)
+
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to get user", slog.String("error", err.Error()))
+
+		return nil, auth.ErrAuthenticateOther
+	}
+
+	ok, err := checkPassword(account.PasswordHash, pass)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "failed to check password",
+			slog.String("username", user),
+			slog.String("error", err.Error()),
+		)
+
+		return nil, auth.ErrAuthenticateOther
+	}
+
+	if !ok {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	return account, nil
+}
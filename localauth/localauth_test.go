@@ -0,0 +1,240 @@
+package localauth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"boardfund/jwtauth"
+	"boardfund/mailer"
+	"boardfund/service/auth"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type fakeUsers struct {
+	users  map[string]User
+	groups map[string][]string
+}
+
+func newFakeUsers() *fakeUsers {
+	return &fakeUsers{users: map[string]User{}, groups: map[string][]string{}}
+}
+
+func (f *fakeUsers) GetLocalUser(_ context.Context, username string) (*User, error) {
+	user, ok := f.users[username]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &user, nil
+}
+
+func (f *fakeUsers) InsertLocalUser(_ context.Context, insert InsertUser) (*User, error) {
+	user := User{
+		ID:           insert.ID,
+		Username:     insert.Username,
+		Email:        insert.Email,
+		MemberID:     insert.MemberID,
+		PasswordHash: insert.PasswordHash,
+		MustReset:    insert.MustReset,
+	}
+	f.users[insert.Username] = user
+
+	return &user, nil
+}
+
+func (f *fakeUsers) DeleteLocalUser(_ context.Context, username string) error {
+	delete(f.users, username)
+
+	return nil
+}
+
+func (f *fakeUsers) SetLocalUserPassword(_ context.Context, set SetPassword) (*User, error) {
+	user := f.users[set.Username]
+	user.PasswordHash = set.PasswordHash
+	user.MustReset = set.MustReset
+	f.users[set.Username] = user
+
+	return &user, nil
+}
+
+func (f *fakeUsers) AddLocalUserToGroup(_ context.Context, username, group string) error {
+	f.groups[username] = append(f.groups[username], group)
+
+	return nil
+}
+
+func (f *fakeUsers) RemoveLocalUserFromGroup(context.Context, string, string) error {
+	return nil
+}
+
+func (f *fakeUsers) GetLocalUserGroups(_ context.Context, username string) ([]string, error) {
+	return f.groups[username], nil
+}
+
+type capturedMail struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (c *capturedMail) Send(_ context.Context, msg mailer.Message) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	c.sent = append(c.sent, msg)
+
+	return nil
+}
+
+func newTestAuthorizer(t *testing.T, users *fakeUsers, sender passwordSender) (*Authorizer, *Issuer) {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	issuer, err := NewIssuer(context.Background(), &fakeKeys{}, "http://localhost:8080", logger)
+	if err != nil {
+		t.Fatalf("issuer: %v", err)
+	}
+
+	authorizer, err := NewAuthorizer(users, issuer, sender, logger, "http://localhost:8080")
+	if err != nil {
+		t.Fatalf("authorizer: %v", err)
+	}
+
+	return authorizer, issuer
+}
+
+// The whole life of an account as the auth service drives it: created with a
+// temporary password that is emailed, refused tokens until it is replaced, and
+// then issued tokens that jwtauth verifies and auth.Authenticate can read a
+// member out of -- the same claims, under the same names, Cognito's carry.
+func TestAnAccountFromCreationToAVerifiedToken(t *testing.T) {
+	ctx := context.Background()
+	users := newFakeUsers()
+	mail := &capturedMail{}
+	authorizer, issuer := newTestAuthorizer(t, users, mail)
+
+	memberID := uuid.New()
+
+	id, err := authorizer.CreateUser(ctx, "ada", "ada@example.com", memberID)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if len(mail.sent) != 1 || mail.sent[0].To[0] != "ada@example.com" {
+		t.Fatalf("sent %+v, want the temporary password mailed to the new account", mail.sent)
+	}
+
+	temporary := passwordFromMail(t, mail.sent[0].Body)
+
+	resp, err := authorizer.Authorize(ctx, "ada", temporary)
+	if err != nil {
+		t.Fatalf("authorize with temporary password: %v", err)
+	}
+
+	if !resp.ResetPassword || resp.Token != nil {
+		t.Fatalf("response = %+v, want a reset asked for and no token", resp)
+	}
+
+	if err = authorizer.SetPassword(ctx, "ada", temporary, "a much better password"); err != nil {
+		t.Fatalf("set password: %v", err)
+	}
+
+	if err = authorizer.AddToGroup(ctx, "ada", jwtauth.AdminGroup); err != nil {
+		t.Fatalf("add to group: %v", err)
+	}
+
+	resp, err = authorizer.Authorize(ctx, "ada", "a much better password")
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	if resp.ResetPassword || resp.Token == nil {
+		t.Fatalf("response = %+v, want tokens", resp)
+	}
+
+	verified, err := jwtauth.NewToken(issuer.PublicKeys()).VerifyAdmin(resp.Token.IDTokenStr)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	claims := verified.PrivateClaims()
+	if claims["custom:member_id"] != memberID.String() || jwtauth.Username(claims) != "ada" || verified.Subject() != id {
+		t.Errorf("claims = %v, sub %q; want the member, the username and the account id", claims, verified.Subject())
+	}
+
+	if _, err = authorizer.Authorize(ctx, "ada", temporary); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("temporary password after reset: err = %v, want invalid credentials", err)
+	}
+}
+
+// A wrong username and a wrong password get the same answer, so the login form
+// says nothing about who has an account.
+func TestAnUnknownUserIsAWrongPassword(t *testing.T) {
+	ctx := context.Background()
+	users := newFakeUsers()
+	authorizer, _ := newTestAuthorizer(t, users, &capturedMail{})
+
+	if _, err := authorizer.CreateUser(ctx, "ada", "ada@example.com", uuid.New()); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	_, errWrongPassword := authorizer.Authorize(ctx, "ada", "not it")
+	_, errNobody := authorizer.Authorize(ctx, "grace", "not it")
+
+	if !errors.Is(errWrongPassword, auth.ErrInvalidCredentials) || !errors.Is(errNobody, auth.ErrInvalidCredentials) {
+		t.Errorf("wrong password: %v, unknown user: %v; want both invalid credentials", errWrongPassword, errNobody)
+	}
+}
+
+// An account whose password never reached anybody could not be logged in to,
+// and would hold its username forever.
+func TestAnAccountThatCouldNotBeToldIsRemoved(t *testing.T) {
+	users := newFakeUsers()
+	authorizer, _ := newTestAuthorizer(t, users, &capturedMail{err: errors.New("relay down")})
+
+	_, err := authorizer.CreateUser(context.Background(), "ada", "ada@example.com", uuid.New())
+	if !errors.Is(err, auth.ErrNewUserOther) {
+		t.Fatalf("err = %v, want the create to fail", err)
+	}
+
+	if _, ok := users.users["ada"]; ok {
+		t.Error("the account is still there")
+	}
+}
+
+func TestAShortPasswordIsRefused(t *testing.T) {
+	ctx := context.Background()
+	users := newFakeUsers()
+	mail := &capturedMail{}
+	authorizer, _ := newTestAuthorizer(t, users, mail)
+
+	if _, err := authorizer.CreateUser(ctx, "ada", "ada@example.com", uuid.New()); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	err := authorizer.SetPassword(ctx, "ada", passwordFromMail(t, mail.sent[0].Body), "short")
+	if !errors.Is(err, auth.ErrInvalidPassword) {
+		t.Errorf("err = %v, want invalid password", err)
+	}
+}
+
+func passwordFromMail(t *testing.T, body string) string {
+	t.Helper()
+
+	for _, line := range strings.Split(body, "\n") {
+		if password, ok := strings.CutPrefix(line, "temporary password: "); ok {
+			return password
+		}
+	}
+
+	t.Fatalf("no temporary password in %q", body)
+
+	return ""
+}
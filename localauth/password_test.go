@@ -0,0 +1,35 @@
+package localauth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHashes(t *testing.T) {
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$") {
+		t.Errorf("hash = %q, want a PHC argon2id string", hash)
+	}
+
+	if ok, _ := checkPassword(hash, "correct horse"); !ok {
+		t.Error("the password does not match its own hash")
+	}
+
+	if ok, _ := checkPassword(hash, "battery staple"); ok {
+		t.Error("a different password matches")
+	}
+
+	again, _ := hashPassword("correct horse")
+	if again == hash {
+		t.Error("two hashes of one password are identical; the salt is not random")
+	}
+
+	if _, err = checkPassword("$2a$10$notargon", "x"); !errors.Is(err, errMalformedHash) {
+		t.Errorf("err = %v, want a hash in another format refused", err)
+	}
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"boardfund/db"
+	"boardfund/localauth"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func fromDBLocalUser(user db.LocalUser) localauth.User {
+	return localauth.User{
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		MemberID:     user.MemberID,
+		PasswordHash: user.PasswordHash,
+		MustReset:    user.MustReset,
+		Created:      user.Created.Time,
+	}
+}
+
+func toDBInsertLocalUser(user localauth.InsertUser) db.InsertLocalUserParams {
+	return db.InsertLocalUserParams{
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		MemberID:     user.MemberID,
+		PasswordHash: user.PasswordHash,
+		MustReset:    user.MustReset,
+	}
+}
+
+func toDBSetLocalUserPassword(set localauth.SetPassword) db.SetLocalUserPasswordParams {
+	return db.SetLocalUserPasswordParams{
+		Username:     set.Username,
+		PasswordHash: set.PasswordHash,
+		MustReset:    set.MustReset,
+	}
+}
+
+func fromDBSigningKey(key db.SigningKey) localauth.SigningKey {
+	return localauth.SigningKey{
+		KID:        key.Kid,
+		PrivateJWK: key.PrivateJwk,
+		Created:    key.Created.Time,
+	}
+}
+
+func toDBInsertSigningKey(key localauth.SigningKey) db.InsertSigningKeyParams {
+	return db.InsertSigningKeyParams{
+		Kid:        key.KID,
+		PrivateJwk: key.PrivateJWK,
+		Created:    pgtype.Timestamptz{Time: key.Created, Valid: true},
+	}
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"boardfund/db"
+	"boardfund/localauth"
+	"boardfund/pg"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LocalAuthStore struct {
+	queries *db.Queries
+}
+
+func NewLocalAuthStore(pool *pgxpool.Pool) LocalAuthStore {
+	return LocalAuthStore{
+		queries: db.New(pool),
+	}
+}
+
+func (s LocalAuthStore) GetLocalUser(ctx context.Context, username string) (*localauth.User, error) {
+	query := s.queries.GetLocalUserByUsername
+
+	argIdentity := func(in string) string { return in }
+
+	return pg.FetchOne(ctx, username, query, argIdentity, fromDBLocalUser)
+}
+
+func (s LocalAuthStore) InsertLocalUser(ctx context.Context, user localauth.InsertUser) (*localauth.User, error) {
+	query := s.queries.InsertLocalUser
+
+	return pg.CreateOne(ctx, user, query, toDBInsertLocalUser, fromDBLocalUser)
+}
+
+func (s LocalAuthStore) DeleteLocalUser(ctx context.Context, username string) error {
+	return s.queries.DeleteLocalUser(ctx, username)
+}
+
+func (s LocalAuthStore) SetLocalUserPassword(ctx context.Context, set localauth.SetPassword) (*localauth.User, error) {
+	query := s.queries.SetLocalUserPassword
+
+	return pg.UpdateOne(ctx, set, query, toDBSetLocalUserPassword, fromDBLocalUser)
+}
+
+func (s LocalAuthStore) AddLocalUserToGroup(ctx context.Context, username, group string) error {
+	return s.queries.AddLocalUserToGroup(ctx, db.AddLocalUserToGroupParams{
+		Username:  username,
+		GroupName: group,
+	})
+}
+
+func (s LocalAuthStore) RemoveLocalUserFromGroup(ctx context.Context, username, group string) error {
+	return s.queries.RemoveLocalUserFromGroup(ctx, db.RemoveLocalUserFromGroupParams{
+		Username:  username,
+		GroupName: group,
+	})
+}
+
+func (s LocalAuthStore) GetLocalUserGroups(ctx context.Context, username string) ([]string, error) {
+	return s.queries.GetLocalUserGroups(ctx, username)
+}
+
+func (s LocalAuthStore) GetSigningKeys(ctx context.Context) ([]localauth.SigningKey, error) {
+	query := s.queries.GetSigningKeys
+
+	return pg.FetchAll(ctx, query, fromDBSigningKey)
+}
+
+func (s LocalAuthStore) InsertSigningKey(ctx context.Context, key localauth.SigningKey) (*localauth.SigningKey, error) {
+	query := s.queries.InsertSigningKey
+
+	return pg.CreateOne(ctx, key, query, toDBInsertSigningKey, fromDBSigningKey)
+}
+
+func (s LocalAuthStore) DeleteSigningKeysBefore(ctx context.Context, before time.Time) error {
+	return s.queries.DeleteSigningKeysBefore(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+}
@@ -0,0 +1,235 @@
+// Package mailer sends plain-text email over SMTP.
+//
+// Deliberately small: one message, one connection, no queue. The callers that
+// need a message to arrive record whether it did, and a queue here would only be
+// somewhere else for a failure to sit unseen.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoRecipients is returned for a message addressed to nobody. Its own error
+// because the server would refuse it anyway, later, with a less useful reply.
+var ErrNoRecipients = errors.New("message has no recipients")
+
+// Config is where and as whom to send.
+type Config struct {
+	Host string
+	Port int
+
+	// Username and Password are optional. A relay on the local network often
+	// takes mail without them; when set, the server must offer STARTTLS, because
+	// net/smtp will not send credentials in the clear to anything but localhost.
+	Username string
+	Password string
+
+	// From is the envelope sender and the From header, e.g.
+	// "Fund <treasurer@example.org>".
+	From string
+
+	// Timeout bounds the whole exchange when the context does not. A relay that
+	// accepts the connection and then says nothing would otherwise hold the
+	// approval sweep open indefinitely.
+	Timeout time.Duration
+}
+
+// DefaultTimeout is used when Config.Timeout is zero.
+const DefaultTimeout = 30 * time.Second
+
+// Message is one email. Body is plain text; lines may end in \n, and are sent
+// as CRLF.
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+type Mailer struct {
+	config Config
+	from   *mail.Address
+}
+
+// NewMailer checks the sender address once, up front, so a typo in the
+// environment fails at boot rather than on the first batch that needs approving.
+func NewMailer(config Config) (*Mailer, error) {
+	if config.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+
+	if config.Port == 0 {
+		config.Port = 587
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp from address: %w", err)
+	}
+
+	return &Mailer{config: config, from: from}, nil
+}
+
+// Send delivers one message to every recipient in a single transaction.
+//
+// Success means the server accepted it for delivery, not that it arrived: SMTP
+// has nothing further to say. A recipient the server refuses fails the whole
+// send, so nobody is left believing a message reached a list it only partly
+// reached.
+func (m Mailer) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	recipients := make([]string, 0, len(msg.To))
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("recipient %q: %w", to, err)
+		}
+
+		recipients = append(recipients, addr.Address)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	address := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", address, err)
+	}
+
+	// net/smtp takes no context, so the deadline goes on the connection instead.
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	defer client.Close()
+
+	err = client.Hello(helloName())
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: m.config.Host})
+		if err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if m.config.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host))
+		if err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	err = client.Mail(m.from.Address)
+	if err != nil {
+		return err
+	}
+
+	for _, rcpt := range recipients {
+		err = client.Rcpt(rcpt)
+		if err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(m.compose(msg, recipients))
+	if err != nil {
+		writer.Close()
+
+		return err
+	}
+
+	// Close is where the server says whether it took the message; an error from
+	// it is a refusal, not a hiccup in tidying up.
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// compose renders headers and body. The subject is encoded so a fund named in
+// something other than ASCII does not arrive as mojibake.
+func (m Mailer) compose(msg Message, recipients []string) []byte {
+	var buf bytes.Buffer
+
+	header := func(name, value string) {
+		buf.WriteString(name)
+		buf.WriteString(": ")
+		buf.WriteString(value)
+		buf.WriteString("\r\n")
+	}
+
+	header("From", m.from.String())
+	header("To", strings.Join(recipients, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", uuid.New(), domainOf(m.from.Address)))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "8bit")
+	buf.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return buf.Bytes()
+}
+
+func domainOf(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return "localhost"
+	}
+
+	return address[at+1:]
+}
+
+// helloName is what we call ourselves in EHLO. Some relays refuse a bare
+// "localhost" from a remote peer, so the machine's name is used where there is
+// one.
+func helloName() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "localhost"
+	}
+
+	return name
+}
@@ -0,0 +1,110 @@
+package mailer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"boardfund/mailer/mailertest"
+)
+
+func newTestMailer(t *testing.T, server *mailertest.Server) *Mailer {
+	t.Helper()
+
+	m, err := NewMailer(Config{
+		Host: server.Host(),
+		Port: server.Port(),
+		From: "Fund <treasurer@example.org>",
+	})
+	if err != nil {
+		t.Fatalf("new mailer: %v", err)
+	}
+
+	return m
+}
+
+func TestSendHandsTheServerTheWholeMessage(t *testing.T) {
+	server := mailertest.NewServer(t)
+	m := newTestMailer(t, server)
+
+	err := m.Send(context.Background(), Message{
+		To:      []string{"a@example.org", "Bee <b@example.org>"},
+		Subject: "batch needs approval",
+		Body:    "line one\nline two\n.a line that starts with a dot\n",
+	})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	received := server.Received()
+	if len(received) != 1 {
+		t.Fatalf("server took %d messages, want 1", len(received))
+	}
+
+	got := received[0]
+	if got.From != "treasurer@example.org" {
+		t.Errorf("envelope sender is %q", got.From)
+	}
+
+	if strings.Join(got.To, ",") != "a@example.org,b@example.org" {
+		t.Errorf("envelope recipients are %v", got.To)
+	}
+
+	for _, want := range []string{
+		"Subject: batch needs approval\r\n",
+		"To: a@example.org, b@example.org\r\n",
+		"line one\r\nline two\r\n",
+		// Dot-stuffed on the way out and unstuffed by the server: a body line
+		// that happens to start with a dot must not end the message early.
+		".a line that starts with a dot\r\n",
+	} {
+		if !strings.Contains(got.Data, want) {
+			t.Errorf("message is missing %q:\n%s", want, got.Data)
+		}
+	}
+}
+
+// A refused mailbox is the failure a misconfigured recipient list actually
+// produces. It has to come back as an error, because the whole point of sending
+// is that somebody finds out when it did not go.
+func TestARefusedRecipientFailsTheSend(t *testing.T) {
+	server := mailertest.NewServer(t)
+	server.RejectRecipient("gone@example.org")
+	m := newTestMailer(t, server)
+
+	err := m.Send(context.Background(), Message{
+		To:      []string{"a@example.org", "gone@example.org"},
+		Subject: "s",
+		Body:    "b",
+	})
+	if err == nil {
+		t.Fatal("a refused recipient should fail the send")
+	}
+
+	if !strings.Contains(err.Error(), "gone@example.org") {
+		t.Errorf("the error should say who was refused: %v", err)
+	}
+
+	if len(server.Received()) != 0 {
+		t.Error("nothing should have been delivered")
+	}
+}
+
+func TestNoRecipientsIsRefusedBeforeConnecting(t *testing.T) {
+	m, err := NewMailer(Config{Host: "127.0.0.1", Port: 1, From: "a@example.org"})
+	if err != nil {
+		t.Fatalf("new mailer: %v", err)
+	}
+
+	err = m.Send(context.Background(), Message{Subject: "s", Body: "b"})
+	if err != ErrNoRecipients {
+		t.Fatalf("got %v, want ErrNoRecipients", err)
+	}
+}
+
+func TestABadFromAddressFailsAtConstruction(t *testing.T) {
+	_, err := NewMailer(Config{Host: "localhost", From: "not an address"})
+	if err == nil {
+		t.Fatal("a malformed sender should be caught at boot")
+	}
+}
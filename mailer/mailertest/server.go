@@ -0,0 +1,214 @@
+// Package mailertest is a local SMTP stand-in for tests.
+//
+// It speaks just enough of the protocol for net/smtp to hand it a message, and
+// keeps what it was given. No TLS and no AUTH are offered, so a client pointed at
+// it sends in the clear without credentials -- which is what a test wants, and
+// what nothing in production should ever be configured to do.
+package mailertest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Received is one message as the server took it.
+type Received struct {
+	From string
+	To   []string
+
+	// Data is everything after DATA, headers and body, with CRLFs intact and
+	// dot-stuffing undone.
+	Data string
+}
+
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	received []Received
+	reject   map[string]bool
+
+	wg sync.WaitGroup
+}
+
+// NewServer starts a server on a loopback port and stops it when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("mailertest: listen: %v", err)
+	}
+
+	server := &Server{listener: listener, reject: map[string]bool{}}
+
+	server.wg.Add(1)
+	go server.serve()
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Host and Port are where to point a mailer.Config.
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+
+	return host
+}
+
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+
+	return n
+}
+
+// RejectRecipient makes the server answer RCPT for this address with a
+// permanent failure, the way a real relay refuses a mailbox that does not exist.
+func (s *Server) RejectRecipient(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reject[strings.ToLower(address)] = true
+}
+
+// Received returns a copy of every message accepted so far.
+func (s *Server) Received() []Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Received, len(s.received))
+	copy(out, s.received)
+
+	return out
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+
+			s.session(conn)
+		}()
+	}
+}
+
+func (s *Server) session(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 mailertest ready")
+
+	var current Received
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 mailertest")
+		case "MAIL":
+			current = Received{From: addressIn(arg)}
+			reply("250 ok")
+		case "RCPT":
+			rcpt := addressIn(arg)
+
+			s.mu.Lock()
+			refused := s.reject[strings.ToLower(rcpt)]
+			s.mu.Unlock()
+
+			if refused {
+				reply("550 no such mailbox: %s", rcpt)
+
+				continue
+			}
+
+			current.To = append(current.To, rcpt)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+
+			data, ok := readData(reader)
+			if !ok {
+				return
+			}
+
+			current.Data = data
+
+			s.mu.Lock()
+			s.received = append(s.received, current)
+			s.mu.Unlock()
+
+			current = Received{}
+			reply("250 queued")
+		case "RSET":
+			current = Received{}
+			reply("250 ok")
+		case "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// readData reads up to the lone dot and undoes dot-stuffing.
+func readData(reader *bufio.Reader) (string, bool) {
+	var data strings.Builder
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", false
+		}
+
+		if line == ".\r\n" {
+			return data.String(), true
+		}
+
+		data.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+// addressIn pulls the address out of "FROM:<a@b>" or "TO:<a@b>".
+func addressIn(arg string) string {
+	start := strings.Index(arg, "<")
+	end := strings.LastIndex(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+
+	return arg[start+1 : end]
+}
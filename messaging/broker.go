@@ -33,8 +33,9 @@ const StreamName = "WEBHOOKS"
 
 // streamSubjects covers the provider event types in keys.go. PayPal names them
 // with dots already, which is NATS's own subject separator, so they need no
-// translation -- PAYMENT.SALE.COMPLETED is a subject as it stands.
-var streamSubjects = []string{"PAYMENT.>", "BILLING.>"}
+// translation -- PAYMENT.SALE.COMPLETED is a subject as it stands. Stripe's are
+// dotted too, and sit under their own STRIPE prefix.
+var streamSubjects = []string{"PAYMENT.>", "BILLING.>", "CUSTOMER.>", "STRIPE.>"}
 
 // retention keeps events for a week whether or not anything consumed them.
 //
@@ -62,28 +63,16 @@ var defaultBackOff = []time.Duration{
 	10 * time.Minute,
 }
 
-// maxRecordedExhausted bounds the in-memory record of messages JetStream gave up
-// on. Fifty is far more than a healthy system produces and small enough to keep.
-const maxRecordedExhausted = 50
-
-// Exhausted is a message JetStream stopped redelivering because it used up
-// MaxDeliver attempts. It is the only silent loss the durable bus still has, so
-// it is worth surfacing somewhere a person will look.
-type Exhausted struct {
-	Consumer   string
-	StreamSeq  uint64
-	Deliveries int
-	At         time.Time
-}
-
 // Status is what the admin page renders.
 type Status struct {
 	Stream    StreamStatus
 	Consumers []ConsumerStatus
-	// Exhausted is since this process started, because it is held in memory. A
-	// restart clears it; the messages themselves are still in the stream until
-	// retention expires them.
-	Exhausted []Exhausted
+
+	// DeadLetters is the newest of what consumers gave up on, and DeadLetterCount
+	// how many there are in all. Read from the dead-letter stream, so a restart
+	// does not clear it.
+	DeadLetters     []DeadLetter
+	DeadLetterCount uint64
 }
 
 type StreamStatus struct {
@@ -111,9 +100,10 @@ type ConsumerStatus struct {
 }
 
 type Broker struct {
-	nc     *nats.Conn
-	js     jetstream.JetStream
-	stream jetstream.Stream
+	nc          *nats.Conn
+	js          jetstream.JetStream
+	stream      jetstream.Stream
+	deadLetters jetstream.Stream
 
 	ctx    context.Context
 	logger *slog.Logger
@@ -129,11 +119,14 @@ type Broker struct {
 	// owns any more.
 	advisories *nats.Subscription
 
+	// outcomes is told what each consumer made of each webhook. Nil reports
+	// nothing, which is how a command attached through Attach runs.
+	outcomes outcomeRecorder
+
 	// names maps durable back to the subject it was created for, because
 	// ConsumerInfo reports the durable name and an operator thinks in event types.
-	mu        sync.Mutex
-	names     map[string]string
-	exhausted []Exhausted
+	mu    sync.Mutex
+	names map[string]string
 }
 
 // NewBroker declares the stream. CreateOrUpdateStream is idempotent, so a restart
@@ -157,14 +150,20 @@ func NewBroker(ctx context.Context, nc *nats.Conn, logger *slog.Logger) (*Broker
 		return nil, fmt.Errorf("failed to declare stream %s: %w", StreamName, err)
 	}
 
+	deadLetters, err := js.CreateOrUpdateStream(ctx, deadLetterConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to declare stream %s: %w", DeadLetterStreamName, err)
+	}
+
 	broker := &Broker{
-		nc:      nc,
-		js:      js,
-		stream:  stream,
-		ctx:     ctx,
-		logger:  logger,
-		backOff: defaultBackOff,
-		names:   map[string]string{},
+		nc:          nc,
+		js:          js,
+		stream:      stream,
+		deadLetters: deadLetters,
+		ctx:         ctx,
+		logger:      logger,
+		backOff:     defaultBackOff,
+		names:       map[string]string{},
 	}
 
 	if err = broker.watchExhausted(); err != nil {
@@ -174,22 +173,59 @@ func NewBroker(ctx context.Context, nc *nats.Conn, logger *slog.Logger) (*Broker
 	return broker, nil
 }
 
-// watchExhausted records the messages JetStream stops redelivering.
+// Attach opens the streams a broker in another process declared, for a command
+// reached through Dial. It declares nothing and watches nothing: the running
+// service already files dead letters, and a second watcher would file each one
+// twice, or change a stream's configuration from a command that does not own it.
+func Attach(ctx context.Context, nc *nats.Conn, logger *slog.Logger) (*Broker, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open jetstream: %w", err)
+	}
+
+	stream, err := js.Stream(ctx, StreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream %s: %w", StreamName, err)
+	}
+
+	deadLetters, err := js.Stream(ctx, DeadLetterStreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream %s: %w", DeadLetterStreamName, err)
+	}
+
+	return &Broker{
+		nc:          nc,
+		js:          js,
+		stream:      stream,
+		deadLetters: deadLetters,
+		ctx:         ctx,
+		logger:      logger,
+		backOff:     defaultBackOff,
+		names:       map[string]string{},
+	}, nil
+}
+
+// advisory is the part of a max-deliveries advisory worth keeping.
+type advisory struct {
+	Consumer   string `json:"consumer"`
+	StreamSeq  uint64 `json:"stream_seq"`
+	Deliveries int    `json:"deliveries"`
+}
+
+// watchExhausted files the messages JetStream stops redelivering as dead
+// letters.
 //
 // Every other failure in this package is recoverable: a nak comes back, a
 // restart resumes. Running out of deliveries is where an event finally stops,
-// and without this it is a single log line at the moment it happens.
+// and without this it is a single log line at the moment it happens and a
+// message that expires from WEBHOOKS a week later.
 func (b *Broker) watchExhausted() error {
 	subject := fmt.Sprintf("$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.%s.*", StreamName)
 
 	sub, err := b.nc.Subscribe(subject, func(msg *nats.Msg) {
-		var advisory struct {
-			Consumer   string `json:"consumer"`
-			StreamSeq  uint64 `json:"stream_seq"`
-			Deliveries int    `json:"deliveries"`
-		}
+		var exhausted advisory
 
-		if errParse := json.Unmarshal(msg.Data, &advisory); errParse != nil {
+		if errParse := json.Unmarshal(msg.Data, &exhausted); errParse != nil {
 			b.logger.Error("failed to read max-deliveries advisory",
 				slog.String("error", errParse.Error()))
 
@@ -197,23 +233,18 @@ func (b *Broker) watchExhausted() error {
 		}
 
 		b.logger.Error("giving up on a message after exhausting redelivery",
-			slog.String("consumer", advisory.Consumer),
-			slog.Uint64("stream_seq", advisory.StreamSeq),
-			slog.Int("deliveries", advisory.Deliveries),
+			slog.String("consumer", exhausted.Consumer),
+			slog.Uint64("stream_seq", exhausted.StreamSeq),
+			slog.Int("deliveries", exhausted.Deliveries),
 		)
 
-		b.mu.Lock()
-		defer b.mu.Unlock()
-
-		b.exhausted = append(b.exhausted, Exhausted{
-			Consumer:   advisory.Consumer,
-			StreamSeq:  advisory.StreamSeq,
-			Deliveries: advisory.Deliveries,
-			At:         time.Now(),
-		})
-
-		if len(b.exhausted) > maxRecordedExhausted {
-			b.exhausted = b.exhausted[len(b.exhausted)-maxRecordedExhausted:]
+		// When this fails the line above is the only record, and it says which
+		// message to go looking for in WEBHOOKS before retention takes it.
+		if errFile := b.deadLetter(exhausted); errFile != nil {
+			b.logger.Error("failed to file a dead letter",
+				slog.Uint64("stream_seq", exhausted.StreamSeq),
+				slog.String("error", errFile.Error()),
+			)
 		}
 	})
 	if err != nil {
@@ -261,10 +292,17 @@ func (b *Broker) Status(ctx context.Context) (Status, error) {
 		return Status{}, fmt.Errorf("failed to list consumers: %w", err)
 	}
 
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	deadInfo, err := b.deadLetters.Info(ctx)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to read dead-letter stream info: %w", err)
+	}
 
-	status.Exhausted = append(status.Exhausted, b.exhausted...)
+	status.DeadLetterCount = deadInfo.State.Msgs
+
+	status.DeadLetters, err = b.DeadLetters(ctx, maxListedDeadLetters)
+	if err != nil {
+		return Status{}, err
+	}
 
 	return status, nil
 }
@@ -319,7 +357,11 @@ func (b *Broker) Subscribe(event string, cb func(data []byte) error) error {
 	}
 
 	consuming, err := consumer.Consume(func(msg jetstream.Msg) {
-		if errHandle := cb(msg.Data()); errHandle != nil {
+		errHandle := cb(msg.Data())
+
+		b.reportOutcome(event, msg, errHandle)
+
+		if errHandle != nil {
 			b.logger.Error("handler failed, message will be redelivered",
 				slog.String("event", event),
 				slog.String("error", errHandle.Error()),
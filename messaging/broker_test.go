@@ -2,6 +2,7 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"path/filepath"
@@ -358,8 +359,8 @@ func TestStatusIsQuietWhenNothingIsWrong(t *testing.T) {
 
 		if len(status.Consumers) == 1 && status.Consumers[0].Pending == 0 &&
 			status.Consumers[0].AckPending == 0 && status.Consumers[0].Redelivered == 0 {
-			if len(status.Exhausted) != 0 {
-				t.Errorf("nothing should have been given up on, got %d", len(status.Exhausted))
+			if len(status.DeadLetters) != 0 || status.DeadLetterCount != 0 {
+				t.Errorf("nothing should have been given up on, got %d", status.DeadLetterCount)
 			}
 
 			return
@@ -374,38 +375,168 @@ func TestStatusIsQuietWhenNothingIsWrong(t *testing.T) {
 }
 
 // Running out of deliveries is where an event finally stops. Without the
-// advisory it is one log line at the moment it happens and nothing afterwards.
-func TestExhaustedMessagesAreRecorded(t *testing.T) {
+// advisory it is one log line at the moment it happens, and the message itself
+// expires from WEBHOOKS a week later.
+func TestExhaustedMessagesAreDeadLettered(t *testing.T) {
+	storeDir := filepath.Join(t.TempDir(), "jetstream")
+
+	func() {
+		broker := newBroker(t, startServer(t, storeDir))
+		broker.backOff = []time.Duration{10 * time.Millisecond}
+
+		if err := broker.Subscribe(PayoutsItemFailed, func([]byte) error {
+			return errNotToday
+		}); err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+
+		if err := broker.Publish(PayoutsItemFailed, []byte(`{"payout_item_id":"PI-1"}`)); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+
+		letter := waitForDeadLetter(t, broker)
+
+		if letter.Subject != PayoutsItemFailed {
+			t.Errorf("subject = %q, want the original event type", letter.Subject)
+		}
+
+		if letter.Deliveries < 2 {
+			t.Errorf("deliveries = %d, want at least 2", letter.Deliveries)
+		}
+
+		if letter.StreamSeq == 0 || letter.Consumer == "" || letter.Received.IsZero() {
+			t.Errorf("the delivery that failed should be described: %+v", letter)
+		}
+	}()
+
+	// The list used to live in memory, so a deploy cleared it.
+	broker := newBroker(t, startServer(t, storeDir))
+
+	status, err := broker.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	if status.DeadLetterCount != 1 || len(status.DeadLetters) != 1 {
+		t.Fatalf("a dead letter did not survive a restart: %+v", status.DeadLetters)
+	}
+
+	if got := string(status.DeadLetters[0].Data); got != `{"payout_item_id":"PI-1"}` {
+		t.Errorf("payload = %s", got)
+	}
+}
+
+// A replay goes back to the consumer that gave up, through the stream, and the
+// letter leaves the list. If the handler fails again it comes back as a new one.
+func TestAReplayedDeadLetterReachesItsConsumer(t *testing.T) {
+	ctx := context.Background()
+
 	broker := newBroker(t, startServer(t, t.TempDir()))
 	broker.backOff = []time.Duration{10 * time.Millisecond}
 
-	if err := broker.Subscribe(PayoutsItemFailed, func([]byte) error {
-		return errNotToday
+	var mu sync.Mutex
+	fixed := false
+	handled := make(chan []byte, 1)
+
+	if err := broker.Subscribe(PaymentCompleted, func(data []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !fixed {
+			return errNotToday
+		}
+
+		handled <- data
+
+		return nil
 	}); err != nil {
 		t.Fatalf("subscribe: %v", err)
 	}
 
-	if err := broker.Publish(PayoutsItemFailed, []byte(`{"payout_item_id":"PI-1"}`)); err != nil {
+	if err := broker.Publish(PaymentCompleted, []byte(`{"id":"SALE-5"}`)); err != nil {
 		t.Fatalf("publish: %v", err)
 	}
 
+	letter := waitForDeadLetter(t, broker)
+
+	mu.Lock()
+	fixed = true
+	mu.Unlock()
+
+	replayed, err := broker.ReplayDeadLetter(ctx, letter.Seq)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	if replayed.Subject != PaymentCompleted {
+		t.Errorf("replayed subject = %q", replayed.Subject)
+	}
+
+	select {
+	case got := <-handled:
+		if string(got) != `{"id":"SALE-5"}` {
+			t.Errorf("payload = %s", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("a replayed dead letter never reached its consumer")
+	}
+
+	letters, err := broker.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("dead letters: %v", err)
+	}
+
+	if len(letters) != 0 {
+		t.Errorf("a replayed letter should leave the list, got %+v", letters)
+	}
+
+	if _, err = broker.ReplayDeadLetter(ctx, letter.Seq); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("replaying it twice: err = %v, want ErrDeadLetterNotFound", err)
+	}
+}
+
+// A command attaches to the streams the service declared. It must not watch
+// advisories as well, or every dead letter would be filed by each process
+// listening.
+func TestAttachOpensWithoutWatching(t *testing.T) {
+	nc := startServer(t, t.TempDir())
+
+	if _, err := Attach(context.Background(), nc, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Fatal("attaching before any broker declared the streams should fail")
+	}
+
+	newBroker(t, nc)
+
+	attached, err := Attach(context.Background(), nc, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+
+	if attached.advisories != nil {
+		t.Error("an attached broker should leave dead letters to the service")
+	}
+
+	if _, err = attached.Status(context.Background()); err != nil {
+		t.Errorf("status through an attached broker: %v", err)
+	}
+}
+
+func waitForDeadLetter(t *testing.T, broker *Broker) DeadLetter {
+	t.Helper()
+
 	deadline := time.Now().Add(20 * time.Second)
 	for {
-		status, err := broker.Status(context.Background())
+		letters, err := broker.DeadLetters(context.Background(), 10)
 		if err != nil {
-			t.Fatalf("status: %v", err)
+			t.Fatalf("dead letters: %v", err)
 		}
 
-		if len(status.Exhausted) > 0 {
-			if got := status.Exhausted[0].Deliveries; got < 2 {
-				t.Errorf("deliveries = %d, want at least 2", got)
-			}
-
-			return
+		if len(letters) > 0 {
+			return letters[0]
 		}
 
 		if time.Now().After(deadline) {
-			t.Fatal("a message that used up every delivery was never recorded")
+			t.Fatal("a message that used up every delivery was never dead-lettered")
 		}
 
 		time.Sleep(50 * time.Millisecond)
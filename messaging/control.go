@@ -0,0 +1,85 @@
+package messaging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nats-io/nats.go"
+)
+
+// controlFile is where the running service says how to reach its server. It
+// lives in the store directory because that is the one place a command run in
+// the same container is certain to find, and because it is on the volume that
+// only this service mounts.
+const controlFile = "control.json"
+
+// Control is how a command reaches the server embedded in the running service.
+//
+// The server listens on loopback only and refuses a client without the token,
+// which is generated at every boot. Anything able to read the file can already
+// read the stream off the volume directly, so the file grants nothing new; the
+// token is what keeps the port closed to everything else on the host.
+type Control struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// NewControlToken is a fresh secret for the server to require.
+func NewControlToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate a control token: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+// WriteControl records where the server is listening. Readable by the owner
+// only: the token is a password in all but name.
+func WriteControl(storeDir string, control Control) error {
+	encoded, err := json.Marshal(control)
+	if err != nil {
+		return fmt.Errorf("failed to encode control file: %w", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(storeDir, controlFile), encoded, 0o600); err != nil {
+		return fmt.Errorf("failed to write control file: %w", err)
+	}
+
+	return nil
+}
+
+// Dial connects to the server the running service started over storeDir.
+//
+// A command cannot start a server of its own over the same directory: two
+// servers writing one store corrupt it, and nothing in JetStream stops the
+// second from trying. So it asks the one that is already there.
+func Dial(storeDir string) (*nats.Conn, error) {
+	raw, err := os.ReadFile(filepath.Join(storeDir, controlFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no control file in %s: is the service running, and is this its container?", storeDir)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read control file: %w", err)
+	}
+
+	var control Control
+	if err = json.Unmarshal(raw, &control); err != nil {
+		return nil, fmt.Errorf("failed to read control file: %w", err)
+	}
+
+	// The file outlives a crash, so a connection refused here most likely means
+	// the service it describes is no longer running.
+	nc, err := nats.Connect(control.URL, nats.Token(control.Token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the service at %s, is it running? %w", control.URL, err)
+	}
+
+	return nc, nil
+}
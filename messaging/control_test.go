@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// A command reaches the running service through what it wrote on the volume,
+// and only with the token in it: the port is on loopback, not open to the host.
+func TestDialUsesTheControlFile(t *testing.T) {
+	storeDir := t.TempDir()
+
+	token, err := NewControlToken()
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	ns, err := server.NewServer(&server.Options{
+		Host:          "127.0.0.1",
+		Port:          server.RANDOM_PORT,
+		Authorization: token,
+		JetStream:     true,
+		StoreDir:      storeDir,
+	})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	go ns.Start()
+	t.Cleanup(ns.Shutdown)
+
+	if !ns.ReadyForConnections(10 * time.Second) {
+		t.Fatal("server not ready")
+	}
+
+	if _, err = Dial(storeDir); err == nil || !strings.Contains(err.Error(), "is the service running") {
+		t.Fatalf("no control file should say why, got %v", err)
+	}
+
+	if err = WriteControl(storeDir, Control{URL: ns.ClientURL(), Token: "wrong"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err = Dial(storeDir); err == nil {
+		t.Fatal("the wrong token should be refused")
+	}
+
+	if err = WriteControl(storeDir, Control{URL: ns.ClientURL(), Token: token}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	nc, err := Dial(storeDir)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	nc.Close()
+}
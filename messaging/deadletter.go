@@ -0,0 +1,219 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DeadLetterStreamName holds a copy of every message a consumer gave up on.
+//
+// WEBHOOKS expires what it holds after retention whether or not it was handled,
+// so a message that ran out of deliveries on a Friday was gone the following
+// Friday -- along with the only copy of what PayPal sent. This stream has no
+// age limit. A message leaves it when somebody replays it, and not before.
+const DeadLetterStreamName = "DEADLETTERS"
+
+// deadLetterPrefix is put in front of the original subject, so a dead letter
+// says what it was without a header and the two streams' subjects never overlap.
+const deadLetterPrefix = "DEADLETTER."
+
+// The headers a dead letter carries about the delivery that failed. The subject
+// and payload are the original's; these are everything else the advisory said.
+const (
+	headerConsumer   = "Fund-Consumer"
+	headerStreamSeq  = "Fund-Stream-Seq"
+	headerDeliveries = "Fund-Deliveries"
+	headerReceived   = "Fund-Received"
+)
+
+// maxListedDeadLetters bounds what Status reads. Far more than a healthy system
+// produces; a page with more than this on it has a problem the list will not
+// help with.
+const maxListedDeadLetters = 50
+
+// ErrDeadLetterNotFound is a sequence with nothing in the dead-letter stream
+// behind it: a typo, or a letter that has already been replayed.
+var ErrDeadLetterNotFound = errors.New("no dead letter with that sequence")
+
+// DeadLetter is a message JetStream stopped redelivering because it used up
+// MaxDeliver attempts, as copied out of WEBHOOKS when it happened.
+type DeadLetter struct {
+	// Seq is its place in the dead-letter stream, and what a replay is asked for.
+	Seq uint64
+
+	// Subject is the event type it was published as, and so which consumer a
+	// replay reaches.
+	Subject    string
+	Consumer   string
+	StreamSeq  uint64
+	Deliveries int
+	Received   time.Time
+	At         time.Time
+	Data       []byte
+
+	// TransmissionID is the webhook's, when it was published with one, and goes
+	// back out with it on a replay.
+	TransmissionID string
+}
+
+func deadLetterConfig() jetstream.StreamConfig {
+	return jetstream.StreamConfig{
+		Name:      DeadLetterStreamName,
+		Subjects:  []string{deadLetterPrefix + ">"},
+		Storage:   jetstream.FileStorage,
+		Retention: jetstream.LimitsPolicy,
+	}
+}
+
+// deadLetter copies one exhausted message into the dead-letter stream.
+//
+// Keyed by its sequence in WEBHOOKS, so an advisory that arrives twice files
+// one letter rather than two, and a replay does not find a twin left behind.
+func (b *Broker) deadLetter(advisory advisory) error {
+	ctx, cancel := context.WithTimeout(b.ctx, publishTimeout)
+	defer cancel()
+
+	original, err := b.stream.GetMsg(ctx, advisory.StreamSeq)
+	if err != nil {
+		return fmt.Errorf("failed to read message %d: %w", advisory.StreamSeq, err)
+	}
+
+	msg := nats.NewMsg(deadLetterPrefix + original.Subject)
+	msg.Data = original.Data
+	msg.Header.Set(headerConsumer, advisory.Consumer)
+	msg.Header.Set(headerStreamSeq, strconv.FormatUint(advisory.StreamSeq, 10))
+	msg.Header.Set(headerDeliveries, strconv.Itoa(advisory.Deliveries))
+	msg.Header.Set(headerReceived, original.Time.UTC().Format(time.RFC3339Nano))
+
+	if transmissionID := original.Header.Get(headerTransmissionID); transmissionID != "" {
+		msg.Header.Set(headerTransmissionID, transmissionID)
+	}
+
+	msgID := fmt.Sprintf("%s-%d", StreamName, advisory.StreamSeq)
+	if _, err = b.js.PublishMsg(ctx, msg, jetstream.WithMsgID(msgID)); err != nil {
+		return fmt.Errorf("failed to file dead letter for message %d: %w", advisory.StreamSeq, err)
+	}
+
+	return nil
+}
+
+// DeadLetter reads one dead letter by its sequence in the dead-letter stream.
+func (b *Broker) DeadLetter(ctx context.Context, seq uint64) (DeadLetter, error) {
+	raw, err := b.deadLetters.GetMsg(ctx, seq)
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+
+	if err != nil {
+		return DeadLetter{}, fmt.Errorf("failed to read dead letter %d: %w", seq, err)
+	}
+
+	return fromRaw(raw), nil
+}
+
+// DeadLetters is the newest dead letters first, at most limit of them.
+//
+// Read backwards from the end of the stream. A replayed letter is deleted, so
+// the sequence has holes in it; counting what has been found against what the
+// stream says it holds stops the walk at the last real one rather than at the
+// beginning of time.
+func (b *Broker) DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	info, err := b.deadLetters.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter stream info: %w", err)
+	}
+
+	want := min(uint64(limit), info.State.Msgs)
+
+	var letters []DeadLetter
+	for seq := info.State.LastSeq; uint64(len(letters)) < want && seq >= info.State.FirstSeq && seq > 0; seq-- {
+		letter, errRead := b.DeadLetter(ctx, seq)
+		if errors.Is(errRead, ErrDeadLetterNotFound) {
+			continue
+		}
+
+		if errRead != nil {
+			return nil, errRead
+		}
+
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+// ReplayDeadLetter publishes a dead letter again, as it was first published,
+// and removes it from the dead-letter stream.
+//
+// Back onto its original subject in WEBHOOKS rather than handed to a callback,
+// so it reaches the consumer that gave up on it -- there is one durable per
+// event type -- with the full retry schedule ahead of it. If it fails all over
+// again it becomes a new dead letter, which is the honest answer to "did the
+// replay work".
+//
+// The removal comes after the publish. A replay that published and then failed
+// to remove leaves a letter that looks unreplayed; replaying it twice is safe,
+// because the handlers are idempotent, and losing it is not.
+func (b *Broker) ReplayDeadLetter(ctx context.Context, seq uint64) (DeadLetter, error) {
+	letter, err := b.DeadLetter(ctx, seq)
+	if err != nil {
+		return DeadLetter{}, err
+	}
+
+	msg := nats.NewMsg(letter.Subject)
+	msg.Data = letter.Data
+
+	if letter.TransmissionID != "" {
+		msg.Header.Set(headerTransmissionID, letter.TransmissionID)
+	}
+
+	// Keyed, so two admins pressing the button at once publish one message.
+	msgID := fmt.Sprintf("%s-%d", DeadLetterStreamName, seq)
+	if _, err = b.js.PublishMsg(ctx, msg, jetstream.WithMsgID(msgID)); err != nil {
+		return DeadLetter{}, fmt.Errorf("failed to replay dead letter %d: %w", seq, err)
+	}
+
+	if err = b.deadLetters.DeleteMsg(ctx, seq); err != nil && !errors.Is(err, jetstream.ErrMsgNotFound) {
+		b.logger.ErrorContext(ctx, "replayed a dead letter but failed to remove it",
+			slog.Uint64("seq", seq),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	b.logger.InfoContext(ctx, "replayed dead letter",
+		slog.Uint64("seq", seq),
+		slog.String("subject", letter.Subject),
+		slog.Uint64("stream_seq", letter.StreamSeq),
+	)
+
+	return letter, nil
+}
+
+// fromRaw reads a dead letter back. A header that does not parse is left at its
+// zero value: the letter is still worth showing, and its payload still worth
+// replaying, without the count of how often it failed.
+func fromRaw(raw *jetstream.RawStreamMsg) DeadLetter {
+	letter := DeadLetter{
+		Seq:      raw.Sequence,
+		Subject:  strings.TrimPrefix(raw.Subject, deadLetterPrefix),
+		Consumer: raw.Header.Get(headerConsumer),
+		At:       raw.Time,
+		Data:     raw.Data,
+
+		TransmissionID: raw.Header.Get(headerTransmissionID),
+	}
+
+	letter.StreamSeq, _ = strconv.ParseUint(raw.Header.Get(headerStreamSeq), 10, 64)
+	letter.Deliveries, _ = strconv.Atoi(raw.Header.Get(headerDeliveries))
+	letter.Received, _ = time.Parse(time.RFC3339Nano, raw.Header.Get(headerReceived))
+
+	return letter
+}
@@ -14,6 +14,37 @@ const (
 	SubscriptionSuspended     = "BILLING.SUBSCRIPTION.SUSPENDED"
 	SubscriptionCancelled     = "BILLING.SUBSCRIPTION.CANCELLED"
 	SubscriptionPaymentFailed = "BILLING.SUBSCRIPTION.PAYMENT.FAILED"
+	SubscriptionUpdated       = "BILLING.SUBSCRIPTION.UPDATED"
+
+	// A dispute is a donor, or their bank, asking for money back. PayPal holds
+	// the money from the moment it opens, and the resolution says whether it is
+	// coming back to us. Chargebacks arrive as disputes too.
+	DisputeCreated  = "CUSTOMER.DISPUTE.CREATED"
+	DisputeUpdated  = "CUSTOMER.DISPUTE.UPDATED"
+	DisputeResolved = "CUSTOMER.DISPUTE.RESOLVED"
+)
+
+// Stripe webhook event types, used as subjects on this bus.
+//
+// Stripe's own names are lower case and already dotted, and are kept as they
+// are under a STRIPE prefix. The prefix is what keeps them apart from PayPal's:
+// both providers have a refund and a dispute, and a handler subscribed to one
+// must never be handed the other's payload.
+const (
+	StripePrefix = "STRIPE."
+
+	StripeInvoicePaid          = StripePrefix + "invoice.paid"
+	StripeInvoicePaymentFailed = StripePrefix + "invoice.payment_failed"
+
+	StripeSubscriptionDeleted = StripePrefix + "customer.subscription.deleted"
+	StripeSubscriptionPaused  = StripePrefix + "customer.subscription.paused"
+	StripeSubscriptionUpdated = StripePrefix + "customer.subscription.updated"
+
+	StripeChargeRefunded = StripePrefix + "charge.refunded"
+
+	StripeDisputeCreated = StripePrefix + "charge.dispute.created"
+	StripeDisputeUpdated = StripePrefix + "charge.dispute.updated"
+	StripeDisputeClosed  = StripePrefix + "charge.dispute.closed"
 )
 
 // Paypal payout events.
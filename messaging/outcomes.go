@@ -0,0 +1,116 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// headerTransmissionID carries the provider's transmission id with the event it
+// delivered. It is the key the webhook archive is stored under, and the only
+// thing a consumer has that ties a message back to what PayPal actually sent.
+const headerTransmissionID = "Fund-Transmission-Id"
+
+// Outcome is what one consumer made of one delivery of a webhook.
+type Outcome struct {
+	TransmissionID string
+
+	// Consumer is the event type it subscribed to. There is one durable per type,
+	// so this says which handler ran.
+	Consumer string
+
+	// StreamSeq and Delivery place the attempt. A replayed dead letter is a new
+	// message with its own sequence, and counts its deliveries from one again.
+	StreamSeq uint64
+	Delivery  int
+
+	// Err is what the handler returned, nil when it handled the message.
+	Err error
+
+	// Final is set on a failure JetStream will not redeliver. The next anybody
+	// hears of the message is as a dead letter.
+	Final bool
+}
+
+// outcomeRecorder is where outcomes go. The webhook archive, in practice.
+type outcomeRecorder interface {
+	RecordOutcome(ctx context.Context, outcome Outcome) error
+}
+
+// RecordOutcomes has every consumer report what it made of each webhook it is
+// handed. Call it before Subscribe; a consumer started earlier reports nothing.
+//
+// Only messages published with PublishWebhook are reported, since an outcome
+// with no transmission id has nothing to be filed against.
+func (b *Broker) RecordOutcomes(recorder outcomeRecorder) {
+	b.outcomes = recorder
+}
+
+// PublishWebhook is Publish for an event that arrived as a webhook. The
+// transmission id goes with it as a header, so the consumers' outcomes can be
+// filed against the archived payload, and a dead letter keeps it through a
+// replay.
+func (b *Broker) PublishWebhook(event, transmissionID string, data []byte) error {
+	msg := nats.NewMsg(event)
+	msg.Data = data
+	msg.Header.Set(headerTransmissionID, transmissionID)
+
+	ctx, cancel := context.WithTimeout(b.ctx, publishTimeout)
+	defer cancel()
+
+	if _, err := b.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", event, err)
+	}
+
+	return nil
+}
+
+// reportOutcome passes one attempt to the recorder.
+//
+// A failure to record is logged and goes no further. The outcome is a record of
+// what happened to the message, and failing the message over it would have the
+// handler run again for the sake of its own paperwork.
+func (b *Broker) reportOutcome(event string, msg jetstream.Msg, errHandle error) {
+	if b.outcomes == nil {
+		return
+	}
+
+	transmissionID := msg.Headers().Get(headerTransmissionID)
+	if transmissionID == "" {
+		return
+	}
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		b.logger.Error("failed to read message metadata for its outcome",
+			slog.String("event", event),
+			slog.String("error", err.Error()),
+		)
+
+		return
+	}
+
+	outcome := Outcome{
+		TransmissionID: transmissionID,
+		Consumer:       event,
+		StreamSeq:      meta.Sequence.Stream,
+		Delivery:       int(meta.NumDelivered),
+		Err:            errHandle,
+		// MaxDeliver is set from the backoff schedule in Subscribe.
+		Final: errHandle != nil && int(meta.NumDelivered) >= len(b.backOff)+1,
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, publishTimeout)
+	defer cancel()
+
+	if err = b.outcomes.RecordOutcome(ctx, outcome); err != nil {
+		b.logger.Error("failed to record webhook outcome",
+			slog.String("event", event),
+			slog.String("transmission_id", transmissionID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,157 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedOutcomes keeps every outcome reported, in order.
+type recordedOutcomes struct {
+	mu       sync.Mutex
+	outcomes []Outcome
+}
+
+func (r *recordedOutcomes) RecordOutcome(_ context.Context, outcome Outcome) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.outcomes = append(r.outcomes, outcome)
+
+	return nil
+}
+
+func (r *recordedOutcomes) waitFor(t *testing.T, n int) []Outcome {
+	t.Helper()
+
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		r.mu.Lock()
+		got := append([]Outcome(nil), r.outcomes...)
+		r.mu.Unlock()
+
+		if len(got) >= n {
+			return got
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d outcome(s), want %d: %+v", len(got), n, got)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Every attempt is reported against the transmission it came in as, and the
+// last failure says it was the last -- which is the difference between "will be
+// retried" and "is now a dead letter" on the archive page. A replay carries the
+// id with it, so what finally handled the event is filed with the rest.
+func TestOutcomesFollowAWebhookThroughItsDeadLetter(t *testing.T) {
+	ctx := context.Background()
+
+	broker := newBroker(t, startServer(t, t.TempDir()))
+	broker.backOff = []time.Duration{10 * time.Millisecond}
+
+	recorder := &recordedOutcomes{}
+	broker.RecordOutcomes(recorder)
+
+	var mu sync.Mutex
+	fixed := false
+
+	if err := broker.Subscribe(PaymentCompleted, func([]byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !fixed {
+			return errNotToday
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	if err := broker.PublishWebhook(PaymentCompleted, "tx-1", []byte(`{"id":"SALE-6"}`)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	failed := recorder.waitFor(t, 2)
+
+	for i, outcome := range failed {
+		if outcome.TransmissionID != "tx-1" || outcome.Consumer != PaymentCompleted || outcome.Err == nil {
+			t.Errorf("attempt %d: %+v", i+1, outcome)
+		}
+
+		if outcome.Delivery != i+1 {
+			t.Errorf("attempt %d reported as delivery %d", i+1, outcome.Delivery)
+		}
+	}
+
+	if failed[0].Final || !failed[1].Final {
+		t.Errorf("only the last attempt is final: %v, %v", failed[0].Final, failed[1].Final)
+	}
+
+	letter := waitForDeadLetter(t, broker)
+	if letter.TransmissionID != "tx-1" {
+		t.Fatalf("the dead letter lost its transmission id: %+v", letter)
+	}
+
+	mu.Lock()
+	fixed = true
+	mu.Unlock()
+
+	if _, err := broker.ReplayDeadLetter(ctx, letter.Seq); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	handled := recorder.waitFor(t, 3)[2]
+
+	if handled.TransmissionID != "tx-1" || handled.Err != nil || handled.Final {
+		t.Errorf("the replay was not reported as handled: %+v", handled)
+	}
+
+	if handled.StreamSeq == failed[0].StreamSeq {
+		t.Error("a replay is a new message and should say so, or its attempts collide with the original's")
+	}
+}
+
+// A message published without an id has nothing to be filed against, and the
+// recorder is not asked to file it.
+func TestPlainPublishesReportNothing(t *testing.T) {
+	broker := newBroker(t, startServer(t, t.TempDir()))
+
+	recorder := &recordedOutcomes{}
+	broker.RecordOutcomes(recorder)
+
+	handled := make(chan struct{}, 1)
+
+	if err := broker.Subscribe(PaymentCompleted, func([]byte) error {
+		handled <- struct{}{}
+
+		return nil
+	}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	if err := broker.Publish(PaymentCompleted, []byte(`{"id":"SALE-7"}`)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	select {
+	case <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("never delivered")
+	}
+
+	// The report is made before the ack, on the same goroutine as the handler,
+	// so by the time the handler has returned there is nothing still to come.
+	time.Sleep(50 * time.Millisecond)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if len(recorder.outcomes) != 0 {
+		t.Errorf("reported %+v for a message with no transmission id", recorder.outcomes)
+	}
+}
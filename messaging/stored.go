@@ -0,0 +1,103 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// storedWait is how long Stored waits for the next message before deciding there
+// is none. The messages are already on disk, so a wait of any length means the
+// range is empty rather than that one is on its way.
+const storedWait = 2 * time.Second
+
+// StoredMessage is one webhook as WEBHOOKS holds it, whether or not any consumer
+// has acknowledged it.
+type StoredMessage struct {
+	Seq      uint64
+	Subject  string
+	Received time.Time
+	Data     []byte
+}
+
+// Stored calls fn with every message received in [since, until), oldest first,
+// optionally only those published as subject.
+//
+// Read through an ordered consumer of its own, which acknowledges nothing and
+// moves none of the durables: the messages are handed to fn and nowhere else,
+// so reading a week back does not redeliver a week of webhooks to the running
+// service. An error from fn stops the walk and is returned.
+//
+// Only as far back as retention. Anything older has gone from the stream, and
+// a range that starts before the oldest message simply starts with it.
+func (b *Broker) Stored(ctx context.Context, since, until time.Time, subject string, fn func(StoredMessage) error) error {
+	config := jetstream.OrderedConsumerConfig{
+		DeliverPolicy: jetstream.DeliverByStartTimePolicy,
+		OptStartTime:  &since,
+	}
+
+	if subject != "" {
+		config.FilterSubjects = []string{subject}
+	}
+
+	consumer, err := b.stream.OrderedConsumer(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to read stored messages: %w", err)
+	}
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		msg, errNext := consumer.Next(jetstream.FetchMaxWait(storedWait))
+		if errors.Is(errNext, nats.ErrTimeout) {
+			return nil
+		}
+
+		if errNext != nil {
+			return fmt.Errorf("failed to read stored messages: %w", errNext)
+		}
+
+		meta, errMeta := msg.Metadata()
+		if errMeta != nil {
+			return fmt.Errorf("failed to read stored message metadata: %w", errMeta)
+		}
+
+		// Sorted by arrival, so the first one past the end is the last one worth
+		// reading.
+		if !meta.Timestamp.Before(until) {
+			return nil
+		}
+
+		// The server's seek by start time is not exact when the consumer filters,
+		// and can begin a message or two early. Checked here as well, so the range
+		// asked for is the range read.
+		if meta.Timestamp.Before(since) {
+			if meta.NumPending == 0 {
+				return nil
+			}
+
+			continue
+		}
+
+		stored := StoredMessage{
+			Seq:      meta.Sequence.Stream,
+			Subject:  msg.Subject(),
+			Received: meta.Timestamp,
+			Data:     msg.Data(),
+		}
+
+		if err = fn(stored); err != nil {
+			return err
+		}
+
+		if meta.NumPending == 0 {
+			return nil
+		}
+	}
+}
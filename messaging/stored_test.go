@@ -0,0 +1,91 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func storedSubjects(t *testing.T, broker *Broker, since, until time.Time, subject string) []string {
+	t.Helper()
+
+	var got []string
+	err := broker.Stored(context.Background(), since, until, subject, func(msg StoredMessage) error {
+		got = append(got, msg.Subject+" "+string(msg.Data))
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stored: %v", err)
+	}
+
+	return got
+}
+
+// A replay reads what is on disk by arrival time and subject, and a range that
+// ends before the newest message leaves it out.
+func TestStoredReadsARangeOfOneSubject(t *testing.T) {
+	broker := newBroker(t, startServer(t, t.TempDir()))
+
+	start := time.Now().Add(-time.Second)
+
+	for _, msg := range []struct{ subject, data string }{
+		{PaymentCompleted, `1`},
+		{PaymentRefunded, `2`},
+		{PaymentCompleted, `3`},
+	} {
+		if err := broker.Publish(msg.subject, []byte(msg.data)); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	middle := time.Now()
+	time.Sleep(20 * time.Millisecond)
+
+	if err := broker.Publish(PaymentCompleted, []byte(`4`)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	got := storedSubjects(t, broker, start, middle, PaymentCompleted)
+	if len(got) != 2 || got[0] != PaymentCompleted+" 1" || got[1] != PaymentCompleted+" 3" {
+		t.Errorf("completed payments before the middle = %v", got)
+	}
+
+	if got = storedSubjects(t, broker, start, time.Now().Add(time.Second), ""); len(got) != 4 {
+		t.Errorf("every subject = %v, want all four", got)
+	}
+
+	if got = storedSubjects(t, broker, middle, time.Now().Add(time.Second), PaymentRefunded); len(got) != 0 {
+		t.Errorf("no refund arrived after the middle, got %v", got)
+	}
+}
+
+// Reading the stream must not be consuming it. The running service's durable
+// still has every message to deliver after a replay has read them all.
+func TestStoredLeavesTheDurablesAlone(t *testing.T) {
+	broker := newBroker(t, startServer(t, t.TempDir()))
+
+	if err := broker.Publish(PaymentCompleted, []byte(`{"id":"SALE-1"}`)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	if got := storedSubjects(t, broker, time.Now().Add(-time.Minute), time.Now().Add(time.Minute), ""); len(got) != 1 {
+		t.Fatalf("stored = %v", got)
+	}
+
+	received := make(chan struct{}, 1)
+	if err := broker.Subscribe(PaymentCompleted, func([]byte) error {
+		received <- struct{}{}
+
+		return nil
+	}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("reading the stored message took it away from the consumer")
+	}
+}
@@ -2,13 +2,11 @@ package paypal
 
 import (
 	"boardfund/paypal/token"
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
+
+	"github.com/google/uuid"
 )
 
 type Client struct {
@@ -16,6 +14,10 @@ type Client struct {
 	httpClient *http.Client
 	logger     *slog.Logger
 	baseURL    string
+
+	retry   retryPolicy
+	breaker *breaker
+	stats   *endpointStats
 }
 
 func NewClient(paypalAuth *token.Store, logger *slog.Logger, baseURL string) *Client {
@@ -24,136 +26,29 @@ func NewClient(paypalAuth *token.Store, logger *slog.Logger, baseURL string) *Cl
 		httpClient: http.DefaultClient,
 		logger:     logger,
 		baseURL:    baseURL,
+		retry:      defaultRetryPolicy,
+		breaker:    newBreaker(),
+		stats:      &endpointStats{endpoints: make(map[string]*EndpointStats)},
 	}
 }
 
 func (c Client) post(ctx context.Context, path string, payload any) error {
-	token, err := c.paypalAuth.GetToken(ctx)
-	if err != nil {
-		return err
-	}
-
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	payloadReader := bytes.NewReader(payloadBytes)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, payloadReader)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Add("Authorization", token.TokenType+" "+token.AccessToken)
-	req.Header.Add("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-
-	defer resp.Body.Close()
+	_, err := c.postWithResponse(ctx, path, payload)
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		var paypalErr ErrPaypal
-		err = json.NewDecoder(resp.Body).Decode(&paypalErr)
-		if err != nil {
-			return fmt.Errorf("error decoding paypal error: %w", err)
-		}
-
-		c.logger.ErrorContext(ctx, "error from paypal", slog.Any("details", paypalErr.Details), slog.String("message", paypalErr.Message))
-
-		return paypalErr
-	}
-
-	return nil
+	return err
 }
 
+// postWithResponse gives the request an id of its own, which is what makes
+// retrying it safe. A caller with an idempotency key of its own uses
+// postWithRequestID, so a retry from a later run is recognised too.
 func (c Client) postWithResponse(ctx context.Context, path string, payload any) ([]byte, error) {
-	paypalToken, err := c.paypalAuth.GetToken(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
-	}
-
-	payloadReader := bytes.NewReader(payloadBytes)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, payloadReader)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Add("Authorization", paypalToken.TokenType+" "+paypalToken.AccessToken)
-	req.Header.Add("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		var paypalErr ErrPaypal
-		err = json.Unmarshal(body, &paypalErr)
-		if err != nil {
-			return nil, fmt.Errorf("error unmarshalling error response: %w", err)
-		}
-
-		c.logger.ErrorContext(ctx, "error from paypal", slog.Any("details", paypalErr.Details), slog.String("message", paypalErr.Message))
-
-		return nil, paypalErr
-	}
+	return c.postWithRequestID(ctx, path, uuid.NewString(), payload)
+}
 
-	return body, nil
+func (c Client) postWithRequestID(ctx context.Context, path, requestID string, payload any) ([]byte, error) {
+	return c.do(ctx, http.MethodPost, path, requestID, payload)
 }
 
 func (c Client) get(ctx context.Context, path string) ([]byte, error) {
-	token, err := c.paypalAuth.GetToken(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Add("Authorization", token.TokenType+" "+token.AccessToken)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		var paypalErr ErrPaypal
-		err = json.Unmarshal(body, &paypalErr)
-		if err != nil {
-			return nil, fmt.Errorf("error unmarshalling error response: %w", err)
-		}
-
-		c.logger.ErrorContext(ctx, "error from paypal", slog.Any("details", paypalErr.Details), slog.String("message", paypalErr.Message))
-
-		return nil, paypalErr
-	}
-
-	return body, nil
+	return c.do(ctx, http.MethodGet, path, "", nil)
 }
@@ -0,0 +1,50 @@
+package paypal
+
+import (
+	"boardfund/service/donations"
+	"boardfund/service/payouts"
+)
+
+// serviceErrors is what PayPal's error names mean to the services.
+//
+// PayPal names an error in one of two places. A payouts refusal names it at
+// the top, as INSUFFICIENT_FUNDS; an order refusal says UNPROCESSABLE_ENTITY at
+// the top and puts the reason in a detail's issue, as INSTRUMENT_DECLINED.
+// Both are looked up here, so callers branch on the service's own errors with
+// errors.Is and never on PayPal's spelling.
+//
+// Anything missing stays an ErrPaypal and nothing more, which is what it was
+// before any of these were named: a failure with PayPal's message on it.
+var serviceErrors = map[string]error{
+	// The donor's card or account said no.
+	"INSTRUMENT_DECLINED":                     donations.ErrPaymentDeclined,
+	"PAYER_CANNOT_PAY":                        donations.ErrPaymentDeclined,
+	"TRANSACTION_REFUSED":                     donations.ErrPaymentDeclined,
+	"PAYER_ACCOUNT_RESTRICTED":                donations.ErrPaymentDeclined,
+	"CARD_EXPIRED":                            donations.ErrPaymentDeclined,
+	"MAX_NUMBER_OF_PAYMENT_ATTEMPTS_EXCEEDED": donations.ErrPaymentDeclined,
+
+	// The fund's own account cannot cover a payout batch.
+	"INSUFFICIENT_FUNDS":   payouts.ErrInsufficientFunds,
+	"SENDER_EMPTY_BALANCE": payouts.ErrInsufficientFunds,
+
+	// A payout item's address has nobody behind it.
+	"RECEIVER_UNREGISTERED":   payouts.ErrReceiverUnregistered,
+	"RECEIVER_ACCOUNT_LOCKED": payouts.ErrReceiverUnregistered,
+}
+
+// Unwrap is the service error the refusal means, or nil for one without a
+// meaning of its own. The name wins over the details when both are known.
+func (e ErrPaypal) Unwrap() error {
+	if err, ok := serviceErrors[e.Name]; ok {
+		return err
+	}
+
+	for _, detail := range e.Details {
+		if err, ok := serviceErrors[detail.Issue]; ok {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package paypal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"boardfund/service/donations"
+	"boardfund/service/payouts"
+
+	"github.com/stretchr/testify/require"
+)
+
+// PayPal puts the reason at the top for payouts and in the details for orders.
+// Either way the caller branches on the service's error, and still has
+// PayPal's own to log.
+func TestPayPalRefusalsReadAsServiceErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"declined in the details",
+			`{"name": "UNPROCESSABLE_ENTITY", "message": "The requested action could not be performed.",
+			  "details": [{"issue": "INSTRUMENT_DECLINED", "description": "The instrument presented was declined."}]}`,
+			donations.ErrPaymentDeclined},
+		{"an empty payouts account",
+			`{"name": "INSUFFICIENT_FUNDS", "message": "Sender does not have sufficient funds."}`,
+			payouts.ErrInsufficientFunds},
+		{"a payout to nobody",
+			`{"name": "RECEIVER_UNREGISTERED", "message": "Receiver is unregistered"}`,
+			payouts.ErrReceiverUnregistered},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var refusal ErrPaypal
+			require.NoError(t, json.Unmarshal([]byte(c.body), &refusal))
+
+			err := error(refusal)
+			require.ErrorIs(t, err, c.want)
+
+			var paypalErr ErrPaypal
+			require.ErrorAs(t, err, &paypalErr)
+			require.NotEmpty(t, paypalErr.Message)
+		})
+	}
+
+	unnamed := ErrPaypal{Name: "RESOURCE_NOT_FOUND", Message: "not found"}
+	require.Nil(t, unnamed.Unwrap())
+	require.False(t, errors.Is(unnamed, donations.ErrPaymentDeclined))
+}
+
+// The buttons capture in the browser, so a decline reaches the server as an
+// order whose capture was refused, not as an error from PayPal.
+func TestARefusedCaptureIsADecline(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"id": "ORDER-1", "status": "COMPLETED", "purchase_units": [{
+			"reference_id": "fund",
+			"payments": {"captures": [{"id": "CAP-1", "status": "DECLINED", "amount": {"value": "25.00"}}]}
+		}]}`))
+	})
+
+	_, err := NewPaypal(client).GetOrder(context.Background(), "ORDER-1")
+	require.ErrorIs(t, err, donations.ErrPaymentDeclined)
+}
+
+func TestAnItemWithoutErrorsIsNoError(t *testing.T) {
+	require.NoError(t, itemError(nil))
+	require.ErrorIs(t, itemError(&ErrPaypal{Name: "RECEIVER_UNREGISTERED"}), payouts.ErrReceiverUnregistered)
+}
@@ -15,7 +15,8 @@ import (
 // senderBatchID is PayPal's idempotency key: a second call carrying a
 // sender_batch_id PayPal has already seen is rejected rather than paid again. It is
 // persisted before this call is made, so a request that times out can be retried
-// without risking a duplicate payout.
+// without risking a duplicate payout. It is the request id too, so a retry inside
+// the call gets back the first attempt's answer rather than a refusal.
 //
 // The response carries only the batch header -- no per-item IDs. Those are learned
 // from GetBatchStatus, matched back via sender_item_id.
@@ -54,7 +55,7 @@ func (p Paypal) SubmitBatch(ctx context.Context, senderBatchID uuid.UUID, note s
 		Items: payoutItems,
 	}
 
-	responseBytes, err := p.client.postWithResponse(ctx, "/v1/payments/payouts", request)
+	responseBytes, err := p.client.postWithRequestID(ctx, "/v1/payments/payouts", senderBatchID.String(), request)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +103,7 @@ func (p Paypal) GetBatchStatus(ctx context.Context, providerBatchID string) (*pa
 			ProviderPayoutItemID: item.PayoutItemID,
 			Status:               item.TransactionStatus,
 			FeeCents:             decimalDollarStringToCents(item.PayoutItemFee.Value),
+			Err:                  itemError(item.Errors),
 		})
 	}
 
@@ -111,3 +113,14 @@ func (p Paypal) GetBatchStatus(ctx context.Context, providerBatchID string) (*pa
 		Items:           items,
 	}, nil
 }
+
+// itemError is an item's refusal as an error, or nil for an item without one.
+// A nil *ErrPaypal has to stay a nil error: wrapped in the interface it would
+// read as a failure.
+func itemError(errs *ErrPaypal) error {
+	if errs == nil {
+		return nil
+	}
+
+	return *errs
+}
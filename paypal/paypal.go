@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"strconv"
 	"strings"
@@ -74,7 +75,18 @@ func (p Paypal) CreateFund(ctx context.Context, name, description string) (strin
 	return response.ID, nil
 }
 
+// CreatePlan registers a billing plan for one amount on one billing cycle.
+//
+// The count is what makes a cycle of two weeks or three months, for donors who
+// give once per payout on a biweekly or quarterly fund. It used to be fixed at
+// one, so every plan billed weekly or monthly whatever it was asked for; a
+// request without a count still gets one.
 func (p Paypal) CreatePlan(ctx context.Context, plan donations.CreatePlan) (string, error) {
+	intervalCount := plan.IntervalCount
+	if intervalCount < 1 {
+		intervalCount = 1
+	}
+
 	payload := CreatePlanRequest{
 		Name:      plan.Name,
 		ProductID: plan.ProviderFundID,
@@ -85,7 +97,7 @@ func (p Paypal) CreatePlan(ctx context.Context, plan donations.CreatePlan) (stri
 				TotalCycles: 0,
 				Frequency: Frequency{
 					IntervalUnit:  string(plan.IntervalUnit),
-					IntervalCount: 1,
+					IntervalCount: intervalCount,
 				},
 				PricingScheme: PricingScheme{
 					FixedPrice: FixedPrice{
@@ -125,7 +137,9 @@ func (p Paypal) DeactivatePlan(ctx context.Context, planID string) error {
 	return p.client.post(ctx, "/v1/billing/plans/"+planID+"/deactivate", nil)
 }
 
-func (p Paypal) InitiateDonation(ctx context.Context, fund donations.Fund, amountCents int32) (string, error) {
+// InitiateDonation creates the order PayPal's buttons approve. The donor never
+// leaves our page, so the return and cancel URLs are not used.
+func (p Paypal) InitiateDonation(ctx context.Context, fund donations.Fund, amountCents int32, _, _ string) (*donations.Checkout, error) {
 	orderRequest := CreateOrderRequest{
 		Intent: "CAPTURE",
 		PurchaseUnits: []OrderPurchaseUnits{
@@ -143,16 +157,22 @@ func (p Paypal) InitiateDonation(ctx context.Context, fund donations.Fund, amoun
 
 	orderResponseBytes, err := p.client.postWithResponse(ctx, "/v2/checkout/orders", orderRequest)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var orderResponse CreateOrderResponse
 	err = json.Unmarshal(orderResponseBytes, &orderResponse)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return orderResponse.ID, nil
+	return &donations.Checkout{ID: orderResponse.ID}, nil
+}
+
+// InitiateSubscription has nothing to ask PayPal. Its subscribe button creates
+// the subscription itself from the plan id, so the checkout is the plan.
+func (p Paypal) InitiateSubscription(_ context.Context, plan donations.DonationPlan, _, _ string) (*donations.Checkout, error) {
+	return &donations.Checkout{ID: plan.ProviderPlanID}, nil
 }
 
 // GetOrder reads an order back from PayPal so a one-time donation can be
@@ -175,9 +195,27 @@ func (p Paypal) GetOrder(ctx context.Context, orderID string) (*donations.Provid
 
 	result := orderFromResponse(order)
 
+	// Nothing captured and a capture refused: the donor's card said no, which
+	// is theirs to try again rather than an order that merely is not finished.
+	if result.ProviderPaymentID == "" && captureDeclined(order) {
+		return nil, donations.ErrPaymentDeclined
+	}
+
 	return &result, nil
 }
 
+func captureDeclined(order PaymentCaptureResponse) bool {
+	for _, unit := range order.PurchaseUnits {
+		for _, capture := range unit.Payments.Captures {
+			if capture.Status == "DECLINED" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // orderFromResponse is the mapping, split out so it can be exercised without a
 // PayPal on the other end. Everything interesting about GetOrder happens here.
 func orderFromResponse(order PaymentCaptureResponse) donations.ProviderOrder {
@@ -242,6 +280,46 @@ func (p Paypal) GetSubscription(ctx context.Context, subscriptionID string) (*do
 	}, nil
 }
 
+// ReviseSubscription moves a subscription onto another plan, and returns the
+// page the donor has to approve the change on.
+//
+// A price change is not something PayPal lets the merchant make alone. The
+// revision stays pending until the donor approves it at the returned link and
+// is sent back to returnURL, and the subscription keeps billing on the old plan
+// until then. An empty link means PayPal applied it without asking, which the
+// caller can confirm straight away.
+func (p Paypal) ReviseSubscription(ctx context.Context, subscriptionID, providerPlanID, returnURL, cancelURL string) (string, error) {
+	request := ReviseSubscriptionRequest{
+		PlanID: providerPlanID,
+		ApplicationContext: ApplicationContext{
+			ReturnURL: returnURL,
+			CancelURL: cancelURL,
+		},
+	}
+
+	responseBytes, err := p.client.postWithResponse(ctx, "/v1/billing/subscriptions/"+subscriptionID+"/revise", request)
+	if err != nil {
+		return "", err
+	}
+
+	var response ReviseSubscriptionResponse
+	if err = json.Unmarshal(responseBytes, &response); err != nil {
+		return "", err
+	}
+
+	return approveLink(response.Links), nil
+}
+
+func approveLink(links []Link) string {
+	for _, link := range links {
+		if link.Rel == "approve" {
+			return link.Href
+		}
+	}
+
+	return ""
+}
+
 func (p Paypal) GetProviderDonationSubscriptionStatus(ctx context.Context, providerSubscriptionID string) (string, error) {
 	subscriptionBytes, err := p.client.get(ctx, "/v1/billing/subscriptions/"+providerSubscriptionID)
 	if err != nil {
@@ -351,6 +429,136 @@ func transactionFromResponse(transaction Transaction) (*finance.ProviderTransact
 	}, nil
 }
 
+// searchWindow is the longest span the transaction search answers for at once.
+const searchWindow = 31 * 24 * time.Hour
+
+// SearchPayments is the one-time money PayPal took for us between start and end,
+// each with the fund its order was created for.
+//
+// The transaction search is the only PayPal API that lists money by date rather
+// than by an id we already hold, and the payments this is for are exactly the
+// ones we hold nothing about. It answers for a month at most and a page at a
+// time, and it does not carry the order's reference, so the fund is read from
+// each payment's order afterwards.
+func (p Paypal) SearchPayments(ctx context.Context, start, end time.Time) ([]finance.ProviderPayment, error) {
+	var payments []finance.ProviderPayment
+
+	for from := start; from.Before(end); from = from.Add(searchWindow) {
+		to := from.Add(searchWindow)
+		if to.After(end) {
+			to = end
+		}
+
+		for page := 1; ; page++ {
+			path := "/v1/reporting/transactions" +
+				"?start_date=" + url.QueryEscape(from.UTC().Format(time.RFC3339)) +
+				"&end_date=" + url.QueryEscape(to.UTC().Format(time.RFC3339)) +
+				"&transaction_status=S&fields=transaction_info,payer_info&page_size=500" +
+				"&page=" + strconv.Itoa(page)
+
+			transactionBytes, err := p.client.get(ctx, path)
+			if err != nil {
+				return nil, err
+			}
+
+			var transactions Transaction
+			if err = json.Unmarshal(transactionBytes, &transactions); err != nil {
+				return nil, err
+			}
+
+			for _, detail := range transactions.TransactionDetails {
+				payment, errPayment := paymentFromDetail(detail)
+				if errPayment != nil {
+					return nil, errPayment
+				}
+
+				if payment == nil {
+					continue
+				}
+
+				// One order that cannot be read back leaves its payment without a fund,
+				// which the import lists as unmatched for an admin to place. Failing the
+				// search for it would leave every other payment in the window unread.
+				if payment.ProviderOrderID != "" {
+					order, errOrder := p.GetOrder(ctx, payment.ProviderOrderID)
+					if errOrder != nil {
+						if ctx.Err() != nil {
+							return nil, ctx.Err()
+						}
+
+						p.client.logger.WarnContext(ctx, "could not read back the order for a payment",
+							slog.String("provider_payment_id", payment.ProviderPaymentID),
+							slog.String("provider_order_id", payment.ProviderOrderID),
+							slog.String("error", errOrder.Error()))
+					} else {
+						payment.FundReferenceID = order.FundReferenceID
+					}
+				}
+
+				payments = append(payments, *payment)
+			}
+
+			if page >= transactions.TotalPages {
+				break
+			}
+		}
+	}
+
+	return payments, nil
+}
+
+// paymentFromDetail is the payment a listed transaction is, or nil for one the
+// import has no business with.
+//
+// Only money coming in from another PayPal account, the T00 event codes, and
+// not a subscription's: recurring payments belong to donations we hold and are
+// backfilled from the subscription. A refund, a fee or a payout is money going
+// out and is negative.
+func paymentFromDetail(detail TransactionDetails) (*finance.ProviderPayment, error) {
+	info := detail.TransactionInfo
+
+	if info.TransactionStatus != "S" ||
+		!strings.HasPrefix(info.TransactionEventCode, "T00") ||
+		info.TransactionEventCode == "T0002" ||
+		info.PaypalReferenceIDType == "SUB" {
+		return nil, nil
+	}
+
+	amountCents := decimalDollarStringToCents(info.TransactionAmount.Value)
+	if amountCents <= 0 {
+		return nil, nil
+	}
+
+	date, err := parseProviderTime(info.TransactionInitiationDate)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &finance.ProviderPayment{
+		ProviderPaymentID: info.TransactionID,
+		AmountCents:       amountCents,
+		FeeCents:          feeCentsFromProvider(info.FeeAmount.Value),
+		Date:              date,
+		PayerID:           detail.PayerInfo.AccountID,
+		PayerEmail:        detail.PayerInfo.EmailAddress,
+		PayerName:         payerName(detail.PayerInfo.PayerName),
+	}
+
+	if info.PaypalReferenceIDType == "ODR" {
+		payment.ProviderOrderID = info.PaypalReferenceID
+	}
+
+	return payment, nil
+}
+
+func payerName(name PayerName) string {
+	if name.AlternateFullName != "" {
+		return name.AlternateFullName
+	}
+
+	return strings.TrimSpace(name.GivenName + " " + name.Surname)
+}
+
 // feeCentsFromProvider reads a fee the reporting API states as a negative amount.
 //
 // Always returned as a positive number of cents: a fee is a fee whichever sign
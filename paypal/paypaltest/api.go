@@ -0,0 +1,666 @@
+package paypaltest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"boardfund/paypal"
+)
+
+// reportingTime is how the transaction search writes a date: an offset with no
+// colon, which RFC3339 refuses and paypal.parseProviderTime has to allow for.
+const reportingTime = "2006-01-02T15:04:05-0700"
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v1/oauth2/token", s.token)
+
+	mux.HandleFunc("POST /v1/catalogs/products", s.authorized(s.createProduct))
+	mux.HandleFunc("POST /v1/billing/plans", s.authorized(s.createPlan))
+	mux.HandleFunc("POST /v1/billing/plans/{id}/activate", s.authorized(s.setPlanActive(true)))
+	mux.HandleFunc("POST /v1/billing/plans/{id}/deactivate", s.authorized(s.setPlanActive(false)))
+
+	mux.HandleFunc("POST /v2/checkout/orders", s.authorized(s.createOrder))
+	mux.HandleFunc("GET /v2/checkout/orders/{id}", s.authorized(s.getOrder))
+
+	mux.HandleFunc("GET /v1/billing/subscriptions/{id}", s.authorized(s.getSubscription))
+	mux.HandleFunc("POST /v1/billing/subscriptions/{id}/cancel", s.authorized(s.cancelSubscription))
+	mux.HandleFunc("POST /v1/billing/subscriptions/{id}/revise", s.authorized(s.reviseSubscription))
+	mux.HandleFunc("GET /v1/billing/subscriptions/{id}/transactions", s.authorized(s.subscriptionTransactions))
+
+	mux.HandleFunc("GET /v1/reporting/transactions", s.authorized(s.searchTransactions))
+
+	mux.HandleFunc("POST /v1/payments/payouts", s.authorized(s.createPayout))
+	mux.HandleFunc("GET /v1/payments/payouts/{id}", s.authorized(s.getPayout))
+
+	return mux
+}
+
+// token issues a bearer token for the fixed client credentials. Every token
+// stays good for the server's lifetime; expires_in is what the client caches
+// by, and nothing here needs to see a token lapse.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != ClientID || secret != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{
+			"error":             "invalid_client",
+			"error_description": "Client Authentication failed",
+		})
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "unsupported_grant_type",
+			"error_description": "Grant Type is NULL",
+		})
+
+		return
+	}
+
+	raw := make([]byte, 16)
+	_, _ = rand.Read(raw)
+	accessToken := "A21AA" + hex.EncodeToString(raw)
+
+	s.mu.Lock()
+	s.tokens[accessToken] = true
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"scope":        "https://uri.paypal.com/services/payments/payment",
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"app_id":       "APP-PAYPALTEST",
+		"expires_in":   32400,
+		"nonce":        strconv.FormatInt(time.Now().UnixNano(), 10),
+	})
+}
+
+// authorized refuses a request without a token this server issued, with the
+// error PayPal gives for one.
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		s.mu.Lock()
+		known := s.tokens[accessToken]
+		s.mu.Unlock()
+
+		if !found || !known {
+			writeError(w, http.StatusUnauthorized, "AUTHENTICATION_FAILURE",
+				"Authentication failed due to invalid authentication credentials or a missing Authorization header.")
+
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *Server) createProduct(w http.ResponseWriter, r *http.Request) {
+	var request paypal.CreateProduct
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	if request.Name == "" {
+		writeInvalid(w, "/name", "MISSING_REQUIRED_PARAMETER")
+
+		return
+	}
+
+	s.mu.Lock()
+	created := &product{id: s.nextID("PROD"), name: request.Name}
+	s.products[created.id] = created
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, paypal.CreateProductResponse{ID: created.id})
+}
+
+func (s *Server) createPlan(w http.ResponseWriter, r *http.Request) {
+	var request paypal.CreatePlanRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	if len(request.BillingCycles) == 0 {
+		writeInvalid(w, "/billing_cycles", "MISSING_REQUIRED_PARAMETER")
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.products[request.ProductID] == nil {
+		writeInvalid(w, "/product_id", "INVALID_PARAMETER_VALUE")
+
+		return
+	}
+
+	created := &plan{
+		id:          s.nextID("P"),
+		productID:   request.ProductID,
+		amountCents: cents(request.BillingCycles[0].PricingScheme.FixedPrice.Value),
+		active:      true,
+	}
+	s.plans[created.id] = created
+
+	writeJSON(w, http.StatusCreated, paypal.CreatePlanResponse{ID: created.id})
+}
+
+func (s *Server) setPlanActive(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		found := s.plans[r.PathValue("id")]
+		if found == nil {
+			writeNotFound(w)
+
+			return
+		}
+
+		found.active = active
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
+	var request paypal.CreateOrderRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	if len(request.PurchaseUnits) == 0 {
+		writeInvalid(w, "/purchase_units", "MISSING_REQUIRED_PARAMETER")
+
+		return
+	}
+
+	unit := request.PurchaseUnits[0]
+
+	s.mu.Lock()
+	created := &order{
+		id:          s.nextID("ORDER"),
+		referenceID: unit.ReferenceID,
+		amountCents: cents(unit.Amount.Value),
+		status:      "CREATED",
+	}
+	s.orders[created.id] = created
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, paypal.CreateOrderResponse{
+		ID: created.id,
+		Links: []paypal.Link{
+			{Href: s.api.URL + "/v2/checkout/orders/" + created.id, Rel: "self", Method: "GET"},
+			{Href: s.api.URL + "/checkoutnow?token=" + created.id, Rel: "approve", Method: "GET"},
+		},
+	})
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.orders[r.PathValue("id")]
+	if found == nil {
+		writeNotFound(w)
+
+		return
+	}
+
+	unit := paypal.CapturePurchaseUnits{ReferenceID: found.referenceID}
+	if found.capture != nil {
+		unit.Payments.Captures = []paypal.Captures{{
+			ID:     found.capture.id,
+			Status: "COMPLETED",
+			Amount: paypal.Amount{CurrencyCode: "USD", Value: dollars(found.capture.amountCents)},
+			SellerReceivableBreakdown: paypal.SellerReceivableBreakdown{
+				GrossAmount: paypal.GrossAmount{CurrencyCode: "USD", Value: dollars(found.capture.amountCents)},
+				PaypalFee:   paypal.PaypalFee{CurrencyCode: "USD", Value: dollars(found.capture.feeCents)},
+				NetAmount:   paypal.NetAmount{CurrencyCode: "USD", Value: dollars(found.capture.amountCents - found.capture.feeCents)},
+			},
+			CreateTime: found.capture.created,
+			UpdateTime: found.capture.created,
+		}}
+	}
+
+	writeJSON(w, http.StatusOK, paypal.PaymentCaptureResponse{
+		ID:            found.id,
+		Status:        found.status,
+		PurchaseUnits: []paypal.CapturePurchaseUnits{unit},
+	})
+}
+
+func (s *Server) getSubscription(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.subscriptions[r.PathValue("id")]
+	if found == nil {
+		writeNotFound(w)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, found.resource())
+}
+
+func (s *Server) cancelSubscription(w http.ResponseWriter, r *http.Request) {
+	var request paypal.CancelSubscriptionRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.subscriptions[r.PathValue("id")]
+	if found == nil {
+		writeNotFound(w)
+
+		return
+	}
+
+	// PayPal refuses to cancel what is already cancelled, rather than treating it
+	// as done -- which is why a second cancellation is worth a test.
+	if found.status == "CANCELLED" {
+		writeError(w, http.StatusUnprocessableEntity, "UNPROCESSABLE_ENTITY",
+			"The requested action could not be performed, semantically incorrect, or failed business validation.",
+			paypal.FieldError{Issue: "SUBSCRIPTION_STATUS_INVALID", Description: "Invalid subscription status for cancel action; subscription status should be active or suspended."})
+
+		return
+	}
+
+	found.status = "CANCELLED"
+	found.updated = time.Now().UTC()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reviseSubscription holds the new plan until ApproveRevision, as PayPal holds
+// it until the donor approves the change.
+func (s *Server) reviseSubscription(w http.ResponseWriter, r *http.Request) {
+	var request paypal.ReviseSubscriptionRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.subscriptions[r.PathValue("id")]
+	if found == nil {
+		writeNotFound(w)
+
+		return
+	}
+
+	if s.plans[request.PlanID] == nil {
+		writeInvalid(w, "/plan_id", "INVALID_PARAMETER_VALUE")
+
+		return
+	}
+
+	found.pendingPlan = request.PlanID
+
+	writeJSON(w, http.StatusOK, paypal.ReviseSubscriptionResponse{
+		PlanID: request.PlanID,
+		Links: []paypal.Link{
+			{Href: s.api.URL + "/webapps/billing/subscriptions/update?ba_token=" + found.id, Rel: "approve", Method: "GET"},
+			{Href: s.api.URL + "/v1/billing/subscriptions/" + found.id, Rel: "edit", Method: "PATCH"},
+		},
+	})
+}
+
+func (s *Server) subscriptionTransactions(w http.ResponseWriter, r *http.Request) {
+	start, end, ok := window(w, r, "start_time", "end_time", time.RFC3339)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriptionID := r.PathValue("id")
+	if s.subscriptions[subscriptionID] == nil {
+		writeNotFound(w)
+
+		return
+	}
+
+	response := paypal.SubscriptionTransactions{Transactions: []paypal.Transactions{}}
+
+	for _, paid := range s.salesInOrder() {
+		if paid.subscriptionID != subscriptionID || paid.created.Before(start) || paid.created.After(end) {
+			continue
+		}
+
+		response.Transactions = append(response.Transactions, paypal.Transactions{
+			ID:     paid.id,
+			Status: paid.status(),
+			AmountWithBreakdown: paypal.AmountWithBreakdown{
+				GrossAmount: paypal.GrossAmount{CurrencyCode: "USD", Value: dollars(paid.amountCents)},
+				FeeAmount:   paypal.FeeAmount{CurrencyCode: "USD", Value: dollars(paid.feeCents)},
+				NetAmount:   paypal.NetAmount{CurrencyCode: "USD", Value: dollars(paid.amountCents - paid.feeCents)},
+			},
+			Time: paid.created,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// searchTransactions answers the reporting API for one transaction id, which is
+// the only way it is asked. Like PayPal's, it finds nothing outside the window.
+// searchTransactions answers both of the ways this client searches: for one
+// transaction by id, and for every transaction in a window, a page at a time.
+func (s *Server) searchTransactions(w http.ResponseWriter, r *http.Request) {
+	start, end, ok := window(w, r, "start_date", "end_date", time.RFC3339)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []paypal.TransactionDetails
+
+	for _, taken := range s.salesInOrder() {
+		if id := query.Get("transaction_id"); id != "" && taken.id != id {
+			continue
+		}
+
+		if taken.created.Before(start) || taken.created.After(end) {
+			continue
+		}
+
+		detail := taken.transactionDetails()
+		if status := query.Get("transaction_status"); status != "" && detail.TransactionInfo.TransactionStatus != status {
+			continue
+		}
+
+		matched = append(matched, detail)
+	}
+
+	pageSize := 100
+	if size, err := strconv.Atoi(query.Get("page_size")); err == nil && size > 0 {
+		pageSize = size
+	}
+
+	page := 1
+	if asked, err := strconv.Atoi(query.Get("page")); err == nil && asked > 0 {
+		page = asked
+	}
+
+	response := paypal.Transaction{
+		Page:               page,
+		TotalItems:         len(matched),
+		TotalPages:         (len(matched) + pageSize - 1) / pageSize,
+		TransactionDetails: []paypal.TransactionDetails{},
+	}
+
+	if from := (page - 1) * pageSize; from < len(matched) {
+		response.TransactionDetails = matched[from:min(from+pageSize, len(matched))]
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// transactionDetails is the sale as the reporting API lists it. A subscription
+// payment and an order capture carry different event codes and reference ids,
+// which is how a search tells them apart. Called with the lock held.
+func (p *sale) transactionDetails() paypal.TransactionDetails {
+	status := "S"
+	if p.refundedCents == p.amountCents {
+		status = "V"
+	}
+
+	info := paypal.TransactionInfo{
+		TransactionID:             p.id,
+		TransactionEventCode:      "T0002",
+		TransactionInitiationDate: p.created.Format(reportingTime),
+		TransactionUpdatedDate:    p.created.Format(reportingTime),
+		TransactionAmount:         paypal.TransactionAmount{CurrencyCode: "USD", Value: dollars(p.amountCents)},
+		FeeAmount:                 paypal.FeeAmount{CurrencyCode: "USD", Value: "-" + dollars(p.feeCents)},
+		TransactionStatus:         status,
+		PaypalReferenceID:         p.subscriptionID,
+		PaypalReferenceIDType:     "SUB",
+	}
+
+	if p.orderID != "" {
+		info.TransactionEventCode = "T0006"
+		info.PaypalReferenceID = p.orderID
+		info.PaypalReferenceIDType = "ODR"
+	}
+
+	return paypal.TransactionDetails{TransactionInfo: info}
+}
+
+func (s *Server) createPayout(w http.ResponseWriter, r *http.Request) {
+	var request paypal.CreatePayoutRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	if request.SenderBatchHeader.SenderBatchID == "" || len(request.Items) == 0 {
+		writeInvalid(w, "/sender_batch_header/sender_batch_id", "MISSING_REQUIRED_PARAMETER")
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The idempotency the payout service depends on: a sender_batch_id already
+	// used is refused, never paid again.
+	if _, seen := s.senderBatches[request.SenderBatchHeader.SenderBatchID]; seen {
+		writeError(w, http.StatusBadRequest, "USER_BUSINESS_ERROR", "User business error.",
+			paypal.FieldError{
+				Field:    "SENDER_BATCH_ID",
+				Location: "body",
+				Issue:    "Batch with given sender_batch_id already exists",
+			})
+
+		return
+	}
+
+	created := &batch{
+		id:            s.nextID("BATCH"),
+		senderBatchID: request.SenderBatchHeader.SenderBatchID,
+		status:        "PENDING",
+		created:       time.Now().UTC(),
+	}
+
+	for _, item := range request.Items {
+		created.items = append(created.items, &payoutItem{
+			id:           s.nextID("ITEM"),
+			senderItemID: item.SenderItemID,
+			receiver:     item.Receiver,
+			amountCents:  cents(item.Amount.Value),
+			status:       "PENDING",
+		})
+	}
+
+	s.batches[created.id] = created
+	s.senderBatches[created.senderBatchID] = created.id
+
+	writeJSON(w, http.StatusCreated, paypal.CreatePayoutResponse{BatchHeader: created.header()})
+}
+
+func (s *Server) getPayout(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.batches[r.PathValue("id")]
+	if found == nil {
+		writeNotFound(w)
+
+		return
+	}
+
+	// Raw rather than paypal.PayoutItemDetail, whose sender_item_id is lifted out
+	// of payout_item on the way in and so would not be written back there.
+	type detail struct {
+		PayoutItemID      string              `json:"payout_item_id"`
+		TransactionStatus string              `json:"transaction_status"`
+		PayoutItemFee     paypal.PayoutAmount `json:"payout_item_fee"`
+		PayoutBatchID     string              `json:"payout_batch_id"`
+		PayoutItem        paypal.PayoutItem   `json:"payout_item"`
+	}
+
+	items := make([]detail, 0, len(found.items))
+	for _, item := range found.items {
+		items = append(items, detail{
+			PayoutItemID:      item.id,
+			TransactionStatus: item.status,
+			PayoutItemFee:     paypal.PayoutAmount{CurrencyCode: "USD", Value: dollars(item.feeCents)},
+			PayoutBatchID:     found.id,
+			PayoutItem: paypal.PayoutItem{
+				RecipientType: "EMAIL",
+				Receiver:      item.receiver,
+				Amount:        paypal.PayoutAmount{CurrencyCode: "USD", Value: dollars(item.amountCents)},
+				SenderItemID:  item.senderItemID,
+			},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"batch_header": found.header(),
+		"items":        items,
+	})
+}
+
+// resource is the subscription as both the API and its webhooks describe it.
+func (found *subscription) resource() paypal.Subscription {
+	resource := paypal.Subscription{
+		ID:               found.id,
+		PlanID:           found.planID,
+		StartTime:        found.created,
+		CreateTime:       found.created,
+		UpdateTime:       found.updated,
+		Status:           found.status,
+		StatusUpdateTime: found.updated,
+		Subscriber:       paypal.Subscriber{EmailAddress: found.email},
+	}
+
+	if found.lastPayment != nil {
+		resource.BillingInfo.LastPayment = paypal.LastPayment{
+			Amount: paypal.Amount{CurrencyCode: "USD", Value: dollars(found.lastPayment.amountCents)},
+			Time:   found.lastPayment.created,
+		}
+	}
+
+	return resource
+}
+
+func (b *batch) header() paypal.PayoutBatchHeader {
+	return paypal.PayoutBatchHeader{
+		PayoutBatchID:     b.id,
+		BatchStatus:       b.status,
+		TimeCreated:       b.created.Format(time.RFC3339),
+		SenderBatchHeader: paypal.SenderBatchHeader{SenderBatchID: b.senderBatchID},
+	}
+}
+
+func (p *sale) status() string {
+	switch {
+	case p.refundedCents == p.amountCents:
+		return "REFUNDED"
+	case p.refundedCents > 0:
+		return "PARTIALLY_REFUNDED"
+	default:
+		return "COMPLETED"
+	}
+}
+
+// window reads the two ends of a date range, both of which PayPal requires.
+func window(w http.ResponseWriter, r *http.Request, startParam, endParam, layout string) (time.Time, time.Time, bool) {
+	query := r.URL.Query()
+
+	start, errStart := time.Parse(layout, query.Get(startParam))
+	end, errEnd := time.Parse(layout, query.Get(endParam))
+
+	if errStart != nil || errEnd != nil {
+		missing := startParam
+		if errStart == nil {
+			missing = endParam
+		}
+
+		writeInvalid(w, missing, "MISSING_REQUIRED_PARAMETER")
+
+		return time.Time{}, time.Time{}, false
+	}
+
+	return start, end, true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, into any) bool {
+	if err := json.NewDecoder(r.Body).Decode(into); err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_REQUEST", "The request payload is not well formed.",
+			paypal.FieldError{Issue: "MALFORMED_REQUEST_JSON", Description: err.Error()})
+
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError answers in PayPal's error shape, which is what paypal.Client
+// decodes into ErrPaypal.
+func writeError(w http.ResponseWriter, status int, name, message string, details ...paypal.FieldError) {
+	writeJSON(w, status, paypal.ErrPaypal{
+		Name:    name,
+		Message: message,
+		DebugID: fmt.Sprintf("paypaltest-%d", time.Now().UnixNano()),
+		Details: details,
+	})
+}
+
+func writeInvalid(w http.ResponseWriter, field, issue string) {
+	writeError(w, http.StatusBadRequest, "INVALID_REQUEST",
+		"Request is not well-formed, syntactically incorrect, or violates schema.",
+		paypal.FieldError{Field: field, Location: "body", Issue: issue})
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, "RESOURCE_NOT_FOUND", "The specified resource does not exist.",
+		paypal.FieldError{Issue: "INVALID_RESOURCE_ID", Description: "Requested resource ID was not found."})
+}
+
+// cents reads a decimal dollar amount. Anything unreadable is zero, which the
+// tests built on this will notice sooner than an error would be read.
+func cents(value string) int32 {
+	whole, fraction, _ := strings.Cut(strings.TrimSpace(value), ".")
+	fraction = (fraction + "00")[:2]
+
+	dollars, errWhole := strconv.Atoi(whole)
+	hundredths, errFraction := strconv.Atoi(fraction)
+	if errWhole != nil || errFraction != nil {
+		return 0
+	}
+
+	return int32(dollars*100 + hundredths)
+}
+
+func dollars(cents int32) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,232 @@
+package paypaltest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sale is a payment the server has taken: an order captured, or a
+// subscription renewing.
+type Sale struct {
+	ID             string
+	SubscriptionID string
+	AmountCents    int32
+	FeeCents       int32
+	RefundedCents  int32
+	Created        time.Time
+}
+
+func (p *sale) export() Sale {
+	return Sale{
+		ID:             p.id,
+		SubscriptionID: p.subscriptionID,
+		AmountCents:    p.amountCents,
+		FeeCents:       p.feeCents,
+		RefundedCents:  p.refundedCents,
+		Created:        p.created,
+	}
+}
+
+// newSale records money taken now, with PayPal's fee out of it. Called with the
+// lock held.
+func (s *Server) newSale(subscriptionID string, amountCents int32) *sale {
+	taken := &sale{
+		id:             s.nextID("SALE"),
+		subscriptionID: subscriptionID,
+		amountCents:    amountCents,
+		feeCents:       feeFor(amountCents),
+		created:        time.Now().UTC().Truncate(time.Second),
+	}
+	s.sales[taken.id] = taken
+
+	return taken
+}
+
+// CaptureOrder is the donor approving a one-time donation at PayPal and the
+// money being taken.
+func (s *Server) CaptureOrder(orderID string) (Sale, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.orders[orderID]
+	if found == nil {
+		return Sale{}, fmt.Errorf("paypaltest: no order %s", orderID)
+	}
+
+	if found.capture == nil {
+		found.capture = s.newSale("", found.amountCents)
+		found.capture.orderID = orderID
+		found.status = "COMPLETED"
+	}
+
+	return found.capture.export(), nil
+}
+
+// Subscribe is a donor approving a subscription to plan, which the PayPal
+// buttons do without our server being involved. It is returned APPROVED, as
+// PayPal returns it before the first payment; Charge makes it ACTIVE.
+func (s *Server) Subscribe(planID, email string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.plans[planID]
+	if found == nil || !found.active {
+		return "", fmt.Errorf("paypaltest: no active plan %s", planID)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	created := &subscription{
+		id:      s.nextID("I"),
+		planID:  planID,
+		email:   email,
+		status:  "APPROVED",
+		created: now,
+		updated: now,
+	}
+	s.subscriptions[created.id] = created
+
+	return created.id, nil
+}
+
+// Charge is a subscription's payment going through: the first one, or a
+// renewal. It is taken at the price of whatever plan the subscription is on.
+func (s *Server) Charge(subscriptionID string) (Sale, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.subscriptions[subscriptionID]
+	if found == nil {
+		return Sale{}, fmt.Errorf("paypaltest: no subscription %s", subscriptionID)
+	}
+
+	if found.status == "CANCELLED" || found.status == "EXPIRED" {
+		return Sale{}, fmt.Errorf("paypaltest: subscription %s is %s and will not be charged", subscriptionID, found.status)
+	}
+
+	taken := s.newSale(found.id, s.plans[found.planID].amountCents)
+
+	found.lastPayment = taken
+	found.status = "ACTIVE"
+	found.updated = taken.created
+
+	return taken.export(), nil
+}
+
+// ApproveRevision is the donor accepting a change of plan at the link
+// ReviseSubscription handed back. Until then the subscription stays where it
+// was.
+func (s *Server) ApproveRevision(subscriptionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.subscriptions[subscriptionID]
+	if found == nil || found.pendingPlan == "" {
+		return fmt.Errorf("paypaltest: no revision pending on %s", subscriptionID)
+	}
+
+	found.planID = found.pendingPlan
+	found.pendingPlan = ""
+	found.updated = time.Now().UTC().Truncate(time.Second)
+
+	return nil
+}
+
+// SetSubscriptionStatus moves a subscription as PayPal moves one on its own:
+// SUSPENDED after failed payments, EXPIRED at the end of its cycles.
+func (s *Server) SetSubscriptionStatus(subscriptionID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.subscriptions[subscriptionID]
+	if found == nil {
+		return fmt.Errorf("paypaltest: no subscription %s", subscriptionID)
+	}
+
+	found.status = status
+	found.updated = time.Now().UTC().Truncate(time.Second)
+
+	return nil
+}
+
+// Refund returns cents more of a sale, up to what it was.
+func (s *Server) Refund(saleID string, cents int32) (Sale, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.sales[saleID]
+	if found == nil {
+		return Sale{}, fmt.Errorf("paypaltest: no sale %s", saleID)
+	}
+
+	if found.refundedCents+cents > found.amountCents {
+		return Sale{}, fmt.Errorf("paypaltest: refunding %d of %s would return more than was taken", cents, saleID)
+	}
+
+	found.refundedCents += cents
+
+	return found.export(), nil
+}
+
+// SettleBatch pays every item of a batch still pending, with PayPal's per-item
+// fee, and marks the batch done.
+func (s *Server) SettleBatch(batchID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.batches[batchID]
+	if found == nil {
+		return fmt.Errorf("paypaltest: no payout batch %s", batchID)
+	}
+
+	for _, item := range found.items {
+		if item.status == "PENDING" {
+			item.status = "SUCCESS"
+			item.feeCents = payoutItemFeeCents
+		}
+	}
+
+	found.status = "SUCCESS"
+
+	return nil
+}
+
+// SetPayoutItemStatus settles one item some other way -- UNCLAIMED, FAILED,
+// RETURNED -- by the sender_item_id it was submitted with, which is our
+// payout's id.
+func (s *Server) SetPayoutItemStatus(batchID, senderItemID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.batches[batchID]
+	if found == nil {
+		return fmt.Errorf("paypaltest: no payout batch %s", batchID)
+	}
+
+	for _, item := range found.items {
+		if item.senderItemID == senderItemID {
+			item.status = status
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("paypaltest: batch %s has no item %s", batchID, senderItemID)
+}
+
+// salesInOrder is every sale, oldest first. Called with the lock held.
+func (s *Server) salesInOrder() []*sale {
+	ordered := make([]*sale, 0, len(s.sales))
+	for _, taken := range s.sales {
+		ordered = append(ordered, taken)
+	}
+
+	// Ids are numbered and zero-padded, so their order is the order of creation.
+	slices.SortFunc(ordered, func(a, b *sale) int {
+		return strings.Compare(a.id, b.id)
+	})
+
+	return ordered
+}
@@ -0,0 +1,222 @@
+// Package paypaltest is a local stand-in for the PayPal REST API, for tests.
+//
+// It serves the endpoints paypal.Paypal calls -- the OAuth token, catalog
+// products, billing plans and subscriptions, checkout orders, transaction search
+// and payouts -- and keeps what it was told in memory, so a test can drive a
+// donation from checkout to payout without a network or a sandbox account.
+//
+// What a donor does in PayPal's own pages, it cannot: approving an order,
+// subscribing to a plan, a subscription renewing. Those are methods on Server
+// instead, and each returns what PayPal would then report.
+//
+// It also sends webhooks, signed with a certificate it generates and served from
+// a TLS listener of its own, so hooksweb verifies them exactly as it verifies
+// PayPal's once told to trust that listener -- see CertHost.
+//
+// None of PayPal's rules beyond what this codebase relies on are enforced. A
+// plan is active from creation, an order is captured the moment it is
+// approved, and nothing is ever pending unless a test says so.
+package paypaltest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The credentials the token endpoint accepts, and the webhook id the signatures
+// are made over. Fixed, because nothing is gained by a test having to thread them
+// through from the server it started.
+const (
+	ClientID     = "paypaltest-client"
+	ClientSecret = "paypaltest-secret"
+	WebhookID    = "WH-PAYPALTEST"
+)
+
+// feePercentBasisPoints and feeFixedCents are PayPal's standard US rate, taken
+// from every payment the server records so that fee handling is exercised
+// rather than always reading zero.
+const (
+	feePercentBasisPoints = 349
+	feeFixedCents         = 49
+	payoutItemFeeCents    = 25
+)
+
+type Server struct {
+	api   *httptest.Server
+	certs *httptest.Server
+
+	key     *rsa.PrivateKey
+	certPEM []byte
+
+	mu            sync.Mutex
+	seq           int
+	tokens        map[string]bool
+	products      map[string]*product
+	plans         map[string]*plan
+	orders        map[string]*order
+	subscriptions map[string]*subscription
+	sales         map[string]*sale
+	batches       map[string]*batch
+	senderBatches map[string]string
+}
+
+type product struct {
+	id   string
+	name string
+}
+
+type plan struct {
+	id          string
+	productID   string
+	amountCents int32
+	active      bool
+}
+
+type order struct {
+	id          string
+	referenceID string
+	amountCents int32
+	status      string
+	capture     *sale
+}
+
+type subscription struct {
+	id          string
+	planID      string
+	pendingPlan string
+	email       string
+	status      string
+	created     time.Time
+	updated     time.Time
+	lastPayment *sale
+}
+
+// sale is money taken, by an order capture or a subscription payment.
+type sale struct {
+	id             string
+	subscriptionID string
+	// orderID is the order a one-time sale was captured under.
+	orderID       string
+	amountCents   int32
+	feeCents      int32
+	refundedCents int32
+	created       time.Time
+}
+
+type batch struct {
+	id            string
+	senderBatchID string
+	status        string
+	created       time.Time
+	items         []*payoutItem
+}
+
+type payoutItem struct {
+	id           string
+	senderItemID string
+	receiver     string
+	amountCents  int32
+	status       string
+	feeCents     int32
+}
+
+// NewServer starts the API and the certificate host on loopback ports, and
+// stops both when the test ends.
+//
+// Two listeners, because the two are reached by different clients. The API is
+// plain HTTP: paypal.Client uses the default HTTP client, which would refuse
+// the test's own certificate authority. The certificate host has to be HTTPS,
+// since hooksweb refuses to fetch a signing certificate any other way.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("paypaltest: generate key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "messageverificationcerts.paypaltest.local"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("paypaltest: create certificate: %v", err)
+	}
+
+	server := &Server{
+		key:           key,
+		certPEM:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		tokens:        map[string]bool{},
+		products:      map[string]*product{},
+		plans:         map[string]*plan{},
+		orders:        map[string]*order{},
+		subscriptions: map[string]*subscription{},
+		sales:         map[string]*sale{},
+		batches:       map[string]*batch{},
+		senderBatches: map[string]string{},
+	}
+
+	server.api = httptest.NewServer(server.routes())
+
+	certMux := http.NewServeMux()
+	certMux.HandleFunc("GET /v1/notifications/certs/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		_, _ = w.Write(server.certPEM)
+	})
+	server.certs = httptest.NewTLSServer(certMux)
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// URL is the base URL to configure paypal.Client and token.Client with.
+func (s *Server) URL() string {
+	return s.api.URL
+}
+
+// CertHost and CertClient are where the webhook signing certificate is served,
+// and a client that trusts the listener serving it -- what a hooksweb handler
+// needs, as a hooksweb.CertSource, to verify this server's webhooks.
+func (s *Server) CertHost() string {
+	parsed, _ := url.Parse(s.certs.URL)
+
+	return parsed.Hostname()
+}
+
+func (s *Server) CertClient() *http.Client {
+	return s.certs.Client()
+}
+
+func (s *Server) Close() {
+	s.api.Close()
+	s.certs.Close()
+}
+
+// nextID hands out ids that are unique for the server's lifetime and say what
+// they are, the way PayPal's prefixes do.
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+
+	return fmt.Sprintf("%s-%06d", prefix, s.seq)
+}
+
+// feeFor is what PayPal keeps of a payment of cents.
+func feeFor(cents int32) int32 {
+	return (cents*feePercentBasisPoints+5000)/10000 + feeFixedCents
+}
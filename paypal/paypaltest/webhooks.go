@@ -0,0 +1,185 @@
+package paypaltest
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// certID names the one certificate the server signs with. PayPal rotates its
+// own; a test that wants to see a rotation can start a second server.
+const certID = "CERT-paypaltest"
+
+// SendWebhook posts eventType to target with resource as the event's resource,
+// signed the way PayPal signs: over the transmission id, its time, WebhookID and
+// the CRC32 of the body, with the key behind the certificate the paypal-cert-url
+// header names. It returns the status the target answered with.
+//
+// resource is marshalled as given, so a test can send a shape PayPal would not
+// as readily as one it would. The builders below cover the ordinary ones.
+func (s *Server) SendWebhook(ctx context.Context, target, eventType string, resource any) (int, error) {
+	encoded, err := json.Marshal(resource)
+	if err != nil {
+		return 0, fmt.Errorf("paypaltest: marshal resource: %w", err)
+	}
+
+	s.mu.Lock()
+	eventID := s.nextID("WH")
+	transmissionID := s.nextID("TX")
+	s.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	body, err := json.Marshal(map[string]any{
+		"id":            eventID,
+		"event_version": "1.0",
+		"create_time":   now.Format(time.RFC3339),
+		"resource_type": resourceType(eventType),
+		"event_type":    eventType,
+		"summary":       "paypaltest " + strings.ToLower(eventType),
+		"resource":      json.RawMessage(encoded),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("paypaltest: marshal event: %w", err)
+	}
+
+	timestamp := now.Format(time.RFC3339)
+
+	signature, err := s.sign(fmt.Sprintf("%s|%s|%s|%d", transmissionID, timestamp, WebhookID, crc32.ChecksumIEEE(body)))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("paypaltest: build webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("paypal-transmission-id", transmissionID)
+	req.Header.Set("paypal-transmission-time", timestamp)
+	req.Header.Set("paypal-transmission-sig", signature)
+	req.Header.Set("paypal-cert-url", s.certs.URL+"/v1/notifications/certs/"+certID)
+	req.Header.Set("paypal-auth-algo", "SHA256withRSA")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("paypaltest: send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+func (s *Server) sign(message string) (string, error) {
+	digest := sha256.Sum256([]byte(message))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("paypaltest: sign webhook: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// resourceType is what PayPal puts in the envelope's resource_type for each
+// family of event. Nothing here reads it, but the archive shows it.
+func resourceType(eventType string) string {
+	switch {
+	case strings.HasPrefix(eventType, "PAYMENT.SALE."):
+		return "sale"
+	case strings.HasPrefix(eventType, "BILLING.SUBSCRIPTION."):
+		return "subscription"
+	case strings.HasPrefix(eventType, "CUSTOMER.DISPUTE."):
+		return "dispute"
+	case strings.HasPrefix(eventType, "PAYMENT.PAYOUTS-ITEM."):
+		return "payouts_item"
+	case strings.HasPrefix(eventType, "PAYMENT.PAYOUTSBATCH."):
+		return "payouts"
+	default:
+		return ""
+	}
+}
+
+// CompletedResource is the sale as PAYMENT.SALE.COMPLETED reports it.
+func (p Sale) CompletedResource() map[string]any {
+	return map[string]any{
+		"id":                   p.ID,
+		"state":                "completed",
+		"billing_agreement_id": p.SubscriptionID,
+		"create_time":          p.Created.Format(time.RFC3339),
+		"update_time":          p.Created.Format(time.RFC3339),
+		"amount": map[string]any{
+			"total":    dollars(p.AmountCents),
+			"currency": "USD",
+		},
+		"transaction_fee": map[string]any{
+			"value":    dollars(p.FeeCents),
+			"currency": "USD",
+		},
+	}
+}
+
+// RefundedResource is PAYMENT.SALE.REFUNDED for everything refunded of the sale
+// so far, which is what PayPal's total_refunded_amount carries.
+func (p Sale) RefundedResource() map[string]any {
+	return map[string]any{
+		"id":          "REFUND-" + p.ID,
+		"sale_id":     p.ID,
+		"state":       "completed",
+		"create_time": time.Now().UTC().Format(time.RFC3339),
+		"total_refunded_amount": map[string]any{
+			"value":    dollars(p.RefundedCents),
+			"currency": "USD",
+		},
+	}
+}
+
+// DisputeResource is a CUSTOMER.DISPUTE.* resource against the whole sale.
+// outcome is read only once status is RESOLVED: RESOLVED_BUYER_FAVOUR or
+// RESOLVED_SELLER_FAVOUR, as PayPal names them.
+func (p Sale) DisputeResource(disputeID, status, outcome string) map[string]any {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	return map[string]any{
+		"dispute_id":  disputeID,
+		"create_time": now,
+		"update_time": now,
+		"reason":      "MERCHANDISE_OR_SERVICE_NOT_RECEIVED",
+		"status":      status,
+		"disputed_transactions": []map[string]any{
+			{"seller_transaction_id": p.ID},
+		},
+		"dispute_amount": map[string]any{
+			"value":         dollars(p.AmountCents),
+			"currency_code": "USD",
+		},
+		"dispute_outcome": map[string]any{
+			"outcome_code": outcome,
+		},
+	}
+}
+
+// SubscriptionResource is the subscription as the BILLING.SUBSCRIPTION.* events
+// report it, which is how GET on it reads.
+func (s *Server) SubscriptionResource(subscriptionID string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := s.subscriptions[subscriptionID]
+	if found == nil {
+		return nil, fmt.Errorf("paypaltest: no subscription %s", subscriptionID)
+	}
+
+	return found.resource(), nil
+}
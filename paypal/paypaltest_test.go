@@ -0,0 +1,209 @@
+package paypal_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"boardfund/paypal"
+	"boardfund/paypal/paypaltest"
+	"boardfund/paypal/token"
+	"boardfund/service/donations"
+	"boardfund/service/payouts"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newPaypal(t *testing.T) (*paypal.Paypal, *paypaltest.Server) {
+	t.Helper()
+
+	server := paypaltest.NewServer(t)
+	auth := token.NewStore(token.NewClient(paypaltest.ClientID, paypaltest.ClientSecret, server.URL()))
+	client := paypal.NewClient(auth, slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL())
+
+	return paypal.NewPaypal(client), server
+}
+
+// A one-time donation from order to capture, read back the way the checkout
+// handler reads it: from PayPal, with the fee PayPal kept.
+func TestAnOrderIsReadBackWithWhatWasTaken(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newPaypal(t)
+
+	fund := donations.Fund{ID: uuid.New(), Name: "rent"}
+
+	checkout, err := provider.InitiateDonation(ctx, fund, 2500, "", "")
+	require.NoError(t, err)
+	require.Empty(t, checkout.RedirectURL, "PayPal's buttons approve the order on our page")
+
+	orderID := checkout.ID
+
+	sale, err := server.CaptureOrder(orderID)
+	require.NoError(t, err)
+
+	order, err := provider.GetOrder(ctx, orderID)
+	require.NoError(t, err)
+
+	require.Equal(t, "COMPLETED", order.Status)
+	require.Equal(t, fund.ID.String(), order.FundReferenceID)
+	require.Equal(t, sale.ID, order.ProviderPaymentID)
+	require.EqualValues(t, 2500, order.AmountCents)
+	require.EqualValues(t, 136, order.FeeCents)
+
+	now := time.Now().UTC()
+
+	transaction, err := provider.GetTransaction(ctx, sale.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, transaction)
+	require.EqualValues(t, 2500, transaction.AmountCents)
+	require.EqualValues(t, 136, transaction.FeeCents)
+}
+
+// A subscription's life: a plan created and subscribed to, paid, repriced once
+// the donor approves, and cancelled.
+func TestASubscriptionFollowsItsPlan(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newPaypal(t)
+
+	productID, err := provider.CreateFund(ctx, "rent", "the rent fund")
+	require.NoError(t, err)
+
+	planID, err := provider.CreatePlan(ctx, donations.CreatePlan{
+		Name:           "rent monthly",
+		ProviderFundID: productID,
+		IntervalUnit:   donations.IntervalUnitMonth,
+		AmountCents:    1000,
+	})
+	require.NoError(t, err)
+
+	subscriptionID, err := server.Subscribe(planID, "donor@example.com")
+	require.NoError(t, err)
+
+	_, err = server.Charge(subscriptionID)
+	require.NoError(t, err)
+
+	subscription, err := provider.GetSubscription(ctx, subscriptionID)
+	require.NoError(t, err)
+	require.Equal(t, planID, subscription.ProviderPlanID)
+	require.EqualValues(t, 1000, subscription.AmountCents)
+	require.True(t, subscription.Active())
+
+	dearer, err := provider.CreatePlan(ctx, donations.CreatePlan{
+		Name:           "rent monthly",
+		ProviderFundID: productID,
+		IntervalUnit:   donations.IntervalUnitMonth,
+		AmountCents:    2000,
+	})
+	require.NoError(t, err)
+
+	link, err := provider.ReviseSubscription(ctx, subscriptionID, dearer, "https://fund.example/back", "https://fund.example/cancel")
+	require.NoError(t, err)
+	require.NotEmpty(t, link, "a price change waits for the donor")
+
+	// Until they approve it, the subscription is where it was.
+	subscription, err = provider.GetSubscription(ctx, subscriptionID)
+	require.NoError(t, err)
+	require.Equal(t, planID, subscription.ProviderPlanID)
+
+	require.NoError(t, server.ApproveRevision(subscriptionID))
+
+	_, err = server.Charge(subscriptionID)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+
+	transactions, err := provider.GetTransactionsForDonationSubscription(ctx, subscriptionID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	require.EqualValues(t, 1000, transactions[0].AmountCents)
+	require.EqualValues(t, 2000, transactions[1].AmountCents)
+
+	cancelled, err := provider.CancelSubscriptions(ctx, []string{subscriptionID})
+	require.NoError(t, err)
+	require.Equal(t, []string{subscriptionID}, cancelled)
+
+	status, err := provider.GetProviderDonationSubscriptionStatus(ctx, subscriptionID)
+	require.NoError(t, err)
+	require.Equal(t, "CANCELLED", status)
+}
+
+// The import's search: a one-time capture comes back with the fund its order
+// named, and a subscription's payment, which backfill already covers, does not
+// come back at all.
+func TestASearchFindsOneTimePaymentsWithTheirFund(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newPaypal(t)
+
+	fund := donations.Fund{ID: uuid.New(), Name: "rent"}
+
+	checkout, err := provider.InitiateDonation(ctx, fund, 2500, "", "")
+	require.NoError(t, err)
+
+	sale, err := server.CaptureOrder(checkout.ID)
+	require.NoError(t, err)
+
+	productID, err := provider.CreateFund(ctx, "rent", "the rent fund")
+	require.NoError(t, err)
+
+	planID, err := provider.CreatePlan(ctx, donations.CreatePlan{
+		Name:           "rent monthly",
+		ProviderFundID: productID,
+		IntervalUnit:   donations.IntervalUnitMonth,
+		AmountCents:    1000,
+	})
+	require.NoError(t, err)
+
+	subscriptionID, err := server.Subscribe(planID, "donor@example.com")
+	require.NoError(t, err)
+
+	_, err = server.Charge(subscriptionID)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+
+	payments, err := provider.SearchPayments(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+
+	require.Equal(t, sale.ID, payments[0].ProviderPaymentID)
+	require.Equal(t, checkout.ID, payments[0].ProviderOrderID)
+	require.Equal(t, fund.ID.String(), payments[0].FundReferenceID)
+	require.EqualValues(t, 2500, payments[0].AmountCents)
+	require.EqualValues(t, 136, payments[0].FeeCents)
+}
+
+// A batch is pending until PayPal pays it, each item comes back under the id we
+// sent, and the same sender_batch_id is never paid twice.
+func TestAPayoutBatchSettlesOnce(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newPaypal(t)
+
+	senderBatchID := uuid.New()
+	items := []payouts.ProviderPayoutItem{
+		{PayoutID: uuid.New(), ReceiverEmail: "a@example.com", AmountCents: 5000},
+		{PayoutID: uuid.New(), ReceiverEmail: "b@example.com", AmountCents: 5000},
+	}
+
+	submitted, err := provider.SubmitBatch(ctx, senderBatchID, "", items)
+	require.NoError(t, err)
+	require.Equal(t, "PENDING", submitted.Status)
+
+	_, err = provider.SubmitBatch(ctx, senderBatchID, "", items)
+	require.Error(t, err, "a second batch with the same sender id must be refused")
+
+	require.NoError(t, server.SettleBatch(submitted.ProviderBatchID))
+
+	settled, err := provider.GetBatchStatus(ctx, submitted.ProviderBatchID)
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", settled.Status)
+	require.Len(t, settled.Items, 2)
+
+	for i, item := range settled.Items {
+		require.Equal(t, items[i].PayoutID, item.PayoutID)
+		require.Equal(t, "SUCCESS", item.Status)
+		require.Positive(t, item.FeeCents)
+	}
+}
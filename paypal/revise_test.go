@@ -0,0 +1,21 @@
+package paypal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A revision hands back several links, and only one of them is the page the donor
+// approves the new amount on. Sending them to "self" would show them JSON.
+func TestReviseSendsTheDonorToTheApprovalLink(t *testing.T) {
+	links := []Link{
+		{Href: "https://api.paypal.com/v1/billing/subscriptions/I-1", Rel: "self", Method: "GET"},
+		{Href: "https://www.paypal.com/webapps/billing/subscriptions/update?ba_token=BA-1", Rel: "approve", Method: "GET"},
+	}
+
+	require.Equal(t, "https://www.paypal.com/webapps/billing/subscriptions/update?ba_token=BA-1", approveLink(links))
+
+	// Nothing to approve: the caller confirms the change itself.
+	require.Empty(t, approveLink(links[:1]))
+}
@@ -0,0 +1,113 @@
+package paypal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The search lists everything that moved through the account. Only money a
+// donor sent us outside a subscription is the import's to record.
+func TestOnlyOneTimeMoneyComingInIsImported(t *testing.T) {
+	detail := func(code, referenceType, amount string) TransactionDetails {
+		return TransactionDetails{
+			TransactionInfo: TransactionInfo{
+				TransactionID:             "TX-1",
+				TransactionEventCode:      code,
+				TransactionInitiationDate: "2026-08-01T12:00:00+0000",
+				TransactionAmount:         TransactionAmount{CurrencyCode: "USD", Value: amount},
+				FeeAmount:                 FeeAmount{CurrencyCode: "USD", Value: "-0.56"},
+				TransactionStatus:         "S",
+				PaypalReferenceID:         "REF-1",
+				PaypalReferenceIDType:     referenceType,
+			},
+			PayerInfo: PayerInfo{
+				AccountID:    "PAYER-1",
+				EmailAddress: "donor@example.com",
+				PayerName:    PayerName{GivenName: "Ada", Surname: "Lovelace"},
+			},
+		}
+	}
+
+	for _, c := range []struct {
+		name   string
+		detail TransactionDetails
+	}{
+		{"a subscription payment", detail("T0002", "SUB", "10.00")},
+		{"a subscription payment without its reference", detail("T0002", "", "10.00")},
+		{"a refund going back out", detail("T1107", "ODR", "-10.00")},
+		{"a payout", detail("T0000", "", "-10.00")},
+		{"a bank transfer in", detail("T0300", "", "100.00")},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			payment, err := paymentFromDetail(c.detail)
+			require.NoError(t, err)
+			require.Nil(t, payment)
+		})
+	}
+
+	pending := detail("T0006", "ODR", "10.00")
+	pending.TransactionInfo.TransactionStatus = "P"
+
+	payment, err := paymentFromDetail(pending)
+	require.NoError(t, err)
+	require.Nil(t, payment, "money not yet settled is not money the fund holds")
+
+	payment, err = paymentFromDetail(detail("T0006", "ODR", "10.00"))
+	require.NoError(t, err)
+	require.NotNil(t, payment)
+
+	require.Equal(t, "TX-1", payment.ProviderPaymentID)
+	require.Equal(t, "REF-1", payment.ProviderOrderID)
+	require.EqualValues(t, 1000, payment.AmountCents)
+	require.EqualValues(t, 56, payment.FeeCents)
+	require.Equal(t, "PAYER-1", payment.PayerID)
+	require.Equal(t, "donor@example.com", payment.PayerEmail)
+	require.Equal(t, "Ada Lovelace", payment.PayerName)
+}
+
+// An order that cannot be read back costs its own payment the fund, and nothing
+// else: the import lists that one as unmatched and records the rest.
+func TestAnUnreadableOrderLeavesOnlyItsPaymentWithoutAFund(t *testing.T) {
+	sale := func(id, order string) TransactionDetails {
+		return TransactionDetails{TransactionInfo: TransactionInfo{
+			TransactionID:             id,
+			TransactionEventCode:      "T0006",
+			TransactionInitiationDate: "2026-08-01T12:00:00+0000",
+			TransactionAmount:         TransactionAmount{CurrencyCode: "USD", Value: "10.00"},
+			TransactionStatus:         "S",
+			PaypalReferenceID:         order,
+			PaypalReferenceIDType:     "ODR",
+		}}
+	}
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/reporting/transactions"):
+			_ = json.NewEncoder(w).Encode(Transaction{
+				TransactionDetails: []TransactionDetails{sale("TX-1", "ORDER-GONE"), sale("TX-2", "ORDER-2")},
+				TotalPages:         1,
+			})
+		case r.URL.Path == "/v2/checkout/orders/ORDER-2":
+			_, _ = w.Write([]byte(`{"id": "ORDER-2", "purchase_units": [{"reference_id": "FUND-2"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"name": "RESOURCE_NOT_FOUND", "message": "no such order"}`))
+		}
+	})
+
+	now := time.Now()
+
+	payments, err := NewPaypal(client).SearchPayments(context.Background(), now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, payments, 2)
+
+	require.Equal(t, "TX-1", payments[0].ProviderPaymentID)
+	require.Empty(t, payments[0].FundReferenceID)
+	require.Equal(t, "FUND-2", payments[1].FundReferenceID)
+}
@@ -0,0 +1,450 @@
+package paypal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrCircuitOpen is returned without calling PayPal while the breaker is open:
+// PayPal has failed often enough in a row that another request would most
+// likely fail too, and only add to the load it is failing under.
+var ErrCircuitOpen = errors.New("paypal is failing; not calling it until the breaker closes")
+
+// retryPolicy is how hard one call tries before giving up.
+//
+// Reconciliation and payout submission each make dozens of calls in a run, and
+// one 503 used to fail the whole run. A few attempts, spread out with jitter so
+// every worker does not come back at the same instant, rides out the blips
+// PayPal has every day without hiding an outage that lasts.
+type retryPolicy struct {
+	attempts int
+	base     time.Duration
+	max      time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	attempts: 4,
+	base:     200 * time.Millisecond,
+	max:      5 * time.Second,
+}
+
+// backoff is how long to wait before the given retry, counted from one: a
+// random time up to base doubled per retry, capped at max. Full jitter rather
+// than a fixed step, so clients that failed together do not retry together.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	ceiling := p.base << (retry - 1)
+	if ceiling <= 0 || ceiling > p.max {
+		ceiling = p.max
+	}
+
+	return rand.N(ceiling) + 1
+}
+
+// retryable is whether a status says "try again later" rather than "this
+// request is wrong". A 4xx other than 429 will be refused the same next time.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// retryAfter reads a Retry-After header, which is either seconds or an HTTP
+// date. Zero when there is none or it cannot be read.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
+
+// breaker stops calls to PayPal after a run of failures and lets one through
+// after a cooldown to find out whether it has recovered.
+//
+// Only failures worth retrying count: a refused request is PayPal answering,
+// which is what the breaker wants to know it can do.
+type breaker struct {
+	mu sync.Mutex
+
+	threshold int
+	cooldown  time.Duration
+
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func newBreaker() *breaker {
+	return &breaker{threshold: 5, cooldown: 30 * time.Second}
+}
+
+// allow is whether a call may go ahead. Once the cooldown has passed, a single
+// call is let through as a trial, and everyone else waits on its outcome.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+
+	if b.trial || now.Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.trial = true
+
+	return true
+}
+
+func (b *breaker) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+// release ends a trial that found nothing out -- the caller gave up, or the
+// token could not be had -- so that the next call can be the trial instead.
+// Without it the trial would be held forever and every call refused.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+func (b *breaker) failed(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+
+	if b.failures >= b.threshold {
+		b.openedAt = now
+	}
+}
+
+// state is "closed", "open" or "half-open", for the admin page.
+func (b *breaker) state(now time.Time) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.failures < b.threshold:
+		return "closed"
+	case b.trial || now.Sub(b.openedAt) >= b.cooldown:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+// EndpointStats is what one endpoint has done since the process started.
+// Latency covers the whole call, retries and waits included, since that is
+// what the job calling it waited for.
+type EndpointStats struct {
+	Endpoint     string
+	Requests     int64
+	Errors       int64
+	Retries      int64
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+}
+
+// MeanLatency is zero before the first request.
+func (s EndpointStats) MeanLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+
+	return s.TotalLatency / time.Duration(s.Requests)
+}
+
+// Health is the client's state as the admin page shows it.
+type Health struct {
+	Breaker   string
+	Endpoints []EndpointStats
+}
+
+type endpointStats struct {
+	mu        sync.Mutex
+	endpoints map[string]*EndpointStats
+}
+
+func (s *endpointStats) record(endpoint string, latency time.Duration, retries int, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.endpoints[endpoint]
+	if !ok {
+		stats = &EndpointStats{Endpoint: endpoint}
+		s.endpoints[endpoint] = stats
+	}
+
+	stats.Requests++
+	stats.Retries += int64(retries)
+	stats.TotalLatency += latency
+	stats.MaxLatency = max(stats.MaxLatency, latency)
+
+	if failed {
+		stats.Errors++
+	}
+}
+
+func (s *endpointStats) snapshot() []EndpointStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make([]EndpointStats, 0, len(s.endpoints))
+	for _, stats := range s.endpoints {
+		snapshot = append(snapshot, *stats)
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Endpoint < snapshot[j].Endpoint })
+
+	return snapshot
+}
+
+// endpoint names a request for the counters: the method and the path, with
+// the query dropped and ids folded into one placeholder, so every order's GET
+// counts against one endpoint rather than one each. A segment with a digit in
+// it is an id; PayPal's own path segments have none, other than the version.
+func endpoint(method, path string) string {
+	path, _, _ = strings.Cut(path, "?")
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "v1" || segment == "v2" {
+			continue
+		}
+
+		if strings.ContainsFunc(segment, unicode.IsDigit) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return method + " " + strings.Join(segments, "/")
+}
+
+// Health reports the breaker and the per-endpoint counters.
+func (c Client) Health() Health {
+	return Health{
+		Breaker:   c.breaker.state(time.Now()),
+		Endpoints: c.stats.snapshot(),
+	}
+}
+
+// do sends one logical request, retrying it as the policy allows, and returns
+// the body of a successful response.
+//
+// requestID goes out as PayPal-Request-Id on every attempt at a POST. PayPal
+// keeps the response to a request id and hands it back to a repeat, so a POST
+// that reached PayPal but whose answer was lost is not carried out twice when
+// it is retried.
+func (c Client) do(ctx context.Context, method, path, requestID string, payload any) ([]byte, error) {
+	var body []byte
+
+	if method != http.MethodGet {
+		var err error
+
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	started := time.Now()
+	retries := 0
+
+	response, err := c.attempt(ctx, method, path, requestID, body)
+	for err != nil && retries+1 < c.retry.attempts {
+		var again errRetryable
+		if !errors.As(err, &again) {
+			break
+		}
+
+		retries++
+
+		wait := c.retry.backoff(retries)
+		if again.after > 0 {
+			// PayPal said when. Waiting longer than the policy allows would hold a
+			// run up for something it is better off hearing about now.
+			if again.after > c.retry.max {
+				break
+			}
+
+			wait = again.after
+		}
+
+		c.logger.WarnContext(ctx, "retrying paypal request",
+			slog.String("endpoint", endpoint(method, path)),
+			slog.Int("retry", retries),
+			slog.Duration("wait", wait),
+			slog.String("error", again.Error()))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+		case <-timer.C:
+			response, err = c.attempt(ctx, method, path, requestID, body)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	c.stats.record(endpoint(method, path), time.Since(started), retries, err != nil)
+
+	var again errRetryable
+	if errors.As(err, &again) {
+		return nil, again.err
+	}
+
+	return response, err
+}
+
+// errRetryable marks a failed attempt as one worth making again. It never
+// leaves do: the caller sees the error it wraps.
+type errRetryable struct {
+	err   error
+	after time.Duration
+}
+
+func (e errRetryable) Error() string {
+	return e.err.Error()
+}
+
+func (e errRetryable) Unwrap() error {
+	return e.err
+}
+
+// attempt makes one request and reports it to the breaker.
+//
+// Only an answer from PayPal is a success; a refused request is still PayPal
+// answering. A timeout is PayPal not answering in time, which is what the
+// breaker is there to notice. Anything else -- a cancelled caller, a token that
+// could not be had -- says nothing about PayPal, and only gives the trial back.
+func (c Client) attempt(ctx context.Context, method, path, requestID string, body []byte) ([]byte, error) {
+	if !c.breaker.allow(time.Now()) {
+		return nil, ErrCircuitOpen
+	}
+
+	response, answered, err := c.send(ctx, method, path, requestID, body)
+
+	var again errRetryable
+	switch {
+	case errors.As(err, &again), errors.Is(err, context.DeadlineExceeded):
+		c.breaker.failed(time.Now())
+	case answered:
+		c.breaker.succeeded()
+	default:
+		c.breaker.release()
+	}
+
+	return response, err
+}
+
+// send makes the request. answered is whether PayPal sent a response back,
+// whatever it said.
+func (c Client) send(ctx context.Context, method, path, requestID string, body []byte) (_ []byte, answered bool, _ error) {
+	paypalToken, err := c.paypalAuth.GetToken(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Add("Authorization", paypalToken.TokenType+" "+paypalToken.AccessToken)
+
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	if requestID != "" {
+		req.Header.Add("PayPal-Request-Id", requestID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+
+		// Nothing came back, so there is no telling whether PayPal acted. The
+		// request id makes it safe to ask again either way.
+		return nil, false, errRetryable{err: err}
+	}
+
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, errRetryable{err: err}
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return responseBody, true, nil
+	}
+
+	var paypalErr ErrPaypal
+	if err = json.Unmarshal(responseBody, &paypalErr); err != nil {
+		// Gateways in front of PayPal answer 502s and 503s in HTML.
+		err = fmt.Errorf("paypal answered %d with an unreadable error: %w", resp.StatusCode, err)
+	} else {
+		c.logger.ErrorContext(ctx, "error from paypal", slog.Any("details", paypalErr.Details), slog.String("message", paypalErr.Message))
+
+		err = paypalErr
+	}
+
+	if retryable(resp.StatusCode) {
+		return nil, true, errRetryable{err: err, after: retryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+
+	return nil, true, err
+}
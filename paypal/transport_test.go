@@ -0,0 +1,252 @@
+package paypal
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"boardfund/paypal/token"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient serves the token and hands every other request to api, with
+// waits short enough not to slow the tests down.
+func newTestClient(t *testing.T, api http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/oauth2/token" {
+			_, _ = w.Write([]byte(`{"access_token": "token", "token_type": "Bearer", "expires_in": 3600}`))
+
+			return
+		}
+
+		api(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(token.NewStore(token.NewClient("id", "secret", server.URL)),
+		slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL)
+	client.retry = retryPolicy{attempts: 4, base: time.Millisecond, max: 10 * time.Millisecond}
+
+	return client
+}
+
+// A retried POST must carry the same request id each time, or PayPal cannot
+// tell it from a new one.
+func TestARetriedPostKeepsItsRequestID(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ids = append(ids, r.Header.Get("PayPal-Request-Id"))
+		attempt := len(ids)
+		mu.Unlock()
+
+		if attempt < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`<html>unavailable</html>`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"id": "PROD-1"}`))
+	})
+
+	body, err := client.postWithResponse(context.Background(), "/v1/catalogs/products", CreateProduct{Name: "rent"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id": "PROD-1"}`, string(body))
+
+	require.Len(t, ids, 3)
+	require.NotEmpty(t, ids[0])
+	require.Equal(t, ids[0], ids[1])
+	require.Equal(t, ids[0], ids[2])
+
+	stats := client.Health().Endpoints
+	require.Len(t, stats, 1)
+	require.Equal(t, "POST /v1/catalogs/products", stats[0].Endpoint)
+	require.EqualValues(t, 1, stats[0].Requests)
+	require.EqualValues(t, 2, stats[0].Retries)
+	require.Zero(t, stats[0].Errors)
+}
+
+// A request PayPal refused will be refused again, so it is not asked twice.
+func TestARefusalIsNotRetried(t *testing.T) {
+	var calls int
+
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"name": "UNPROCESSABLE_ENTITY", "message": "the plan is inactive"}`))
+	})
+
+	_, err := client.get(context.Background(), "/v1/billing/subscriptions/I-1")
+
+	var paypalErr ErrPaypal
+	require.ErrorAs(t, err, &paypalErr)
+	require.Equal(t, "UNPROCESSABLE_ENTITY", paypalErr.Name)
+	require.Equal(t, 1, calls)
+
+	require.Equal(t, "closed", client.Health().Breaker, "a refusal is PayPal answering")
+}
+
+func TestGivingUpReturnsTheLastError(t *testing.T) {
+	var calls int
+
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"name": "INTERNAL_SERVER_ERROR", "message": "try later"}`))
+	})
+
+	_, err := client.get(context.Background(), "/v2/checkout/orders/5O190127TN364715T")
+
+	var paypalErr ErrPaypal
+	require.ErrorAs(t, err, &paypalErr)
+	require.Equal(t, 4, calls)
+
+	stats := client.Health().Endpoints
+	require.Equal(t, "GET /v2/checkout/orders/{id}", stats[0].Endpoint)
+	require.EqualValues(t, 1, stats[0].Errors)
+	require.EqualValues(t, 3, stats[0].Retries)
+}
+
+// Asked to come back later than the policy would wait, the call gives up now
+// rather than hold the run.
+func TestARetryAfterPastThePolicyIsNotWaitedFor(t *testing.T) {
+	var calls int
+
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"name": "RATE_LIMIT_REACHED", "message": "slow down"}`))
+	})
+
+	_, err := client.get(context.Background(), "/v1/payments/payouts/B-1")
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestRetryAfterIsSecondsOrADate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.Equal(t, 3*time.Second, retryAfter("3", now))
+	require.Equal(t, 90*time.Second, retryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	require.Zero(t, retryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+	require.Zero(t, retryAfter("soon", now))
+	require.Zero(t, retryAfter("", now))
+}
+
+func TestBackoffStaysUnderItsCeiling(t *testing.T) {
+	policy := retryPolicy{attempts: 10, base: 100 * time.Millisecond, max: time.Second}
+
+	for retry := 1; retry <= 10; retry++ {
+		ceiling := min(policy.base<<(retry-1), policy.max)
+
+		for range 50 {
+			wait := policy.backoff(retry)
+			require.Positive(t, wait)
+			require.LessOrEqual(t, wait, ceiling)
+		}
+	}
+}
+
+// Open after a run of failures, then one trial after the cooldown: its
+// outcome decides for everyone else.
+func TestTheBreakerOpensAndLetsOneTrialThrough(t *testing.T) {
+	b := &breaker{threshold: 3, cooldown: time.Minute}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for range 3 {
+		require.True(t, b.allow(now))
+		b.failed(now)
+	}
+
+	require.False(t, b.allow(now))
+	require.Equal(t, "open", b.state(now))
+
+	later := now.Add(time.Minute)
+	require.True(t, b.allow(later))
+	require.False(t, b.allow(later), "only one trial at a time")
+
+	b.failed(later)
+	require.False(t, b.allow(later.Add(time.Second)), "a failed trial opens it again")
+
+	recovered := later.Add(2 * time.Minute)
+	require.True(t, b.allow(recovered))
+	b.succeeded()
+	require.Equal(t, "closed", b.state(recovered))
+	require.True(t, b.allow(recovered))
+}
+
+func TestAnOpenBreakerDoesNotCallPayPal(t *testing.T) {
+	var calls int
+
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client.breaker = &breaker{threshold: 2, cooldown: time.Hour}
+
+	_, err := client.get(context.Background(), "/v1/billing/plans/P-1")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, 2, calls)
+
+	_, err = client.get(context.Background(), "/v1/billing/plans/P-1")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, 2, calls)
+}
+
+// A trial whose caller gave up found nothing out. Holding it would refuse every
+// call after it for as long as the process runs.
+func TestACancelledTrialIsGivenBack(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	client.breaker = &breaker{threshold: 1, cooldown: time.Minute, failures: 1, openedAt: time.Now().Add(-time.Hour)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.get(ctx, "/v1/billing/plans/P-1")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, "half-open", client.Health().Breaker, "neither a success nor a failure")
+
+	_, err = client.get(context.Background(), "/v1/billing/plans/P-1")
+	require.NoError(t, err, "the next call should have been let through as the trial")
+	require.Equal(t, "closed", client.Health().Breaker)
+}
+
+// A timeout is PayPal not answering, which is what the breaker counts.
+func TestATimeoutIsAFailure(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	client.breaker = &breaker{threshold: 1, cooldown: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.get(ctx, "/v1/billing/plans/P-1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, "open", client.Health().Breaker)
+}
+
+func TestEndpointsFoldIDsAndDropTheQuery(t *testing.T) {
+	require.Equal(t, "GET /v1/payments/payouts/{id}", endpoint("GET", "/v1/payments/payouts/5UXD2E8A7EBQJ?page_size=1000"))
+	require.Equal(t, "POST /v1/billing/subscriptions/{id}/cancel", endpoint("POST", "/v1/billing/subscriptions/I-BW452GLLEP1G/cancel"))
+	require.Equal(t, "POST /v2/checkout/orders", endpoint("POST", "/v2/checkout/orders"))
+}
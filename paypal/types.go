@@ -221,6 +221,21 @@ type CancelSubscriptionRequest struct {
 	Reason string `json:"reason"`
 }
 
+type ReviseSubscriptionRequest struct {
+	PlanID             string             `json:"plan_id"`
+	ApplicationContext ApplicationContext `json:"application_context"`
+}
+
+type ApplicationContext struct {
+	ReturnURL string `json:"return_url"`
+	CancelURL string `json:"cancel_url"`
+}
+
+type ReviseSubscriptionResponse struct {
+	PlanID string `json:"plan_id"`
+	Links  []Link `json:"links"`
+}
+
 type Subscription struct {
 	ID               string         `json:"id"`
 	PlanID           string         `json:"plan_id"`
@@ -283,8 +298,9 @@ type SubscriptionTransactions struct {
 	Links        []Links        `json:"links"`
 }
 type PayerName struct {
-	GivenName string `json:"given_name"`
-	Surname   string `json:"surname"`
+	GivenName         string `json:"given_name"`
+	Surname           string `json:"surname"`
+	AlternateFullName string `json:"alternate_full_name"`
 }
 
 type FeeAmount struct {
@@ -332,6 +348,10 @@ type TransactionInfo struct {
 	TransactionStatus         string            `json:"transaction_status"`
 	ProtectionEligibility     string            `json:"protection_eligibility"`
 	FundID                    string            `json:"custom_field"`
+	// PaypalReferenceID is what the transaction belongs to, of the kind
+	// PaypalReferenceIDType names: ODR for an order, SUB for a subscription.
+	PaypalReferenceID     string `json:"paypal_reference_id"`
+	PaypalReferenceIDType string `json:"paypal_reference_id_type"`
 }
 
 type PayerInfo struct {
@@ -396,6 +416,7 @@ type IncentiveInfo struct {
 }
 type TransactionDetails struct {
 	TransactionInfo TransactionInfo `json:"transaction_info"`
+	PayerInfo       PayerInfo       `json:"payer_info"`
 }
 
 type Order struct {
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS notification_delivery_batch_id_idx;
+
+DROP TABLE IF EXISTS notification_delivery;
+
+DROP TYPE IF EXISTS notification_channel;
+DROP TYPE IF EXISTS notification_kind;
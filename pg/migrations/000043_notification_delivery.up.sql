@@ -0,0 +1,42 @@
+-- Every attempt to tell somebody that a payout batch needs them.
+--
+-- The approval window is the only thing standing between a planned batch and a
+-- cancelled one, and for as long as nothing delivered the message the window
+-- simply ran out: batches expired three days after planning with nobody ever
+-- having been asked. A failed send was a log line, which is the one place a
+-- treasurer never looks.
+--
+-- So each attempt is a row, sent or not. A failure recorded here is visible on
+-- the batch it was about, next to the deadline it was meant to warn about.
+CREATE TYPE notification_kind AS ENUM (
+    'approval_required',
+    'approval_expiring'
+    );
+
+-- One value for now. A channel is how the message travelled, and the next one is
+-- an ALTER TYPE away rather than a new table.
+CREATE TYPE notification_channel AS ENUM (
+    'email'
+    );
+
+CREATE TABLE notification_delivery
+(
+    id        uuid PRIMARY KEY,
+    batch_id  uuid                     NOT NULL REFERENCES batch_payout (id),
+    kind      notification_kind        NOT NULL,
+    channel   notification_channel     NOT NULL,
+
+    -- Who it was for: an address, for email. Text rather than a member id,
+    -- because the people asked to approve are configured, not looked up, and
+    -- need not have an account here at all.
+    recipient text                     NOT NULL,
+
+    -- Null when the message was handed over. Otherwise what refused it, as the
+    -- provider said it.
+    error     text,
+
+    created   timestamp with time zone NOT NULL DEFAULT now()
+);
+
+-- The batch page asks "who was told about this one, and did it work".
+CREATE INDEX notification_delivery_batch_id_idx ON notification_delivery (batch_id, created DESC);
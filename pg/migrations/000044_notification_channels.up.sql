@@ -0,0 +1,9 @@
+-- Treasurers read chat before they read email, so approval requests now go out
+-- over more than one channel: an outgoing webhook into a chat room, and a notice
+-- on the home page, beside the email that was already there.
+--
+-- Same delivery table, new values. A batch page that lists the attempts should
+-- list all of them together, in order, whatever they travelled over -- that is
+-- the question it answers, and three tables would have it asking three times.
+ALTER TYPE notification_channel ADD VALUE IF NOT EXISTS 'webhook';
+ALTER TYPE notification_channel ADD VALUE IF NOT EXISTS 'notice';
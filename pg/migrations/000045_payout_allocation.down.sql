@@ -0,0 +1,9 @@
+ALTER TABLE fund_enrollment
+    DROP COLUMN IF EXISTS payout_weight,
+    DROP COLUMN IF EXISTS payout_amount_cents;
+
+ALTER TABLE fund
+    DROP COLUMN IF EXISTS payout_cap_cents,
+    DROP COLUMN IF EXISTS payout_allocation;
+
+DROP TYPE IF EXISTS payout_allocation;
@@ -0,0 +1,41 @@
+-- How a fund divides what it has between the people it pays.
+--
+-- Until now every fund split its balance evenly, which suits a fund where
+-- everybody's need is the same and rules out every other kind: rent relief where
+-- each household's shortfall differs, a stipend with a set amount per person, a
+-- fund that pays a family of four more than a single person. Those had to be run
+-- by hand, outside this tool.
+--
+--   even      the balance, less fees, split equally. What every fund did before.
+--   fixed     each enrollment's payout_amount_cents, exactly. Nothing is paid
+--             until the balance covers all of them.
+--   weighted  the balance, less fees, split in proportion to payout_weight.
+CREATE TYPE payout_allocation AS ENUM (
+    'even',
+    'fixed',
+    'weighted'
+    );
+
+ALTER TABLE fund
+    ADD COLUMN payout_allocation payout_allocation NOT NULL DEFAULT 'even';
+
+-- Most any one payee receives in one payout, whatever the strategy gives them.
+-- What the cap holds back is not paid to anyone else: it stays in the fund and
+-- is there for the next payout, which is how a fund stretches a windfall over
+-- several periods rather than spending it in one.
+ALTER TABLE fund
+    ADD COLUMN payout_cap_cents int
+        CONSTRAINT fund_payout_cap_positive CHECK (payout_cap_cents IS NULL OR payout_cap_cents > 0);
+
+-- The amount for a 'fixed' fund. Null leaves the enrollment out of a fixed
+-- fund's payouts altogether, which is the safe reading of "nobody said how much".
+ALTER TABLE fund_enrollment
+    ADD COLUMN payout_amount_cents int
+        CONSTRAINT fund_enrollment_payout_amount_positive CHECK (payout_amount_cents IS NULL OR payout_amount_cents > 0);
+
+-- Shares for a 'weighted' fund. Defaults to one, so switching a fund from even to
+-- weighted changes nothing until somebody sets a weight. Zero is allowed and
+-- means "enrolled, but not paid from this fund for now".
+ALTER TABLE fund_enrollment
+    ADD COLUMN payout_weight int NOT NULL DEFAULT 1
+        CONSTRAINT fund_enrollment_payout_weight_nonnegative CHECK (payout_weight >= 0);
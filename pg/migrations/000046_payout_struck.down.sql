@@ -0,0 +1,7 @@
+-- Postgres cannot drop a value from an enum. Rows using it would have to be
+-- rewritten and the type recreated, which is not worth doing to undo an additive
+-- change; the values simply go unused.
+ALTER TABLE payout
+    DROP CONSTRAINT IF EXISTS payout_strike_complete,
+    DROP COLUMN IF EXISTS struck_at,
+    DROP COLUMN IF EXISTS struck_by;
@@ -0,0 +1,25 @@
+-- A payout taken out of a batch before it was approved.
+--
+-- Its own status rather than 'cancelled', because 'cancelled' is also what PayPal
+-- reports for an item it cancelled after the batch was sent. The two mean
+-- different things to the reconciler: a struck payout never went to the
+-- provider, so it must not count against a batch that otherwise paid everyone,
+-- where a cancelled one did go and did not arrive.
+--
+-- The reason goes in failure_reason with every other explanation of why a
+-- payout was not paid. Who and when are on the row as well as in the fund's
+-- feed, so the payout page can say so beside the payout itself.
+ALTER TYPE payout_status ADD VALUE IF NOT EXISTS 'struck';
+
+ALTER TABLE payout
+    ADD COLUMN struck_by uuid REFERENCES member (id),
+    ADD COLUMN struck_at timestamp with time zone;
+
+-- The same rule as approval: a strike that does not say who made it is not a
+-- record of one.
+ALTER TABLE payout
+    ADD CONSTRAINT payout_strike_complete
+        CHECK ((struck_by IS NULL) = (struck_at IS NULL));
+
+-- Not on the public timeline: it is about one identifiable payee.
+ALTER TYPE fund_event_kind ADD VALUE IF NOT EXISTS 'payout_struck';
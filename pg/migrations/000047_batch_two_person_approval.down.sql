@@ -0,0 +1,8 @@
+ALTER TABLE batch_payout
+    DROP COLUMN IF EXISTS approvals_given,
+    DROP COLUMN IF EXISTS approvals_required;
+
+DROP TABLE IF EXISTS batch_approval;
+
+ALTER TABLE fund
+    DROP COLUMN IF EXISTS second_approval_above_cents;
@@ -0,0 +1,44 @@
+-- Two people for large batches.
+--
+-- Until now one admin approving moved any batch to 'ready', whatever it paid.
+-- Above a threshold a batch now needs two distinct admins, and each approval is
+-- its own row: batch_payout.approved_by holds only whoever completed it, and an
+-- audit of a two-person rule that names one person is not an audit of one.
+--
+-- The threshold is per fund, falling back to one set for the whole site. Null
+-- on the fund means "use the site's", which may itself be unset -- one approval
+-- for everything, as before.
+ALTER TABLE fund
+    ADD COLUMN second_approval_above_cents int CHECK (second_approval_above_cents > 0);
+
+CREATE TABLE batch_approval
+(
+    id          uuid PRIMARY KEY,
+    batch_id    uuid                     NOT NULL REFERENCES batch_payout (id),
+    approver_id uuid                     NOT NULL REFERENCES member (id),
+    created     timestamp with time zone NOT NULL DEFAULT now(),
+    -- The same admin approving twice is one approval, not two.
+    UNIQUE (batch_id, approver_id)
+);
+
+-- How many approvals the batch needs and how many it has, on the batch so every
+-- page listing batches can say "1 of 2" without a join. Required is recomputed
+-- whenever the amount changes or an approval lands, so a strike that takes a
+-- batch under the threshold takes it back to needing one.
+ALTER TABLE batch_payout
+    ADD COLUMN approvals_required int NOT NULL DEFAULT 1 CHECK (approvals_required BETWEEN 1 AND 2),
+    ADD COLUMN approvals_given    int NOT NULL DEFAULT 0 CHECK (approvals_given >= 0);
+
+-- Batches approved before this carry their one approval forward.
+UPDATE batch_payout
+SET approvals_given = 1
+WHERE approved_by IS NOT NULL;
+
+INSERT INTO batch_approval (id, batch_id, approver_id, created)
+SELECT gen_random_uuid(), id, approved_by, approved_at
+FROM batch_payout
+WHERE approved_by IS NOT NULL;
+
+-- The first of two approvals. Not the public "approved" event, which still means
+-- the batch was cleared to send, and only once.
+ALTER TYPE fund_event_kind ADD VALUE IF NOT EXISTS 'payout_batch_first_approval';
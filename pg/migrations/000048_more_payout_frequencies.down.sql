@@ -0,0 +1,19 @@
+ALTER TABLE donation_plan
+    DROP CONSTRAINT donation_plan_interval_count_positive,
+    DROP CONSTRAINT interval_unit_count_amount,
+    ADD CONSTRAINT interval_unit_amount UNIQUE (interval_unit, amount_cents);
+
+-- Postgres has no DROP VALUE, so removing the new frequencies means rebuilding
+-- the type and recasting the column, as 000025's down does for 'daily'.
+--
+-- The cast fails if any fund is still weekly, biweekly or quarterly, and that is
+-- intended: rewriting them to another frequency would change when they pay out.
+ALTER TYPE payout_frequency RENAME TO payout_frequency_old;
+
+CREATE TYPE payout_frequency AS ENUM ('monthly', 'once', 'daily');
+
+ALTER TABLE fund
+    ALTER COLUMN payout_frequency TYPE payout_frequency
+        USING payout_frequency::text::payout_frequency;
+
+DROP TYPE payout_frequency_old;
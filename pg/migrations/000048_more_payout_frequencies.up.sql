@@ -0,0 +1,28 @@
+-- Rent and utilities are paid weekly or every other week, and some grants
+-- quarterly. Funds covering those had to pick monthly and have an admin plan
+-- the batches in between by hand.
+--
+-- ADD VALUE for the same reason as 'daily' in 000025: existing funds keep their
+-- rows untouched, and nothing here uses the new values before the transaction
+-- commits.
+ALTER TYPE payout_frequency ADD VALUE IF NOT EXISTS 'weekly';
+ALTER TYPE payout_frequency ADD VALUE IF NOT EXISTS 'biweekly';
+ALTER TYPE payout_frequency ADD VALUE IF NOT EXISTS 'quarterly';
+
+-- A donor giving once per payout on a biweekly fund is billed every two weeks,
+-- which is a plan of WEEK x 2 beside the existing WEEK x 1 at the same amount.
+-- Plans were unique on unit and amount alone, so the second would have been
+-- upserted over the first and every weekly subscriber at that amount left
+-- pointing at a row describing somebody else's plan.
+--
+-- interval_count was never set by the donation handler and is 0 on existing
+-- rows. PayPal was always asked for a count of 1, so that is what they are.
+UPDATE donation_plan
+SET interval_count = 1,
+    updated        = now()
+WHERE interval_count < 1;
+
+ALTER TABLE donation_plan
+    DROP CONSTRAINT interval_unit_amount,
+    ADD CONSTRAINT interval_unit_count_amount UNIQUE (interval_unit, interval_count, amount_cents),
+    ADD CONSTRAINT donation_plan_interval_count_positive CHECK (interval_count >= 1);
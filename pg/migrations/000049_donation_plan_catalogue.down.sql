@@ -0,0 +1,18 @@
+-- Postgres has no DROP VALUE, so removing 'YEAR' means rebuilding the type. The
+-- cast fails while a yearly plan exists, which is intended: those donors are
+-- billed yearly and no other unit describes that.
+ALTER TYPE interval_unit RENAME TO interval_unit_old;
+
+CREATE TYPE interval_unit AS ENUM ('WEEK', 'MONTH');
+
+ALTER TABLE donation_plan
+    ALTER COLUMN interval_unit TYPE interval_unit
+        USING interval_unit::text::interval_unit;
+
+DROP TYPE interval_unit_old;
+
+-- Fails if two funds have a plan for the same amount and cycle, which the old
+-- constraint cannot describe.
+ALTER TABLE donation_plan
+    DROP CONSTRAINT donation_plan_catalogue,
+    ADD CONSTRAINT interval_unit_count_amount UNIQUE (interval_unit, interval_count, amount_cents);
@@ -0,0 +1,13 @@
+-- Plans were unique on unit, count and amount across every fund. The recurring
+-- form upserted on that key, so the one $10 monthly plan was handed to whichever
+-- fund last asked for it -- and a donor completing a subscription on the fund it
+-- was taken from was then refused, because the plan no longer belonged to it.
+--
+-- One plan per fund, amount and billing cycle instead, which is what the form
+-- now looks up before asking PayPal for another.
+ALTER TABLE donation_plan
+    DROP CONSTRAINT interval_unit_count_amount,
+    ADD CONSTRAINT donation_plan_catalogue UNIQUE (fund_id, interval_unit, interval_count, amount_cents);
+
+-- Yearly giving. Declared only; nothing uses it before the transaction commits.
+ALTER TYPE interval_unit ADD VALUE IF NOT EXISTS 'YEAR';
@@ -0,0 +1,2 @@
+ALTER TABLE fund
+    DROP COLUMN IF EXISTS close_on_goal;
@@ -0,0 +1,8 @@
+-- Whether a fund closes itself once it has collected its goal.
+--
+-- Off by default, and off for every fund that already exists. A goal has only
+-- ever been a number shown to donors, and a fund that happens to have passed its
+-- own would otherwise be closed -- subscriptions cancelled -- by the first run
+-- of the job after this deploy, without anyone having asked for that.
+ALTER TABLE fund
+    ADD COLUMN close_on_goal boolean NOT NULL DEFAULT false;
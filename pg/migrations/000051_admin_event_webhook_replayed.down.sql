@@ -0,0 +1 @@
+-- Postgres cannot drop a value from an enum; the one added above goes unused.
@@ -0,0 +1,9 @@
+-- Replaying a dead-lettered webhook re-runs whatever that event does to the
+-- money: a payment recorded, a refund applied, a subscription ended. It is done
+-- by an admin, or from the command line, and not about any one fund -- the
+-- payload may not even name one the app knows about yet -- so it belongs here
+-- rather than in fund_event.
+--
+-- The subject is the dead letter itself, named in subject_label ("event type,
+-- dead letter N"). There is no member whose access changed.
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'webhook_replayed';
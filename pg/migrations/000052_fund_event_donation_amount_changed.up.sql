@@ -0,0 +1,4 @@
+-- A donor moving their subscription to a different amount. Its own kind rather
+-- than a cancellation and a start: the donation is the same one, with the same
+-- history, and the feed should say so.
+ALTER TYPE fund_event_kind ADD VALUE IF NOT EXISTS 'donation_amount_changed';
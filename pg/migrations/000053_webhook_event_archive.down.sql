@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS webhook_event_outcome;
+DROP TYPE IF EXISTS webhook_outcome;
+DROP TABLE IF EXISTS webhook_event;
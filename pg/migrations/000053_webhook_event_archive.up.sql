@@ -0,0 +1,87 @@
+-- Every verified webhook, as PayPal sent it, kept for as long as the database is.
+--
+-- webhook_delivery records that a transmission arrived and nothing else, and the
+-- payload lived only in the WEBHOOKS stream -- which drops it after a week. A
+-- donor disputing a charge in March, or a tax question about last year, needed
+-- the event itself, and there was nothing left to show them.
+--
+-- One row per transmission, written in the same transaction as its delivery
+-- row, so an event is archived exactly when it is accepted. A request that
+-- failed verification is not here: it is not something PayPal sent.
+CREATE TABLE webhook_event
+(
+    transmission_id     text PRIMARY KEY REFERENCES webhook_delivery (transmission_id),
+
+    -- The envelope, pulled out of the payload so the admin page can filter on it
+    -- without reaching into jsonb. Empty rather than null when PayPal leaves one
+    -- out: the payload is the record, and these are only ways into it.
+    event_id            text                     NOT NULL,
+    event_type          text                     NOT NULL,
+    resource_type       text                     NOT NULL,
+    resource_id         text                     NOT NULL,
+    summary             text                     NOT NULL,
+
+    -- The whole body, unchanged in meaning. jsonb reorders keys and drops
+    -- whitespace, which is fine: the signature was checked before it got here,
+    -- and nothing re-verifies from this copy.
+    payload             jsonb                    NOT NULL,
+
+    -- How verification went. The signature always checked out, or the row would
+    -- not exist; what varies is whether the signing certificate chained to a
+    -- trusted root, which PayPal's ordinarily does not. Kept per event so the day
+    -- it starts to is visible, and so is any day it stops.
+    cert_url            text                     NOT NULL,
+    certificate_chained boolean                  NOT NULL,
+    chain_error         text,
+
+    -- When PayPal says the event happened, which can be well before it arrived
+    -- here when it was redelivered. Null when the envelope has no usable time.
+    created_at          timestamp with time zone,
+    received_at         timestamp with time zone NOT NULL DEFAULT now()
+);
+
+-- The admin page filters by type and reads newest first; by resource when
+-- somebody has a payment or subscription id in hand; and by date alone.
+CREATE INDEX webhook_event_type_received_idx ON webhook_event (event_type, received_at DESC);
+CREATE INDEX webhook_event_resource_id_idx ON webhook_event (resource_id);
+CREATE INDEX webhook_event_received_at_idx ON webhook_event (received_at DESC);
+
+-- What the consumers made of an archived event, one row per delivery attempt.
+--
+-- 'failed' is an attempt that will be retried; 'abandoned' is the last one,
+-- after which the event is in the dead-letter stream. A replay from there files
+-- further attempts against the same transmission, so the history of an event
+-- reads straight through from first try to whatever finally handled it.
+CREATE TYPE webhook_outcome AS ENUM (
+    'handled',
+    'failed',
+    'abandoned'
+    );
+
+CREATE TABLE webhook_event_outcome
+(
+    id              uuid PRIMARY KEY,
+    transmission_id text                     NOT NULL REFERENCES webhook_event (transmission_id),
+
+    -- The event type the consumer subscribed to. There is one durable consumer
+    -- per type, so this names which handler ran.
+    consumer        text                     NOT NULL,
+
+    -- Where the attempt's message sat in WEBHOOKS, and which delivery of it this
+    -- was. A replayed dead letter is a new message and counts from one again, so
+    -- the sequence is what keeps its attempts apart from the original's.
+    stream_seq      bigint                   NOT NULL,
+    delivery        int                      NOT NULL CHECK (delivery > 0),
+    outcome         webhook_outcome          NOT NULL,
+
+    -- What the handler returned, when it failed.
+    error           text,
+
+    occurred_at     timestamp with time zone NOT NULL DEFAULT now(),
+
+    -- An attempt whose ack was lost is delivered again under the next number, so
+    -- this only refuses the same report twice.
+    UNIQUE (transmission_id, consumer, stream_seq, delivery)
+);
+
+CREATE INDEX webhook_event_outcome_transmission_idx ON webhook_event_outcome (transmission_id, occurred_at);
@@ -0,0 +1,14 @@
+ALTER TABLE donation_payment
+    DROP CONSTRAINT IF EXISTS donation_payment_dispute_within_amount;
+
+ALTER TABLE donation_payment
+    DROP COLUMN IF EXISTS dispute_updated_at,
+    DROP COLUMN IF EXISTS dispute_reason,
+    DROP COLUMN IF EXISTS disputed_cents,
+    DROP COLUMN IF EXISTS dispute_state,
+    DROP COLUMN IF EXISTS dispute_id;
+
+DROP TYPE IF EXISTS payment_dispute_state;
+
+-- Postgres cannot drop a value from an enum. payment_disputed and
+-- payment_dispute_resolved stay on fund_event_kind, unused.
@@ -0,0 +1,34 @@
+-- Disputes, and chargebacks, which PayPal reports as disputes too.
+--
+-- A dispute is not a refund. While it is open the money is held: PayPal has
+-- taken it out of the balance we can spend but may yet give it back. The payment
+-- row used to learn about this only if the dispute ended in a reversal, so for
+-- the weeks a dispute ran the fund went on counting the money, and the planner
+-- could pay it out to an enrollee before PayPal clawed it back.
+--
+-- Kept as a state beside refunded_cents rather than folded into it, because it
+-- can go either way. A dispute the fund wins returns the money, and a refund
+-- total cannot be unwound without losing the record that it ever happened.
+CREATE TYPE payment_dispute_state AS ENUM ('open', 'won', 'lost');
+
+-- One dispute per payment is all PayPal allows on a sale, so the columns sit on
+-- the payment rather than in a table of their own.
+--
+-- dispute_updated_at is the provider's update_time for the state recorded. The
+-- three dispute webhooks can arrive in any order, and a late CREATED must not
+-- reopen a dispute a RESOLVED has already closed.
+ALTER TABLE donation_payment
+    ADD COLUMN dispute_id         text,
+    ADD COLUMN dispute_state      payment_dispute_state,
+    ADD COLUMN disputed_cents     int NOT NULL DEFAULT 0,
+    ADD COLUMN dispute_reason     text,
+    ADD COLUMN dispute_updated_at timestamptz;
+
+-- Held money is a part of the payment, never more, for the same reason the
+-- refund total is: a bad amount would otherwise make the balance negative.
+ALTER TABLE donation_payment
+    ADD CONSTRAINT donation_payment_dispute_within_amount
+        CHECK (disputed_cents >= 0 AND disputed_cents <= amount_cents);
+
+ALTER TYPE fund_event_kind ADD VALUE IF NOT EXISTS 'payment_disputed';
+ALTER TYPE fund_event_kind ADD VALUE IF NOT EXISTS 'payment_dispute_resolved';
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS fund_provider;
+
+-- Fails while a fund has the same plan at two providers, which the old
+-- constraint cannot describe.
+ALTER TABLE donation_plan
+    DROP CONSTRAINT donation_plan_catalogue,
+    ADD CONSTRAINT donation_plan_catalogue UNIQUE (fund_id, interval_unit, interval_count, amount_cents);
+
+ALTER TABLE donation_plan
+    DROP COLUMN provider_name;
+
+ALTER TABLE donation
+    DROP COLUMN provider_name;
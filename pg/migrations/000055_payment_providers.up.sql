@@ -0,0 +1,30 @@
+-- A fund can now take donations through more than one provider, so the rows a
+-- provider owns say which one. Everything before this was PayPal's, which is
+-- what the default records.
+ALTER TABLE donation
+    ADD COLUMN provider_name varchar(200) NOT NULL DEFAULT 'paypal';
+
+-- paypal_plan_id keeps its name and now holds whichever provider's id the plan
+-- was created under: a Stripe price, for a Stripe plan. Renaming it would touch
+-- every query that reads a plan for no change in what it means.
+ALTER TABLE donation_plan
+    ADD COLUMN provider_name varchar(200) NOT NULL DEFAULT 'paypal';
+
+-- One plan per fund, provider, amount and cycle. A $10 monthly plan at PayPal is
+-- no use to a donor paying through Stripe, so each provider has its own.
+ALTER TABLE donation_plan
+    DROP CONSTRAINT donation_plan_catalogue,
+    ADD CONSTRAINT donation_plan_catalogue UNIQUE (fund_id, provider_name, interval_unit, interval_count, amount_cents);
+
+-- Where a fund is held at providers other than its own. fund.provider_id and
+-- provider_name stay the fund's home; a row here is created the first time a
+-- donor picks another provider, since a product somewhere the fund never takes
+-- money is clutter in somebody's dashboard.
+CREATE TABLE fund_provider
+(
+    fund_id       uuid         NOT NULL REFERENCES fund (id),
+    provider_name varchar(200) NOT NULL,
+    provider_id   varchar(200) NOT NULL,
+    created       timestamptz  NOT NULL DEFAULT now(),
+    PRIMARY KEY (fund_id, provider_name)
+);
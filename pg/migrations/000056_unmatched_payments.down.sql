@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS unmatched_payment;
+-- Postgres cannot drop a value from an enum; payment_assigned goes unused.
@@ -0,0 +1,51 @@
+-- Money a provider took that nothing here accounts for.
+--
+-- A one-time donation is recorded when the browser reports back after PayPal
+-- captures it. A donor who closes the tab in between has paid, and the fund
+-- never hears about it. The import job finds those captures by searching the
+-- provider's transactions, and records whatever it can tie to a fund and a
+-- donor. What it cannot tie lands here for an admin to assign, rather than in a
+-- log line nobody reads.
+--
+-- One row per provider payment, so a second import over the same window adds
+-- nothing.
+CREATE TABLE unmatched_payment
+(
+    id                   uuid         NOT NULL PRIMARY KEY,
+    provider_name        varchar(200) NOT NULL,
+    provider_payment_id  varchar(200) NOT NULL,
+
+    -- The order the payment was captured under, when the provider named one.
+    provider_order_id    varchar(200),
+    amount_cents         int          NOT NULL,
+    provider_fee_cents   int          NOT NULL DEFAULT 0,
+
+    -- Who paid, as the provider reports them, for the admin to recognise.
+    payer_email          varchar(200),
+    payer_name           varchar(200),
+
+    -- The fund the order named, when it named one that exists. A payment with a
+    -- fund and no donor is the usual case: somebody gave without an account.
+    fund_id              uuid REFERENCES fund (id),
+
+    -- Why the import could not record it.
+    reason               text         NOT NULL,
+    occurred_at          timestamptz  NOT NULL,
+
+    -- Set together when an admin assigns it, which is when it stops being
+    -- unmatched. Kept rather than deleted, so the donation it became can be
+    -- traced back to how it was found.
+    donation_id          uuid REFERENCES donation (id),
+    assigned_by          uuid REFERENCES member (id),
+    assigned_at          timestamptz,
+
+    created              timestamptz  NOT NULL DEFAULT now(),
+
+    CONSTRAINT unmatched_payment_once UNIQUE (provider_name, provider_payment_id)
+);
+
+CREATE INDEX unmatched_payment_open ON unmatched_payment (occurred_at DESC) WHERE assigned_at IS NULL;
+
+-- An admin saying whose money an unmatched payment was. It records a donation
+-- and moves a fund's balance, on the admin's word alone.
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'payment_assigned';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS signing_key;
+DROP TABLE IF EXISTS local_user_group;
+DROP TABLE IF EXISTS local_user;
@@ -0,0 +1,49 @@
+-- Accounts for the self-hosted authorizer, which stands in for Cognito where
+-- there is no AWS to reach: development, and staging. Production keeps Cognito
+-- and leaves these tables empty.
+--
+-- Shaped after what Cognito holds, because the rest of the application was
+-- written against Cognito and reads it through the same interface: a username,
+-- an email, the member the account belongs to, and whether the password is a
+-- temporary one the member must replace before anything else.
+CREATE TABLE local_user
+(
+    -- Given back from CreateUser and kept as member.cognito_id, the way
+    -- Cognito's sub is.
+    id            uuid         NOT NULL PRIMARY KEY,
+    username      varchar(200) NOT NULL UNIQUE,
+    email         varchar(200) NOT NULL,
+
+    -- Not a reference: the account is created before the member row, exactly as
+    -- it is at Cognito.
+    member_id     uuid         NOT NULL,
+
+    -- argon2id, in the PHC string format, parameters and salt included. Only
+    -- the hash is kept; nothing can recover the password from it.
+    password_hash text         NOT NULL,
+    must_reset    boolean      NOT NULL DEFAULT true,
+
+    created       timestamptz  NOT NULL DEFAULT now(),
+    updated       timestamptz  NOT NULL DEFAULT now()
+);
+
+-- Group membership, the local cognito:groups. One row per group a user is in.
+CREATE TABLE local_user_group
+(
+    username   varchar(200) NOT NULL REFERENCES local_user (username) ON DELETE CASCADE,
+    group_name varchar(200) NOT NULL,
+    created    timestamptz  NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (username, group_name)
+);
+
+-- The keys the authorizer signs tokens with. Kept here rather than generated at
+-- boot so a deploy does not sign everybody out: a token issued before the
+-- restart still names a key the new process has. The public halves are what the
+-- JWKS endpoint serves.
+CREATE TABLE signing_key
+(
+    kid         varchar(200) NOT NULL PRIMARY KEY,
+    private_jwk jsonb        NOT NULL,
+    created     timestamptz  NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS password_reset;
+-- Postgres cannot drop a value from an enum; the password_reset kinds go unused.
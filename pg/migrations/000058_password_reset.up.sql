@@ -0,0 +1,31 @@
+-- Requests to reset a forgotten password, and the single-use tokens they issue.
+--
+-- Every request is a row, whether or not the address belongs to anybody. That
+-- is what lets the limits be counted per address and per IP without the count
+-- itself saying which addresses have accounts. Only a request for a member's
+-- address has a member and a token.
+CREATE TABLE password_reset
+(
+    id          uuid         NOT NULL PRIMARY KEY,
+
+    -- As typed, lowercased: the limit is on the address asked about.
+    email       varchar(200) NOT NULL,
+    ip_address  varchar(100) NOT NULL,
+
+    member_id   uuid REFERENCES member (id),
+
+    -- sha256 of the token that was emailed. The token itself is never stored,
+    -- so a read of this table resets nobody's password.
+    token_hash  bytea UNIQUE,
+    expires_at  timestamptz,
+    used_at     timestamptz,
+
+    created     timestamptz  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX password_reset_by_email ON password_reset (email, created);
+CREATE INDEX password_reset_by_ip ON password_reset (ip_address, created);
+
+-- Somebody asking for a reset link, and somebody using one.
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'password_reset_requested';
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'password_reset_completed';
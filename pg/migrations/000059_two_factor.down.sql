@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS member_recovery_code;
+DROP TABLE IF EXISTS member_totp;
+-- Postgres cannot drop a value from an enum; the two-factor kinds go unused.
@@ -0,0 +1,38 @@
+-- A member's TOTP authenticator, and the recovery codes for when it is lost.
+--
+-- One authenticator per member. A row with no confirmed_at is an enrolment
+-- begun and not finished: the secret has been shown, and no code from it has
+-- been given back yet, so it protects nothing and is replaced by the next try.
+CREATE TABLE member_totp
+(
+    member_id      uuid        NOT NULL PRIMARY KEY REFERENCES member (id),
+
+    -- base32, as the authenticator app was given it. Kept readable because a
+    -- code cannot be checked without it; the database is the boundary here, as
+    -- it already is for the session store.
+    secret         text        NOT NULL,
+    confirmed_at   timestamptz,
+
+    -- The last 30-second step a code was accepted for. A code is only good once:
+    -- anybody who sees one typed cannot use it again in the same half-minute.
+    last_used_step bigint      NOT NULL DEFAULT 0,
+
+    created        timestamptz NOT NULL DEFAULT now()
+);
+
+-- Single-use codes given out at enrolment. Only a hash is kept, as for a
+-- password: they are shown once and never again.
+CREATE TABLE member_recovery_code
+(
+    id        uuid        NOT NULL PRIMARY KEY,
+    member_id uuid        NOT NULL REFERENCES member (id),
+    code_hash bytea       NOT NULL,
+    used_at   timestamptz,
+    created   timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT member_recovery_code_once UNIQUE (member_id, code_hash)
+);
+
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'two_factor_enabled';
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'two_factor_disabled';
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'recovery_code_used';
@@ -0,0 +1,5 @@
+ALTER TABLE admin_event
+    DROP COLUMN IF EXISTS role;
+
+DROP TABLE IF EXISTS fund_manager;
+-- Postgres cannot drop a value from an enum; the role kinds go unused.
@@ -0,0 +1,28 @@
+-- Admin is no longer all-or-nothing: treasurer, moderator and fund manager are
+-- groups alongside the admin group, each granting part of what it does. See
+-- jwtauth/roles.go.
+--
+-- A fund manager manages particular funds, which a token cannot say: groups are
+-- the same for every request, and the funds are a list that changes. This is
+-- the list, checked per request.
+CREATE TABLE fund_manager
+(
+    member_id uuid        NOT NULL REFERENCES member (id),
+    fund_id   uuid        NOT NULL REFERENCES fund (id),
+    created   timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (member_id, fund_id)
+);
+
+CREATE INDEX fund_manager_fund_idx ON fund_manager (fund_id);
+
+-- Which role a grant or revoke was of. Null for the events that are not about
+-- a role, and for admin_granted and admin_revoked, which can only have been of
+-- the admin group.
+ALTER TABLE admin_event
+    ADD COLUMN role text;
+
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'role_granted';
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'role_revoked';
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'fund_manager_assigned';
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'fund_manager_removed';
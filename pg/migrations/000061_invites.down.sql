@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS invite;
+-- Postgres cannot drop a value from an enum; the invite kinds go unused.
@@ -0,0 +1,36 @@
+-- Invite links, which replace approved_email as the way somebody is let in to
+-- register. approved_email is an address typed in ahead of time and matched
+-- exactly; an invite is a link an admin hands over, which can be bound to an
+-- address or left for whoever has it, and can carry a fund to enroll in.
+--
+-- The link is signed over the id and the expiry, so nothing secret is stored
+-- here: a read of this table invites nobody. The row is what makes the link
+-- single-use and revocable, and what answers who let whom in.
+CREATE TABLE invite
+(
+    id         uuid         NOT NULL PRIMARY KEY,
+
+    -- Lowercased. Null means the link works for any address.
+    email      varchar(200),
+
+    -- The fund the new member is enrolled in as they register. Null for none.
+    fund_id    uuid REFERENCES fund (id),
+
+    invited_by uuid         NOT NULL REFERENCES member (id),
+
+    -- Who registered with it, once somebody has.
+    member_id  uuid REFERENCES member (id),
+
+    expires_at timestamptz  NOT NULL,
+    used_at    timestamptz,
+    revoked_at timestamptz,
+
+    created    timestamptz  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX invite_created_idx ON invite (created);
+
+-- An invite issued, withdrawn, and registered with.
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'invite_created';
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'invite_revoked';
+ALTER TYPE admin_event_kind ADD VALUE IF NOT EXISTS 'invite_redeemed';
@@ -0,0 +1,13 @@
+-- Wrong second-factor codes, counted per member rather than per session.
+--
+-- The login form already gave up after a few wrong codes, but it counted in the
+-- session, and the step-up and the disable form did not count at all: anybody
+-- holding a logged-in session could keep guessing, with three codes good at any
+-- moment. Counted here, every form that takes a code draws on the same allowance.
+--
+-- failed_attempts is counted before a code is checked and cleared when one is
+-- right, so what it holds is the wrong ones since the last right one. Reaching
+-- the limit sets locked_until, and until then no code is checked at all.
+ALTER TABLE member_totp
+    ADD COLUMN failed_attempts int NOT NULL DEFAULT 0,
+    ADD COLUMN locked_until    timestamptz;
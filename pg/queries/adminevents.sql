@@ -1,7 +1,7 @@
 -- name: InsertAdminEvent :one
 INSERT INTO admin_event (id, kind, occurred_at, actor_member_id, subject_member_id, subject_label,
-                         detail)
-VALUES ($1, $2, COALESCE(sqlc.narg(occurred_at)::timestamptz, now()), $3, $4, $5, $6)
+                         detail, role)
+VALUES ($1, $2, COALESCE(sqlc.narg(occurred_at)::timestamptz, now()), $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- Newest first, with both names resolved so the page does not issue a query per
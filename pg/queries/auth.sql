@@ -24,3 +24,204 @@ ORDER BY created;
 DELETE FROM approved_email
 WHERE email = $1
 RETURNING *;
+
+-- name: GetActiveMemberByEmail :one
+SELECT id, bco_name
+FROM member
+WHERE active
+  AND lower(email) = lower($1)
+ORDER BY created
+LIMIT 1;
+
+-- name: InsertPasswordReset :one
+INSERT INTO password_reset (id, email, ip_address, member_id, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: CountPasswordResetsByEmail :one
+SELECT count(*)
+FROM password_reset
+WHERE email = $1
+  AND created > $2;
+
+-- name: CountPasswordResetsByIP :one
+SELECT count(*)
+FROM password_reset
+WHERE ip_address = $1
+  AND created > $2;
+
+-- name: RedeemPasswordReset :one
+UPDATE password_reset
+SET used_at = now()
+WHERE token_hash = $1
+  AND member_id IS NOT NULL
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING *;
+
+-- name: ReleasePasswordReset :exec
+UPDATE password_reset
+SET used_at = NULL
+WHERE id = $1;
+
+-- name: RetireOtherPasswordResets :exec
+UPDATE password_reset
+SET used_at = now()
+WHERE member_id = $1
+  AND id <> $2
+  AND used_at IS NULL;
+
+-- name: GetMemberTOTP :one
+SELECT *
+FROM member_totp
+WHERE member_id = $1;
+
+-- name: UpsertPendingMemberTOTP :one
+INSERT INTO member_totp (member_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (member_id) DO UPDATE
+    SET secret  = excluded.secret,
+        created = now()
+WHERE member_totp.confirmed_at IS NULL
+RETURNING *;
+
+-- name: ConfirmMemberTOTP :one
+UPDATE member_totp
+SET confirmed_at   = now(),
+    last_used_step = $2
+WHERE member_id = $1
+  AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: UseMemberTOTPStep :one
+UPDATE member_totp
+SET last_used_step = $2
+WHERE member_id = $1
+  AND confirmed_at IS NOT NULL
+  AND last_used_step < $2
+RETURNING *;
+
+-- Takes one attempt from the member's allowance before the code is checked, so
+-- guesses sent all at once are each counted rather than all checked against the
+-- count as it stood. No row means the member is locked out.
+-- name: ClaimMemberTOTPAttempt :one
+UPDATE member_totp
+SET failed_attempts = failed_attempts + 1,
+    locked_until    = CASE
+                          WHEN failed_attempts + 1 >= sqlc.arg(max_attempts)::int THEN sqlc.arg(lock_until)::timestamptz
+                          ELSE locked_until END
+WHERE member_id = sqlc.arg(member_id)
+  AND confirmed_at IS NOT NULL
+  AND (locked_until IS NULL OR locked_until <= sqlc.arg(now)::timestamptz)
+RETURNING *;
+
+-- name: ClearMemberTOTPAttempts :exec
+UPDATE member_totp
+SET failed_attempts = 0,
+    locked_until    = NULL
+WHERE member_id = $1;
+
+-- name: DeleteMemberTOTP :exec
+DELETE FROM member_totp
+WHERE member_id = $1;
+
+-- name: InsertRecoveryCode :exec
+INSERT INTO member_recovery_code (id, member_id, code_hash)
+VALUES ($1, $2, $3);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM member_recovery_code
+WHERE member_id = $1;
+
+-- name: UseRecoveryCode :one
+UPDATE member_recovery_code
+SET used_at = now()
+WHERE member_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL
+RETURNING *;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT count(*)
+FROM member_recovery_code
+WHERE member_id = $1
+  AND used_at IS NULL;
+
+-- name: AssignFundManager :execrows
+INSERT INTO fund_manager (member_id, fund_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveFundManager :execrows
+DELETE FROM fund_manager
+WHERE member_id = $1
+  AND fund_id = $2;
+
+-- name: IsFundManager :one
+SELECT EXISTS (SELECT 1
+               FROM fund_manager
+               WHERE member_id = $1
+                 AND fund_id = $2);
+
+-- name: GetManagedFunds :many
+SELECT fund.id, fund.name
+FROM fund_manager
+         JOIN fund ON fund.id = fund_manager.fund_id
+WHERE fund_manager.member_id = $1
+ORDER BY fund.name;
+
+-- name: InsertInvite :one
+INSERT INTO invite (id, email, fund_id, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetInvite :one
+SELECT invite.*,
+       fund.name        AS fund_name,
+       inviter.bco_name AS inviter_name,
+       invitee.bco_name AS member_name
+FROM invite
+         LEFT JOIN fund ON fund.id = invite.fund_id
+         JOIN member inviter ON inviter.id = invite.invited_by
+         LEFT JOIN member invitee ON invitee.id = invite.member_id
+WHERE invite.id = $1;
+
+-- name: GetInvites :many
+SELECT invite.*,
+       fund.name        AS fund_name,
+       inviter.bco_name AS inviter_name,
+       invitee.bco_name AS member_name
+FROM invite
+         LEFT JOIN fund ON fund.id = invite.fund_id
+         JOIN member inviter ON inviter.id = invite.invited_by
+         LEFT JOIN member invitee ON invitee.id = invite.member_id
+ORDER BY invite.created DESC
+LIMIT $1;
+
+-- name: ClaimInvite :one
+UPDATE invite
+SET used_at = now()
+WHERE id = $1
+  AND used_at IS NULL
+  AND revoked_at IS NULL
+  AND expires_at > now()
+RETURNING *;
+
+-- name: ReleaseInvite :exec
+UPDATE invite
+SET used_at = NULL
+WHERE id = $1
+  AND member_id IS NULL;
+
+-- name: SetInviteMember :exec
+UPDATE invite
+SET member_id = $2
+WHERE id = $1;
+
+-- name: RevokeInvite :one
+UPDATE invite
+SET revoked_at = now()
+WHERE id = $1
+  AND used_at IS NULL
+  AND revoked_at IS NULL
+RETURNING *;
@@ -3,12 +3,69 @@ INSERT INTO donation_plan (id, name, amount_cents, interval_unit, interval_count
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
--- name: UpsertDonationPlan :one
+-- The fund's plan for this amount and billing cycle, if it has a live one.
+--
+-- Touching updated is the point of doing this as an UPDATE: a plan is abandoned
+-- when nobody has subscribed to it and nobody has asked for it lately, and a
+-- donor who has just been handed this plan may be in PayPal's popup with it now.
+-- Pruning measures its grace period from here.
+-- name: ReuseDonationPlan :one
+UPDATE donation_plan
+SET updated = now()
+WHERE fund_id = $1
+  AND amount_cents = $2
+  AND interval_unit = $3
+  AND interval_count = $4
+  AND provider_name = $5
+  AND active = true
+RETURNING *;
+
+-- Adds a plan to the catalogue, or brings back one that was pruned with the
+-- provider plan just created for it. A pruned plan had no subscriptions, so
+-- nothing refers to the provider id being replaced.
+--
+-- Returns nothing when a live plan is already there: somebody else asked for
+-- the same amount at the same moment and theirs won. The caller deactivates the
+-- plan it made rather than leaving it behind at PayPal.
+-- name: CatalogueDonationPlan :many
 INSERT INTO donation_plan (id, name, amount_cents, interval_unit, interval_count, active, paypal_plan_id, fund_id,
-                           updated)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now())
-ON CONFLICT (interval_unit, amount_cents) DO UPDATE
-    SET (name, active, paypal_plan_id, fund_id) = ($2, $6, $7, $8)
+                           provider_name, updated)
+VALUES ($1, $2, $3, $4, $5, true, $6, $7, $8, now())
+ON CONFLICT (fund_id, provider_name, interval_unit, interval_count, amount_cents) DO UPDATE
+    SET (name, active, paypal_plan_id, updated) = (EXCLUDED.name, true, EXCLUDED.paypal_plan_id, now())
+    WHERE donation_plan.active = false
+RETURNING *;
+
+-- name: GetActiveDonationPlansForFund :many
+SELECT *
+FROM donation_plan
+WHERE fund_id = $1
+  AND active = true
+ORDER BY created;
+
+-- Plans that are only costing clutter at PayPal: nobody has subscribed to them
+-- and nobody has been handed one since the cutoff. Every opening of the
+-- recurring form used to create one, so most were made for a donor who then
+-- closed the popup.
+--
+-- A closed fund's plans are included whether or not anyone subscribed. Closing
+-- cancelled the subscriptions and deactivates its plans; any it could not are
+-- picked up here.
+-- name: GetAbandonedDonationPlans :many
+SELECT p.*
+FROM donation_plan p
+         JOIN fund f ON f.id = p.fund_id
+WHERE p.active = true
+  AND (f.active = false
+    OR (p.updated < sqlc.arg('unused_since')::timestamptz
+        AND NOT EXISTS (SELECT 1 FROM donation d WHERE d.donation_plan_id = p.id)))
+ORDER BY p.created;
+
+-- name: SetDonationPlanInactive :one
+UPDATE donation_plan
+SET active  = false,
+    updated = now()
+WHERE id = $1
 RETURNING *;
 
 -- name: GetDonationPlanById :one
@@ -16,6 +73,13 @@ SELECT *
 FROM donation_plan
 WHERE id = $1;
 
+-- The plan a subscription reports paying into, by the provider's id for it. :many
+-- so a plan that is not ours is no rows rather than ErrNoRows.
+-- name: GetDonationPlanByProviderPlanId :many
+SELECT *
+FROM donation_plan
+WHERE paypal_plan_id = $1;
+
 -- name: UpdateDonationPlan :one
 UPDATE donation_plan
 SET (name, amount_cents, interval_unit, interval_count, active, paypal_plan_id, fund_id,
@@ -29,8 +93,9 @@ FROM donation_plan
 ORDER BY created;
 
 -- name: InsertDonation :one
-INSERT INTO donation (id, donor_id, fund_id, recurring, donation_plan_id, provider_order_id, provider_subscription_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+INSERT INTO donation (id, donor_id, fund_id, recurring, donation_plan_id, provider_order_id, provider_subscription_id,
+                      provider_name)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
 -- name: GetDonationById :one
@@ -61,13 +126,14 @@ SELECT d.id,
        COALESCE(SUM(dp.amount_cents - dp.refunded_cents), 0)::bigint AS total_given_cents,
        MAX(dp.created)::timestamptz                                  AS last_payment_at,
        p.amount_cents                                                AS plan_amount_cents,
-       p.interval_unit                                               AS plan_interval_unit
+       p.interval_unit                                               AS plan_interval_unit,
+       p.interval_count                                              AS plan_interval_count
 FROM donation d
          JOIN fund f ON f.id = d.fund_id
          LEFT JOIN donation_payment dp ON dp.donation_id = d.id
          LEFT JOIN donation_plan p ON p.id = d.donation_plan_id
 WHERE d.donor_id = $1
-GROUP BY d.id, f.name, f.active, p.amount_cents, p.interval_unit
+GROUP BY d.id, f.name, f.active, p.amount_cents, p.interval_unit, p.interval_count
 -- Live donations first, because those are the ones with a decision attached.
 ORDER BY d.active DESC, d.created DESC;
 
@@ -97,6 +163,19 @@ WHERE donor_id = $1
   AND active = true
 RETURNING *;
 
+-- Moves a donation onto another plan of its fund, once the provider has moved the
+-- subscription. A compare-and-set: the donor coming back from PayPal and the
+-- provider's webhook both apply the same change, and whichever is second finds
+-- the donation already on the plan and returns no row, so the feed records the
+-- change once.
+-- name: SetDonationPlanForDonation :many
+UPDATE donation
+SET donation_plan_id = $2,
+    updated          = now()
+WHERE id = $1
+  AND donation_plan_id IS DISTINCT FROM $2
+RETURNING *;
+
 -- name: SetDonationToInactiveBySubscriptionId :one
 UPDATE donation
 SET active          = false,
@@ -126,6 +205,18 @@ WHERE donation.fund_id = fund.id
   AND fund.active = true
 RETURNING donation.*;
 
+-- The donation ReactivateSuspendedDonationBySubscriptionId would bring back,
+-- read without bringing it back. The same predicate, so a dry run of a webhook
+-- replay predicts what the live one does.
+-- name: GetSuspendedDonationBySubscriptionId :many
+SELECT donation.*
+FROM donation
+         JOIN fund ON fund.id = donation.fund_id
+WHERE donation.provider_subscription_id = $1
+  AND donation.active = false
+  AND donation.inactive_reason = 'SUSPENDED'
+  AND fund.active = true;
+
 -- name: SetDonationsToActive :many
 UPDATE donation
 SET active = true
@@ -193,6 +284,56 @@ WHERE dp.id = p.id
 RETURNING dp.id AS payment_id, d.id AS donation_id, d.fund_id, d.donor_id,
     dp.amount_cents, dp.refunded_cents, p.refunded_cents AS previously_refunded_cents;
 
+-- Records where a dispute against a payment stands.
+--
+-- The three dispute webhooks carry the whole dispute each time, so this sets
+-- rather than steps: whichever arrives, the row ends up as the provider last
+-- described it. What keeps them in order is the provider's update_time. A
+-- redelivery carries the same one and matches nothing, and a CREATED arriving
+-- after its RESOLVED carries an older one and cannot reopen what was settled.
+--
+-- The state before the update is returned beside the new one, as with refunds,
+-- so the caller can tell a dispute opening or closing from one merely updated --
+-- only the first two belong in the feed.
+-- name: SetDonationPaymentDispute :many
+WITH previous AS (SELECT prev.id, prev.dispute_state
+                  FROM donation_payment prev
+                  WHERE prev.paypal_payment_id = sqlc.arg(paypal_payment_id))
+UPDATE donation_payment dp
+SET dispute_id         = sqlc.arg(dispute_id),
+    dispute_state      = sqlc.arg(dispute_state)::payment_dispute_state,
+    disputed_cents     = LEAST(sqlc.arg(disputed_cents)::int, dp.amount_cents),
+    dispute_reason     = sqlc.narg(dispute_reason)::text,
+    dispute_updated_at = sqlc.arg(update_time),
+    updated            = now()
+FROM donation d, previous p
+WHERE dp.id = p.id
+  AND dp.donation_id = d.id
+  AND (dp.dispute_updated_at IS NULL OR dp.dispute_updated_at < sqlc.arg(update_time))
+RETURNING dp.id AS payment_id, d.id AS donation_id, d.fund_id, d.donor_id,
+    dp.amount_cents, dp.dispute_id, dp.dispute_state, dp.disputed_cents,
+    p.dispute_state AS previous_dispute_state;
+
+-- A payment's dispute as it stands, for predicting what the update above would
+-- do without making it. :many for the same reason as the lookup below.
+-- name: GetDonationPaymentDispute :many
+SELECT dp.id AS payment_id, d.id AS donation_id, d.fund_id, d.donor_id,
+       dp.amount_cents, dp.dispute_id, dp.dispute_state, dp.disputed_cents,
+       dp.dispute_updated_at
+FROM donation_payment dp
+         JOIN donation d ON d.id = dp.donation_id
+WHERE dp.paypal_payment_id = $1;
+
+-- A payment by the provider's id, with what a refund against it needs: the
+-- donation it belongs to and how much of it has come back so far. :many so an
+-- unknown payment is no rows rather than ErrNoRows, as with the refund itself.
+-- name: GetDonationPaymentByProviderPaymentId :many
+SELECT dp.id AS payment_id, d.id AS donation_id, d.fund_id, d.donor_id,
+       dp.amount_cents, dp.refunded_cents
+FROM donation_payment dp
+         JOIN donation d ON d.id = dp.donation_id
+WHERE dp.paypal_payment_id = $1;
+
 -- Records what reconciliation saw at the provider, beside the payment itself.
 --
 -- reconciled_at is set on every check, including one that found nothing wrong, so
@@ -222,6 +363,9 @@ SELECT dp.id,
        dp.provider_status,
        dp.provider_amount_cents,
        dp.reconciled_at,
+       dp.dispute_state,
+       dp.disputed_cents,
+       dp.dispute_reason,
        dp.created,
        d.recurring,
        m.bco_name AS donor_name
@@ -239,8 +383,8 @@ RETURNING *;
 
 -- name: InsertFund :one
 INSERT INTO fund (id, name, description, provider_id, provider_name, active, payout_frequency, goal_cents, expires,
-                  principal, enrollees_visible, next_payment)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+                  principal, enrollees_visible, close_on_goal, next_payment)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
         -- Anchored to midnight UTC rather than the moment of creation.
         --
         -- A fund becomes due on the first cron run after its anchor, and the
@@ -257,6 +401,12 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
                  THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '1 month') AT TIME ZONE 'UTC'
              WHEN $7::payout_frequency = 'daily'
                  THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '1 day') AT TIME ZONE 'UTC'
+             WHEN $7::payout_frequency = 'weekly'
+                 THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '7 days') AT TIME ZONE 'UTC'
+             WHEN $7::payout_frequency = 'biweekly'
+                 THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '14 days') AT TIME ZONE 'UTC'
+             WHEN $7::payout_frequency = 'quarterly'
+                 THEN (date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '3 months') AT TIME ZONE 'UTC'
              ELSE $9::timestamptz END))
 RETURNING *;
 
@@ -277,7 +427,7 @@ RETURNING *;
 -- name: UpdateFund :one
 UPDATE fund
 SET (name, description, active, payout_frequency, goal_cents, expires, principal,
-     enrollees_visible, updated) = ($2, $3, $4, $5, $6, $7, $8, $9, now()),
+     enrollees_visible, close_on_goal, updated) = ($2, $3, $4, $5, $6, $7, $8, $9, $10, now()),
     next_payment = CASE
                        WHEN $5::payout_frequency = 'once' AND next_payment IS NOT NULL
                            THEN $7::timestamptz
@@ -290,6 +440,21 @@ SELECT *
 FROM fund
 ORDER BY created;
 
+-- The fund's product at a provider other than its own, if a donor has used one.
+-- name: GetFundProviderId :many
+SELECT provider_id
+FROM fund_provider
+WHERE fund_id = $1
+  AND provider_name = $2;
+
+-- Returns nothing when another request recorded the fund at this provider first;
+-- the caller reads theirs back and leaves its own product unused.
+-- name: InsertFundProvider :many
+INSERT INTO fund_provider (fund_id, provider_name, provider_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (fund_id, provider_name) DO NOTHING
+RETURNING provider_id;
+
 -- name: GetFundById :one
 WITH FundStats AS (SELECT fund_id,
                           COALESCE(SUM(amount_cents - refunded_cents), 0)::INTEGER AS total_donated,
@@ -504,6 +669,40 @@ WHERE active = true
   AND (payout_frequency <> 'once' OR next_payment IS NULL)
 ORDER BY expires;
 
+-- Funds set to close at their goal that have collected it. The goal closer walks
+-- these and runs the same deactivation as the expiry job.
+--
+-- Collected is counted as GetFundBalanceCents counts a donation: less refunds,
+-- less money held by an open or lost dispute, and less the provider's fee. A
+-- goal reached on gross receipts closed funds that had not got the money --
+-- the fees were never in the account, and a chargeback can take the rest back
+-- after the fund has stopped taking donations to replace it. The larger of the
+-- refund and the dispute, for the reason given there.
+--
+-- The same guard as GetExpiredActiveFunds for a one-off fund, for the same
+-- reason: closed funds are skipped by the planner, so one closed before its
+-- single payout has been planned would strand everything it collected. It
+-- closes on the first run after the payout is planned instead.
+-- name: GetGoalReachedActiveFunds :many
+SELECT f.id, f.name, f.goal_cents, c.collected_cents
+FROM fund f
+         JOIN (SELECT d.fund_id,
+                      SUM(dp.amount_cents
+                          - GREATEST(dp.refunded_cents,
+                                     CASE
+                                         WHEN dp.dispute_state IN ('open', 'lost') THEN dp.disputed_cents
+                                         ELSE 0 END)
+                          - dp.provider_fee_cents)::integer AS collected_cents
+               FROM donation d
+                        JOIN donation_payment dp ON dp.donation_id = d.id
+               GROUP BY d.fund_id) c ON c.fund_id = f.id
+WHERE f.active = true
+  AND f.close_on_goal = true
+  AND f.goal_cents > 0
+  AND c.collected_cents >= f.goal_cents
+  AND (f.payout_frequency <> 'once' OR f.next_payment IS NULL)
+ORDER BY f.name;
+
 -- The public archive: funds that have ended, newest first.
 --
 -- Kept separate from the admin listing because this one is shown to donors, so
@@ -759,3 +958,65 @@ WHERE fund_id = $1;
 DELETE
 FROM fund_image
 WHERE fund_id = $1;
+
+-- Whether a provider payment is already on record, so the import can skip it
+-- without starting a donation it would only roll back.
+-- name: DonationPaymentExists :one
+SELECT EXISTS (SELECT 1 FROM donation_payment WHERE paypal_payment_id = $1);
+
+-- The member a provider's payer is, for a payment found without the browser
+-- that would have said. The payer id is what PayPal's login recorded and is
+-- certain; an email is a guess worth taking only when nothing else matches, and
+-- never when it is empty, since an empty email matches every member without one.
+-- name: FindMemberByPayer :one
+SELECT id
+FROM member
+WHERE active
+  AND ((sqlc.arg(payer_id)::text <> '' AND provider_payer_id = sqlc.arg(payer_id)::text)
+    OR (sqlc.arg(email)::text <> '' AND (lower(email) = lower(sqlc.arg(email)::text)
+        OR lower(paypal_email) = lower(sqlc.arg(email)::text))))
+ORDER BY (provider_payer_id IS NOT DISTINCT FROM sqlc.arg(payer_id)::text) DESC, created
+LIMIT 1;
+
+-- name: InsertUnmatchedPayment :many
+INSERT INTO unmatched_payment (id, provider_name, provider_payment_id, provider_order_id, amount_cents,
+                               provider_fee_cents, payer_email, payer_name, fund_id, reason, occurred_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (provider_name, provider_payment_id) DO NOTHING
+RETURNING *;
+
+-- Closes the unmatched row for a payment that has since been recorded some other
+-- way -- a webhook that arrived after the import gave up on it. Nobody assigned
+-- it, so assigned_by stays empty; the donation says where it went.
+-- name: CloseUnmatchedPayment :exec
+UPDATE unmatched_payment
+SET donation_id = $2,
+    assigned_at = now()
+WHERE provider_payment_id = $1
+  AND assigned_at IS NULL;
+
+-- The fund's name is joined for the page, which is about what to do with the
+-- money and where it may have been meant to go.
+-- name: GetOpenUnmatchedPayments :many
+SELECT up.*, f.name AS fund_name
+FROM unmatched_payment up
+         LEFT JOIN fund f ON f.id = up.fund_id
+WHERE up.assigned_at IS NULL
+ORDER BY up.occurred_at DESC;
+
+-- name: GetUnmatchedPaymentById :one
+SELECT up.*, f.name AS fund_name
+FROM unmatched_payment up
+         LEFT JOIN fund f ON f.id = up.fund_id
+WHERE up.id = $1;
+
+-- Guarded on assigned_at, so two admins assigning the same payment at once
+-- cannot both record it.
+-- name: SetUnmatchedPaymentAssigned :one
+UPDATE unmatched_payment
+SET donation_id = $2,
+    assigned_by = $3,
+    assigned_at = now()
+WHERE id = $1
+  AND assigned_at IS NULL
+RETURNING *;
@@ -25,6 +25,11 @@ SET paypal_email = $2
 WHERE id = $1
 RETURNING *;
 
+-- name: GetEnrollmentById :one
+SELECT *
+FROM fund_enrollment
+WHERE id = $1;
+
 -- name: GetEnrollmentForFundByMemberId :one
 SELECT *
 FROM fund_enrollment
@@ -0,0 +1,51 @@
+-- name: GetLocalUserByUsername :one
+SELECT *
+FROM local_user
+WHERE username = $1;
+
+-- name: InsertLocalUser :one
+INSERT INTO local_user (id, username, email, member_id, password_hash, must_reset)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: SetLocalUserPassword :one
+UPDATE local_user
+SET password_hash = $2,
+    must_reset    = $3,
+    updated       = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: AddLocalUserToGroup :exec
+INSERT INTO local_user_group (username, group_name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveLocalUserFromGroup :exec
+DELETE FROM local_user_group
+WHERE username = $1
+  AND group_name = $2;
+
+-- name: GetLocalUserGroups :many
+SELECT group_name
+FROM local_user_group
+WHERE username = $1
+ORDER BY group_name;
+
+-- name: GetSigningKeys :many
+SELECT *
+FROM signing_key
+ORDER BY created DESC;
+
+-- name: InsertSigningKey :one
+INSERT INTO signing_key (kid, private_jwk, created)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: DeleteSigningKeysBefore :exec
+DELETE FROM signing_key
+WHERE created < $1;
+
+-- name: DeleteLocalUser :exec
+DELETE FROM local_user
+WHERE username = $1;
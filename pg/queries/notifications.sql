@@ -0,0 +1,12 @@
+-- name: InsertNotificationDelivery :one
+INSERT INTO notification_delivery (id, batch_id, kind, channel, recipient, error)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- Newest first: the question on the batch page is whether the last attempt
+-- worked.
+-- name: GetNotificationDeliveriesByBatchId :many
+SELECT *
+FROM notification_delivery
+WHERE batch_id = $1
+ORDER BY created DESC;
@@ -1,7 +1,15 @@
+-- approvals_required is decided here rather than by the caller, from the same
+-- expression the recount and the approval use, so there is one definition of
+-- "large" and the fund's own threshold always beats the site's.
 -- name: InsertBatchPayout :one
 INSERT INTO batch_payout (id, fund_id, amount_cents, num_enrollments, status, description, notes,
-                          payout_date, sender_batch_id, approval_deadline)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+                          payout_date, sender_batch_id, approval_deadline, approvals_required)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+        CASE
+           WHEN $3::int > COALESCE((SELECT f.second_approval_above_cents FROM fund f WHERE f.id = $2),
+                                    sqlc.narg('default_second_approval_above_cents')::int) THEN 2
+           ELSE 1
+    END)
 RETURNING *;
 
 -- name: InsertPayout :one
@@ -27,11 +35,26 @@ FROM batch_payout
 WHERE status = $1
 ORDER BY payout_date;
 
+-- The name of whoever struck a payout comes with it, so the batch page can say
+-- who took it out without a lookup per row. LEFT, because most payouts were
+-- never struck.
 -- name: GetPayoutsByBatchId :many
+SELECT sqlc.embed(payout),
+       struck.bco_name AS struck_by_name
+FROM payout
+         LEFT JOIN member struck ON struck.id = payout.struck_by
+WHERE payout.batch_id = $1
+ORDER BY payout.created;
+
+-- name: GetPayoutById :one
+SELECT *
+FROM payout
+WHERE id = $1;
+
+-- name: GetPayoutByProviderItemId :one
 SELECT *
 FROM payout
-WHERE batch_id = $1
-ORDER BY created;
+WHERE provider_payout_item_id = $1;
 
 -- Approves only from 'awaiting_approval'. The status predicate makes this a
 -- compare-and-set: a batch already cancelled by the expiry sweep, or already
@@ -87,11 +110,13 @@ WHERE id = $1
 RETURNING *;
 
 -- Records the provider's batch ID and moves the batch to 'pending'. Constrained to
--- 'ready' so a submission can only ever happen once per approved batch.
+-- 'ready' so a submission can only ever happen once per approved batch. Clears
+-- the reason a refused attempt left, which no longer applies once it is taken.
 -- name: SetBatchPayoutSubmitted :one
 UPDATE batch_payout
 SET provider_batch_id = $2,
     status            = 'pending',
+    failure_reason    = NULL,
     updated           = now()
 WHERE id = $1
   AND status = 'ready'
@@ -168,7 +193,7 @@ WHERE id = $1;
 -- instead of silently dropping a period. 'once' funds are advanced to NULL after
 -- planning, which is what keeps them from being picked up forever.
 -- name: GetFundsDueForPayout :many
-SELECT id, name, payout_frequency, next_payment
+SELECT id, name, payout_frequency, next_payment, payout_allocation, payout_cap_cents
 FROM fund
 WHERE active = true
   AND next_payment IS NOT NULL
@@ -202,7 +227,21 @@ ORDER BY next_payment;
 --
 -- A refunded payment keeps its fee subtracted: PayPal retains the fee on a
 -- refund, so the money is gone whether or not the donation stayed.
-SELECT (COALESCE((SELECT SUM(dp.amount_cents - dp.refunded_cents - dp.provider_fee_cents)
+--
+-- Disputed money is held out while the dispute is open, and stays out if it is
+-- lost. PayPal takes it from the account the moment a dispute opens, so a fund
+-- counting it until the resolution was planning payouts from money that was not
+-- there and, half the time, never would be again. A won dispute gives it back.
+--
+-- The larger of the two, not their sum: a lost chargeback is often followed by
+-- PAYMENT.SALE.REVERSED for the same money, and subtracting both would take it
+-- out twice.
+SELECT (COALESCE((SELECT SUM(dp.amount_cents
+                                 - GREATEST(dp.refunded_cents,
+                                            CASE
+                                                WHEN dp.dispute_state IN ('open', 'lost') THEN dp.disputed_cents
+                                                ELSE 0 END)
+                                 - dp.provider_fee_cents)
                   FROM donation
                            JOIN donation_payment dp ON donation.id = dp.donation_id
                   WHERE donation.fund_id = $1), 0)
@@ -218,7 +257,9 @@ SELECT (COALESCE((SELECT SUM(dp.amount_cents - dp.refunded_cents - dp.provider_f
                   -- of every batch that came to nothing, permanently, and the
                   -- money could never be paid out by a later one.
                   AND batch_payout.status <> 'cancelled'
-                  AND payout.status NOT IN ('failed', 'cancelled', 'returned')), 0))::bigint
+                  -- A struck payout was taken out before approval and will
+                  -- never be sent, so its money is free again too.
+                  AND payout.status NOT IN ('failed', 'cancelled', 'returned', 'struck')), 0))::bigint
            AS available_cents;
 
 -- Moves the fund to its next scheduled payout, anchored on the existing date
@@ -236,6 +277,11 @@ SELECT (COALESCE((SELECT SUM(dp.amount_cents - dp.refunded_cents - dp.provider_f
 -- every month has a tomorrow -- but it does need a wider search, since 60 of
 -- anything is two months of days. Ten years of them keeps the bound honest
 -- against a test fund left running.
+--
+-- 'weekly' and 'biweekly' step by seven and fourteen days, so a fund paying on
+-- a Friday keeps paying on a Friday; 'quarterly' steps by three months and
+-- clamps the way monthly does. The same rules as Go's Fund.NextPaymentAfter,
+-- and the same bound of 60 periods.
 -- name: AdvanceFundNextPayment :one
 UPDATE fund
 SET next_payment = CASE
@@ -243,6 +289,15 @@ SET next_payment = CASE
                        WHEN payout_frequency = 'daily' THEN next_payment + (interval '1 day' * (SELECT MIN(n)
                                                                                                 FROM generate_series(1, 3650) n
                                                                                                 WHERE next_payment + (interval '1 day' * n) > now()))
+                       WHEN payout_frequency = 'weekly' THEN next_payment + (interval '7 days' * (SELECT MIN(n)
+                                                                                                  FROM generate_series(1, 60) n
+                                                                                                  WHERE next_payment + (interval '7 days' * n) > now()))
+                       WHEN payout_frequency = 'biweekly' THEN next_payment + (interval '14 days' * (SELECT MIN(n)
+                                                                                                     FROM generate_series(1, 60) n
+                                                                                                     WHERE next_payment + (interval '14 days' * n) > now()))
+                       WHEN payout_frequency = 'quarterly' THEN next_payment + (interval '3 months' * (SELECT MIN(n)
+                                                                                                       FROM generate_series(1, 60) n
+                                                                                                       WHERE next_payment + (interval '3 months' * n) > now()))
                        ELSE next_payment + (interval '1 month' * (SELECT MIN(n)
                                                                   FROM generate_series(1, 60) n
                                                                   WHERE next_payment + (interval '1 month' * n) > now()))
@@ -268,6 +323,9 @@ RETURNING *;
 --
 -- LEFT JOIN throughout, so a batch with no payouts recorded yet still lists,
 -- rather than disappearing from the page that exists to approve it.
+--
+-- Struck payouts are left out of the join: "who is being paid" does not include
+-- somebody a treasurer has already taken out.
 -- name: GetDetailedBatchPayoutsByStatus :many
 SELECT bp.id,
        bp.fund_id,
@@ -284,6 +342,8 @@ SELECT bp.id,
        bp.approved_by,
        bp.approved_at,
        bp.reminder_sent_at,
+       bp.approvals_required,
+       bp.approvals_given,
        bp.created,
        bp.updated,
        f.name                                                          AS fund_name,
@@ -307,13 +367,56 @@ SELECT bp.id,
        )::uuid[]                                                       AS payee_ids
 FROM batch_payout bp
          JOIN fund f ON f.id = bp.fund_id
-         LEFT JOIN payout p ON p.batch_id = bp.id
+         LEFT JOIN payout p ON p.batch_id = bp.id AND p.status <> 'struck'
          LEFT JOIN fund_enrollment fe ON fe.id = p.fund_enrollment_id
          LEFT JOIN member m ON m.id = fe.member_id
 WHERE bp.status = $1
 GROUP BY bp.id, f.name
 ORDER BY bp.payout_date;
 
+-- One batch, read the same way. What a message about a batch needs to say:
+-- whose money, and to whom.
+-- name: GetDetailedBatchPayoutById :one
+SELECT bp.id,
+       bp.fund_id,
+       bp.amount_cents,
+       bp.num_enrollments,
+       bp.status,
+       bp.failure_reason,
+       bp.notes,
+       bp.description,
+       bp.provider_batch_id,
+       bp.payout_date,
+       bp.sender_batch_id,
+       bp.approval_deadline,
+       bp.approved_by,
+       bp.approved_at,
+       bp.reminder_sent_at,
+       bp.approvals_required,
+       bp.approvals_given,
+       bp.created,
+       bp.updated,
+       f.name                                                          AS fund_name,
+       COALESCE(
+               array_agg(COALESCE(m.bco_name, fe.member_bco_name)
+                         ORDER BY COALESCE(m.bco_name, fe.member_bco_name))
+               FILTER (WHERE COALESCE(m.bco_name, fe.member_bco_name) IS NOT NULL),
+               '{}'
+       )::text[]                                                       AS payee_names,
+       COALESCE(
+               array_agg(COALESCE(m.id, '00000000-0000-0000-0000-000000000000'::uuid)
+                         ORDER BY COALESCE(m.bco_name, fe.member_bco_name))
+               FILTER (WHERE COALESCE(m.bco_name, fe.member_bco_name) IS NOT NULL),
+               '{}'
+       )::uuid[]                                                       AS payee_ids
+FROM batch_payout bp
+         JOIN fund f ON f.id = bp.fund_id
+         LEFT JOIN payout p ON p.batch_id = bp.id AND p.status <> 'struck'
+         LEFT JOIN fund_enrollment fe ON fe.id = p.fund_enrollment_id
+         LEFT JOIN member m ON m.id = fe.member_id
+WHERE bp.id = $1
+GROUP BY bp.id, f.name;
+
 -- Enrollments named by a batch that has been planned but not yet sent.
 --
 -- Removing a member sets fund_enrollment.active = false, and every later plan
@@ -330,7 +433,8 @@ SELECT DISTINCT payout.fund_enrollment_id
 FROM payout
          JOIN batch_payout ON batch_payout.id = payout.batch_id
 WHERE batch_payout.fund_id = $1
-  AND batch_payout.status IN ('awaiting_approval', 'ready');
+  AND batch_payout.status IN ('awaiting_approval', 'ready')
+  AND payout.status <> 'struck';
 
 -- Puts a one-off fund's payout back on the schedule after its batch came to
 -- nothing.
@@ -356,3 +460,128 @@ WHERE id = $1
   AND payout_frequency = 'once'
   AND next_payment IS NULL
   AND expires IS NOT NULL;
+
+-- How the fund divides its payouts. Returns what was stored so the caller shows
+-- the setting as it now is, not as it was asked for.
+-- name: SetFundPayoutAllocation :one
+UPDATE fund
+SET payout_allocation = $2,
+    payout_cap_cents  = $3,
+    updated           = now()
+WHERE id = $1
+RETURNING id, name, payout_allocation, payout_cap_cents;
+
+-- name: SetEnrollmentPayoutShare :one
+UPDATE fund_enrollment
+SET payout_amount_cents = $2,
+    payout_weight       = $3,
+    updated             = now()
+WHERE id = $1
+RETURNING *;
+
+-- Holds a batch still awaiting approval while payouts are struck from it. The
+-- row lock is what keeps an approval from landing between the strike and the
+-- recount: approved, the batch would be cleared for the old total.
+-- name: LockBatchPayoutAwaitingApproval :one
+SELECT *
+FROM batch_payout
+WHERE id = $1
+  AND status = 'awaiting_approval'
+FOR UPDATE;
+
+-- Bounded by batch so a payout id from another batch strikes nothing, and by
+-- status so striking twice does not overwrite who did it first.
+-- name: StrikePayout :one
+UPDATE payout
+SET status         = 'struck',
+    failure_reason = $3,
+    struck_by      = $4,
+    struck_at      = now(),
+    updated        = now()
+WHERE id = $1
+  AND batch_id = $2
+  AND status <> 'struck'
+RETURNING *;
+
+-- Recomputed from what is left rather than decremented, so the header cannot
+-- drift from its rows whatever happened before.
+-- name: RecountBatchPayout :one
+UPDATE batch_payout
+SET amount_cents    = (SELECT COALESCE(SUM(p.amount_cents), 0)
+                       FROM payout p
+                       WHERE p.batch_id = batch_payout.id
+                         AND p.status <> 'struck')::int,
+    num_enrollments = (SELECT COUNT(*)
+                       FROM payout p
+                       WHERE p.batch_id = batch_payout.id
+                         AND p.status <> 'struck')::int,
+    updated         = now()
+WHERE batch_payout.id = $1
+RETURNING *;
+
+-- After the recount, because the threshold compares against the new total.
+-- name: SetBatchApprovalsRequired :one
+UPDATE batch_payout
+SET approvals_required = CASE
+           WHEN batch_payout.amount_cents > COALESCE((SELECT f.second_approval_above_cents FROM fund f WHERE f.id = batch_payout.fund_id),
+                                    sqlc.narg('default_second_approval_above_cents')::int) THEN 2
+           ELSE 1
+    END,
+    updated            = now()
+WHERE batch_payout.id = $1
+RETURNING *;
+
+-- name: GetEnrollmentMemberId :one
+SELECT member_id
+FROM fund_enrollment
+WHERE id = $1;
+
+-- Whether a member has ever been enrolled in a fund, active or not. An admin
+-- who is or was paid by a fund does not approve its payouts: the rule is about
+-- interest, and a cancelled enrollment can be re-activated.
+-- name: IsMemberEnrolledInFund :one
+SELECT EXISTS (SELECT 1
+               FROM fund_enrollment
+               WHERE fund_id = $1
+                 AND member_id = $2) AS enrolled;
+
+-- No row back when this admin has already approved the batch.
+-- name: InsertBatchApproval :one
+INSERT INTO batch_approval (id, batch_id, approver_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (batch_id, approver_id) DO NOTHING
+RETURNING *;
+
+-- Counts the approval and re-decides how many are needed, in case the fund's
+-- threshold or the site's changed since the batch was planned.
+-- name: CountBatchApproval :one
+UPDATE batch_payout
+SET approvals_given    = approvals_given + 1,
+    approvals_required = CASE
+           WHEN batch_payout.amount_cents > COALESCE((SELECT f.second_approval_above_cents FROM fund f WHERE f.id = batch_payout.fund_id),
+                                    sqlc.narg('default_second_approval_above_cents')::int) THEN 2
+           ELSE 1
+    END,
+    updated            = now()
+WHERE batch_payout.id = $1
+RETURNING *;
+
+-- Who approved a batch, in order, named the way the rest of the page names
+-- people.
+-- name: GetBatchApprovals :many
+SELECT ba.id,
+       ba.batch_id,
+       ba.approver_id,
+       m.bco_name AS approver_name,
+       ba.created
+FROM batch_approval ba
+         JOIN member m ON m.id = ba.approver_id
+WHERE ba.batch_id = $1
+ORDER BY ba.created;
+
+-- name: SetFundSecondApprovalAbove :one
+UPDATE fund
+SET second_approval_above_cents = $2,
+    updated                     = now()
+WHERE id = $1
+RETURNING id, second_approval_above_cents;
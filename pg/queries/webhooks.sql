@@ -7,3 +7,65 @@ INSERT INTO webhook_delivery (transmission_id, event_type)
 VALUES ($1, $2)
 ON CONFLICT (transmission_id) DO NOTHING
 RETURNING *;
+
+-- Keeps what a newly accepted webhook carried. Called only for a transmission
+-- RecordWebhookDelivery has just reported new, in the same transaction, so there
+-- is no conflict to handle: a replay never gets this far.
+-- name: ArchiveWebhookEvent :exec
+INSERT INTO webhook_event (transmission_id, event_id, event_type, resource_type, resource_id, summary, payload,
+                           cert_url, certificate_chained, chain_error, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, sqlc.narg(chain_error)::text, sqlc.narg(created_at)::timestamptz);
+
+-- One attempt by one consumer. DO NOTHING, because a report is made after the
+-- handler runs and before the ack, and a lost ack can make it twice.
+--
+-- Only for an archived event. Messages published before the archive existed
+-- are still in the stream, and a dead letter from then can still be replayed;
+-- their attempts have nothing to be filed against and are left out rather than
+-- failing the foreign key.
+-- name: RecordWebhookOutcome :exec
+INSERT INTO webhook_event_outcome (id, transmission_id, consumer, stream_seq, delivery, outcome, error)
+SELECT $1, $2, $3, $4, $5, $6, sqlc.narg(error)::text
+WHERE EXISTS (SELECT 1 FROM webhook_event WHERE transmission_id = $2)
+ON CONFLICT (transmission_id, consumer, stream_seq, delivery) DO NOTHING;
+
+-- The admin viewer's list. Each filter is skipped when null, and the counts are
+-- of attempts, so an event that failed twice and then went through reads as
+-- such without opening it.
+-- name: SearchWebhookEvents :many
+SELECT e.transmission_id,
+       e.event_type,
+       e.resource_type,
+       e.resource_id,
+       e.summary,
+       e.certificate_chained,
+       e.received_at,
+       (SELECT count(*) FROM webhook_event_outcome o
+        WHERE o.transmission_id = e.transmission_id AND o.outcome = 'handled')::int AS handled,
+       (SELECT count(*) FROM webhook_event_outcome o
+        WHERE o.transmission_id = e.transmission_id AND o.outcome <> 'handled')::int AS failed
+FROM webhook_event e
+WHERE (sqlc.narg(event_type)::text IS NULL OR e.event_type = sqlc.narg(event_type)::text)
+  AND (sqlc.narg(resource_id)::text IS NULL OR e.resource_id = sqlc.narg(resource_id)::text)
+  AND (sqlc.narg(received_from)::timestamptz IS NULL OR e.received_at >= sqlc.narg(received_from)::timestamptz)
+  AND (sqlc.narg(received_until)::timestamptz IS NULL OR e.received_at < sqlc.narg(received_until)::timestamptz)
+ORDER BY e.received_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetWebhookEvent :one
+SELECT *
+FROM webhook_event
+WHERE transmission_id = $1;
+
+-- name: GetWebhookEventOutcomes :many
+SELECT *
+FROM webhook_event_outcome
+WHERE transmission_id = $1
+ORDER BY occurred_at, delivery;
+
+-- What the type filter offers: every type that has been archived, rather than a
+-- list kept in code that falls behind the first time PayPal sends something new.
+-- name: GetWebhookEventTypes :many
+SELECT DISTINCT event_type
+FROM webhook_event
+ORDER BY event_type;
@@ -0,0 +1,94 @@
+// Package providers names the payment providers a fund can be held at and keeps
+// the implementations a service reaches them through.
+//
+// A fund has always carried provider_name beside provider_id, and until now the
+// only value ever written was "paypal": every service held one paypal.Paypal and
+// called it whatever the fund said. The registry is what makes the column mean
+// something. A service asks it for the provider a fund, a plan or a donation
+// names, and gets an error rather than PayPal when it names one that is not
+// configured.
+package providers
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The names stored in provider_name. They are data, so they do not change: a
+// donation recorded under one of these must still find its provider years later.
+const (
+	PayPal = "paypal"
+	Stripe = "stripe"
+)
+
+// ErrUnknown means a row names a provider this process was not configured with.
+var ErrUnknown = errors.New("payment provider is not configured")
+
+// Registry is the set of providers one service can reach, by name.
+//
+// Generic because each service wants a different slice of a provider: donations
+// create plans and checkouts, members only cancel, finance only reads
+// transactions. Each keeps its own narrow interface, and its own registry of it.
+//
+// One provider is primary. It is where a new fund is created, and what an empty
+// name means: rows written before provider_name was set on them carry the
+// default, but a value built in code may not, and it was PayPal for all of them.
+type Registry[P any] struct {
+	primary string
+	byName  map[string]P
+	order   []string
+}
+
+func NewRegistry[P any](primary string, provider P) *Registry[P] {
+	return &Registry[P]{
+		primary: primary,
+		byName:  map[string]P{primary: provider},
+		order:   []string{primary},
+	}
+}
+
+// Register adds a provider, or replaces the one already under name. It is meant
+// for start-up, before anything reads the registry: it is not safe to call while
+// requests are being served.
+func (r *Registry[P]) Register(name string, provider P) {
+	if _, ok := r.byName[name]; !ok {
+		r.order = append(r.order, name)
+	}
+
+	r.byName[name] = provider
+}
+
+// For is the provider stored under name. An empty name is the primary.
+func (r *Registry[P]) For(name string) (P, error) {
+	if name == "" {
+		name = r.primary
+	}
+
+	provider, ok := r.byName[name]
+	if !ok {
+		var zero P
+
+		return zero, fmt.Errorf("%w: %q", ErrUnknown, name)
+	}
+
+	return provider, nil
+}
+
+// Primary is the name new funds are created under.
+func (r *Registry[P]) Primary() string {
+	return r.primary
+}
+
+// Names lists the configured providers, primary first and then in the order
+// they were registered, so a page that offers a choice offers it the same way
+// every time.
+func (r *Registry[P]) Names() []string {
+	return append([]string(nil), r.order...)
+}
+
+// Has reports whether name is configured.
+func (r *Registry[P]) Has(name string) bool {
+	_, ok := r.byName[name]
+
+	return ok
+}
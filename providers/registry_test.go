@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"errors"
+	"testing"
+)
+
+// An empty name is the primary, because rows and values from before there was
+// a choice carry none. An unknown name is an error, never the primary: sending
+// Stripe's subscription id to PayPal would fail at best.
+func TestANameFindsItsProviderAndOnlyThatOne(t *testing.T) {
+	registry := NewRegistry(PayPal, "paypal client")
+	registry.Register(Stripe, "stripe client")
+
+	for name, want := range map[string]string{"": "paypal client", PayPal: "paypal client", Stripe: "stripe client"} {
+		got, err := registry.For(name)
+		if err != nil || got != want {
+			t.Errorf("For(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := registry.For("square"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("an unconfigured provider should be ErrUnknown, got %v", err)
+	}
+}
+
+func TestNamesArePrimaryFirstAndRegisteredOnce(t *testing.T) {
+	registry := NewRegistry(PayPal, 1)
+	registry.Register(Stripe, 2)
+	registry.Register(Stripe, 3)
+
+	names := registry.Names()
+	if len(names) != 2 || names[0] != PayPal || names[1] != Stripe {
+		t.Fatalf("Names() = %v", names)
+	}
+
+	// A copy, so a caller cannot reorder the registry.
+	names[0] = "changed"
+	if registry.Names()[0] != PayPal {
+		t.Error("Names should not hand out the registry's own slice")
+	}
+
+	if got, _ := registry.For(Stripe); got != 3 {
+		t.Errorf("re-registering should replace, got %d", got)
+	}
+}
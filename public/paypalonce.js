@@ -24,7 +24,20 @@ window.paypal_once.Buttons({
         return data.orderId
     },
     onApprove: async function(data, actions) {
-        let capture = await actions.order.capture()
+        let capture
+        try {
+            capture = await actions.order.capture()
+        } catch (err) {
+            // A declined card. PayPal's buttons can start over with the same
+            // order, so the donor picks another way to pay instead of ending on
+            // an error for something they can fix.
+            if (String(err).includes('INSTRUMENT_DECLINED')) {
+                return actions.restart()
+            }
+
+            throw err
+        }
+
         let paymentId = capture.purchase_units[0].payments.captures[0].id
 
         let response = await fetch('/donation/once/complete', {
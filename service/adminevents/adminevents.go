@@ -87,6 +87,10 @@ func logAttrs(record Record) []any {
 		attrs = append(attrs, slog.String("detail", record.Detail))
 	}
 
+	if record.Role != "" {
+		attrs = append(attrs, slog.String("role", record.Role))
+	}
+
 	return attrs
 }
 
@@ -65,6 +65,7 @@ func (s EventStore) InsertAdminEvent(ctx context.Context, arg adminevents.Record
 		SubjectMemberID: nullUUID(arg.SubjectMemberID),
 		SubjectLabel:    text(arg.SubjectLabel),
 		Detail:          text(arg.Detail),
+		Role:            text(arg.Role),
 	})
 	if err != nil {
 		return nil, err
@@ -100,6 +101,7 @@ func fromDB(e db.AdminEvent, actorName, subjectName string) adminevents.Event {
 		SubjectName:     subjectName,
 		SubjectLabel:    e.SubjectLabel.String,
 		Detail:          e.Detail.String,
+		Role:            e.Role.String,
 		Created:         e.Created.Time,
 	}
 }
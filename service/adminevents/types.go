@@ -4,9 +4,13 @@
 // access is granted by Cognito group membership and by nothing else, which makes
 // the group the entire authorisation model -- and a model with no record of who
 // changed it cannot answer the one question asked after an incident.
+//
+// It also holds the few other things an admin does that touch money without
+// touching one fund, such as replaying a dead-lettered webhook.
 package adminevents
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +24,32 @@ const (
 
 	KindEmailApproved        Kind = "email_approved"
 	KindEmailApprovalRemoved Kind = "email_approval_removed"
+
+	KindWebhookReplayed Kind = "webhook_replayed"
+
+	KindPaymentAssigned Kind = "payment_assigned"
+
+	KindPasswordResetRequested Kind = "password_reset_requested"
+	KindPasswordResetCompleted Kind = "password_reset_completed"
+
+	KindTwoFactorEnabled  Kind = "two_factor_enabled"
+	KindTwoFactorDisabled Kind = "two_factor_disabled"
+	KindRecoveryCodeUsed  Kind = "recovery_code_used"
+
+	// KindRoleGranted and KindRoleRevoked are any role, with Record.Role saying
+	// which. KindAdminGranted and KindAdminRevoked are from before there was more
+	// than one, and are still what older rows say.
+	KindRoleGranted Kind = "role_granted"
+	KindRoleRevoked Kind = "role_revoked"
+
+	KindFundManagerAssigned Kind = "fund_manager_assigned"
+	KindFundManagerRemoved  Kind = "fund_manager_removed"
+
+	// KindInviteCreated and KindInviteRevoked have the invite's address as their
+	// subject, or say it was open; KindInviteRedeemed has the member it made.
+	KindInviteCreated  Kind = "invite_created"
+	KindInviteRevoked  Kind = "invite_revoked"
+	KindInviteRedeemed Kind = "invite_redeemed"
 )
 
 // Record is one privilege change.
@@ -56,6 +86,38 @@ type Record struct {
 
 	// Detail is free text for the reader, such as how the change was made.
 	Detail string
+
+	// Role is the name of the role granted or revoked, for the kinds that are
+	// about one.
+	Role string
+}
+
+// WebhookReplayed is the record of a dead letter sent back to its consumer.
+//
+// The subject is the letter, by event type and sequence, because nobody's
+// access changed. actor is nil from the command line, which has no member to
+// name; via says which of the two it was.
+func WebhookReplayed(actor *uuid.UUID, event string, seq uint64, via string) Record {
+	return Record{
+		Kind:          KindWebhookReplayed,
+		ActorMemberID: actor,
+		SubjectLabel:  fmt.Sprintf("%s, dead letter %d", event, seq),
+		Detail:        via,
+	}
+}
+
+// PaymentAssigned is the record of an admin saying whose an unmatched payment
+// was. The subject is the donor it was credited to; the detail says which
+// payment, how much, and to which fund, since the fund's own feed is not where
+// anybody looking at this log will go to find out.
+func PaymentAssigned(actor, donor uuid.UUID, providerPaymentID string, amountCents int32, fundName string) Record {
+	return Record{
+		Kind:            KindPaymentAssigned,
+		ActorMemberID:   &actor,
+		SubjectMemberID: &donor,
+		Detail: fmt.Sprintf("%s for $%d.%02d to %s", providerPaymentID,
+			amountCents/100, amountCents%100, fundName),
+	}
 }
 
 // Event is a recorded Record, with the names resolved.
@@ -69,6 +131,7 @@ type Event struct {
 	SubjectName     string
 	SubjectLabel    string
 	Detail          string
+	Role            string
 	Created         time.Time
 }
 
@@ -91,7 +154,9 @@ func (e Event) AboutAMember() bool {
 // Granted reports whether this event handed access out rather than taking it
 // away.
 func (e Event) Granted() bool {
-	return e.Kind == KindAdminGranted || e.Kind == KindEmailApproved
+	return e.Kind == KindAdminGranted || e.Kind == KindEmailApproved ||
+		e.Kind == KindRoleGranted || e.Kind == KindFundManagerAssigned ||
+		e.Kind == KindInviteCreated
 }
 
 // ByProvider reports whether this happened without a person the app knows about
@@ -109,3 +174,22 @@ func (e Event) SelfInflicted() bool {
 		e.SubjectMemberID != nil &&
 		*e.ActorMemberID == *e.SubjectMemberID
 }
+
+// StoredWebhooksReplayed is the record of a range of stored webhooks run back
+// through the handlers. Only a confirmed run is recorded; a dry run changes
+// nothing, and the log is of what was done.
+//
+// subject is empty when every event type in the range was replayed.
+func StoredWebhooksReplayed(actor *uuid.UUID, subject string, since, until time.Time, changes int) Record {
+	if subject == "" {
+		subject = "every event"
+	}
+
+	return Record{
+		Kind:          KindWebhookReplayed,
+		ActorMemberID: actor,
+		SubjectLabel: fmt.Sprintf("%s stored %s to %s", subject,
+			since.UTC().Format(time.RFC3339), until.UTC().Format(time.RFC3339)),
+		Detail: fmt.Sprintf("from the command line, %d change(s)", changes),
+	}
+}
@@ -31,6 +31,10 @@ func (f *fakeAuthorizer) SetPassword(context.Context, string, string, string) er
 	return nil
 }
 
+func (f *fakeAuthorizer) AdminSetPassword(context.Context, string, string) error {
+	return nil
+}
+
 func (f *fakeAuthorizer) CreateUser(context.Context, string, string, uuid.UUID) (string, error) {
 	return "", nil
 }
@@ -93,48 +97,60 @@ func newAuditedTestService(f *fakeAuthorizer, log *recorder) AuthService {
 	}
 }
 
-func TestGrantAndRevokeAdminUseTheCognitoGroup(t *testing.T) {
+func adminRole() jwtauth.Role {
+	role, _ := jwtauth.RoleByGroup(jwtauth.AdminGroup)
+
+	return role
+}
+
+func treasurerRole() jwtauth.Role {
+	role, _ := jwtauth.RoleByGroup(jwtauth.TreasurerGroup)
+
+	return role
+}
+
+func TestGrantAndRevokeRoleUseItsCognitoGroup(t *testing.T) {
 	fake := &fakeAuthorizer{groups: map[string][]string{"michael": {"some-other-group"}}}
 	svc := newTestService(fake)
 
 	ctx := context.Background()
 	actor, subject := testMember("gofreescout"), testMember("michael")
 
-	if err := svc.GrantAdmin(ctx, actor, subject); err != nil {
+	if err := svc.GrantRole(ctx, actor, subject, treasurerRole()); err != nil {
 		t.Fatalf("grant: %v", err)
 	}
 
 	// The group name is the entire authorisation decision. Writing any other
-	// group would silently grant nothing.
-	if len(fake.added) != 1 || fake.added[0] != "michael:"+jwtauth.AdminGroup {
-		t.Fatalf("added %v, want one write of michael:%s", fake.added, jwtauth.AdminGroup)
+	// group would silently grant nothing, or the wrong thing.
+	if len(fake.added) != 1 || fake.added[0] != "michael:"+jwtauth.TreasurerGroup {
+		t.Fatalf("added %v, want one write of michael:%s", fake.added, jwtauth.TreasurerGroup)
 	}
 
-	isAdmin, err := svc.IsAdmin(ctx, "michael")
+	roles, err := svc.Roles(ctx, "michael")
 	if err != nil {
-		t.Fatalf("is admin: %v", err)
+		t.Fatalf("roles: %v", err)
 	}
-	if !isAdmin {
-		t.Error("member should be an admin after being granted")
+	if len(roles) != 1 || roles[0].Group != jwtauth.TreasurerGroup {
+		t.Errorf("roles = %v, want only treasurer", roles)
 	}
 
-	if err := svc.RevokeAdmin(ctx, actor, subject); err != nil {
+	if err := svc.RevokeRole(ctx, actor, subject, treasurerRole()); err != nil {
 		t.Fatalf("revoke: %v", err)
 	}
 
-	if len(fake.removed) != 1 || fake.removed[0] != "michael:"+jwtauth.AdminGroup {
-		t.Fatalf("removed %v, want one write of michael:%s", fake.removed, jwtauth.AdminGroup)
+	if len(fake.removed) != 1 || fake.removed[0] != "michael:"+jwtauth.TreasurerGroup {
+		t.Fatalf("removed %v, want one write of michael:%s", fake.removed, jwtauth.TreasurerGroup)
 	}
 
-	isAdmin, err = svc.IsAdmin(ctx, "michael")
+	roles, err = svc.Roles(ctx, "michael")
 	if err != nil {
-		t.Fatalf("is admin: %v", err)
+		t.Fatalf("roles: %v", err)
 	}
-	if isAdmin {
-		t.Error("member should not be an admin after revocation")
+	if len(roles) != 0 {
+		t.Errorf("roles = %v, want none after revocation", roles)
 	}
 
-	// Revoking admin must not disturb the member's other groups.
+	// Revoking a role must not disturb the member's other groups.
 	if got := fake.groups["michael"]; len(got) != 1 || got[0] != "some-other-group" {
 		t.Errorf("other groups = %v, want [some-other-group]", got)
 	}
@@ -144,7 +160,7 @@ func testMember(name string) members.Member {
 	return members.Member{ID: uuid.New(), BCOName: name}
 }
 
-func TestAdminChangesAreRecordedWithBothParties(t *testing.T) {
+func TestRoleChangesAreRecordedWithBothPartiesAndTheRole(t *testing.T) {
 	fake := &fakeAuthorizer{groups: map[string][]string{"michael": nil}}
 	log := &recorder{}
 	svc := newAuditedTestService(fake, log)
@@ -152,10 +168,10 @@ func TestAdminChangesAreRecordedWithBothParties(t *testing.T) {
 	ctx := context.Background()
 	actor, subject := testMember("gofreescout"), testMember("michael")
 
-	if err := svc.GrantAdmin(ctx, actor, subject); err != nil {
+	if err := svc.GrantRole(ctx, actor, subject, adminRole()); err != nil {
 		t.Fatalf("grant: %v", err)
 	}
-	if err := svc.RevokeAdmin(ctx, actor, subject); err != nil {
+	if err := svc.RevokeRole(ctx, actor, subject, treasurerRole()); err != nil {
 		t.Fatalf("revoke: %v", err)
 	}
 
@@ -165,11 +181,17 @@ func TestAdminChangesAreRecordedWithBothParties(t *testing.T) {
 
 	granted, revoked := log.records[0], log.records[1]
 
-	if granted.Kind != adminevents.KindAdminGranted {
-		t.Errorf("first event is %q, want %q", granted.Kind, adminevents.KindAdminGranted)
+	if granted.Kind != adminevents.KindRoleGranted {
+		t.Errorf("first event is %q, want %q", granted.Kind, adminevents.KindRoleGranted)
 	}
-	if revoked.Kind != adminevents.KindAdminRevoked {
-		t.Errorf("second event is %q, want %q", revoked.Kind, adminevents.KindAdminRevoked)
+	if revoked.Kind != adminevents.KindRoleRevoked {
+		t.Errorf("second event is %q, want %q", revoked.Kind, adminevents.KindRoleRevoked)
+	}
+
+	// With more than one role, "access changed" is no longer an answer: the line
+	// has to say which.
+	if granted.Role != "admin" || revoked.Role != "treasurer" {
+		t.Errorf("roles recorded %q and %q, want admin and treasurer", granted.Role, revoked.Role)
 	}
 
 	// The point of the log is attribution. An event naming only the subject
@@ -199,10 +221,10 @@ func TestAFailedGroupWriteIsNotRecorded(t *testing.T) {
 	// Both must fail; recording either would be the audit trail asserting a
 	// privilege change that never reached Cognito. A log that invents grants is
 	// worse than no log, because it will be believed.
-	if err := svc.GrantAdmin(ctx, actor, subject); err == nil {
+	if err := svc.GrantRole(ctx, actor, subject, adminRole()); err == nil {
 		t.Fatal("a failed group write should return an error")
 	}
-	if err := svc.RevokeAdmin(ctx, actor, subject); err == nil {
+	if err := svc.RevokeRole(ctx, actor, subject, adminRole()); err == nil {
 		t.Fatal("a failed group removal should return an error")
 	}
 
@@ -218,8 +240,8 @@ func TestAnUnattributedChangeRecordsNoActor(t *testing.T) {
 	// A zero-value actor is what a caller with no signed-in member holds. The
 	// nil uuid is a real value that would render as a member link to nothing, so
 	// it must become "not recorded" rather than travel into the log.
-	if err := newAuditedTestService(fake, log).GrantAdmin(
-		context.Background(), members.Member{}, testMember("michael"),
+	if err := newAuditedTestService(fake, log).GrantRole(
+		context.Background(), members.Member{}, testMember("michael"), adminRole(),
 	); err != nil {
 		t.Fatalf("grant: %v", err)
 	}
@@ -232,27 +254,27 @@ func TestAnUnattributedChangeRecordsNoActor(t *testing.T) {
 	}
 }
 
-func TestIsAdminIgnoresOtherGroups(t *testing.T) {
+func TestRolesIgnoreOtherGroups(t *testing.T) {
 	fake := &fakeAuthorizer{groups: map[string][]string{
-		"gofreescout": {"bco-admin", "admin", "bco-admin-group-x"},
+		"gofreescout": {"bco-admin", "admin", "bco-admin-group-x", jwtauth.ModeratorGroup},
 	}}
 
-	// Names that merely resemble the admin group must not grant admin.
-	isAdmin, err := newTestService(fake).IsAdmin(context.Background(), "gofreescout")
+	// Names that merely resemble a role's group must not grant it.
+	roles, err := newTestService(fake).Roles(context.Background(), "gofreescout")
 	if err != nil {
-		t.Fatalf("is admin: %v", err)
+		t.Fatalf("roles: %v", err)
 	}
-	if isAdmin {
-		t.Error("only an exact group name should grant admin")
+	if len(roles) != 1 || roles[0].Group != jwtauth.ModeratorGroup {
+		t.Errorf("roles = %v, want only moderator", roles)
 	}
 }
 
-func TestIsAdminSurfacesLookupFailure(t *testing.T) {
+func TestRolesSurfaceLookupFailure(t *testing.T) {
 	fake := &fakeAuthorizer{groups: map[string][]string{}, err: errors.New("cognito down")}
 
-	// A failed lookup must not read as "not an admin": the caller renders an
-	// unknown state from the error, and swallowing it would show a wrong answer.
-	if _, err := newTestService(fake).IsAdmin(context.Background(), "michael"); err == nil {
+	// A failed lookup must not read as "no roles": the caller renders an unknown
+	// state from the error, and swallowing it would show a wrong answer.
+	if _, err := newTestService(fake).Roles(context.Background(), "michael"); err == nil {
 		t.Fatal("a failed group lookup should return an error")
 	}
 }
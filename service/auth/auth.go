@@ -12,6 +12,8 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"log/slog"
+	"slices"
+	"time"
 )
 
 type memberStore interface {
@@ -25,6 +27,37 @@ type authStore interface {
 	MarkEmailAsUsed(ctx context.Context, email string) (*ApprovedEmail, error)
 	GetApprovedEmails(ctx context.Context) ([]ApprovedEmail, error)
 	DeleteApprovedEmail(ctx context.Context, email string) (*ApprovedEmail, error)
+
+	FindMemberForReset(ctx context.Context, email string) (*ResetSubject, error)
+	InsertPasswordReset(ctx context.Context, reset InsertPasswordReset) (*PasswordReset, error)
+	CountPasswordResetsByEmail(ctx context.Context, email string, since time.Time) (int, error)
+	CountPasswordResetsByIP(ctx context.Context, ip string, since time.Time) (int, error)
+	RedeemPasswordReset(ctx context.Context, tokenHash []byte) (*PasswordReset, error)
+	ReleasePasswordReset(ctx context.Context, id uuid.UUID) error
+	RetireOtherPasswordResets(ctx context.Context, memberID, keepID uuid.UUID) error
+
+	GetTOTP(ctx context.Context, memberID uuid.UUID) (*TOTP, error)
+	UpsertPendingTOTP(ctx context.Context, memberID uuid.UUID, secret string) (*TOTP, error)
+	ConfirmTOTP(ctx context.Context, memberID uuid.UUID, step int64, recoveryCodeHashes [][]byte) (*TOTP, error)
+	UseTOTPStep(ctx context.Context, memberID uuid.UUID, step int64) (*TOTP, error)
+	ClaimTOTPAttempt(ctx context.Context, memberID uuid.UUID, maxAttempts int, lockUntil, now time.Time) (*TOTP, error)
+	ClearTOTPAttempts(ctx context.Context, memberID uuid.UUID) error
+	UseRecoveryCode(ctx context.Context, memberID uuid.UUID, codeHash []byte) error
+	CountUnusedRecoveryCodes(ctx context.Context, memberID uuid.UUID) (int, error)
+	DeleteTOTP(ctx context.Context, memberID uuid.UUID) error
+
+	AssignFundManager(ctx context.Context, memberID, fundID uuid.UUID) (bool, error)
+	RemoveFundManager(ctx context.Context, memberID, fundID uuid.UUID) (bool, error)
+	IsFundManager(ctx context.Context, memberID, fundID uuid.UUID) (bool, error)
+	GetManagedFunds(ctx context.Context, memberID uuid.UUID) ([]ManagedFund, error)
+
+	InsertInvite(ctx context.Context, invite InsertInvite) (*Invite, error)
+	GetInvite(ctx context.Context, id uuid.UUID) (*Invite, error)
+	GetInvites(ctx context.Context, limit int) ([]Invite, error)
+	ClaimInvite(ctx context.Context, id uuid.UUID) (*Invite, error)
+	ReleaseInvite(ctx context.Context, id uuid.UUID) error
+	SetInviteMember(ctx context.Context, id, memberID uuid.UUID) error
+	RevokeInvite(ctx context.Context, id uuid.UUID) (*Invite, error)
 }
 
 // adminEventRecorder is the audit trail for privilege changes. Narrowed to the
@@ -36,18 +69,40 @@ type adminEventRecorder interface {
 type authorizer interface {
 	Authorize(ctx context.Context, user, pass string) (*AuthResponse, error)
 	SetPassword(ctx context.Context, user, old, new string) error
+	// AdminSetPassword sets a password without the old one, for a member who has
+	// proved who they are some other way: a reset link sent to their address.
+	AdminSetPassword(ctx context.Context, user, password string) error
 	CreateUser(ctx context.Context, username, email string, memberID uuid.UUID) (string, error)
 	AddToGroup(ctx context.Context, username, group string) error
 	RemoveFromGroup(ctx context.Context, username, group string) error
 	ListGroups(ctx context.Context, username string) ([]string, error)
 }
 
+// sessionRoles are the member roles Authenticate gives the session for each
+// group on the token.
+var sessionRoles = map[string]members.MemberRole{
+	jwtauth.AdminGroup:       members.AdminRole,
+	jwtauth.TreasurerGroup:   members.TreasurerRole,
+	jwtauth.ModeratorGroup:   members.ModeratorRole,
+	jwtauth.FundManagerGroup: members.FundManagerRole,
+}
+
 type AuthService struct {
 	memberStore memberStore
 	authStore   authStore
 	authorizer  authorizer
 	adminEvents adminEventRecorder
 
+	// resetSender and resetURL are set by SendPasswordResetsWith. Nil means
+	// forgotten-password resets are off.
+	resetSender resetSender
+	resetURL    string
+
+	// inviteKey and inviteURL are set by SignInvitesWith. Nil means invite links
+	// are off.
+	inviteKey []byte
+	inviteURL string
+
 	logger *slog.Logger
 }
 
@@ -88,52 +143,57 @@ func (s AuthService) Register(ctx context.Context, username, email string) (*mem
 	return member, nil
 }
 
-// GrantAdmin puts a member in the Cognito group that authorises the admin
-// section, which is the whole of what makes someone an admin -- see the comment
-// on jwtauth.AdminGroup. Nothing is written to member.roles: that column
-// authorises nothing, and Authenticate already derives the session's view of it
-// from the token, so a second copy could only ever disagree.
+// GrantRole puts a member in the Cognito group for role, which is the whole of
+// what gives them that role -- see the comment on jwtauth.AdminGroup. Nothing is
+// written to member.roles: that column authorises nothing, and Authenticate
+// already derives the session's view of it from the token, so a second copy
+// could only ever disagree.
 //
 // Cognito stamps group membership into the ID token at authentication, so the
-// member stays non-admin until they log in again. Callers should say so.
+// member keeps their old roles until they log in again. Callers should say so.
 //
 // It takes both members rather than the subject's username because the audit
 // record needs the actor, and a parameter that is easy to omit is one that gets
 // omitted. Cognito is addressed by username; the log is written in member ids.
-func (s AuthService) GrantAdmin(ctx context.Context, actor, subject members.Member) error {
-	if err := s.authorizer.AddToGroup(ctx, subject.BCOName, jwtauth.AdminGroup); err != nil {
-		s.logger.ErrorContext(ctx, "failed to grant admin",
+func (s AuthService) GrantRole(ctx context.Context, actor, subject members.Member, role jwtauth.Role) error {
+	if err := s.authorizer.AddToGroup(ctx, subject.BCOName, role.Group); err != nil {
+		s.logger.ErrorContext(ctx, "failed to grant role",
 			slog.String("username", subject.BCOName),
+			slog.String("role", role.Name),
 			slog.String("error", err.Error()),
 		)
 
 		return err
 	}
 
-	s.recordAdminChange(ctx, adminevents.KindAdminGranted, actor, subject)
+	s.recordRoleChange(ctx, adminevents.KindRoleGranted, actor, subject, role)
 
 	return nil
 }
 
-// RevokeAdmin is the inverse. It takes effect on the member's next login for the
+// RevokeRole is the inverse. It takes effect on the member's next login for the
 // same reason, but sooner in practice: their current token expires within the
 // hour, and nothing reissues one without a fresh authentication.
-func (s AuthService) RevokeAdmin(ctx context.Context, actor, subject members.Member) error {
-	if err := s.authorizer.RemoveFromGroup(ctx, subject.BCOName, jwtauth.AdminGroup); err != nil {
-		s.logger.ErrorContext(ctx, "failed to revoke admin",
+//
+// A fund manager's funds are left assigned. They grant nothing without the role,
+// and granting it back should not mean assigning them all again.
+func (s AuthService) RevokeRole(ctx context.Context, actor, subject members.Member, role jwtauth.Role) error {
+	if err := s.authorizer.RemoveFromGroup(ctx, subject.BCOName, role.Group); err != nil {
+		s.logger.ErrorContext(ctx, "failed to revoke role",
 			slog.String("username", subject.BCOName),
+			slog.String("role", role.Name),
 			slog.String("error", err.Error()),
 		)
 
 		return err
 	}
 
-	s.recordAdminChange(ctx, adminevents.KindAdminRevoked, actor, subject)
+	s.recordRoleChange(ctx, adminevents.KindRoleRevoked, actor, subject, role)
 
 	return nil
 }
 
-// recordAdminChange writes the audit line, after the group write has succeeded
+// recordRoleChange writes the audit line, after the group write has succeeded
 // and never before it: an event recorded ahead of the change it describes is a
 // claim that something happened when it may not have.
 //
@@ -143,17 +203,15 @@ func (s AuthService) RevokeAdmin(ctx context.Context, actor, subject members.Mem
 // copied. The error paths above still name the username, because that is the key
 // the failing Cognito call was made with and is what a person would need to
 // retry it by hand.
-func (s AuthService) recordAdminChange(ctx context.Context, kind adminevents.Kind, actor, subject members.Member) {
+func (s AuthService) recordRoleChange(ctx context.Context, kind adminevents.Kind, actor, subject members.Member, role jwtauth.Role) {
 	if s.adminEvents == nil {
 		return
 	}
 
-	// No Detail: every change made here is the same change to the same Cognito
-	// group, so a constant string in every row would be a column the reader
-	// learns to skip. It is left for the cases that differ.
 	record := adminevents.Record{
 		Kind:            kind,
 		SubjectMemberID: &subject.ID,
+		Role:            role.Name,
 	}
 
 	// A zero id means the caller had no signed-in member to attribute this to,
@@ -166,10 +224,11 @@ func (s AuthService) recordAdminChange(ctx context.Context, kind adminevents.Kin
 	s.adminEvents.Record(ctx, record)
 }
 
-// IsAdmin asks Cognito rather than the database. member.roles would be cheaper to
-// read and wrong: nothing writes ADMIN to it, so it reports every admin as an
-// ordinary member.
-func (s AuthService) IsAdmin(ctx context.Context, username string) (bool, error) {
+// Roles asks Cognito rather than the database which roles a member has.
+// member.roles would be cheaper to read and wrong: nothing writes ADMIN to it,
+// so it reports every admin as an ordinary member. Groups that are not a role
+// are left out.
+func (s AuthService) Roles(ctx context.Context, username string) ([]jwtauth.Role, error) {
 	groups, err := s.authorizer.ListGroups(ctx, username)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to list groups",
@@ -177,16 +236,17 @@ func (s AuthService) IsAdmin(ctx context.Context, username string) (bool, error)
 			slog.String("error", err.Error()),
 		)
 
-		return false, err
+		return nil, err
 	}
 
-	for _, group := range groups {
-		if group == jwtauth.AdminGroup {
-			return true, nil
+	var roles []jwtauth.Role
+	for _, role := range jwtauth.Roles {
+		if slices.Contains(groups, role.Group) {
+			roles = append(roles, role)
 		}
 	}
 
-	return false, nil
+	return roles, nil
 }
 
 func (s AuthService) ResetPassword(ctx context.Context, username, password, newPassword string) (*members.Member, *AuthResponse, error) {
@@ -337,15 +397,17 @@ func (s AuthService) Authenticate(ctx context.Context, username, password string
 		return nil, nil, err
 	}
 
-	// Admin routes are gated on the token's Cognito group, but the navigation asks
-	// member.IsAdmin(), which reads member.roles -- a column nothing ever writes
+	// Admin routes are gated on the token's Cognito groups, but the navigation asks
+	// member.IsStaff(), which reads member.roles -- a column nothing ever writes
 	// ADMIN to. Left alone, the admin section is invisible to every admin.
 	//
 	// Reconcile them here rather than in the database: the token is what actually
 	// authorises the request, so deriving the session's view from it means the menu
 	// cannot disagree with what the middleware will allow.
-	if jwtauth.HasGroup(claims, jwtauth.AdminGroup) && !member.IsAdmin() {
-		member.Roles = append(member.Roles, members.AdminRole)
+	for group, role := range sessionRoles {
+		if jwtauth.HasGroup(claims, group) && !slices.Contains(member.Roles, role) {
+			member.Roles = append(member.Roles, role)
+		}
 	}
 
 	return member, resp, nil
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"boardfund/service/adminevents"
+	"boardfund/service/members"
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// ManagedFund is a fund a fund manager is assigned.
+type ManagedFund struct {
+	ID   uuid.UUID
+	Name string
+}
+
+// AssignFundManager lets subject manage a fund, if they have the fund manager
+// role. Assigning a fund they already have changes nothing and records nothing.
+func (s AuthService) AssignFundManager(ctx context.Context, actor, subject members.Member, fund ManagedFund) error {
+	assigned, err := s.authStore.AssignFundManager(ctx, subject.ID, fund.ID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to assign fund manager",
+			slog.String("fund_id", fund.ID.String()),
+			slog.String("error", err.Error()),
+		)
+
+		return err
+	}
+
+	if assigned {
+		s.recordFundManager(ctx, adminevents.KindFundManagerAssigned, actor, subject, fund)
+	}
+
+	return nil
+}
+
+// RemoveFundManager is the inverse, and records only a removal that removed
+// something, for the same reason.
+func (s AuthService) RemoveFundManager(ctx context.Context, actor, subject members.Member, fund ManagedFund) error {
+	removed, err := s.authStore.RemoveFundManager(ctx, subject.ID, fund.ID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to remove fund manager",
+			slog.String("fund_id", fund.ID.String()),
+			slog.String("error", err.Error()),
+		)
+
+		return err
+	}
+
+	if removed {
+		s.recordFundManager(ctx, adminevents.KindFundManagerRemoved, actor, subject, fund)
+	}
+
+	return nil
+}
+
+// ManagesFund reports whether the member is assigned the fund. It says nothing
+// about whether they have the role that makes the assignment count; the token
+// says that.
+func (s AuthService) ManagesFund(ctx context.Context, memberID, fundID uuid.UUID) (bool, error) {
+	manages, err := s.authStore.IsFundManager(ctx, memberID, fundID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to check fund manager", slog.String("error", err.Error()))
+
+		return false, err
+	}
+
+	return manages, nil
+}
+
+func (s AuthService) ManagedFunds(ctx context.Context, memberID uuid.UUID) ([]ManagedFund, error) {
+	funds, err := s.authStore.GetManagedFunds(ctx, memberID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get managed funds", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	return funds, nil
+}
+
+// recordFundManager names the fund in the detail: the subject column is the
+// member, and an assignment that does not say to what answers nothing.
+func (s AuthService) recordFundManager(ctx context.Context, kind adminevents.Kind, actor, subject members.Member, fund ManagedFund) {
+	if s.adminEvents == nil {
+		return
+	}
+
+	s.adminEvents.Record(ctx, adminevents.Record{
+		Kind:            kind,
+		ActorMemberID:   &actor.ID,
+		SubjectMemberID: &subject.ID,
+		Detail:          fund.Name,
+	})
+}
@@ -0,0 +1,422 @@
+package auth
+
+import (
+	"boardfund/service/adminevents"
+	"boardfund/service/members"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultInviteLifetime is how long an invite link works for when the admin
+// does not say. A week covers somebody who reads their messages at the weekend.
+const DefaultInviteLifetime = 7 * 24 * time.Hour
+
+// MaxInviteLifetime is the longest an invite may be made for. A link is a key to
+// the front door for whoever holds it, and one forgotten in a chat history
+// should stop working on its own.
+const MaxInviteLifetime = 30 * 24 * time.Hour
+
+// InviteListLimit is how many invites the admin page shows, newest first.
+const InviteListLimit = 100
+
+// MinInviteKeyBytes is the shortest signing key SignInvitesWith accepts: the
+// length of the HMAC it keys, below which the key and not the MAC is the thing
+// to guess.
+const MinInviteKeyBytes = sha256.Size
+
+// ErrInvitesUnavailable means no signing key is configured, so no link can be
+// made or checked. Registration by approved email still works without one.
+var ErrInvitesUnavailable = errors.New("invite links are not available")
+
+// ErrInviteInvalid means the link is malformed, forged, expired, used or
+// revoked. One error for all of them: the remedy is the same, ask for another,
+// and which it was is something only a forger wants to know.
+var ErrInviteInvalid = errors.New("that invite link has expired or been used")
+
+// ErrInviteEmailMismatch means the invite was made for a different address.
+var ErrInviteEmailMismatch = errors.New("that invite is for a different email address")
+
+// ErrInvalidInviteEmail means the address an invite was to be bound to is not
+// one.
+var ErrInvalidInviteEmail = errors.New("that is not an email address")
+
+// ErrInviteLifetime means the invite was asked to last longer than
+// MaxInviteLifetime, or for no time at all.
+var ErrInviteLifetime = errors.New("an invite must last between a minute and thirty days")
+
+// Invite is a link somebody may register with, and what became of it.
+type Invite struct {
+	ID uuid.UUID
+
+	// Email is the only address the invite registers, or empty for any.
+	Email string
+
+	// FundID is the fund the new member is enrolled in as they register.
+	FundID   *uuid.UUID
+	FundName string
+
+	InvitedBy   uuid.UUID
+	InviterName string
+
+	// MemberID is who registered with it, once somebody has.
+	MemberID   *uuid.UUID
+	MemberName string
+
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	RevokedAt time.Time
+	Created   time.Time
+
+	// Link is the URL to hand over. Set only for an invite that can still be
+	// used: there is no reason to show one that cannot.
+	Link string
+}
+
+// Open reports whether the invite can still be registered with.
+func (i Invite) Open(now time.Time) bool {
+	return i.UsedAt.IsZero() && i.RevokedAt.IsZero() && now.Before(i.ExpiresAt)
+}
+
+// Status is the invite's state in a word, for the admin page.
+func (i Invite) Status(now time.Time) string {
+	switch {
+	case !i.UsedAt.IsZero():
+		return "used"
+	case !i.RevokedAt.IsZero():
+		return "revoked"
+	case !now.Before(i.ExpiresAt):
+		return "expired"
+	default:
+		return "open"
+	}
+}
+
+// NewInvite is what an admin asks for. Both fields are optional.
+type NewInvite struct {
+	Email  string
+	FundID *uuid.UUID
+
+	// Lifetime is how long the link works. Zero is DefaultInviteLifetime.
+	Lifetime time.Duration
+}
+
+type InsertInvite struct {
+	ID        uuid.UUID
+	Email     string
+	FundID    *uuid.UUID
+	InvitedBy uuid.UUID
+	ExpiresAt time.Time
+}
+
+// SignInvitesWith turns invite links on. Until it is called CreateInvite and
+// every check of a link answer ErrInvitesUnavailable.
+//
+// key signs the links, and is all that makes one: keep it as secret as the
+// database password, and change it to void every link outstanding at once.
+// publicURL is where the links point.
+func (s *AuthService) SignInvitesWith(key []byte, publicURL string) error {
+	if len(key) < MinInviteKeyBytes {
+		return errors.New("invite signing key is too short")
+	}
+
+	s.inviteKey = key
+	s.inviteURL = strings.TrimSuffix(publicURL, "/") + "/register"
+
+	return nil
+}
+
+// InvitesEnabled reports whether SignInvitesWith has been called, for a page
+// that would rather not offer a form that can only fail.
+func (s AuthService) InvitesEnabled() bool {
+	return s.inviteKey != nil
+}
+
+// CreateInvite makes an invite from actor and returns it with its link.
+func (s AuthService) CreateInvite(ctx context.Context, actor members.Member, invite NewInvite) (*Invite, error) {
+	if s.inviteKey == nil {
+		return nil, ErrInvitesUnavailable
+	}
+
+	email, err := normaliseInviteEmail(invite.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	lifetime := invite.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultInviteLifetime
+	}
+
+	if lifetime < time.Minute || lifetime > MaxInviteLifetime {
+		return nil, ErrInviteLifetime
+	}
+
+	// To the second, because that is what the link carries and the two are
+	// compared when it comes back.
+	insert := InsertInvite{
+		ID:        uuid.New(),
+		Email:     email,
+		FundID:    invite.FundID,
+		InvitedBy: actor.ID,
+		ExpiresAt: time.Now().Add(lifetime).Truncate(time.Second),
+	}
+
+	if _, err = s.authStore.InsertInvite(ctx, insert); err != nil {
+		s.logger.ErrorContext(ctx, "failed to insert invite", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	created, err := s.authStore.GetInvite(ctx, insert.ID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get invite", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	detail := ""
+	if created.FundName != "" {
+		detail = "with enrollment in " + created.FundName
+	}
+
+	s.recordInvite(ctx, adminevents.KindInviteCreated, &actor.ID, nil, *created, detail)
+
+	created.Link = s.inviteLink(*created)
+
+	return created, nil
+}
+
+// Invites are the most recent invites, with links on those still open.
+func (s AuthService) Invites(ctx context.Context) ([]Invite, error) {
+	invites, err := s.authStore.GetInvites(ctx, InviteListLimit)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get invites", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	now := time.Now()
+
+	for i := range invites {
+		if s.inviteKey != nil && invites[i].Open(now) {
+			invites[i].Link = s.inviteLink(invites[i])
+		}
+	}
+
+	return invites, nil
+}
+
+// RevokeInvite stops an unused invite working. An invite already used or
+// revoked is ErrInviteInvalid: there is nothing left to stop.
+func (s AuthService) RevokeInvite(ctx context.Context, actor members.Member, id uuid.UUID) error {
+	revoked, err := s.authStore.RevokeInvite(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInviteInvalid
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to revoke invite", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	s.recordInvite(ctx, adminevents.KindInviteRevoked, &actor.ID, nil, *revoked, "")
+
+	return nil
+}
+
+// CheckInvite finds the invite token was made for, if it can still be used. For
+// the registration page, which needs to know what to ask for; it spends
+// nothing.
+func (s AuthService) CheckInvite(ctx context.Context, token string) (*Invite, error) {
+	if s.inviteKey == nil {
+		return nil, ErrInvitesUnavailable
+	}
+
+	id, expires, ok := s.verifyInviteToken(token)
+	if !ok || !time.Now().Before(expires) {
+		return nil, ErrInviteInvalid
+	}
+
+	invite, err := s.authStore.GetInvite(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInviteInvalid
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get invite", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	// A link signed for another expiry is one made with the key but not for this
+	// row, which nothing here ever does.
+	if !invite.ExpiresAt.Equal(expires) || !invite.Open(time.Now()) {
+		return nil, ErrInviteInvalid
+	}
+
+	return invite, nil
+}
+
+// RegisterWithInvite registers a member with the invite token was made for, and
+// returns the invite so the caller can make the enrollment it carries.
+//
+// The invite is spent before the account is made, so two registrations from
+// one link cannot both succeed. If the account then cannot be made -- the name
+// is taken, most often -- the invite is given back: a typo should not cost
+// somebody their link.
+func (s AuthService) RegisterWithInvite(ctx context.Context, token, username, email string) (*members.Member, *Invite, error) {
+	invite, err := s.CheckInvite(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if invite.Email != "" && !strings.EqualFold(strings.TrimSpace(email), invite.Email) {
+		return nil, nil, ErrInviteEmailMismatch
+	}
+
+	_, err = s.authStore.ClaimInvite(ctx, invite.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrInviteInvalid
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to claim invite", slog.String("error", err.Error()))
+
+		return nil, nil, err
+	}
+
+	member, err := s.Register(ctx, username, strings.TrimSpace(email))
+	if err != nil {
+		if errRelease := s.authStore.ReleaseInvite(ctx, invite.ID); errRelease != nil {
+			s.logger.ErrorContext(ctx, "failed to release invite", slog.String("error", errRelease.Error()))
+		}
+
+		return nil, nil, err
+	}
+
+	// The account exists and the invite is spent either way; what is lost without
+	// this is the name on the admin page, not the registration.
+	if err = s.authStore.SetInviteMember(ctx, invite.ID, member.ID); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record who used an invite", slog.String("error", err.Error()))
+	}
+
+	invite.MemberID = &member.ID
+	invite.MemberName = member.BCOName
+
+	s.recordInvite(ctx, adminevents.KindInviteRedeemed, &member.ID, &member.ID, *invite, "invited by "+invite.InviterName)
+
+	return member, invite, nil
+}
+
+// recordInvite writes the audit line. Until it is used an invite has no member
+// to point at, so the subject is the address it was made for, or says it was
+// open to anybody.
+func (s AuthService) recordInvite(ctx context.Context, kind adminevents.Kind, actorID, subjectID *uuid.UUID, invite Invite, detail string) {
+	if s.adminEvents == nil {
+		return
+	}
+
+	record := adminevents.Record{
+		Kind:            kind,
+		ActorMemberID:   actorID,
+		SubjectMemberID: subjectID,
+		Detail:          detail,
+	}
+
+	if subjectID == nil {
+		record.SubjectLabel = inviteLabel(invite)
+	}
+
+	s.adminEvents.Record(ctx, record)
+}
+
+func inviteLabel(invite Invite) string {
+	if invite.Email != "" {
+		return invite.Email
+	}
+
+	return "open invite " + invite.ID.String()[:8]
+}
+
+func (s AuthService) inviteLink(invite Invite) string {
+	return s.inviteURL + "?" + url.Values{"invite": {s.inviteToken(invite.ID, invite.ExpiresAt)}}.Encode()
+}
+
+// inviteToken is the id and the expiry, and an HMAC of both. Nothing is looked
+// up to refuse a forged or expired link, and nothing stored is enough to make
+// one.
+func (s AuthService) inviteToken(id uuid.UUID, expires time.Time) string {
+	payload := invitePayload(id, expires)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.inviteMAC(payload))
+}
+
+func (s AuthService) verifyInviteToken(token string) (uuid.UUID, time.Time, bool) {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != len(uuid.UUID{})+8 {
+		return uuid.Nil, time.Time{}, false
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.inviteMAC(payload)) {
+		return uuid.Nil, time.Time{}, false
+	}
+
+	id, err := uuid.FromBytes(payload[:16])
+	if err != nil {
+		return uuid.Nil, time.Time{}, false
+	}
+
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0)
+
+	return id, expires, true
+}
+
+func (s AuthService) inviteMAC(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.inviteKey)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
+
+func invitePayload(id uuid.UUID, expires time.Time) []byte {
+	payload := make([]byte, 0, len(id)+8)
+	payload = append(payload, id[:]...)
+
+	return binary.BigEndian.AppendUint64(payload, uint64(expires.Unix()))
+}
+
+// normaliseInviteEmail lowercases a bound address, which is compared without
+// case when it comes back. Empty stays empty: an invite for anybody.
+func normaliseInviteEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+
+	parsed, err := mail.ParseAddress(email)
+	if err != nil || parsed.Address != email {
+		return "", ErrInvalidInviteEmail
+	}
+
+	return email, nil
+}
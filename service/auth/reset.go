@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"boardfund/mailer"
+	"boardfund/service/adminevents"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ResetLinkLifetime is how long an emailed reset link works for.
+const ResetLinkLifetime = time.Hour
+
+// The most reset requests taken in resetWindow for one address and from one IP.
+// The address limit stops somebody's inbox being filled; the IP limit stops one
+// client working through a list of addresses.
+const (
+	resetWindow      = time.Hour
+	resetsPerAddress = 3
+	resetsPerIP      = 10
+)
+
+// ErrPasswordResetUnavailable means nothing is configured to send the link.
+var ErrPasswordResetUnavailable = errors.New("password reset by email is not available")
+
+// ErrTooManyResetRequests means the address or the IP has asked too often
+// lately. Said the same way for an address with an account and one without.
+var ErrTooManyResetRequests = errors.New("too many password reset requests")
+
+// ErrResetLinkInvalid means the link is unknown, used or expired. One error for
+// all three, because the remedy is the same: ask for another.
+var ErrResetLinkInvalid = errors.New("that reset link has expired or been used")
+
+// ResetSubject is the member a reset request is for, when there is one.
+type ResetSubject struct {
+	MemberID uuid.UUID
+	Username string
+}
+
+type PasswordReset struct {
+	ID        uuid.UUID
+	Email     string
+	IPAddress string
+	MemberID  *uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	Created   time.Time
+}
+
+type InsertPasswordReset struct {
+	ID        uuid.UUID
+	Email     string
+	IPAddress string
+	MemberID  *uuid.UUID
+	TokenHash []byte
+	ExpiresAt *time.Time
+}
+
+// resetSender delivers the reset link. Narrowed from mailer.Mailer.
+type resetSender interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+// SendPasswordResetsWith turns forgotten-password resets on. Until it is called
+// a request is refused with ErrPasswordResetUnavailable, because the only thing
+// a reset can do without email is tell somebody their link went nowhere.
+//
+// publicURL is where the link in the email points.
+func (s *AuthService) SendPasswordResetsWith(sender resetSender, publicURL string) {
+	s.resetSender = sender
+	s.resetURL = strings.TrimSuffix(publicURL, "/") + "/password/reset"
+}
+
+// RequestPasswordReset emails a single-use link to the member with email, if
+// there is one.
+//
+// The caller cannot tell which happened, and neither can anybody watching: the
+// answer is the same, the audit line is the same, and the email is sent after
+// the answer rather than before it, so a known address does not take longer to
+// refuse than an unknown one. Both count towards the limits.
+func (s AuthService) RequestPasswordReset(ctx context.Context, email, ip string) error {
+	if s.resetSender == nil {
+		return ErrPasswordResetUnavailable
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	since := time.Now().Add(-resetWindow)
+
+	byAddress, err := s.authStore.CountPasswordResetsByEmail(ctx, email, since)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count password resets", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	byIP, err := s.authStore.CountPasswordResetsByIP(ctx, ip, since)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count password resets", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	// Not written down: a client being refused does not get to fill the table
+	// it is being counted in.
+	if byAddress >= resetsPerAddress || byIP >= resetsPerIP {
+		s.logger.WarnContext(ctx, "refused a password reset request over the limit",
+			slog.String("ip_address", ip),
+			slog.Int("by_address", byAddress),
+			slog.Int("by_ip", byIP),
+		)
+
+		return ErrTooManyResetRequests
+	}
+
+	insert := InsertPasswordReset{
+		ID:        uuid.New(),
+		Email:     email,
+		IPAddress: ip,
+	}
+
+	subject, err := s.authStore.FindMemberForReset(ctx, email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.ErrorContext(ctx, "failed to find member for password reset", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	var token string
+	if subject != nil {
+		token, err = resetToken()
+		if err != nil {
+			return err
+		}
+
+		hash := sha256.Sum256([]byte(token))
+		expires := time.Now().Add(ResetLinkLifetime)
+
+		insert.MemberID = &subject.MemberID
+		insert.TokenHash = hash[:]
+		insert.ExpiresAt = &expires
+	}
+
+	if _, err = s.authStore.InsertPasswordReset(ctx, insert); err != nil {
+		s.logger.ErrorContext(ctx, "failed to insert password reset", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	// The address, never the member: the line reads the same whether or not
+	// anybody has it, so the log answers nothing the form does not.
+	if s.adminEvents != nil {
+		s.adminEvents.Record(ctx, adminevents.Record{
+			Kind:         adminevents.KindPasswordResetRequested,
+			SubjectLabel: email,
+			Detail:       "from " + ip,
+		})
+	}
+
+	if subject != nil {
+		go s.sendResetLink(context.WithoutCancel(ctx), email, subject.Username, token)
+	}
+
+	return nil
+}
+
+func (s AuthService) sendResetLink(ctx context.Context, email, username, token string) {
+	link := s.resetURL + "?" + url.Values{"token": {token}}.Encode()
+
+	err := s.resetSender.Send(ctx, mailer.Message{
+		To:      []string{email},
+		Subject: "reset your password",
+		Body: fmt.Sprintf(
+			"somebody asked to reset the password for %s. if it was you, choose a new one here:\n\n%s\n\n"+
+				"the link works once, for the next %d minutes. if it was not you, there is nothing to do: "+
+				"your password has not changed.\n",
+			username, link, int(ResetLinkLifetime.Minutes()),
+		),
+	})
+	if err != nil {
+		// The member sees the same page either way and can ask again; the limit
+		// leaves room for that.
+		s.logger.ErrorContext(ctx, "failed to send password reset link", slog.String("error", err.Error()))
+	}
+}
+
+// ResetForgottenPassword sets a new password for whoever token was sent to.
+//
+// The token is spent before the password is set, so two uses of one link cannot
+// both succeed. If the authorizer then refuses the password, the token is given
+// back: being told a password is too weak should not cost somebody their link.
+func (s AuthService) ResetForgottenPassword(ctx context.Context, token, newPassword string) error {
+	hash := sha256.Sum256([]byte(token))
+
+	reset, err := s.authStore.RedeemPasswordReset(ctx, hash[:])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrResetLinkInvalid
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to redeem password reset", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	member, err := s.memberStore.GetMemberByID(ctx, *reset.MemberID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get member for password reset", slog.String("error", err.Error()))
+
+		s.releaseReset(ctx, reset.ID)
+
+		return err
+	}
+
+	if err = s.authorizer.AdminSetPassword(ctx, member.BCOName, newPassword); err != nil {
+		s.logger.ErrorContext(ctx, "failed to set password from reset link", slog.String("error", err.Error()))
+
+		s.releaseReset(ctx, reset.ID)
+
+		return err
+	}
+
+	// Any other link still in somebody's inbox would set the password again.
+	if err = s.authStore.RetireOtherPasswordResets(ctx, member.ID, reset.ID); err != nil {
+		s.logger.ErrorContext(ctx, "failed to retire other password resets", slog.String("error", err.Error()))
+	}
+
+	if s.adminEvents != nil {
+		s.adminEvents.Record(ctx, adminevents.Record{
+			Kind:            adminevents.KindPasswordResetCompleted,
+			ActorMemberID:   &member.ID,
+			SubjectMemberID: &member.ID,
+			Detail:          "by emailed link",
+		})
+	}
+
+	return nil
+}
+
+func (s AuthService) releaseReset(ctx context.Context, id uuid.UUID) {
+	if err := s.authStore.ReleasePasswordReset(ctx, id); err != nil {
+		s.logger.ErrorContext(ctx, "failed to release password reset", slog.String("error", err.Error()))
+	}
+}
+
+func resetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to read reset token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
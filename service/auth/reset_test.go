@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"boardfund/mailer"
+	"boardfund/service/adminevents"
+	"boardfund/service/members"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// resetStore keeps password_reset in memory. Only the reset methods are used;
+// the approved-email half of authStore is embedded and left nil.
+type resetStore struct {
+	authStore
+
+	members map[string]ResetSubject
+	resets  []storedReset
+}
+
+type storedReset struct {
+	PasswordReset
+	hash []byte
+}
+
+func (s *resetStore) FindMemberForReset(_ context.Context, email string) (*ResetSubject, error) {
+	subject, ok := s.members[email]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &subject, nil
+}
+
+func (s *resetStore) InsertPasswordReset(_ context.Context, insert InsertPasswordReset) (*PasswordReset, error) {
+	reset := PasswordReset{ID: insert.ID, Email: insert.Email, IPAddress: insert.IPAddress, MemberID: insert.MemberID, Created: time.Now()}
+	if insert.ExpiresAt != nil {
+		reset.ExpiresAt = *insert.ExpiresAt
+	}
+
+	s.resets = append(s.resets, storedReset{PasswordReset: reset, hash: insert.TokenHash})
+
+	return &reset, nil
+}
+
+func (s *resetStore) CountPasswordResetsByEmail(_ context.Context, email string, since time.Time) (int, error) {
+	count := 0
+	for _, reset := range s.resets {
+		if reset.Email == email && reset.Created.After(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *resetStore) CountPasswordResetsByIP(_ context.Context, ip string, since time.Time) (int, error) {
+	count := 0
+	for _, reset := range s.resets {
+		if reset.IPAddress == ip && reset.Created.After(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *resetStore) RedeemPasswordReset(_ context.Context, hash []byte) (*PasswordReset, error) {
+	for n, reset := range s.resets {
+		if reset.MemberID != nil && string(reset.hash) == string(hash) && reset.UsedAt.IsZero() && reset.ExpiresAt.After(time.Now()) {
+			s.resets[n].UsedAt = time.Now()
+
+			return &s.resets[n].PasswordReset, nil
+		}
+	}
+
+	return nil, pgx.ErrNoRows
+}
+
+func (s *resetStore) ReleasePasswordReset(_ context.Context, id uuid.UUID) error {
+	for n := range s.resets {
+		if s.resets[n].ID == id {
+			s.resets[n].UsedAt = time.Time{}
+		}
+	}
+
+	return nil
+}
+
+func (s *resetStore) RetireOtherPasswordResets(_ context.Context, memberID, keepID uuid.UUID) error {
+	for n, reset := range s.resets {
+		if reset.MemberID != nil && *reset.MemberID == memberID && reset.ID != keepID && reset.UsedAt.IsZero() {
+			s.resets[n].UsedAt = time.Now()
+		}
+	}
+
+	return nil
+}
+
+type resetMembers struct {
+	memberStore
+
+	byID map[uuid.UUID]members.Member
+}
+
+func (m resetMembers) GetMemberByID(_ context.Context, id uuid.UUID) (*members.Member, error) {
+	member, ok := m.byID[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &member, nil
+}
+
+// sentMail hands each message to the test as it is sent, since the link goes
+// out after the request has been answered.
+type sentMail chan mailer.Message
+
+func (m sentMail) Send(_ context.Context, msg mailer.Message) error {
+	m <- msg
+
+	return nil
+}
+
+// passwordAuthorizer records the passwords set without the old one, and refuses
+// the ones it is told to.
+type passwordAuthorizer struct {
+	fakeAuthorizer
+
+	set    map[string]string
+	refuse string
+}
+
+func (a *passwordAuthorizer) AdminSetPassword(_ context.Context, user, password string) error {
+	if password == a.refuse {
+		return ErrInvalidPassword
+	}
+
+	a.set[user] = password
+
+	return nil
+}
+
+type resetFixture struct {
+	svc        *AuthService
+	store      *resetStore
+	authorizer *passwordAuthorizer
+	events     *recorder
+	mail       sentMail
+	member     members.Member
+}
+
+func newResetFixture() resetFixture {
+	member := members.Member{ID: uuid.New(), BCOName: "ada", Email: "ada@example.com"}
+
+	f := resetFixture{
+		store: &resetStore{
+			members: map[string]ResetSubject{member.Email: {MemberID: member.ID, Username: member.BCOName}},
+		},
+		authorizer: &passwordAuthorizer{set: map[string]string{}, refuse: "weak"},
+		events:     &recorder{},
+		mail:       make(sentMail, 10),
+		member:     member,
+	}
+
+	f.svc = &AuthService{
+		memberStore: resetMembers{byID: map[uuid.UUID]members.Member{member.ID: member}},
+		authStore:   f.store,
+		authorizer:  f.authorizer,
+		adminEvents: f.events,
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	f.svc.SendPasswordResetsWith(f.mail, "https://fund.example.org/")
+
+	return f
+}
+
+func (f resetFixture) token(t *testing.T) string {
+	t.Helper()
+
+	select {
+	case msg := <-f.mail:
+		for _, field := range strings.Fields(msg.Body) {
+			if link, err := url.Parse(field); err == nil && link.Query().Get("token") != "" {
+				if link.Path != "/password/reset" || link.Host != "fund.example.org" {
+					t.Errorf("link = %s, want one to /password/reset on the public URL", link)
+				}
+
+				return link.Query().Get("token")
+			}
+		}
+
+		t.Fatalf("no link in %q", msg.Body)
+	case <-time.After(time.Second):
+		t.Fatal("no reset link sent")
+	}
+
+	return ""
+}
+
+func TestAResetLinkWorksOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newResetFixture()
+
+	if err := f.svc.RequestPasswordReset(ctx, " Ada@Example.com ", "192.0.2.1"); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	token := f.token(t)
+
+	// Refused by the authorizer: the link is given back rather than spent.
+	if err := f.svc.ResetForgottenPassword(ctx, token, "weak"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("weak password: err = %v, want it refused", err)
+	}
+
+	if err := f.svc.ResetForgottenPassword(ctx, token, "a much better password"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	if f.authorizer.set["ada"] != "a much better password" {
+		t.Errorf("passwords set = %v, want ada's set through the authorizer", f.authorizer.set)
+	}
+
+	if err := f.svc.ResetForgottenPassword(ctx, token, "another password"); !errors.Is(err, ErrResetLinkInvalid) {
+		t.Errorf("second use: err = %v, want the link spent", err)
+	}
+
+	if err := f.svc.ResetForgottenPassword(ctx, "not-a-token", "another password"); !errors.Is(err, ErrResetLinkInvalid) {
+		t.Errorf("made-up token: err = %v, want it refused", err)
+	}
+
+	completed := f.events.records[len(f.events.records)-1]
+	if completed.Kind != adminevents.KindPasswordResetCompleted || completed.SubjectMemberID == nil || *completed.SubjectMemberID != f.member.ID {
+		t.Errorf("last event = %+v, want the member's reset recorded", completed)
+	}
+}
+
+// Setting the password with one link retires the others, which would otherwise
+// set it again from an inbox somebody else may be reading.
+func TestAResetRetiresTheOtherLinks(t *testing.T) {
+	ctx := context.Background()
+	f := newResetFixture()
+
+	for range 2 {
+		if err := f.svc.RequestPasswordReset(ctx, "ada@example.com", "192.0.2.1"); err != nil {
+			t.Fatalf("request: %v", err)
+		}
+	}
+
+	first, second := f.token(t), f.token(t)
+
+	if err := f.svc.ResetForgottenPassword(ctx, second, "a much better password"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	if err := f.svc.ResetForgottenPassword(ctx, first, "another password"); !errors.Is(err, ErrResetLinkInvalid) {
+		t.Errorf("older link: err = %v, want it retired", err)
+	}
+}
+
+// An address nobody has gets the same answer and the same audit line as one
+// that somebody does, and counts towards the same limit.
+func TestAnUnknownAddressLooksLikeAKnownOne(t *testing.T) {
+	ctx := context.Background()
+	f := newResetFixture()
+
+	known := f.svc.RequestPasswordReset(ctx, "ada@example.com", "192.0.2.1")
+	unknown := f.svc.RequestPasswordReset(ctx, "nobody@example.com", "192.0.2.2")
+
+	if known != nil || unknown != nil {
+		t.Fatalf("known: %v, unknown: %v; want both answered alike", known, unknown)
+	}
+
+	f.token(t)
+
+	select {
+	case msg := <-f.mail:
+		t.Errorf("sent %+v to an address with no account", msg)
+	default:
+	}
+
+	if len(f.events.records) != 2 {
+		t.Fatalf("recorded %d events, want one per request", len(f.events.records))
+	}
+
+	for _, record := range f.events.records {
+		if record.Kind != adminevents.KindPasswordResetRequested || record.SubjectMemberID != nil || record.SubjectLabel == "" {
+			t.Errorf("event = %+v, want the address and no member", record)
+		}
+	}
+
+	for range resetsPerAddress - 1 {
+		_ = f.svc.RequestPasswordReset(ctx, "nobody@example.com", "192.0.2.3")
+	}
+
+	if err := f.svc.RequestPasswordReset(ctx, "nobody@example.com", "192.0.2.4"); !errors.Is(err, ErrTooManyResetRequests) {
+		t.Errorf("err = %v, want the address limit applied to an address with no account", err)
+	}
+}
+
+func TestOneClientCannotWorkThroughAList(t *testing.T) {
+	ctx := context.Background()
+	f := newResetFixture()
+
+	for n := range resetsPerIP {
+		if err := f.svc.RequestPasswordReset(ctx, uuid.NewString()+"@example.com", "192.0.2.1"); err != nil {
+			t.Fatalf("request %d: %v", n, err)
+		}
+	}
+
+	if err := f.svc.RequestPasswordReset(ctx, "ada@example.com", "192.0.2.1"); !errors.Is(err, ErrTooManyResetRequests) {
+		t.Errorf("err = %v, want the IP limit applied", err)
+	}
+
+	if err := f.svc.RequestPasswordReset(ctx, "ada@example.com", "192.0.2.9"); err != nil {
+		t.Errorf("another client: %v, want it unaffected", err)
+	}
+}
+
+func TestWithoutEmailThereIsNoReset(t *testing.T) {
+	svc := AuthService{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	if err := svc.RequestPasswordReset(context.Background(), "ada@example.com", "192.0.2.1"); !errors.Is(err, ErrPasswordResetUnavailable) {
+		t.Errorf("err = %v, want reset unavailable", err)
+	}
+}
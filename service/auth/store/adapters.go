@@ -3,6 +3,9 @@ package store
 import (
 	"boardfund/db"
 	"boardfund/service/auth"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func fromDBApprovedEmail(email db.ApprovedEmail) auth.ApprovedEmail {
@@ -13,3 +16,116 @@ func fromDBApprovedEmail(email db.ApprovedEmail) auth.ApprovedEmail {
 		UsedAt:  email.UsedAt.Time,
 	}
 }
+
+func fromDBResetSubject(row db.GetActiveMemberByEmailRow) auth.ResetSubject {
+	return auth.ResetSubject{
+		MemberID: row.ID,
+		Username: row.BcoName.String,
+	}
+}
+
+func fromDBPasswordReset(reset db.PasswordReset) auth.PasswordReset {
+	var memberID *uuid.UUID
+	if reset.MemberID.Valid {
+		memberID = &reset.MemberID.UUID
+	}
+
+	return auth.PasswordReset{
+		ID:        reset.ID,
+		Email:     reset.Email,
+		IPAddress: reset.IpAddress,
+		MemberID:  memberID,
+		ExpiresAt: reset.ExpiresAt.Time,
+		UsedAt:    reset.UsedAt.Time,
+		Created:   reset.Created.Time,
+	}
+}
+
+func toDBInsertPasswordReset(reset auth.InsertPasswordReset) db.InsertPasswordResetParams {
+	params := db.InsertPasswordResetParams{
+		ID:        reset.ID,
+		Email:     reset.Email,
+		IpAddress: reset.IPAddress,
+		TokenHash: reset.TokenHash,
+	}
+
+	if reset.MemberID != nil {
+		params.MemberID = uuid.NullUUID{UUID: *reset.MemberID, Valid: true}
+	}
+
+	if reset.ExpiresAt != nil {
+		params.ExpiresAt = pgtype.Timestamptz{Time: *reset.ExpiresAt, Valid: true}
+	}
+
+	return params
+}
+
+func fromDBTOTP(totp db.MemberTotp) auth.TOTP {
+	return auth.TOTP{
+		MemberID:     totp.MemberID,
+		Secret:       totp.Secret,
+		ConfirmedAt:  totp.ConfirmedAt.Time,
+		LastUsedStep: totp.LastUsedStep,
+		Created:      totp.Created.Time,
+
+		FailedAttempts: int(totp.FailedAttempts),
+		LockedUntil:    totp.LockedUntil.Time,
+	}
+}
+
+func fromDBInvite(invite db.Invite) auth.Invite {
+	return auth.Invite{
+		ID:        invite.ID,
+		Email:     invite.Email.String,
+		FundID:    nullableUUID(invite.FundID),
+		InvitedBy: invite.InvitedBy,
+		MemberID:  nullableUUID(invite.MemberID),
+		ExpiresAt: invite.ExpiresAt.Time,
+		UsedAt:    invite.UsedAt.Time,
+		RevokedAt: invite.RevokedAt.Time,
+		Created:   invite.Created.Time,
+	}
+}
+
+func fromDBInviteRow(row db.GetInviteRow) auth.Invite {
+	invite := fromDBInvite(db.Invite{
+		ID:        row.ID,
+		Email:     row.Email,
+		FundID:    row.FundID,
+		InvitedBy: row.InvitedBy,
+		MemberID:  row.MemberID,
+		ExpiresAt: row.ExpiresAt,
+		UsedAt:    row.UsedAt,
+		RevokedAt: row.RevokedAt,
+		Created:   row.Created,
+	})
+
+	invite.FundName = row.FundName.String
+	invite.InviterName = row.InviterName.String
+	invite.MemberName = row.MemberName.String
+
+	return invite
+}
+
+func toDBInsertInvite(invite auth.InsertInvite) db.InsertInviteParams {
+	params := db.InsertInviteParams{
+		ID:        invite.ID,
+		Email:     pgtype.Text{String: invite.Email, Valid: invite.Email != ""},
+		InvitedBy: invite.InvitedBy,
+		ExpiresAt: pgtype.Timestamptz{Time: invite.ExpiresAt, Valid: true},
+	}
+
+	if invite.FundID != nil {
+		params.FundID = uuid.NullUUID{UUID: *invite.FundID, Valid: true}
+	}
+
+	return params
+}
+
+func nullableUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+
+	return &id.UUID
+}
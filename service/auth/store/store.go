@@ -5,16 +5,22 @@ import (
 	"boardfund/pg"
 	"boardfund/service/auth"
 	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type AuthStore struct {
 	queries *db.Queries
+	conn    *pgxpool.Pool
 }
 
 func NewAuthStore(pool *pgxpool.Pool) AuthStore {
 	return AuthStore{
 		queries: db.New(pool),
+		conn:    pool,
 	}
 }
 
@@ -55,3 +61,269 @@ func (s AuthStore) DeleteApprovedEmail(ctx context.Context, email string) (*auth
 
 	return pg.DeleteOne(ctx, email, query, argIdentity, fromDBApprovedEmail)
 }
+
+func (s AuthStore) FindMemberForReset(ctx context.Context, email string) (*auth.ResetSubject, error) {
+	query := s.queries.GetActiveMemberByEmail
+
+	argIdentity := func(in string) string { return in }
+
+	return pg.FetchOne(ctx, email, query, argIdentity, fromDBResetSubject)
+}
+
+func (s AuthStore) InsertPasswordReset(ctx context.Context, reset auth.InsertPasswordReset) (*auth.PasswordReset, error) {
+	query := s.queries.InsertPasswordReset
+
+	return pg.CreateOne(ctx, reset, query, toDBInsertPasswordReset, fromDBPasswordReset)
+}
+
+func (s AuthStore) CountPasswordResetsByEmail(ctx context.Context, email string, since time.Time) (int, error) {
+	count, err := s.queries.CountPasswordResetsByEmail(ctx, db.CountPasswordResetsByEmailParams{
+		Email:   email,
+		Created: pgtype.Timestamptz{Time: since, Valid: true},
+	})
+
+	return int(count), err
+}
+
+func (s AuthStore) CountPasswordResetsByIP(ctx context.Context, ip string, since time.Time) (int, error) {
+	count, err := s.queries.CountPasswordResetsByIP(ctx, db.CountPasswordResetsByIPParams{
+		IpAddress: ip,
+		Created:   pgtype.Timestamptz{Time: since, Valid: true},
+	})
+
+	return int(count), err
+}
+
+func (s AuthStore) RedeemPasswordReset(ctx context.Context, tokenHash []byte) (*auth.PasswordReset, error) {
+	query := s.queries.RedeemPasswordReset
+
+	argIdentity := func(in []byte) []byte { return in }
+
+	return pg.UpdateOne(ctx, tokenHash, query, argIdentity, fromDBPasswordReset)
+}
+
+func (s AuthStore) ReleasePasswordReset(ctx context.Context, id uuid.UUID) error {
+	return s.queries.ReleasePasswordReset(ctx, id)
+}
+
+func (s AuthStore) RetireOtherPasswordResets(ctx context.Context, memberID, keepID uuid.UUID) error {
+	return s.queries.RetireOtherPasswordResets(ctx, db.RetireOtherPasswordResetsParams{
+		MemberID: uuid.NullUUID{UUID: memberID, Valid: true},
+		ID:       keepID,
+	})
+}
+
+func (s AuthStore) GetTOTP(ctx context.Context, memberID uuid.UUID) (*auth.TOTP, error) {
+	query := s.queries.GetMemberTOTP
+
+	argIdentity := func(in uuid.UUID) uuid.UUID { return in }
+
+	return pg.FetchOne(ctx, memberID, query, argIdentity, fromDBTOTP)
+}
+
+// UpsertPendingTOTP replaces an unfinished enrolment. A confirmed one is left
+// alone and reported as pgx.ErrNoRows.
+func (s AuthStore) UpsertPendingTOTP(ctx context.Context, memberID uuid.UUID, secret string) (*auth.TOTP, error) {
+	query := s.queries.UpsertPendingMemberTOTP
+
+	params := db.UpsertPendingMemberTOTPParams{MemberID: memberID, Secret: secret}
+	argIdentity := func(in db.UpsertPendingMemberTOTPParams) db.UpsertPendingMemberTOTPParams { return in }
+
+	return pg.CreateOne(ctx, params, query, argIdentity, fromDBTOTP)
+}
+
+// ConfirmTOTP finishes an enrolment and replaces the recovery codes, together:
+// an authenticator turned on with no way back if the phone is lost, or codes
+// issued for one that is not on, would each be a member locked out or let in by
+// half a write.
+func (s AuthStore) ConfirmTOTP(ctx context.Context, memberID uuid.UUID, step int64, recoveryCodeHashes [][]byte) (*auth.TOTP, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	txQueries := s.queries.WithTx(tx)
+
+	confirmed, err := txQueries.ConfirmMemberTOTP(ctx, db.ConfirmMemberTOTPParams{MemberID: memberID, LastUsedStep: step})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = txQueries.DeleteRecoveryCodes(ctx, memberID); err != nil {
+		return nil, err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		err = txQueries.InsertRecoveryCode(ctx, db.InsertRecoveryCodeParams{ID: uuid.New(), MemberID: memberID, CodeHash: hash})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	totp := fromDBTOTP(confirmed)
+
+	return &totp, nil
+}
+
+// UseTOTPStep records step as used. pgx.ErrNoRows means it, or a later one,
+// already was.
+func (s AuthStore) UseTOTPStep(ctx context.Context, memberID uuid.UUID, step int64) (*auth.TOTP, error) {
+	query := s.queries.UseMemberTOTPStep
+
+	params := db.UseMemberTOTPStepParams{MemberID: memberID, LastUsedStep: step}
+	argIdentity := func(in db.UseMemberTOTPStepParams) db.UseMemberTOTPStepParams { return in }
+
+	return pg.UpdateOne(ctx, params, query, argIdentity, fromDBTOTP)
+}
+
+// ClaimTOTPAttempt counts an attempt against the member's allowance and locks
+// them out until lockUntil if it is the last. pgx.ErrNoRows means they are
+// locked out already, or have no confirmed authenticator.
+func (s AuthStore) ClaimTOTPAttempt(ctx context.Context, memberID uuid.UUID, maxAttempts int, lockUntil, now time.Time) (*auth.TOTP, error) {
+	query := s.queries.ClaimMemberTOTPAttempt
+
+	params := db.ClaimMemberTOTPAttemptParams{
+		MemberID:    memberID,
+		MaxAttempts: int32(maxAttempts),
+		LockUntil:   pgtype.Timestamptz{Time: lockUntil, Valid: true},
+		Now:         pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	argIdentity := func(in db.ClaimMemberTOTPAttemptParams) db.ClaimMemberTOTPAttemptParams { return in }
+
+	return pg.UpdateOne(ctx, params, query, argIdentity, fromDBTOTP)
+}
+
+func (s AuthStore) ClearTOTPAttempts(ctx context.Context, memberID uuid.UUID) error {
+	return s.queries.ClearMemberTOTPAttempts(ctx, memberID)
+}
+
+// UseRecoveryCode spends a code. pgx.ErrNoRows means there is no such unused
+// code.
+func (s AuthStore) UseRecoveryCode(ctx context.Context, memberID uuid.UUID, codeHash []byte) error {
+	_, err := s.queries.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{MemberID: memberID, CodeHash: codeHash})
+
+	return err
+}
+
+func (s AuthStore) CountUnusedRecoveryCodes(ctx context.Context, memberID uuid.UUID) (int, error) {
+	count, err := s.queries.CountUnusedRecoveryCodes(ctx, memberID)
+
+	return int(count), err
+}
+
+// DeleteTOTP removes the authenticator and its recovery codes.
+func (s AuthStore) DeleteTOTP(ctx context.Context, memberID uuid.UUID) error {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	txQueries := s.queries.WithTx(tx)
+
+	if err = txQueries.DeleteRecoveryCodes(ctx, memberID); err != nil {
+		return err
+	}
+
+	if err = txQueries.DeleteMemberTOTP(ctx, memberID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// AssignFundManager reports whether the assignment is new.
+func (s AuthStore) AssignFundManager(ctx context.Context, memberID, fundID uuid.UUID) (bool, error) {
+	rows, err := s.queries.AssignFundManager(ctx, db.AssignFundManagerParams{MemberID: memberID, FundID: fundID})
+
+	return rows > 0, err
+}
+
+// RemoveFundManager reports whether there was an assignment to remove.
+func (s AuthStore) RemoveFundManager(ctx context.Context, memberID, fundID uuid.UUID) (bool, error) {
+	rows, err := s.queries.RemoveFundManager(ctx, db.RemoveFundManagerParams{MemberID: memberID, FundID: fundID})
+
+	return rows > 0, err
+}
+
+func (s AuthStore) IsFundManager(ctx context.Context, memberID, fundID uuid.UUID) (bool, error) {
+	return s.queries.IsFundManager(ctx, db.IsFundManagerParams{MemberID: memberID, FundID: fundID})
+}
+
+func (s AuthStore) GetManagedFunds(ctx context.Context, memberID uuid.UUID) ([]auth.ManagedFund, error) {
+	rows, err := s.queries.GetManagedFunds(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	funds := make([]auth.ManagedFund, len(rows))
+	for i, row := range rows {
+		funds[i] = auth.ManagedFund{ID: row.ID, Name: row.Name}
+	}
+
+	return funds, nil
+}
+
+func (s AuthStore) InsertInvite(ctx context.Context, invite auth.InsertInvite) (*auth.Invite, error) {
+	query := s.queries.InsertInvite
+
+	return pg.CreateOne(ctx, invite, query, toDBInsertInvite, fromDBInvite)
+}
+
+func (s AuthStore) GetInvite(ctx context.Context, id uuid.UUID) (*auth.Invite, error) {
+	query := s.queries.GetInvite
+
+	argIdentity := func(in uuid.UUID) uuid.UUID { return in }
+
+	return pg.FetchOne(ctx, id, query, argIdentity, fromDBInviteRow)
+}
+
+func (s AuthStore) GetInvites(ctx context.Context, limit int) ([]auth.Invite, error) {
+	query := s.queries.GetInvites
+
+	toLimit := func(in int) int32 { return int32(in) }
+
+	// The list and the single lookup select the same columns, so sqlc's two row
+	// types convert into each other.
+	fromRow := func(row db.GetInvitesRow) auth.Invite { return fromDBInviteRow(db.GetInviteRow(row)) }
+
+	return pg.FetchMany(ctx, limit, query, toLimit, fromRow)
+}
+
+// ClaimInvite marks an open invite used. One already used, revoked or expired
+// is reported as pgx.ErrNoRows.
+func (s AuthStore) ClaimInvite(ctx context.Context, id uuid.UUID) (*auth.Invite, error) {
+	query := s.queries.ClaimInvite
+
+	argIdentity := func(in uuid.UUID) uuid.UUID { return in }
+
+	return pg.UpdateOne(ctx, id, query, argIdentity, fromDBInvite)
+}
+
+func (s AuthStore) ReleaseInvite(ctx context.Context, id uuid.UUID) error {
+	return s.queries.ReleaseInvite(ctx, id)
+}
+
+func (s AuthStore) SetInviteMember(ctx context.Context, id, memberID uuid.UUID) error {
+	return s.queries.SetInviteMember(ctx, db.SetInviteMemberParams{
+		ID:       id,
+		MemberID: uuid.NullUUID{UUID: memberID, Valid: true},
+	})
+}
+
+// RevokeInvite reports an invite that is already used or revoked as
+// pgx.ErrNoRows.
+func (s AuthStore) RevokeInvite(ctx context.Context, id uuid.UUID) (*auth.Invite, error) {
+	query := s.queries.RevokeInvite
+
+	argIdentity := func(in uuid.UUID) uuid.UUID { return in }
+
+	return pg.UpdateOne(ctx, id, query, argIdentity, fromDBInvite)
+}
@@ -0,0 +1,404 @@
+package auth
+
+import (
+	"boardfund/service/adminevents"
+	"boardfund/service/members"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// TOTPIssuer is the name an authenticator app lists the account under.
+const TOTPIssuer = "BCO Mutual Aid"
+
+// RecoveryCodeCount is how many recovery codes an enrolment hands out.
+const RecoveryCodeCount = 10
+
+// totpPeriod is the RFC 6238 default, and the only one every authenticator app
+// honours: some ignore the period in the QR code and use thirty seconds anyway.
+const totpPeriod = 30 * time.Second
+
+// totpSkew is how many steps either side of now a code is taken from. One covers
+// a phone clock a little out and a code typed as it turned over.
+const totpSkew = 1
+
+// maxTwoFactorFailures is how many wrong codes in a row lock a member out, on
+// every form that takes one together. With three codes good at any moment, a
+// guess has about one chance in three hundred thousand; a handful is what makes
+// that the odds of the whole attack rather than of each try.
+const maxTwoFactorFailures = 5
+
+// twoFactorLockout is how long the lock lasts. The count is not cleared when it
+// ends, so each wrong code after that locks again at once: one guess a quarter
+// of an hour, until a right code clears it.
+const twoFactorLockout = 15 * time.Minute
+
+// ErrTwoFactorNotEnrolled means the member has no confirmed authenticator.
+var ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not set up")
+
+// ErrTwoFactorAlreadyEnrolled means an enrolment was begun for a member who has
+// one. Turning it off comes first, which asks for a code.
+var ErrTwoFactorAlreadyEnrolled = errors.New("two-factor authentication is already set up")
+
+// ErrInvalidTwoFactorCode means the code is wrong, stale, already used, or a
+// recovery code that is spent. One error for all of them: which it was is
+// something only an attacker wants to know.
+var ErrInvalidTwoFactorCode = errors.New("that code is not valid")
+
+// ErrTwoFactorLocked means too many wrong codes have been given, and no code is
+// being checked for now -- the right one included.
+var ErrTwoFactorLocked = errors.New("too many wrong two-factor codes")
+
+// TOTP is a member's authenticator as member_totp holds it.
+type TOTP struct {
+	MemberID     uuid.UUID
+	Secret       string
+	ConfirmedAt  time.Time
+	LastUsedStep int64
+	Created      time.Time
+
+	// FailedAttempts is the wrong codes since the last right one, and LockedUntil
+	// is zero unless they reached maxTwoFactorFailures.
+	FailedAttempts int
+	LockedUntil    time.Time
+}
+
+// Confirmed reports whether a code from it has been given back. Until then it
+// is an enrolment in progress, and protects nothing.
+func (t TOTP) Confirmed() bool {
+	return !t.ConfirmedAt.IsZero()
+}
+
+// TOTPEnrolment is what a member needs to add the account to an authenticator
+// app: a QR code to scan, and the secret to type for a device that cannot.
+type TOTPEnrolment struct {
+	Secret string
+	URL    string
+	QRCode []byte
+}
+
+// HasTwoFactor reports whether the member has a confirmed authenticator, and so
+// whether logging in takes a second step.
+func (s AuthService) HasTwoFactor(ctx context.Context, memberID uuid.UUID) (bool, error) {
+	current, err := s.authStore.GetTOTP(ctx, memberID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get totp", slog.String("error", err.Error()))
+
+		return false, err
+	}
+
+	return current.Confirmed(), nil
+}
+
+// BeginTOTPEnrolment makes a new secret for the member. Nothing is protected by
+// it until ConfirmTOTPEnrolment is given a code from it, which is the only proof
+// the member's app has it; a second call before then replaces the first.
+func (s AuthService) BeginTOTPEnrolment(ctx context.Context, member members.Member) (*TOTPEnrolment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      TOTPIssuer,
+		AccountName: member.BCOName,
+		Period:      uint(totpPeriod.Seconds()),
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	_, err = s.authStore.UpsertPendingTOTP(ctx, member.ID, key.Secret())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTwoFactorAlreadyEnrolled
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to store pending totp", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	img, err := key.Image(240, 240)
+	if err != nil {
+		return nil, fmt.Errorf("failed to draw totp qr code: %w", err)
+	}
+
+	var qr bytes.Buffer
+	if err = png.Encode(&qr, img); err != nil {
+		return nil, fmt.Errorf("failed to encode totp qr code: %w", err)
+	}
+
+	return &TOTPEnrolment{
+		Secret: key.Secret(),
+		URL:    key.URL(),
+		QRCode: qr.Bytes(),
+	}, nil
+}
+
+// ConfirmTOTPEnrolment turns two-factor on, given a code from the secret that
+// BeginTOTPEnrolment made, and returns the recovery codes. They are shown once:
+// only their hashes are kept.
+func (s AuthService) ConfirmTOTPEnrolment(ctx context.Context, member members.Member, code string) ([]string, error) {
+	pending, err := s.authStore.GetTOTP(ctx, member.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get totp", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	if pending.Confirmed() {
+		return nil, ErrTwoFactorAlreadyEnrolled
+	}
+
+	step, ok := matchTOTP(pending.Secret, normaliseCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := recoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// The step is recorded as used, so the code that turned two-factor on cannot
+	// also be the one that gets past it.
+	_, err = s.authStore.ConfirmTOTP(ctx, member.ID, step, hashes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTwoFactorAlreadyEnrolled
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to confirm totp", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	s.recordTwoFactor(ctx, adminevents.KindTwoFactorEnabled, member, member, "")
+
+	return codes, nil
+}
+
+// VerifySecondFactor checks a code from the member's authenticator, or one of
+// their recovery codes. Either is good once: a TOTP code cannot be given again
+// in the half-minute it is valid for, and a recovery code is spent.
+//
+// Every call counts against the member, whichever form it came from, and the
+// one that reaches maxTwoFactorFailures wrong codes is answered with
+// ErrTwoFactorLocked rather than ErrInvalidTwoFactorCode, as is every call
+// until the lock ends.
+func (s AuthService) VerifySecondFactor(ctx context.Context, member members.Member, code string) error {
+	current, err := s.authStore.GetTOTP(ctx, member.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get totp", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	if !current.Confirmed() {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	now := time.Now()
+
+	claimed, err := s.authStore.ClaimTOTPAttempt(ctx, member.ID, maxTwoFactorFailures, now.Add(twoFactorLockout), now)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTwoFactorLocked
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count totp attempt", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	err = s.checkSecondFactor(ctx, *current, member, normaliseCode(code), now)
+
+	switch {
+	case errors.Is(err, ErrInvalidTwoFactorCode) && claimed.LockedUntil.After(now):
+		s.logger.WarnContext(ctx, "locked two-factor after too many wrong codes", slog.String("member_id", member.ID.String()))
+
+		return ErrTwoFactorLocked
+	case err != nil:
+		return err
+	}
+
+	if errClear := s.authStore.ClearTOTPAttempts(ctx, member.ID); errClear != nil {
+		s.logger.ErrorContext(ctx, "failed to clear totp attempts", slog.String("error", errClear.Error()))
+	}
+
+	return nil
+}
+
+// checkSecondFactor is VerifySecondFactor once the attempt has been counted.
+func (s AuthService) checkSecondFactor(ctx context.Context, current TOTP, member members.Member, code string, now time.Time) error {
+	if len(code) == int(otp.DigitsSix) {
+		step, ok := matchTOTP(current.Secret, code, now)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		_, err := s.authStore.UseTOTPStep(ctx, member.ID, step)
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.logger.WarnContext(ctx, "refused a totp code that was already used", slog.String("member_id", member.ID.String()))
+
+			return ErrInvalidTwoFactorCode
+		}
+
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to record totp step", slog.String("error", err.Error()))
+
+			return err
+		}
+
+		return nil
+	}
+
+	hash := sha256.Sum256([]byte(code))
+
+	err := s.authStore.UseRecoveryCode(ctx, member.ID, hash[:])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to use recovery code", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	// A recovery code used is most often a phone lost, and the fewer are left the
+	// nearer the member is to being locked out. Both are worth an admin knowing.
+	remaining, err := s.authStore.CountUnusedRecoveryCodes(ctx, member.ID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count recovery codes", slog.String("error", err.Error()))
+	}
+
+	s.recordTwoFactor(ctx, adminevents.KindRecoveryCodeUsed, member, member, fmt.Sprintf("%d left", remaining))
+
+	return nil
+}
+
+// DisableTwoFactor removes the subject's authenticator and recovery codes.
+//
+// A member turning off their own has given a code to get here; an admin doing
+// it for somebody is the way back for a member who has lost both their phone
+// and their codes. The audit line tells the two apart by its actor.
+func (s AuthService) DisableTwoFactor(ctx context.Context, actor, subject members.Member) error {
+	if err := s.authStore.DeleteTOTP(ctx, subject.ID); err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete totp", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	detail := ""
+	if actor.ID != subject.ID {
+		detail = "reset by an admin"
+	}
+
+	s.recordTwoFactor(ctx, adminevents.KindTwoFactorDisabled, actor, subject, detail)
+
+	return nil
+}
+
+func (s AuthService) recordTwoFactor(ctx context.Context, kind adminevents.Kind, actor, subject members.Member, detail string) {
+	if s.adminEvents == nil {
+		return
+	}
+
+	s.adminEvents.Record(ctx, adminevents.Record{
+		Kind:            kind,
+		ActorMemberID:   &actor.ID,
+		SubjectMemberID: &subject.ID,
+		Detail:          detail,
+	})
+}
+
+// matchTOTP finds the step code belongs to, within totpSkew of now.
+//
+// By hand rather than with totp.Validate, which says whether a code is good and
+// not for which step: the step is what stops the code being used twice.
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	opts := totp.ValidateOpts{
+		Period:    uint(totpPeriod.Seconds()),
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+
+	for offset := -totpSkew; offset <= totpSkew; offset++ {
+		at := now.Add(time.Duration(offset) * totpPeriod)
+
+		want, err := totp.GenerateCodeCustom(secret, at, opts)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return at.Unix() / int64(totpPeriod.Seconds()), true
+		}
+	}
+
+	return 0, false
+}
+
+// normaliseCode drops what people type between the characters -- the space an
+// app shows in the middle of a TOTP code, the dash in a recovery code -- and
+// the case, so that either is accepted however it was copied.
+func normaliseCode(code string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+
+		return r
+	}, code))
+}
+
+// recoveryCodes makes RecoveryCodeCount codes of ten base32 characters, shown
+// as two groups of five, and the hashes to store. Fifty bits each, which with
+// the codes single-use and every code form locked after maxTwoFactorFailures is
+// well past guessing.
+func recoveryCodes() ([]string, [][]byte, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([][]byte, 0, RecoveryCodeCount)
+
+	for range RecoveryCodeCount {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to read recovery code: %w", err)
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(b)[:10])
+		hash := sha256.Sum256([]byte(code))
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hash[:])
+	}
+
+	return codes, hashes, nil
+}
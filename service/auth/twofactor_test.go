@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"boardfund/service/adminevents"
+	"boardfund/service/members"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// totpStore keeps member_totp and member_recovery_code in memory, with the
+// conditions the queries put on their updates.
+type totpStore struct {
+	authStore
+
+	totps map[uuid.UUID]TOTP
+	codes map[uuid.UUID]map[string]bool
+}
+
+func (s *totpStore) GetTOTP(_ context.Context, memberID uuid.UUID) (*TOTP, error) {
+	current, ok := s.totps[memberID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &current, nil
+}
+
+func (s *totpStore) UpsertPendingTOTP(_ context.Context, memberID uuid.UUID, secret string) (*TOTP, error) {
+	if current, ok := s.totps[memberID]; ok && current.Confirmed() {
+		return nil, pgx.ErrNoRows
+	}
+
+	pending := TOTP{MemberID: memberID, Secret: secret, Created: time.Now()}
+	s.totps[memberID] = pending
+
+	return &pending, nil
+}
+
+func (s *totpStore) ConfirmTOTP(_ context.Context, memberID uuid.UUID, step int64, hashes [][]byte) (*TOTP, error) {
+	current, ok := s.totps[memberID]
+	if !ok || current.Confirmed() {
+		return nil, pgx.ErrNoRows
+	}
+
+	current.ConfirmedAt = time.Now()
+	current.LastUsedStep = step
+	s.totps[memberID] = current
+
+	s.codes[memberID] = map[string]bool{}
+	for _, hash := range hashes {
+		s.codes[memberID][string(hash)] = false
+	}
+
+	return &current, nil
+}
+
+func (s *totpStore) UseTOTPStep(_ context.Context, memberID uuid.UUID, step int64) (*TOTP, error) {
+	current, ok := s.totps[memberID]
+	if !ok || !current.Confirmed() || current.LastUsedStep >= step {
+		return nil, pgx.ErrNoRows
+	}
+
+	current.LastUsedStep = step
+	s.totps[memberID] = current
+
+	return &current, nil
+}
+
+func (s *totpStore) ClaimTOTPAttempt(_ context.Context, memberID uuid.UUID, maxAttempts int, lockUntil, now time.Time) (*TOTP, error) {
+	current, ok := s.totps[memberID]
+	if !ok || !current.Confirmed() || current.LockedUntil.After(now) {
+		return nil, pgx.ErrNoRows
+	}
+
+	current.FailedAttempts++
+	if current.FailedAttempts >= maxAttempts {
+		current.LockedUntil = lockUntil
+	}
+
+	s.totps[memberID] = current
+
+	return &current, nil
+}
+
+func (s *totpStore) ClearTOTPAttempts(_ context.Context, memberID uuid.UUID) error {
+	current, ok := s.totps[memberID]
+	if !ok {
+		return nil
+	}
+
+	current.FailedAttempts = 0
+	current.LockedUntil = time.Time{}
+	s.totps[memberID] = current
+
+	return nil
+}
+
+func (s *totpStore) UseRecoveryCode(_ context.Context, memberID uuid.UUID, hash []byte) error {
+	used, ok := s.codes[memberID][string(hash)]
+	if !ok || used {
+		return pgx.ErrNoRows
+	}
+
+	s.codes[memberID][string(hash)] = true
+
+	return nil
+}
+
+func (s *totpStore) CountUnusedRecoveryCodes(_ context.Context, memberID uuid.UUID) (int, error) {
+	count := 0
+	for _, used := range s.codes[memberID] {
+		if !used {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *totpStore) DeleteTOTP(_ context.Context, memberID uuid.UUID) error {
+	delete(s.totps, memberID)
+	delete(s.codes, memberID)
+
+	return nil
+}
+
+func newTwoFactorService() (AuthService, *recorder) {
+	events := &recorder{}
+
+	return AuthService{
+		authStore:   &totpStore{totps: map[uuid.UUID]TOTP{}, codes: map[uuid.UUID]map[string]bool{}},
+		adminEvents: events,
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, events
+}
+
+func codeAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	code, err := totp.GenerateCodeCustom(secret, at, totp.ValidateOpts{
+		Period:    uint(totpPeriod.Seconds()),
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatalf("code: %v", err)
+	}
+
+	return code
+}
+
+// Enrolment turns on only with a code from the secret shown, and every code
+// after that -- the one that turned it on included -- works once.
+func TestATOTPCodeWorksOnce(t *testing.T) {
+	ctx := context.Background()
+	svc, events := newTwoFactorService()
+	member := members.Member{ID: uuid.New(), BCOName: "ada"}
+
+	enrolment, err := svc.BeginTOTPEnrolment(ctx, member)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if len(enrolment.QRCode) == 0 {
+		t.Error("no QR code to scan")
+	}
+
+	if on, _ := svc.HasTwoFactor(ctx, member.ID); on {
+		t.Fatal("two-factor is on before any code came back")
+	}
+
+	if _, err = svc.ConfirmTOTPEnrolment(ctx, member, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("wrong code: err = %v, want it refused", err)
+	}
+
+	// The previous step, so that the current one is still unused below.
+	previous := codeAt(t, enrolment.Secret, time.Now().Add(-totpPeriod))
+
+	codes, err := svc.ConfirmTOTPEnrolment(ctx, member, previous)
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	if len(codes) != RecoveryCodeCount {
+		t.Errorf("got %d recovery codes, want %d", len(codes), RecoveryCodeCount)
+	}
+
+	if err = svc.VerifySecondFactor(ctx, member, previous); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("the enrolment code again: err = %v, want it spent", err)
+	}
+
+	current := codeAt(t, enrolment.Secret, time.Now())
+
+	if err = svc.VerifySecondFactor(ctx, member, current[:3]+" "+current[3:]); err != nil {
+		t.Fatalf("current code, as an app shows it: %v", err)
+	}
+
+	if err = svc.VerifySecondFactor(ctx, member, current); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("current code again: err = %v, want it refused as replayed", err)
+	}
+
+	if _, err = svc.BeginTOTPEnrolment(ctx, member); !errors.Is(err, ErrTwoFactorAlreadyEnrolled) {
+		t.Errorf("second enrolment: err = %v, want it refused while the first is on", err)
+	}
+
+	if len(events.records) != 1 || events.records[0].Kind != adminevents.KindTwoFactorEnabled {
+		t.Errorf("events = %+v, want only two-factor enabled", events.records)
+	}
+}
+
+func TestARecoveryCodeWorksOnceAndIsRecorded(t *testing.T) {
+	ctx := context.Background()
+	svc, events := newTwoFactorService()
+	member := members.Member{ID: uuid.New(), BCOName: "ada"}
+
+	enrolment, err := svc.BeginTOTPEnrolment(ctx, member)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	codes, err := svc.ConfirmTOTPEnrolment(ctx, member, codeAt(t, enrolment.Secret, time.Now()))
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	if err = svc.VerifySecondFactor(ctx, member, " "+codes[0]+" "); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+
+	if err = svc.VerifySecondFactor(ctx, member, codes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("recovery code again: err = %v, want it spent", err)
+	}
+
+	used := events.records[len(events.records)-1]
+	if used.Kind != adminevents.KindRecoveryCodeUsed || used.Detail != "9 left" {
+		t.Errorf("last event = %+v, want the code's use and what is left", used)
+	}
+}
+
+// An admin resetting somebody's two-factor is the way back from a lost phone,
+// and the record says who did it.
+func TestAnAdminResetIsRecordedAgainstTheAdmin(t *testing.T) {
+	ctx := context.Background()
+	svc, events := newTwoFactorService()
+	member := members.Member{ID: uuid.New(), BCOName: "ada"}
+	admin := members.Member{ID: uuid.New(), BCOName: "grace"}
+
+	enrolment, err := svc.BeginTOTPEnrolment(ctx, member)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if _, err = svc.ConfirmTOTPEnrolment(ctx, member, codeAt(t, enrolment.Secret, time.Now())); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	if err = svc.DisableTwoFactor(ctx, admin, member); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+
+	if on, _ := svc.HasTwoFactor(ctx, member.ID); on {
+		t.Error("two-factor is still on")
+	}
+
+	reset := events.records[len(events.records)-1]
+	if reset.Kind != adminevents.KindTwoFactorDisabled || *reset.ActorMemberID != admin.ID || *reset.SubjectMemberID != member.ID {
+		t.Errorf("last event = %+v, want the admin's reset of the member", reset)
+	}
+}
+
+// Wrong codes are counted against the member, not the form or the session they
+// came from. The one that reaches the limit locks them out, and while locked not
+// even the right code is checked.
+func TestWrongCodesLockTheMemberOut(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTwoFactorService()
+	member := members.Member{ID: uuid.New(), BCOName: "ada"}
+
+	enrolment, err := svc.BeginTOTPEnrolment(ctx, member)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if _, err = svc.ConfirmTOTPEnrolment(ctx, member, codeAt(t, enrolment.Secret, time.Now().Add(-totpPeriod))); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	// A right code clears what came before it, so a fumble or two never adds up
+	// to a lockout over weeks of logins.
+	for range maxTwoFactorFailures - 1 {
+		if err = svc.VerifySecondFactor(ctx, member, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("wrong code: err = %v, want it refused", err)
+		}
+	}
+
+	if err = svc.VerifySecondFactor(ctx, member, codeAt(t, enrolment.Secret, time.Now())); err != nil {
+		t.Fatalf("right code after a few wrong ones: %v", err)
+	}
+
+	for i := 1; i <= maxTwoFactorFailures; i++ {
+		err = svc.VerifySecondFactor(ctx, member, "000000")
+
+		if i < maxTwoFactorFailures && !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("wrong code %d: err = %v, want it refused", i, err)
+		}
+
+		if i == maxTwoFactorFailures && !errors.Is(err, ErrTwoFactorLocked) {
+			t.Fatalf("wrong code %d: err = %v, want the member locked out", i, err)
+		}
+	}
+
+	if err = svc.VerifySecondFactor(ctx, member, codeAt(t, enrolment.Secret, time.Now().Add(totpPeriod))); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Errorf("right code while locked: err = %v, want it not checked", err)
+	}
+}
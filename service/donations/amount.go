@@ -0,0 +1,233 @@
+package donations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"boardfund/service/fundevents"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AmountChange is a donor asking for their recurring donation to bill a
+// different amount.
+//
+// The two URLs are where PayPal sends the donor back to: ReturnURL once they
+// have approved the new amount, CancelURL if they back out. Both are the
+// caller's, because only the web layer knows what its routes are called.
+type AmountChange struct {
+	DonationID  uuid.UUID
+	MemberID    uuid.UUID
+	AmountCents int32
+	ReturnURL   string
+	CancelURL   string
+}
+
+// ChangeDonationAmount revises a donor's subscription onto the fund's plan for a
+// new amount, and returns where to send the donor next.
+//
+// The subscription is revised in place rather than cancelled and started again.
+// Starting again created a second donation row for the same donor and the same
+// fund, and left the first one's history on a donation marked cancelled -- so
+// the donor's page showed two gifts where they had made one and changed it.
+//
+// Nothing is written here. A price change has to be approved by the donor at
+// PayPal, and until it is the subscription goes on billing the old amount; the
+// donation moves to the new plan in ConfirmDonationAmount, once PayPal says the
+// subscription has. The plan is made now, because the revision has to name it,
+// and one the donor never approves is left for the prune job like any other.
+//
+// When PayPal asks for no approval the next page is ReturnURL itself, so there
+// is one way in to the confirmation whichever PayPal decided.
+func (s DonationService) ChangeDonationAmount(ctx context.Context, change AmountChange) (string, error) {
+	donation, err := s.memberDonation(ctx, change.DonationID, change.MemberID)
+	if err != nil {
+		return "", err
+	}
+
+	if !donation.Active || !donation.Recurring || donation.ProviderSubscriptionID == "" || !donation.DonationPlanID.Valid {
+		return "", ErrDonationNotChangeable
+	}
+
+	if change.AmountCents <= 0 {
+		return "", ErrInvalidAmount
+	}
+
+	current, err := s.donationStore.GetDonationPlanByID(ctx, donation.DonationPlanID.UUID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get donation plan", slog.String("error", err.Error()))
+
+		return "", err
+	}
+
+	if current.AmountCents == change.AmountCents {
+		return "", ErrAmountUnchanged
+	}
+
+	// The cycle stays what it was. A donor who gives every two weeks and wants to
+	// give more is still giving every two weeks; changing how often is a
+	// different donation, and the form does not offer it. The provider stays too:
+	// a subscription cannot be moved onto another provider's plan.
+	plan, err := s.CreateDonationPlan(ctx, CreatePlan{
+		Name:          fmt.Sprintf("%d-%d-%s", change.AmountCents/100, current.IntervalCount, current.IntervalUnit),
+		ProviderName:  donation.ProviderName,
+		IntervalUnit:  current.IntervalUnit,
+		IntervalCount: current.IntervalCount,
+		AmountCents:   change.AmountCents,
+		FundID:        donation.FundID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	provider, err := s.provider(ctx, donation.ProviderName)
+	if err != nil {
+		return "", err
+	}
+
+	approveURL, err := provider.ReviseSubscription(ctx, donation.ProviderSubscriptionID, plan.ProviderPlanID, change.ReturnURL, change.CancelURL)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to revise subscription at provider",
+			slog.String("donation_id", donation.ID.String()),
+			slog.String("error", err.Error()),
+		)
+
+		return "", err
+	}
+
+	if approveURL == "" {
+		return change.ReturnURL, nil
+	}
+
+	return approveURL, nil
+}
+
+// ConfirmDonationAmount records the plan a donor's subscription is on now, after
+// they have been to PayPal to approve a new amount.
+//
+// The plan is read from PayPal, not from the request, for the reason the
+// completion flow reads it: it is what the donor is actually paying. A donor who
+// arrives here before PayPal has moved the subscription finds nothing changed,
+// which is not an error -- the provider's update webhook applies it when it
+// lands, through the same step, and whichever of the two is second changes
+// nothing.
+func (s DonationService) ConfirmDonationAmount(ctx context.Context, donationID, memberID uuid.UUID) error {
+	donation, err := s.memberDonation(ctx, donationID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if !donation.Active || !donation.Recurring || donation.ProviderSubscriptionID == "" {
+		return ErrDonationNotChangeable
+	}
+
+	provider, err := s.provider(ctx, donation.ProviderName)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := provider.GetSubscription(ctx, donation.ProviderSubscriptionID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read subscription from provider",
+			slog.String("provider_subscription_id", donation.ProviderSubscriptionID),
+			slog.String("error", err.Error()),
+		)
+
+		return err
+	}
+
+	if !subscription.Active() {
+		return ErrSubscriptionNotActive
+	}
+
+	_, err = applySubscriptionPlan(ctx, s.donationStore, s.events, *donation, subscription.ProviderPlanID, &memberID, time.Time{})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to record the new donation amount",
+			slog.String("donation_id", donation.ID.String()),
+			slog.String("subscription_plan_id", subscription.ProviderPlanID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return err
+}
+
+// memberDonation is a donation that belongs to memberID, or ErrDonationNotYours.
+// An id that matches nothing gets the same answer as one belonging to somebody
+// else, as in CancelDonationForMember.
+func (s DonationService) memberDonation(ctx context.Context, donationID, memberID uuid.UUID) (*Donation, error) {
+	donation, err := s.donationStore.GetDonationByID(ctx, donationID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDonationNotYours
+		}
+
+		s.logger.ErrorContext(ctx, "failed to get donation", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	if donation == nil || donation.DonorID != memberID {
+		return nil, ErrDonationNotYours
+	}
+
+	return donation, nil
+}
+
+// applySubscriptionPlan moves a donation onto the plan its subscription reports,
+// and records the change in the fund's feed. Reports whether anything moved.
+//
+// Shared by the donor's return from PayPal and the update webhook, so the rule
+// is the same whichever arrives: the plan has to be ours and the fund's, and a
+// donation already on it is left alone. The update is a compare-and-set, so the
+// two racing each other record one event between them.
+func applySubscriptionPlan(ctx context.Context, store donationStore, events eventRecorder, donation Donation, providerPlanID string, actor *uuid.UUID, occurredAt time.Time) (bool, error) {
+	plan, err := store.GetDonationPlanByProviderPlanID(ctx, providerPlanID)
+	if err != nil {
+		return false, err
+	}
+
+	if plan == nil || plan.FundID != donation.FundID {
+		return false, ErrSubscriptionPlanMismatch
+	}
+
+	if donation.DonationPlanID.Valid && donation.DonationPlanID.UUID == plan.ID {
+		return false, nil
+	}
+
+	interval := "every " + IntervalLabel(plan.IntervalUnit, plan.IntervalCount)
+	detail := "now " + dollarDescription(plan.AmountCents) + " " + interval
+
+	if donation.DonationPlanID.Valid {
+		previous, errPrevious := store.GetDonationPlanByID(ctx, donation.DonationPlanID.UUID)
+		if errPrevious != nil {
+			return false, errPrevious
+		}
+
+		detail = dollarDescription(previous.AmountCents) + " -> " + dollarDescription(plan.AmountCents) + " " + interval
+	}
+
+	moved, err := store.SetDonationPlanForDonation(ctx, donation.ID, plan.ID)
+	if err != nil || moved == nil {
+		return false, err
+	}
+
+	amount := plan.AmountCents
+
+	events.Record(ctx, fundevents.Record{
+		FundID:          donation.FundID,
+		Kind:            fundevents.KindDonationAmountChanged,
+		OccurredAt:      occurredAt,
+		ActorMemberID:   actor,
+		SubjectMemberID: &donation.DonorID,
+		AmountCents:     &amount,
+		Detail:          detail,
+		ReferenceID:     &donation.ID,
+	})
+
+	return true, nil
+}
@@ -0,0 +1,276 @@
+package donations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"boardfund/service/fundevents"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// amountStore is one donation on one of two plans of the same fund, and a third
+// plan that belongs to a different fund. It embeds the interface, so a call the
+// change flow should not make is a nil panic.
+type amountStore struct {
+	donationStore
+
+	donation Donation
+	fund     Fund
+	plans    map[uuid.UUID]DonationPlan
+
+	moves int
+}
+
+func (s *amountStore) GetDonationByID(_ context.Context, id uuid.UUID) (*Donation, error) {
+	if id != s.donation.ID {
+		return nil, pgx.ErrNoRows
+	}
+
+	donation := s.donation
+
+	return &donation, nil
+}
+
+func (s *amountStore) GetDonationByProviderSubscriptionID(_ context.Context, id string) (*Donation, error) {
+	if id != s.donation.ProviderSubscriptionID {
+		return nil, pgx.ErrNoRows
+	}
+
+	donation := s.donation
+
+	return &donation, nil
+}
+
+func (s *amountStore) GetFundByID(context.Context, uuid.UUID) (*Fund, error) {
+	fund := s.fund
+
+	return &fund, nil
+}
+
+func (s *amountStore) GetDonationPlanByID(_ context.Context, id uuid.UUID) (*DonationPlan, error) {
+	plan, ok := s.plans[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &plan, nil
+}
+
+func (s *amountStore) GetDonationPlanByProviderPlanID(_ context.Context, providerPlanID string) (*DonationPlan, error) {
+	for _, plan := range s.plans {
+		if plan.ProviderPlanID == providerPlanID {
+			return &plan, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *amountStore) ReuseDonationPlan(_ context.Context, want CreatePlan) (*DonationPlan, error) {
+	for _, plan := range s.plans {
+		if plan.FundID == want.FundID && plan.AmountCents == want.AmountCents &&
+			plan.IntervalUnit == want.IntervalUnit && plan.IntervalCount == want.IntervalCount {
+			return &plan, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *amountStore) SetDonationPlanForDonation(_ context.Context, donationID, planID uuid.UUID) (*Donation, error) {
+	if donationID != s.donation.ID || s.donation.DonationPlanID.UUID == planID {
+		return nil, nil
+	}
+
+	s.moves++
+	s.donation.DonationPlanID = uuid.NullUUID{UUID: planID, Valid: true}
+
+	donation := s.donation
+
+	return &donation, nil
+}
+
+func newAmountStore() (*amountStore, DonationPlan, DonationPlan) {
+	fund := Fund{ID: uuid.New(), ProviderID: "PROD-1", Active: true}
+
+	monthly := func(cents int32, providerID string, fundID uuid.UUID) DonationPlan {
+		return DonationPlan{ID: uuid.New(), ProviderPlanID: providerID, AmountCents: cents,
+			IntervalUnit: IntervalUnitMonth, IntervalCount: 1, FundID: fundID, Active: true}
+	}
+
+	ten, twenty := monthly(1000, "P-10", fund.ID), monthly(2000, "P-20", fund.ID)
+	elsewhere := monthly(5000, "P-ELSEWHERE", uuid.New())
+
+	store := &amountStore{
+		donation: Donation{
+			ID:                     uuid.New(),
+			DonorID:                uuid.New(),
+			FundID:                 fund.ID,
+			DonationPlanID:         uuid.NullUUID{UUID: ten.ID, Valid: true},
+			Recurring:              true,
+			Active:                 true,
+			ProviderSubscriptionID: "I-SUB",
+		},
+		fund:  fund,
+		plans: map[uuid.UUID]DonationPlan{ten.ID: ten, twenty.ID: twenty, elsewhere.ID: elsewhere},
+	}
+
+	return store, ten, twenty
+}
+
+// amountProvider is the two provider calls the change flow makes. The generated
+// mock cannot be used from inside the package it mocks.
+type amountProvider struct {
+	PaymentsProvider
+
+	revise       func(subscriptionID, providerPlanID string) (string, error)
+	subscription *ProviderSubscription
+}
+
+func (p amountProvider) ReviseSubscription(_ context.Context, subscriptionID, providerPlanID, _, _ string) (string, error) {
+	return p.revise(subscriptionID, providerPlanID)
+}
+
+func (p amountProvider) GetSubscription(context.Context, string) (*ProviderSubscription, error) {
+	return p.subscription, nil
+}
+
+func amountService(store donationStore, provider PaymentsProvider, events eventRecorder) *DonationService {
+	return NewDonationService(store, nil, nil, provider, events, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// The subscription is revised onto the fund's plan for the new amount, on the
+// same cycle, and the donor is sent to PayPal to approve it. Nothing changes
+// here until they have.
+func TestChangingTheAmountRevisesTheSubscriptionInPlace(t *testing.T) {
+	store, ten, twenty := newAmountStore()
+
+	var revisedTo string
+	provider := amountProvider{
+		revise: func(subscriptionID, providerPlanID string) (string, error) {
+			if subscriptionID != "I-SUB" {
+				t.Errorf("revised %s, want the donor's own subscription", subscriptionID)
+			}
+			revisedTo = providerPlanID
+
+			return "https://paypal.test/approve", nil
+		},
+	}
+
+	events := &recordedEvents{}
+
+	next, err := amountService(store, provider, events).ChangeDonationAmount(context.Background(), AmountChange{
+		DonationID:  store.donation.ID,
+		MemberID:    store.donation.DonorID,
+		AmountCents: 2000,
+		ReturnURL:   "https://fund.test/return",
+	})
+	if err != nil {
+		t.Fatalf("change: %v", err)
+	}
+
+	if next != "https://paypal.test/approve" {
+		t.Errorf("next = %q, want the approval page", next)
+	}
+
+	if revisedTo != twenty.ProviderPlanID {
+		t.Errorf("revised onto %q, want the fund's existing $20 plan", revisedTo)
+	}
+
+	if store.donation.DonationPlanID.UUID != ten.ID || len(events.records) != 0 {
+		t.Error("the donation moved before the donor approved the new amount")
+	}
+}
+
+func TestChangingTheAmountRefusesWhatItCannotDo(t *testing.T) {
+	store, _, _ := newAmountStore()
+	svc := amountService(store, amountProvider{}, &recordedEvents{})
+
+	change := func(memberID uuid.UUID, cents int32) error {
+		_, err := svc.ChangeDonationAmount(context.Background(), AmountChange{
+			DonationID: store.donation.ID, MemberID: memberID, AmountCents: cents,
+		})
+
+		return err
+	}
+
+	if err := change(uuid.New(), 2000); !errors.Is(err, ErrDonationNotYours) {
+		t.Errorf("somebody else's donation: %v", err)
+	}
+
+	if err := change(store.donation.DonorID, 1000); !errors.Is(err, ErrAmountUnchanged) {
+		t.Errorf("the same amount: %v", err)
+	}
+
+	if err := change(store.donation.DonorID, 0); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("nothing: %v", err)
+	}
+
+	store.donation.Active = false
+	if err := change(store.donation.DonorID, 2000); !errors.Is(err, ErrDonationNotChangeable) {
+		t.Errorf("an ended donation: %v", err)
+	}
+}
+
+// The donor's return and the provider's webhook both apply the new plan. The
+// feed gets one line between them, with the old amount and the new.
+func TestTheNewAmountIsRecordedOnceWhicheverArrivesFirst(t *testing.T) {
+	store, _, twenty := newAmountStore()
+
+	provider := amountProvider{
+		subscription: &ProviderSubscription{Status: "ACTIVE", ProviderPlanID: twenty.ProviderPlanID},
+	}
+
+	events := &recordedEvents{}
+
+	if err := amountService(store, provider, events).ConfirmDonationAmount(context.Background(), store.donation.ID, store.donation.DonorID); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	if err := newHandlers(store, events).subscriptionUpdated([]byte(`{"id":"I-SUB","plan_id":"P-20"}`)); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+
+	if store.donation.DonationPlanID.UUID != twenty.ID {
+		t.Error("the donation is not on the plan the subscription pays into")
+	}
+
+	if len(events.records) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(events.records))
+	}
+
+	record := events.records[0]
+	if record.Kind != fundevents.KindDonationAmountChanged || record.Detail != "$10.00 -> $20.00 every month" {
+		t.Errorf("recorded %s %q", record.Kind, record.Detail)
+	}
+
+	if record.AmountCents == nil || *record.AmountCents != 2000 {
+		t.Error("the event should carry the new amount")
+	}
+}
+
+// A plan that is not this fund's is not something a redelivery fixes, and moving
+// the donation onto it would credit the wrong fund's balance. Acknowledged, and
+// nothing changes.
+func TestAnUpdateToAnotherFundsPlanChangesNothing(t *testing.T) {
+	store, ten, _ := newAmountStore()
+	events := &recordedEvents{}
+
+	if err := newHandlers(store, events).subscriptionUpdated([]byte(`{"id":"I-SUB","plan_id":"P-ELSEWHERE"}`)); err != nil {
+		t.Fatalf("a mismatch should be acknowledged, got %v", err)
+	}
+
+	if store.donation.DonationPlanID.UUID != ten.ID || store.moves != 0 || len(events.records) != 0 {
+		t.Error("the donation moved onto another fund's plan")
+	}
+
+	// And a subscription that is not ours at all.
+	if err := newHandlers(store, events).subscriptionUpdated([]byte(`{"id":"I-OTHER","plan_id":"P-20"}`)); err != nil {
+		t.Errorf("an unknown subscription should be acknowledged, got %v", err)
+	}
+}
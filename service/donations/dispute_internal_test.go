@@ -0,0 +1,269 @@
+package donations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"boardfund/service/fundevents"
+
+	"github.com/google/uuid"
+)
+
+const disputeOpened = `{"dispute_id":"PP-D-1","create_time":"2026-08-01T10:00:00Z","update_time":"2026-08-01T10:00:00Z",
+	"reason":"MERCHANDISE_OR_SERVICE_NOT_RECEIVED","status":"OPEN",
+	"disputed_transactions":[{"seller_transaction_id":"SALE-1"}],
+	"dispute_amount":{"currency_code":"USD","value":"25.00"}}`
+
+func disputedPayment(state, previous DisputeState) *DisputedPayment {
+	return &DisputedPayment{
+		PaymentID: uuid.New(), DonationID: uuid.New(),
+		FundID: uuid.New(), DonorID: uuid.New(),
+		AmountCents: 2500, DisputeID: "PP-D-1",
+		State: state, PreviousState: previous, DisputedCents: 2500,
+	}
+}
+
+// Every status short of RESOLVED is open, and the outcome decides which way a
+// resolved one went. An outcome we have not seen keeps the money held: releasing
+// it on a guess pays out what PayPal may have taken.
+func TestADisputeStateIsReadFromStatusAndOutcome(t *testing.T) {
+	cases := []struct {
+		status, outcome string
+		want            DisputeState
+		known           bool
+	}{
+		{"OPEN", "", DisputeOpen, true},
+		{"WAITING_FOR_SELLER_RESPONSE", "", DisputeOpen, true},
+		{"UNDER_REVIEW", "", DisputeOpen, true},
+		{"RESOLVED", "RESOLVED_BUYER_FAVOUR", DisputeLost, true},
+		{"RESOLVED", "ACCEPTED", DisputeLost, true},
+		{"RESOLVED", "RESOLVED_SELLER_FAVOUR", DisputeWon, true},
+		{"RESOLVED", "CANCELED_BY_BUYER", DisputeWon, true},
+		{"RESOLVED", "SOMETHING_NEW", DisputeOpen, false},
+	}
+
+	for _, c := range cases {
+		event := DisputeEvent{Status: c.status}
+		event.DisputeOutcome.OutcomeCode = c.outcome
+
+		got, known := event.State()
+		if got != c.want || known != c.known {
+			t.Errorf("%s/%s: got %s (%v), want %s (%v)", c.status, c.outcome, got, known, c.want, c.known)
+		}
+	}
+}
+
+func TestADisputeIsRecordedAgainstItsSale(t *testing.T) {
+	store := &fakeDonationStore{disputed: disputedPayment(DisputeOpen, "")}
+	events := &recordedEvents{}
+
+	if err := newHandlers(store, events).paymentDisputed([]byte(disputeOpened)); err != nil {
+		t.Fatalf("paymentDisputed: %v", err)
+	}
+
+	if len(store.disputeArgs) != 1 {
+		t.Fatalf("recorded %d disputes, want 1", len(store.disputeArgs))
+	}
+
+	arg := store.disputeArgs[0]
+	if arg.ProviderPaymentID != "SALE-1" || arg.State != DisputeOpen || arg.DisputedCents != 2500 {
+		t.Errorf("recorded %+v", arg)
+	}
+
+	if !arg.UpdatedAt.Equal(time.Date(2026, 8, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("the update time orders a dispute's webhooks and was recorded as %s", arg.UpdatedAt)
+	}
+
+	if len(events.records) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(events.records))
+	}
+
+	record := events.records[0]
+	if record.Kind != fundevents.KindPaymentDisputed {
+		t.Errorf("kind = %s", record.Kind)
+	}
+
+	// Held money has left as far as the fund is concerned, and the feed says so.
+	if record.AmountCents == nil || *record.AmountCents != -2500 {
+		t.Errorf("amount = %v, want -2500", record.AmountCents)
+	}
+}
+
+// A dispute's amount is its total. Written whole to each transaction it named,
+// it was held once per transaction, and one larger than a single payment broke
+// the payment's constraint and failed the event on every delivery.
+func TestADisputeOverTwoPaymentsIsDividedBetweenThem(t *testing.T) {
+	store := &fakeDonationStore{
+		disputed: disputedPayment(DisputeOpen, ""),
+		payments: map[string]DisputedPayment{"SALE-1": {AmountCents: 2500}, "SALE-2": {AmountCents: 2500}},
+	}
+
+	err := newHandlers(store, &recordedEvents{}).paymentDisputed([]byte(`{"dispute_id":"PP-D-3","status":"OPEN",
+		"update_time":"2026-08-01T10:00:00Z",
+		"disputed_transactions":[{"seller_transaction_id":"SALE-1"},{"seller_transaction_id":"SALE-2"}],
+		"dispute_amount":{"currency_code":"USD","value":"40.00"}}`))
+	if err != nil {
+		t.Fatalf("paymentDisputed: %v", err)
+	}
+
+	if len(store.disputeArgs) != 2 {
+		t.Fatalf("recorded %d disputes, want 2", len(store.disputeArgs))
+	}
+
+	held := map[string]int32{}
+	for _, arg := range store.disputeArgs {
+		held[arg.ProviderPaymentID] = arg.DisputedCents
+	}
+
+	if held["SALE-1"] != 2500 || held["SALE-2"] != 1500 {
+		t.Errorf("held %v, want 2500 then the 1500 left", held)
+	}
+}
+
+// The feed shows a dispute opening and closing. What PayPal sends in between
+// is written to the payment and nowhere else.
+func TestOnlyAChangeOfStateReachesTheFeed(t *testing.T) {
+	events := &recordedEvents{}
+	store := &fakeDonationStore{disputed: disputedPayment(DisputeOpen, DisputeOpen)}
+
+	if err := newHandlers(store, events).paymentDisputed([]byte(disputeOpened)); err != nil {
+		t.Fatalf("paymentDisputed: %v", err)
+	}
+
+	if len(events.records) != 0 {
+		t.Errorf("an update to an open dispute recorded %d events", len(events.records))
+	}
+}
+
+// Won after being held, the money comes back. Lost after being held, nothing
+// further moves: it left when the dispute opened.
+func TestAResolutionMovesOnlyWhatWasHeld(t *testing.T) {
+	cases := []struct {
+		name     string
+		disputed *DisputedPayment
+		want     *int32
+	}{
+		{"won after being held", disputedPayment(DisputeWon, DisputeOpen), cents(2500)},
+		{"lost after being held", disputedPayment(DisputeLost, DisputeOpen), nil},
+		{"lost, never seen open", disputedPayment(DisputeLost, ""), cents(-2500)},
+		{"won, never seen open", disputedPayment(DisputeWon, ""), nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			events := &recordedEvents{}
+
+			if err := newHandlers(&fakeDonationStore{disputed: c.disputed}, events).paymentDisputed([]byte(disputeOpened)); err != nil {
+				t.Fatalf("paymentDisputed: %v", err)
+			}
+
+			if len(events.records) != 1 {
+				t.Fatalf("recorded %d events, want 1", len(events.records))
+			}
+
+			record := events.records[0]
+			if record.Kind != fundevents.KindPaymentDisputeResolved {
+				t.Errorf("kind = %s", record.Kind)
+			}
+
+			switch {
+			case c.want == nil && record.AmountCents != nil:
+				t.Errorf("amount = %d, want none", *record.AmountCents)
+			case c.want != nil && (record.AmountCents == nil || *record.AmountCents != *c.want):
+				t.Errorf("amount = %v, want %d", record.AmountCents, *c.want)
+			}
+		})
+	}
+}
+
+func TestADisputeDecidesWhatIsWorthRetrying(t *testing.T) {
+	t.Run("a database failure is retried", func(t *testing.T) {
+		store := &fakeDonationStore{disputeErr: errors.New("deadlock")}
+
+		if err := newHandlers(store, &recordedEvents{}).paymentDisputed([]byte(disputeOpened)); err == nil {
+			t.Error("losing a dispute leaves held money payable")
+		}
+	})
+
+	t.Run("one naming no sale is discarded", func(t *testing.T) {
+		store := &fakeDonationStore{}
+
+		err := newHandlers(store, &recordedEvents{}).paymentDisputed([]byte(`{"dispute_id":"PP-D-2","status":"OPEN",
+			"dispute_amount":{"value":"5.00"}}`))
+		if err != nil {
+			t.Errorf("no retry finds the sale: %v", err)
+		}
+
+		if store.disputeCalls != 0 {
+			t.Error("nothing should have been written")
+		}
+	})
+
+	t.Run("a payment we do not know records nothing", func(t *testing.T) {
+		events := &recordedEvents{}
+
+		if err := newHandlers(&fakeDonationStore{}, events).paymentDisputed([]byte(disputeOpened)); err != nil {
+			t.Fatalf("an unknown sale is ordinary on a shared account: %v", err)
+		}
+
+		if len(events.records) != 0 {
+			t.Error("a dispute that changed nothing should not appear in the feed")
+		}
+	})
+}
+
+// disputeReplayStore answers the dry run's read and nothing else.
+type disputeReplayStore struct {
+	donationStore
+
+	payment *DisputedPayment
+}
+
+func (f disputeReplayStore) GetPaymentDisputeByProviderPaymentID(context.Context, string) (*DisputedPayment, error) {
+	if f.payment == nil {
+		return nil, nil
+	}
+
+	payment := *f.payment
+
+	return &payment, nil
+}
+
+// A dry run predicts the dispute from what is stored, and stays quiet about an
+// update the stored one is already newer than.
+func TestADryRunDisputeIsPredictedFromWhatIsStored(t *testing.T) {
+	dryRun := func(store disputeReplayStore) []string {
+		var changes []string
+
+		h := NewReplayHandlers(store, failingRecorder{t: t}, true, func(change string) {
+			changes = append(changes, change)
+		}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		if err := h.paymentDisputed([]byte(disputeOpened)); err != nil {
+			t.Fatalf("paymentDisputed: %v", err)
+		}
+
+		return changes
+	}
+
+	undisputed := disputeReplayStore{payment: &DisputedPayment{PaymentID: uuid.New(), AmountCents: 2500}}
+	if changes := dryRun(undisputed); len(changes) != 1 || changes[0] != "payment SALE-1: dispute PP-D-1 undisputed -> open, $25.00" {
+		t.Errorf("changes = %q", changes)
+	}
+
+	resolved := disputeReplayStore{payment: &DisputedPayment{
+		PaymentID: uuid.New(), AmountCents: 2500, State: DisputeWon,
+		UpdatedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	if changes := dryRun(resolved); len(changes) != 0 {
+		t.Errorf("a stale CREATED would reopen a resolved dispute: %q", changes)
+	}
+}
+
+func cents(n int32) *int32 {
+	return &n
+}
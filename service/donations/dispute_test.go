@@ -0,0 +1,157 @@
+package donations_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"boardfund/pg"
+	"boardfund/service/donations"
+	donationsstore "boardfund/service/donations/store"
+	payoutsstore "boardfund/service/payouts/store"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Money under an open dispute is already gone from the PayPal account, and may
+// not come back. The planner divides the fund balance between enrollees, so a
+// balance that still counted it paid out money that was held.
+func TestDisputedMoneyIsHeldOutOfTheFund(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	store := donationsstore.NewDonationStore(pool)
+	payouts := payoutsstore.NewPayoutStore(pool)
+
+	seedPaidDonation := func(t *testing.T, cents int32) (uuid.UUID, string) {
+		t.Helper()
+
+		fundID := seedOnceFund(t, ctx, pool)
+		donationID := seedDonationRow(t, ctx, pool, fundID)
+		providerPaymentID := uuid.NewString()
+
+		_, errPayment := pool.Exec(ctx,
+			`INSERT INTO donation_payment (id, donation_id, paypal_payment_id, amount_cents)
+			 VALUES ($1, $2, $3, $4)`,
+			uuid.New(), donationID, providerPaymentID, cents,
+		)
+		require.NoError(t, errPayment)
+
+		return fundID, providerPaymentID
+	}
+
+	balance := func(t *testing.T, fundID uuid.UUID) int64 {
+		t.Helper()
+
+		available, errBalance := payouts.GetFundBalanceCents(ctx, fundID)
+		require.NoError(t, errBalance)
+
+		return available
+	}
+
+	opened := time.Date(2026, 8, 1, 10, 0, 0, 0, time.UTC)
+
+	dispute := func(paymentID string, state donations.DisputeState, cents int32, at time.Time) donations.SetPaymentDispute {
+		return donations.SetPaymentDispute{
+			ProviderPaymentID: paymentID,
+			DisputeID:         "PP-D-" + paymentID,
+			State:             state,
+			DisputedCents:     cents,
+			Reason:            "MERCHANDISE_OR_SERVICE_NOT_RECEIVED",
+			UpdatedAt:         at,
+		}
+	}
+
+	t.Run("an open dispute holds its amount, and a won one releases it", func(t *testing.T) {
+		fundID, paymentID := seedPaidDonation(t, 5000)
+
+		held, errHeld := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeOpen, 2000, opened))
+		require.NoError(t, errHeld)
+		require.NotNil(t, held)
+
+		assert.EqualValues(t, 3000, balance(t, fundID))
+		assert.Equal(t, fundID, held.FundID)
+		assert.Empty(t, held.PreviousState, "nothing was disputed before")
+
+		won, errWon := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeWon, 2000, opened.Add(time.Hour)))
+		require.NoError(t, errWon)
+		require.NotNil(t, won)
+
+		assert.Equal(t, donations.DisputeOpen, won.PreviousState)
+		assert.EqualValues(t, 5000, balance(t, fundID), "a won dispute gives the money back")
+	})
+
+	t.Run("a lost dispute keeps it held", func(t *testing.T) {
+		fundID, paymentID := seedPaidDonation(t, 5000)
+
+		_, errLost := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeLost, 5000, opened))
+		require.NoError(t, errLost)
+
+		assert.Zero(t, balance(t, fundID))
+	})
+
+	t.Run("a reversal after a lost chargeback is not subtracted twice", func(t *testing.T) {
+		fundID, paymentID := seedPaidDonation(t, 5000)
+
+		_, errLost := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeLost, 5000, opened))
+		require.NoError(t, errLost)
+
+		_, errRefund := store.SetDonationPaymentRefunded(ctx, paymentID, 5000)
+		require.NoError(t, errRefund)
+
+		assert.Zero(t, balance(t, fundID), "the same money, reported twice, leaves once")
+	})
+
+	t.Run("a stale update cannot reopen a resolved dispute", func(t *testing.T) {
+		fundID, paymentID := seedPaidDonation(t, 5000)
+
+		_, errWon := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeWon, 2000, opened.Add(time.Hour)))
+		require.NoError(t, errWon)
+
+		// CREATED arriving after RESOLVED, which PayPal does not promise against.
+		late, errLate := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeOpen, 2000, opened))
+		require.NoError(t, errLate)
+		assert.Nil(t, late)
+
+		assert.EqualValues(t, 5000, balance(t, fundID))
+	})
+
+	t.Run("a redelivery reports nothing to do", func(t *testing.T) {
+		_, paymentID := seedPaidDonation(t, 5000)
+
+		first, errFirst := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeOpen, 2000, opened))
+		require.NoError(t, errFirst)
+		require.NotNil(t, first)
+
+		second, errSecond := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeOpen, 2000, opened))
+		require.NoError(t, errSecond)
+		assert.Nil(t, second)
+	})
+
+	t.Run("a payment we do not know is not an error", func(t *testing.T) {
+		disputed, errDispute := store.SetDonationPaymentDispute(ctx, dispute(uuid.NewString(), donations.DisputeOpen, 100, opened))
+		require.NoError(t, errDispute)
+		assert.Nil(t, disputed)
+	})
+
+	t.Run("the audit page sees it", func(t *testing.T) {
+		fundID, paymentID := seedPaidDonation(t, 5000)
+
+		_, errOpen := store.SetDonationPaymentDispute(ctx, dispute(paymentID, donations.DisputeOpen, 2000, opened))
+		require.NoError(t, errOpen)
+
+		payments, errAudit := store.GetFundPaymentsForAudit(ctx, fundID)
+		require.NoError(t, errAudit)
+		require.Len(t, payments, 1)
+
+		assert.Equal(t, donations.DisputeOpen, payments[0].DisputeState)
+		assert.EqualValues(t, 2000, payments[0].DisputedCents)
+		assert.True(t, payments[0].NeedsAttention())
+	})
+}
@@ -1,6 +1,7 @@
 package donations
 
 import (
+	"boardfund/providers"
 	"boardfund/service/fundevents"
 	"context"
 	"errors"
@@ -20,11 +21,11 @@ type eventRecorder interface {
 }
 
 type DonationService struct {
-	donationStore    donationStore
-	documentStorage  documentStorage
-	fundImages       fundImageStorage
-	paymentsProvider PaymentsProvider
-	events           eventRecorder
+	donationStore   donationStore
+	documentStorage documentStorage
+	fundImages      fundImageStorage
+	payments        *providers.Registry[PaymentsProvider]
+	events          eventRecorder
 
 	reportBuckets []string
 
@@ -33,13 +34,13 @@ type DonationService struct {
 
 func NewDonationService(donationStore donationStore, documentStorage documentStorage, fundImages fundImageStorage, provider PaymentsProvider, events eventRecorder, reportBuckets []string, logger *slog.Logger) *DonationService {
 	return &DonationService{
-		donationStore:    donationStore,
-		documentStorage:  documentStorage,
-		fundImages:       fundImages,
-		paymentsProvider: provider,
-		events:           events,
-		logger:           logger,
-		reportBuckets:    reportBuckets,
+		donationStore:   donationStore,
+		documentStorage: documentStorage,
+		fundImages:      fundImages,
+		payments:        providers.NewRegistry(providers.PayPal, provider),
+		events:          events,
+		logger:          logger,
+		reportBuckets:   reportBuckets,
 	}
 }
 
@@ -149,6 +150,13 @@ var ErrSubscriptionsNotCancelled = errors.New("could not cancel all subscription
 // attributing the closure to a person who was not involved -- and a zero uuid
 // here would fail fund_event's foreign key to member anyway.
 func (s DonationService) DeactivateFund(ctx context.Context, id uuid.UUID, actorID *uuid.UUID) error {
+	return s.deactivateFund(ctx, id, actorID, "")
+}
+
+// deactivateFund is DeactivateFund with a reason for the closure's line in the
+// feed. Unexported because the reasons are the automatic ones -- a goal reached
+// -- and a person closing a fund by hand is the reason.
+func (s DonationService) deactivateFund(ctx context.Context, id uuid.UUID, actorID *uuid.UUID, detail string) error {
 	recurring, err := s.donationStore.GetRecurringDonationsForFund(ctx, GetRecurringDonationsForFundRequest{
 		FundID: id,
 		Active: true,
@@ -160,14 +168,23 @@ func (s DonationService) DeactivateFund(ctx context.Context, id uuid.UUID, actor
 		return err
 	}
 
-	toCancel := extractProviderSubscriptionIDs(recurring)
+	// Each provider cancels its own. A fund's donors can be at more than one, and
+	// every one of them has to stop before the fund is closed. One provider can
+	// refuse after another has already cancelled, which leaves the fund open with
+	// some donations ended at their provider: reconciliation marks those, and
+	// closing again cancels the rest.
+	for name, toCancel := range subscriptionsByProvider(recurring) {
+		provider, errProvider := s.provider(ctx, name)
+		if errProvider != nil {
+			return errProvider
+		}
 
-	if len(toCancel) > 0 {
-		cancelled, errCancel := s.paymentsProvider.CancelSubscriptions(ctx, toCancel)
+		cancelled, errCancel := provider.CancelSubscriptions(ctx, toCancel)
 		if errCancel != nil {
 			s.logger.ErrorContext(ctx, "failed to cancel subscriptions, fund left active",
 				slog.String("error", errCancel.Error()),
 				slog.String("fund_id", id.String()),
+				slog.String("provider_name", name),
 			)
 
 			return errCancel
@@ -195,6 +212,8 @@ func (s DonationService) DeactivateFund(ctx context.Context, id uuid.UUID, actor
 		return err
 	}
 
+	s.deactivateFundPlans(ctx, id)
+
 	// The closure itself, recorded whether or not anyone was subscribed. Without
 	// this a fund with no recurring donors closed silently, which is the case the
 	// expiry job produces most often.
@@ -202,6 +221,7 @@ func (s DonationService) DeactivateFund(ctx context.Context, id uuid.UUID, actor
 		FundID:        id,
 		Kind:          fundevents.KindFundClosed,
 		ActorMemberID: actorID,
+		Detail:        detail,
 	})
 
 	// Recorded only once everything has actually happened. They used to be
@@ -279,7 +299,12 @@ func (s DonationService) CancelDonationForMember(ctx context.Context, donationID
 		return ErrDonationNotCancellable
 	}
 
-	cancelled, err := s.paymentsProvider.CancelSubscriptions(ctx, []string{donation.ProviderSubscriptionID})
+	provider, err := s.provider(ctx, donation.ProviderName)
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := provider.CancelSubscriptions(ctx, []string{donation.ProviderSubscriptionID})
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to cancel subscription at provider, donation left active",
 			slog.String("donation_id", donationID.String()),
@@ -382,35 +407,6 @@ func (s DonationService) GetFundByID(ctx context.Context, id uuid.UUID) (*Fund,
 	return fund, nil
 }
 
-func (s DonationService) CreateDonationPlan(ctx context.Context, plan CreatePlan) (*DonationPlan, error) {
-	providerID, err := s.paymentsProvider.CreatePlan(ctx, plan)
-	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to create plan with provider", slog.String("error", err.Error()))
-
-		return nil, err
-	}
-
-	upsertPlan := UpsertDonationPlan{
-		ID:             uuid.New(),
-		Name:           plan.Name,
-		ProviderPlanID: providerID,
-		AmountCents:    plan.AmountCents,
-		IntervalUnit:   plan.IntervalUnit,
-		IntervalCount:  plan.IntervalCount,
-		Active:         true,
-		FundID:         plan.FundID,
-	}
-
-	planOut, err := s.donationStore.UpsertDonationPlan(ctx, upsertPlan)
-	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to upsert donation plan", slog.String("error", err.Error()))
-
-		return nil, err
-	}
-
-	return planOut, nil
-}
-
 // CompleteRecurringDonation records a subscription from the provider's account of
 // it, not the browser's.
 //
@@ -425,8 +421,37 @@ func (s DonationService) CreateDonationPlan(ctx context.Context, plan CreatePlan
 //
 // The one-time path was fixed for the same reason; this is its twin, and it was
 // left behind because the payments arrive by signed webhook and the link did not.
+//
+// A Stripe donor comes back with the checkout session and no subscription id, so
+// the subscription is read off the session -- from the provider again, not the
+// query string.
 func (s DonationService) CompleteRecurringDonation(ctx context.Context, memberID uuid.UUID, completion RecurringCompletion) error {
-	subscription, err := s.paymentsProvider.GetSubscription(ctx, completion.ProviderSubscriptionID)
+	providerName := s.providerName(completion.ProviderName)
+
+	provider, err := s.provider(ctx, providerName)
+	if err != nil {
+		return err
+	}
+
+	if completion.ProviderSubscriptionID == "" {
+		order, errOrder := provider.GetOrder(ctx, completion.ProviderOrderID)
+		if errOrder != nil {
+			s.logger.ErrorContext(ctx, "failed to read checkout from provider",
+				slog.String("order_id", completion.ProviderOrderID),
+				slog.String("error", errOrder.Error()),
+			)
+
+			return errOrder
+		}
+
+		if order.ProviderSubscriptionID == "" {
+			return ErrSubscriptionNotActive
+		}
+
+		completion.ProviderSubscriptionID = order.ProviderSubscriptionID
+	}
+
+	subscription, err := provider.GetSubscription(ctx, completion.ProviderSubscriptionID)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to read subscription from provider",
 			slog.String("provider_subscription_id", completion.ProviderSubscriptionID),
@@ -458,8 +483,10 @@ func (s DonationService) CompleteRecurringDonation(ctx context.Context, memberID
 
 	// The plan is ours and was created for one fund. Both halves matter: the
 	// subscription must pay into the plan being claimed, and that plan must belong
-	// to the fund about to be credited.
-	if plan == nil || plan.ProviderPlanID != subscription.ProviderPlanID || plan.FundID != completion.FundID {
+	// to the fund about to be credited. It must also be at the provider that was
+	// asked, or an id one provider issued could be vouched for by the other.
+	if plan == nil || plan.ProviderPlanID != subscription.ProviderPlanID || plan.FundID != completion.FundID ||
+		s.providerName(plan.ProviderName) != providerName {
 		s.logger.ErrorContext(ctx, "subscription does not match the plan or fund claimed",
 			slog.String("provider_subscription_id", completion.ProviderSubscriptionID),
 			slog.String("subscription_plan_id", subscription.ProviderPlanID),
@@ -480,6 +507,7 @@ func (s DonationService) CompleteRecurringDonation(ctx context.Context, memberID
 		FundID:                 completion.FundID,
 		ProviderOrderID:        completion.ProviderOrderID,
 		ProviderSubscriptionID: completion.ProviderSubscriptionID,
+		ProviderName:           providerName,
 		Recurring:              true,
 	}
 
@@ -503,24 +531,6 @@ func (s DonationService) CompleteRecurringDonation(ctx context.Context, memberID
 	return nil
 }
 
-func (s DonationService) InitiateDonation(ctx context.Context, fundID uuid.UUID, amountCents int32) (string, error) {
-	fund, err := s.donationStore.GetFundByID(ctx, fundID)
-	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to get fund by id", slog.String("error", err.Error()))
-
-		return "", err
-	}
-
-	orderID, err := s.paymentsProvider.InitiateDonation(ctx, *fund, amountCents)
-	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to initiate donation with provider", slog.String("error", err.Error()))
-
-		return "", err
-	}
-
-	return orderID, nil
-}
-
 // CompleteDonation records a one-time donation from the provider's account of the
 // order, not the browser's.
 //
@@ -530,7 +540,14 @@ func (s DonationService) InitiateDonation(ctx context.Context, fundID uuid.UUID,
 // signed PAYMENT.SALE.COMPLETED webhook -- and this closes the same gap on the
 // one-time path, which trusted the form outright.
 func (s DonationService) CompleteDonation(ctx context.Context, memberID uuid.UUID, completion OneTimeCompletion) error {
-	order, err := s.paymentsProvider.GetOrder(ctx, completion.ProviderOrderID)
+	providerName := s.providerName(completion.ProviderName)
+
+	provider, err := s.provider(ctx, providerName)
+	if err != nil {
+		return err
+	}
+
+	order, err := provider.GetOrder(ctx, completion.ProviderOrderID)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to read order from provider",
 			slog.String("order_id", completion.ProviderOrderID),
@@ -567,6 +584,7 @@ func (s DonationService) CompleteDonation(ctx context.Context, memberID uuid.UUI
 		DonorID:         memberID,
 		FundID:          completion.FundID,
 		ProviderOrderID: completion.ProviderOrderID,
+		ProviderName:    providerName,
 	}
 
 	insertPayment := InsertDonationPayment{
@@ -632,7 +650,18 @@ func (s DonationService) CreateFund(ctx context.Context, createFund Fund, actorI
 		return nil, err
 	}
 
-	providerID, err := s.paymentsProvider.CreateFund(ctx, createFund.Name, createFund.Description)
+	if err := checkGoal(createFund); err != nil {
+		return nil, err
+	}
+
+	// At the primary provider. Any other a donor chooses gets its own product
+	// the first time they do, in fund_provider.
+	provider, err := s.provider(ctx, s.payments.Primary())
+	if err != nil {
+		return nil, err
+	}
+
+	providerID, err := provider.CreateFund(ctx, createFund.Name, createFund.Description)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to create fund with provider", slog.String("error", err.Error()))
 
@@ -648,11 +677,12 @@ func (s DonationService) CreateFund(ctx context.Context, createFund Fund, actorI
 		GoalCents:       createFund.GoalCents,
 		Expires:         createFund.Expires,
 		Active:          true,
-		ProviderName:    "paypal",
+		ProviderName:    s.payments.Primary(),
 		// Chosen when the fund is created, like the picture and the goal. It was
 		// only editable afterwards, so a fund whose recipients had agreed to be
 		// named still spent its first moments not naming them.
 		EnrolleesVisible: createFund.EnrolleesVisible,
+		CloseOnGoal:      createFund.CloseOnGoal,
 	}
 
 	fund, err := s.donationStore.InsertFund(ctx, insertFund)
@@ -707,6 +737,10 @@ func (s DonationService) UpdateFund(ctx context.Context, updateFund Fund, actorI
 		return nil, err
 	}
 
+	if err = checkGoal(updateFund); err != nil {
+		return nil, err
+	}
+
 	// Computed against what is stored, before the write. Afterwards the previous
 	// values are gone: `updated` is overwritten and nothing keeps the old ones.
 	changes := describeFundChanges(*current, updateFund)
@@ -720,6 +754,7 @@ func (s DonationService) UpdateFund(ctx context.Context, updateFund Fund, actorI
 		PayoutFrequency:  string(updateFund.PayoutFrequency),
 		Expires:          updateFund.Expires,
 		EnrolleesVisible: updateFund.EnrolleesVisible,
+		CloseOnGoal:      updateFund.CloseOnGoal,
 	}
 
 	fund, err := s.donationStore.UpdateFund(ctx, update)
@@ -788,6 +823,14 @@ func describeFundChanges(before, after Fund) string {
 		}
 	}
 
+	if before.CloseOnGoal != after.CloseOnGoal {
+		if after.CloseOnGoal {
+			changes = append(changes, "closes when the goal is reached")
+		} else {
+			changes = append(changes, "no longer closes at its goal")
+		}
+	}
+
 	if !sameDay(before.Expires, after.Expires) {
 		changes = append(changes, fmt.Sprintf("end date %s to %s",
 			expiryDescription(before.Expires), expiryDescription(after.Expires)))
@@ -808,6 +851,11 @@ func goalDescription(cents int32) string {
 		return "none"
 	}
 
+	return dollarDescription(cents)
+}
+
+// dollarDescription is goalDescription without the special case: zero is $0.00.
+func dollarDescription(cents int32) string {
 	value, sign := int64(cents), ""
 	if value < 0 {
 		value, sign = -value, "-"
@@ -859,16 +907,18 @@ func (s DonationService) createFundBuckets(ctx context.Context, fundID uuid.UUID
 	return nil
 }
 
-func extractProviderSubscriptionIDs(donations []Donation) []string {
-	var subscriptionIDs []string
+// subscriptionsByProvider groups the donations' subscription ids by the provider
+// that holds them, which is the only one that can cancel them.
+func subscriptionsByProvider(donations []Donation) map[string][]string {
+	byProvider := make(map[string][]string)
 
 	for _, donation := range donations {
 		if donation.ProviderSubscriptionID != "" {
-			subscriptionIDs = append(subscriptionIDs, donation.ProviderSubscriptionID)
+			byProvider[donation.ProviderName] = append(byProvider[donation.ProviderName], donation.ProviderSubscriptionID)
 		}
 	}
 
-	return subscriptionIDs
+	return byProvider
 }
 
 func uncancelledSubscriptions(cancelled []string, all []string) []string {
@@ -999,10 +1049,14 @@ func (s DonationService) GetClosedFund(ctx context.Context, fundID uuid.UUID) (*
 // checkPayoutSchedule refuses a fund whose frequency and end date cannot produce
 // a payout.
 //
-// One rule so far, and it belongs here rather than at the form: the form is a
-// courtesy, and the route is reachable without it. See
-// ErrOneTimeFundNeedsEndDate for why the combination is unpayable.
+// The rules belong here rather than at the form: the form is a courtesy, and the
+// route is reachable without it. See ErrOneTimeFundNeedsEndDate for why that
+// combination is unpayable.
 func checkPayoutSchedule(fund Fund) error {
+	if !fund.PayoutFrequency.Known() {
+		return ErrUnknownFrequency
+	}
+
 	if !fund.PayoutFrequency.Recurring() && fund.Expires == nil {
 		return ErrOneTimeFundNeedsEndDate
 	}
@@ -1023,5 +1077,9 @@ func createdFundDetail(fund Fund) string {
 		detail += ", recipient names shown to donors"
 	}
 
+	if fund.CloseOnGoal {
+		detail += ", closes when the goal is reached"
+	}
+
 	return detail
 }
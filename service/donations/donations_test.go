@@ -51,6 +51,10 @@ func TestDonationService_DeactivateFund(t *testing.T) {
 			return "provider-plan-id", nil
 		}
 
+		paymentsMock.DeactivatePlanFunc = func(ctx context.Context, planID string) error {
+			return nil
+		}
+
 		nopHandler := slog.NewJSONHandler(io.Discard, nil)
 		logger := slog.New(nopHandler)
 
@@ -156,6 +160,12 @@ func TestDonationService_DeactivateFund(t *testing.T) {
 		require.Len(t, argIDs, 1)
 
 		assert.Equal(t, completeDonationTwo.ProviderSubscriptionID, argIDs[0])
+
+		// And its plan is taken down at PayPal, since nothing can subscribe to a
+		// closed fund.
+		deactivated := paymentsMock.DeactivatePlanCalls()
+		require.Len(t, deactivated, 1)
+		assert.Equal(t, plan.ProviderPlanID, deactivated[0].PlanID)
 	})
 }
 
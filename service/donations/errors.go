@@ -13,6 +13,13 @@ var ErrPaymentAlreadyRecorded = errors.New("provider payment already recorded")
 // ErrOrderNotComplete means the provider does not agree that the order was paid.
 var ErrOrderNotComplete = errors.New("provider order is not complete")
 
+// ErrPaymentDeclined means the provider refused the donor's card or account.
+//
+// Nothing was taken, and nothing is wrong with the fund or the site: the same
+// donation with another card may well go through. Callers say so, rather than
+// report a failure the donor can do nothing about.
+var ErrPaymentDeclined = errors.New("the payment was declined")
+
 // ErrOrderFundMismatch means the order was created for a different fund than the
 // one the request claims. The reference id is set by us when the order is
 // created, so a mismatch is not something an honest client can produce.
@@ -39,6 +46,19 @@ var ErrDonationNotYours = errors.New("donation does not belong to this member")
 // donation, or one already ended.
 var ErrDonationNotCancellable = errors.New("donation cannot be cancelled")
 
+// ErrDonationNotChangeable means the donation has no subscription to revise -- a
+// one-off donation, one already ended, or one recorded without its plan.
+var ErrDonationNotChangeable = errors.New("donation amount cannot be changed")
+
+// ErrInvalidAmount refuses an amount of nothing, or less.
+var ErrInvalidAmount = errors.New("the amount must be more than zero")
+
+// ErrAmountUnchanged refuses a change to the amount already being given.
+//
+// Revising a subscription onto the plan it is already on still sends the donor
+// through PayPal's approval page, to approve nothing.
+var ErrAmountUnchanged = errors.New("that is the amount already being given")
+
 // ErrFundClosed means the fund has ended -- deactivated, or past its end date --
 // and is not something to be changed any more.
 //
@@ -64,3 +84,9 @@ var ErrFundClosed = errors.New("a closed fund cannot be changed")
 // the code that reads it. Recurring funds are different: their schedule stands
 // on its own and an end date is genuinely optional.
 var ErrOneTimeFundNeedsEndDate = errors.New("a one-time fund needs an end date, which is when it pays out")
+
+// ErrUnknownFrequency refuses a fund with a frequency it cannot have.
+//
+// The column would refuse it too, but only at the insert, by which point the
+// provider has been asked to create a product for a fund that will not exist.
+var ErrUnknownFrequency = errors.New("unknown payout frequency")
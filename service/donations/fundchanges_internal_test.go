@@ -222,3 +222,19 @@ func TestLockedFieldsAreStillCompared(t *testing.T) {
 		t.Errorf("describeFundChanges = %q, want a frequency change to be described", got)
 	}
 }
+
+// Closing at the goal decides whether donors' subscriptions are cancelled for
+// them, so turning it on or off is its own line rather than lost in "edited".
+func TestClosingAtTheGoalIsDescribedBothWays(t *testing.T) {
+	before := openFund()
+	after := before
+	after.CloseOnGoal = true
+
+	if got := describeFundChanges(before, after); got != "closes when the goal is reached" {
+		t.Errorf("describeFundChanges = %q", got)
+	}
+
+	if got := describeFundChanges(after, before); got != "no longer closes at its goal" {
+		t.Errorf("describeFundChanges = %q", got)
+	}
+}
@@ -0,0 +1,44 @@
+package donations
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGoalProgress(t *testing.T) {
+	cases := []struct {
+		name      string
+		goal      int32
+		collected int32
+		want      int
+	}{
+		{"no goal", 0, 5000, 0},
+		{"nothing given", 5000, 0, 0},
+		{"partway", 5000, 1250, 25},
+		{"rounded down", 3000, 1999, 66},
+		{"exactly", 5000, 5000, 100},
+		{"past it", 5000, 9000, 100},
+	}
+
+	for _, c := range cases {
+		fund := Fund{GoalCents: c.goal, Stats: FundStats{TotalDonated: c.collected}}
+		if got := fund.GoalProgress(); got != c.want {
+			t.Errorf("%s: %d%%, want %d%%", c.name, got, c.want)
+		}
+	}
+}
+
+// A fund told to close at a goal it does not have would never close.
+func TestClosingOnAGoalNeedsOne(t *testing.T) {
+	if err := checkGoal(Fund{CloseOnGoal: true}); !errors.Is(err, ErrCloseOnGoalNeedsGoal) {
+		t.Errorf("got %v, want ErrCloseOnGoalNeedsGoal", err)
+	}
+
+	if err := checkGoal(Fund{CloseOnGoal: true, GoalCents: 100}); err != nil {
+		t.Errorf("a fund with a goal was refused: %v", err)
+	}
+
+	if err := checkGoal(Fund{}); err != nil {
+		t.Errorf("a fund without the mode was refused: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package donations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// ErrCloseOnGoalNeedsGoal refuses a fund set to close at its goal without one.
+//
+// A goal of zero means "no goal" everywhere else, so the fund would never close
+// -- and the admin who ticked the box would reasonably believe it will.
+var ErrCloseOnGoalNeedsGoal = errors.New("a fund that closes at its goal needs a goal")
+
+// goalReachedDetail is the closure's line in the feed, so a fund that closed by
+// itself says why rather than looking like one a treasurer shut down.
+const goalReachedDetail = "goal reached"
+
+func checkGoal(fund Fund) error {
+	if fund.CloseOnGoal && fund.GoalCents <= 0 {
+		return ErrCloseOnGoalNeedsGoal
+	}
+
+	return nil
+}
+
+// GoalProgress is how far a fund is towards its goal, in whole percent and
+// capped at 100. Zero when the fund has no goal, which the pages read as
+// nothing to show.
+//
+// Measured in what donors gave less refunds, the figure the pages call donated.
+// The goal closer waits for what the fund actually holds -- fees and disputed
+// money off as well -- so a full bar can sit a little while before the fund
+// closes, but a fund never closes short of it.
+func (f Fund) GoalProgress() int {
+	if f.GoalCents <= 0 || f.Stats.TotalDonated <= 0 {
+		return 0
+	}
+
+	progress := int(int64(f.Stats.TotalDonated) * 100 / int64(f.GoalCents))
+
+	return min(progress, 100)
+}
+
+// CloseReachedFunds closes every fund set to close at its goal that has
+// collected it.
+//
+// The same path as CloseExpiredFunds, for the same reasons: the provider is
+// called before anything is written, partial cancellation is refused, and a fund
+// that fails to close stays open for the next run. The closure is recorded with
+// no actor and "goal reached", which is what tells it apart in the feed from an
+// expiry or a treasurer.
+//
+// Like the expiry job this must run after the payout planner. A closed fund is
+// not planned, so on a fund's payout day closing first would skip the payout of
+// the money that reached the goal.
+func (s DonationService) CloseReachedFunds(ctx context.Context) (int, error) {
+	reached, err := s.ListGoalReachedOpenFunds(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, fund := range reached {
+		if errClose := s.deactivateFund(ctx, fund.ID, nil, goalReachedDetail); errClose != nil {
+			s.logger.ErrorContext(ctx, "failed to close fund that reached its goal",
+				slog.String("error", errClose.Error()),
+				slog.String("fund_id", fund.ID.String()),
+				slog.String("fund", fund.Name),
+			)
+
+			continue
+		}
+
+		s.logger.InfoContext(ctx, "closed fund that reached its goal",
+			slog.String("fund_id", fund.ID.String()),
+			slog.String("fund", fund.Name),
+			slog.Int("goal_cents", int(fund.GoalCents)),
+			slog.Int("collected_cents", int(fund.Stats.TotalDonated)),
+		)
+
+		closed++
+	}
+
+	s.logger.InfoContext(ctx, "goal closure complete",
+		slog.Int("reached", len(reached)),
+		slog.Int("closed", closed),
+	)
+
+	return closed, nil
+}
+
+// ListGoalReachedOpenFunds is what CloseReachedFunds would act on, for the dry
+// run. Each fund carries its goal and what it has collected in
+// Stats.TotalDonated.
+func (s DonationService) ListGoalReachedOpenFunds(ctx context.Context) ([]Fund, error) {
+	reached, err := s.donationStore.GetGoalReachedActiveFunds(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get funds that reached their goal", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	return reached, nil
+}
@@ -0,0 +1,140 @@
+package donations_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"boardfund/pg"
+	"boardfund/service/donations"
+	donationsstore "boardfund/service/donations/store"
+	"boardfund/service/fundevents"
+	fundeventstore "boardfund/service/fundevents/store"
+	"boardfund/service/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseReachedFunds(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	provider := &mocks.PaymentsProviderMock{}
+	provider.CancelSubscriptionsFunc = func(ctx context.Context, ids []string) ([]string, error) {
+		return ids, nil
+	}
+
+	svc := donations.NewDonationService(
+		donationsstore.NewDonationStore(pool), stubDocumentStorage{}, newFakeBucket(),
+		provider, fundevents.NewService(fundeventstore.NewEventStore(pool), logger), []string{"payments"}, logger,
+	)
+
+	goalFund := func(t *testing.T, name string, goalCents int32, closeOnGoal bool) uuid.UUID {
+		t.Helper()
+
+		fundID := insertFund(t, ctx, pool, name, true, nil)
+
+		_, errGoal := pool.Exec(ctx, `UPDATE fund SET goal_cents = $2, close_on_goal = $3 WHERE id = $1`,
+			fundID, goalCents, closeOnGoal)
+		require.NoError(t, errGoal)
+
+		return fundID
+	}
+
+	give := func(t *testing.T, fundID uuid.UUID, amountCents, refundedCents int32) uuid.UUID {
+		t.Helper()
+
+		donationID := uuid.New()
+
+		_, errDonation := pool.Exec(ctx,
+			`INSERT INTO donation (id, recurring, donor_id, provider_order_id, fund_id)
+			 VALUES ($1, false, $2, $3, $4)`,
+			donationID, seedTestMember(t, ctx, pool), uuid.NewString(), fundID)
+		require.NoError(t, errDonation)
+
+		paymentID := uuid.New()
+
+		_, errPayment := pool.Exec(ctx,
+			`INSERT INTO donation_payment (id, donation_id, paypal_payment_id, amount_cents, refunded_cents)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			paymentID, donationID, uuid.NewString(), amountCents, refundedCents)
+		require.NoError(t, errPayment)
+
+		return paymentID
+	}
+
+	isActive := func(t *testing.T, fundID uuid.UUID) bool {
+		t.Helper()
+
+		var active bool
+		require.NoError(t, pool.QueryRow(ctx, `SELECT active FROM fund WHERE id = $1`, fundID).Scan(&active))
+
+		return active
+	}
+
+	reached := goalFund(t, "reached", 5000, true)
+	give(t, reached, 3000, 0)
+	give(t, reached, 2000, 0)
+
+	// Over the goal gross, short of it once the refund is taken off.
+	refunded := goalFund(t, "refunded", 5000, true)
+	give(t, refunded, 6000, 1500)
+
+	// Over the goal in what donors paid, short of it in what arrived.
+	fees := goalFund(t, "fees", 5000, true)
+	_, err = pool.Exec(ctx, `UPDATE donation_payment SET provider_fee_cents = 200 WHERE id = $1`,
+		give(t, fees, 5100, 0))
+	require.NoError(t, err)
+
+	// Over the goal until a chargeback holds part of it back.
+	disputed := goalFund(t, "disputed", 5000, true)
+	give(t, disputed, 3000, 0)
+	_, err = pool.Exec(ctx,
+		`UPDATE donation_payment SET dispute_state = 'open', disputed_cents = 1000 WHERE id = $1`,
+		give(t, disputed, 3000, 0))
+	require.NoError(t, err)
+
+	// Past the goal, but nobody asked for it to close.
+	optedOut := goalFund(t, "opted-out", 1000, false)
+	give(t, optedOut, 5000, 0)
+
+	listed, err := svc.ListGoalReachedOpenFunds(ctx)
+	require.NoError(t, err)
+	require.Len(t, listed, 1, "the dry run should list only the fund that reached its goal")
+	assert.Equal(t, reached, listed[0].ID)
+	assert.Equal(t, int32(5000), listed[0].Stats.TotalDonated)
+
+	closed, err := svc.CloseReachedFunds(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, closed)
+
+	assert.False(t, isActive(t, reached))
+	assert.True(t, isActive(t, refunded), "a refund takes money back off the goal")
+	assert.True(t, isActive(t, fees), "fees never reach the fund, so they do not count towards the goal")
+	assert.True(t, isActive(t, disputed), "money held by a dispute does not count towards the goal")
+	assert.True(t, isActive(t, optedOut), "a goal alone does not close a fund")
+
+	// Automatic, and saying why.
+	var detail *string
+	var actor *string
+	require.NoError(t, pool.QueryRow(ctx,
+		`SELECT detail, actor_member_id::text FROM fund_event WHERE fund_id = $1 AND kind = 'fund_closed'`,
+		reached).Scan(&detail, &actor))
+	require.NotNil(t, detail)
+	assert.Equal(t, "goal reached", *detail)
+	assert.Nil(t, actor)
+
+	// Nothing left to do on the next run.
+	closed, err = svc.CloseReachedFunds(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, closed)
+}
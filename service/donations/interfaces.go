@@ -4,12 +4,17 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"io"
+	"time"
 )
 
 type donationStore interface {
 	InsertFund(ctx context.Context, fund InsertFund) (*Fund, error)
 	UpdateFund(ctx context.Context, fund UpdateFund) (*Fund, error)
-	UpsertDonationPlan(ctx context.Context, plan UpsertDonationPlan) (*DonationPlan, error)
+	ReuseDonationPlan(ctx context.Context, plan CreatePlan) (*DonationPlan, error)
+	CatalogueDonationPlan(ctx context.Context, plan UpsertDonationPlan) (*DonationPlan, error)
+	GetActiveDonationPlansForFund(ctx context.Context, fundID uuid.UUID) ([]DonationPlan, error)
+	GetAbandonedDonationPlans(ctx context.Context, unusedSince time.Time) ([]DonationPlan, error)
+	SetDonationPlanInactive(ctx context.Context, id uuid.UUID) (*DonationPlan, error)
 	InsertDonation(ctx context.Context, donation InsertDonation) (*Donation, error)
 	InsertDonationPayment(ctx context.Context, payment InsertDonationPayment) (*DonationPayment, error)
 	InsertDonationWithPayment(ctx context.Context, donation InsertDonation, payment InsertDonationPayment) (*Donation, error)
@@ -22,6 +27,7 @@ type donationStore interface {
 	GetAllFundsWithStats(ctx context.Context) ([]Fund, error)
 	GetClosedFundsWithStats(ctx context.Context) ([]ClosedFund, error)
 	GetExpiredActiveFunds(ctx context.Context) ([]Fund, error)
+	GetGoalReachedActiveFunds(ctx context.Context) ([]Fund, error)
 	GetFundPayoutStats(ctx context.Context, fundID uuid.UUID) (PayoutStats, error)
 	GetRecurringDonationsForFund(ctx context.Context, arg GetRecurringDonationsForFundRequest) ([]Donation, error)
 	GetMonthlyDonationTotalsForFund(ctx context.Context, id uuid.UUID) ([]MonthTotal, error)
@@ -45,16 +51,29 @@ type donationStore interface {
 	SetDonationToInactiveBySubscriptionID(ctx context.Context, arg DeactivateDonationBySubscription) (*Donation, error)
 	ReactivateSuspendedDonation(ctx context.Context, subscriptionID string) (*Donation, error)
 	SetDonationPaymentRefunded(ctx context.Context, providerPaymentID string, refundedCents int32) (*RefundedPayment, error)
+	GetSuspendedDonationBySubscriptionID(ctx context.Context, subscriptionID string) (*Donation, error)
+	GetPaymentByProviderPaymentID(ctx context.Context, providerPaymentID string) (*RefundedPayment, error)
+	SetDonationPaymentDispute(ctx context.Context, arg SetPaymentDispute) (*DisputedPayment, error)
+	GetPaymentDisputeByProviderPaymentID(ctx context.Context, providerPaymentID string) (*DisputedPayment, error)
+	GetDonationPlanByProviderPlanID(ctx context.Context, providerPlanID string) (*DonationPlan, error)
+	SetDonationPlanForDonation(ctx context.Context, donationID, planID uuid.UUID) (*Donation, error)
+	GetFundProviderID(ctx context.Context, fundID uuid.UUID, providerName string) (string, error)
+	InsertFundProvider(ctx context.Context, fundID uuid.UUID, providerName, providerID string) (string, error)
 }
 
 //go:generate moq -pkg mocks -out ../mocks/payments_moq.go . PaymentsProvider
 type PaymentsProvider interface {
 	CreatePlan(ctx context.Context, plan CreatePlan) (string, error)
+	DeactivatePlan(ctx context.Context, planID string) error
 	CreateFund(ctx context.Context, name, description string) (string, error)
-	InitiateDonation(ctx context.Context, fund Fund, amountCents int32) (string, error)
+	// InitiateDonation and InitiateSubscription are given the fund as this
+	// provider knows it, and where to send a donor who leaves our page to pay.
+	InitiateDonation(ctx context.Context, fund Fund, amountCents int32, returnURL, cancelURL string) (*Checkout, error)
+	InitiateSubscription(ctx context.Context, plan DonationPlan, returnURL, cancelURL string) (*Checkout, error)
 	GetOrder(ctx context.Context, orderID string) (*ProviderOrder, error)
 	GetSubscription(ctx context.Context, subscriptionID string) (*ProviderSubscription, error)
 	CancelSubscriptions(ctx context.Context, ids []string) ([]string, error)
+	ReviseSubscription(ctx context.Context, subscriptionID, providerPlanID, returnURL, cancelURL string) (string, error)
 }
 
 type subscriber interface {
@@ -1,6 +1,7 @@
 package donations
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -212,12 +213,150 @@ func TestRecurringDistinguishesRepeatingFundsFromOneOffs(t *testing.T) {
 	// Every call site that branches on this reads "does this fund pay more than
 	// once", so a new frequency being absent here is the bug, not a style point.
 	for freq, want := range map[PayoutFrequency]bool{
-		PayoutFrequencyMonthly: true,
-		PayoutFrequencyDaily:   true,
-		PayoutFrequencyOnce:    false,
+		PayoutFrequencyMonthly:   true,
+		PayoutFrequencyDaily:     true,
+		PayoutFrequencyWeekly:    true,
+		PayoutFrequencyBiweekly:  true,
+		PayoutFrequencyQuarterly: true,
+		PayoutFrequencyOnce:      false,
 	} {
 		if got := freq.Recurring(); got != want {
 			t.Errorf("%s.Recurring() = %v, want %v", freq, got, want)
 		}
 	}
 }
+
+// Weeks step in days, so a fund paying on a Friday goes on paying on a Friday;
+// quarters step in months and clamp the way monthly does.
+func TestNextPaymentAfterForWeeksAndQuarters(t *testing.T) {
+	fund := func(freq PayoutFrequency, anchor time.Time) Fund {
+		return Fund{PayoutFrequency: freq, NextPayment: anchor}
+	}
+
+	afternoon := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 17, 0, 0, 0, time.UTC)
+	}
+
+	// 7 August 2026 is a Friday.
+	friday := at(2026, time.August, 7)
+
+	cases := []struct {
+		name string
+		fund Fund
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "weekly, part way through the week",
+			fund: fund(PayoutFrequencyWeekly, friday),
+			now:  afternoon(2026, time.August, 10),
+			want: at(2026, time.August, 14),
+		},
+		{
+			name: "weekly, due exactly now",
+			fund: fund(PayoutFrequencyWeekly, friday),
+			now:  at(2026, time.August, 21),
+			want: at(2026, time.August, 21),
+		},
+		{
+			name: "weekly, still a friday across a month end",
+			fund: fund(PayoutFrequencyWeekly, friday),
+			now:  afternoon(2026, time.August, 29),
+			want: at(2026, time.September, 4),
+		},
+		{
+			name: "biweekly skips the week between",
+			fund: fund(PayoutFrequencyBiweekly, friday),
+			now:  afternoon(2026, time.August, 7),
+			want: at(2026, time.August, 21),
+		},
+		{
+			name: "biweekly, months of missed runs",
+			fund: fund(PayoutFrequencyBiweekly, friday),
+			now:  afternoon(2026, time.November, 20),
+			want: at(2026, time.November, 27),
+		},
+		{
+			name: "quarterly, within the quarter",
+			fund: fund(PayoutFrequencyQuarterly, at(2026, time.January, 15)),
+			now:  at(2026, time.February, 20),
+			want: at(2026, time.April, 15),
+		},
+		{
+			// Stepping from the anchor by months would land on 15 March, which is
+			// not a quarter on from January.
+			name: "quarterly, a month past a quarter",
+			fund: fund(PayoutFrequencyQuarterly, at(2026, time.January, 15)),
+			now:  at(2026, time.May, 1),
+			want: at(2026, time.July, 15),
+		},
+		{
+			name: "quarterly, the 31st clamps to a short month's end",
+			fund: fund(PayoutFrequencyQuarterly, at(2026, time.March, 31)),
+			now:  at(2026, time.April, 2),
+			want: at(2026, time.June, 30),
+		},
+		{
+			name: "quarterly, clamping does not stick",
+			fund: fund(PayoutFrequencyQuarterly, at(2026, time.March, 31)),
+			now:  at(2026, time.July, 2),
+			want: at(2026, time.September, 30),
+		},
+		{
+			name: "quarterly, across a year end",
+			fund: fund(PayoutFrequencyQuarterly, at(2026, time.November, 5)),
+			now:  at(2026, time.December, 1),
+			want: at(2027, time.February, 5),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.fund.NextPaymentAfter(c.now)
+			if !got.Equal(c.want) {
+				t.Errorf("NextPaymentAfter(%s) = %s, want %s",
+					c.now.Format(time.RFC3339), got.Format(time.RFC3339), c.want.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+// The form offers what the enum holds, and anything else is refused before the
+// provider is asked to create a product for it.
+func TestCheckPayoutScheduleRefusesAnUnknownFrequency(t *testing.T) {
+	for _, freq := range PayoutFrequencies {
+		end := at(2026, time.December, 1)
+		if err := checkPayoutSchedule(Fund{PayoutFrequency: freq, Expires: &end}); err != nil {
+			t.Errorf("%s was refused: %v", freq, err)
+		}
+	}
+
+	err := checkPayoutSchedule(Fund{PayoutFrequency: "fortnightly"})
+	if !errors.Is(err, ErrUnknownFrequency) {
+		t.Errorf("got %v, want ErrUnknownFrequency", err)
+	}
+}
+
+func TestPlanIntervalMatchesThePayoutCycle(t *testing.T) {
+	for freq, want := range map[PayoutFrequency]string{
+		PayoutFrequencyWeekly:    "week",
+		PayoutFrequencyBiweekly:  "2 weeks",
+		PayoutFrequencyMonthly:   "month",
+		PayoutFrequencyQuarterly: "3 months",
+	} {
+		unit, count, ok := freq.PlanInterval()
+		if !ok {
+			t.Fatalf("%s has no plan interval", freq)
+		}
+
+		if got := IntervalLabel(unit, count); got != want {
+			t.Errorf("%s bills every %s, want every %s", freq, got, want)
+		}
+	}
+
+	for _, freq := range []PayoutFrequency{PayoutFrequencyOnce, PayoutFrequencyDaily} {
+		if _, _, ok := freq.PlanInterval(); ok {
+			t.Errorf("%s should have no plan interval", freq)
+		}
+	}
+}
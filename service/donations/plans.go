@@ -0,0 +1,231 @@
+package donations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidInterval refuses a billing cycle PayPal will not bill on.
+//
+// Checked here rather than left to the provider, so a donor who typed 60 into
+// the count is told what is wrong instead of getting "internal error" back from
+// a plan PayPal refused.
+var ErrInvalidInterval = errors.New("that is not a billing cycle we can set up")
+
+// maxIntervalCounts is the longest cycle PayPal allows in each unit: a year,
+// however it is counted.
+var maxIntervalCounts = map[IntervalUnit]int32{
+	IntervalUnitWeek:  52,
+	IntervalUnitMonth: 12,
+	IntervalUnitYear:  1,
+}
+
+// ValidInterval reports whether a plan can bill every count units.
+func ValidInterval(unit IntervalUnit, count int32) bool {
+	maxCount, ok := maxIntervalCounts[unit]
+
+	return ok && count >= 1 && count <= maxCount
+}
+
+// CreateDonationPlan returns the fund's plan for this amount and billing cycle,
+// creating it at the provider only if the fund does not already have one.
+//
+// Every submission of the recurring form used to create and activate a new
+// billing plan, including the many where the donor then closed PayPal's popup.
+// Most plans at PayPal were therefore ones nobody ever subscribed to. Reusing the
+// catalogue's plan means a fund has one per amount and cycle, however many
+// times the form is opened.
+//
+// The plan is made at plan.ProviderName, or the fund's own provider when that is
+// empty, and under the fund's product there. ProviderFundID is filled in here
+// rather than trusted from the caller, who would otherwise have to know which
+// provider's product to name.
+func (s DonationService) CreateDonationPlan(ctx context.Context, plan CreatePlan) (*DonationPlan, error) {
+	if !ValidInterval(plan.IntervalUnit, plan.IntervalCount) {
+		return nil, ErrInvalidInterval
+	}
+
+	fund, err := s.donationStore.GetFundByID(ctx, plan.FundID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read the fund being changed", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	// A closed fund's plans have been deactivated. Making another would only
+	// leave something new at PayPal for the prune job to find.
+	if fund.Closed() {
+		return nil, ErrFundClosed
+	}
+
+	held, err := s.fundAt(ctx, *fund, plan.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.ProviderName = s.providerName(held.ProviderName)
+	plan.ProviderFundID = held.ProviderID
+
+	provider, err := s.provider(ctx, plan.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.donationStore.ReuseDonationPlan(ctx, plan)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to look up donation plan", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	if existing != nil {
+		return existing, nil
+	}
+
+	providerID, err := provider.CreatePlan(ctx, plan)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to create plan with provider", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	catalogued, err := s.donationStore.CatalogueDonationPlan(ctx, UpsertDonationPlan{
+		ID:             uuid.New(),
+		Name:           plan.Name,
+		ProviderPlanID: providerID,
+		ProviderName:   plan.ProviderName,
+		AmountCents:    plan.AmountCents,
+		IntervalUnit:   plan.IntervalUnit,
+		IntervalCount:  plan.IntervalCount,
+		FundID:         plan.FundID,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to catalogue donation plan", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	if catalogued != nil {
+		return catalogued, nil
+	}
+
+	// Two donors asked for the same plan at once and the other one's was
+	// catalogued first. Theirs is the plan; ours is deactivated rather than left
+	// live at PayPal with nothing here pointing at it. Not deactivating it is
+	// clutter, not harm, so a failure is logged and the donor carries on.
+	if errDeactivate := provider.DeactivatePlan(ctx, providerID); errDeactivate != nil {
+		s.logger.ErrorContext(ctx, "failed to deactivate a duplicate plan",
+			slog.String("provider_plan_id", providerID),
+			slog.String("error", errDeactivate.Error()),
+		)
+	}
+
+	winner, err := s.donationStore.ReuseDonationPlan(ctx, plan)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read the catalogued plan", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	if winner == nil {
+		return nil, errors.New("donation plan disappeared while it was being catalogued")
+	}
+
+	return winner, nil
+}
+
+// ListAbandonedPlans is what PruneAbandonedPlans would deactivate, for the dry
+// run. See GetAbandonedDonationPlans for what counts.
+func (s DonationService) ListAbandonedPlans(ctx context.Context, grace time.Duration) ([]DonationPlan, error) {
+	plans, err := s.donationStore.GetAbandonedDonationPlans(ctx, time.Now().Add(-grace))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get abandoned donation plans", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	return plans, nil
+}
+
+// PruneAbandonedPlans deactivates plans nobody subscribed to and nobody has
+// asked for within the grace period, and any still live on a closed fund.
+//
+// The grace period is there for the donor who has just been handed a plan and
+// is still in PayPal's popup. It is measured from the last time the plan was
+// handed out, not from when it was made, so an old plan a donor has just picked
+// is as safe as a new one.
+//
+// Each is deactivated at the provider before it is marked here, and one that
+// fails is left active to be tried on the next run.
+func (s DonationService) PruneAbandonedPlans(ctx context.Context, grace time.Duration) (int, error) {
+	plans, err := s.ListAbandonedPlans(ctx, grace)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := s.deactivatePlans(ctx, plans)
+
+	s.logger.InfoContext(ctx, "abandoned plan pruning complete",
+		slog.Int("abandoned", len(plans)),
+		slog.Int("pruned", pruned),
+	)
+
+	return pruned, nil
+}
+
+// deactivateFundPlans is the last part of closing a fund. Its subscriptions
+// have been cancelled; its plans would otherwise stay live at PayPal for a fund
+// that takes nothing.
+//
+// Best effort. The fund is closed whatever happens here, and anything left
+// active is picked up by PruneAbandonedPlans.
+func (s DonationService) deactivateFundPlans(ctx context.Context, fundID uuid.UUID) {
+	plans, err := s.donationStore.GetActiveDonationPlansForFund(ctx, fundID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read plans of a closed fund",
+			slog.String("fund_id", fundID.String()),
+			slog.String("error", err.Error()),
+		)
+
+		return
+	}
+
+	s.deactivatePlans(ctx, plans)
+}
+
+func (s DonationService) deactivatePlans(ctx context.Context, plans []DonationPlan) int {
+	deactivated := 0
+	for _, plan := range plans {
+		provider, err := s.provider(ctx, plan.ProviderName)
+		if err != nil {
+			continue
+		}
+
+		if err := provider.DeactivatePlan(ctx, plan.ProviderPlanID); err != nil {
+			s.logger.ErrorContext(ctx, "failed to deactivate plan with provider",
+				slog.String("plan_id", plan.ID.String()),
+				slog.String("provider_plan_id", plan.ProviderPlanID),
+				slog.String("error", err.Error()),
+			)
+
+			continue
+		}
+
+		if _, err := s.donationStore.SetDonationPlanInactive(ctx, plan.ID); err != nil {
+			s.logger.ErrorContext(ctx, "failed to mark plan inactive",
+				slog.String("plan_id", plan.ID.String()),
+				slog.String("error", err.Error()),
+			)
+
+			continue
+		}
+
+		deactivated++
+	}
+
+	return deactivated
+}
@@ -0,0 +1,221 @@
+package donations_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"boardfund/pg"
+	"boardfund/service/donations"
+	donationsstore "boardfund/service/donations/store"
+	"boardfund/service/fundevents"
+	fundeventstore "boardfund/service/fundevents/store"
+	"boardfund/service/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlansAreCataloguedAndPruned(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	store := donationsstore.NewDonationStore(pool)
+	events := fundevents.NewService(fundeventstore.NewEventStore(pool), logger)
+
+	newProvider := func() *mocks.PaymentsProviderMock {
+		return &mocks.PaymentsProviderMock{
+			CreateFundFunc: func(context.Context, string, string) (string, error) {
+				return uuid.NewString(), nil
+			},
+			CreatePlanFunc: func(context.Context, donations.CreatePlan) (string, error) {
+				return "PROVIDER-PLAN-" + uuid.NewString(), nil
+			},
+			DeactivatePlanFunc: func(context.Context, string) error {
+				return nil
+			},
+			CancelSubscriptionsFunc: func(_ context.Context, ids []string) ([]string, error) {
+				return ids, nil
+			},
+		}
+	}
+
+	setup := func(t *testing.T, provider *mocks.PaymentsProviderMock) (*donations.DonationService, uuid.UUID) {
+		t.Helper()
+
+		svc := donations.NewDonationService(store, stubDocumentStorage{}, newFakeBucket(), provider, events, nil, logger)
+
+		fund, errFund := svc.CreateFund(ctx, donations.Fund{
+			Name: uuid.NewString(), Description: "d",
+			PayoutFrequency: donations.PayoutFrequencyMonthly,
+		}, nil)
+		require.NoError(t, errFund)
+
+		return svc, fund.ID
+	}
+
+	monthly := func(fundID uuid.UUID, cents int32) donations.CreatePlan {
+		return donations.CreatePlan{
+			Name: uuid.NewString(), AmountCents: cents,
+			IntervalUnit: donations.IntervalUnitMonth, IntervalCount: 1, FundID: fundID,
+		}
+	}
+
+	t.Run("the same amount and cycle reuses the fund's plan", func(t *testing.T) {
+		provider := newProvider()
+		svc, fundID := setup(t, provider)
+
+		first, err := svc.CreateDonationPlan(ctx, monthly(fundID, 1000))
+		require.NoError(t, err)
+
+		again, err := svc.CreateDonationPlan(ctx, monthly(fundID, 1000))
+		require.NoError(t, err)
+
+		assert.Equal(t, first.ID, again.ID)
+		assert.Equal(t, first.ProviderPlanID, again.ProviderPlanID)
+		assert.Len(t, provider.CreatePlanCalls(), 1, "a second plan was created at the provider")
+
+		// A different cycle is a different plan.
+		quarterly := monthly(fundID, 1000)
+		quarterly.IntervalCount = 3
+
+		other, err := svc.CreateDonationPlan(ctx, quarterly)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ID, other.ID)
+	})
+
+	t.Run("each fund has its own plans", func(t *testing.T) {
+		provider := newProvider()
+		svc, firstFund := setup(t, provider)
+		_, secondFund := setup(t, provider)
+
+		first, err := svc.CreateDonationPlan(ctx, monthly(firstFund, 2000))
+		require.NoError(t, err)
+
+		second, err := svc.CreateDonationPlan(ctx, monthly(secondFund, 2000))
+		require.NoError(t, err)
+
+		assert.NotEqual(t, first.ID, second.ID)
+
+		// The first fund's plan still belongs to it. The old upsert moved it.
+		reread, err := svc.CreateDonationPlan(ctx, monthly(firstFund, 2000))
+		require.NoError(t, err)
+		assert.Equal(t, firstFund, reread.FundID)
+		assert.Equal(t, first.ID, reread.ID)
+	})
+
+	t.Run("a cycle PayPal will not bill is refused before it is asked", func(t *testing.T) {
+		provider := newProvider()
+		svc, fundID := setup(t, provider)
+
+		yearly := monthly(fundID, 1000)
+		yearly.IntervalUnit = donations.IntervalUnitYear
+		yearly.IntervalCount = 2
+
+		_, err := svc.CreateDonationPlan(ctx, yearly)
+		require.ErrorIs(t, err, donations.ErrInvalidInterval)
+		assert.Empty(t, provider.CreatePlanCalls())
+	})
+
+	t.Run("an abandoned plan is pruned and a subscribed one is not", func(t *testing.T) {
+		provider := newProvider()
+		svc, fundID := setup(t, provider)
+
+		abandoned, err := svc.CreateDonationPlan(ctx, monthly(fundID, 3000))
+		require.NoError(t, err)
+
+		subscribed, err := svc.CreateDonationPlan(ctx, monthly(fundID, 4000))
+		require.NoError(t, err)
+
+		_, err = pool.Exec(ctx,
+			`INSERT INTO donation (id, donor_id, fund_id, recurring, donation_plan_id, provider_order_id, provider_subscription_id)
+			 VALUES ($1, $2, $3, true, $4, $5, $6)`,
+			uuid.New(), seedMemberRow(t, ctx, pool), fundID, subscribed.ID, uuid.NewString(), "I-"+uuid.NewString())
+		require.NoError(t, err)
+
+		// Both were handed out a moment ago, so neither is past a day's grace.
+		waiting, err := svc.ListAbandonedPlans(ctx, 24*time.Hour)
+		require.NoError(t, err)
+		for _, plan := range waiting {
+			assert.NotEqual(t, abandoned.ID, plan.ID, "a plan inside its grace period was listed")
+		}
+
+		pruned, err := svc.PruneAbandonedPlans(ctx, -time.Minute)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, pruned, 1)
+
+		var deactivated []string
+		for _, call := range provider.DeactivatePlanCalls() {
+			deactivated = append(deactivated, call.PlanID)
+		}
+
+		assert.Contains(t, deactivated, abandoned.ProviderPlanID)
+		assert.NotContains(t, deactivated, subscribed.ProviderPlanID)
+
+		// Asking for a pruned plan again brings it back with a new provider plan.
+		revived, err := svc.CreateDonationPlan(ctx, monthly(fundID, 3000))
+		require.NoError(t, err)
+		assert.Equal(t, abandoned.ID, revived.ID)
+		assert.True(t, revived.Active)
+		assert.NotEqual(t, abandoned.ProviderPlanID, revived.ProviderPlanID)
+	})
+
+	t.Run("a plan that fails to deactivate is left for the next run", func(t *testing.T) {
+		provider := newProvider()
+		svc, fundID := setup(t, provider)
+
+		plan, err := svc.CreateDonationPlan(ctx, monthly(fundID, 5000))
+		require.NoError(t, err)
+
+		provider.DeactivatePlanFunc = func(_ context.Context, planID string) error {
+			if planID == plan.ProviderPlanID {
+				return assert.AnError
+			}
+
+			return nil
+		}
+
+		_, err = svc.PruneAbandonedPlans(ctx, -time.Minute)
+		require.NoError(t, err)
+
+		still, err := svc.ListAbandonedPlans(ctx, -time.Minute)
+		require.NoError(t, err)
+
+		var found bool
+		for _, p := range still {
+			found = found || p.ID == plan.ID
+		}
+
+		assert.True(t, found, "a plan still live at PayPal was marked inactive")
+	})
+}
+
+func TestValidInterval(t *testing.T) {
+	for _, c := range []struct {
+		unit  donations.IntervalUnit
+		count int32
+		want  bool
+	}{
+		{donations.IntervalUnitWeek, 1, true},
+		{donations.IntervalUnitWeek, 52, true},
+		{donations.IntervalUnitWeek, 53, false},
+		{donations.IntervalUnitMonth, 12, true},
+		{donations.IntervalUnitMonth, 13, false},
+		{donations.IntervalUnitYear, 1, true},
+		{donations.IntervalUnitYear, 2, false},
+		{donations.IntervalUnitMonth, 0, false},
+		{"DAY", 1, false},
+	} {
+		if got := donations.ValidInterval(c.unit, c.count); got != c.want {
+			t.Errorf("ValidInterval(%s, %d) = %v, want %v", c.unit, c.count, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package donations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// AddProvider lets donors pay through another provider as well as the one the
+// service was built with.
+//
+// A setter rather than a constructor argument, like the broker's RecordOutcomes:
+// the service is complete with PayPal alone, which is how every command and test
+// builds it, and a second provider is something start-up adds only when it has
+// been configured.
+func (s *DonationService) AddProvider(name string, provider PaymentsProvider) {
+	s.payments.Register(name, provider)
+}
+
+// PaymentProviders is the providers a donor can choose between, PayPal first.
+func (s DonationService) PaymentProviders() []string {
+	return s.payments.Names()
+}
+
+// provider is the one a fund, plan or donation names. A row naming a provider
+// this process was not configured with is an error rather than a quiet fall back
+// to PayPal, which would cancel, revise or look up an id PayPal has never seen.
+func (s DonationService) provider(ctx context.Context, name string) (PaymentsProvider, error) {
+	provider, err := s.payments.For(name)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "no payments provider by that name", slog.String("provider_name", name))
+
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// providerName is name, or the primary when a value built before there was a
+// choice left it empty. Rows written since carry one; the store fills it in.
+func (s DonationService) providerName(name string) string {
+	if name == "" {
+		return s.payments.Primary()
+	}
+
+	return name
+}
+
+// fundAt is fund as providerName knows it: its ProviderID is the fund's product
+// there, created the first time a donor chooses that provider.
+//
+// A fund is created at one provider, and fund.provider_id is its product there.
+// Every other provider's product is recorded in fund_provider, lazily, so a fund
+// nobody pays through Stripe has nothing in Stripe's dashboard.
+//
+// Two donors choosing the same new provider at once both create a product, and
+// the second to record it finds the first's and uses that. The spare product
+// costs nothing and is left alone: nothing refers to it.
+func (s DonationService) fundAt(ctx context.Context, fund Fund, providerName string) (Fund, error) {
+	if providerName == "" || providerName == fund.ProviderName {
+		return fund, nil
+	}
+
+	provider, err := s.provider(ctx, providerName)
+	if err != nil {
+		return Fund{}, err
+	}
+
+	existing, err := s.donationStore.GetFundProviderID(ctx, fund.ID, providerName)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read the fund's product at a provider",
+			slog.String("provider_name", providerName),
+			slog.String("error", err.Error()),
+		)
+
+		return Fund{}, err
+	}
+
+	if existing == "" {
+		created, errCreate := provider.CreateFund(ctx, fund.Name, fund.Description)
+		if errCreate != nil {
+			s.logger.ErrorContext(ctx, "failed to create the fund at a provider",
+				slog.String("provider_name", providerName),
+				slog.String("error", errCreate.Error()),
+			)
+
+			return Fund{}, errCreate
+		}
+
+		existing, err = s.donationStore.InsertFundProvider(ctx, fund.ID, providerName, created)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to record the fund's product at a provider",
+				slog.String("provider_name", providerName),
+				slog.String("error", err.Error()),
+			)
+
+			return Fund{}, err
+		}
+
+		if existing == "" {
+			existing, err = s.donationStore.GetFundProviderID(ctx, fund.ID, providerName)
+			if err != nil {
+				return Fund{}, err
+			}
+		}
+
+		if existing == "" {
+			return Fund{}, errors.New("fund's product at the provider disappeared while it was being recorded")
+		}
+	}
+
+	fund.ProviderName = providerName
+	fund.ProviderID = existing
+
+	return fund, nil
+}
+
+// DonationStart is a one-time donation about to be paid for.
+//
+// ProviderName is the donor's choice, and empty means the fund's own provider.
+// The URLs are where a provider with a checkout page of its own sends the donor
+// back to, and are the caller's because only the web layer knows its routes.
+// PayPal's buttons never leave our page and do not use them.
+type DonationStart struct {
+	FundID       uuid.UUID
+	AmountCents  int32
+	ProviderName string
+	ReturnURL    string
+	CancelURL    string
+}
+
+// InitiateDonation starts a one-time donation at the provider the donor chose.
+func (s DonationService) InitiateDonation(ctx context.Context, start DonationStart) (*Checkout, error) {
+	fund, err := s.donationStore.GetFundByID(ctx, start.FundID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get fund by id", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	held, err := s.fundAt(ctx, *fund, start.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.provider(ctx, held.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+
+	checkout, err := provider.InitiateDonation(ctx, held, start.AmountCents, start.ReturnURL, start.CancelURL)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to initiate donation with provider", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	return checkout, nil
+}
+
+// InitiateSubscription starts a subscription to plan at the provider the plan
+// was created at. The plan comes from CreateDonationPlan, which is where the
+// donor's choice of provider was made.
+func (s DonationService) InitiateSubscription(ctx context.Context, plan DonationPlan, returnURL, cancelURL string) (*Checkout, error) {
+	provider, err := s.provider(ctx, plan.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+
+	checkout, err := provider.InitiateSubscription(ctx, plan, returnURL, cancelURL)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to initiate subscription with provider",
+			slog.String("plan_id", plan.ID.String()),
+			slog.String("error", err.Error()),
+		)
+
+		return nil, err
+	}
+
+	return checkout, nil
+}
@@ -0,0 +1,224 @@
+package donations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"boardfund/service/fundevents"
+
+	"github.com/google/uuid"
+)
+
+// NewReplayHandlers is NewHandlers for running stored webhooks through again,
+// with every change the callbacks make passed to report.
+//
+// The callbacks are the live ones, unchanged: a replay through different code
+// would say nothing about what the live handlers did, or will do with the next
+// delivery. What differs is the store under them. A write that changes nothing
+// -- a payment already recorded, a refund total already set -- is not
+// reported, so replaying a week that was handled correctly reports nothing.
+//
+// With dryRun the writes are not made. What each would have changed is read
+// instead and reported, the predicted result is handed back to the callback in
+// place of the real one, and no fund events are recorded. Each prediction is
+// made against what is stored now, not against the predictions before it: a
+// dry run over a payment and its refund, neither of them recorded, reports the
+// payment and is silent about the refund, which finds no payment to refund.
+func NewReplayHandlers(store donationStore, events eventRecorder, dryRun bool, report func(change string), logger *slog.Logger) *Handlers {
+	if dryRun {
+		events = discardedEvents{}
+	}
+
+	return NewHandlers(replayStore{donationStore: store, dryRun: dryRun, report: report}, events, logger)
+}
+
+type discardedEvents struct{}
+
+func (discardedEvents) Record(context.Context, fundevents.Record) {}
+
+// replayStore overrides the six writes the webhook callbacks make. Everything
+// else, the reads included, goes straight to the store it wraps.
+type replayStore struct {
+	donationStore
+
+	dryRun bool
+	report func(change string)
+}
+
+func (s replayStore) InsertDonationPayment(ctx context.Context, payment InsertDonationPayment) (*DonationPayment, error) {
+	change := fmt.Sprintf("payment %s: %s recorded against donation %s",
+		payment.ProviderPaymentID, dollarDescription(payment.AmountCents), payment.DonationID)
+
+	if !s.dryRun {
+		recorded, err := s.donationStore.InsertDonationPayment(ctx, payment)
+		if recorded != nil {
+			s.report(change)
+		}
+
+		return recorded, err
+	}
+
+	existing, err := s.donationStore.GetPaymentByProviderPaymentID(ctx, payment.ProviderPaymentID)
+	if err != nil || existing != nil {
+		return nil, err
+	}
+
+	s.report(change)
+
+	return &DonationPayment{
+		ID:                payment.ID,
+		DonationID:        payment.DonationID,
+		ProviderPaymentID: payment.ProviderPaymentID,
+		AmountCents:       payment.AmountCents,
+		ProviderFeeCents:  payment.ProviderFeeCents,
+	}, nil
+}
+
+func (s replayStore) SetDonationPaymentRefunded(ctx context.Context, providerPaymentID string, refundedCents int32) (*RefundedPayment, error) {
+	var refunded *RefundedPayment
+
+	if s.dryRun {
+		existing, err := s.donationStore.GetPaymentByProviderPaymentID(ctx, providerPaymentID)
+		if err != nil {
+			return nil, err
+		}
+
+		// The same two ways the update itself matches nothing.
+		if existing != nil && existing.RefundedCents != refundedCents {
+			refunded = existing
+			refunded.RefundedCents = refundedCents
+		}
+	} else {
+		var err error
+		if refunded, err = s.donationStore.SetDonationPaymentRefunded(ctx, providerPaymentID, refundedCents); err != nil {
+			return nil, err
+		}
+	}
+
+	if refunded != nil {
+		s.report(fmt.Sprintf("payment %s: refunded total %s -> %s",
+			providerPaymentID, dollarDescription(refunded.PreviouslyRefundedCents), dollarDescription(refunded.RefundedCents)))
+	}
+
+	return refunded, nil
+}
+
+func (s replayStore) SetDonationPaymentDispute(ctx context.Context, arg SetPaymentDispute) (*DisputedPayment, error) {
+	var disputed *DisputedPayment
+
+	if s.dryRun {
+		existing, err := s.donationStore.GetPaymentDisputeByProviderPaymentID(ctx, arg.ProviderPaymentID)
+		if err != nil {
+			return nil, err
+		}
+
+		// The update's own guard: an unknown payment, or one already carrying
+		// this update or a later one.
+		if existing != nil && (existing.UpdatedAt.IsZero() || existing.UpdatedAt.Before(arg.UpdatedAt)) {
+			disputed = existing
+			disputed.PreviousState = existing.State
+			disputed.DisputeID = arg.DisputeID
+			disputed.State = arg.State
+			disputed.DisputedCents = min(arg.DisputedCents, existing.AmountCents)
+			disputed.UpdatedAt = arg.UpdatedAt
+		}
+	} else {
+		var err error
+		if disputed, err = s.donationStore.SetDonationPaymentDispute(ctx, arg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Reported only when the state moves, as the feed is. An update that
+	// rewrites an open dispute as open is PayPal's bookkeeping, not a change.
+	if disputed != nil && disputed.State != disputed.PreviousState {
+		previous := string(disputed.PreviousState)
+		if previous == "" {
+			previous = "undisputed"
+		}
+
+		s.report(fmt.Sprintf("payment %s: dispute %s %s -> %s, %s",
+			arg.ProviderPaymentID, arg.DisputeID, previous, disputed.State, dollarDescription(disputed.DisputedCents)))
+	}
+
+	return disputed, nil
+}
+
+// SetDonationToInactiveBySubscriptionID is the one write that reports success
+// whether or not it changed anything, so the donation is read first in both
+// modes. Deactivating one that is already inactive rewrites its reason and
+// nothing else, which is not reported: the donation was off and stays off.
+func (s replayStore) SetDonationToInactiveBySubscriptionID(ctx context.Context, arg DeactivateDonationBySubscription) (*Donation, error) {
+	before, err := s.donationStore.GetDonationByProviderSubscriptionID(ctx, arg.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	after := before
+	if !s.dryRun {
+		if after, err = s.donationStore.SetDonationToInactiveBySubscriptionID(ctx, arg); err != nil {
+			return nil, err
+		}
+	}
+
+	if before.Active {
+		s.report(fmt.Sprintf("donation %s (subscription %s): active -> inactive, %s",
+			before.ID, arg.SubscriptionID, strings.ToLower(arg.Reason)))
+	}
+
+	return after, nil
+}
+
+func (s replayStore) ReactivateSuspendedDonation(ctx context.Context, subscriptionID string) (*Donation, error) {
+	var resumed *Donation
+	var err error
+
+	if s.dryRun {
+		resumed, err = s.donationStore.GetSuspendedDonationBySubscriptionID(ctx, subscriptionID)
+	} else {
+		resumed, err = s.donationStore.ReactivateSuspendedDonation(ctx, subscriptionID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resumed != nil {
+		s.report(fmt.Sprintf("donation %s (subscription %s): suspended -> active", resumed.ID, subscriptionID))
+	}
+
+	return resumed, nil
+}
+
+func (s replayStore) SetDonationPlanForDonation(ctx context.Context, donationID, planID uuid.UUID) (*Donation, error) {
+	var moved *Donation
+
+	if s.dryRun {
+		// The callback has already compared the plans, so the donation is read
+		// only to hand back what the update would have returned.
+		donation, err := s.donationStore.GetDonationByID(ctx, donationID)
+		if err != nil {
+			return nil, err
+		}
+
+		moved = donation
+		moved.DonationPlanID = uuid.NullUUID{UUID: planID, Valid: true}
+	} else {
+		var err error
+		if moved, err = s.donationStore.SetDonationPlanForDonation(ctx, donationID, planID); err != nil || moved == nil {
+			return nil, err
+		}
+	}
+
+	plan, err := s.donationStore.GetDonationPlanByID(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.report(fmt.Sprintf("donation %s: now %s every %s",
+		donationID, dollarDescription(plan.AmountCents), IntervalLabel(plan.IntervalUnit, plan.IntervalCount)))
+
+	return moved, nil
+}
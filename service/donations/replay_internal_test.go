@@ -0,0 +1,80 @@
+package donations
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"boardfund/service/fundevents"
+
+	"github.com/google/uuid"
+)
+
+// replayFakeStore embeds the interface, so a write the dry run should not make
+// is a nil panic rather than a quiet success.
+type replayFakeStore struct {
+	donationStore
+
+	payment *RefundedPayment
+}
+
+func (f replayFakeStore) GetPaymentByProviderPaymentID(context.Context, string) (*RefundedPayment, error) {
+	if f.payment == nil {
+		return nil, nil
+	}
+
+	payment := *f.payment
+
+	return &payment, nil
+}
+
+type failingRecorder struct{ t *testing.T }
+
+func (r failingRecorder) Record(context.Context, fundevents.Record) {
+	r.t.Error("a dry run recorded a fund event")
+}
+
+func dryRunRefund(t *testing.T, store replayFakeStore, payload string) []string {
+	t.Helper()
+
+	var changes []string
+	h := NewReplayHandlers(store, failingRecorder{t: t}, true, func(change string) {
+		changes = append(changes, change)
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := h.paymentRefunded([]byte(payload)); err != nil {
+		t.Fatalf("paymentRefunded: %v", err)
+	}
+
+	return changes
+}
+
+// The refund is predicted from what is stored, through the live callback, and
+// nothing is written or recorded.
+func TestADryRunRefundReportsTheNewTotal(t *testing.T) {
+	store := replayFakeStore{payment: &RefundedPayment{
+		PaymentID:   uuid.New(),
+		DonationID:  uuid.New(),
+		AmountCents: 2000,
+	}}
+
+	changes := dryRunRefund(t, store, `{"sale_id":"SALE-1","total_refunded_amount":{"value":"5.00"}}`)
+
+	if len(changes) != 1 || changes[0] != "payment SALE-1: refunded total $0.00 -> $5.00" {
+		t.Errorf("changes = %q", changes)
+	}
+}
+
+// A refund already applied, and one against somebody else's sale, change
+// nothing and so report nothing -- the same two cases the update skips.
+func TestADryRunReportsNothingTheLiveRunWouldNotChange(t *testing.T) {
+	applied := replayFakeStore{payment: &RefundedPayment{AmountCents: 2000, RefundedCents: 500}}
+	if changes := dryRunRefund(t, applied, `{"sale_id":"SALE-1","total_refunded_amount":{"value":"5.00"}}`); len(changes) != 0 {
+		t.Errorf("an applied refund reported %q", changes)
+	}
+
+	if changes := dryRunRefund(t, replayFakeStore{}, `{"sale_id":"SALE-2","total_refunded_amount":{"value":"5.00"}}`); len(changes) != 0 {
+		t.Errorf("an unknown payment reported %q", changes)
+	}
+}
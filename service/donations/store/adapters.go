@@ -2,6 +2,7 @@ package store
 
 import (
 	"boardfund/db"
+	"boardfund/providers"
 	"boardfund/service/donations"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -16,6 +17,7 @@ func fromDBFundRow(fund db.GetActiveFundsRow) donations.Fund {
 		Active:           fund.Active,
 		PayoutFrequency:  donations.PayoutFrequency(fund.PayoutFrequency),
 		EnrolleesVisible: fund.EnrolleesVisible,
+		CloseOnGoal:      fund.CloseOnGoal,
 		NextPayment:      fund.NextPayment.Time,
 		GoalCents:        fund.GoalCents.Int32,
 		Created:          fund.Created.Time,
@@ -46,6 +48,7 @@ func fromDBFundByID(fund db.GetFundByIdRow) donations.Fund {
 		Active:           fund.Active,
 		PayoutFrequency:  donations.PayoutFrequency(fund.PayoutFrequency),
 		EnrolleesVisible: fund.EnrolleesVisible,
+		CloseOnGoal:      fund.CloseOnGoal,
 		NextPayment:      fund.NextPayment.Time,
 		GoalCents:        fund.GoalCents.Int32,
 		Created:          fund.Created.Time,
@@ -76,6 +79,7 @@ func fromDBFund(fund db.Fund) donations.Fund {
 		Active:           fund.Active,
 		PayoutFrequency:  donations.PayoutFrequency(fund.PayoutFrequency),
 		EnrolleesVisible: fund.EnrolleesVisible,
+		CloseOnGoal:      fund.CloseOnGoal,
 		NextPayment:      fund.NextPayment.Time,
 		GoalCents:        fund.GoalCents.Int32,
 		Created:          fund.Created.Time,
@@ -112,6 +116,7 @@ func toDBFundInsertParams(fund donations.InsertFund) db.InsertFundParams {
 		},
 		PayoutFrequency:  db.PayoutFrequency(fund.PayoutFrequency),
 		EnrolleesVisible: fund.EnrolleesVisible,
+		CloseOnGoal:      fund.CloseOnGoal,
 		Principal:        fund.Principal,
 	}
 
@@ -143,6 +148,7 @@ func toDBFundUpdateParams(fund donations.UpdateFund) db.UpdateFundParams {
 		// column unconditionally, so an unset field here would turn recipient
 		// names off on every save of anything else on the fund.
 		EnrolleesVisible: fund.EnrolleesVisible,
+		CloseOnGoal:      fund.CloseOnGoal,
 	}
 
 	if fund.Expires != nil {
@@ -162,6 +168,7 @@ func fromDBDonationPlan(plan db.DonationPlan) donations.DonationPlan {
 		ID:             plan.ID,
 		Name:           plan.Name,
 		ProviderPlanID: plan.PaypalPlanID.String,
+		ProviderName:   plan.ProviderName,
 		AmountCents:    plan.AmountCents,
 		IntervalUnit:   donations.IntervalUnit(plan.IntervalUnit),
 		IntervalCount:  plan.IntervalCount,
@@ -172,8 +179,8 @@ func fromDBDonationPlan(plan db.DonationPlan) donations.DonationPlan {
 	}
 }
 
-func toDBDonationPlanUpsertParams(plan donations.UpsertDonationPlan) db.UpsertDonationPlanParams {
-	return db.UpsertDonationPlanParams{
+func toDBCatalogueDonationPlanParams(plan donations.UpsertDonationPlan) db.CatalogueDonationPlanParams {
+	return db.CatalogueDonationPlanParams{
 		PaypalPlanID: pgtype.Text{
 			String: plan.ProviderPlanID,
 			Valid:  true,
@@ -184,10 +191,21 @@ func toDBDonationPlanUpsertParams(plan donations.UpsertDonationPlan) db.UpsertDo
 		AmountCents:   plan.AmountCents,
 		IntervalUnit:  db.IntervalUnit(plan.IntervalUnit),
 		IntervalCount: plan.IntervalCount,
-		Active:        plan.Active,
+		ProviderName:  storedProviderName(plan.ProviderName),
 	}
 }
 
+// storedProviderName is the name written for a row that did not say. The column
+// defaults to PayPal for the same reason: everything before there was a choice
+// was PayPal's, and a caller that predates the choice still means it.
+func storedProviderName(name string) string {
+	if name == "" {
+		return providers.PayPal
+	}
+
+	return name
+}
+
 func fromDBDonation(donation db.Donation) donations.Donation {
 	donationOut := donations.Donation{
 		ID:                     donation.ID,
@@ -198,6 +216,7 @@ func fromDBDonation(donation db.Donation) donations.Donation {
 		Active:                 donation.Active,
 		Created:                donation.Created.Time,
 		Updated:                donation.Updated.Time,
+		ProviderName:           donation.ProviderName,
 		ProviderOrderID:        donation.ProviderOrderID,
 		ProviderSubscriptionID: donation.ProviderSubscriptionID.String,
 	}
@@ -213,6 +232,7 @@ func toDBDonationInsertParams(donation donations.InsertDonation) db.InsertDonati
 		FundID:          donation.FundID,
 		ProviderOrderID: donation.ProviderOrderID,
 		DonationPlanID:  donation.PlanID,
+		ProviderName:    storedProviderName(donation.ProviderName),
 	}
 
 	if donation.ProviderSubscriptionID == "" {
@@ -236,6 +256,8 @@ func fromDBDonationPayment(payment db.DonationPayment) donations.DonationPayment
 		ProviderPaymentID: payment.PaypalPaymentID,
 		AmountCents:       payment.AmountCents,
 		ProviderFeeCents:  payment.ProviderFeeCents,
+		DisputeState:      disputeState(payment.DisputeState),
+		DisputedCents:     payment.DisputedCents,
 		Created:           payment.Created.Time,
 		Updated:           payment.Updated.Time,
 	}
@@ -305,6 +327,7 @@ func fromDBAllFundsRow(fund db.GetAllFundsWithStatsRow) donations.Fund {
 		Active:           fund.Active,
 		PayoutFrequency:  donations.PayoutFrequency(fund.PayoutFrequency),
 		EnrolleesVisible: fund.EnrolleesVisible,
+		CloseOnGoal:      fund.CloseOnGoal,
 		NextPayment:      fund.NextPayment.Time,
 		GoalCents:        fund.GoalCents.Int32,
 		Created:          fund.Created.Time,
@@ -335,6 +358,7 @@ func fromDBClosedFundRow(fund db.GetClosedFundsWithStatsRow) donations.ClosedFun
 		Active:           fund.Active,
 		PayoutFrequency:  donations.PayoutFrequency(fund.PayoutFrequency),
 		EnrolleesVisible: fund.EnrolleesVisible,
+		CloseOnGoal:      fund.CloseOnGoal,
 		NextPayment:      fund.NextPayment.Time,
 		GoalCents:        fund.GoalCents.Int32,
 		Created:          fund.Created.Time,
@@ -390,3 +414,12 @@ func fromDBPayoutStats(stats db.GetFundPayoutStatsRow) donations.PayoutStats {
 func fromDBExpiredFund(fund db.GetExpiredActiveFundsRow) donations.Fund {
 	return donations.Fund{ID: fund.ID, Name: fund.Name}
 }
+
+func fromDBGoalReachedFund(fund db.GetGoalReachedActiveFundsRow) donations.Fund {
+	return donations.Fund{
+		ID:        fund.ID,
+		Name:      fund.Name,
+		GoalCents: fund.GoalCents.Int32,
+		Stats:     donations.FundStats{TotalDonated: fund.CollectedCents},
+	}
+}
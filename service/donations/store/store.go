@@ -181,10 +181,93 @@ func (s DonationStore) InsertFund(ctx context.Context, fund donations.InsertFund
 	return pg.CreateOne(ctx, fund, query, toDBFundInsertParams, fromDBFund)
 }
 
-func (s DonationStore) UpsertDonationPlan(ctx context.Context, plan donations.UpsertDonationPlan) (*donations.DonationPlan, error) {
-	query := s.queries.UpsertDonationPlan
+// GetFundProviderID is the fund's product at providerName, or empty when no
+// donor has used that provider for it yet. Only providers other than the fund's
+// own are recorded here; its own is fund.provider_id.
+func (s DonationStore) GetFundProviderID(ctx context.Context, fundID uuid.UUID, providerName string) (string, error) {
+	rows, err := s.queries.GetFundProviderId(ctx, db.GetFundProviderIdParams{
+		FundID:       fundID,
+		ProviderName: providerName,
+	})
+	if err != nil {
+		return "", err
+	}
 
-	return pg.UpsertOne(ctx, plan, query, toDBDonationPlanUpsertParams, fromDBDonationPlan)
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	return rows[0], nil
+}
+
+// InsertFundProvider records the fund's product at providerName, and returns
+// empty when another request recorded one first.
+func (s DonationStore) InsertFundProvider(ctx context.Context, fundID uuid.UUID, providerName, providerID string) (string, error) {
+	rows, err := s.queries.InsertFundProvider(ctx, db.InsertFundProviderParams{
+		FundID:       fundID,
+		ProviderName: providerName,
+		ProviderID:   providerID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	return rows[0], nil
+}
+
+// ReuseDonationPlan returns nil when the fund has no live plan for the amount
+// and cycle, which is the ordinary state of the first donor to ask for it.
+func (s DonationStore) ReuseDonationPlan(ctx context.Context, plan donations.CreatePlan) (*donations.DonationPlan, error) {
+	row, err := s.queries.ReuseDonationPlan(ctx, db.ReuseDonationPlanParams{
+		FundID:        plan.FundID,
+		AmountCents:   plan.AmountCents,
+		IntervalUnit:  db.IntervalUnit(plan.IntervalUnit),
+		IntervalCount: plan.IntervalCount,
+		ProviderName:  storedProviderName(plan.ProviderName),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	reused := fromDBDonationPlan(row)
+
+	return &reused, nil
+}
+
+// CatalogueDonationPlan returns nil when a live plan for the same amount and
+// cycle got there first.
+func (s DonationStore) CatalogueDonationPlan(ctx context.Context, plan donations.UpsertDonationPlan) (*donations.DonationPlan, error) {
+	query := s.queries.CatalogueDonationPlan
+
+	return pg.CreateOneIfNew(ctx, plan, query, toDBCatalogueDonationPlanParams, fromDBDonationPlan)
+}
+
+func (s DonationStore) GetActiveDonationPlansForFund(ctx context.Context, fundID uuid.UUID) ([]donations.DonationPlan, error) {
+	query := s.queries.GetActiveDonationPlansForFund
+
+	return pg.FetchMany(ctx, fundID, query, uuidIdentity, fromDBDonationPlan)
+}
+
+func (s DonationStore) GetAbandonedDonationPlans(ctx context.Context, unusedSince time.Time) ([]donations.DonationPlan, error) {
+	query := s.queries.GetAbandonedDonationPlans
+
+	toTimestamptz := func(t time.Time) pgtype.Timestamptz { return pgtype.Timestamptz{Time: t, Valid: true} }
+
+	return pg.FetchMany(ctx, unusedSince, query, toTimestamptz, fromDBDonationPlan)
+}
+
+func (s DonationStore) SetDonationPlanInactive(ctx context.Context, id uuid.UUID) (*donations.DonationPlan, error) {
+	query := s.queries.SetDonationPlanInactive
+
+	return pg.UpdateOne(ctx, id, query, uuidIdentity, fromDBDonationPlan)
 }
 
 func (s DonationStore) InsertDonationWithPayment(ctx context.Context, donation donations.InsertDonation, payment donations.InsertDonationPayment) (*donations.Donation, error) {
@@ -215,6 +298,10 @@ func (s DonationStore) InsertDonationWithPayment(ctx context.Context, donation d
 		return nil, donations.ErrPaymentAlreadyRecorded
 	}
 
+	if err = closeUnmatchedPayment(ctx, txQueries, *paymentOut); err != nil {
+		return nil, err
+	}
+
 	donationOut.Payment = paymentOut
 
 	err = tx.Commit(ctx)
@@ -273,6 +360,7 @@ func (s DonationStore) GetDonationsForDonor(ctx context.Context, donorID uuid.UU
 			TotalGivenCents: row.TotalGivenCents,
 			PlanAmountCents: row.PlanAmountCents.Int32,
 			PlanInterval:    string(row.PlanIntervalUnit.IntervalUnit),
+			PlanCount:       row.PlanIntervalCount.Int32,
 			Started:         row.Created.Time,
 			LastPayment:     timePtr(row.LastPaymentAt),
 		}))
@@ -345,12 +433,192 @@ func (s DonationStore) SetDonationPaymentRefunded(ctx context.Context, providerP
 	}, nil
 }
 
+// SetDonationPaymentDispute records a dispute as the provider last described it.
+// Returns nil when the payment is unknown, or already carries this update or a
+// later one -- a redelivery, or a webhook overtaken by the next.
+func (s DonationStore) SetDonationPaymentDispute(ctx context.Context, arg donations.SetPaymentDispute) (*donations.DisputedPayment, error) {
+	rows, err := s.queries.SetDonationPaymentDispute(ctx, db.SetDonationPaymentDisputeParams{
+		PaypalPaymentID: arg.ProviderPaymentID,
+		DisputeID:       pgtype.Text{String: arg.DisputeID, Valid: arg.DisputeID != ""},
+		DisputeState:    db.PaymentDisputeState(arg.State),
+		DisputedCents:   arg.DisputedCents,
+		DisputeReason:   pgtype.Text{String: arg.Reason, Valid: arg.Reason != ""},
+		UpdateTime:      pgtype.Timestamptz{Time: arg.UpdatedAt, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	row := rows[0]
+
+	return &donations.DisputedPayment{
+		PaymentID:     row.PaymentID,
+		DonationID:    row.DonationID,
+		FundID:        row.FundID,
+		DonorID:       row.DonorID,
+		AmountCents:   row.AmountCents,
+		DisputeID:     row.DisputeID.String,
+		State:         disputeState(row.DisputeState),
+		DisputedCents: row.DisputedCents,
+		PreviousState: disputeState(row.PreviousDisputeState),
+		UpdatedAt:     arg.UpdatedAt,
+	}, nil
+}
+
+// GetPaymentDisputeByProviderPaymentID is a payment's dispute as it stands, or
+// nil when the payment is unknown. A payment never disputed comes back with an
+// empty state.
+func (s DonationStore) GetPaymentDisputeByProviderPaymentID(ctx context.Context, providerPaymentID string) (*donations.DisputedPayment, error) {
+	rows, err := s.queries.GetDonationPaymentDispute(ctx, providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	row := rows[0]
+
+	return &donations.DisputedPayment{
+		PaymentID:     row.PaymentID,
+		DonationID:    row.DonationID,
+		FundID:        row.FundID,
+		DonorID:       row.DonorID,
+		AmountCents:   row.AmountCents,
+		DisputeID:     row.DisputeID.String,
+		State:         disputeState(row.DisputeState),
+		DisputedCents: row.DisputedCents,
+		UpdatedAt:     row.DisputeUpdatedAt.Time,
+	}, nil
+}
+
+// GetSuspendedDonationBySubscriptionID is the donation ReactivateSuspendedDonation
+// would bring back, or nil when it would bring back nothing.
+func (s DonationStore) GetSuspendedDonationBySubscriptionID(ctx context.Context, subscriptionID string) (*donations.Donation, error) {
+	rows, err := s.queries.GetSuspendedDonationBySubscriptionId(ctx,
+		pgtype.Text{String: subscriptionID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	donation := fromDBDonation(rows[0])
+
+	return &donation, nil
+}
+
+// GetPaymentByProviderPaymentID is a payment as it stands, in the shape a refund
+// reports, or nil when the provider's id is not one of ours. Nothing is newly
+// refunded: the previous total is the current one.
+func (s DonationStore) GetPaymentByProviderPaymentID(ctx context.Context, providerPaymentID string) (*donations.RefundedPayment, error) {
+	rows, err := s.queries.GetDonationPaymentByProviderPaymentId(ctx, providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	row := rows[0]
+
+	return &donations.RefundedPayment{
+		PaymentID:               row.PaymentID,
+		DonationID:              row.DonationID,
+		FundID:                  row.FundID,
+		DonorID:                 row.DonorID,
+		AmountCents:             row.AmountCents,
+		RefundedCents:           row.RefundedCents,
+		PreviouslyRefundedCents: row.RefundedCents,
+	}, nil
+}
+
+// GetDonationPlanByProviderPlanID is the plan the provider knows by this id, or
+// nil when it is not one of ours.
+func (s DonationStore) GetDonationPlanByProviderPlanID(ctx context.Context, providerPlanID string) (*donations.DonationPlan, error) {
+	rows, err := s.queries.GetDonationPlanByProviderPlanId(ctx,
+		pgtype.Text{String: providerPlanID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	plan := fromDBDonationPlan(rows[0])
+
+	return &plan, nil
+}
+
+// SetDonationPlanForDonation moves a donation onto a plan. Returns nil when the
+// donation is unknown or already on it.
+func (s DonationStore) SetDonationPlanForDonation(ctx context.Context, donationID, planID uuid.UUID) (*donations.Donation, error) {
+	rows, err := s.queries.SetDonationPlanForDonation(ctx, db.SetDonationPlanForDonationParams{
+		ID:             donationID,
+		DonationPlanID: uuid.NullUUID{UUID: planID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	donation := fromDBDonation(rows[0])
+
+	return &donation, nil
+}
+
 func (s DonationStore) InsertDonationPayment(ctx context.Context, payment donations.InsertDonationPayment) (*donations.DonationPayment, error) {
-	query := s.queries.InsertDonationPayment
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	txQueries := s.queries.WithTx(tx)
 
 	// Returns nil, nil when the provider payment is already recorded. Callers
 	// must treat that as "nothing more to do" rather than as a payment.
-	return pg.CreateOneIfNew(ctx, payment, query, toDBDonationPaymentInsertParams, fromDBDonationPayment)
+	paymentOut, err := pg.CreateOneIfNew(ctx, payment, txQueries.InsertDonationPayment, toDBDonationPaymentInsertParams, fromDBDonationPayment)
+	if err != nil || paymentOut == nil {
+		return nil, err
+	}
+
+	if err = closeUnmatchedPayment(ctx, txQueries, *paymentOut); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return paymentOut, nil
+}
+
+// closeUnmatchedPayment takes a payment off the unmatched list once it has been
+// recorded. Without it a payment the import gave up on, and a late webhook then
+// recorded, stayed on the admin page, where assigning it could only fail: the
+// payment was already on record.
+//
+// Not called by AssignUnmatchedPayment, which closes the row itself and needs
+// its guard to find the row still open.
+func closeUnmatchedPayment(ctx context.Context, queries *db.Queries, payment donations.DonationPayment) error {
+	return queries.CloseUnmatchedPayment(ctx, db.CloseUnmatchedPaymentParams{
+		ProviderPaymentID: payment.ProviderPaymentID,
+		DonationID:        uuid.NullUUID{UUID: payment.DonationID, Valid: true},
+	})
 }
 
 func (s DonationStore) GetDonationPaymentByID(ctx context.Context, id uuid.UUID) (*donations.DonationPayment, error) {
@@ -414,6 +682,10 @@ func (s DonationStore) GetExpiredActiveFunds(ctx context.Context) ([]donations.F
 	return pg.FetchAll(ctx, s.queries.GetExpiredActiveFunds, fromDBExpiredFund)
 }
 
+func (s DonationStore) GetGoalReachedActiveFunds(ctx context.Context) ([]donations.Fund, error) {
+	return pg.FetchAll(ctx, s.queries.GetGoalReachedActiveFunds, fromDBGoalReachedFund)
+}
+
 func (s DonationStore) GetFundPayoutStats(ctx context.Context, fundID uuid.UUID) (donations.PayoutStats, error) {
 	return pg.FetchScalar(ctx, fundID, s.queries.GetFundPayoutStats, fromDBPayoutStats)
 }
@@ -451,6 +723,9 @@ func (s DonationStore) GetFundPaymentsForAudit(ctx context.Context, fundID uuid.
 			ProviderStatus:      row.ProviderStatus.String,
 			ProviderAmountCents: row.ProviderAmountCents.Int32,
 			ReconciledAt:        reconciledAt(row.ReconciledAt),
+			DisputeState:        disputeState(row.DisputeState),
+			DisputedCents:       row.DisputedCents,
+			DisputeReason:       row.DisputeReason.String,
 			Created:             row.Created.Time,
 		})
 	}
@@ -458,6 +733,168 @@ func (s DonationStore) GetFundPaymentsForAudit(ctx context.Context, fundID uuid.
 	return out, nil
 }
 
+func (s DonationStore) DonationPaymentExists(ctx context.Context, providerPaymentID string) (bool, error) {
+	return s.queries.DonationPaymentExists(ctx, providerPaymentID)
+}
+
+// FindMemberByPayer is nil, not an error, when the payer is nobody we know.
+func (s DonationStore) FindMemberByPayer(ctx context.Context, payerID, email string) (*uuid.UUID, error) {
+	id, err := s.queries.FindMemberByPayer(ctx, db.FindMemberByPayerParams{
+		PayerID: payerID,
+		Email:   email,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// InsertUnmatchedPayment is nil when an earlier import already set the payment
+// aside.
+func (s DonationStore) InsertUnmatchedPayment(ctx context.Context, payment finance.InsertUnmatchedPayment) (*finance.UnmatchedPayment, error) {
+	rows, err := s.queries.InsertUnmatchedPayment(ctx, db.InsertUnmatchedPaymentParams{
+		ID:                payment.ID,
+		ProviderName:      payment.ProviderName,
+		ProviderPaymentID: payment.ProviderPaymentID,
+		ProviderOrderID:   optionalText(payment.ProviderOrderID),
+		AmountCents:       payment.AmountCents,
+		ProviderFeeCents:  payment.FeeCents,
+		PayerEmail:        optionalText(payment.PayerEmail),
+		PayerName:         optionalText(payment.PayerName),
+		FundID:            nullUUID(payment.FundID),
+		Reason:            payment.Reason,
+		OccurredAt:        pgtype.Timestamptz{Time: payment.OccurredAt, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	out := fromDBUnmatchedPayment(db.GetOpenUnmatchedPaymentsRow{
+		ID:                rows[0].ID,
+		ProviderName:      rows[0].ProviderName,
+		ProviderPaymentID: rows[0].ProviderPaymentID,
+		ProviderOrderID:   rows[0].ProviderOrderID,
+		AmountCents:       rows[0].AmountCents,
+		ProviderFeeCents:  rows[0].ProviderFeeCents,
+		PayerEmail:        rows[0].PayerEmail,
+		PayerName:         rows[0].PayerName,
+		FundID:            rows[0].FundID,
+		Reason:            rows[0].Reason,
+		OccurredAt:        rows[0].OccurredAt,
+	})
+
+	return &out, nil
+}
+
+func (s DonationStore) GetOpenUnmatchedPayments(ctx context.Context) ([]finance.UnmatchedPayment, error) {
+	return pg.FetchAll(ctx, s.queries.GetOpenUnmatchedPayments, fromDBUnmatchedPayment)
+}
+
+func (s DonationStore) GetUnmatchedPaymentByID(ctx context.Context, id uuid.UUID) (*finance.UnmatchedPayment, error) {
+	toOut := func(row db.GetUnmatchedPaymentByIdRow) finance.UnmatchedPayment {
+		return fromDBUnmatchedPayment(db.GetOpenUnmatchedPaymentsRow(row))
+	}
+
+	return pg.FetchOne(ctx, id, s.queries.GetUnmatchedPaymentById, uuidIdentity, toOut)
+}
+
+// AssignUnmatchedPayment records the donation and marks the payment assigned to
+// it, or does neither.
+//
+// The donation goes in first because the unmatched row points at it. The guard
+// on assigned_at is what stops a second admin, and it is checked before commit,
+// so the loser's donation is rolled back with it.
+func (s DonationStore) AssignUnmatchedPayment(ctx context.Context, arg finance.AssignUnmatchedPayment) (*donations.Donation, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	txQueries := s.queries.WithTx(tx)
+
+	donationOut, err := pg.CreateOne(ctx, arg.Donation, txQueries.InsertDonation, toDBDonationInsertParams, fromDBDonation)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentOut, err := pg.CreateOneIfNew(ctx, arg.Payment, txQueries.InsertDonationPayment, toDBDonationPaymentInsertParams, fromDBDonationPayment)
+	if err != nil {
+		return nil, err
+	}
+
+	if paymentOut == nil {
+		return nil, donations.ErrPaymentAlreadyRecorded
+	}
+
+	_, err = txQueries.SetUnmatchedPaymentAssigned(ctx, db.SetUnmatchedPaymentAssignedParams{
+		ID:         arg.ID,
+		DonationID: uuid.NullUUID{UUID: donationOut.ID, Valid: true},
+		AssignedBy: uuid.NullUUID{UUID: arg.AssignedBy, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, finance.ErrUnmatchedPaymentAssigned
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	donationOut.Payment = paymentOut
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return donationOut, nil
+}
+
+func fromDBUnmatchedPayment(row db.GetOpenUnmatchedPaymentsRow) finance.UnmatchedPayment {
+	out := finance.UnmatchedPayment{
+		ID:                row.ID,
+		ProviderName:      row.ProviderName,
+		ProviderPaymentID: row.ProviderPaymentID,
+		ProviderOrderID:   row.ProviderOrderID.String,
+		AmountCents:       row.AmountCents,
+		FeeCents:          row.ProviderFeeCents,
+		PayerEmail:        row.PayerEmail.String,
+		PayerName:         row.PayerName.String,
+		FundName:          row.FundName.String,
+		Reason:            row.Reason,
+		OccurredAt:        row.OccurredAt.Time,
+	}
+
+	if row.FundID.Valid {
+		out.FundID = &row.FundID.UUID
+	}
+
+	return out
+}
+
+// optionalText stores an empty string as NULL, which is what the provider not
+// saying looks like.
+func optionalText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
 func nullText(s *string) pgtype.Text {
 	if s == nil {
 		return pgtype.Text{}
@@ -474,6 +911,15 @@ func nullInt32(n *int32) pgtype.Int4 {
 	return pgtype.Int4{Int32: *n, Valid: true}
 }
 
+// disputeState is the empty state for a payment nobody has disputed.
+func disputeState(state db.NullPaymentDisputeState) donations.DisputeState {
+	if !state.Valid {
+		return ""
+	}
+
+	return donations.DisputeState(state.PaymentDisputeState)
+}
+
 // reconciledAt distinguishes "never checked" from "checked", which is the whole
 // point of the column.
 func reconciledAt(t db.NullDBTime) *time.Time {
@@ -0,0 +1,266 @@
+package donations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"boardfund/messaging"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Stripe's events, read into the same calls PayPal's are. Each handler here
+// only translates: which payment, which subscription, how much, in cents. What
+// is then done about it is the PayPal handler's own code, so the two providers
+// cannot drift apart on what a refund or a dispute does to a fund.
+//
+// Payments are keyed by payment intent throughout. That is the id a Checkout
+// session, an invoice and a charge all point to, and the one GetOrder records a
+// one-time donation's payment under.
+
+// stripeInvoice is the part of a Stripe invoice the handlers read.
+type stripeInvoice struct {
+	ID                string `json:"id"`
+	Subscription      string `json:"subscription"`
+	PaymentIntent     string `json:"payment_intent"`
+	AmountPaid        int32  `json:"amount_paid"`
+	AttemptCount      int    `json:"attempt_count"`
+	Created           int64  `json:"created"`
+	StatusTransitions struct {
+		PaidAt int64 `json:"paid_at"`
+	} `json:"status_transitions"`
+}
+
+type stripeSubscription struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	CanceledAt int64  `json:"canceled_at"`
+	EndedAt    int64  `json:"ended_at"`
+	Items      struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+type stripeCharge struct {
+	ID             string `json:"id"`
+	PaymentIntent  string `json:"payment_intent"`
+	AmountRefunded int32  `json:"amount_refunded"`
+}
+
+type stripeDispute struct {
+	ID            string `json:"id"`
+	PaymentIntent string `json:"payment_intent"`
+	Amount        int32  `json:"amount"`
+	Reason        string `json:"reason"`
+	Status        string `json:"status"`
+	Created       int64  `json:"created"`
+}
+
+// unixOr is a Stripe timestamp, or fallback when Stripe did not set one.
+func unixOr(seconds int64, fallback time.Time) time.Time {
+	if seconds == 0 {
+		return fallback
+	}
+
+	return time.Unix(seconds, 0).UTC()
+}
+
+func (h *Handlers) subscribeStripe(subscriber subscriber) error {
+	var errResult error
+
+	handlers := map[string]func([]byte) error{
+		messaging.StripeInvoicePaid:          h.stripeInvoicePaid,
+		messaging.StripeInvoicePaymentFailed: h.stripeInvoicePaymentFailed,
+		messaging.StripeSubscriptionDeleted:  h.stripeSubscriptionEnded,
+		messaging.StripeSubscriptionPaused:   h.stripeSubscriptionEnded,
+		messaging.StripeSubscriptionUpdated:  h.stripeSubscriptionUpdated,
+		messaging.StripeChargeRefunded:       h.stripeChargeRefunded,
+		messaging.StripeDisputeCreated:       h.stripeDispute,
+		messaging.StripeDisputeUpdated:       h.stripeDispute,
+		messaging.StripeDisputeClosed:        h.stripeDispute,
+	}
+
+	for event, handler := range handlers {
+		if err := subscriber.Subscribe(event, handler); err != nil {
+			errResult = multierror.Append(errResult, fmt.Errorf("failed to subscribe to %s: %w", event, err))
+		}
+	}
+
+	return errResult
+}
+
+// stripeInvoicePaid is a subscription's payment. Stripe does not put its fee on
+// the invoice, so it is recorded as zero and filled in by reconciliation, which
+// reads it off the balance transaction.
+func (h *Handlers) stripeInvoicePaid(data []byte) error {
+	var invoice stripeInvoice
+	if err := json.Unmarshal(data, &invoice); err != nil {
+		h.logger.Error("discarding unparseable stripe invoice", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	// An invoice with no subscription is not a recurring donation's, and a paid
+	// one with no payment intent -- paid from credit -- moved no money.
+	if invoice.Subscription == "" || invoice.PaymentIntent == "" {
+		return nil
+	}
+
+	return h.recordSubscriptionPayment(context.Background(), subscriptionPayment{
+		SubscriptionID: invoice.Subscription,
+		PaymentID:      invoice.PaymentIntent,
+		AmountCents:    invoice.AmountPaid,
+		OccurredAt:     unixOr(invoice.StatusTransitions.PaidAt, unixOr(invoice.Created, time.Now())),
+	})
+}
+
+// stripeInvoicePaymentFailed is a failed attempt at an invoice. Stripe retries
+// the same invoice, so the attempt count is what tells one failure from the
+// next.
+func (h *Handlers) stripeInvoicePaymentFailed(data []byte) error {
+	var invoice stripeInvoice
+	if err := json.Unmarshal(data, &invoice); err != nil {
+		h.logger.Error("discarding unparseable stripe invoice", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	if invoice.Subscription == "" {
+		return nil
+	}
+
+	attempt := unixOr(invoice.Created, time.Now()).Add(time.Duration(invoice.AttemptCount) * time.Second)
+
+	return h.recordPaymentFailed(context.Background(), invoice.Subscription, attempt)
+}
+
+// stripeSubscriptionEnded is a subscription deleted, which is Stripe's word for
+// cancelled, or paused. A paused subscription collects nothing, which is what
+// PayPal's suspended means and is recorded as such.
+func (h *Handlers) stripeSubscriptionEnded(data []byte) error {
+	var subscription stripeSubscription
+	if err := json.Unmarshal(data, &subscription); err != nil {
+		h.logger.Error("discarding unparseable stripe subscription", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	status := "SUSPENDED"
+	if subscription.Status == "canceled" {
+		status = "CANCELLED"
+	}
+
+	endedAt := unixOr(subscription.EndedAt, unixOr(subscription.CanceledAt, time.Now()))
+
+	return h.endSubscription(context.Background(), subscription.ID, status, endedAt)
+}
+
+// stripeSubscriptionUpdated follows a subscription onto a new price. Stripe
+// sends this for every change, as PayPal does, and one still on the donation's
+// price changes nothing.
+func (h *Handlers) stripeSubscriptionUpdated(data []byte) error {
+	var subscription stripeSubscription
+	if err := json.Unmarshal(data, &subscription); err != nil {
+		h.logger.Error("discarding unparseable stripe subscription", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	if len(subscription.Items.Data) == 0 || subscription.Items.Data[0].Price.ID == "" {
+		return nil
+	}
+
+	return h.moveSubscription(context.Background(), subscription.ID, subscription.Items.Data[0].Price.ID, time.Now())
+}
+
+// stripeChargeRefunded carries the charge's running refunded total, which is
+// what recordRefund wants.
+func (h *Handlers) stripeChargeRefunded(data []byte) error {
+	var charge stripeCharge
+	if err := json.Unmarshal(data, &charge); err != nil {
+		h.logger.Error("discarding unparseable stripe charge", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	if charge.PaymentIntent == "" {
+		h.logger.Error("discarding stripe refund with no payment to attribute it to", slog.String("charge_id", charge.ID))
+
+		return nil
+	}
+
+	return h.recordRefund(context.Background(), charge.PaymentIntent, charge.AmountRefunded, time.Now())
+}
+
+// stripeDisputeStates reads a dispute's status. Warnings are an inquiry before
+// a chargeback, with the money not yet taken; they are held anyway, as the
+// PayPal side holds an inquiry, because most become chargebacks. One closed
+// without becoming one is the fund's.
+var stripeDisputeStates = map[string]DisputeState{
+	"won":            DisputeWon,
+	"warning_closed": DisputeWon,
+	"lost":           DisputeLost,
+}
+
+// stripeDispute records a dispute at any stage.
+//
+// Stripe's dispute has no update time, which the store uses to put a dispute's
+// events in order, and only its object is published, not the envelope's time.
+// The time of hearing stands in. The dispute's creation time used to, while it
+// was open, which made every update after the first no newer than the one
+// already recorded, and the store dropped them all.
+//
+// Hearing time orders updates as they arrive, so a late copy of an opening could
+// reopen a dispute already decided. Stripe never reopens one: a closed dispute
+// is final, and an open status for it is ignored.
+func (h *Handlers) stripeDispute(data []byte) error {
+	ctx := context.Background()
+
+	var dispute stripeDispute
+	if err := json.Unmarshal(data, &dispute); err != nil {
+		h.logger.Error("discarding unparseable stripe dispute", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	if dispute.PaymentIntent == "" {
+		h.logger.Error("discarding stripe dispute with no payment to attribute it to", slog.String("dispute_id", dispute.ID))
+
+		return nil
+	}
+
+	state, closed := stripeDisputeStates[dispute.Status]
+	if !closed {
+		state = DisputeOpen
+
+		recorded, err := h.donationStore.GetPaymentDisputeByProviderPaymentID(ctx, dispute.PaymentIntent)
+		if err != nil {
+			return fmt.Errorf("failed to read the dispute on payment %s: %w", dispute.PaymentIntent, err)
+		}
+
+		if recorded != nil && recorded.DisputeID == dispute.ID && (recorded.State == DisputeWon || recorded.State == DisputeLost) {
+			h.logger.Info("ignoring an open status for a stripe dispute already closed",
+				slog.String("dispute_id", dispute.ID),
+				slog.String("state", string(recorded.State)),
+			)
+
+			return nil
+		}
+	}
+
+	return h.recordDispute(ctx, providerDispute{
+		ID:            dispute.ID,
+		PaymentIDs:    []string{dispute.PaymentIntent},
+		State:         state,
+		DisputedCents: dispute.Amount,
+		Reason:        dispute.Reason,
+		UpdatedAt:     time.Now(),
+	})
+}
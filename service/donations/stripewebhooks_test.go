@@ -0,0 +1,154 @@
+package donations
+
+import (
+	"testing"
+
+	"boardfund/service/fundevents"
+
+	"github.com/google/uuid"
+)
+
+// A Stripe invoice is a subscription's payment in cents, keyed by its payment
+// intent, and goes through the same recording as a PayPal sale.
+func TestAPaidStripeInvoiceIsARecurringPayment(t *testing.T) {
+	donation := &Donation{ID: uuid.New(), FundID: uuid.New(), DonorID: uuid.New(), Active: true}
+	store := &fakeDonationStore{donation: donation, inserted: &DonationPayment{}}
+	events := &recordedEvents{}
+
+	err := newHandlers(store, events).stripeInvoicePaid([]byte(`{"id":"in_1","subscription":"sub_1",
+		"payment_intent":"pi_1","amount_paid":1500,"status_transitions":{"paid_at":1800000000}}`))
+	if err != nil {
+		t.Fatalf("stripeInvoicePaid: %v", err)
+	}
+
+	if store.calls != 1 || len(events.records) != 1 {
+		t.Fatalf("inserted %d payments and recorded %d events, want 1 and 1", store.calls, len(events.records))
+	}
+
+	record := events.records[0]
+	if record.Kind != fundevents.KindPaymentReceived || *record.AmountCents != 1500 || record.OccurredAt.Unix() != 1800000000 {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+// An invoice outside a subscription is not a recurring donation's. Retrying it
+// would never find one.
+func TestAStripeInvoiceWithNoSubscriptionIsIgnored(t *testing.T) {
+	store := &fakeDonationStore{}
+
+	if err := newHandlers(store, &recordedEvents{}).stripeInvoicePaid([]byte(`{"id":"in_2","payment_intent":"pi_2","amount_paid":100}`)); err != nil {
+		t.Fatalf("stripeInvoicePaid: %v", err)
+	}
+
+	if store.calls != 0 {
+		t.Error("nothing should have been recorded")
+	}
+}
+
+// Stripe's refund total is already in cents and already running, which is what
+// the store takes.
+func TestAStripeRefundIsTheChargesRefundedTotal(t *testing.T) {
+	store := &fakeDonationStore{}
+
+	err := newHandlers(store, &recordedEvents{}).stripeChargeRefunded([]byte(`{"id":"ch_1","payment_intent":"pi_1","amount_refunded":700}`))
+	if err != nil {
+		t.Fatalf("stripeChargeRefunded: %v", err)
+	}
+
+	if store.refundCalls != 1 || store.refundCents != 700 {
+		t.Errorf("refund calls %d for %d cents, want 1 for 700", store.refundCalls, store.refundCents)
+	}
+}
+
+func TestAStripeDisputeStateIsReadFromItsStatus(t *testing.T) {
+	for status, want := range map[string]DisputeState{
+		"warning_needs_response": DisputeOpen,
+		"needs_response":         DisputeOpen,
+		"under_review":           DisputeOpen,
+		"won":                    DisputeWon,
+		"warning_closed":         DisputeWon,
+		"lost":                   DisputeLost,
+	} {
+		store := &fakeDonationStore{}
+
+		err := newHandlers(store, &recordedEvents{}).stripeDispute([]byte(`{"id":"dp_1","payment_intent":"pi_1",
+			"amount":2500,"reason":"fraudulent","created":1800000000,"status":"` + status + `"}`))
+		if err != nil {
+			t.Fatalf("%s: %v", status, err)
+		}
+
+		if len(store.disputeArgs) != 1 {
+			t.Fatalf("%s: recorded %d disputes, want 1", status, len(store.disputeArgs))
+		}
+
+		arg := store.disputeArgs[0]
+		if arg.State != want || arg.ProviderPaymentID != "pi_1" || arg.DisputedCents != 2500 {
+			t.Errorf("%s: recorded %+v, want state %s", status, arg, want)
+		}
+	}
+}
+
+// Every update to an open dispute is newer than the last. Stamped with the
+// dispute's creation time, each was as old as the first and was dropped.
+func TestEachStripeDisputeUpdateIsNewer(t *testing.T) {
+	store := &fakeDonationStore{}
+	h := newHandlers(store, &recordedEvents{})
+
+	_ = h.stripeDispute([]byte(`{"id":"dp_1","payment_intent":"pi_1","amount":2500,"created":1700000000,"status":"needs_response"}`))
+	_ = h.stripeDispute([]byte(`{"id":"dp_1","payment_intent":"pi_1","amount":2500,"created":1700000000,"status":"under_review"}`))
+
+	if len(store.disputeArgs) != 2 || !store.disputeArgs[1].UpdatedAt.After(store.disputeArgs[0].UpdatedAt) {
+		t.Errorf("recorded %+v, want the update newer than the opening", store.disputeArgs)
+	}
+}
+
+// A closed dispute is final at Stripe, so a late copy of its opening is old news
+// rather than a reopening.
+func TestALateOpeningDoesNotReopenAStripeDispute(t *testing.T) {
+	store := &fakeDonationStore{payments: map[string]DisputedPayment{
+		"pi_1": {DisputeID: "dp_1", State: DisputeWon, AmountCents: 2500},
+	}}
+
+	err := newHandlers(store, &recordedEvents{}).
+		stripeDispute([]byte(`{"id":"dp_1","payment_intent":"pi_1","amount":2500,"created":1700000000,"status":"needs_response"}`))
+	if err != nil {
+		t.Fatalf("stripeDispute: %v", err)
+	}
+
+	if len(store.disputeArgs) != 0 {
+		t.Errorf("recorded %+v over a won dispute", store.disputeArgs)
+	}
+}
+
+// Deleted is cancelled; paused collects nothing, which is suspended. Both in
+// PayPal's words, which the local cancellation's dedupe key uses too.
+func TestAnEndedStripeSubscriptionEndsTheDonation(t *testing.T) {
+	for status, want := range map[string]string{"canceled": "CANCELLED", "paused": "SUSPENDED"} {
+		donation := &Donation{ID: uuid.New(), FundID: uuid.New(), DonorID: uuid.New()}
+		events := &recordedEvents{}
+
+		err := newHandlers(&fakeDonationStore{deactivate: donation}, events).
+			stripeSubscriptionEnded([]byte(`{"id":"sub_1","status":"` + status + `","canceled_at":1800000000}`))
+		if err != nil {
+			t.Fatalf("%s: %v", status, err)
+		}
+
+		if len(events.records) != 1 || events.records[0].DedupeKey != subscriptionEndedKey("sub_1", want) {
+			t.Errorf("%s: recorded %+v", status, events.records)
+		}
+	}
+}
+
+// Stripe retries one invoice, so two failures share everything but the attempt.
+func TestEachFailedStripeAttemptIsRecorded(t *testing.T) {
+	donation := &Donation{ID: uuid.New(), FundID: uuid.New(), DonorID: uuid.New()}
+	events := &recordedEvents{}
+	h := newHandlers(&fakeDonationStore{donation: donation}, events)
+
+	_ = h.stripeInvoicePaymentFailed([]byte(`{"id":"in_1","subscription":"sub_1","created":1800000000,"attempt_count":1}`))
+	_ = h.stripeInvoicePaymentFailed([]byte(`{"id":"in_1","subscription":"sub_1","created":1800000000,"attempt_count":2}`))
+
+	if len(events.records) != 2 || events.records[0].DedupeKey == events.records[1].DedupeKey {
+		t.Errorf("two attempts should be two entries: %+v", events.records)
+	}
+}
@@ -1,8 +1,12 @@
 package donations
 
 import (
-	"github.com/google/uuid"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type IntervalUnit string
@@ -11,6 +15,7 @@ type PayoutFrequency string
 const (
 	IntervalUnitWeek  IntervalUnit = "WEEK"
 	IntervalUnitMonth IntervalUnit = "MONTH"
+	IntervalUnitYear  IntervalUnit = "YEAR"
 
 	PayoutFrequencyMonthly PayoutFrequency = "monthly"
 	PayoutFrequencyOnce    PayoutFrequency = "once"
@@ -19,6 +24,13 @@ const (
 	// fund takes a month per period, and a 'once' fund never advances to a second
 	// one, so neither shows that the schedule actually rolls forward.
 	PayoutFrequencyDaily PayoutFrequency = "daily"
+
+	// Rent and utilities are paid weekly or every other week, and some grants
+	// quarterly. Funds covering those had to pick monthly and have an admin
+	// plan the batches in between by hand.
+	PayoutFrequencyWeekly    PayoutFrequency = "weekly"
+	PayoutFrequencyBiweekly  PayoutFrequency = "biweekly"
+	PayoutFrequencyQuarterly PayoutFrequency = "quarterly"
 )
 
 // PayoutFrequencies is every frequency a fund can have.
@@ -29,6 +41,9 @@ const (
 var PayoutFrequencies = []PayoutFrequency{
 	PayoutFrequencyMonthly,
 	PayoutFrequencyDaily,
+	PayoutFrequencyWeekly,
+	PayoutFrequencyBiweekly,
+	PayoutFrequencyQuarterly,
 	PayoutFrequencyOnce,
 }
 
@@ -40,7 +55,69 @@ var PayoutFrequencies = []PayoutFrequency{
 // the next payout date, and donors offered a single payment instead of a
 // subscription.
 func (f PayoutFrequency) Recurring() bool {
-	return f == PayoutFrequencyMonthly || f == PayoutFrequencyDaily
+	months, days := f.step()
+
+	return months > 0 || days > 0
+}
+
+// Known reports whether the frequency is one a fund can have. The column is an
+// enum, so anything else fails at the insert -- after the provider has already
+// been asked to create the fund.
+func (f PayoutFrequency) Known() bool {
+	return slices.Contains(PayoutFrequencies, f)
+}
+
+// step is one period of the schedule, in whole months or in whole days, and
+// zero for a frequency that does not repeat.
+//
+// Weeks are counted as days: seven of them need no clamping, and a fund paying
+// every other Friday should still pay on a Friday after February. Quarters are
+// counted as months for the opposite reason -- a grant paid on the 31st of the
+// quarter's first month clamps where it has to, like a monthly fund does.
+func (f PayoutFrequency) step() (months, days int) {
+	switch f {
+	case PayoutFrequencyDaily:
+		return 0, 1
+	case PayoutFrequencyWeekly:
+		return 0, 7
+	case PayoutFrequencyBiweekly:
+		return 0, 14
+	case PayoutFrequencyMonthly:
+		return 1, 0
+	case PayoutFrequencyQuarterly:
+		return 3, 0
+	}
+
+	return 0, 0
+}
+
+// PlanInterval is the billing cycle that matches the fund's own schedule, for a
+// donor who wants to give once per payout. ok is false where there is no such
+// cycle: a one-off fund takes no subscriptions, and a daily one is for testing
+// and a donor is not charged every day.
+func (f PayoutFrequency) PlanInterval() (unit IntervalUnit, count int32, ok bool) {
+	switch f {
+	case PayoutFrequencyWeekly:
+		return IntervalUnitWeek, 1, true
+	case PayoutFrequencyBiweekly:
+		return IntervalUnitWeek, 2, true
+	case PayoutFrequencyMonthly:
+		return IntervalUnitMonth, 1, true
+	case PayoutFrequencyQuarterly:
+		return IntervalUnitMonth, 3, true
+	}
+
+	return "", 0, false
+}
+
+// IntervalLabel is a billing cycle as a donor reads it: "month", "2 weeks".
+func IntervalLabel(unit IntervalUnit, count int32) string {
+	name := strings.ToLower(string(unit))
+	if count <= 1 {
+		return name
+	}
+
+	return fmt.Sprintf("%d %ss", count, name)
 }
 
 // ProviderOrder is an order as the provider reports it, which is the only
@@ -62,6 +139,23 @@ type ProviderOrder struct {
 	FundReferenceID   string
 	ProviderPaymentID string
 	AmountCents       int32
+
+	// ProviderSubscriptionID is set when the order started a subscription, which
+	// is how a Stripe checkout is completed: the browser comes back with the
+	// session, and the subscription is read off it rather than taken from the
+	// query string. PayPal's buttons create the subscription themselves and
+	// leave this empty.
+	ProviderSubscriptionID string
+}
+
+// Checkout is where a donor is sent to pay.
+//
+// PayPal's buttons run on our page and only need the id -- an order, or the
+// plan the button subscribes to. Stripe's checkout is its own page, so it comes
+// with somewhere to go. The handler looks at RedirectURL to tell them apart.
+type Checkout struct {
+	ID          string
+	RedirectURL string
 }
 
 // MemberDonation is one row of a donor's own donations page.
@@ -78,9 +172,10 @@ type MemberDonation struct {
 	// cancelled here. Shown so a donor who did not cancel can see who did.
 	InactiveReason string
 
-	TotalGivenCents  int64
-	PlanAmountCents  int32
-	PlanIntervalUnit IntervalUnit
+	TotalGivenCents   int64
+	PlanAmountCents   int32
+	PlanIntervalUnit  IntervalUnit
+	PlanIntervalCount int32
 
 	Started     time.Time
 	LastPayment *time.Time
@@ -98,6 +193,13 @@ func (m MemberDonation) Cancellable() bool {
 	return m.Active && m.Recurring && m.hasSubscription
 }
 
+// AmountChangeable reports whether the donor can move this to another amount.
+// Everything Cancellable needs, and an open fund: a closed fund makes no new
+// plans, so the change would be refused after the donor had typed it.
+func (m MemberDonation) AmountChangeable() bool {
+	return m.Cancellable() && m.FundActive
+}
+
 // MemberDonationRow is what a store hands NewMemberDonation. It exists so
 // hasSubscription stays unexported: whether a donation can be cancelled is a rule
 // of this package, not something a caller assembles for itself.
@@ -113,25 +215,27 @@ type MemberDonationRow struct {
 	TotalGivenCents int64
 	PlanAmountCents int32
 	PlanInterval    string
+	PlanCount       int32
 	Started         time.Time
 	LastPayment     *time.Time
 }
 
 func NewMemberDonation(row MemberDonationRow) MemberDonation {
 	return MemberDonation{
-		ID:               row.ID,
-		FundID:           row.FundID,
-		FundName:         row.FundName,
-		FundActive:       row.FundActive,
-		Recurring:        row.Recurring,
-		Active:           row.Active,
-		InactiveReason:   row.InactiveReason,
-		TotalGivenCents:  row.TotalGivenCents,
-		PlanAmountCents:  row.PlanAmountCents,
-		PlanIntervalUnit: IntervalUnit(row.PlanInterval),
-		Started:          row.Started,
-		LastPayment:      row.LastPayment,
-		hasSubscription:  row.HasSubscription,
+		ID:                row.ID,
+		FundID:            row.FundID,
+		FundName:          row.FundName,
+		FundActive:        row.FundActive,
+		Recurring:         row.Recurring,
+		Active:            row.Active,
+		InactiveReason:    row.InactiveReason,
+		TotalGivenCents:   row.TotalGivenCents,
+		PlanAmountCents:   row.PlanAmountCents,
+		PlanIntervalUnit:  IntervalUnit(row.PlanInterval),
+		PlanIntervalCount: row.PlanCount,
+		Started:           row.Started,
+		LastPayment:       row.LastPayment,
+		hasSubscription:   row.HasSubscription,
 	}
 }
 
@@ -196,6 +300,64 @@ func (r RefundEvent) RefundedTotal() string {
 	return r.Amount.Total
 }
 
+// DisputeEvent is the resource on CUSTOMER.DISPUTE.CREATED, .UPDATED and
+// .RESOLVED. Each carries the whole dispute as it stands, so the three are read
+// the same way.
+//
+// A dispute can name several transactions, but PayPal opens one per sale for a
+// subscription payment, and the sale is what we store: seller_transaction_id is
+// the id PAYMENT.SALE.COMPLETED reported.
+type DisputeEvent struct {
+	DisputeID            string    `json:"dispute_id"`
+	CreateTime           time.Time `json:"create_time"`
+	UpdateTime           time.Time `json:"update_time"`
+	Reason               string    `json:"reason"`
+	Status               string    `json:"status"`
+	DisputedTransactions []struct {
+		SellerTransactionID string `json:"seller_transaction_id"`
+	} `json:"disputed_transactions"`
+	DisputeAmount struct {
+		Value string `json:"value"`
+	} `json:"dispute_amount"`
+	DisputeOutcome struct {
+		OutcomeCode string `json:"outcome_code"`
+	} `json:"dispute_outcome"`
+}
+
+// PaymentIDs are the sales this dispute is against.
+func (d DisputeEvent) PaymentIDs() []string {
+	ids := make([]string, 0, len(d.DisputedTransactions))
+	for _, transaction := range d.DisputedTransactions {
+		if transaction.SellerTransactionID != "" {
+			ids = append(ids, transaction.SellerTransactionID)
+		}
+	}
+
+	return ids
+}
+
+// State reads the provider's status and outcome as one of ours, and reports
+// false for an outcome it does not recognise.
+//
+// Every status short of RESOLVED is open, however PayPal describes the stage it
+// has reached: waiting on the buyer, waiting on us, under review, escalated to a
+// claim. For the fund they are one question -- is the money coming back -- and
+// none of them answers it.
+func (d DisputeEvent) State() (DisputeState, bool) {
+	if !strings.EqualFold(d.Status, "RESOLVED") {
+		return DisputeOpen, true
+	}
+
+	switch strings.ToUpper(d.DisputeOutcome.OutcomeCode) {
+	case "RESOLVED_BUYER_FAVOUR", "ACCEPTED":
+		return DisputeLost, true
+	case "RESOLVED_SELLER_FAVOUR", "RESOLVED_WITH_PAYOUT", "CANCELED_BY_BUYER", "DENIED":
+		return DisputeWon, true
+	default:
+		return DisputeOpen, false
+	}
+}
+
 // SetPaymentReconciliation records what the provider said about a payment.
 //
 // The pointers distinguish "the provider told us nothing" from "the provider told
@@ -242,6 +404,58 @@ func (r RefundedPayment) NewlyRefundedCents() int32 {
 	return r.RefundedCents - r.PreviouslyRefundedCents
 }
 
+// DisputeState is where a dispute against a payment stands. Empty is a payment
+// nobody has disputed.
+type DisputeState string
+
+const (
+	DisputeOpen DisputeState = "open"
+	DisputeWon  DisputeState = "won"
+	DisputeLost DisputeState = "lost"
+)
+
+// Held reports whether disputed money is out of the fund's reach. Open, PayPal
+// has taken it pending the outcome; lost, it is not coming back.
+func (s DisputeState) Held() bool {
+	return s == DisputeOpen || s == DisputeLost
+}
+
+// SetPaymentDispute is a dispute as the provider last described it.
+type SetPaymentDispute struct {
+	ProviderPaymentID string
+	DisputeID         string
+	State             DisputeState
+	DisputedCents     int32
+	Reason            string
+
+	// UpdatedAt is the provider's own update time, which orders the dispute's
+	// webhooks however they arrive.
+	UpdatedAt time.Time
+}
+
+// DisputedPayment is what recording a dispute changed, carrying the fund and
+// donor for the activity entry as RefundedPayment does.
+type DisputedPayment struct {
+	PaymentID  uuid.UUID
+	DonationID uuid.UUID
+	FundID     uuid.UUID
+	DonorID    uuid.UUID
+
+	AmountCents   int32
+	DisputeID     string
+	State         DisputeState
+	DisputedCents int32
+
+	// PreviousState is the state before this update, empty when there was no
+	// dispute. The feed records a dispute opening and closing, not every update
+	// in between, and this is how the handler tells them apart.
+	PreviousState DisputeState
+
+	// UpdatedAt is the provider update time of the state recorded. Only read
+	// back, for a dry run to decide whether an update would be stale.
+	UpdatedAt time.Time
+}
+
 type Fund struct {
 	ID              uuid.UUID       `json:"id"`
 	Principal       uuid.NullUUID   `json:"principal"`
@@ -262,6 +476,10 @@ type Fund struct {
 	// rather than a default anybody inherits.
 	EnrolleesVisible bool `json:"enrollees_visible"`
 
+	// CloseOnGoal closes the fund by itself once donors have given its goal,
+	// rather than leaving it to collect past it until somebody notices.
+	CloseOnGoal bool `json:"close_on_goal"`
+
 	Created time.Time `json:"created"`
 	Updated time.Time `json:"updated"`
 	Stats   FundStats `json:"stats"`
@@ -291,14 +509,16 @@ func (f Fund) NextPaymentAfter(now time.Time) time.Time {
 		return f.NextPayment
 	}
 
+	months, days := f.PayoutFrequency.step()
+
 	// Days need no clamping -- every month has a tomorrow -- so the anchor's
-	// time of day carries forward by whole days from the original date.
-	if f.PayoutFrequency == PayoutFrequencyDaily {
-		days := int(now.Sub(f.NextPayment) / (24 * time.Hour))
+	// time of day carries forward by whole periods from the original date.
+	if days > 0 {
+		periods := int(now.Sub(f.NextPayment) / (time.Duration(days) * 24 * time.Hour))
 
-		next := f.NextPayment.AddDate(0, 0, days)
+		next := f.NextPayment.AddDate(0, 0, periods*days)
 		if next.Before(now) {
-			next = f.NextPayment.AddDate(0, 0, days+1)
+			next = f.NextPayment.AddDate(0, 0, (periods+1)*days)
 		}
 
 		return next
@@ -306,11 +526,12 @@ func (f Fund) NextPaymentAfter(now time.Time) time.Time {
 
 	// Computed in one step from the anchor rather than by repeated addition,
 	// which would accumulate the clamping below into real drift.
-	months := (now.Year()-f.NextPayment.Year())*12 + int(now.Month()) - int(f.NextPayment.Month())
+	elapsed := (now.Year()-f.NextPayment.Year())*12 + int(now.Month()) - int(f.NextPayment.Month())
+	periods := elapsed / months
 
-	next := addMonths(f.NextPayment, months)
+	next := addMonths(f.NextPayment, periods*months)
 	if next.Before(now) {
-		next = addMonths(f.NextPayment, months+1)
+		next = addMonths(f.NextPayment, (periods+1)*months)
 	}
 
 	return next
@@ -352,6 +573,7 @@ type InsertFund struct {
 	Expires          *time.Time
 	Principal        uuid.NullUUID
 	EnrolleesVisible bool
+	CloseOnGoal      bool
 }
 
 type UpdateFund struct {
@@ -364,6 +586,7 @@ type UpdateFund struct {
 	Expires          *time.Time
 	Principal        uuid.NullUUID
 	EnrolleesVisible bool
+	CloseOnGoal      bool
 }
 
 type Donation struct {
@@ -375,6 +598,7 @@ type Donation struct {
 	Recurring              bool
 	Active                 bool
 	ProviderID             string
+	ProviderName           string
 	ProviderOrderID        string
 	ProviderSubscriptionID string
 	Payment                *DonationPayment
@@ -408,8 +632,14 @@ type DonationPayment struct {
 	AmountCents         int32
 	ProviderFeeCents    int32
 	MemberProviderEmail string
-	Created             time.Time
-	Updated             time.Time
+
+	// DisputeState is empty for a payment nobody has disputed. DisputedCents is
+	// what the dispute is over, held out of the fund while DisputeState.Held.
+	DisputeState  DisputeState
+	DisputedCents int32
+
+	Created time.Time
+	Updated time.Time
 }
 
 type DonationOrderCapture struct {
@@ -426,6 +656,7 @@ type RecurringCompletion struct {
 	FundID                 uuid.UUID     `json:"fund_id"`
 	ProviderOrderID        string        `json:"provider_order_id"`
 	ProviderSubscriptionID string        `json:"provider_subscription_id"`
+	ProviderName           string        `json:"provider_name"`
 }
 
 type OneTimeCompletion struct {
@@ -440,6 +671,7 @@ type OneTimeCompletion struct {
 	PayerLastName     string
 	ProviderOrderID   string
 	ProviderPaymentID string
+	ProviderName      string
 }
 
 type DonationCompletionResponse struct {
@@ -455,6 +687,7 @@ type DonationPlan struct {
 	ID             uuid.UUID
 	Name           string
 	ProviderPlanID string
+	ProviderName   string
 	AmountCents    int32
 	IntervalUnit   IntervalUnit
 	IntervalCount  int32
@@ -468,17 +701,18 @@ type UpsertDonationPlan struct {
 	ID             uuid.UUID
 	Name           string
 	ProviderPlanID string
+	ProviderName   string
 	FundID         uuid.UUID
 	AmountCents    int32
 	IntervalUnit   IntervalUnit
 	IntervalCount  int32
-	Active         bool
 }
 
 type CreatePlan struct {
 	Name           string       `json:"name"`
 	Description    string       `json:"description"`
 	ProviderFundID string       `json:"product_id"`
+	ProviderName   string       `json:"provider_name"`
 	IntervalUnit   IntervalUnit `json:"interval_unit"`
 	IntervalCount  int32        `json:"interval_count"`
 	AmountCents    int32        `json:"amount_cents"`
@@ -493,6 +727,7 @@ type InsertDonation struct {
 	PlanID                 uuid.NullUUID
 	ProviderOrderID        string
 	ProviderSubscriptionID string
+	ProviderName           string
 }
 
 type UpdateDonation struct {
@@ -0,0 +1,66 @@
+package donations_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"boardfund/pg"
+	"boardfund/service/donations"
+	donationsstore "boardfund/service/donations/store"
+	"boardfund/service/finance"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// A payment the import could not place, recorded afterwards by a webhook that
+// arrived late, is no longer unmatched. Left on the list, it could only be
+// assigned into an error: the payment is already on record.
+func TestARecordedPaymentLeavesTheUnmatchedList(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	store := donationsstore.NewDonationStore(pool)
+
+	fundID := seedOnceFund(t, ctx, pool)
+	donationID := seedDonationRow(t, ctx, pool, fundID)
+	providerPaymentID := uuid.NewString()
+
+	_, err = store.InsertUnmatchedPayment(ctx, finance.InsertUnmatchedPayment{
+		ID:                uuid.New(),
+		ProviderName:      "paypal",
+		ProviderPaymentID: providerPaymentID,
+		AmountCents:       2500,
+		Reason:            "the order names no fund",
+		OccurredAt:        time.Now(),
+	})
+	require.NoError(t, err)
+
+	open, err := store.GetOpenUnmatchedPayments(ctx)
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+
+	recorded, err := store.InsertDonationPayment(ctx, donations.InsertDonationPayment{
+		ID:                uuid.New(),
+		DonationID:        donationID,
+		ProviderPaymentID: providerPaymentID,
+		AmountCents:       2500,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, recorded)
+
+	open, err = store.GetOpenUnmatchedPayments(ctx)
+	require.NoError(t, err)
+	require.Empty(t, open)
+
+	// Kept, pointing at the donation it became, as an assigned one is.
+	var became uuid.UUID
+	require.NoError(t, pool.QueryRow(ctx,
+		`SELECT donation_id FROM unmatched_payment WHERE provider_payment_id = $1`, providerPaymentID).Scan(&became))
+	require.Equal(t, donationID, became)
+}
@@ -5,9 +5,11 @@ import (
 	"boardfund/service/fundevents"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-multierror"
+	"github.com/jackc/pgx/v5"
 	"log/slog"
 	"strconv"
 	"strings"
@@ -62,15 +64,99 @@ func (h *Handlers) Subscribe(subscriber subscriber) error {
 		}
 	}
 
+	// A dispute holds money the fund was counting, so all three are handled by
+	// the one callback: each carries the dispute as it stands, and recording it
+	// is the same whichever stage it reports.
+	for _, event := range []string{messaging.DisputeCreated, messaging.DisputeUpdated, messaging.DisputeResolved} {
+		if err := subscriber.Subscribe(event, h.paymentDisputed); err != nil {
+			errResult = multierror.Append(err, fmt.Errorf("failed to subscribe to %s: %w", event, err))
+		}
+	}
+
 	// A failed payment is not an ended subscription. PayPal retries, and most
 	// recover, so this records and changes nothing.
 	if err := subscriber.Subscribe(messaging.SubscriptionPaymentFailed, h.subscriptionPaymentFailed); err != nil {
 		errResult = multierror.Append(err, fmt.Errorf("failed to subscribe to %s: %w", messaging.SubscriptionPaymentFailed, err))
 	}
 
+	// A donor's new amount, as the provider reports it. The donor's return from
+	// PayPal usually records it first; this is for the donor who approved the
+	// change and closed the tab.
+	if err := subscriber.Subscribe(messaging.SubscriptionUpdated, h.subscriptionUpdated); err != nil {
+		errResult = multierror.Append(err, fmt.Errorf("failed to subscribe to %s: %w", messaging.SubscriptionUpdated, err))
+	}
+
+	// Stripe's are subscribed whether or not Stripe is configured. Nothing is
+	// published under them without it, and a deployment that stops taking
+	// Stripe keeps handling refunds and disputes of what it already took.
+	if err := h.subscribeStripe(subscriber); err != nil {
+		errResult = multierror.Append(errResult, err)
+	}
+
 	return errResult
 }
 
+// subscriptionUpdated moves a donation onto the plan its subscription now pays
+// into.
+//
+// PayPal sends this for any change to a subscription, most of which are not a
+// new plan, so one that names the plan the donation is already on is the usual
+// case and changes nothing. One naming a plan that is not this fund's is logged
+// and acknowledged: it is not something a redelivery will fix.
+func (h *Handlers) subscriptionUpdated(data []byte) error {
+	var event SubscriptionEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		h.logger.Error("discarding unparseable subscription updated event", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	if event.PlanID == "" {
+		return nil
+	}
+
+	return h.moveSubscription(context.Background(), event.ID, event.PlanID, event.UpdateTime)
+}
+
+// moveSubscription is subscriptionUpdated once the provider's event has been
+// read, shared by every provider that reports a subscription's new plan.
+func (h *Handlers) moveSubscription(ctx context.Context, subscriptionID, providerPlanID string, updatedAt time.Time) error {
+	donation, err := h.donationStore.GetDonationByProviderSubscriptionID(ctx, subscriptionID)
+	if err != nil {
+		// Not a subscription we have recorded. Unlike a payment there is nothing to
+		// wait for: a donation recorded later is recorded on the plan the provider
+		// reports at the time.
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to get donation by provider subscription id: %w", err)
+	}
+
+	moved, err := applySubscriptionPlan(ctx, h.donationStore, h.events, *donation, providerPlanID, nil, updatedAt)
+	if errors.Is(err, ErrSubscriptionPlanMismatch) {
+		h.logger.Error("subscription moved to a plan that is not its fund's",
+			slog.String("provider_subscription_id", subscriptionID),
+			slog.String("provider_plan_id", providerPlanID),
+		)
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to move donation %s to plan %s: %w", donation.ID, providerPlanID, err)
+	}
+
+	if moved {
+		h.logger.Info("moved a donation to its subscription's new plan",
+			slog.String("donation_id", donation.ID.String()),
+			slog.String("provider_plan_id", providerPlanID),
+		)
+	}
+
+	return nil
+}
+
 // paymentRefunded records money returned to the donor or taken back by their
 // bank.
 //
@@ -103,7 +189,14 @@ func (h *Handlers) paymentRefunded(data []byte) error {
 		return nil
 	}
 
-	refunded, err := h.donationStore.SetDonationPaymentRefunded(context.Background(), paymentID, refundedCents)
+	return h.recordRefund(context.Background(), paymentID, refundedCents, event.CreateTime)
+}
+
+// recordRefund is paymentRefunded once the provider's event has been read:
+// refundedCents is the payment's running refunded total, which is what both
+// providers report.
+func (h *Handlers) recordRefund(ctx context.Context, paymentID string, refundedCents int32, occurredAt time.Time) error {
+	refunded, err := h.donationStore.SetDonationPaymentRefunded(ctx, paymentID, refundedCents)
 	if err != nil {
 		return fmt.Errorf("failed to record refund for payment %s: %w", paymentID, err)
 	}
@@ -129,10 +222,10 @@ func (h *Handlers) paymentRefunded(data []byte) error {
 	// partial refund would otherwise report the whole refunded sum again.
 	amount := -refunded.NewlyRefundedCents()
 
-	h.events.Record(context.Background(), fundevents.Record{
+	h.events.Record(ctx, fundevents.Record{
 		FundID:          refunded.FundID,
 		Kind:            fundevents.KindPaymentRefunded,
-		OccurredAt:      event.CreateTime,
+		OccurredAt:      occurredAt,
 		SubjectMemberID: &refunded.DonorID,
 		AmountCents:     &amount,
 		Detail:          "refunded at provider",
@@ -142,6 +235,217 @@ func (h *Handlers) paymentRefunded(data []byte) error {
 	return nil
 }
 
+// paymentDisputed records a dispute against a payment: opened, moved along, or
+// resolved.
+//
+// Money under an open dispute is held out of the fund balance, because PayPal
+// has already taken it and may keep it. Without this the planner went on paying
+// it out to enrollees, and a lost chargeback came out of a PayPal balance every
+// other fund shares.
+//
+// The feed records the dispute opening and closing. The updates between -- a
+// message from the buyer, an escalation -- change nothing the fund can act on,
+// and are only written to the payment.
+func (h *Handlers) paymentDisputed(data []byte) error {
+	var event DisputeEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		h.logger.Error("discarding unparseable dispute event", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	paymentIDs := event.PaymentIDs()
+	if len(paymentIDs) == 0 {
+		h.logger.Error("discarding dispute event with no payment to attribute it to",
+			slog.String("dispute_id", event.DisputeID),
+		)
+
+		return nil
+	}
+
+	state, known := event.State()
+	if !known {
+		// Left open rather than guessed at. Holding money that turns out to be
+		// ours delays a payout; releasing money that turns out not to be pays
+		// out what PayPal has taken back.
+		h.logger.Error("dispute resolved with an outcome we do not recognise, keeping it open",
+			slog.String("dispute_id", event.DisputeID),
+			slog.String("outcome_code", event.DisputeOutcome.OutcomeCode),
+		)
+	}
+
+	disputedCents, err := dollarStringToCents(event.DisputeAmount.Value)
+	if err != nil {
+		h.logger.Error("discarding dispute with an unreadable amount",
+			slog.String("dispute_id", event.DisputeID),
+			slog.String("amount", event.DisputeAmount.Value),
+			slog.String("error", err.Error()),
+		)
+
+		return nil
+	}
+
+	updatedAt := event.UpdateTime
+	if updatedAt.IsZero() {
+		updatedAt = event.CreateTime
+	}
+
+	return h.recordDispute(context.Background(), providerDispute{
+		ID:            event.DisputeID,
+		PaymentIDs:    paymentIDs,
+		State:         state,
+		DisputedCents: disputedCents,
+		Reason:        event.Reason,
+		UpdatedAt:     updatedAt,
+	})
+}
+
+// providerDispute is a dispute as any provider describes it, read out of its
+// event.
+type providerDispute struct {
+	ID            string
+	PaymentIDs    []string
+	State         DisputeState
+	DisputedCents int32
+	Reason        string
+	UpdatedAt     time.Time
+}
+
+// recordDispute is paymentDisputed once the provider's event has been read.
+func (h *Handlers) recordDispute(ctx context.Context, dispute providerDispute) error {
+	shares, err := h.disputeShares(ctx, dispute)
+	if err != nil {
+		return err
+	}
+
+	for i, paymentID := range dispute.PaymentIDs {
+		disputed, errDispute := h.donationStore.SetDonationPaymentDispute(ctx, SetPaymentDispute{
+			ProviderPaymentID: paymentID,
+			DisputeID:         dispute.ID,
+			State:             dispute.State,
+			DisputedCents:     shares[i],
+			Reason:            dispute.Reason,
+			UpdatedAt:         dispute.UpdatedAt,
+		})
+		if errDispute != nil {
+			return fmt.Errorf("failed to record dispute %s against payment %s: %w", dispute.ID, paymentID, errDispute)
+		}
+
+		// Nil is the refund handler's two cases again, and one more: the payment
+		// is somebody else's, this is a redelivery, or a later update has already
+		// been recorded and this one arrived behind it.
+		if disputed == nil {
+			h.logger.Info("dispute recorded nothing",
+				slog.String("dispute_id", dispute.ID),
+				slog.String("provider_payment_id", paymentID),
+			)
+
+			continue
+		}
+
+		h.logger.Info("recorded a dispute against a payment",
+			slog.String("dispute_id", dispute.ID),
+			slog.String("provider_payment_id", paymentID),
+			slog.String("state", string(disputed.State)),
+		)
+
+		if record, ok := disputeRecord(*disputed, dispute.Reason); ok {
+			record.OccurredAt = dispute.UpdatedAt
+			h.events.Record(ctx, record)
+		}
+	}
+
+	return nil
+}
+
+// disputeShares divides the disputed amount among the payments the dispute
+// names, in the order it names them: each holds what is left of the amount, up
+// to its own. The amount is the dispute's total, so writing all of it to every
+// payment held it once per payment -- and a dispute larger than any one of them
+// broke the constraint that a payment holds no more than it is worth, failing
+// the event on every delivery.
+//
+// A dispute over one payment needs no lookup; the store caps it at the payment.
+func (h *Handlers) disputeShares(ctx context.Context, dispute providerDispute) ([]int32, error) {
+	shares := make([]int32, len(dispute.PaymentIDs))
+	if len(shares) == 1 {
+		shares[0] = dispute.DisputedCents
+
+		return shares, nil
+	}
+
+	remaining := dispute.DisputedCents
+
+	for i, paymentID := range dispute.PaymentIDs {
+		payment, err := h.donationStore.GetPaymentDisputeByProviderPaymentID(ctx, paymentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payment %s for dispute %s: %w", paymentID, dispute.ID, err)
+		}
+
+		// Somebody else's sale on a shared account takes no share: it will record
+		// nothing, and what it would have held belongs to the payments that will.
+		if payment == nil {
+			continue
+		}
+
+		shares[i] = min(remaining, payment.AmountCents)
+		remaining -= shares[i]
+	}
+
+	return shares, nil
+}
+
+// disputeRecord is the feed entry for a change in a dispute, or false when the
+// state did not change.
+//
+// A dispute first seen already resolved -- its earlier webhooks lost, or sent
+// before this was subscribed -- is recorded as resolved alone. Inventing the
+// opening would put money in the feed twice that only moved once, or never.
+func disputeRecord(disputed DisputedPayment, reason string) (fundevents.Record, bool) {
+	if disputed.State == disputed.PreviousState {
+		return fundevents.Record{}, false
+	}
+
+	record := fundevents.Record{
+		FundID:          disputed.FundID,
+		SubjectMemberID: &disputed.DonorID,
+		ReferenceID:     &disputed.DonationID,
+	}
+
+	held := -disputed.DisputedCents
+	released := disputed.DisputedCents
+
+	switch disputed.State {
+	case DisputeOpen:
+		record.Kind = fundevents.KindPaymentDisputed
+		record.AmountCents = &held
+		record.Detail = "disputed at provider"
+		if reason != "" {
+			record.Detail += ": " + strings.ToLower(strings.ReplaceAll(reason, "_", " "))
+		}
+	case DisputeWon:
+		record.Kind = fundevents.KindPaymentDisputeResolved
+		record.Detail = "dispute resolved in the fund's favour"
+		// Only money that was held comes back. A dispute first heard of already
+		// won never held anything.
+		if disputed.PreviousState == DisputeOpen {
+			record.AmountCents = &released
+		}
+	case DisputeLost:
+		record.Kind = fundevents.KindPaymentDisputeResolved
+		record.Detail = "dispute lost, the money is not coming back"
+		// Lost after being held, nothing further moves. Lost without ever having
+		// been seen open, the money leaves now.
+		if disputed.PreviousState != DisputeOpen {
+			record.AmountCents = &held
+		}
+	default:
+		return fundevents.Record{}, false
+	}
+
+	return record, true
+}
+
 // subscriptionPaymentFailed records a charge that did not go through.
 //
 // Deliberately no state change. Deactivating on one failed payment would cancel
@@ -157,7 +461,14 @@ func (h *Handlers) subscriptionPaymentFailed(data []byte) error {
 		return nil
 	}
 
-	donation, err := h.donationStore.GetDonationByProviderSubscriptionID(context.Background(), event.ID)
+	return h.recordPaymentFailed(context.Background(), event.ID, event.StatusUpdateTime)
+}
+
+// recordPaymentFailed is subscriptionPaymentFailed once the provider's event
+// has been read. occurredAt must differ between one failure and the next, as it
+// is what tells them apart.
+func (h *Handlers) recordPaymentFailed(ctx context.Context, subscriptionID string, occurredAt time.Time) error {
+	donation, err := h.donationStore.GetDonationByProviderSubscriptionID(ctx, subscriptionID)
 	if err != nil {
 		return fmt.Errorf("failed to get donation by provider subscription id: %w", err)
 	}
@@ -165,22 +476,22 @@ func (h *Handlers) subscriptionPaymentFailed(data []byte) error {
 	if donation == nil {
 		// Not ours, or not recorded yet. Retried on the same reasoning as a
 		// payment: the subscription may still be being written.
-		return fmt.Errorf("no donation for provider subscription %s yet", event.ID)
+		return fmt.Errorf("no donation for provider subscription %s yet", subscriptionID)
 	}
 
 	// This handler changes nothing, so there is no state to consult about whether
 	// it has run before. The provider's own update time separates one failure from
 	// the next; keying on the subscription alone would record the first failure
 	// and silently swallow every one after it.
-	h.events.Record(context.Background(), fundevents.Record{
+	h.events.Record(ctx, fundevents.Record{
 		FundID:          donation.FundID,
 		Kind:            fundevents.KindPaymentFailed,
-		OccurredAt:      event.StatusUpdateTime,
+		OccurredAt:      occurredAt,
 		SubjectMemberID: &donation.DonorID,
 		Detail:          "payment failed at provider",
 		ReferenceID:     &donation.ID,
-		DedupeKey: "payment-failed:" + event.ID + ":" +
-			event.StatusUpdateTime.UTC().Format(time.RFC3339Nano),
+		DedupeKey: "payment-failed:" + subscriptionID + ":" +
+			occurredAt.UTC().Format(time.RFC3339Nano),
 	})
 
 	return nil
@@ -196,12 +507,20 @@ func (h *Handlers) subscriptionEnded(data []byte) error {
 		return nil
 	}
 
+	return h.endSubscription(context.Background(), subscriptionEnded.ID, subscriptionEnded.Status, subscriptionEnded.StatusUpdateTime)
+}
+
+// endSubscription is subscriptionEnded once the provider's event has been read.
+// status is in PayPal's words, CANCELLED or SUSPENDED, whichever provider sent
+// it: it is the donation's recorded reason and part of the feed's dedupe key,
+// which the local cancellation paths write in those words too.
+func (h *Handlers) endSubscription(ctx context.Context, subscriptionID, status string, occurredAt time.Time) error {
 	deactivateSub := DeactivateDonationBySubscription{
-		SubscriptionID: subscriptionEnded.ID,
-		Reason:         subscriptionEnded.Status,
+		SubscriptionID: subscriptionID,
+		Reason:         status,
 	}
 
-	donation, err := h.donationStore.SetDonationToInactiveBySubscriptionID(context.Background(), deactivateSub)
+	donation, err := h.donationStore.SetDonationToInactiveBySubscriptionID(ctx, deactivateSub)
 	if err != nil {
 		return fmt.Errorf("failed to deactivate donation by subscription id: %w", err)
 	}
@@ -215,14 +534,14 @@ func (h *Handlers) subscriptionEnded(data []byte) error {
 	// webhook is the provider echoing it back and the key discards it -- the feed
 	// showed one action twice, once as "cancelled by donor" and once as
 	// "subscription cancelled at provider".
-	h.events.Record(context.Background(), fundevents.Record{
+	h.events.Record(ctx, fundevents.Record{
 		FundID:          donation.FundID,
 		Kind:            fundevents.KindDonationCancelled,
-		OccurredAt:      subscriptionEnded.StatusUpdateTime,
+		OccurredAt:      occurredAt,
 		SubjectMemberID: &donation.DonorID,
-		Detail:          "subscription " + strings.ToLower(subscriptionEnded.Status) + " at provider",
+		Detail:          "subscription " + strings.ToLower(status) + " at provider",
 		ReferenceID:     &donation.ID,
-		DedupeKey:       subscriptionEndedKey(subscriptionEnded.ID, subscriptionEnded.Status),
+		DedupeKey:       subscriptionEndedKey(subscriptionID, status),
 	})
 
 	return nil
@@ -236,16 +555,58 @@ func (h *Handlers) paymentSaleCompleted(data []byte) error {
 		return nil
 	}
 
-	parentDonation, err := h.donationStore.GetDonationByProviderSubscriptionID(context.Background(), paymentSale.BillingAgreementID)
+	amountCents, err := dollarStringToCents(paymentSale.Amount.Total)
+	if err != nil {
+		h.logger.Error("discarding payment with an unreadable amount",
+			slog.String("amount", paymentSale.Amount.Total),
+			slog.String("error", err.Error()),
+		)
+
+		return nil
+	}
+
+	feeAmountCents, err := dollarStringToCents(paymentSale.TransactionFee.Value)
+	if err != nil {
+		h.logger.Error("discarding payment with an unreadable fee",
+			slog.String("fee", paymentSale.TransactionFee.Value),
+			slog.String("error", err.Error()),
+		)
+
+		return nil
+	}
+
+	return h.recordSubscriptionPayment(context.Background(), subscriptionPayment{
+		SubscriptionID: paymentSale.BillingAgreementID,
+		PaymentID:      paymentSale.ID,
+		AmountCents:    amountCents,
+		FeeCents:       feeAmountCents,
+		OccurredAt:     paymentSale.CreateTime,
+	})
+}
+
+// subscriptionPayment is a recurring payment as any provider reports it, read
+// out of its event.
+type subscriptionPayment struct {
+	SubscriptionID string
+	PaymentID      string
+	AmountCents    int32
+	FeeCents       int32
+	OccurredAt     time.Time
+}
+
+// recordSubscriptionPayment is paymentSaleCompleted once the provider's event
+// has been read.
+func (h *Handlers) recordSubscriptionPayment(ctx context.Context, payment subscriptionPayment) error {
+	parentDonation, err := h.donationStore.GetDonationByProviderSubscriptionID(ctx, payment.SubscriptionID)
 	if err != nil {
 		return fmt.Errorf("failed to get donation by provider subscription id: %w", err)
 	}
 
 	if parentDonation == nil {
-		// Retried rather than dropped: PayPal can report the first payment before
+		// Retried rather than dropped: the provider can report the first payment before
 		// the browser has finished telling us the subscription exists, and the
 		// money is real either way.
-		return fmt.Errorf("no donation for provider subscription %s yet", paymentSale.BillingAgreementID)
+		return fmt.Errorf("no donation for provider subscription %s yet", payment.SubscriptionID)
 	}
 
 	// A payment against a suspended subscription is the provider telling us it
@@ -259,7 +620,7 @@ func (h *Handlers) paymentSaleCompleted(data []byte) error {
 	// other order leaves the redelivery returning early on the duplicate payment,
 	// having never reached this.
 	if !parentDonation.Active {
-		resumed, errResume := h.donationStore.ReactivateSuspendedDonation(context.Background(), paymentSale.BillingAgreementID)
+		resumed, errResume := h.donationStore.ReactivateSuspendedDonation(ctx, payment.SubscriptionID)
 		if errResume != nil {
 			return fmt.Errorf("failed to reactivate suspended donation: %w", errResume)
 		}
@@ -272,10 +633,10 @@ func (h *Handlers) paymentSaleCompleted(data []byte) error {
 				slog.String("donation_id", resumed.ID.String()),
 			)
 
-			h.events.Record(context.Background(), fundevents.Record{
+			h.events.Record(ctx, fundevents.Record{
 				FundID:          resumed.FundID,
 				Kind:            fundevents.KindDonationResumed,
-				OccurredAt:      paymentSale.CreateTime,
+				OccurredAt:      payment.OccurredAt,
 				SubjectMemberID: &resumed.DonorID,
 				Detail:          "payment received after suspension",
 				ReferenceID:     &resumed.ID,
@@ -283,35 +644,15 @@ func (h *Handlers) paymentSaleCompleted(data []byte) error {
 		}
 	}
 
-	amountCents, err := dollarStringToCents(paymentSale.Amount.Total)
-	if err != nil {
-		h.logger.Error("discarding payment with an unreadable amount",
-			slog.String("amount", paymentSale.Amount.Total),
-			slog.String("error", err.Error()),
-		)
-
-		return nil
-	}
-
-	feeAmountCents, err := dollarStringToCents(paymentSale.TransactionFee.Value)
-	if err != nil {
-		h.logger.Error("discarding payment with an unreadable fee",
-			slog.String("fee", paymentSale.TransactionFee.Value),
-			slog.String("error", err.Error()),
-		)
-
-		return nil
-	}
-
 	insertPayment := InsertDonationPayment{
 		ID:                uuid.New(),
 		DonationID:        parentDonation.ID,
-		ProviderPaymentID: paymentSale.ID,
-		AmountCents:       amountCents,
-		ProviderFeeCents:  feeAmountCents,
+		ProviderPaymentID: payment.PaymentID,
+		AmountCents:       payment.AmountCents,
+		ProviderFeeCents:  payment.FeeCents,
 	}
 
-	recorded, err := h.donationStore.InsertDonationPayment(context.Background(), insertPayment)
+	recorded, err := h.donationStore.InsertDonationPayment(ctx, insertPayment)
 	if err != nil {
 		return fmt.Errorf("failed to insert donation payment: %w", err)
 	}
@@ -321,18 +662,18 @@ func (h *Handlers) paymentSaleCompleted(data []byte) error {
 	// should not show it arriving twice.
 	if recorded == nil {
 		h.logger.Info("payment already recorded, ignoring redelivery",
-			slog.String("provider_payment_id", paymentSale.ID),
+			slog.String("provider_payment_id", payment.PaymentID),
 		)
 
 		return nil
 	}
 
-	h.events.Record(context.Background(), fundevents.Record{
+	h.events.Record(ctx, fundevents.Record{
 		FundID:          parentDonation.FundID,
 		Kind:            fundevents.KindPaymentReceived,
-		OccurredAt:      paymentSale.CreateTime,
+		OccurredAt:      payment.OccurredAt,
 		SubjectMemberID: &parentDonation.DonorID,
-		AmountCents:     &amountCents,
+		AmountCents:     &payment.AmountCents,
 		Detail:          "recurring",
 		ReferenceID:     &parentDonation.ID,
 	})
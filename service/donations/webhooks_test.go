@@ -36,9 +36,34 @@ type fakeDonationStore struct {
 	refundCents int32
 	refundCalls int
 
+	disputed     *DisputedPayment
+	disputeErr   error
+	disputeArgs  []SetPaymentDispute
+	disputeCalls int
+
+	// payments are the payments as a dispute finds them, by provider id.
+	// Absent is a payment we do not have.
+	payments map[string]DisputedPayment
+
 	calls int
 }
 
+func (f *fakeDonationStore) SetDonationPaymentDispute(_ context.Context, arg SetPaymentDispute) (*DisputedPayment, error) {
+	f.disputeCalls++
+	f.disputeArgs = append(f.disputeArgs, arg)
+
+	return f.disputed, f.disputeErr
+}
+
+func (f *fakeDonationStore) GetPaymentDisputeByProviderPaymentID(_ context.Context, providerPaymentID string) (*DisputedPayment, error) {
+	payment, ok := f.payments[providerPaymentID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &payment, nil
+}
+
 func (f *fakeDonationStore) ReactivateSuspendedDonation(context.Context, string) (*Donation, error) {
 	f.resumeCall++
 
@@ -265,8 +290,21 @@ func TestSubscribesToEveryDonationEvent(t *testing.T) {
 		messaging.SubscriptionCancelled,
 		messaging.SubscriptionSuspended,
 		messaging.SubscriptionPaymentFailed,
+		messaging.SubscriptionUpdated,
 		messaging.PaymentRefunded,
 		messaging.PaymentReversed,
+		messaging.DisputeCreated,
+		messaging.DisputeUpdated,
+		messaging.DisputeResolved,
+		messaging.StripeInvoicePaid,
+		messaging.StripeInvoicePaymentFailed,
+		messaging.StripeSubscriptionDeleted,
+		messaging.StripeSubscriptionPaused,
+		messaging.StripeSubscriptionUpdated,
+		messaging.StripeChargeRefunded,
+		messaging.StripeDisputeCreated,
+		messaging.StripeDisputeUpdated,
+		messaging.StripeDisputeClosed,
 	} {
 		if !registered[event] {
 			t.Errorf("%s is never subscribed, so it would accumulate in the stream unread", event)
@@ -48,3 +48,10 @@ func validatePaypalEmail(address string) (string, error) {
 
 	return trimmed, nil
 }
+
+// ValidatePaypalEmail is the check CreateEnrollment makes, for a caller that has
+// to refuse a bad address before doing something it cannot take back --
+// registering an account, say, with the enrollment to follow.
+func ValidatePaypalEmail(address string) (string, error) {
+	return validatePaypalEmail(address)
+}
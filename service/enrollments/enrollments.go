@@ -19,6 +19,7 @@ type fundStore interface {
 type enrollmentStore interface {
 	InsertEnrollment(ctx context.Context, arg InsertEnrollment) (*Enrollment, error)
 	InsertEnrollmentWithPaypalEmail(ctx context.Context, insertEnrollment InsertEnrollment, updatePaypalEmail UpdatePaypalEmail) (*Enrollment, error)
+	GetEnrollmentByID(ctx context.Context, arg uuid.UUID) (*Enrollment, error)
 	GetEnrollmentByMemberID(ctx context.Context, arg GetEnrollmentForFundByMemberID) (*Enrollment, error)
 	FundEnrollmentExists(ctx context.Context, arg FundEnrollmentExists) (*bool, error)
 	GetActiveEnrollmentsForFund(ctx context.Context, arg uuid.UUID) ([]Enrollment, error)
@@ -135,6 +136,17 @@ func (s EnrollmentsService) CreateEnrollment(ctx context.Context, createEnrollme
 	return enrollment, nil
 }
 
+func (s EnrollmentsService) GetEnrollmentByID(ctx context.Context, id uuid.UUID) (*Enrollment, error) {
+	enrollment, err := s.enrollmentStore.GetEnrollmentByID(ctx, id)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get enrollment", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
 func (s EnrollmentsService) GetEnrollmentForFundByMemberID(ctx context.Context, fundID, memberID uuid.UUID) (*Enrollment, error) {
 	arg := GetEnrollmentForFundByMemberID{
 		FundID:   fundID,
@@ -65,6 +65,14 @@ func (s EnrollmentStore) InsertEnrollmentWithPaypalEmail(ctx context.Context, in
 	return enrollment, nil
 }
 
+func (s EnrollmentStore) GetEnrollmentByID(ctx context.Context, arg uuid.UUID) (*enrollments.Enrollment, error) {
+	query := s.queries.GetEnrollmentById
+
+	argIdentity := func(id uuid.UUID) uuid.UUID { return id }
+
+	return pg.FetchOne(ctx, arg, query, argIdentity, fromDBEnrollment)
+}
+
 func (s EnrollmentStore) GetEnrollmentByMemberID(ctx context.Context, arg enrollments.GetEnrollmentForFundByMemberID) (*enrollments.Enrollment, error) {
 	query := s.queries.GetEnrollmentForFundByMemberId
 
@@ -3,6 +3,8 @@ package finance
 import (
 	"testing"
 	"time"
+
+	"boardfund/service/donations"
 )
 
 // The old page compared our amount to the provider's and flagged anything that
@@ -100,3 +102,30 @@ func TestUncheckedIsNotAProblem(t *testing.T) {
 		t.Error("nor should it be reported as agreeing with the provider")
 	}
 }
+
+// An open dispute is waiting on somebody to answer PayPal, and the money is held
+// until they do. That is worth a red mark however reconciliation came out; a
+// dispute settled either way is not.
+func TestAnOpenDisputeNeedsAttention(t *testing.T) {
+	checked := time.Now()
+
+	payment := AuditPayment{
+		AmountCents:         2500,
+		ProviderStatus:      "COMPLETED",
+		ProviderAmountCents: 2500,
+		ReconciledAt:        &checked,
+		DisputeState:        donations.DisputeOpen,
+		DisputedCents:       2500,
+	}
+
+	if !payment.NeedsAttention() {
+		t.Error("an open dispute should be flagged even when the provider agrees on the amount")
+	}
+
+	for _, settled := range []donations.DisputeState{donations.DisputeWon, donations.DisputeLost} {
+		payment.DisputeState = settled
+		if payment.NeedsAttention() {
+			t.Errorf("a dispute %s is over and should not be flagged", settled)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"boardfund/providers"
 	"boardfund/service/donations"
 	"boardfund/service/fundevents"
 
@@ -91,10 +92,10 @@ func (c *capturedEvents) Record(_ context.Context, record fundevents.Record) {
 
 func newService(store donationStore, provider paymentsProvider, events eventRecorder) FinanceService {
 	return FinanceService{
-		donationStore:    store,
-		paymentsProvider: provider,
-		events:           events,
-		logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		donationStore: store,
+		payments:      providers.NewRegistry(providers.PayPal, provider),
+		events:        events,
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
 	}
 }
 
@@ -357,6 +358,9 @@ func TestEveryFrequencyIsAccountedFor(t *testing.T) {
 	for _, frequency := range []donations.PayoutFrequency{
 		donations.PayoutFrequencyMonthly,
 		donations.PayoutFrequencyDaily,
+		donations.PayoutFrequencyWeekly,
+		donations.PayoutFrequencyBiweekly,
+		donations.PayoutFrequencyQuarterly,
 		donations.PayoutFrequencyOnce,
 	} {
 		var found bool
@@ -387,7 +391,7 @@ func TestRecordProviderViewReportsWriteFailures(t *testing.T) {
 		store := &fakeStore{reconcileErr: errors.New("deadlock")}
 
 		err := newService(store, &fakeProvider{}, &capturedEvents{}).
-			recordProviderView(context.Background(), payments, quiet)
+			recordProviderView(context.Background(), &fakeProvider{}, payments, quiet)
 
 		if err == nil {
 			t.Fatal("a run that persisted nothing must not report success")
@@ -404,7 +408,7 @@ func TestRecordProviderViewReportsWriteFailures(t *testing.T) {
 		store := &fakeStore{}
 
 		if err := newService(store, &fakeProvider{}, &capturedEvents{}).
-			recordProviderView(context.Background(), payments, quiet); err != nil {
+			recordProviderView(context.Background(), &fakeProvider{}, payments, quiet); err != nil {
 			t.Errorf("nothing failed, so the run should be clean: %v", err)
 		}
 
@@ -1,6 +1,7 @@
 package finance
 
 import (
+	"boardfund/providers"
 	"boardfund/service/donations"
 	"boardfund/service/fundevents"
 	"context"
@@ -85,6 +86,11 @@ type AuditPayment struct {
 	ProviderAmountCents int32
 	ReconciledAt        *time.Time
 
+	// DisputeState is empty for a payment nobody has disputed.
+	DisputeState  donations.DisputeState
+	DisputedCents int32
+	DisputeReason string
+
 	Created time.Time
 }
 
@@ -112,8 +118,14 @@ func (a AuditPayment) Verdict() AuditVerdict {
 }
 
 // NeedsAttention marks the rows a person should look at. Unchecked is not one of
-// them: it means the job has not got there, not that anything is wrong.
+// them: it means the job has not got there, not that anything is wrong. An open
+// dispute is, whatever reconciliation found: it is waiting on somebody to
+// answer PayPal, and the fund cannot pay the money out until they do.
 func (a AuditPayment) NeedsAttention() bool {
+	if a.DisputeState == donations.DisputeOpen {
+		return true
+	}
+
 	switch a.Verdict() {
 	case AuditAmountMismatch, AuditNotSettled:
 		return true
@@ -140,6 +152,13 @@ type donationStore interface {
 	InsertDonationPayment(ctx context.Context, payment donations.InsertDonationPayment) (*donations.DonationPayment, error)
 	GetFundPaymentsForAudit(ctx context.Context, fundID uuid.UUID) ([]AuditPayment, error)
 	SetPaymentReconciliation(ctx context.Context, arg donations.SetPaymentReconciliation) error
+	DonationPaymentExists(ctx context.Context, providerPaymentID string) (bool, error)
+	FindMemberByPayer(ctx context.Context, payerID, email string) (*uuid.UUID, error)
+	InsertDonationWithPayment(ctx context.Context, donation donations.InsertDonation, payment donations.InsertDonationPayment) (*donations.Donation, error)
+	InsertUnmatchedPayment(ctx context.Context, payment InsertUnmatchedPayment) (*UnmatchedPayment, error)
+	GetOpenUnmatchedPayments(ctx context.Context) ([]UnmatchedPayment, error)
+	GetUnmatchedPaymentByID(ctx context.Context, id uuid.UUID) (*UnmatchedPayment, error)
+	AssignUnmatchedPayment(ctx context.Context, arg AssignUnmatchedPayment) (*donations.Donation, error)
 }
 
 type paymentsProvider interface {
@@ -163,10 +182,10 @@ type eventRecorder interface {
 }
 
 type FinanceService struct {
-	donationStore    donationStore
-	paymentsProvider paymentsProvider
-	documentManager  documentManager
-	events           eventRecorder
+	donationStore   donationStore
+	payments        *providers.Registry[paymentsProvider]
+	documentManager documentManager
+	events          eventRecorder
 
 	reportPrefixes []string
 
@@ -175,15 +194,21 @@ type FinanceService struct {
 
 func NewFinanceService(donationStore donationStore, paymentsProvider paymentsProvider, documentManager documentManager, events eventRecorder, reportPrefixes []string, logger *slog.Logger) *FinanceService {
 	return &FinanceService{
-		donationStore:    donationStore,
-		paymentsProvider: paymentsProvider,
-		documentManager:  documentManager,
-		events:           events,
-		reportPrefixes:   reportPrefixes,
-		logger:           logger,
+		donationStore:   donationStore,
+		payments:        providers.NewRegistry(providers.PayPal, paymentsProvider),
+		documentManager: documentManager,
+		events:          events,
+		reportPrefixes:  reportPrefixes,
+		logger:          logger,
 	}
 }
 
+// AddProvider lets reconciliation ask another provider about the donations it
+// holds. See DonationService.AddProvider.
+func (s *FinanceService) AddProvider(name string, provider paymentsProvider) {
+	s.payments.Register(name, provider)
+}
+
 // GetAudit is a fund's payments, as they stand.
 //
 // It used to fetch a CSV from S3 and parse it by column position -- record[5],
@@ -288,9 +313,16 @@ func (s FinanceService) backfillMissingPayments(ctx context.Context, donation do
 
 	logger := s.logger.With(slog.String("donation_id", donation.ID.String()))
 
+	provider, err := s.payments.For(donation.ProviderName)
+	if err != nil {
+		logger.ErrorContext(ctx, "no payments provider by that name", slog.String("provider_name", donation.ProviderName))
+
+		return 0, err
+	}
+
 	// From just before the donation existed. Asking earlier is asking about
 	// transactions that cannot exist, and PayPal requires a window either way.
-	transactions, err := s.paymentsProvider.GetTransactionsForDonationSubscription(ctx,
+	transactions, err := provider.GetTransactionsForDonationSubscription(ctx,
 		donation.ProviderSubscriptionID, donation.Created.AddDate(0, 0, -1), time.Now())
 	if err != nil {
 		// Returned as well as logged. One unreadable subscription should not stop
@@ -383,7 +415,19 @@ func (s FinanceService) reconcileRecurringDonationsForFund(ctx context.Context,
 	}
 
 	for _, donation := range recurringDonations {
-		status, errInner := s.paymentsProvider.GetProviderDonationSubscriptionStatus(ctx, donation.ProviderSubscriptionID)
+		// A donation at a provider we cannot reach is skipped, not failed: the
+		// others in the fund are still worth checking.
+		provider, errProvider := s.payments.For(donation.ProviderName)
+		if errProvider != nil {
+			logger.ErrorContext(ctx, "no payments provider by that name",
+				slog.String("provider_name", donation.ProviderName),
+				slog.String("donation_id", donation.ID.String()),
+			)
+
+			continue
+		}
+
+		status, errInner := provider.GetProviderDonationSubscriptionStatus(ctx, donation.ProviderSubscriptionID)
 		if errInner != nil {
 			// Leave the donation alone: an unreadable status is not evidence that the
 			// subscription ended, and deactivating here would cancel a live donation
@@ -439,7 +483,7 @@ func (s FinanceService) reconcileRecurringDonationsForFund(ctx context.Context,
 			}
 		}
 
-		if errRecord := s.recordProviderView(ctx, payments, logger); errRecord != nil {
+		if errRecord := s.recordProviderView(ctx, provider, payments, logger); errRecord != nil {
 			return errRecord
 		}
 	}
@@ -459,11 +503,11 @@ func (s FinanceService) reconcileRecurringDonationsForFund(ctx context.Context,
 // now that the CSV is gone, so swallowing a failure would let the run report
 // success while the audit page stayed blank and nothing said why. Every payment
 // is still attempted first: one bad row should not cost the rest of the fund.
-func (s FinanceService) recordProviderView(ctx context.Context, payments []donations.DonationPayment, logger *slog.Logger) error {
+func (s FinanceService) recordProviderView(ctx context.Context, provider paymentsProvider, payments []donations.DonationPayment, logger *slog.Logger) error {
 	var failed int
 
 	for _, payment := range payments {
-		transaction, err := s.paymentsProvider.GetTransaction(ctx,
+		transaction, err := provider.GetTransaction(ctx,
 			payment.ProviderPaymentID, payment.Created.AddDate(0, 0, -1), time.Now())
 		if err != nil {
 			// Left unreconciled rather than recorded as missing: we did not manage
@@ -543,6 +587,16 @@ func (s FinanceService) reconcileOneTimeDonationsForFund(ctx context.Context, fu
 	}
 
 	for _, donation := range oneTimeDonations {
+		provider, errProvider := s.payments.For(donation.ProviderName)
+		if errProvider != nil {
+			logger.ErrorContext(ctx, "no payments provider by that name",
+				slog.String("provider_name", donation.ProviderName),
+				slog.String("donation_id", donation.ID.String()),
+			)
+
+			continue
+		}
+
 		payments, errInner := s.donationStore.GetDonationPaymentsByDonationID(ctx, donation.ID)
 		if errInner != nil {
 			logger.ErrorContext(ctx, "failed to get donation payments", slog.String("error", errInner.Error()))
@@ -550,7 +604,7 @@ func (s FinanceService) reconcileOneTimeDonationsForFund(ctx context.Context, fu
 			return errInner
 		}
 
-		if errRecord := s.recordProviderView(ctx, payments, logger); errRecord != nil {
+		if errRecord := s.recordProviderView(ctx, provider, payments, logger); errRecord != nil {
 			return errRecord
 		}
 	}
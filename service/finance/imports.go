@@ -0,0 +1,392 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"boardfund/service/donations"
+	"boardfund/service/fundevents"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnmatchedPaymentAssigned means somebody assigned the payment first.
+var ErrUnmatchedPaymentAssigned = errors.New("that payment has already been assigned")
+
+// ErrNoSuchDonor means the email an admin gave belongs to no active member.
+var ErrNoSuchDonor = errors.New("no member has that email")
+
+// ProviderPayment is one-time money as the provider's transaction search
+// reports it: captured, and paid to us rather than by us.
+type ProviderPayment struct {
+	ProviderPaymentID string
+	ProviderOrderID   string
+	// FundReferenceID is the reference the order was created with, which is the
+	// fund's id. Empty when the order could not be read back.
+	FundReferenceID string
+	AmountCents     int32
+	FeeCents        int32
+	Date            time.Time
+
+	PayerID    string
+	PayerEmail string
+	PayerName  string
+}
+
+// paymentSearcher is the provider that can list what it took over a window. It
+// is asked for by assertion rather than added to paymentsProvider, so a provider
+// with no such search -- Stripe, as wired here -- is skipped by the import
+// rather than made to pretend.
+type paymentSearcher interface {
+	SearchPayments(ctx context.Context, start, end time.Time) ([]ProviderPayment, error)
+}
+
+// UnmatchedPayment is money the import could not tie to a fund and a donor.
+type UnmatchedPayment struct {
+	ID                uuid.UUID
+	ProviderName      string
+	ProviderPaymentID string
+	ProviderOrderID   string
+	AmountCents       int32
+	FeeCents          int32
+	PayerEmail        string
+	PayerName         string
+	// FundID is the fund the order named, when it named one that exists.
+	FundID     *uuid.UUID
+	FundName   string
+	Reason     string
+	OccurredAt time.Time
+}
+
+type InsertUnmatchedPayment struct {
+	ID                uuid.UUID
+	ProviderName      string
+	ProviderPaymentID string
+	ProviderOrderID   string
+	AmountCents       int32
+	FeeCents          int32
+	PayerEmail        string
+	PayerName         string
+	FundID            *uuid.UUID
+	Reason            string
+	OccurredAt        time.Time
+}
+
+// AssignUnmatchedPayment records an unmatched payment as a donation, and marks
+// it assigned, in one transaction.
+type AssignUnmatchedPayment struct {
+	ID         uuid.UUID
+	AssignedBy uuid.UUID
+	Donation   donations.InsertDonation
+	Payment    donations.InsertDonationPayment
+}
+
+// ImportResult is what one import run did, for the job's log line.
+type ImportResult struct {
+	Searched  int
+	Known     int
+	Recorded  int
+	Unmatched int
+}
+
+// ImportMissedPayments records one-time payments the providers took and we
+// never heard about.
+//
+// A one-time donation is recorded when the donor's browser comes back from the
+// capture to /donation/once/complete. Close the tab in between and the money is
+// at PayPal with nothing here to say whose it is or which fund it was for, and
+// backfillMissingPayments cannot help: it walks donations we already hold, and
+// this one never became one.
+//
+// So this searches the providers' transactions over the window instead. A
+// payment on record already is skipped; one whose order names a fund and whose
+// payer is a member is recorded as the donation the browser would have
+// completed; anything else is kept as an unmatched payment for an admin to
+// assign. Every step is idempotent on the provider payment id, so overlapping
+// windows and repeated runs are safe.
+func (s FinanceService) ImportMissedPayments(ctx context.Context, start, end time.Time) (ImportResult, error) {
+	var result ImportResult
+
+	for _, name := range s.payments.Names() {
+		provider, err := s.payments.For(name)
+		if err != nil {
+			return result, err
+		}
+
+		searcher, ok := provider.(paymentSearcher)
+		if !ok {
+			s.logger.InfoContext(ctx, "provider cannot search its payments; skipping it", slog.String("provider_name", name))
+
+			continue
+		}
+
+		payments, err := searcher.SearchPayments(ctx, start, end)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to search provider payments",
+				slog.String("provider_name", name),
+				slog.String("error", err.Error()),
+			)
+
+			return result, err
+		}
+
+		for _, payment := range payments {
+			result.Searched++
+
+			outcome, errImport := s.importPayment(ctx, name, payment)
+			if errImport != nil {
+				// One payment that will not record should not stop the rest. The
+				// next run finds it again, since nothing was written for it.
+				s.logger.ErrorContext(ctx, "failed to import a provider payment",
+					slog.String("provider_name", name),
+					slog.String("provider_payment_id", payment.ProviderPaymentID),
+					slog.String("error", errImport.Error()),
+				)
+
+				continue
+			}
+
+			switch outcome {
+			case importKnown:
+				result.Known++
+			case importRecorded:
+				result.Recorded++
+			case importUnmatched:
+				result.Unmatched++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+type importOutcome int
+
+const (
+	importKnown importOutcome = iota
+	importRecorded
+	importUnmatched
+)
+
+func (s FinanceService) importPayment(ctx context.Context, providerName string, payment ProviderPayment) (importOutcome, error) {
+	exists, err := s.donationStore.DonationPaymentExists(ctx, payment.ProviderPaymentID)
+	if err != nil {
+		return 0, err
+	}
+
+	if exists {
+		return importKnown, nil
+	}
+
+	unmatched := InsertUnmatchedPayment{
+		ID:                uuid.New(),
+		ProviderName:      providerName,
+		ProviderPaymentID: payment.ProviderPaymentID,
+		ProviderOrderID:   payment.ProviderOrderID,
+		AmountCents:       payment.AmountCents,
+		FeeCents:          payment.FeeCents,
+		PayerEmail:        payment.PayerEmail,
+		PayerName:         payment.PayerName,
+		OccurredAt:        payment.Date,
+	}
+
+	fund, reason, err := s.fundForPayment(ctx, payment)
+	if err != nil {
+		return 0, err
+	}
+
+	if fund == nil {
+		unmatched.Reason = reason
+
+		return s.keepUnmatched(ctx, unmatched)
+	}
+
+	unmatched.FundID = &fund.ID
+
+	donorID, err := s.donationStore.FindMemberByPayer(ctx, payment.PayerID, payment.PayerEmail)
+	if err != nil {
+		return 0, err
+	}
+
+	if donorID == nil {
+		// The usual case: somebody gave to a fund without being a member we can
+		// recognise. The fund is known, so the admin only has to say who.
+		unmatched.Reason = "the payer is not a member"
+
+		return s.keepUnmatched(ctx, unmatched)
+	}
+
+	donationID := uuid.New()
+
+	donation, err := s.donationStore.InsertDonationWithPayment(ctx, donations.InsertDonation{
+		ID:              donationID,
+		DonorID:         *donorID,
+		FundID:          fund.ID,
+		ProviderOrderID: payment.ProviderOrderID,
+		ProviderName:    providerName,
+	}, donations.InsertDonationPayment{
+		ID:                uuid.New(),
+		DonationID:        donationID,
+		ProviderPaymentID: payment.ProviderPaymentID,
+		AmountCents:       payment.AmountCents,
+		ProviderFeeCents:  payment.FeeCents,
+	})
+	if errors.Is(err, donations.ErrPaymentAlreadyRecorded) {
+		// The browser came back after all, between the check and now.
+		return importKnown, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.InfoContext(ctx, "recorded a one-time payment the provider had and we did not",
+		slog.String("provider_payment_id", payment.ProviderPaymentID),
+		slog.String("fund_id", fund.ID.String()),
+		slog.Int("amount_cents", int(payment.AmountCents)),
+	)
+
+	amount := payment.AmountCents
+
+	s.events.Record(ctx, fundevents.Record{
+		FundID:          fund.ID,
+		Kind:            fundevents.KindPaymentReceived,
+		OccurredAt:      payment.Date,
+		SubjectMemberID: donorID,
+		AmountCents:     &amount,
+		Detail:          "one-time, recovered by import",
+		ReferenceID:     &donation.ID,
+		DedupeKey:       "payment-recovered:" + payment.ProviderPaymentID,
+	})
+
+	return importRecorded, nil
+}
+
+// fundForPayment is the fund the payment's order was created for, or nil and
+// the reason there is none.
+func (s FinanceService) fundForPayment(ctx context.Context, payment ProviderPayment) (*donations.Fund, string, error) {
+	if payment.FundReferenceID == "" {
+		return nil, "the order names no fund", nil
+	}
+
+	fundID, err := uuid.Parse(payment.FundReferenceID)
+	if err != nil {
+		return nil, fmt.Sprintf("the order names %q, which is not a fund", payment.FundReferenceID), nil
+	}
+
+	fund, err := s.donationStore.GetFundByID(ctx, fundID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, "the order names a fund that does not exist", nil
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return fund, "", nil
+}
+
+func (s FinanceService) keepUnmatched(ctx context.Context, payment InsertUnmatchedPayment) (importOutcome, error) {
+	inserted, err := s.donationStore.InsertUnmatchedPayment(ctx, payment)
+	if err != nil {
+		return 0, err
+	}
+
+	// Nil is a payment an earlier run already set aside. Counted again, since it
+	// is still waiting on somebody.
+	if inserted != nil {
+		s.logger.WarnContext(ctx, "set aside a provider payment nothing here accounts for",
+			slog.String("provider_payment_id", payment.ProviderPaymentID),
+			slog.String("reason", payment.Reason),
+			slog.Int("amount_cents", int(payment.AmountCents)),
+		)
+	}
+
+	return importUnmatched, nil
+}
+
+// GetUnmatchedPayments is the money waiting on an admin, newest first.
+func (s FinanceService) GetUnmatchedPayments(ctx context.Context) ([]UnmatchedPayment, error) {
+	payments, err := s.donationStore.GetOpenUnmatchedPayments(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get unmatched payments", slog.String("error", err.Error()))
+
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// AssignUnmatchedPayment records an unmatched payment as a one-time donation by
+// the member with donorEmail to fundID, on an admin's say-so.
+//
+// The fund is the admin's choice rather than the order's. An order that named a
+// fund is shown with it, and the form starts there, but the admin may know
+// better -- a donor who wrote to say they picked the wrong one, say.
+func (s FinanceService) AssignUnmatchedPayment(ctx context.Context, id, fundID uuid.UUID, donorEmail string, actorID uuid.UUID) (*donations.Donation, *UnmatchedPayment, error) {
+	payment, err := s.donationStore.GetUnmatchedPaymentByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fund, err := s.donationStore.GetFundByID(ctx, fundID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	donorID, err := s.donationStore.FindMemberByPayer(ctx, "", donorEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if donorID == nil {
+		return nil, nil, ErrNoSuchDonor
+	}
+
+	donationID := uuid.New()
+
+	donation, err := s.donationStore.AssignUnmatchedPayment(ctx, AssignUnmatchedPayment{
+		ID:         payment.ID,
+		AssignedBy: actorID,
+		Donation: donations.InsertDonation{
+			ID:              donationID,
+			DonorID:         *donorID,
+			FundID:          fund.ID,
+			ProviderOrderID: payment.ProviderOrderID,
+			ProviderName:    payment.ProviderName,
+		},
+		Payment: donations.InsertDonationPayment{
+			ID:                uuid.New(),
+			DonationID:        donationID,
+			ProviderPaymentID: payment.ProviderPaymentID,
+			AmountCents:       payment.AmountCents,
+			ProviderFeeCents:  payment.FeeCents,
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payment.FundID = &fund.ID
+	payment.FundName = fund.Name
+
+	amount := payment.AmountCents
+
+	s.events.Record(ctx, fundevents.Record{
+		FundID:          fund.ID,
+		Kind:            fundevents.KindPaymentReceived,
+		OccurredAt:      payment.OccurredAt,
+		ActorMemberID:   &actorID,
+		SubjectMemberID: donorID,
+		AmountCents:     &amount,
+		Detail:          "one-time, assigned by an admin",
+		ReferenceID:     &donation.ID,
+		DedupeKey:       "payment-recovered:" + payment.ProviderPaymentID,
+	})
+
+	return donation, payment, nil
+}
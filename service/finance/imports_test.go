@@ -0,0 +1,163 @@
+package finance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"boardfund/providers"
+	"boardfund/service/donations"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// importStore is what the import reads and writes. As with fakeStore, anything
+// it has no business calling is absent.
+type importStore struct {
+	donationStore
+
+	known   map[string]bool
+	funds   map[uuid.UUID]donations.Fund
+	members map[string]uuid.UUID
+
+	donations []donations.InsertDonation
+	payments  []donations.InsertDonationPayment
+	unmatched []InsertUnmatchedPayment
+}
+
+func (s *importStore) DonationPaymentExists(_ context.Context, providerPaymentID string) (bool, error) {
+	return s.known[providerPaymentID], nil
+}
+
+func (s *importStore) GetFundByID(_ context.Context, id uuid.UUID) (*donations.Fund, error) {
+	fund, ok := s.funds[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &fund, nil
+}
+
+func (s *importStore) FindMemberByPayer(_ context.Context, payerID, email string) (*uuid.UUID, error) {
+	for _, key := range []string{payerID, email} {
+		if id, ok := s.members[key]; ok && key != "" {
+			return &id, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *importStore) InsertDonationWithPayment(_ context.Context, donation donations.InsertDonation, payment donations.InsertDonationPayment) (*donations.Donation, error) {
+	s.donations = append(s.donations, donation)
+	s.payments = append(s.payments, payment)
+
+	return &donations.Donation{ID: donation.ID, DonorID: donation.DonorID, FundID: donation.FundID}, nil
+}
+
+func (s *importStore) InsertUnmatchedPayment(_ context.Context, payment InsertUnmatchedPayment) (*UnmatchedPayment, error) {
+	s.unmatched = append(s.unmatched, payment)
+
+	return &UnmatchedPayment{ID: payment.ID}, nil
+}
+
+type searchingProvider struct {
+	fakeProvider
+
+	payments []ProviderPayment
+}
+
+func (p *searchingProvider) SearchPayments(context.Context, time.Time, time.Time) ([]ProviderPayment, error) {
+	return p.payments, nil
+}
+
+// A donor who closed the tab between PayPal's capture and our completion page
+// paid, and the fund never heard. The import records what it can tie to a fund
+// and a member, and sets the rest aside for an admin rather than guessing.
+func TestImportRecordsWhatItCanAndSetsAsideTheRest(t *testing.T) {
+	fund := donations.Fund{ID: uuid.New(), Name: "rent"}
+	donor := uuid.New()
+	when := time.Date(2026, time.August, 1, 12, 0, 0, 0, time.UTC)
+
+	provider := &searchingProvider{payments: []ProviderPayment{
+		{ProviderPaymentID: "SALE-KNOWN", FundReferenceID: fund.ID.String(), AmountCents: 1000},
+		{ProviderPaymentID: "SALE-MEMBER", ProviderOrderID: "ORDER-1", FundReferenceID: fund.ID.String(),
+			AmountCents: 2500, FeeCents: 136, Date: when, PayerID: "PAYER-1"},
+		{ProviderPaymentID: "SALE-STRANGER", FundReferenceID: fund.ID.String(), AmountCents: 500,
+			PayerEmail: "stranger@example.com"},
+		{ProviderPaymentID: "SALE-NO-FUND", AmountCents: 700, PayerID: "PAYER-1"},
+		{ProviderPaymentID: "SALE-GONE-FUND", FundReferenceID: uuid.NewString(), AmountCents: 800, PayerID: "PAYER-1"},
+	}}
+
+	store := &importStore{
+		known:   map[string]bool{"SALE-KNOWN": true},
+		funds:   map[uuid.UUID]donations.Fund{fund.ID: fund},
+		members: map[string]uuid.UUID{"PAYER-1": donor},
+	}
+	events := &capturedEvents{}
+
+	result, err := newService(store, provider, events).
+		ImportMissedPayments(context.Background(), when.AddDate(0, 0, -7), when)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	want := ImportResult{Searched: 5, Known: 1, Recorded: 1, Unmatched: 3}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+
+	if len(store.donations) != 1 {
+		t.Fatalf("recorded %d donations, want 1", len(store.donations))
+	}
+
+	recorded := store.donations[0]
+	if recorded.DonorID != donor || recorded.FundID != fund.ID || recorded.Recurring {
+		t.Errorf("recorded %+v, want a one-time donation by the payer to the order's fund", recorded)
+	}
+
+	if recorded.ProviderName != providers.PayPal || recorded.ProviderOrderID != "ORDER-1" {
+		t.Errorf("recorded %+v, want the provider and order it came from", recorded)
+	}
+
+	if payment := store.payments[0]; payment.DonationID != recorded.ID || payment.AmountCents != 2500 || payment.ProviderFeeCents != 136 {
+		t.Errorf("payment = %+v, want the provider's figures on the new donation", payment)
+	}
+
+	if len(events.records) != 1 || events.records[0].DedupeKey == "" || events.records[0].OccurredAt != when {
+		t.Errorf("events = %+v, want one keyed entry dated when the money moved", events.records)
+	}
+
+	reasons := map[string]InsertUnmatchedPayment{}
+	for _, unmatched := range store.unmatched {
+		reasons[unmatched.ProviderPaymentID] = unmatched
+	}
+
+	// The fund is known, so it is kept: the admin only has to say who.
+	if stranger := reasons["SALE-STRANGER"]; stranger.FundID == nil || *stranger.FundID != fund.ID {
+		t.Errorf("stranger = %+v, want it set aside with its fund", stranger)
+	}
+
+	// A member paid, but for nothing we can name. Not credited to any fund on a
+	// guess.
+	for _, id := range []string{"SALE-NO-FUND", "SALE-GONE-FUND"} {
+		if unmatched, ok := reasons[id]; !ok || unmatched.FundID != nil || unmatched.Reason == "" {
+			t.Errorf("%s = %+v, want it set aside with a reason and no fund", id, unmatched)
+		}
+	}
+}
+
+// Not every provider can list its payments by date, and a run with one of those
+// configured must not fail for it.
+func TestAProviderWithoutASearchIsSkipped(t *testing.T) {
+	result, err := newService(&importStore{}, &fakeProvider{}, &capturedEvents{}).
+		ImportMissedPayments(context.Background(), time.Now().AddDate(0, 0, -1), time.Now())
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	if result != (ImportResult{}) {
+		t.Errorf("result = %+v, want nothing searched", result)
+	}
+}
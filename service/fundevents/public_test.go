@@ -17,9 +17,12 @@ var everyKind = []fundevents.Kind{
 	fundevents.KindDonationStarted,
 	fundevents.KindDonationCancelled,
 	fundevents.KindDonationResumed,
+	fundevents.KindDonationAmountChanged,
 	fundevents.KindPaymentReceived,
 	fundevents.KindPaymentFailed,
 	fundevents.KindPaymentRefunded,
+	fundevents.KindPaymentDisputed,
+	fundevents.KindPaymentDisputeResolved,
 	fundevents.KindMemberEnrolled,
 	fundevents.KindEnrollmentCancelled,
 	fundevents.KindBatchPlanned,
@@ -40,9 +43,12 @@ func TestNothingAboutAnIndividualIsPublic(t *testing.T) {
 		fundevents.KindDonationStarted,
 		fundevents.KindDonationCancelled,
 		fundevents.KindDonationResumed,
+		fundevents.KindDonationAmountChanged,
 		fundevents.KindPaymentReceived,
 		fundevents.KindPaymentFailed,
 		fundevents.KindPaymentRefunded,
+		fundevents.KindPaymentDisputed,
+		fundevents.KindPaymentDisputeResolved,
 		fundevents.KindMemberEnrolled,
 		fundevents.KindEnrollmentCancelled,
 	}
@@ -184,7 +190,7 @@ func TestEveryKindIsAccountedFor(t *testing.T) {
 		seen[kind] = true
 	}
 
-	if len(seen) != 16 {
+	if len(seen) != 19 {
 		t.Errorf("everyKind has %d entries; update it and decide whether the new kind is public", len(seen))
 	}
 }
@@ -24,6 +24,7 @@ const (
 	KindEnrollmentCancelled Kind = "enrollment_cancelled"
 	KindBatchPlanned        Kind = "payout_batch_planned"
 	KindBatchApproved       Kind = "payout_batch_approved"
+	KindBatchFirstApproval  Kind = "payout_batch_first_approval"
 	KindBatchRejected       Kind = "payout_batch_rejected"
 	KindBatchExpired        Kind = "payout_batch_expired"
 	KindBatchSubmitted      Kind = "payout_batch_submitted"
@@ -32,6 +33,21 @@ const (
 	KindFundUpdated         Kind = "fund_updated"
 	KindFundCreated         Kind = "fund_created"
 	KindFundNoteRemoved     Kind = "fund_note_removed"
+	KindPayoutStruck        Kind = "payout_struck"
+
+	// KindDonationAmountChanged is a donor moving their subscription onto another
+	// of the fund's plans. AmountCents is the new amount; the detail says what it
+	// was before.
+	KindDonationAmountChanged Kind = "donation_amount_changed"
+
+	// KindPaymentDisputed is a donor disputing a payment, or their bank charging
+	// it back. AmountCents is what PayPal is holding, as money leaving.
+	KindPaymentDisputed Kind = "payment_disputed"
+	// KindPaymentDisputeResolved is the dispute closing either way. Won, the
+	// held money comes back and AmountCents says how much; lost, it is gone, and
+	// AmountCents is absent unless the dispute was never seen open -- otherwise
+	// the money already left when it was held.
+	KindPaymentDisputeResolved Kind = "payment_dispute_resolved"
 )
 
 // Public reports whether this kind belongs on a timeline that donors can read.
@@ -1,6 +1,7 @@
 package members
 
 import (
+	"boardfund/providers"
 	"boardfund/service/donations"
 	"boardfund/service/fundevents"
 	"context"
@@ -39,10 +40,10 @@ type paymentsProvider interface {
 }
 
 type MemberService struct {
-	memberStore      memberStore
-	donationStore    donationStore
-	paymentsProvider paymentsProvider
-	events           eventRecorder
+	memberStore   memberStore
+	donationStore donationStore
+	payments      *providers.Registry[paymentsProvider]
+	events        eventRecorder
 
 	logger *slog.Logger
 }
@@ -51,14 +52,20 @@ func NewMemberService(memberStore memberStore, donationStore donationStore, paym
 	gob.Register(Member{})
 
 	return &MemberService{
-		memberStore:      memberStore,
-		donationStore:    donationStore,
-		paymentsProvider: paymentsProvider,
-		events:           events,
-		logger:           logger,
+		memberStore:   memberStore,
+		donationStore: donationStore,
+		payments:      providers.NewRegistry(providers.PayPal, paymentsProvider),
+		events:        events,
+		logger:        logger,
 	}
 }
 
+// AddProvider lets the service cancel subscriptions held at another provider.
+// See DonationService.AddProvider; a member's donations can be at either.
+func (s *MemberService) AddProvider(name string, provider paymentsProvider) {
+	s.payments.Register(name, provider)
+}
+
 func (s MemberService) GetMemberWithDonations(ctx context.Context, id uuid.UUID) (*Member, error) {
 	member, err := s.memberStore.GetMemberWithDonations(ctx, id)
 	if err != nil {
@@ -101,14 +108,26 @@ func (s MemberService) DeactivateMember(ctx context.Context, id uuid.UUID) (*Mem
 		}
 	}
 
-	toCancel := extractProviderSubscriptionIDs(active)
+	// One call per provider. A member who gave through both can be left with one
+	// provider's subscriptions cancelled if the other refuses; deactivating again
+	// cancels what is left.
+	for name, toCancel := range subscriptionsByProvider(active) {
+		provider, errProvider := s.payments.For(name)
+		if errProvider != nil {
+			s.logger.ErrorContext(ctx, "no payments provider by that name, member left active",
+				slog.String("provider_name", name),
+				slog.String("member_id", id.String()),
+			)
+
+			return nil, errProvider
+		}
 
-	if len(toCancel) > 0 {
-		cancelled, errCancel := s.paymentsProvider.CancelSubscriptions(ctx, toCancel)
+		cancelled, errCancel := provider.CancelSubscriptions(ctx, toCancel)
 		if errCancel != nil {
 			s.logger.ErrorContext(ctx, "failed to cancel subscriptions, member left active",
 				slog.String("error", errCancel.Error()),
 				slog.String("member_id", id.String()),
+				slog.String("provider_name", name),
 			)
 
 			return nil, errCancel
@@ -240,16 +259,18 @@ func (s MemberService) GetMemberByUsername(ctx context.Context, username string)
 	return member, nil
 }
 
-func extractProviderSubscriptionIDs(donations []donations.Donation) []string {
-	var subscriptionIDs []string
+// subscriptionsByProvider groups the subscriptions by the provider that holds
+// them, which is the only one that can cancel them.
+func subscriptionsByProvider(donations []donations.Donation) map[string][]string {
+	byProvider := make(map[string][]string)
 
 	for _, donation := range donations {
 		if donation.ProviderSubscriptionID != "" {
-			subscriptionIDs = append(subscriptionIDs, donation.ProviderSubscriptionID)
+			byProvider[donation.ProviderName] = append(byProvider[donation.ProviderName], donation.ProviderSubscriptionID)
 		}
 	}
 
-	return subscriptionIDs
+	return byProvider
 }
 
 func uncancelledSubscriptions(cancelled []string, all []string) []string {
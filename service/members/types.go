@@ -13,6 +13,13 @@ const (
 	AdminRole MemberRole = "ADMIN"
 	DonorRole MemberRole = "DONOR"
 	PayeeRole MemberRole = "PAYEE"
+
+	// The roles below ADMIN exist only in the session, where Authenticate puts
+	// them from the token's groups. The role enum in the database has no such
+	// values, and nothing writes a session's roles back to it.
+	TreasurerRole   MemberRole = "TREASURER"
+	ModeratorRole   MemberRole = "MODERATOR"
+	FundManagerRole MemberRole = "FUND_MANAGER"
 )
 
 type MemberDonation struct {
@@ -77,8 +84,12 @@ func (m Member) GetTotalDonatedCents() int32 {
 }
 
 func (m Member) IsAdmin() bool {
-	for _, role := range m.Roles {
-		if role == AdminRole {
+	return m.HasRole(AdminRole)
+}
+
+func (m Member) HasRole(role MemberRole) bool {
+	for _, r := range m.Roles {
+		if r == role {
 			return true
 		}
 	}
@@ -86,6 +97,16 @@ func (m Member) IsAdmin() bool {
 	return false
 }
 
+// IsStaff reports whether the member has any role the admin pages let in.
+func (m Member) IsStaff() bool {
+	return m.IsAdmin() || m.HasRole(TreasurerRole) || m.HasRole(ModeratorRole) || m.HasRole(FundManagerRole)
+}
+
+// CanModerate reports whether the member may remove notes.
+func (m Member) CanModerate() bool {
+	return m.IsAdmin() || m.HasRole(ModeratorRole)
+}
+
 type UpsertMember struct {
 	ID              uuid.UUID
 	Email           string
@@ -28,15 +28,24 @@ var _ donations.PaymentsProvider = &PaymentsProviderMock{}
 //			CreatePlanFunc: func(ctx context.Context, plan donations.CreatePlan) (string, error) {
 //				panic("mock out the CreatePlan method")
 //			},
+//			DeactivatePlanFunc: func(ctx context.Context, planID string) error {
+//				panic("mock out the DeactivatePlan method")
+//			},
 //			GetOrderFunc: func(ctx context.Context, orderID string) (*donations.ProviderOrder, error) {
 //				panic("mock out the GetOrder method")
 //			},
 //			GetSubscriptionFunc: func(ctx context.Context, subscriptionID string) (*donations.ProviderSubscription, error) {
 //				panic("mock out the GetSubscription method")
 //			},
-//			InitiateDonationFunc: func(ctx context.Context, fund donations.Fund, amountCents int32) (string, error) {
+//			InitiateDonationFunc: func(ctx context.Context, fund donations.Fund, amountCents int32, returnURL string, cancelURL string) (*donations.Checkout, error) {
 //				panic("mock out the InitiateDonation method")
 //			},
+//			InitiateSubscriptionFunc: func(ctx context.Context, plan donations.DonationPlan, returnURL string, cancelURL string) (*donations.Checkout, error) {
+//				panic("mock out the InitiateSubscription method")
+//			},
+//			ReviseSubscriptionFunc: func(ctx context.Context, subscriptionID string, providerPlanID string, returnURL string, cancelURL string) (string, error) {
+//				panic("mock out the ReviseSubscription method")
+//			},
 //		}
 //
 //		// use mockedPaymentsProvider in code that requires donations.PaymentsProvider
@@ -53,6 +62,9 @@ type PaymentsProviderMock struct {
 	// CreatePlanFunc mocks the CreatePlan method.
 	CreatePlanFunc func(ctx context.Context, plan donations.CreatePlan) (string, error)
 
+	// DeactivatePlanFunc mocks the DeactivatePlan method.
+	DeactivatePlanFunc func(ctx context.Context, planID string) error
+
 	// GetOrderFunc mocks the GetOrder method.
 	GetOrderFunc func(ctx context.Context, orderID string) (*donations.ProviderOrder, error)
 
@@ -60,7 +72,13 @@ type PaymentsProviderMock struct {
 	GetSubscriptionFunc func(ctx context.Context, subscriptionID string) (*donations.ProviderSubscription, error)
 
 	// InitiateDonationFunc mocks the InitiateDonation method.
-	InitiateDonationFunc func(ctx context.Context, fund donations.Fund, amountCents int32) (string, error)
+	InitiateDonationFunc func(ctx context.Context, fund donations.Fund, amountCents int32, returnURL string, cancelURL string) (*donations.Checkout, error)
+
+	// InitiateSubscriptionFunc mocks the InitiateSubscription method.
+	InitiateSubscriptionFunc func(ctx context.Context, plan donations.DonationPlan, returnURL string, cancelURL string) (*donations.Checkout, error)
+
+	// ReviseSubscriptionFunc mocks the ReviseSubscription method.
+	ReviseSubscriptionFunc func(ctx context.Context, subscriptionID string, providerPlanID string, returnURL string, cancelURL string) (string, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -87,6 +105,13 @@ type PaymentsProviderMock struct {
 			// Plan is the plan argument value.
 			Plan donations.CreatePlan
 		}
+		// DeactivatePlan holds details about calls to the DeactivatePlan method.
+		DeactivatePlan []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// PlanID is the planID argument value.
+			PlanID string
+		}
 		// GetOrder holds details about calls to the GetOrder method.
 		GetOrder []struct {
 			// Ctx is the ctx argument value.
@@ -109,14 +134,45 @@ type PaymentsProviderMock struct {
 			Fund donations.Fund
 			// AmountCents is the amountCents argument value.
 			AmountCents int32
+			// ReturnURL is the returnURL argument value.
+			ReturnURL string
+			// CancelURL is the cancelURL argument value.
+			CancelURL string
+		}
+		// InitiateSubscription holds details about calls to the InitiateSubscription method.
+		InitiateSubscription []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Plan is the plan argument value.
+			Plan donations.DonationPlan
+			// ReturnURL is the returnURL argument value.
+			ReturnURL string
+			// CancelURL is the cancelURL argument value.
+			CancelURL string
+		}
+		// ReviseSubscription holds details about calls to the ReviseSubscription method.
+		ReviseSubscription []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// SubscriptionID is the subscriptionID argument value.
+			SubscriptionID string
+			// ProviderPlanID is the providerPlanID argument value.
+			ProviderPlanID string
+			// ReturnURL is the returnURL argument value.
+			ReturnURL string
+			// CancelURL is the cancelURL argument value.
+			CancelURL string
 		}
 	}
-	lockCancelSubscriptions sync.RWMutex
-	lockCreateFund          sync.RWMutex
-	lockCreatePlan          sync.RWMutex
-	lockGetOrder            sync.RWMutex
-	lockGetSubscription     sync.RWMutex
-	lockInitiateDonation    sync.RWMutex
+	lockCancelSubscriptions  sync.RWMutex
+	lockCreateFund           sync.RWMutex
+	lockCreatePlan           sync.RWMutex
+	lockDeactivatePlan       sync.RWMutex
+	lockGetOrder             sync.RWMutex
+	lockGetSubscription      sync.RWMutex
+	lockInitiateDonation     sync.RWMutex
+	lockInitiateSubscription sync.RWMutex
+	lockReviseSubscription   sync.RWMutex
 }
 
 // CancelSubscriptions calls CancelSubscriptionsFunc.
@@ -231,6 +287,42 @@ func (mock *PaymentsProviderMock) CreatePlanCalls() []struct {
 	return calls
 }
 
+// DeactivatePlan calls DeactivatePlanFunc.
+func (mock *PaymentsProviderMock) DeactivatePlan(ctx context.Context, planID string) error {
+	if mock.DeactivatePlanFunc == nil {
+		panic("PaymentsProviderMock.DeactivatePlanFunc: method is nil but PaymentsProvider.DeactivatePlan was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		PlanID string
+	}{
+		Ctx:    ctx,
+		PlanID: planID,
+	}
+	mock.lockDeactivatePlan.Lock()
+	mock.calls.DeactivatePlan = append(mock.calls.DeactivatePlan, callInfo)
+	mock.lockDeactivatePlan.Unlock()
+	return mock.DeactivatePlanFunc(ctx, planID)
+}
+
+// DeactivatePlanCalls gets all the calls that were made to DeactivatePlan.
+// Check the length with:
+//
+//	len(mockedPaymentsProvider.DeactivatePlanCalls())
+func (mock *PaymentsProviderMock) DeactivatePlanCalls() []struct {
+	Ctx    context.Context
+	PlanID string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		PlanID string
+	}
+	mock.lockDeactivatePlan.RLock()
+	calls = mock.calls.DeactivatePlan
+	mock.lockDeactivatePlan.RUnlock()
+	return calls
+}
+
 // GetOrder calls GetOrderFunc.
 func (mock *PaymentsProviderMock) GetOrder(ctx context.Context, orderID string) (*donations.ProviderOrder, error) {
 	if mock.GetOrderFunc == nil {
@@ -304,7 +396,7 @@ func (mock *PaymentsProviderMock) GetSubscriptionCalls() []struct {
 }
 
 // InitiateDonation calls InitiateDonationFunc.
-func (mock *PaymentsProviderMock) InitiateDonation(ctx context.Context, fund donations.Fund, amountCents int32) (string, error) {
+func (mock *PaymentsProviderMock) InitiateDonation(ctx context.Context, fund donations.Fund, amountCents int32, returnURL string, cancelURL string) (*donations.Checkout, error) {
 	if mock.InitiateDonationFunc == nil {
 		panic("PaymentsProviderMock.InitiateDonationFunc: method is nil but PaymentsProvider.InitiateDonation was just called")
 	}
@@ -312,15 +404,19 @@ func (mock *PaymentsProviderMock) InitiateDonation(ctx context.Context, fund don
 		Ctx         context.Context
 		Fund        donations.Fund
 		AmountCents int32
+		ReturnURL   string
+		CancelURL   string
 	}{
 		Ctx:         ctx,
 		Fund:        fund,
 		AmountCents: amountCents,
+		ReturnURL:   returnURL,
+		CancelURL:   cancelURL,
 	}
 	mock.lockInitiateDonation.Lock()
 	mock.calls.InitiateDonation = append(mock.calls.InitiateDonation, callInfo)
 	mock.lockInitiateDonation.Unlock()
-	return mock.InitiateDonationFunc(ctx, fund, amountCents)
+	return mock.InitiateDonationFunc(ctx, fund, amountCents, returnURL, cancelURL)
 }
 
 // InitiateDonationCalls gets all the calls that were made to InitiateDonation.
@@ -331,14 +427,110 @@ func (mock *PaymentsProviderMock) InitiateDonationCalls() []struct {
 	Ctx         context.Context
 	Fund        donations.Fund
 	AmountCents int32
+	ReturnURL   string
+	CancelURL   string
 } {
 	var calls []struct {
 		Ctx         context.Context
 		Fund        donations.Fund
 		AmountCents int32
+		ReturnURL   string
+		CancelURL   string
 	}
 	mock.lockInitiateDonation.RLock()
 	calls = mock.calls.InitiateDonation
 	mock.lockInitiateDonation.RUnlock()
 	return calls
 }
+
+// InitiateSubscription calls InitiateSubscriptionFunc.
+func (mock *PaymentsProviderMock) InitiateSubscription(ctx context.Context, plan donations.DonationPlan, returnURL string, cancelURL string) (*donations.Checkout, error) {
+	if mock.InitiateSubscriptionFunc == nil {
+		panic("PaymentsProviderMock.InitiateSubscriptionFunc: method is nil but PaymentsProvider.InitiateSubscription was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Plan      donations.DonationPlan
+		ReturnURL string
+		CancelURL string
+	}{
+		Ctx:       ctx,
+		Plan:      plan,
+		ReturnURL: returnURL,
+		CancelURL: cancelURL,
+	}
+	mock.lockInitiateSubscription.Lock()
+	mock.calls.InitiateSubscription = append(mock.calls.InitiateSubscription, callInfo)
+	mock.lockInitiateSubscription.Unlock()
+	return mock.InitiateSubscriptionFunc(ctx, plan, returnURL, cancelURL)
+}
+
+// InitiateSubscriptionCalls gets all the calls that were made to InitiateSubscription.
+// Check the length with:
+//
+//	len(mockedPaymentsProvider.InitiateSubscriptionCalls())
+func (mock *PaymentsProviderMock) InitiateSubscriptionCalls() []struct {
+	Ctx       context.Context
+	Plan      donations.DonationPlan
+	ReturnURL string
+	CancelURL string
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Plan      donations.DonationPlan
+		ReturnURL string
+		CancelURL string
+	}
+	mock.lockInitiateSubscription.RLock()
+	calls = mock.calls.InitiateSubscription
+	mock.lockInitiateSubscription.RUnlock()
+	return calls
+}
+
+// ReviseSubscription calls ReviseSubscriptionFunc.
+func (mock *PaymentsProviderMock) ReviseSubscription(ctx context.Context, subscriptionID string, providerPlanID string, returnURL string, cancelURL string) (string, error) {
+	if mock.ReviseSubscriptionFunc == nil {
+		panic("PaymentsProviderMock.ReviseSubscriptionFunc: method is nil but PaymentsProvider.ReviseSubscription was just called")
+	}
+	callInfo := struct {
+		Ctx            context.Context
+		SubscriptionID string
+		ProviderPlanID string
+		ReturnURL      string
+		CancelURL      string
+	}{
+		Ctx:            ctx,
+		SubscriptionID: subscriptionID,
+		ProviderPlanID: providerPlanID,
+		ReturnURL:      returnURL,
+		CancelURL:      cancelURL,
+	}
+	mock.lockReviseSubscription.Lock()
+	mock.calls.ReviseSubscription = append(mock.calls.ReviseSubscription, callInfo)
+	mock.lockReviseSubscription.Unlock()
+	return mock.ReviseSubscriptionFunc(ctx, subscriptionID, providerPlanID, returnURL, cancelURL)
+}
+
+// ReviseSubscriptionCalls gets all the calls that were made to ReviseSubscription.
+// Check the length with:
+//
+//	len(mockedPaymentsProvider.ReviseSubscriptionCalls())
+func (mock *PaymentsProviderMock) ReviseSubscriptionCalls() []struct {
+	Ctx            context.Context
+	SubscriptionID string
+	ProviderPlanID string
+	ReturnURL      string
+	CancelURL      string
+} {
+	var calls []struct {
+		Ctx            context.Context
+		SubscriptionID string
+		ProviderPlanID string
+		ReturnURL      string
+		CancelURL      string
+	}
+	mock.lockReviseSubscription.RLock()
+	calls = mock.calls.ReviseSubscription
+	mock.lockReviseSubscription.RUnlock()
+	return calls
+}
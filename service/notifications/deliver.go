@@ -0,0 +1,89 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"boardfund/service/payouts"
+)
+
+// target is one place a message goes: an address, a webhook, the home page.
+// Recipient is what the delivery row calls it, and must be safe to show.
+type target struct {
+	recipient string
+	send      func(ctx context.Context) error
+}
+
+// deliver sends to every target, retrying each on its own, and writes one row
+// per target with how it ended.
+//
+// An error only when every target failed -- see ErrNobodyReached. Each channel
+// goes through here, so "reached" means the same thing in all of them.
+func deliver(
+	ctx context.Context,
+	store deliveryStore,
+	retry Retry,
+	channel Channel,
+	kind Kind,
+	batch payouts.Batch,
+	targets []target,
+	logger *slog.Logger,
+) error {
+	logger = logger.With(
+		slog.String("batch_id", batch.ID.String()),
+		slog.String("kind", string(kind)),
+		slog.String("channel", string(channel)),
+	)
+
+	if len(targets) == 0 {
+		return fmt.Errorf("%w: no %s recipients configured", ErrNobodyReached, channel)
+	}
+
+	reached := 0
+	var lastErr error
+	for _, t := range targets {
+		errSend := retry.do(ctx, t.send)
+
+		failure := ""
+		if errSend != nil {
+			failure = errSend.Error()
+			lastErr = errSend
+
+			logger.ErrorContext(ctx, "notification not delivered",
+				slog.String("error", failure),
+				slog.String("recipient", t.recipient),
+			)
+		} else {
+			reached++
+		}
+
+		// Written whatever happened. It cannot fail the notification: the
+		// message has already gone or not, and the log line is all that is left
+		// to say.
+		_, errRecord := store.InsertDelivery(ctx, InsertDelivery{
+			BatchID:   batch.ID,
+			Kind:      kind,
+			Channel:   channel,
+			Recipient: t.recipient,
+			Error:     failure,
+		})
+		if errRecord != nil {
+			logger.ErrorContext(ctx, "failed to record notification delivery",
+				slog.String("error", errRecord.Error()),
+				slog.String("recipient", t.recipient),
+			)
+		}
+	}
+
+	if reached == 0 {
+		return fmt.Errorf("%w: %w", ErrNobodyReached, lastErr)
+	}
+
+	logger.InfoContext(ctx, "notification sent",
+		slog.Int("reached", reached),
+		slog.Int("recipients", len(targets)),
+	)
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"boardfund/mailer"
+	"boardfund/service/payouts"
+)
+
+// sender is the one thing asked of a mail transport.
+type sender interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+// EmailNotifier mails the configured treasurers when a batch needs approving,
+// and again when its window is about to close.
+//
+// One message per recipient rather than one addressed to all of them: a relay
+// refuses a whole transaction over one dead mailbox, and one treasurer's typo in
+// the environment should not be why the others never heard.
+type EmailNotifier struct {
+	sender     sender
+	batches    batchReader
+	store      deliveryStore
+	recipients []string
+	baseURL    string
+	retry      Retry
+
+	logger *slog.Logger
+}
+
+// NewEmailNotifier takes the site's public base URL so the message can link to
+// the batch. Without it the link would be a path, which a mail client cannot
+// open.
+func NewEmailNotifier(
+	sender sender,
+	batches batchReader,
+	store deliveryStore,
+	recipients []string,
+	baseURL string,
+	retry Retry,
+	logger *slog.Logger,
+) *EmailNotifier {
+	return &EmailNotifier{
+		sender:     sender,
+		batches:    batches,
+		store:      store,
+		recipients: recipients,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		retry:      retry,
+		logger:     logger,
+	}
+}
+
+func (n EmailNotifier) NotifyApprovalRequired(ctx context.Context, batch payouts.Batch) error {
+	return n.notify(ctx, KindApprovalRequired, batch)
+}
+
+func (n EmailNotifier) NotifyApprovalExpiring(ctx context.Context, batch payouts.Batch) error {
+	return n.notify(ctx, KindApprovalExpiring, batch)
+}
+
+func (n EmailNotifier) notify(ctx context.Context, kind Kind, batch payouts.Batch) error {
+	msg, err := describe(ctx, n.batches, n.baseURL, kind, batch, n.logger)
+	if err != nil {
+		n.logger.ErrorContext(ctx, "failed to compose approval email", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	targets := make([]target, 0, len(n.recipients))
+	for _, recipient := range n.recipients {
+		email := mailer.Message{
+			To:      []string{recipient},
+			Subject: msg.Subject,
+			Body:    msg.Body,
+		}
+
+		targets = append(targets, target{
+			recipient: recipient,
+			send: func(ctx context.Context) error {
+				return n.sender.Send(ctx, email)
+			},
+		})
+	}
+
+	return deliver(ctx, n.store, n.retry, ChannelEmail, kind, batch, targets, n.logger)
+}
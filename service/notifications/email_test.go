@@ -0,0 +1,244 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"boardfund/mailer"
+	"boardfund/mailer/mailertest"
+	"boardfund/service/payouts"
+
+	"github.com/google/uuid"
+)
+
+type memoryStore struct {
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+func (s *memoryStore) InsertDelivery(_ context.Context, arg InsertDelivery) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery := Delivery{
+		ID:        uuid.New(),
+		BatchID:   arg.BatchID,
+		Kind:      arg.Kind,
+		Channel:   arg.Channel,
+		Recipient: arg.Recipient,
+		Error:     arg.Error,
+		Created:   time.Now(),
+	}
+	s.deliveries = append(s.deliveries, delivery)
+
+	return &delivery, nil
+}
+
+func (s *memoryStore) GetDeliveriesForBatch(_ context.Context, batchID uuid.UUID) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Delivery
+	for _, delivery := range s.deliveries {
+		if delivery.BatchID == batchID {
+			out = append(out, delivery)
+		}
+	}
+
+	return out, nil
+}
+
+type fixedBatches struct {
+	detail *payouts.BatchDetail
+	err    error
+}
+
+func (f fixedBatches) GetDetailedBatchByID(context.Context, uuid.UUID) (*payouts.BatchDetail, error) {
+	return f.detail, f.err
+}
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func awaitingBatch() payouts.Batch {
+	deadline := time.Date(2026, 3, 5, 17, 30, 0, 0, time.UTC)
+
+	return payouts.Batch{
+		ID:               uuid.New(),
+		FundID:           uuid.New(),
+		AmountCents:      12345,
+		NumEnrollments:   2,
+		Status:           payouts.StatusAwaitingApproval,
+		ApprovalDeadline: &deadline,
+	}
+}
+
+func notifierAgainst(t *testing.T, server *mailertest.Server, batches batchReader, recipients ...string) (*EmailNotifier, *memoryStore) {
+	t.Helper()
+
+	m, err := mailer.NewMailer(mailer.Config{
+		Host: server.Host(),
+		Port: server.Port(),
+		From: "Fund <fund@example.org>",
+	})
+	if err != nil {
+		t.Fatalf("new mailer: %v", err)
+	}
+
+	store := &memoryStore{}
+
+	return NewEmailNotifier(m, batches, store, recipients, "https://fund.example.org/", NoRetry, quietLogger()), store
+}
+
+// Everything a treasurer needs to decide from the message alone, and the link
+// for when they have. A message that said "a batch needs approval" and nothing
+// else is one that gets left until later.
+func TestTheApprovalEmailSaysWhatIsBeingApproved(t *testing.T) {
+	server := mailertest.NewServer(t)
+	batch := awaitingBatch()
+
+	notifier, store := notifierAgainst(t, server, fixedBatches{detail: &payouts.BatchDetail{
+		Batch:    batch,
+		FundName: "Rent Relief",
+		Payees:   []payouts.Payee{{Name: "alice"}, {Name: "bob"}},
+	}}, "treasurer@example.org")
+
+	err := notifier.NotifyApprovalRequired(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	received := server.Received()
+	if len(received) != 1 {
+		t.Fatalf("server took %d messages, want 1", len(received))
+	}
+
+	for _, want := range []string{
+		"Subject: Payout for Rent Relief needs approval",
+		"$123.45 to 2 payees",
+		"alice, bob",
+		"Thu 5 Mar 2026 17:30 UTC",
+		"https://fund.example.org/admin/payout/" + batch.ID.String(),
+	} {
+		if !strings.Contains(received[0].Data, want) {
+			t.Errorf("message is missing %q:\n%s", want, received[0].Data)
+		}
+	}
+
+	deliveries, _ := store.GetDeliveriesForBatch(context.Background(), batch.ID)
+	if len(deliveries) != 1 || !deliveries[0].Delivered() || deliveries[0].Kind != KindApprovalRequired {
+		t.Errorf("the send should be recorded as delivered: %+v", deliveries)
+	}
+}
+
+func TestTheReminderIsItsOwnMessage(t *testing.T) {
+	server := mailertest.NewServer(t)
+	batch := awaitingBatch()
+
+	notifier, store := notifierAgainst(t, server, fixedBatches{detail: &payouts.BatchDetail{
+		Batch:    batch,
+		FundName: "Rent Relief",
+	}}, "treasurer@example.org")
+
+	err := notifier.NotifyApprovalExpiring(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	received := server.Received()
+	if len(received) != 1 || !strings.Contains(received[0].Data, "Subject: Reminder: payout for Rent Relief expires") {
+		t.Fatalf("expected a reminder subject, got %+v", received)
+	}
+
+	deliveries, _ := store.GetDeliveriesForBatch(context.Background(), batch.ID)
+	if len(deliveries) != 1 || deliveries[0].Kind != KindApprovalExpiring {
+		t.Errorf("the reminder should be recorded as one: %+v", deliveries)
+	}
+}
+
+// The failure this package exists for. The refused address is written down,
+// where the batch page can show it, and the other treasurer still hears -- so
+// the notification as a whole has worked and the sweep should not resend it.
+func TestARefusedRecipientIsRecordedAndDoesNotStopTheOthers(t *testing.T) {
+	server := mailertest.NewServer(t)
+	server.RejectRecipient("typo@example.org")
+	batch := awaitingBatch()
+
+	notifier, store := notifierAgainst(t, server, fixedBatches{err: errors.New("no detail")},
+		"typo@example.org", "treasurer@example.org")
+
+	err := notifier.NotifyApprovalRequired(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("one recipient reached is a notification sent: %v", err)
+	}
+
+	if len(server.Received()) != 1 {
+		t.Fatalf("server took %d messages, want 1", len(server.Received()))
+	}
+
+	deliveries, _ := store.GetDeliveriesForBatch(context.Background(), batch.ID)
+	if len(deliveries) != 2 {
+		t.Fatalf("want a row per recipient, got %d", len(deliveries))
+	}
+
+	byRecipient := map[string]Delivery{}
+	for _, delivery := range deliveries {
+		byRecipient[delivery.Recipient] = delivery
+	}
+
+	if byRecipient["typo@example.org"].Delivered() {
+		t.Error("the refused address should be recorded as a failure")
+	}
+
+	if !strings.Contains(byRecipient["typo@example.org"].Error, "550") {
+		t.Errorf("the failure should carry the server's reply: %q", byRecipient["typo@example.org"].Error)
+	}
+
+	if !byRecipient["treasurer@example.org"].Delivered() {
+		t.Error("the good address should be recorded as delivered")
+	}
+}
+
+// Nobody reached is an error, so the sweep leaves the reminder unmarked and
+// tries again next time.
+func TestReachingNobodyIsAnError(t *testing.T) {
+	server := mailertest.NewServer(t)
+	server.RejectRecipient("typo@example.org")
+	batch := awaitingBatch()
+
+	notifier, store := notifierAgainst(t, server, fixedBatches{err: errors.New("no detail")}, "typo@example.org")
+
+	err := notifier.NotifyApprovalExpiring(context.Background(), batch)
+	if !errors.Is(err, ErrNobodyReached) {
+		t.Fatalf("got %v, want ErrNobodyReached", err)
+	}
+
+	deliveries, _ := store.GetDeliveriesForBatch(context.Background(), batch.ID)
+	if len(deliveries) != 1 || deliveries[0].Delivered() {
+		t.Errorf("the miss should still be recorded: %+v", deliveries)
+	}
+}
+
+func TestAnUnreadableBatchStillSends(t *testing.T) {
+	server := mailertest.NewServer(t)
+	batch := awaitingBatch()
+
+	notifier, _ := notifierAgainst(t, server, fixedBatches{err: errors.New("database is down")}, "treasurer@example.org")
+
+	err := notifier.NotifyApprovalRequired(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	received := server.Received()
+	if len(received) != 1 || !strings.Contains(received[0].Data, batch.FundID.String()) {
+		t.Fatalf("expected a message naming the fund by id, got %+v", received)
+	}
+}
@@ -0,0 +1,83 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"boardfund/service/payouts"
+)
+
+// Fanout sends one notification down every configured channel.
+//
+// It satisfies the same interface as each channel, so the payout service is
+// handed one notifier and never learns how many there are. The channels run side
+// by side: each has its own retries, and a chat webhook backing off should not
+// hold the email up behind it.
+type Fanout struct {
+	channels []ApprovalNotifier
+
+	logger *slog.Logger
+}
+
+func NewFanout(logger *slog.Logger, channels ...ApprovalNotifier) *Fanout {
+	return &Fanout{channels: channels, logger: logger}
+}
+
+func (f Fanout) NotifyApprovalRequired(ctx context.Context, batch payouts.Batch) error {
+	return f.each(ctx, func(n ApprovalNotifier) error {
+		return n.NotifyApprovalRequired(ctx, batch)
+	})
+}
+
+func (f Fanout) NotifyApprovalExpiring(ctx context.Context, batch payouts.Batch) error {
+	return f.each(ctx, func(n ApprovalNotifier) error {
+		return n.NotifyApprovalExpiring(ctx, batch)
+	})
+}
+
+// each reports failure only when every channel failed, for the reason each
+// channel does the same with its recipients: the sweep resends on error, and a
+// reminder that reached the chat room should not go to the chat room again
+// because the mail relay was down.
+func (f Fanout) each(ctx context.Context, notify func(ApprovalNotifier) error) error {
+	if len(f.channels) == 0 {
+		return fmt.Errorf("%w: no channels configured", ErrNobodyReached)
+	}
+
+	errs := make([]error, len(f.channels))
+
+	var wg sync.WaitGroup
+	for i, channel := range f.channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			errs[i] = notify(channel)
+		}()
+	}
+
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed == len(f.channels) {
+		return fmt.Errorf("%w: %w", ErrNobodyReached, errors.Join(errs...))
+	}
+
+	if failed > 0 {
+		f.logger.WarnContext(ctx, "notification reached some channels but not all",
+			slog.Int("failed", failed),
+			slog.Int("channels", len(f.channels)),
+		)
+	}
+
+	return nil
+}
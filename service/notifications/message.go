@@ -0,0 +1,153 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+
+	"boardfund/service/payouts"
+)
+
+// message is one notification rendered for every channel at once. Subject and
+// Body are the email; Short is the one paragraph a chat room or the home page
+// has room for.
+type message struct {
+	Subject string
+	Body    string
+	Short   string
+}
+
+// describe renders a batch into a message.
+//
+// A failed lookup of the fund and payees still renders: a message that says
+// less than it should is better than the silence this package exists to end.
+func describe(ctx context.Context, batches batchReader, baseURL string, kind Kind, batch payouts.Batch, logger *slog.Logger) (message, error) {
+	detail := payouts.BatchDetail{Batch: batch}
+
+	found, err := batches.GetDetailedBatchByID(ctx, batch.ID)
+	if err != nil {
+		logger.WarnContext(ctx, "describing a batch without its detail",
+			slog.String("error", err.Error()),
+			slog.String("batch_id", batch.ID.String()),
+		)
+	} else {
+		detail = *found
+	}
+
+	view := messageView{
+		FundName: detail.FundName,
+		Amount:   dollars(batch.AmountCents),
+		Count:    int(batch.NumEnrollments),
+		Link:     fmt.Sprintf("%s/admin/payout/%s", baseURL, batch.ID),
+	}
+
+	if view.FundName == "" {
+		view.FundName = "fund " + batch.FundID.String()
+	}
+
+	for _, payee := range detail.Payees {
+		view.Payees = append(view.Payees, payee.Name)
+	}
+
+	if batch.ApprovalDeadline != nil {
+		view.Deadline = batch.ApprovalDeadline.UTC().Format(deadlineLayout)
+	}
+
+	templates := approvalRequired
+	if kind == KindApprovalExpiring {
+		templates = approvalExpiring
+	}
+
+	var out message
+	for name, into := range map[string]*string{"subject": &out.Subject, "body": &out.Body, "short": &out.Short} {
+		var buf bytes.Buffer
+
+		err = templates.ExecuteTemplate(&buf, name, view)
+		if err != nil {
+			return message{}, err
+		}
+
+		*into = buf.String()
+	}
+
+	out.Subject = strings.TrimSpace(out.Subject)
+	out.Short = strings.TrimSpace(out.Short)
+
+	return out, nil
+}
+
+// deadlineLayout is UTC with the zone spelled out. Treasurers are not all in
+// one place, and a bare "15:04" invites each to read it as their own.
+const deadlineLayout = "Mon 2 Jan 2006 15:04 MST"
+
+// shortPayees is how many names the one-paragraph form lists before it counts
+// the rest. A notice has a length limit and a chat line has a reader's patience.
+const shortPayees = 5
+
+type messageView struct {
+	FundName string
+	Amount   string
+	Count    int
+	Payees   []string
+	Deadline string
+	Link     string
+}
+
+func (v messageView) PayeeList() string {
+	if len(v.Payees) == 0 {
+		return "(none recorded)"
+	}
+
+	return strings.Join(v.Payees, ", ")
+}
+
+func (v messageView) ShortPayeeList() string {
+	if len(v.Payees) <= shortPayees {
+		return v.PayeeList()
+	}
+
+	return fmt.Sprintf("%s and %d more", strings.Join(v.Payees[:shortPayees], ", "), len(v.Payees)-shortPayees)
+}
+
+const messageDetail = `{{define "detail"}}Fund:     {{.FundName}}
+Amount:   {{.Amount}} to {{.Count}} {{if eq .Count 1}}payee{{else}}payees{{end}}
+Payees:   {{.PayeeList}}
+Deadline: {{if .Deadline}}{{.Deadline}}{{else}}none{{end}}
+
+Review it here:
+{{.Link}}
+{{end}}
+{{define "summary"}}{{.Amount}} to {{.Count}} {{if eq .Count 1}}payee{{else}}payees{{end}} ({{.ShortPayeeList}}){{if .Deadline}}, deadline {{.Deadline}}{{end}}. {{.Link}}{{end}}`
+
+var approvalRequired = template.Must(template.New("approval_required").Parse(messageDetail + `
+{{define "subject"}}Payout for {{.FundName}} needs approval{{end}}
+{{define "body"}}A payout batch for {{.FundName}} is waiting for approval. Nothing is sent until somebody approves it.
+
+{{template "detail" .}}
+If nobody approves it before the deadline it is cancelled, and nobody on it is paid this period.
+{{end}}
+{{define "short"}}Payout for {{.FundName}} needs approval: {{template "summary" .}}{{end}}`))
+
+var approvalExpiring = template.Must(template.New("approval_expiring").Parse(messageDetail + `
+{{define "subject"}}Reminder: payout for {{.FundName}} expires {{if .Deadline}}{{.Deadline}}{{else}}soon{{end}}{{end}}
+{{define "body"}}The payout batch for {{.FundName}} is still waiting for approval, and its window is about to close.
+
+{{template "detail" .}}
+Once the deadline passes the batch is cancelled and has to be planned again.
+{{end}}
+{{define "short"}}Payout for {{.FundName}} still needs approval and expires soon: {{template "summary" .}}{{end}}`))
+
+// dollars renders cents for a person. Unlike the admin pages, a message has no
+// column to right-align into, so no padding.
+func dollars(cents int32) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	return fmt.Sprintf("%s$%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"boardfund/service/notices"
+	"boardfund/service/payouts"
+
+	"github.com/google/uuid"
+)
+
+// noticeRecipient is what a notice delivery row names as its recipient. There
+// is nobody in particular: it is on the page for whoever looks.
+const noticeRecipient = "home page"
+
+type noticePoster interface {
+	Create(ctx context.Context, body string, actorID *uuid.UUID) (*notices.Notice, error)
+}
+
+// NoticeNotifier puts the message up as a notice on the home page.
+//
+// Seen by every member, not only admins, so it is the loudest of the channels
+// and off unless asked for. It does not take itself down once the batch is
+// decided -- that would mean the payout service knowing it is here -- so
+// whoever approves the batch takes the notice down from the notices page.
+type NoticeNotifier struct {
+	notices noticePoster
+	batches batchReader
+	store   deliveryStore
+	baseURL string
+	retry   Retry
+
+	logger *slog.Logger
+}
+
+func NewNoticeNotifier(
+	notices noticePoster,
+	batches batchReader,
+	store deliveryStore,
+	baseURL string,
+	retry Retry,
+	logger *slog.Logger,
+) *NoticeNotifier {
+	return &NoticeNotifier{
+		notices: notices,
+		batches: batches,
+		store:   store,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		retry:   retry,
+		logger:  logger,
+	}
+}
+
+func (n NoticeNotifier) NotifyApprovalRequired(ctx context.Context, batch payouts.Batch) error {
+	return n.notify(ctx, KindApprovalRequired, batch)
+}
+
+func (n NoticeNotifier) NotifyApprovalExpiring(ctx context.Context, batch payouts.Batch) error {
+	return n.notify(ctx, KindApprovalExpiring, batch)
+}
+
+func (n NoticeNotifier) notify(ctx context.Context, kind Kind, batch payouts.Batch) error {
+	msg, err := describe(ctx, n.batches, n.baseURL, kind, batch, n.logger)
+	if err != nil {
+		n.logger.ErrorContext(ctx, "failed to compose approval notice", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	body := fitNotice(msg.Short)
+
+	return deliver(ctx, n.store, n.retry, ChannelNotice, kind, batch, []target{{
+		recipient: noticeRecipient,
+		send: func(ctx context.Context) error {
+			// No actor: nobody put this up, the planner did.
+			_, errCreate := n.notices.Create(ctx, body, nil)
+
+			return errCreate
+		},
+	}}, n.logger)
+}
+
+// fitNotice trims a message to what the notice column holds. The short form is
+// already bounded in every part but the fund's name, so this only bites on a
+// fund named at unreasonable length -- and a clipped notice is still better
+// than a refused one.
+func fitNotice(body string) string {
+	if utf8.RuneCountInString(body) <= notices.MaxBodyLength {
+		return body
+	}
+
+	runes := []rune(body)
+
+	return string(runes[:notices.MaxBodyLength-1]) + "…"
+}
@@ -0,0 +1,56 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+
+	"boardfund/service/payouts"
+
+	"github.com/google/uuid"
+)
+
+type deliveryStore interface {
+	InsertDelivery(ctx context.Context, arg InsertDelivery) (*Delivery, error)
+	GetDeliveriesForBatch(ctx context.Context, batchID uuid.UUID) ([]Delivery, error)
+}
+
+// batchReader is how a notifier turns the batch it was handed into something
+// worth reading. The payout service passes a bare Batch -- a fund id and a
+// count -- and a message that said only that would be one nobody acted on.
+type batchReader interface {
+	GetDetailedBatchByID(ctx context.Context, id uuid.UUID) (*payouts.BatchDetail, error)
+}
+
+// ApprovalNotifier is the payout service's notifier, exported so the commands
+// that wire it can hold one without naming a concrete channel.
+type ApprovalNotifier interface {
+	NotifyApprovalRequired(ctx context.Context, batch payouts.Batch) error
+	NotifyApprovalExpiring(ctx context.Context, batch payouts.Batch) error
+}
+
+// Service reads back what was sent, for the batch page.
+type Service struct {
+	store deliveryStore
+
+	logger *slog.Logger
+}
+
+func NewService(store deliveryStore, logger *slog.Logger) *Service {
+	return &Service{store: store, logger: logger}
+}
+
+// DeliveriesForBatch is every attempt to tell somebody about a batch, newest
+// first.
+func (s Service) DeliveriesForBatch(ctx context.Context, batchID uuid.UUID) ([]Delivery, error) {
+	found, err := s.store.GetDeliveriesForBatch(ctx, batchID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read notification deliveries",
+			slog.String("error", err.Error()),
+			slog.String("batch_id", batchID.String()),
+		)
+
+		return nil, err
+	}
+
+	return found, nil
+}
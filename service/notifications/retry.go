@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Retry is how hard a channel tries before recording a failure.
+//
+// Per channel because the channels fail differently. A chat webhook that
+// answers 429 wants a few seconds and another go; a relay that refused a
+// mailbox will refuse it again however long it is given, and retrying it only
+// delays the rows the batch page needs.
+type Retry struct {
+	// Attempts is the total number of tries, the first included. Zero or one
+	// means no retrying.
+	Attempts int
+
+	// Backoff is the wait before the second attempt, doubled before each one
+	// after it.
+	Backoff time.Duration
+}
+
+// NoRetry is one attempt and no waiting.
+var NoRetry = Retry{Attempts: 1}
+
+// do runs send until it succeeds, attempts run out, or the context ends. The
+// error is the last one seen, saying how many tries it took to get it, so the
+// row on the batch page tells an operator whether the channel was flaky or
+// simply wrong.
+func (r Retry) do(ctx context.Context, send func(ctx context.Context) error) error {
+	attempts := max(r.Attempts, 1)
+	wait := r.Backoff
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = send(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == attempts {
+			break
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("gave up after %d of %d attempts: %w", attempt, attempts, err)
+		case <-timer.C:
+		}
+
+		wait *= 2
+	}
+
+	if attempts > 1 {
+		return fmt.Errorf("after %d attempts: %w", attempts, err)
+	}
+
+	return err
+}
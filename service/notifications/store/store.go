@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+
+	"boardfund/db"
+	"boardfund/service/notifications"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationStore struct {
+	queries *db.Queries
+	conn    *pgxpool.Pool
+}
+
+func NewNotificationStore(conn *pgxpool.Pool) NotificationStore {
+	return NotificationStore{
+		queries: db.New(conn),
+		conn:    conn,
+	}
+}
+
+func (s NotificationStore) InsertDelivery(ctx context.Context, arg notifications.InsertDelivery) (*notifications.Delivery, error) {
+	row, err := s.queries.InsertNotificationDelivery(ctx, db.InsertNotificationDeliveryParams{
+		ID:        uuid.New(),
+		BatchID:   arg.BatchID,
+		Kind:      db.NotificationKind(arg.Kind),
+		Channel:   db.NotificationChannel(arg.Channel),
+		Recipient: arg.Recipient,
+		Error:     pgtype.Text{String: arg.Error, Valid: arg.Error != ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := fromDB(row)
+
+	return &delivery, nil
+}
+
+func (s NotificationStore) GetDeliveriesForBatch(ctx context.Context, batchID uuid.UUID) ([]notifications.Delivery, error) {
+	rows, err := s.queries.GetNotificationDeliveriesByBatchId(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]notifications.Delivery, len(rows))
+	for i, row := range rows {
+		out[i] = fromDB(row)
+	}
+
+	return out, nil
+}
+
+func fromDB(row db.NotificationDelivery) notifications.Delivery {
+	return notifications.Delivery{
+		ID:        row.ID,
+		BatchID:   row.BatchID,
+		Kind:      notifications.Kind(row.Kind),
+		Channel:   notifications.Channel(row.Channel),
+		Recipient: row.Recipient,
+		Error:     row.Error.String,
+		Created:   row.Created.Time,
+	}
+}
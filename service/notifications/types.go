@@ -0,0 +1,69 @@
+// Package notifications tells people that a payout batch needs them.
+//
+// The payout service only knows that somebody should be told; this package is
+// who, how, and the record of whether it worked. That record is the point. A
+// batch that expires because nobody approved it looks, from the payout side,
+// exactly like one that expired because nobody was asked -- and only the second
+// is something an operator can fix.
+package notifications
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kind is what the message was about. Mirrors the notification_kind enum.
+type Kind string
+
+const (
+	KindApprovalRequired Kind = "approval_required"
+	KindApprovalExpiring Kind = "approval_expiring"
+)
+
+// Channel is how it travelled. Mirrors the notification_channel enum.
+type Channel string
+
+const (
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+	ChannelNotice  Channel = "notice"
+)
+
+// ErrNobodyReached is returned when every attempt for a message failed.
+//
+// Only then: a reminder that reached one treasurer out of three has done its
+// job, and reporting it as failed would have the sweep send it again to the two
+// who already have it. The misses are still recorded, one row each.
+var ErrNobodyReached = errors.New("notification reached nobody")
+
+// Delivery is one attempt to reach one recipient.
+type Delivery struct {
+	ID        uuid.UUID
+	BatchID   uuid.UUID
+	Kind      Kind
+	Channel   Channel
+	Recipient string
+
+	// Error is empty when the message was handed over, and otherwise what
+	// refused it.
+	Error string
+
+	Created time.Time
+}
+
+// Delivered reports whether the channel took the message. For email that is the
+// relay accepting it, which is as far as anybody can see.
+func (d Delivery) Delivered() bool {
+	return d.Error == ""
+}
+
+// InsertDelivery is what the store is given; the id and time are its business.
+type InsertDelivery struct {
+	BatchID   uuid.UUID
+	Kind      Kind
+	Channel   Channel
+	Recipient string
+	Error     string
+}
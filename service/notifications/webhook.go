@@ -0,0 +1,143 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"boardfund/service/payouts"
+)
+
+// webhookTimeout bounds one POST. Chat services answer in well under a second
+// when they answer at all.
+const webhookTimeout = 10 * time.Second
+
+// webhookPayload is what both Slack and Discord incoming webhooks accept. Slack
+// reads "text" and Discord reads "content"; each ignores the other's field, so
+// one body serves either without anybody configuring which is which.
+type webhookPayload struct {
+	Text    string `json:"text"`
+	Content string `json:"content"`
+}
+
+// WebhookNotifier posts a one-paragraph message to each configured chat
+// webhook.
+type WebhookNotifier struct {
+	client  *http.Client
+	batches batchReader
+	store   deliveryStore
+	urls    []string
+	baseURL string
+	retry   Retry
+
+	logger *slog.Logger
+}
+
+func NewWebhookNotifier(
+	batches batchReader,
+	store deliveryStore,
+	urls []string,
+	baseURL string,
+	retry Retry,
+	logger *slog.Logger,
+) *WebhookNotifier {
+	return &WebhookNotifier{
+		client:  &http.Client{Timeout: webhookTimeout},
+		batches: batches,
+		store:   store,
+		urls:    urls,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		retry:   retry,
+		logger:  logger,
+	}
+}
+
+func (n WebhookNotifier) NotifyApprovalRequired(ctx context.Context, batch payouts.Batch) error {
+	return n.notify(ctx, KindApprovalRequired, batch)
+}
+
+func (n WebhookNotifier) NotifyApprovalExpiring(ctx context.Context, batch payouts.Batch) error {
+	return n.notify(ctx, KindApprovalExpiring, batch)
+}
+
+func (n WebhookNotifier) notify(ctx context.Context, kind Kind, batch payouts.Batch) error {
+	msg, err := describe(ctx, n.batches, n.baseURL, kind, batch, n.logger)
+	if err != nil {
+		n.logger.ErrorContext(ctx, "failed to compose approval webhook", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	body, err := json.Marshal(webhookPayload{Text: msg.Short, Content: msg.Short})
+	if err != nil {
+		return err
+	}
+
+	targets := make([]target, 0, len(n.urls))
+	for _, hook := range n.urls {
+		targets = append(targets, target{
+			recipient: redactWebhook(hook),
+			send: func(ctx context.Context) error {
+				return n.post(ctx, hook, body)
+			},
+		})
+	}
+
+	return deliver(ctx, n.store, n.retry, ChannelWebhook, kind, batch, targets, n.logger)
+}
+
+func (n WebhookNotifier) post(ctx context.Context, hook string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// The client error names the URL, and the URL is the secret.
+		return fmt.Errorf("posting to %s: %w", redactWebhook(hook), unwrapURLError(err))
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// A little of what came back: Slack says "invalid_token" or
+		// "channel_not_found" in the body, and that is the part worth reading.
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+
+		return fmt.Errorf("webhook answered %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+
+	return nil
+}
+
+// redactWebhook is what a webhook is called anywhere it might be read. Slack
+// and Discord both put the credential in the path, so a full URL on the batch
+// page would hand anyone who can see it the means to post into the room.
+func redactWebhook(hook string) string {
+	parsed, err := url.Parse(hook)
+	if err != nil || parsed.Host == "" {
+		return "webhook"
+	}
+
+	return parsed.Scheme + "://" + parsed.Host + "/…"
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+
+	return err
+}
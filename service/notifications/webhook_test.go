@@ -0,0 +1,197 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"boardfund/service/notices"
+	"boardfund/service/payouts"
+
+	"github.com/google/uuid"
+)
+
+func rentRelief(batch payouts.Batch) fixedBatches {
+	return fixedBatches{detail: &payouts.BatchDetail{
+		Batch:    batch,
+		FundName: "Rent Relief",
+		Payees:   []payouts.Payee{{Name: "alice"}, {Name: "bob"}},
+	}}
+}
+
+// One body for both services, so nobody has to say which kind of room a URL
+// points at. Slack reads one field and Discord the other.
+func TestTheWebhookBodySuitsSlackAndDiscord(t *testing.T) {
+	var got webhookPayload
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("content type is %q", r.Header.Get("Content-Type"))
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer hook.Close()
+
+	batch := awaitingBatch()
+	store := &memoryStore{}
+	notifier := NewWebhookNotifier(rentRelief(batch), store, []string{hook.URL + "/services/T000/B000/secret"},
+		"https://fund.example.org", NoRetry, quietLogger())
+
+	err := notifier.NotifyApprovalRequired(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	if got.Text == "" || got.Text != got.Content {
+		t.Fatalf("want the same message in text and content, got %+v", got)
+	}
+
+	for _, want := range []string{"Rent Relief", "$123.45", "alice, bob", "https://fund.example.org/admin/payout/" + batch.ID.String()} {
+		if !strings.Contains(got.Text, want) {
+			t.Errorf("message is missing %q: %s", want, got.Text)
+		}
+	}
+
+	deliveries, _ := store.GetDeliveriesForBatch(context.Background(), batch.ID)
+	if len(deliveries) != 1 || deliveries[0].Channel != ChannelWebhook || !deliveries[0].Delivered() {
+		t.Fatalf("want one delivered webhook row, got %+v", deliveries)
+	}
+
+	// The path is the credential. It must not reach a page an admin can
+	// screenshot.
+	if strings.Contains(deliveries[0].Recipient, "secret") {
+		t.Errorf("the recorded recipient leaks the webhook path: %q", deliveries[0].Recipient)
+	}
+}
+
+// A chat service that is briefly unhappy gets another go, and the row records
+// the outcome rather than the first refusal.
+func TestAWebhookIsRetriedUntilItTakes(t *testing.T) {
+	var calls atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "rate_limited", http.StatusTooManyRequests)
+
+			return
+		}
+	}))
+	defer hook.Close()
+
+	batch := awaitingBatch()
+	store := &memoryStore{}
+	notifier := NewWebhookNotifier(rentRelief(batch), store, []string{hook.URL},
+		"https://fund.example.org", Retry{Attempts: 3, Backoff: time.Millisecond}, quietLogger())
+
+	err := notifier.NotifyApprovalExpiring(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	if calls.Load() != 3 {
+		t.Errorf("webhook was called %d times, want 3", calls.Load())
+	}
+
+	deliveries, _ := store.GetDeliveriesForBatch(context.Background(), batch.ID)
+	if len(deliveries) != 1 || !deliveries[0].Delivered() {
+		t.Fatalf("want one delivered row, got %+v", deliveries)
+	}
+}
+
+func TestAWebhookThatNeverTakesSaysWhatItAnswered(t *testing.T) {
+	var calls atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer hook.Close()
+
+	batch := awaitingBatch()
+	store := &memoryStore{}
+	notifier := NewWebhookNotifier(rentRelief(batch), store, []string{hook.URL},
+		"https://fund.example.org", Retry{Attempts: 2, Backoff: time.Millisecond}, quietLogger())
+
+	err := notifier.NotifyApprovalRequired(context.Background(), batch)
+	if !errors.Is(err, ErrNobodyReached) {
+		t.Fatalf("got %v, want ErrNobodyReached", err)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("webhook was called %d times, want 2", calls.Load())
+	}
+
+	deliveries, _ := store.GetDeliveriesForBatch(context.Background(), batch.ID)
+	if len(deliveries) != 1 || !strings.Contains(deliveries[0].Error, "invalid_token") ||
+		!strings.Contains(deliveries[0].Error, "after 2 attempts") {
+		t.Fatalf("the failure should say what the service said and how often it was asked: %+v", deliveries)
+	}
+}
+
+type failingChannel struct{}
+
+func (failingChannel) NotifyApprovalRequired(context.Context, payouts.Batch) error {
+	return errors.New("relay is down")
+}
+
+func (failingChannel) NotifyApprovalExpiring(context.Context, payouts.Batch) error {
+	return errors.New("relay is down")
+}
+
+type recordingPoster struct {
+	bodies []string
+}
+
+func (p *recordingPoster) Create(_ context.Context, body string, _ *uuid.UUID) (*notices.Notice, error) {
+	p.bodies = append(p.bodies, body)
+
+	return &notices.Notice{ID: uuid.New(), Body: body, Active: true}, nil
+}
+
+// The mail relay being down is no reason to tell the sweep the reminder failed
+// when the home page has it: the sweep would put it up again next time.
+func TestTheFanoutSucceedsIfAnyChannelDoes(t *testing.T) {
+	batch := awaitingBatch()
+	store := &memoryStore{}
+	poster := &recordingPoster{}
+
+	fanout := NewFanout(quietLogger(),
+		failingChannel{},
+		NewNoticeNotifier(poster, rentRelief(batch), store, "https://fund.example.org", NoRetry, quietLogger()),
+	)
+
+	err := fanout.NotifyApprovalExpiring(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("one channel reached is a notification sent: %v", err)
+	}
+
+	if len(poster.bodies) != 1 || !strings.Contains(poster.bodies[0], "Rent Relief") {
+		t.Fatalf("want one notice naming the fund, got %v", poster.bodies)
+	}
+
+	deliveries, _ := store.GetDeliveriesForBatch(context.Background(), batch.ID)
+	if len(deliveries) != 1 || deliveries[0].Channel != ChannelNotice || deliveries[0].Recipient != noticeRecipient {
+		t.Fatalf("want one notice row, got %+v", deliveries)
+	}
+}
+
+func TestTheFanoutFailsOnlyWhenEveryChannelDoes(t *testing.T) {
+	fanout := NewFanout(quietLogger(), failingChannel{}, failingChannel{})
+
+	err := fanout.NotifyApprovalRequired(context.Background(), awaitingBatch())
+	if !errors.Is(err, ErrNobodyReached) {
+		t.Fatalf("got %v, want ErrNobodyReached", err)
+	}
+}
+
+func TestALongNoticeIsClippedRatherThanRefused(t *testing.T) {
+	body := fitNotice(strings.Repeat("é", notices.MaxBodyLength+20))
+
+	if got := len([]rune(body)); got != notices.MaxBodyLength {
+		t.Fatalf("clipped to %d runes, want %d", got, notices.MaxBodyLength)
+	}
+}
@@ -0,0 +1,306 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"boardfund/service/fundevents"
+
+	"github.com/google/uuid"
+)
+
+// AllocationStrategy is how a fund divides a payout between its payees. Mirrors
+// the payout_allocation enum.
+type AllocationStrategy string
+
+const (
+	// AllocationEven splits what is available equally. What every fund did
+	// before there was a choice.
+	AllocationEven AllocationStrategy = "even"
+
+	// AllocationFixed pays each enrollment its own set amount.
+	AllocationFixed AllocationStrategy = "fixed"
+
+	// AllocationWeighted splits what is available in proportion to each
+	// enrollment's weight.
+	AllocationWeighted AllocationStrategy = "weighted"
+)
+
+var AllocationStrategies = []AllocationStrategy{AllocationEven, AllocationFixed, AllocationWeighted}
+
+func (s AllocationStrategy) Valid() bool {
+	for _, strategy := range AllocationStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	// ErrInvalidAllocation is a strategy or cap the fund cannot be given.
+	ErrInvalidAllocation = errors.New("invalid payout allocation")
+
+	// ErrNothingToAllocate means the fund cannot pay anybody anything this
+	// period after fees. Not a failure: donations may arrive before the next run.
+	ErrNothingToAllocate = errors.New("nothing available to pay out")
+
+	// ErrFixedShortfall means a fixed fund cannot cover every fixed amount.
+	//
+	// Nobody is paid rather than somebody short. A fixed amount is a promise to
+	// each payee, and paying whoever sorts first in full while the last gets
+	// nothing would be the planner choosing between them -- which is exactly the
+	// decision a fixed fund was set up not to make.
+	ErrFixedShortfall = errors.New("balance does not cover every fixed amount")
+)
+
+// Allocation is a fund's strategy and its optional per-payee cap.
+type Allocation struct {
+	Strategy AllocationStrategy
+
+	// CapCents is the most any one payee gets from one payout. Nil is no cap.
+	CapCents *int32
+}
+
+func (a Allocation) String() string {
+	strategy := a.Strategy
+	if strategy == "" {
+		strategy = AllocationEven
+	}
+
+	if a.CapCents == nil {
+		return string(strategy)
+	}
+
+	return fmt.Sprintf("%s, capped at %d cents each", strategy, *a.CapCents)
+}
+
+func (a Allocation) validate() error {
+	if !a.Strategy.Valid() {
+		return fmt.Errorf("%w: unknown strategy %q", ErrInvalidAllocation, a.Strategy)
+	}
+
+	if a.CapCents != nil && *a.CapCents <= 0 {
+		return fmt.Errorf("%w: a cap must be more than zero", ErrInvalidAllocation)
+	}
+
+	return nil
+}
+
+// allocated is what the allocator decided.
+type allocated struct {
+	// Amounts is keyed by enrollment id and holds only the enrollments being
+	// paid.
+	Amounts map[uuid.UUID]int32
+
+	TotalCents    int64
+	ReservedCents int64
+
+	// RemainderCents is what is left in the fund after this payout and its fees:
+	// rounding, what the cap held back, what a fixed fund did not need. It is
+	// not paid to anyone now and is there for the next payout.
+	RemainderCents int64
+}
+
+// Payees is how many enrollments are being paid.
+func (a allocated) Payees() int {
+	return len(a.Amounts)
+}
+
+// allocate divides available between the payable enrollments.
+//
+// Fees are reserved only for the enrollments actually being paid. Before this,
+// every payable enrollee was paid, so the two counts were the same; now a zero
+// weight or a missing fixed amount leaves somebody out, and reserving a fee for
+// a payout that will not be sent would hold back money for nothing.
+//
+// Every strategy floors. A cent that cannot be divided stays in the fund rather
+// than going to whoever sorts first, the same as the even split always did.
+func allocate(allocation Allocation, available int64, payable []PayoutEnrollment) (allocated, error) {
+	strategy := allocation.Strategy
+	if strategy == "" {
+		strategy = AllocationEven
+	}
+
+	var amounts map[uuid.UUID]int64
+	var err error
+
+	switch strategy {
+	case AllocationEven:
+		amounts, err = allocateEven(available, payable)
+	case AllocationFixed:
+		amounts, err = allocateFixed(available, payable)
+	case AllocationWeighted:
+		amounts, err = allocateWeighted(available, payable)
+	default:
+		return allocated{}, fmt.Errorf("%w: unknown strategy %q", ErrInvalidAllocation, strategy)
+	}
+
+	if err != nil {
+		return allocated{}, err
+	}
+
+	result := allocated{Amounts: make(map[uuid.UUID]int32, len(amounts))}
+	for id, amount := range amounts {
+		if allocation.CapCents != nil && amount > int64(*allocation.CapCents) {
+			amount = int64(*allocation.CapCents)
+		}
+
+		if amount <= 0 {
+			continue
+		}
+
+		result.Amounts[id] = int32(amount)
+		result.TotalCents += amount
+	}
+
+	if len(result.Amounts) == 0 {
+		return allocated{}, ErrNothingToAllocate
+	}
+
+	result.ReservedCents = PayoutFeeCents * int64(len(result.Amounts))
+	result.RemainderCents = available - result.ReservedCents - result.TotalCents
+
+	return result, nil
+}
+
+func allocateEven(available int64, payable []PayoutEnrollment) (map[uuid.UUID]int64, error) {
+	if len(payable) == 0 {
+		return nil, ErrNothingToAllocate
+	}
+
+	budget := available - PayoutFeeCents*int64(len(payable))
+
+	perHead := budget / int64(len(payable))
+	if perHead <= 0 {
+		return nil, ErrNothingToAllocate
+	}
+
+	amounts := make(map[uuid.UUID]int64, len(payable))
+	for _, enrollment := range payable {
+		amounts[enrollment.ID] = perHead
+	}
+
+	return amounts, nil
+}
+
+func allocateFixed(available int64, payable []PayoutEnrollment) (map[uuid.UUID]int64, error) {
+	amounts := make(map[uuid.UUID]int64, len(payable))
+
+	var needed int64
+	for _, enrollment := range payable {
+		if enrollment.FixedAmountCents == nil || *enrollment.FixedAmountCents <= 0 {
+			continue
+		}
+
+		amounts[enrollment.ID] = int64(*enrollment.FixedAmountCents)
+		needed += int64(*enrollment.FixedAmountCents) + PayoutFeeCents
+	}
+
+	if len(amounts) == 0 {
+		return nil, ErrNothingToAllocate
+	}
+
+	if needed > available {
+		return nil, fmt.Errorf("%w: need %d cents including fees, have %d", ErrFixedShortfall, needed, available)
+	}
+
+	return amounts, nil
+}
+
+func allocateWeighted(available int64, payable []PayoutEnrollment) (map[uuid.UUID]int64, error) {
+	var shares int64
+	weighted := make([]PayoutEnrollment, 0, len(payable))
+	for _, enrollment := range payable {
+		if enrollment.Weight <= 0 {
+			continue
+		}
+
+		weighted = append(weighted, enrollment)
+		shares += int64(enrollment.Weight)
+	}
+
+	if len(weighted) == 0 {
+		return nil, ErrNothingToAllocate
+	}
+
+	budget := available - PayoutFeeCents*int64(len(weighted))
+	if budget <= 0 {
+		return nil, ErrNothingToAllocate
+	}
+
+	amounts := make(map[uuid.UUID]int64, len(weighted))
+	for _, enrollment := range weighted {
+		amounts[enrollment.ID] = budget * int64(enrollment.Weight) / shares
+	}
+
+	return amounts, nil
+}
+
+// SetFundAllocation changes how a fund divides its future payouts. Batches
+// already planned keep the amounts they were planned with: those are what a
+// treasurer has been asked to approve.
+func (s PayoutService) SetFundAllocation(ctx context.Context, fundID uuid.UUID, allocation Allocation) (*Allocation, error) {
+	err := allocation.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.payoutStore.SetFundAllocation(ctx, SetFundAllocation{FundID: fundID, Allocation: allocation})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to set fund allocation",
+			slog.String("error", err.Error()),
+			slog.String("fund_id", fundID.String()),
+		)
+
+		return nil, err
+	}
+
+	s.events.Record(ctx, fundevents.Record{
+		FundID: fundID,
+		Kind:   fundevents.KindFundUpdated,
+		Detail: "payouts now allocated " + stored.String(),
+	})
+
+	return stored, nil
+}
+
+// SetEnrollmentShare sets what one enrollment is owed under a fixed or weighted
+// allocation. Both are stored whatever the fund's strategy is now, so switching
+// strategy later does not mean entering everybody's share again.
+func (s PayoutService) SetEnrollmentShare(ctx context.Context, enrollmentID uuid.UUID, share EnrollmentShare) (*PayoutEnrollment, error) {
+	if share.Weight < 0 {
+		return nil, fmt.Errorf("%w: a weight cannot be negative", ErrInvalidAllocation)
+	}
+
+	if share.FixedAmountCents != nil && *share.FixedAmountCents <= 0 {
+		return nil, fmt.Errorf("%w: a fixed amount must be more than zero", ErrInvalidAllocation)
+	}
+
+	enrollment, err := s.payoutStore.SetEnrollmentShare(ctx, SetEnrollmentShare{EnrollmentID: enrollmentID, Share: share})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to set enrollment share",
+			slog.String("error", err.Error()),
+			slog.String("enrollment_id", enrollmentID.String()),
+		)
+
+		return nil, err
+	}
+
+	detail := fmt.Sprintf("payout weight %d", enrollment.Weight)
+	if enrollment.FixedAmountCents != nil {
+		detail += fmt.Sprintf(", fixed amount %d cents", *enrollment.FixedAmountCents)
+	}
+
+	s.events.Record(ctx, fundevents.Record{
+		FundID:          enrollment.FundID,
+		Kind:            fundevents.KindFundUpdated,
+		SubjectMemberID: &enrollment.MemberID,
+		Detail:          detail,
+	})
+
+	return enrollment, nil
+}
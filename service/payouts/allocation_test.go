@@ -0,0 +1,148 @@
+package payouts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func payee(fixed int32, weight int32) PayoutEnrollment {
+	e := PayoutEnrollment{ID: uuid.New(), Weight: weight}
+	if fixed > 0 {
+		e.FixedAmountCents = &fixed
+	}
+
+	return e
+}
+
+func cents(n int32) *int32 { return &n }
+
+// Whatever the strategy, what leaves the account -- the payouts and a fee for
+// each of them -- is never more than the fund holds, and what is not sent is
+// accounted for as the remainder.
+func checkFits(t *testing.T, got allocated, available int64) {
+	t.Helper()
+
+	if got.TotalCents+got.ReservedCents > available {
+		t.Fatalf("allocated %d plus %d in fees against %d available", got.TotalCents, got.ReservedCents, available)
+	}
+
+	if got.TotalCents+got.ReservedCents+got.RemainderCents != available {
+		t.Fatalf("%d + %d + %d does not add back up to %d", got.TotalCents, got.ReservedCents, got.RemainderCents, available)
+	}
+}
+
+func TestEvenIsTheSplitFundsAlwaysHad(t *testing.T) {
+	payable := []PayoutEnrollment{payee(0, 1), payee(0, 1), payee(0, 1)}
+
+	got, err := allocate(Allocation{}, 1000, payable)
+	if err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+
+	checkFits(t, got, 1000)
+
+	// The numbers the planner test has always asserted: 925 after fees floors to
+	// 308 each and the odd cent stays put.
+	for _, e := range payable {
+		if got.Amounts[e.ID] != 308 {
+			t.Errorf("paid %d, want 308", got.Amounts[e.ID])
+		}
+	}
+
+	if got.RemainderCents != 1 {
+		t.Errorf("remainder %d, want 1", got.RemainderCents)
+	}
+}
+
+func TestFixedPaysEachTheirOwnAmount(t *testing.T) {
+	small, large, unset := payee(500, 1), payee(2000, 1), payee(0, 1)
+
+	got, err := allocate(Allocation{Strategy: AllocationFixed}, 10000, []PayoutEnrollment{small, large, unset})
+	if err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+
+	checkFits(t, got, 10000)
+
+	if got.Amounts[small.ID] != 500 || got.Amounts[large.ID] != 2000 {
+		t.Errorf("got %v, want 500 and 2000", got.Amounts)
+	}
+
+	// No amount set is not paid, and no fee is held back for it.
+	if _, ok := got.Amounts[unset.ID]; ok || got.ReservedCents != 2*PayoutFeeCents {
+		t.Errorf("an enrollment without a fixed amount was counted: %+v", got)
+	}
+}
+
+func TestFixedPaysNobodyRatherThanSomebodyShort(t *testing.T) {
+	_, err := allocate(Allocation{Strategy: AllocationFixed}, 1000, []PayoutEnrollment{payee(500, 1), payee(500, 1)})
+	if !errors.Is(err, ErrFixedShortfall) {
+		t.Fatalf("got %v, want ErrFixedShortfall", err)
+	}
+}
+
+func TestWeightedSplitsByShare(t *testing.T) {
+	one, three, none := payee(0, 1), payee(0, 3), payee(0, 0)
+
+	got, err := allocate(Allocation{Strategy: AllocationWeighted}, 4050, []PayoutEnrollment{one, three, none})
+	if err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+
+	checkFits(t, got, 4050)
+
+	// 4050 less two fees is 4000, a quarter and three quarters of it.
+	if got.Amounts[one.ID] != 1000 || got.Amounts[three.ID] != 3000 {
+		t.Errorf("got %v, want 1000 and 3000", got.Amounts)
+	}
+
+	if _, ok := got.Amounts[none.ID]; ok {
+		t.Error("a zero weight was paid")
+	}
+}
+
+func TestTheCapLeavesTheRestForNextTime(t *testing.T) {
+	payable := []PayoutEnrollment{payee(0, 1), payee(0, 1)}
+
+	got, err := allocate(Allocation{Strategy: AllocationEven, CapCents: cents(1000)}, 5050, payable)
+	if err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+
+	checkFits(t, got, 5050)
+
+	for _, e := range payable {
+		if got.Amounts[e.ID] != 1000 {
+			t.Errorf("paid %d, want the cap of 1000", got.Amounts[e.ID])
+		}
+	}
+
+	if got.RemainderCents != 3000 {
+		t.Errorf("remainder %d, want 3000 rolled forward", got.RemainderCents)
+	}
+}
+
+func TestNothingToAllocate(t *testing.T) {
+	cases := []struct {
+		name       string
+		allocation Allocation
+		available  int64
+		payable    []PayoutEnrollment
+	}{
+		{"nobody payable", Allocation{}, 1000, nil},
+		{"fees eat it all", Allocation{}, 70, []PayoutEnrollment{payee(0, 1), payee(0, 1), payee(0, 1)}},
+		{"nobody has a fixed amount", Allocation{Strategy: AllocationFixed}, 1000, []PayoutEnrollment{payee(0, 1)}},
+		{"every weight is zero", Allocation{Strategy: AllocationWeighted}, 1000, []PayoutEnrollment{payee(0, 0)}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := allocate(tc.allocation, tc.available, tc.payable)
+			if !errors.Is(err, ErrNothingToAllocate) {
+				t.Fatalf("got %v, want ErrNothingToAllocate", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package payouts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"boardfund/pg"
+	"boardfund/service/payouts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLargeBatchesNeedTwoApprovers(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	// Three payees at $10 is a $30 batch, above a $25 threshold.
+	threshold := int32(2500)
+
+	t.Run("the first approval does not release the batch, the second does", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "A-1"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 3)
+		require.NoError(t, svc.SetFundSecondApproval(ctx, fundID, &threshold))
+
+		first, second := seedMember(t, ctx, pool), seedMember(t, ctx, pool)
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000, RequireApproval: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), batch.ApprovalsRequired)
+
+		once, err := svc.ApproveBatch(ctx, batch.ID, first)
+		require.NoError(t, err)
+		assert.Equal(t, payouts.StatusAwaitingApproval, once.Status)
+		assert.True(t, once.AwaitingSecondApproval())
+
+		_, err = svc.ApproveBatch(ctx, batch.ID, first)
+		require.ErrorIs(t, err, payouts.ErrAlreadyApproved, "one admin cannot be both approvers")
+
+		twice, err := svc.ApproveBatch(ctx, batch.ID, second)
+		require.NoError(t, err)
+		assert.Equal(t, payouts.StatusReady, twice.Status)
+
+		approvals, err := svc.GetApprovalsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+		require.Len(t, approvals, 2)
+		assert.Equal(t, first, approvals[0].ApproverID)
+		assert.Equal(t, second, approvals[1].ApproverID)
+	})
+
+	t.Run("an approver enrolled in the fund is refused", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "A-2"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 2)
+		enrolled := seedMember(t, ctx, pool)
+		seedEnrollment(t, ctx, pool, fundID, enrolled, "self@example.org")
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000, RequireApproval: true,
+		})
+		require.NoError(t, err)
+
+		_, err = svc.ApproveBatch(ctx, batch.ID, enrolled)
+		require.ErrorIs(t, err, payouts.ErrApproverEnrolled)
+
+		approvals, err := svc.GetApprovalsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+		assert.Empty(t, approvals)
+	})
+
+	t.Run("striking below the threshold needs only one", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "A-3"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 3)
+		require.NoError(t, svc.SetFundSecondApproval(ctx, fundID, &threshold))
+
+		treasurer := seedMember(t, ctx, pool)
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000, RequireApproval: true,
+		})
+		require.NoError(t, err)
+
+		items, err := svc.GetPayoutsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+
+		struck, err := svc.StrikePayout(ctx, batch.ID, items[0].ID, treasurer, "")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), struck.ApprovalsRequired)
+
+		approved, err := svc.ApproveBatch(ctx, batch.ID, treasurer)
+		require.NoError(t, err)
+		assert.Equal(t, payouts.StatusReady, approved.Status)
+	})
+
+	t.Run("a batch above the threshold cannot skip approval", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "A-4"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 3)
+		require.NoError(t, svc.SetFundSecondApproval(ctx, fundID, &threshold))
+
+		_, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000,
+		})
+		require.ErrorIs(t, err, payouts.ErrNeedsTwoApprovals)
+	})
+}
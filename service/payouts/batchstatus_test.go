@@ -1,6 +1,10 @@
 package payouts
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
 
 // A batch of one UNCLAIMED item came back from PayPal as SUCCESS, and the fund
 // page said "paid" about a dollar nobody had received. The batch's status has to
@@ -44,6 +48,14 @@ func TestBatchStatusFrom(t *testing.T) {
 			items:    []Payout{item(StatusPaid), item(StatusReturned)},
 			want:     StatusFailed,
 		},
+		{
+			// Struck before approval and never sent. Counting it would fail a
+			// batch that paid everyone it was sent to.
+			name:     "a struck payout does not hold the batch back",
+			provider: StatusPaid,
+			items:    []Payout{item(StatusPaid), item(StatusStruck)},
+			want:     StatusPaid,
+		},
 		{
 			name:     "all returned",
 			provider: StatusPaid,
@@ -81,3 +93,20 @@ func TestBatchStatusFrom(t *testing.T) {
 		})
 	}
 }
+
+// An unregistered address is the one failure a treasurer can fix without
+// reading PayPal's wording, so it is recorded in ours.
+func TestItemFailureReason(t *testing.T) {
+	if got := itemFailureReason(nil); got != "" {
+		t.Errorf("no error gave %q", got)
+	}
+
+	wrapped := fmt.Errorf("Receiver is unregistered: %w", ErrReceiverUnregistered)
+	if got := itemFailureReason(wrapped); got != ErrReceiverUnregistered.Error() {
+		t.Errorf("unregistered receiver gave %q", got)
+	}
+
+	if got := itemFailureReason(errors.New("Receiver is blocked")); got != "Receiver is blocked" {
+		t.Errorf("anything else should keep the provider's words, got %q", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -35,6 +36,46 @@ var (
 	// different things done about them, and distinct from the raw pgx error,
 	// which reaches a CLI user as "no rows in result set".
 	ErrFundNotFound = errors.New("fund not found")
+
+	// ErrEnrollmentNotFound is the same for an enrollment id.
+	ErrEnrollmentNotFound = errors.New("enrollment not found")
+
+	// ErrPayoutNotFound is returned when a payout is not in the batch named, or
+	// has already been struck from it.
+	ErrPayoutNotFound = errors.New("payout not found in batch")
+
+	// ErrApproverEnrolled is returned when an admin tries to approve a payout
+	// from a fund they are, or were, enrolled in. Approving money to yourself is
+	// not an approval, and neither is approving the batch that pays you along
+	// with others.
+	ErrApproverEnrolled = errors.New("approver is enrolled in the fund being paid")
+
+	// ErrAlreadyApproved is returned when the same admin approves a batch twice.
+	// A batch needing two approvals needs two people.
+	ErrAlreadyApproved = errors.New("batch already approved by this admin; it needs a different one")
+
+	// ErrNeedsTwoApprovals is returned when a batch above the second-approval
+	// threshold is planned without the approval gate. Skipping approval is for
+	// batches one person could approve anyway.
+	ErrNeedsTwoApprovals = errors.New("batch is above the second-approval threshold and must be approved by two admins")
+
+	// ErrInvalidThreshold is a second-approval threshold of zero or less, which
+	// would put every batch behind two people by accident.
+	ErrInvalidThreshold = errors.New("a second-approval threshold must be more than zero")
+
+	// ErrLastPayout is returned when striking would leave a batch paying
+	// nobody. That is a rejection, and should be recorded as one.
+	ErrLastPayout = errors.New("cannot strike the last payout in a batch; reject the batch instead")
+
+	// ErrInsufficientFunds is returned when the provider refuses a batch because
+	// the account paying it does not hold enough. Unlike most submission
+	// failures it is certain nothing was sent, and it will not clear up by
+	// itself: someone has to move money into the account first.
+	ErrInsufficientFunds = errors.New("the payout account does not hold enough to pay this batch")
+
+	// ErrReceiverUnregistered is a payout the provider could not deliver because
+	// nobody has an account at the address it was sent to.
+	ErrReceiverUnregistered = errors.New("the payout address has no account with the provider")
 )
 
 // DefaultApprovalWindow is how long a treasurer has to approve a batch before it
@@ -61,8 +102,13 @@ type payoutStore interface {
 	GetFundBalanceCents(ctx context.Context, fundID uuid.UUID) (int64, error)
 	AdvanceFundNextPayment(ctx context.Context, fundID uuid.UUID) error
 	IsFundActive(ctx context.Context, fundID uuid.UUID) (bool, error)
+	SetFundAllocation(ctx context.Context, arg SetFundAllocation) (*Allocation, error)
+	SetEnrollmentShare(ctx context.Context, arg SetEnrollmentShare) (*PayoutEnrollment, error)
 	ApproveBatch(ctx context.Context, arg ApproveBatch) (*Batch, error)
 	RejectBatch(ctx context.Context, arg RejectBatch) (*Batch, error)
+	StrikePayout(ctx context.Context, arg StrikePayout) (*StruckPayout, error)
+	GetApprovalsForBatch(ctx context.Context, batchID uuid.UUID) ([]Approval, error)
+	SetFundSecondApproval(ctx context.Context, arg SetFundSecondApproval) error
 	CancelExpiredBatches(ctx context.Context) ([]Batch, error)
 	GetBatchesNeedingReminder(ctx context.Context, within time.Duration) ([]Batch, error)
 	MarkReminderSent(ctx context.Context, batchID uuid.UUID) (*Batch, error)
@@ -70,6 +116,8 @@ type payoutStore interface {
 	SetBatchStatus(ctx context.Context, arg SetBatchStatus) (*Batch, error)
 	SetPayoutResult(ctx context.Context, arg SetPayoutResult) (*Payout, error)
 	SetPayoutStatusByProviderItemID(ctx context.Context, arg SetPayoutStatusByItem) (*Payout, error)
+	GetPayoutByID(ctx context.Context, id uuid.UUID) (*Payout, error)
+	GetPayoutByProviderItemID(ctx context.Context, itemID string) (*Payout, error)
 }
 
 // PayoutsProvider is the payments provider's batch payout surface. SubmitBatch must
@@ -102,10 +150,16 @@ type PayoutService struct {
 	approvalWindow time.Duration
 	reminderWindow time.Duration
 
+	// secondApprovalAbove is the site-wide threshold above which a batch needs
+	// two admins, for funds that do not set their own. Nil is no threshold.
+	secondApprovalAbove *int32
+
 	logger *slog.Logger
 }
 
-func NewPayoutService(payoutStore payoutStore, provider PayoutsProvider, notifier approvalNotifier, events eventRecorder, approvalWindow, reminderWindow time.Duration, logger *slog.Logger) *PayoutService {
+// NewPayoutService takes the site's second-approval threshold in cents, with
+// zero meaning none: a fund can still set its own.
+func NewPayoutService(payoutStore payoutStore, provider PayoutsProvider, notifier approvalNotifier, events eventRecorder, approvalWindow, reminderWindow time.Duration, secondApprovalAboveCents int32, logger *slog.Logger) *PayoutService {
 	if approvalWindow <= 0 {
 		approvalWindow = DefaultApprovalWindow
 	}
@@ -114,6 +168,11 @@ func NewPayoutService(payoutStore payoutStore, provider PayoutsProvider, notifie
 		reminderWindow = DefaultReminderWindow
 	}
 
+	var secondApprovalAbove *int32
+	if secondApprovalAboveCents > 0 {
+		secondApprovalAbove = &secondApprovalAboveCents
+	}
+
 	return &PayoutService{
 		payoutStore:    payoutStore,
 		provider:       provider,
@@ -121,7 +180,10 @@ func NewPayoutService(payoutStore payoutStore, provider PayoutsProvider, notifie
 		events:         events,
 		approvalWindow: approvalWindow,
 		reminderWindow: reminderWindow,
-		logger:         logger,
+
+		secondApprovalAbove: secondApprovalAbove,
+
+		logger: logger,
 	}
 }
 
@@ -188,31 +250,49 @@ func (s PayoutService) PlanBatch(ctx context.Context, req PlanBatch) (*Batch, er
 		status = StatusReady
 	}
 
+	var total int32
 	items := make([]InsertPayout, 0, len(payable))
 	for _, enrollment := range payable {
+		amount := req.AmountCents
+		if req.Amounts != nil {
+			amount = req.Amounts[enrollment.ID]
+		}
+
+		if amount <= 0 {
+			continue
+		}
+
 		items = append(items, InsertPayout{
 			ID:               uuid.New(),
 			FundEnrollmentID: enrollment.ID,
 			BatchID:          batchID,
-			AmountCents:      req.AmountCents,
+			AmountCents:      amount,
 			Status:           StatusPlanned,
 			Description:      req.Description,
 			PayoutDate:       req.PayoutDate,
 			DestinationEmail: enrollment.PaypalEmail,
 		})
+
+		total += amount
+	}
+
+	if len(items) == 0 {
+		return nil, ErrNoEnrollments
 	}
 
 	insert := InsertBatch{
 		ID:               batchID,
 		FundID:           req.FundID,
 		SenderBatchID:    uuid.New(),
-		AmountCents:      req.AmountCents * int32(len(payable)),
-		NumEnrollments:   int32(len(payable)),
+		AmountCents:      total,
+		NumEnrollments:   int32(len(items)),
 		Status:           status,
 		Description:      req.Description,
 		Notes:            req.Notes,
 		PayoutDate:       req.PayoutDate,
 		ApprovalDeadline: deadline,
+
+		SecondApprovalAboveCents: s.secondApprovalAbove,
 	}
 
 	batch, _, err := s.payoutStore.CreateBatchWithPayouts(ctx, insert, items)
@@ -255,16 +335,48 @@ func (s PayoutService) ApproveBatch(ctx context.Context, batchID, approvedBy uui
 	batch, err := s.payoutStore.ApproveBatch(ctx, ApproveBatch{
 		BatchID:    batchID,
 		ApprovedBy: approvedBy,
+
+		SecondApprovalAboveCents: s.secondApprovalAbove,
 	})
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to approve batch",
 			slog.String("error", err.Error()),
 			slog.String("batch_id", batchID.String()),
+			slog.String("approved_by", approvedBy.String()),
 		)
 
+		// Refused for who is asking, not for the state of the batch. Wrapping
+		// these as not approvable would tell the admin the batch had gone when
+		// it is waiting for somebody else.
+		if errors.Is(err, ErrApproverEnrolled) || errors.Is(err, ErrAlreadyApproved) {
+			return nil, err
+		}
+
 		return nil, fmt.Errorf("%w: %w", ErrNotApprovable, err)
 	}
 
+	if batch.Status == StatusAwaitingApproval {
+		// Half of a two-person approval. Its own kind, so the public timeline's
+		// "approved" still means the batch was cleared to send.
+		s.events.Record(ctx, fundevents.Record{
+			FundID:        batch.FundID,
+			Kind:          fundevents.KindBatchFirstApproval,
+			ActorMemberID: &approvedBy,
+			AmountCents:   &batch.AmountCents,
+			Detail:        fmt.Sprintf("%d of %d approvals", batch.ApprovalsGiven, batch.ApprovalsRequired),
+			ReferenceID:   &batch.ID,
+		})
+
+		s.logger.InfoContext(ctx, "batch approval recorded, another needed",
+			slog.String("batch_id", batch.ID.String()),
+			slog.String("approved_by", approvedBy.String()),
+			slog.Int("approvals_given", int(batch.ApprovalsGiven)),
+			slog.Int("approvals_required", int(batch.ApprovalsRequired)),
+		)
+
+		return batch, nil
+	}
+
 	// The one event with a real actor throughout: a person authorised money to
 	// leave the fund, and the feed should say who.
 	s.events.Record(ctx, fundevents.Record{
@@ -283,6 +395,43 @@ func (s PayoutService) ApproveBatch(ctx context.Context, batchID, approvedBy uui
 	return batch, nil
 }
 
+// GetApprovalsForBatch is who approved a batch, oldest first.
+func (s PayoutService) GetApprovalsForBatch(ctx context.Context, batchID uuid.UUID) ([]Approval, error) {
+	return s.payoutStore.GetApprovalsForBatch(ctx, batchID)
+}
+
+// SetFundSecondApproval sets the amount above which a fund's batches need two
+// admins to approve them. Nil falls back to the site's threshold. Batches
+// already planned pick the change up when they are next approved.
+func (s PayoutService) SetFundSecondApproval(ctx context.Context, fundID uuid.UUID, aboveCents *int32) error {
+	if aboveCents != nil && *aboveCents <= 0 {
+		return ErrInvalidThreshold
+	}
+
+	err := s.payoutStore.SetFundSecondApproval(ctx, SetFundSecondApproval{FundID: fundID, AboveCents: aboveCents})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to set second-approval threshold",
+			slog.String("error", err.Error()),
+			slog.String("fund_id", fundID.String()),
+		)
+
+		return err
+	}
+
+	detail := "payouts need one approval unless the site says otherwise"
+	if aboveCents != nil {
+		detail = fmt.Sprintf("payouts above %d cents need two approvals", *aboveCents)
+	}
+
+	s.events.Record(ctx, fundevents.Record{
+		FundID: fundID,
+		Kind:   fundevents.KindFundUpdated,
+		Detail: detail,
+	})
+
+	return nil
+}
+
 func (s PayoutService) RejectBatch(ctx context.Context, batchID uuid.UUID, reason string) (*Batch, error) {
 	if reason == "" {
 		reason = "rejected by treasurer"
@@ -316,6 +465,78 @@ func (s PayoutService) RejectBatch(ctx context.Context, batchID uuid.UUID, reaso
 	return batch, nil
 }
 
+// StrikePayout takes one payee out of a batch awaiting approval, so that one
+// wrong PayPal address does not mean rejecting everybody's payout with it.
+//
+// The batch's total and count are recomputed from what is left, so the amount
+// a treasurer then approves is the amount that will be sent. The struck payout
+// stays on the batch with its reason, and the fund's feed records who struck it
+// -- the only record of why somebody expecting money did not get it this time.
+//
+// The money stays in the fund: the balance stops counting a struck payout as
+// committed, and the next period's plan offers it again.
+func (s PayoutService) StrikePayout(ctx context.Context, batchID, payoutID, struckBy uuid.UUID, reason string) (*Batch, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "struck by treasurer"
+	}
+
+	result, err := s.payoutStore.StrikePayout(ctx, StrikePayout{
+		BatchID:  batchID,
+		PayoutID: payoutID,
+		StruckBy: struckBy,
+		Reason:   reason,
+
+		SecondApprovalAboveCents: s.secondApprovalAbove,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to strike payout",
+			slog.String("error", err.Error()),
+			slog.String("batch_id", batchID.String()),
+			slog.String("payout_id", payoutID.String()),
+		)
+
+		return nil, err
+	}
+
+	batch, struck := result.Batch, result.Payout
+
+	s.events.Record(ctx, fundevents.Record{
+		FundID:          batch.FundID,
+		Kind:            fundevents.KindPayoutStruck,
+		ActorMemberID:   &struckBy,
+		SubjectMemberID: &result.MemberID,
+		AmountCents:     &struck.AmountCents,
+		Detail:          fmt.Sprintf("%s removed from batch: %s", struck.DestinationEmail, reason),
+		ReferenceID:     &batch.ID,
+	})
+
+	s.logger.InfoContext(ctx, "payout struck from batch",
+		slog.String("batch_id", batch.ID.String()),
+		slog.String("payout_id", struck.ID.String()),
+		slog.String("reason", reason),
+	)
+
+	// The strike took the batch under the threshold its one approval fell short
+	// of, and so released it. The feed says it was approved, by whoever did.
+	if batch.Status == StatusReady {
+		s.events.Record(ctx, fundevents.Record{
+			FundID:        batch.FundID,
+			Kind:          fundevents.KindBatchApproved,
+			ActorMemberID: batch.ApprovedBy,
+			AmountCents:   &batch.AmountCents,
+			Detail:        "approval completed by a strike",
+			ReferenceID:   &batch.ID,
+		})
+
+		s.logger.InfoContext(ctx, "batch approved by a strike",
+			slog.String("batch_id", batch.ID.String()),
+		)
+	}
+
+	return &batch, nil
+}
+
 // requeueOneTimePayout puts a one-off fund's payout back on the schedule after
 // its batch came to nothing.
 //
@@ -373,6 +594,10 @@ func (s PayoutService) SubmitBatch(ctx context.Context, batchID uuid.UUID) (*Bat
 
 	providerItems := make([]ProviderPayoutItem, 0, len(items))
 	for _, item := range items {
+		if item.Struck() {
+			continue
+		}
+
 		providerItems = append(providerItems, ProviderPayoutItem{
 			PayoutID:      item.ID,
 			ReceiverEmail: item.DestinationEmail,
@@ -382,6 +607,23 @@ func (s PayoutService) SubmitBatch(ctx context.Context, batchID uuid.UUID) (*Bat
 	}
 
 	result, err := s.provider.SubmitBatch(ctx, batch.SenderBatchID, batch.Description, providerItems)
+	if errors.Is(err, ErrInsufficientFunds) {
+		// Refused outright, so nothing was sent and the batch can stay 'ready' to
+		// go again once the account is topped up. The reason goes on the batch,
+		// since the unattended run that hit it has nobody to tell.
+		logger.WarnContext(ctx, "provider refused batch for insufficient funds")
+
+		if _, errStatus := s.payoutStore.SetBatchStatus(ctx, SetBatchStatus{
+			BatchID:       batch.ID,
+			Status:        StatusReady,
+			FailureReason: ErrInsufficientFunds.Error() + "; it will be sent again once the account is topped up",
+		}); errStatus != nil {
+			logger.ErrorContext(ctx, "failed to record why the batch was refused", slog.String("error", errStatus.Error()))
+		}
+
+		return nil, err
+	}
+
 	if err != nil {
 		logger.ErrorContext(ctx, "failed to submit batch to provider", slog.String("error", err.Error()))
 
@@ -533,6 +775,7 @@ func (s PayoutService) ReconcileBatch(ctx context.Context, batchID uuid.UUID) er
 			PayoutID:             item.PayoutID,
 			ProviderPayoutItemID: item.ProviderPayoutItemID,
 			Status:               ProviderStatusToStatus(item.Status),
+			FailureReason:        itemFailureReason(item.Err),
 			ProviderFeeCents:     item.FeeCents,
 		})
 		if errItem != nil {
@@ -602,6 +845,11 @@ func batchStatusFrom(providerStatus Status, items []Payout) Status {
 	anyUnresolved := false
 
 	for _, item := range items {
+		// Never sent, so it has no outcome to wait for or to fail on.
+		if item.Struck() {
+			continue
+		}
+
 		if item.Status != StatusPaid {
 			allPaid = false
 		}
@@ -654,9 +902,10 @@ type PlanResult struct {
 
 // PlanDueBatches builds a batch for every fund whose payout date has arrived.
 //
-// The amount is the fund's available balance divided evenly among eligible
-// enrollees, floored: the remainder stays in the fund and rolls into the next
-// payout rather than being handed to whoever sorts first. Nothing here decides
+// The amount is the fund's available balance divided among eligible enrollees by
+// the fund's allocation -- evenly, by fixed amounts, or by weight, with an
+// optional cap -- floored: the remainder stays in the fund and rolls into the
+// next payout rather than being handed to whoever sorts first. Nothing here decides
 // to send money -- every batch lands awaiting a treasurer, which is the point of
 // planning being separate from submitting.
 //
@@ -716,14 +965,14 @@ func (s PayoutService) planOneDueFund(ctx context.Context, fund DueFund, logger
 		return false, err
 	}
 
-	payable := 0
+	payable := make([]PayoutEnrollment, 0, len(enrollments))
 	for _, enrollment := range enrollments {
 		if enrollment.PaypalEmail != "" {
-			payable++
+			payable = append(payable, enrollment)
 		}
 	}
 
-	if payable == 0 {
+	if len(payable) == 0 {
 		// Nobody to pay, and no amount of waiting changes that for this period.
 		// Advanced so a fund with no enrollees does not report itself due every
 		// day forever.
@@ -745,41 +994,45 @@ func (s PayoutService) planOneDueFund(ctx context.Context, fund DueFund, logger
 		return false, err
 	}
 
-	// Held back for what sending the money will cost. The fee on a payout is only
-	// known once it has been sent, so the balance cannot already have it
-	// subtracted -- planning the whole balance means submitting a batch the account
-	// cannot cover, and PayPal refuses the lot rather than the last item.
+	// Fees are held back inside allocate for what sending the money will cost.
+	// The fee on a payout is only known once it has been sent, so the balance
+	// cannot already have it subtracted -- planning the whole balance means
+	// submitting a batch the account cannot cover, and PayPal refuses the lot
+	// rather than the last item.
 	//
 	// Reserved high rather than exactly: what is not spent stays in the fund and
 	// rolls into the next payout, which is where a remainder goes anyway.
-	reserved := PayoutFeeCents * int64(payable)
-
-	perHead := (available - reserved) / int64(payable)
-	if perHead <= 0 {
+	allocation, err := allocate(fund.Allocation, available, payable)
+	if errors.Is(err, ErrNothingToAllocate) || errors.Is(err, ErrFixedShortfall) {
 		// Deliberately not advanced: the payout is still owed, and donations may
 		// arrive tomorrow. Retrying is the right behaviour even though it means
 		// this warning repeats until the fund is funded or deactivated.
-		logger.WarnContext(ctx, "fund is due but has nothing to pay out, will retry",
+		logger.WarnContext(ctx, "fund is due but cannot pay out yet, will retry",
+			slog.String("reason", err.Error()),
+			slog.String("allocation", fund.Allocation.String()),
 			slog.Int64("available_cents", available),
-			slog.Int64("reserved_for_fees_cents", reserved),
-			slog.Int("payable", payable),
+			slog.Int("payable", len(payable)),
 		)
 
 		return false, nil
 	}
 
-	// int32 to match the column. perHead cannot exceed available, and available is
-	// bounded by what the fund actually holds.
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to allocate payout", slog.String("error", err.Error()))
+
+		return false, err
+	}
+
 	batch, err := s.PlanBatch(ctx, PlanBatch{
 		FundID: fund.ID,
 		// The scheduled date, not today. A run that catches up a missed period must
 		// record the date it is paying for, and the (fund_id, payout_date) unique
 		// index is what stops a second run paying it twice.
 		PayoutDate:  fund.NextPayment,
-		AmountCents: int32(perHead),
+		Amounts:     allocation.Amounts,
 		Description: fmt.Sprintf("%s payout", fund.Name),
-		Notes: fmt.Sprintf("planned automatically: %d cents available, %d reserved for fees, %d payees",
-			available, reserved, payable),
+		Notes: fmt.Sprintf("planned automatically (%s): %d cents available, %d reserved for fees, %d payees, %d carried forward",
+			fund.Allocation, available, allocation.ReservedCents, allocation.Payees(), allocation.RemainderCents),
 		RequireApproval: true,
 	})
 	if err != nil {
@@ -802,10 +1055,11 @@ func (s PayoutService) planOneDueFund(ctx context.Context, fund DueFund, logger
 
 	logger.InfoContext(ctx, "planned batch for due fund",
 		slog.String("batch_id", batch.ID.String()),
-		slog.Int64("per_head_cents", perHead),
-		slog.Int("payees", payable),
-		slog.Int64("reserved_for_fees_cents", reserved),
-		slog.Int64("remainder_cents", available-reserved-perHead*int64(payable)),
+		slog.String("allocation", fund.Allocation.String()),
+		slog.Int64("total_cents", allocation.TotalCents),
+		slog.Int("payees", allocation.Payees()),
+		slog.Int64("reserved_for_fees_cents", allocation.ReservedCents),
+		slog.Int64("remainder_cents", allocation.RemainderCents),
 	)
 
 	return true, nil
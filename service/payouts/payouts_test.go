@@ -53,7 +53,7 @@ func newService(t *testing.T, pool *pgxpool.Pool, provider payouts.PayoutsProvid
 
 	fundEvents := fundevents.NewService(fundeventstore.NewEventStore(pool), logger)
 
-	return payouts.NewPayoutService(payoutstore.NewPayoutStore(pool), provider, nil, fundEvents, 72*time.Hour, 24*time.Hour, logger)
+	return payouts.NewPayoutService(payoutstore.NewPayoutStore(pool), provider, nil, fundEvents, 72*time.Hour, 24*time.Hour, 0, logger)
 }
 
 // seedMember creates an active member. bco_name is unique per member because the
@@ -392,6 +392,38 @@ func TestPayoutService(t *testing.T) {
 		assert.Equal(t, payouts.StatusReady, after.Status)
 	})
 
+	t.Run("a batch refused for insufficient funds stays ready and says why", func(t *testing.T) {
+		provider := &stubProvider{submitErr: fmt.Errorf("paypal: %w", payouts.ErrInsufficientFunds), batchID: "PAYPAL-BATCH-IF"}
+		svc := newService(t, pool, provider)
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 1)
+
+		approverID := seedMember(t, ctx, pool)
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 500, RequireApproval: true,
+		})
+		require.NoError(t, err)
+
+		_, err = svc.ApproveBatch(ctx, batch.ID, approverID)
+		require.NoError(t, err)
+
+		_, err = svc.SubmitBatch(ctx, batch.ID)
+		require.ErrorIs(t, err, payouts.ErrInsufficientFunds)
+
+		after, err := svc.GetBatchByID(ctx, batch.ID)
+		require.NoError(t, err)
+		assert.Equal(t, payouts.StatusReady, after.Status)
+		assert.Contains(t, after.FailureReason, payouts.ErrInsufficientFunds.Error())
+
+		// Topped up and sent again, the old reason no longer applies.
+		provider.submitErr = nil
+
+		submitted, err := svc.SubmitBatch(ctx, batch.ID)
+		require.NoError(t, err)
+		assert.Empty(t, submitted.FailureReason)
+	})
+
 	t.Run("reconcile writes per-item status back through sender_item_id", func(t *testing.T) {
 		provider := &stubProvider{batchID: "PAYPAL-BATCH-6"}
 		svc := newService(t, pool, provider)
@@ -519,7 +551,7 @@ func TestPayoutItemWebhookMatchesBeforeReconcile(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 	store := payoutstore.NewPayoutStore(pool)
 	fundEvents := fundevents.NewService(fundeventstore.NewEventStore(pool), logger)
-	svc := payouts.NewPayoutService(store, &stubProvider{batchID: "PAYPAL-WEBHOOK"}, nil, fundEvents, 72*time.Hour, 24*time.Hour, logger)
+	svc := payouts.NewPayoutService(store, &stubProvider{batchID: "PAYPAL-WEBHOOK"}, nil, fundEvents, 72*time.Hour, 24*time.Hour, 0, logger)
 
 	fundID := seedFundWithEnrollees(t, ctx, pool, 1)
 	approverID := seedMember(t, ctx, pool)
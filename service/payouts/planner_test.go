@@ -2,6 +2,7 @@ package payouts_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -391,6 +392,52 @@ func TestDailyFundsAdvanceByADay(t *testing.T) {
 	})
 }
 
+// Weekly and biweekly funds step in days from their anchor, quarterly ones in
+// months, and each lands on the first period that has not yet passed.
+func TestLongerFrequenciesAdvanceByTheirOwnPeriod(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	// A day overdue for the weeks. The quarter's anchor is the first of a month,
+	// which every month has, so the expected date needs no clamping.
+	dayAgo := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	firstOfMonth := time.Date(dayAgo.Year(), dayAgo.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		frequency string
+		due       time.Time
+		want      func(due time.Time) time.Time
+	}{
+		{"weekly", dayAgo, func(due time.Time) time.Time { return due.AddDate(0, 0, 7) }},
+		{"biweekly", dayAgo, func(due time.Time) time.Time { return due.AddDate(0, 0, 14) }},
+		{"quarterly", firstOfMonth, func(due time.Time) time.Time { return due.AddDate(0, 3, 0) }},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.frequency, func(t *testing.T) {
+			svc := newService(t, pool, &stubProvider{batchID: fmt.Sprintf("P-LONG-%d", i)})
+
+			fundID := seedFundWithEnrollees(t, ctx, pool, 2)
+			setFundFrequency(t, ctx, pool, fundID, tc.frequency)
+			seedDonation(t, ctx, pool, fundID, 1000)
+
+			setFundNextPayment(t, ctx, pool, fundID, tc.due)
+
+			_, err := svc.PlanDueBatches(ctx)
+			require.NoError(t, err)
+
+			next := fundNextPayment(t, ctx, pool, fundID)
+			require.NotNil(t, next)
+			assert.WithinDuration(t, tc.want(tc.due), *next, time.Second)
+			assert.True(t, next.After(time.Now()), "the fund must not stay due")
+		})
+	}
+}
+
 // The fee to send a payout is only known once it has been sent, so the balance
 // cannot already have it taken off. Planning the whole balance means submitting a
 // batch the account cannot cover, and PayPal refuses the whole batch rather than
@@ -457,3 +504,120 @@ func TestPlanningHoldsBackWhatSendingWillCost(t *testing.T) {
 		require.Equal(t, 1, due)
 	})
 }
+
+func TestPlanningFollowsTheFundsAllocation(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	enrollmentsOf := func(t *testing.T, fundID uuid.UUID) []uuid.UUID {
+		t.Helper()
+
+		rows, err := pool.Query(ctx, `SELECT id FROM fund_enrollment WHERE fund_id = $1 ORDER BY created`, fundID)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+
+		return ids
+	}
+
+	t.Run("a weighted fund pays by share, item by item", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "A-1"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 2)
+		seedDonation(t, ctx, pool, fundID, 4050)
+
+		_, err := svc.SetFundAllocation(ctx, fundID, payouts.Allocation{Strategy: payouts.AllocationWeighted})
+		require.NoError(t, err)
+
+		ids := enrollmentsOf(t, fundID)
+		_, err = svc.SetEnrollmentShare(ctx, ids[1], payouts.EnrollmentShare{Weight: 3})
+		require.NoError(t, err)
+
+		_, err = svc.PlanDueBatches(ctx)
+		require.NoError(t, err)
+
+		batches, err := svc.GetBatchesForFund(ctx, fundID)
+		require.NoError(t, err)
+		require.Len(t, batches, 1)
+		assert.Equal(t, int32(4000), batches[0].AmountCents)
+
+		items, err := svc.GetPayoutsForBatch(ctx, batches[0].ID)
+		require.NoError(t, err)
+
+		paid := map[uuid.UUID]int32{}
+		for _, item := range items {
+			paid[item.FundEnrollmentID] = item.AmountCents
+		}
+
+		assert.Equal(t, map[uuid.UUID]int32{ids[0]: 1000, ids[1]: 3000}, paid)
+	})
+
+	t.Run("a fixed fund it cannot cover is still due", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "A-2"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 2)
+		seedDonation(t, ctx, pool, fundID, 1000)
+		due := time.Now().Add(-time.Hour)
+		setFundNextPayment(t, ctx, pool, fundID, due)
+
+		_, err := svc.SetFundAllocation(ctx, fundID, payouts.Allocation{Strategy: payouts.AllocationFixed})
+		require.NoError(t, err)
+
+		amount := int32(600)
+		for _, id := range enrollmentsOf(t, fundID) {
+			_, err = svc.SetEnrollmentShare(ctx, id, payouts.EnrollmentShare{Weight: 1, FixedAmountCents: &amount})
+			require.NoError(t, err)
+		}
+
+		_, err = svc.PlanDueBatches(ctx)
+		require.NoError(t, err)
+
+		batches, err := svc.GetBatchesForFund(ctx, fundID)
+		require.NoError(t, err)
+		assert.Empty(t, batches, "1200 promised against 1000 held pays nobody")
+
+		next := fundNextPayment(t, ctx, pool, fundID)
+		require.NotNil(t, next)
+		assert.WithinDuration(t, due, *next, time.Second)
+	})
+
+	t.Run("a cap leaves the rest in the fund", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "A-3"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 2)
+		seedDonation(t, ctx, pool, fundID, 5050)
+
+		capCents := int32(1000)
+		_, err := svc.SetFundAllocation(ctx, fundID, payouts.Allocation{Strategy: payouts.AllocationEven, CapCents: &capCents})
+		require.NoError(t, err)
+
+		_, err = svc.PlanDueBatches(ctx)
+		require.NoError(t, err)
+
+		batches, err := svc.GetBatchesForFund(ctx, fundID)
+		require.NoError(t, err)
+		require.Len(t, batches, 1)
+		assert.Equal(t, int32(2000), batches[0].AmountCents)
+
+		// 5050 less two fees and two capped payouts. Not paid to anyone, so it is
+		// still in the balance the next period plans from.
+		assert.Contains(t, batches[0].Notes, "3000 carried forward")
+	})
+
+	t.Run("an unknown strategy is refused", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "A-4"})
+
+		_, err := svc.SetFundAllocation(ctx, seedFund(t, ctx, pool), payouts.Allocation{Strategy: "lottery"})
+		assert.ErrorIs(t, err, payouts.ErrInvalidAllocation)
+	})
+}
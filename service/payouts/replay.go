@@ -0,0 +1,98 @@
+package payouts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// NewReplayHandlers is NewHandlers for running stored webhooks through again,
+// with every status the callbacks change passed to report.
+//
+// The same arrangement as the donations side: the live callbacks over a store
+// that reads what each write would change before making it, or instead of
+// making it when dryRun is set. A payout or batch already in the status the
+// webhook carries is not reported.
+func NewReplayHandlers(store payoutStore, dryRun bool, report func(change string), logger *slog.Logger) *Handlers {
+	return NewHandlers(replayStore{payoutStore: store, dryRun: dryRun, report: report}, logger)
+}
+
+// replayStore overrides the three writes the webhook callbacks make.
+//
+// All three are updates that return the row whether or not anything in it
+// changed, so each reads first, in both modes. A read that fails with no rows
+// fails the way the update would have, and the callback returns the same error.
+type replayStore struct {
+	payoutStore
+
+	dryRun bool
+	report func(change string)
+}
+
+func (s replayStore) SetPayoutResult(ctx context.Context, arg SetPayoutResult) (*Payout, error) {
+	before, err := s.payoutStore.GetPayoutByID(ctx, arg.PayoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.reportPayout(before, arg.Status)
+
+	if s.dryRun {
+		predicted := *before
+		predicted.ProviderPayoutItemID = arg.ProviderPayoutItemID
+		predicted.Status = arg.Status
+		predicted.FailureReason = arg.FailureReason
+		predicted.ProviderFeeCents = arg.ProviderFeeCents
+
+		return &predicted, nil
+	}
+
+	return s.payoutStore.SetPayoutResult(ctx, arg)
+}
+
+func (s replayStore) SetPayoutStatusByProviderItemID(ctx context.Context, arg SetPayoutStatusByItem) (*Payout, error) {
+	before, err := s.payoutStore.GetPayoutByProviderItemID(ctx, arg.ProviderPayoutItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.reportPayout(before, arg.Status)
+
+	if s.dryRun {
+		predicted := *before
+		predicted.Status = arg.Status
+		predicted.FailureReason = arg.FailureReason
+		predicted.ProviderFeeCents = arg.ProviderFeeCents
+
+		return &predicted, nil
+	}
+
+	return s.payoutStore.SetPayoutStatusByProviderItemID(ctx, arg)
+}
+
+func (s replayStore) SetBatchStatus(ctx context.Context, arg SetBatchStatus) (*Batch, error) {
+	before, err := s.payoutStore.GetBatchByID(ctx, arg.BatchID)
+	if err != nil {
+		return nil, err
+	}
+
+	if before.Status != arg.Status {
+		s.report(fmt.Sprintf("batch %s: %s -> %s", before.ID, before.Status, arg.Status))
+	}
+
+	if s.dryRun {
+		predicted := *before
+		predicted.Status = arg.Status
+		predicted.FailureReason = arg.FailureReason
+
+		return &predicted, nil
+	}
+
+	return s.payoutStore.SetBatchStatus(ctx, arg)
+}
+
+func (s replayStore) reportPayout(before *Payout, status Status) {
+	if before.Status != status {
+		s.report(fmt.Sprintf("payout %s in batch %s: %s -> %s", before.ID, before.BatchID, before.Status, status))
+	}
+}
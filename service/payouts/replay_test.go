@@ -0,0 +1,67 @@
+package payouts
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type replayFakeStore struct {
+	payoutStore
+
+	payout  Payout
+	written int
+}
+
+func (f *replayFakeStore) GetPayoutByID(context.Context, uuid.UUID) (*Payout, error) {
+	payout := f.payout
+
+	return &payout, nil
+}
+
+func (f *replayFakeStore) SetPayoutResult(_ context.Context, arg SetPayoutResult) (*Payout, error) {
+	f.written++
+	f.payout.Status = arg.Status
+
+	return &f.payout, nil
+}
+
+func replayItem(t *testing.T, store *replayFakeStore, dryRun bool) []string {
+	t.Helper()
+
+	var changes []string
+	h := NewReplayHandlers(store, dryRun, func(change string) {
+		changes = append(changes, change)
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	payload := `{"payout_item_id":"ITEM-1","transaction_status":"SUCCESS","payout_item":{"sender_item_id":"` +
+		store.payout.ID.String() + `"}}`
+
+	if err := h.payoutItemUpdated([]byte(payload)); err != nil {
+		t.Fatalf("payoutItemUpdated: %v", err)
+	}
+
+	return changes
+}
+
+// A dry run says what the status would become and leaves it where it was; a
+// confirmed run makes the change, after which the same webhook reports nothing.
+func TestAReplayedItemReportsOnlyARealChange(t *testing.T) {
+	store := &replayFakeStore{payout: Payout{ID: uuid.New(), BatchID: uuid.New(), Status: StatusPending}}
+
+	changes := replayItem(t, store, true)
+	if len(changes) != 1 || store.written != 0 {
+		t.Fatalf("dry run: changes %q, %d write(s)", changes, store.written)
+	}
+
+	if changes = replayItem(t, store, false); len(changes) != 1 || store.written != 1 {
+		t.Fatalf("confirmed: changes %q, %d write(s)", changes, store.written)
+	}
+
+	if changes = replayItem(t, store, false); len(changes) != 0 {
+		t.Errorf("a status already applied reported %q", changes)
+	}
+}
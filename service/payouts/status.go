@@ -1,6 +1,9 @@
 package payouts
 
-import "strings"
+import (
+	"errors"
+	"strings"
+)
 
 // ProviderStatusToStatus maps a payments-provider status onto our own.
 //
@@ -31,3 +34,18 @@ func ProviderStatusToStatus(providerStatus string) Status {
 		return StatusPending
 	}
 }
+
+// itemFailureReason is what is recorded against a payout the provider did not
+// deliver. An unregistered address gets words a treasurer can act on -- ask
+// the member for the email their account is under -- and anything else keeps
+// the provider's own.
+func itemFailureReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrReceiverUnregistered):
+		return ErrReceiverUnregistered.Error()
+	default:
+		return err.Error()
+	}
+}
@@ -46,6 +46,24 @@ func uuidPtr(id uuid.NullUUID) *uuid.UUID {
 	return &out
 }
 
+func int4(n *int32) pgtype.Int4 {
+	if n == nil {
+		return pgtype.Int4{}
+	}
+
+	return pgtype.Int4{Int32: *n, Valid: true}
+}
+
+func int32Ptr(n pgtype.Int4) *int32 {
+	if !n.Valid {
+		return nil
+	}
+
+	out := n.Int32
+
+	return &out
+}
+
 func nullUUID(id uuid.UUID) uuid.NullUUID {
 	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
 }
@@ -62,6 +80,8 @@ func toDBInsertBatchParams(arg payouts.InsertBatch) db.InsertBatchPayoutParams {
 		PayoutDate:       timestamptz(arg.PayoutDate),
 		SenderBatchID:    arg.SenderBatchID,
 		ApprovalDeadline: nullDBTime(arg.ApprovalDeadline),
+
+		DefaultSecondApprovalAboveCents: int4(arg.SecondApprovalAboveCents),
 	}
 }
 
@@ -82,8 +102,12 @@ func fromDBBatch(dbBatch db.BatchPayout) payouts.Batch {
 		ApprovedBy:       uuidPtr(dbBatch.ApprovedBy),
 		ApprovedAt:       timePtr(dbBatch.ApprovedAt),
 		ReminderSentAt:   timePtr(dbBatch.ReminderSentAt),
-		Created:          dbBatch.Created.Time,
-		Updated:          dbBatch.Updated.Time,
+
+		ApprovalsRequired: dbBatch.ApprovalsRequired,
+		ApprovalsGiven:    dbBatch.ApprovalsGiven,
+
+		Created: dbBatch.Created.Time,
+		Updated: dbBatch.Updated.Time,
 	}
 }
 
@@ -105,8 +129,12 @@ func fromDBDetailedBatch(row db.GetDetailedBatchPayoutsByStatusRow) payouts.Batc
 			ApprovedBy:       uuidPtr(row.ApprovedBy),
 			ApprovedAt:       timePtr(row.ApprovedAt),
 			ReminderSentAt:   timePtr(row.ReminderSentAt),
-			Created:          row.Created.Time,
-			Updated:          row.Updated.Time,
+
+			ApprovalsRequired: row.ApprovalsRequired,
+			ApprovalsGiven:    row.ApprovalsGiven,
+
+			Created: row.Created.Time,
+			Updated: row.Updated.Time,
 		},
 		FundName: row.FundName,
 		Payees:   payeesFrom(row.PayeeNames, row.PayeeIds),
@@ -160,11 +188,20 @@ func fromDBPayout(dbPayout db.Payout) payouts.Payout {
 		Notes:                dbPayout.Notes.String,
 		Description:          dbPayout.Description.String,
 		PayoutDate:           dbPayout.PayoutDate.Time,
+		StruckBy:             uuidPtr(dbPayout.StruckBy),
+		StruckAt:             timePtr(dbPayout.StruckAt),
 		Created:              dbPayout.Created.Time,
 		Updated:              dbPayout.Updated.Time,
 	}
 }
 
+func fromDBBatchPayout(row db.GetPayoutsByBatchIdRow) payouts.Payout {
+	payout := fromDBPayout(row.Payout)
+	payout.StruckByName = row.StruckByName.String
+
+	return payout
+}
+
 func fromDBPayoutEnrollment(dbEnrollment db.FundEnrollment) payouts.PayoutEnrollment {
 	return payouts.PayoutEnrollment{
 		ID:              dbEnrollment.ID,
@@ -173,8 +210,12 @@ func fromDBPayoutEnrollment(dbEnrollment db.FundEnrollment) payouts.PayoutEnroll
 		MemberBCOName:   dbEnrollment.MemberBcoName.String,
 		PaypalEmail:     dbEnrollment.PaypalEmail,
 		FirstPayoutDate: dbEnrollment.FirstPayoutDate.Time,
-		Created:         dbEnrollment.Created.Time,
-		Updated:         dbEnrollment.Updated.Time,
+
+		FixedAmountCents: int32Ptr(dbEnrollment.PayoutAmountCents),
+		Weight:           dbEnrollment.PayoutWeight,
+
+		Created: dbEnrollment.Created.Time,
+		Updated: dbEnrollment.Updated.Time,
 	}
 }
 
@@ -185,6 +226,32 @@ func toDBApproveBatchParams(arg payouts.ApproveBatch) db.ApproveBatchPayoutParam
 	}
 }
 
+func fromDBApproval(row db.GetBatchApprovalsRow) payouts.Approval {
+	return payouts.Approval{
+		ID:           row.ID,
+		BatchID:      row.BatchID,
+		ApproverID:   row.ApproverID,
+		ApproverName: row.ApproverName.String,
+		Created:      row.Created.Time,
+	}
+}
+
+func toDBSetFundSecondApprovalAboveParams(arg payouts.SetFundSecondApproval) db.SetFundSecondApprovalAboveParams {
+	return db.SetFundSecondApprovalAboveParams{
+		ID:                       arg.FundID,
+		SecondApprovalAboveCents: int4(arg.AboveCents),
+	}
+}
+
+func toDBStrikePayoutParams(arg payouts.StrikePayout) db.StrikePayoutParams {
+	return db.StrikePayoutParams{
+		ID:            arg.PayoutID,
+		BatchID:       arg.BatchID,
+		FailureReason: text(arg.Reason),
+		StruckBy:      nullUUID(arg.StruckBy),
+	}
+}
+
 func toDBRejectBatchParams(arg payouts.RejectBatch) db.RejectBatchPayoutParams {
 	return db.RejectBatchPayoutParams{
 		ID:            arg.BatchID,
@@ -239,5 +306,32 @@ func fromDBDueFund(dbFund db.GetFundsDueForPayoutRow) payouts.DueFund {
 		Name:        dbFund.Name,
 		Frequency:   string(dbFund.PayoutFrequency),
 		NextPayment: dbFund.NextPayment.Time,
+		Allocation: payouts.Allocation{
+			Strategy: payouts.AllocationStrategy(dbFund.PayoutAllocation),
+			CapCents: int32Ptr(dbFund.PayoutCapCents),
+		},
+	}
+}
+
+func toDBSetFundPayoutAllocationParams(arg payouts.SetFundAllocation) db.SetFundPayoutAllocationParams {
+	return db.SetFundPayoutAllocationParams{
+		ID:               arg.FundID,
+		PayoutAllocation: db.PayoutAllocation(arg.Allocation.Strategy),
+		PayoutCapCents:   int4(arg.Allocation.CapCents),
+	}
+}
+
+func fromDBFundAllocation(row db.SetFundPayoutAllocationRow) payouts.Allocation {
+	return payouts.Allocation{
+		Strategy: payouts.AllocationStrategy(row.PayoutAllocation),
+		CapCents: int32Ptr(row.PayoutCapCents),
+	}
+}
+
+func toDBSetEnrollmentPayoutShareParams(arg payouts.SetEnrollmentShare) db.SetEnrollmentPayoutShareParams {
+	return db.SetEnrollmentPayoutShareParams{
+		ID:                arg.EnrollmentID,
+		PayoutAmountCents: int4(arg.Share.FixedAmountCents),
+		PayoutWeight:      arg.Share.Weight,
 	}
 }
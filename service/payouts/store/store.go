@@ -48,6 +48,12 @@ func (s PayoutStore) CreateBatchWithPayouts(ctx context.Context, batch payouts.I
 		return nil, nil, err
 	}
 
+	// A batch planned without the approval gate has had nobody approve it, and
+	// one above the threshold needs two people to have done so.
+	if created.Status == payouts.StatusReady && created.ApprovalsRequired > 1 {
+		return nil, nil, payouts.ErrNeedsTwoApprovals
+	}
+
 	written := make([]payouts.Payout, 0, len(items))
 	for _, item := range items {
 		payout, errInner := pg.CreateOne(ctx, item, txQueries.InsertPayout, toDBInsertPayoutParams, fromDBPayout)
@@ -92,8 +98,18 @@ func (s PayoutStore) GetDetailedBatchesByStatus(ctx context.Context, status payo
 	return pg.FetchMany(ctx, status, s.queries.GetDetailedBatchPayoutsByStatus, argIn, fromDBDetailedBatch)
 }
 
+// GetDetailedBatchByID is one batch with its fund and payees, for anything that
+// has to describe it to a person rather than act on it.
+func (s PayoutStore) GetDetailedBatchByID(ctx context.Context, id uuid.UUID) (*payouts.BatchDetail, error) {
+	adapt := func(row db.GetDetailedBatchPayoutByIdRow) payouts.BatchDetail {
+		return fromDBDetailedBatch(db.GetDetailedBatchPayoutsByStatusRow(row))
+	}
+
+	return pg.FetchOne(ctx, id, s.queries.GetDetailedBatchPayoutById, uuidIdentity, adapt)
+}
+
 func (s PayoutStore) GetPayoutsForBatch(ctx context.Context, batchID uuid.UUID) ([]payouts.Payout, error) {
-	return pg.FetchMany(ctx, batchID, s.queries.GetPayoutsByBatchId, uuidIdentity, fromDBPayout)
+	return pg.FetchMany(ctx, batchID, s.queries.GetPayoutsByBatchId, uuidIdentity, fromDBBatchPayout)
 }
 
 func (s PayoutStore) IsFundActive(ctx context.Context, fundID uuid.UUID) (bool, error) {
@@ -132,14 +148,187 @@ func (s PayoutStore) GetEnrollmentsInUnsentBatches(ctx context.Context, fundID u
 	return s.queries.GetEnrollmentsInUnsentBatches(ctx, fundID)
 }
 
+// ApproveBatch records one admin's approval and, if that makes enough, moves
+// the batch to 'ready' -- all under a lock on the batch row, so two admins
+// approving at once are counted one after the other rather than both reading
+// "none so far".
+//
+// The batch comes back still awaiting approval when it needs another. Refusals
+// roll back: an approval from somebody enrolled in the fund is not recorded as
+// having happened.
 func (s PayoutStore) ApproveBatch(ctx context.Context, arg payouts.ApproveBatch) (*payouts.Batch, error) {
-	return pg.UpdateOne(ctx, arg, s.queries.ApproveBatchPayout, toDBApproveBatchParams, fromDBBatch)
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	txQueries := s.queries.WithTx(tx)
+
+	locked, err := txQueries.LockBatchPayoutAwaitingApproval(ctx, arg.BatchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, payouts.ErrNotApprovable
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	enrolled, err := txQueries.IsMemberEnrolledInFund(ctx, db.IsMemberEnrolledInFundParams{
+		FundID:   locked.FundID,
+		MemberID: arg.ApprovedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if enrolled {
+		return nil, payouts.ErrApproverEnrolled
+	}
+
+	_, err = txQueries.InsertBatchApproval(ctx, db.InsertBatchApprovalParams{
+		ID:         uuid.New(),
+		BatchID:    arg.BatchID,
+		ApproverID: arg.ApprovedBy,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, payouts.ErrAlreadyApproved
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	counted, err := txQueries.CountBatchApproval(ctx, db.CountBatchApprovalParams{
+		ID:                              arg.BatchID,
+		DefaultSecondApprovalAboveCents: int4(arg.SecondApprovalAboveCents),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batch := fromDBBatch(counted)
+	if counted.ApprovalsGiven >= counted.ApprovalsRequired {
+		approved, errApprove := pg.UpdateOne(ctx, arg, txQueries.ApproveBatchPayout, toDBApproveBatchParams, fromDBBatch)
+		if errApprove != nil {
+			return nil, errApprove
+		}
+
+		batch = *approved
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+func (s PayoutStore) GetApprovalsForBatch(ctx context.Context, batchID uuid.UUID) ([]payouts.Approval, error) {
+	return pg.FetchMany(ctx, batchID, s.queries.GetBatchApprovals, uuidIdentity, fromDBApproval)
+}
+
+func (s PayoutStore) SetFundSecondApproval(ctx context.Context, arg payouts.SetFundSecondApproval) error {
+	_, err := s.queries.SetFundSecondApprovalAbove(ctx, toDBSetFundSecondApprovalAboveParams(arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return payouts.ErrFundNotFound
+	}
+
+	return err
 }
 
 func (s PayoutStore) RejectBatch(ctx context.Context, arg payouts.RejectBatch) (*payouts.Batch, error) {
 	return pg.UpdateOne(ctx, arg, s.queries.RejectBatchPayout, toDBRejectBatchParams, fromDBBatch)
 }
 
+// StrikePayout takes one payout out of a batch awaiting approval and recounts
+// the batch, in one transaction under a lock on the batch row. Striking the
+// last payout is refused and rolled back: a batch of nobody is a rejected
+// batch, and rejecting it is what should be recorded.
+func (s PayoutStore) StrikePayout(ctx context.Context, arg payouts.StrikePayout) (*payouts.StruckPayout, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	txQueries := s.queries.WithTx(tx)
+
+	_, err = txQueries.LockBatchPayoutAwaitingApproval(ctx, arg.BatchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, payouts.ErrNotApprovable
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	struck, err := pg.UpdateOne(ctx, arg, txQueries.StrikePayout, toDBStrikePayoutParams, fromDBPayout)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, payouts.ErrPayoutNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	memberID, err := txQueries.GetEnrollmentMemberId(ctx, struck.FundEnrollmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = txQueries.RecountBatchPayout(ctx, arg.BatchID)
+	if err != nil {
+		return nil, err
+	}
+
+	// After the recount, so the threshold is compared against the new total.
+	recounted, err := txQueries.SetBatchApprovalsRequired(ctx, db.SetBatchApprovalsRequiredParams{
+		ID:                              arg.BatchID,
+		DefaultSecondApprovalAboveCents: int4(arg.SecondApprovalAboveCents),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batch := fromDBBatch(recounted)
+
+	if batch.NumEnrollments == 0 {
+		return nil, payouts.ErrLastPayout
+	}
+
+	// A strike can take a batch back under the second-approval threshold after
+	// its first approval. That approval is then all it needs, and no second one
+	// will come to move it on: left awaiting approval, it would sit until the
+	// expiry sweep cancelled it. The approver recorded is the one who gave it.
+	if recounted.ApprovalsGiven >= recounted.ApprovalsRequired {
+		approvals, errApprovals := txQueries.GetBatchApprovals(ctx, arg.BatchID)
+		if errApprovals != nil {
+			return nil, errApprovals
+		}
+
+		approved, errApprove := pg.UpdateOne(ctx, payouts.ApproveBatch{
+			BatchID:    arg.BatchID,
+			ApprovedBy: approvals[len(approvals)-1].ApproverID,
+		}, txQueries.ApproveBatchPayout, toDBApproveBatchParams, fromDBBatch)
+		if errApprove != nil {
+			return nil, errApprove
+		}
+
+		batch = *approved
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payouts.StruckPayout{Batch: batch, Payout: *struck, MemberID: memberID}, nil
+}
+
 func (s PayoutStore) CancelExpiredBatches(ctx context.Context) ([]payouts.Batch, error) {
 	dbBatches, err := s.queries.CancelExpiredBatchPayouts(ctx)
 	if err != nil {
@@ -178,6 +367,16 @@ func (s PayoutStore) SetPayoutProviderItemID(ctx context.Context, arg payouts.Se
 	return pg.UpdateOne(ctx, arg, s.queries.SetPayoutProviderItemId, toDBSetPayoutProviderItemParams, fromDBPayout)
 }
 
+func (s PayoutStore) GetPayoutByID(ctx context.Context, id uuid.UUID) (*payouts.Payout, error) {
+	return pg.FetchOne(ctx, id, s.queries.GetPayoutById, uuidIdentity, fromDBPayout)
+}
+
+func (s PayoutStore) GetPayoutByProviderItemID(ctx context.Context, itemID string) (*payouts.Payout, error) {
+	argIn := func(id string) pgtype.Text { return pgtype.Text{String: id, Valid: true} }
+
+	return pg.FetchOne(ctx, itemID, s.queries.GetPayoutByProviderItemId, argIn, fromDBPayout)
+}
+
 func (s PayoutStore) SetPayoutResult(ctx context.Context, arg payouts.SetPayoutResult) (*payouts.Payout, error) {
 	return pg.UpdateOne(ctx, arg, s.queries.SetPayoutResultById, toDBSetPayoutResultParams, fromDBPayout)
 }
@@ -199,3 +398,21 @@ func (s PayoutStore) AdvanceFundNextPayment(ctx context.Context, fundID uuid.UUI
 
 	return err
 }
+
+func (s PayoutStore) SetFundAllocation(ctx context.Context, arg payouts.SetFundAllocation) (*payouts.Allocation, error) {
+	allocation, err := pg.UpdateOne(ctx, arg, s.queries.SetFundPayoutAllocation, toDBSetFundPayoutAllocationParams, fromDBFundAllocation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, payouts.ErrFundNotFound
+	}
+
+	return allocation, err
+}
+
+func (s PayoutStore) SetEnrollmentShare(ctx context.Context, arg payouts.SetEnrollmentShare) (*payouts.PayoutEnrollment, error) {
+	enrollment, err := pg.UpdateOne(ctx, arg, s.queries.SetEnrollmentPayoutShare, toDBSetEnrollmentPayoutShareParams, fromDBPayoutEnrollment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, payouts.ErrEnrollmentNotFound
+	}
+
+	return enrollment, err
+}
@@ -0,0 +1,174 @@
+package payouts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"boardfund/pg"
+	"boardfund/service/payouts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrikingOnePayoutLeavesTheRest(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	t.Run("the batch is recounted and only the rest are sent", func(t *testing.T) {
+		provider := &stubProvider{batchID: "S-1"}
+		svc := newService(t, pool, provider)
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 3)
+		treasurer := seedMember(t, ctx, pool)
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000, RequireApproval: true,
+		})
+		require.NoError(t, err)
+
+		items, err := svc.GetPayoutsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+
+		struck, err := svc.StrikePayout(ctx, batch.ID, items[0].ID, treasurer, "wrong paypal address")
+		require.NoError(t, err)
+
+		// What the treasurer approves next is what will be sent.
+		assert.Equal(t, int32(2000), struck.AmountCents)
+		assert.Equal(t, int32(2), struck.NumEnrollments)
+		assert.Equal(t, payouts.StatusAwaitingApproval, struck.Status)
+
+		after, err := svc.GetPayoutsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+		require.Len(t, after, 3, "a struck payout stays on the batch as a record")
+
+		for _, item := range after {
+			if item.ID != items[0].ID {
+				continue
+			}
+
+			assert.True(t, item.Struck())
+			assert.Equal(t, "wrong paypal address", item.FailureReason)
+			require.NotNil(t, item.StruckBy)
+			assert.Equal(t, treasurer, *item.StruckBy)
+			assert.NotEmpty(t, item.StruckByName, "the batch listing names whoever struck it")
+		}
+
+		_, err = svc.ApproveBatch(ctx, batch.ID, treasurer)
+		require.NoError(t, err)
+
+		_, err = svc.SubmitBatch(ctx, batch.ID)
+		require.NoError(t, err)
+
+		require.Len(t, provider.submitted, 1)
+		require.Len(t, provider.submitted[0], 2)
+
+		for _, sent := range provider.submitted[0] {
+			assert.NotEqual(t, items[0].ID, sent.PayoutID, "the struck payout reached the provider")
+		}
+	})
+
+	t.Run("the same payout cannot be struck twice", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "S-2"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 3)
+		treasurer := seedMember(t, ctx, pool)
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000, RequireApproval: true,
+		})
+		require.NoError(t, err)
+
+		items, err := svc.GetPayoutsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+
+		_, err = svc.StrikePayout(ctx, batch.ID, items[0].ID, treasurer, "")
+		require.NoError(t, err)
+
+		_, err = svc.StrikePayout(ctx, batch.ID, items[0].ID, treasurer, "")
+		require.ErrorIs(t, err, payouts.ErrPayoutNotFound)
+	})
+
+	t.Run("the last payout cannot be struck", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "S-3"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 1)
+		treasurer := seedMember(t, ctx, pool)
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000, RequireApproval: true,
+		})
+		require.NoError(t, err)
+
+		items, err := svc.GetPayoutsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+
+		_, err = svc.StrikePayout(ctx, batch.ID, items[0].ID, treasurer, "")
+		require.ErrorIs(t, err, payouts.ErrLastPayout)
+
+		// Rolled back: the refused strike left nothing behind.
+		after, err := svc.GetPayoutsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+		assert.False(t, after[0].Struck())
+	})
+
+	// Three payees at $10 is over a $25 threshold; two are not. With one
+	// approval already given, the strike leaves nothing more to wait for.
+	t.Run("a strike under the threshold releases a half-approved batch", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "S-5"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 3)
+		threshold := int32(2500)
+		require.NoError(t, svc.SetFundSecondApproval(ctx, fundID, &threshold))
+
+		approver, treasurer := seedMember(t, ctx, pool), seedMember(t, ctx, pool)
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000, RequireApproval: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(2), batch.ApprovalsRequired)
+
+		once, err := svc.ApproveBatch(ctx, batch.ID, approver)
+		require.NoError(t, err)
+		require.Equal(t, payouts.StatusAwaitingApproval, once.Status)
+
+		items, err := svc.GetPayoutsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+
+		struck, err := svc.StrikePayout(ctx, batch.ID, items[0].ID, treasurer, "left the board")
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(1), struck.ApprovalsRequired)
+		assert.Equal(t, payouts.StatusReady, struck.Status, "no second approval is needed, so none would ever come")
+		require.NotNil(t, struck.ApprovedBy)
+		assert.Equal(t, approver, *struck.ApprovedBy)
+	})
+
+	t.Run("an approved batch cannot be changed", func(t *testing.T) {
+		svc := newService(t, pool, &stubProvider{batchID: "S-4"})
+
+		fundID := seedFundWithEnrollees(t, ctx, pool, 2)
+		treasurer := seedMember(t, ctx, pool)
+
+		batch, err := svc.PlanBatch(ctx, payouts.PlanBatch{
+			FundID: fundID, PayoutDate: time.Now(), AmountCents: 1000, RequireApproval: true,
+		})
+		require.NoError(t, err)
+
+		_, err = svc.ApproveBatch(ctx, batch.ID, treasurer)
+		require.NoError(t, err)
+
+		items, err := svc.GetPayoutsForBatch(ctx, batch.ID)
+		require.NoError(t, err)
+
+		_, err = svc.StrikePayout(ctx, batch.ID, items[0].ID, treasurer, "")
+		require.ErrorIs(t, err, payouts.ErrNotApprovable)
+	})
+}
@@ -20,6 +20,11 @@ const (
 	StatusBlocked          Status = "blocked"
 	StatusOnhold           Status = "onhold"
 	StatusCancelled        Status = "cancelled"
+
+	// StatusStruck is a payout a treasurer took out of its batch before
+	// approving it. Only ever set on a payout, never on a batch, and never sent
+	// to the provider.
+	StatusStruck Status = "struck"
 )
 
 // Terminal reports whether a status will never change again without operator
@@ -27,7 +32,7 @@ const (
 // items after 30 days, which moves them to 'returned'.
 func (s Status) Terminal() bool {
 	switch s {
-	case StatusPaid, StatusFailed, StatusReturned, StatusBlocked, StatusCancelled:
+	case StatusPaid, StatusFailed, StatusReturned, StatusBlocked, StatusCancelled, StatusStruck:
 		return true
 	default:
 		return false
@@ -41,8 +46,13 @@ func (s Status) Terminal() bool {
 // other ways of sending, and on the amounts here would be less.
 //
 // A constant rather than a lookup because it is the same for every item and
-// there is one provider. When there are two, it belongs on whatever answers for
-// the fund's provider.
+// payouts have one provider. There are two providers for collecting now, but
+// Stripe only collects: it has no way to pay a recipient by email, so every
+// payout is sent through PayPal whichever provider the fund's money came in
+// through. Money collected at Stripe has to be moved to the PayPal balance
+// before a batch draws on it, and that transfer happens outside this program.
+// If payouts ever go through a second provider, this belongs on whatever sends
+// the batch.
 const PayoutFeeCents = 25
 
 type Batch struct {
@@ -61,8 +71,30 @@ type Batch struct {
 	ApprovedBy       *uuid.UUID
 	ApprovedAt       *time.Time
 	ReminderSentAt   *time.Time
-	Created          time.Time
-	Updated          time.Time
+
+	// ApprovalsRequired is two for a batch above its fund's second-approval
+	// threshold, or the site's, and one otherwise. ApprovalsGiven counts the
+	// distinct admins who have approved it so far.
+	ApprovalsRequired int32
+	ApprovalsGiven    int32
+
+	Created time.Time
+	Updated time.Time
+}
+
+// AwaitingSecondApproval reports whether one admin has approved a batch that
+// needs two, so the next approval must come from somebody else.
+func (b Batch) AwaitingSecondApproval() bool {
+	return b.Status == StatusAwaitingApproval && b.ApprovalsGiven > 0 && b.ApprovalsGiven < b.ApprovalsRequired
+}
+
+// Approval is one admin's approval of a batch. A batch that needed two has two.
+type Approval struct {
+	ID           uuid.UUID
+	BatchID      uuid.UUID
+	ApproverID   uuid.UUID
+	ApproverName string
+	Created      time.Time
 }
 
 // BatchDetail is a batch as a person reads it, rather than as a job acts on it.
@@ -120,8 +152,21 @@ type Payout struct {
 	Notes                string
 	Description          string
 	PayoutDate           time.Time
+	StruckBy             *uuid.UUID
+	StruckAt             *time.Time
 	Created              time.Time
 	Updated              time.Time
+
+	// StruckByName is who StruckBy is, for showing. Only the batch listing
+	// resolves it, and it is empty if that member has since gone.
+	StruckByName string
+}
+
+// Struck reports whether a treasurer took this payout out of its batch. A
+// struck payout stays on the batch as a record and is skipped by everything
+// that sends, counts or reconciles.
+func (p Payout) Struck() bool {
+	return p.Status == StatusStruck
 }
 
 type PayoutEnrollment struct {
@@ -131,8 +176,31 @@ type PayoutEnrollment struct {
 	MemberBCOName   string
 	PaypalEmail     string
 	FirstPayoutDate time.Time
-	Created         time.Time
-	Updated         time.Time
+
+	// FixedAmountCents is what a 'fixed' fund pays this enrollment. Nil leaves
+	// them out of such a fund's payouts.
+	FixedAmountCents *int32
+	// Weight is this enrollment's shares in a 'weighted' fund.
+	Weight int32
+
+	Created time.Time
+	Updated time.Time
+}
+
+// EnrollmentShare is what one enrollment is owed under its fund's allocation.
+type EnrollmentShare struct {
+	FixedAmountCents *int32
+	Weight           int32
+}
+
+type SetFundAllocation struct {
+	FundID     uuid.UUID
+	Allocation Allocation
+}
+
+type SetEnrollmentShare struct {
+	EnrollmentID uuid.UUID
+	Share        EnrollmentShare
 }
 
 type InsertBatch struct {
@@ -146,6 +214,10 @@ type InsertBatch struct {
 	Notes            string
 	PayoutDate       time.Time
 	ApprovalDeadline *time.Time
+
+	// SecondApprovalAboveCents is the site's threshold, used when the fund has
+	// none of its own. Nil is no site threshold.
+	SecondApprovalAboveCents *int32
 }
 
 type InsertPayout struct {
@@ -163,6 +235,14 @@ type InsertPayout struct {
 type ApproveBatch struct {
 	BatchID    uuid.UUID
 	ApprovedBy uuid.UUID
+
+	// SecondApprovalAboveCents is the site's threshold, as on InsertBatch.
+	SecondApprovalAboveCents *int32
+}
+
+type SetFundSecondApproval struct {
+	FundID     uuid.UUID
+	AboveCents *int32
 }
 
 type RejectBatch struct {
@@ -170,6 +250,25 @@ type RejectBatch struct {
 	Reason  string
 }
 
+type StrikePayout struct {
+	BatchID  uuid.UUID
+	PayoutID uuid.UUID
+	StruckBy uuid.UUID
+	Reason   string
+
+	// SecondApprovalAboveCents is the site's threshold, as on InsertBatch: a
+	// strike can take a batch back under it.
+	SecondApprovalAboveCents *int32
+}
+
+// StruckPayout is what a strike changed: the batch as recounted, the payout
+// taken out of it, and the member that payout was for.
+type StruckPayout struct {
+	Batch    Batch
+	Payout   Payout
+	MemberID uuid.UUID
+}
+
 type SetBatchStatus struct {
 	BatchID       uuid.UUID
 	Status        Status
@@ -203,9 +302,16 @@ type SetPayoutResult struct {
 
 // PlanBatch is the request to build a batch for a fund's upcoming payout date.
 type PlanBatch struct {
-	FundID          uuid.UUID
-	PayoutDate      time.Time
-	AmountCents     int32
+	FundID     uuid.UUID
+	PayoutDate time.Time
+
+	// AmountCents is paid to every payable enrollee, unless Amounts is set.
+	AmountCents int32
+	// Amounts, when not nil, is what each enrollment is paid, keyed by
+	// enrollment id. An enrollment absent from it, or at zero, is left out of
+	// the batch -- the allocator decided they are owed nothing this period.
+	Amounts map[uuid.UUID]int32
+
 	Description     string
 	Notes           string
 	ApprovalWindow  time.Duration
@@ -232,6 +338,10 @@ type ProviderItemResult struct {
 	ProviderPayoutItemID string
 	Status               string
 	FeeCents             int32
+
+	// Err is why the provider did not deliver the item, when it says. Nil for
+	// an item that went through or has not been decided yet.
+	Err error
 }
 
 // DueFund is a fund whose scheduled payout date has arrived. Name is carried so
@@ -243,4 +353,5 @@ type DueFund struct {
 	Name        string
 	Frequency   string
 	NextPayment time.Time
+	Allocation  Allocation
 }
@@ -0,0 +1,384 @@
+// Package stripe is Stripe as a payments provider: one-time donations through
+// Checkout, recurring ones as subscriptions to a price, and what reconciliation
+// needs to read back.
+//
+// It answers in the vocabulary the services already speak, which is PayPal's.
+// A completed order is "COMPLETED" and a live subscription "ACTIVE", so nothing
+// past this package has to know which provider it is talking to.
+package stripe
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"boardfund/service/donations"
+	"boardfund/service/finance"
+)
+
+type Stripe struct {
+	client *Client
+}
+
+func NewStripe(client *Client) *Stripe {
+	return &Stripe{
+		client: client,
+	}
+}
+
+// CreateFund registers the fund as a product, which every price and checkout
+// for it hangs off -- the same role a PayPal catalog product plays.
+func (s Stripe) CreateFund(ctx context.Context, name, description string) (string, error) {
+	form := url.Values{"name": {name}}
+
+	// Stripe refuses an empty description rather than ignoring it.
+	if description != "" {
+		form.Set("description", description)
+	}
+
+	var product object
+	if err := s.client.post(ctx, "/v1/products", form, &product); err != nil {
+		return "", err
+	}
+
+	return product.ID, nil
+}
+
+// CreatePlan is a recurring price on the fund's product. A Stripe price is a
+// PayPal plan in every way that matters here: fixed amount, fixed cycle, and
+// what a subscription is to.
+func (s Stripe) CreatePlan(ctx context.Context, plan donations.CreatePlan) (string, error) {
+	count := plan.IntervalCount
+	if count < 1 {
+		count = 1
+	}
+
+	form := url.Values{
+		"currency":                  {"usd"},
+		"unit_amount":               {strconv.Itoa(int(plan.AmountCents))},
+		"product":                   {plan.ProviderFundID},
+		"nickname":                  {plan.Name},
+		"recurring[interval]":       {strings.ToLower(string(plan.IntervalUnit))},
+		"recurring[interval_count]": {strconv.Itoa(int(count))},
+	}
+
+	var price object
+	if err := s.client.post(ctx, "/v1/prices", form, &price); err != nil {
+		return "", err
+	}
+
+	return price.ID, nil
+}
+
+// DeactivatePlan archives the price. Stripe does not delete a price anything
+// has been sold at, and an archived one cannot be subscribed to, which is all
+// deactivating is for.
+func (s Stripe) DeactivatePlan(ctx context.Context, planID string) error {
+	return s.client.post(ctx, "/v1/prices/"+planID, url.Values{"active": {"false"}}, nil)
+}
+
+// InitiateDonation opens a Checkout session for one payment to the fund.
+//
+// The fund id goes in client_reference_id, which is what PayPal's reference_id
+// is for: GetOrder reads it back, and the completion refuses a session whose
+// fund is not the one being credited.
+func (s Stripe) InitiateDonation(ctx context.Context, fund donations.Fund, amountCents int32, returnURL, cancelURL string) (*donations.Checkout, error) {
+	form := url.Values{
+		"mode":                                   {"payment"},
+		"client_reference_id":                    {fund.ID.String()},
+		"success_url":                            {withSessionID(returnURL)},
+		"cancel_url":                             {cancelURL},
+		"line_items[0][quantity]":                {"1"},
+		"line_items[0][price_data][currency]":    {"usd"},
+		"line_items[0][price_data][unit_amount]": {strconv.Itoa(int(amountCents))},
+		"line_items[0][price_data][product]":     {fund.ProviderID},
+		"payment_intent_data[metadata][fund_id]": {fund.ID.String()},
+	}
+
+	return s.checkout(ctx, form)
+}
+
+// InitiateSubscription opens a Checkout session that subscribes the donor to
+// the plan's price. Unlike PayPal, whose button makes the subscription on our
+// page, the subscription exists only once the donor has finished at Stripe.
+func (s Stripe) InitiateSubscription(ctx context.Context, plan donations.DonationPlan, returnURL, cancelURL string) (*donations.Checkout, error) {
+	form := url.Values{
+		"mode":                                 {"subscription"},
+		"client_reference_id":                  {plan.FundID.String()},
+		"success_url":                          {withSessionID(returnURL)},
+		"cancel_url":                           {cancelURL},
+		"line_items[0][quantity]":              {"1"},
+		"line_items[0][price]":                 {plan.ProviderPlanID},
+		"subscription_data[metadata][fund_id]": {plan.FundID.String()},
+		"subscription_data[metadata][plan_id]": {plan.ID.String()},
+	}
+
+	return s.checkout(ctx, form)
+}
+
+func (s Stripe) checkout(ctx context.Context, form url.Values) (*donations.Checkout, error) {
+	var session CheckoutSession
+	if err := s.client.post(ctx, "/v1/checkout/sessions", form, &session); err != nil {
+		return nil, err
+	}
+
+	return &donations.Checkout{ID: session.ID, RedirectURL: session.URL}, nil
+}
+
+// withSessionID asks Stripe to put the session id on the way back. The
+// placeholder has to arrive verbatim, braces and all, so it is appended to the
+// URL rather than set through url.Values, which would escape it.
+func withSessionID(returnURL string) string {
+	separator := "?"
+	if strings.Contains(returnURL, "?") {
+		separator = "&"
+	}
+
+	return returnURL + separator + "session_id={CHECKOUT_SESSION_ID}"
+}
+
+// GetOrder reads a Checkout session back, so the donation is recorded from what
+// Stripe says was paid rather than from the browser returning with an id.
+//
+// The payment intent is expanded down to its balance transaction, which is the
+// only place Stripe states its fee.
+func (s Stripe) GetOrder(ctx context.Context, orderID string) (*donations.ProviderOrder, error) {
+	query := url.Values{"expand[]": {"payment_intent.latest_charge.balance_transaction"}}
+
+	var session CheckoutSession
+	if err := s.client.get(ctx, "/v1/checkout/sessions/"+orderID, query, &session); err != nil {
+		return nil, err
+	}
+
+	result := orderFromSession(session)
+
+	return &result, nil
+}
+
+// orderFromSession is the mapping, split out so it can be exercised without a
+// Stripe on the other end.
+func orderFromSession(session CheckoutSession) donations.ProviderOrder {
+	order := donations.ProviderOrder{
+		Status:                 strings.ToUpper(session.Status),
+		FundReferenceID:        session.ClientReferenceID,
+		AmountCents:            session.AmountTotal,
+		ProviderSubscriptionID: session.Subscription.ID,
+	}
+
+	// Complete is the session finished; paid is the money taken. A session can
+	// be the first without the second while a bank payment clears, and that is
+	// not yet a donation.
+	if session.Status == "complete" && session.PaymentStatus == "paid" {
+		order.Status = "COMPLETED"
+	}
+
+	order.ProviderPaymentID = session.PaymentIntent.ID
+	if intent := session.PaymentIntent.Object; intent != nil {
+		order.FeeCents = intent.FeeCents()
+	}
+
+	return order
+}
+
+// subscriptionStatuses maps Stripe's statuses onto the ones the services act
+// on. past_due and unpaid are Stripe retrying a failed payment, which is what
+// PayPal calls suspended; incomplete is a subscription whose first payment has
+// not gone through, and is treated as PayPal's not-yet-approved.
+var subscriptionStatuses = map[string]string{
+	"active":             "ACTIVE",
+	"trialing":           "ACTIVE",
+	"past_due":           "SUSPENDED",
+	"unpaid":             "SUSPENDED",
+	"paused":             "SUSPENDED",
+	"canceled":           "CANCELLED",
+	"incomplete_expired": "EXPIRED",
+	"incomplete":         "APPROVAL_PENDING",
+}
+
+// SubscriptionStatus is status in the services' vocabulary. An unknown one is
+// passed through upper-cased, which is not ACTIVE and is therefore treated as
+// ended by reconciliation -- the safe direction to be wrong in.
+func SubscriptionStatus(status string) string {
+	if mapped, ok := subscriptionStatuses[status]; ok {
+		return mapped
+	}
+
+	return strings.ToUpper(status)
+}
+
+func (s Stripe) subscription(ctx context.Context, subscriptionID string) (*Subscription, error) {
+	var subscription Subscription
+	if err := s.client.get(ctx, "/v1/subscriptions/"+subscriptionID, nil, &subscription); err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+func (s Stripe) GetSubscription(ctx context.Context, subscriptionID string) (*donations.ProviderSubscription, error) {
+	subscription, err := s.subscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := donations.ProviderSubscription{
+		Status: SubscriptionStatus(subscription.Status),
+	}
+
+	// One item, because Checkout made it with one. Quantity is always 1 for the
+	// same reason, but is multiplied in anyway: the amount is what is billed.
+	if len(subscription.Items.Data) > 0 {
+		item := subscription.Items.Data[0]
+		result.ProviderPlanID = item.Price.ID
+		result.AmountCents = item.Price.UnitAmount * max(item.Quantity, 1)
+	}
+
+	return &result, nil
+}
+
+// CancelSubscriptions cancels each immediately. Like PayPal's, it stops at the
+// first refusal and returns what it managed, so the caller can see which are
+// still running.
+func (s Stripe) CancelSubscriptions(ctx context.Context, ids []string) ([]string, error) {
+	var cancelledIDs []string
+
+	for _, id := range ids {
+		if err := s.client.delete(ctx, "/v1/subscriptions/"+id, nil); err != nil {
+			return cancelledIDs, err
+		}
+
+		cancelledIDs = append(cancelledIDs, id)
+	}
+
+	return cancelledIDs, nil
+}
+
+// ReviseSubscription moves the subscription onto another price, from the next
+// invoice on.
+//
+// Stripe needs no approval from the donor for this, so it returns no link and
+// the donor goes straight to the confirmation, which finds the subscription
+// already moved. No proration: the donor asked to give a different amount from
+// now on, not to settle the difference on the current period.
+func (s Stripe) ReviseSubscription(ctx context.Context, subscriptionID, providerPlanID, _, _ string) (string, error) {
+	subscription, err := s.subscription(ctx, subscriptionID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(subscription.Items.Data) == 0 {
+		return "", errors.New("stripe subscription has no items to revise")
+	}
+
+	form := url.Values{
+		"items[0][id]":       {subscription.Items.Data[0].ID},
+		"items[0][price]":    {providerPlanID},
+		"proration_behavior": {"none"},
+	}
+
+	return "", s.client.post(ctx, "/v1/subscriptions/"+subscriptionID, form, nil)
+}
+
+func (s Stripe) GetProviderDonationSubscriptionStatus(ctx context.Context, providerSubscriptionID string) (string, error) {
+	subscription, err := s.subscription(ctx, providerSubscriptionID)
+	if err != nil {
+		return "", err
+	}
+
+	return SubscriptionStatus(subscription.Status), nil
+}
+
+// GetTransactionsForDonationSubscription lists the subscription's paid invoices
+// between two instants, one transaction per invoice.
+//
+// The payment id is the invoice's payment intent, which is what the invoice.paid
+// webhook records the payment under, so a payment found here and one recorded
+// from the webhook are recognised as the same.
+func (s Stripe) GetTransactionsForDonationSubscription(ctx context.Context, subscriptionID string, start, end time.Time) ([]finance.ProviderTransaction, error) {
+	query := url.Values{
+		"subscription": {subscriptionID},
+		"status":       {"paid"},
+		"created[gte]": {strconv.FormatInt(start.Unix(), 10)},
+		"created[lte]": {strconv.FormatInt(end.Unix(), 10)},
+		"limit":        {"100"},
+		"expand[]":     {"data.payment_intent.latest_charge.balance_transaction"},
+	}
+
+	var transactions []finance.ProviderTransaction
+
+	for {
+		var page invoiceList
+		if err := s.client.get(ctx, "/v1/invoices", query, &page); err != nil {
+			return nil, err
+		}
+
+		for _, invoice := range page.Data {
+			transactions = append(transactions, transactionFromInvoice(invoice))
+		}
+
+		if !page.HasMore || len(page.Data) == 0 {
+			return transactions, nil
+		}
+
+		query.Set("starting_after", page.Data[len(page.Data)-1].ID)
+	}
+}
+
+func transactionFromInvoice(invoice Invoice) finance.ProviderTransaction {
+	paid := invoice.StatusTransitions.PaidAt
+	if paid == 0 {
+		paid = invoice.Created
+	}
+
+	status := "OTHER"
+	if invoice.Status == "paid" {
+		status = "COMPLETED"
+	}
+
+	transaction := finance.ProviderTransaction{
+		ProviderPaymentID: invoice.PaymentIntent.ID,
+		Date:              time.Unix(paid, 0).UTC(),
+		Status:            status,
+		AmountCents:       invoice.AmountPaid,
+	}
+
+	if intent := invoice.PaymentIntent.Object; intent != nil {
+		transaction.FeeCents = intent.FeeCents()
+	}
+
+	return transaction
+}
+
+// GetTransaction reads one payment back by its payment intent. The window is
+// PayPal's requirement and Stripe has no use for it.
+func (s Stripe) GetTransaction(ctx context.Context, id string, _, _ time.Time) (*finance.ProviderTransaction, error) {
+	query := url.Values{"expand[]": {"latest_charge.balance_transaction"}}
+
+	var intent PaymentIntent
+	if err := s.client.get(ctx, "/v1/payment_intents/"+id, query, &intent); err != nil {
+		// Nil for a payment Stripe has never heard of, as PayPal's search answers
+		// with nothing: reconciliation records that as missing, which it is.
+		var stripeErr ErrStripe
+		if errors.As(err, &stripeErr) && stripeErr.NotFound() {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	status := "OTHER"
+	if intent.Status == "succeeded" {
+		status = "COMPLETED"
+	}
+
+	return &finance.ProviderTransaction{
+		ProviderPaymentID: intent.ID,
+		Date:              time.Unix(intent.Created, 0).UTC(),
+		Status:            status,
+		AmountCents:       intent.AmountReceived,
+		FeeCents:          intent.FeeCents(),
+	}, nil
+}
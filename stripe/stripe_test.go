@@ -0,0 +1,163 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"boardfund/service/donations"
+)
+
+func newTestStripe(t *testing.T, handler http.HandlerFunc) *Stripe {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewStripe(NewClient("sk_test", slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL))
+}
+
+// An id where the request did not expand, the whole object where it did. The
+// session below carries one of each.
+func TestAnExpandableFieldDecodesEitherWay(t *testing.T) {
+	var session CheckoutSession
+
+	err := json.Unmarshal([]byte(`{
+		"id": "cs_1",
+		"subscription": "sub_1",
+		"payment_intent": {
+			"id": "pi_1",
+			"latest_charge": {"id": "ch_1", "balance_transaction": {"id": "txn_1", "fee": 59}}
+		}
+	}`), &session)
+	require.NoError(t, err)
+
+	require.Equal(t, "sub_1", session.Subscription.ID)
+	require.Nil(t, session.Subscription.Object)
+	require.Equal(t, "pi_1", session.PaymentIntent.ID)
+	require.Equal(t, int32(59), session.PaymentIntent.Object.FeeCents())
+}
+
+// A session the donor finished but whose money has not arrived is not a
+// donation yet, and must not read as COMPLETED.
+func TestOnlyAPaidSessionIsACompletedOrder(t *testing.T) {
+	paid := orderFromSession(CheckoutSession{Status: "complete", PaymentStatus: "paid", ClientReferenceID: "fund", AmountTotal: 500})
+	require.Equal(t, "COMPLETED", paid.Status)
+	require.Equal(t, "fund", paid.FundReferenceID)
+	require.Equal(t, int32(500), paid.AmountCents)
+
+	pending := orderFromSession(CheckoutSession{Status: "complete", PaymentStatus: "unpaid"})
+	require.NotEqual(t, "COMPLETED", pending.Status)
+
+	open := orderFromSession(CheckoutSession{Status: "open", PaymentStatus: "unpaid"})
+	require.NotEqual(t, "COMPLETED", open.Status)
+}
+
+func TestStripeStatusesReadAsTheServicesExpect(t *testing.T) {
+	for stripeStatus, want := range map[string]string{
+		"active":     "ACTIVE",
+		"trialing":   "ACTIVE",
+		"past_due":   "SUSPENDED",
+		"canceled":   "CANCELLED",
+		"incomplete": "APPROVAL_PENDING",
+		"something":  "SOMETHING",
+	} {
+		require.Equal(t, want, SubscriptionStatus(stripeStatus), stripeStatus)
+	}
+}
+
+// Stripe swaps the placeholder for the session id, so it must reach Stripe as
+// written, after whatever the return URL already carries.
+func TestTheCheckoutAsksForTheSessionIDBack(t *testing.T) {
+	fundID := uuid.New()
+
+	var form url.Values
+
+	s := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/checkout/sessions", r.URL.Path)
+		require.Equal(t, "Bearer sk_test", r.Header.Get("Authorization"))
+		require.Equal(t, apiVersion, r.Header.Get("Stripe-Version"))
+
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+
+		_, _ = w.Write([]byte(`{"id": "cs_1", "url": "https://checkout.stripe.com/c/pay/cs_1"}`))
+	})
+
+	checkout, err := s.InitiateDonation(context.Background(),
+		donations.Fund{ID: fundID, ProviderID: "prod_1"}, 2500,
+		"https://fund.example/donation/stripe/once?fund="+fundID.String(), "https://fund.example/donate")
+	require.NoError(t, err)
+
+	require.Equal(t, "cs_1", checkout.ID)
+	require.Equal(t, "https://checkout.stripe.com/c/pay/cs_1", checkout.RedirectURL)
+
+	require.Equal(t, "payment", form.Get("mode"))
+	require.Equal(t, fundID.String(), form.Get("client_reference_id"))
+	require.Equal(t, "2500", form.Get("line_items[0][price_data][unit_amount]"))
+	require.Equal(t, "prod_1", form.Get("line_items[0][price_data][product]"))
+	require.Equal(t, "https://fund.example/donation/stripe/once?fund="+fundID.String()+"&session_id={CHECKOUT_SESSION_ID}",
+		form.Get("success_url"))
+}
+
+// Stripe says "no such object" with a 404. Reconciliation asks about payments
+// that may not exist, and that answer is "nothing", not a failure.
+func TestAPaymentStripeHasNotHeardOfIsNothing(t *testing.T) {
+	s := newTestStripe(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": {"type": "invalid_request_error", "code": "resource_missing", "message": "No such payment_intent"}}`))
+	})
+
+	transaction, err := s.GetTransaction(context.Background(), "pi_missing", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Nil(t, transaction)
+}
+
+func TestASubscriptionsInvoicesAreReadPageByPage(t *testing.T) {
+	var pages int
+
+	s := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/invoices", r.URL.Path)
+		require.Equal(t, "sub_1", r.URL.Query().Get("subscription"))
+
+		pages++
+
+		if r.URL.Query().Get("starting_after") == "" {
+			_, _ = w.Write([]byte(`{"has_more": true, "data": [
+				{"id": "in_1", "status": "paid", "amount_paid": 1000, "status_transitions": {"paid_at": 1700000000},
+				 "payment_intent": {"id": "pi_1", "latest_charge": {"id": "ch_1", "balance_transaction": {"id": "txn_1", "fee": 59}}}}
+			]}`))
+
+			return
+		}
+
+		require.Equal(t, "in_1", r.URL.Query().Get("starting_after"))
+		_, _ = w.Write([]byte(`{"has_more": false, "data": [
+			{"id": "in_2", "status": "paid", "amount_paid": 1000, "created": 1702592000, "payment_intent": "pi_2"}
+		]}`))
+	})
+
+	transactions, err := s.GetTransactionsForDonationSubscription(context.Background(), "sub_1",
+		time.Unix(1690000000, 0), time.Unix(1710000000, 0))
+	require.NoError(t, err)
+
+	require.Equal(t, 2, pages)
+	require.Len(t, transactions, 2)
+
+	require.Equal(t, "pi_1", transactions[0].ProviderPaymentID)
+	require.Equal(t, "COMPLETED", transactions[0].Status)
+	require.Equal(t, int32(59), transactions[0].FeeCents)
+	require.Equal(t, time.Unix(1700000000, 0).UTC(), transactions[0].Date)
+
+	require.Equal(t, "pi_2", transactions[1].ProviderPaymentID)
+	require.Equal(t, time.Unix(1702592000, 0).UTC(), transactions[1].Date)
+}
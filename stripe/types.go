@@ -0,0 +1,145 @@
+package stripe
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// ErrStripe is an error response from the API.
+type ErrStripe struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param"`
+
+	// Status is the HTTP status it came with. Stripe says "not found" with a 404
+	// and a resource_missing code, and the status is the half that cannot be
+	// renamed.
+	Status int `json:"-"`
+}
+
+func (e ErrStripe) Error() string {
+	return e.Message
+}
+
+// NotFound reports whether the object asked for does not exist.
+func (e ErrStripe) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+type errorEnvelope struct {
+	Error ErrStripe `json:"error"`
+}
+
+// expandable is a field Stripe sends as an id, or as the whole object when the
+// request asked for it with expand[]. Both arrive under the same name, so the
+// decoder has to look before it knows which it has.
+type expandable[T any] struct {
+	ID     string
+	Object *T
+}
+
+func (e *expandable[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &e.ID)
+	}
+
+	var withID struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &withID); err != nil {
+		return err
+	}
+
+	e.ID = withID.ID
+	e.Object = new(T)
+
+	return json.Unmarshal(data, e.Object)
+}
+
+type object struct {
+	ID string `json:"id"`
+}
+
+type CheckoutSession struct {
+	ID                string                    `json:"id"`
+	URL               string                    `json:"url"`
+	Mode              string                    `json:"mode"`
+	Status            string                    `json:"status"`
+	PaymentStatus     string                    `json:"payment_status"`
+	ClientReferenceID string                    `json:"client_reference_id"`
+	AmountTotal       int32                     `json:"amount_total"`
+	PaymentIntent     expandable[PaymentIntent] `json:"payment_intent"`
+	Subscription      expandable[Subscription]  `json:"subscription"`
+}
+
+type PaymentIntent struct {
+	ID             string             `json:"id"`
+	Status         string             `json:"status"`
+	Amount         int32              `json:"amount"`
+	AmountReceived int32              `json:"amount_received"`
+	Created        int64              `json:"created"`
+	LatestCharge   expandable[Charge] `json:"latest_charge"`
+}
+
+// FeeCents is what Stripe kept of the payment, when the charge was expanded far
+// enough to say. Zero otherwise, which reconciliation reads as "not known" and
+// does not write over a fee already recorded.
+func (p PaymentIntent) FeeCents() int32 {
+	charge := p.LatestCharge.Object
+	if charge == nil || charge.BalanceTransaction.Object == nil {
+		return 0
+	}
+
+	return charge.BalanceTransaction.Object.Fee
+}
+
+type Charge struct {
+	ID                 string                         `json:"id"`
+	BalanceTransaction expandable[BalanceTransaction] `json:"balance_transaction"`
+}
+
+type BalanceTransaction struct {
+	ID  string `json:"id"`
+	Fee int32  `json:"fee"`
+}
+
+type Subscription struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Items  struct {
+		Data []SubscriptionItem `json:"data"`
+	} `json:"items"`
+}
+
+type SubscriptionItem struct {
+	ID       string `json:"id"`
+	Quantity int32  `json:"quantity"`
+	Price    Price  `json:"price"`
+}
+
+type Price struct {
+	ID         string `json:"id"`
+	UnitAmount int32  `json:"unit_amount"`
+}
+
+type Invoice struct {
+	ID                string                    `json:"id"`
+	Status            string                    `json:"status"`
+	AmountPaid        int32                     `json:"amount_paid"`
+	Created           int64                     `json:"created"`
+	PaymentIntent     expandable[PaymentIntent] `json:"payment_intent"`
+	StatusTransitions struct {
+		PaidAt int64 `json:"paid_at"`
+	} `json:"status_transitions"`
+}
+
+type invoiceList struct {
+	Data    []Invoice `json:"data"`
+	HasMore bool      `json:"has_more"`
+}
@@ -2,34 +2,47 @@ package adminweb
 
 import (
 	"context"
+	"slices"
 	"strings"
 	"testing"
 
+	"boardfund/jwtauth"
 	"boardfund/service/members"
 
 	"github.com/google/uuid"
 )
 
+// rolesHeld is the state for a member holding the roles in held.
+func rolesHeld(held ...string) []RoleAccess {
+	var roles []RoleAccess
+	for _, role := range jwtauth.Roles {
+		roles = append(roles, RoleAccess{Role: role, Held: slices.Contains(held, role.Group)})
+	}
+
+	return roles
+}
+
 func TestAdminAccessControl(t *testing.T) {
 	viewed := members.Member{ID: uuid.New(), BCOName: "michael"}
 
-	promote := "hx-post=\"/admin/member/promote/" + viewed.ID.String() + "\""
-	revoke := "hx-post=\"/admin/member/demote/" + viewed.ID.String() + "\""
+	grant := "hx-post=\"/admin/member/role/grant/" + viewed.ID.String() + "\""
+	revoke := "hx-post=\"/admin/member/role/revoke/" + viewed.ID.String() + "\""
 
 	cases := []struct {
-		name       string
-		state      AdminAccessState
-		wantValue  string
-		wantAction string
+		name        string
+		state       AdminAccessState
+		wantValue   string
+		wantGrants  int
+		wantRevokes int
 	}{
-		{"not an admin", AdminAccessState{}, ">no<", promote},
-		{"already an admin", AdminAccessState{IsAdmin: true}, ">yes<", revoke},
+		{"no roles", AdminAccessState{Roles: rolesHeld()}, ">no<", len(jwtauth.Roles), 0},
+		{"a treasurer", AdminAccessState{Roles: rolesHeld(jwtauth.TreasurerGroup)}, ">yes<", len(jwtauth.Roles) - 1, 1},
 		// Self-revocation is the lockout case: an admin removing their own access
 		// when they are the only one leaves nobody able to restore it.
-		{"yourself", AdminAccessState{IsAdmin: true, IsSelf: true}, ">yes<", ""},
+		{"yourself", AdminAccessState{Roles: rolesHeld(jwtauth.AdminGroup), IsSelf: true}, ">yes<", 0, 0},
 		// A failed Cognito lookup must not render a control, which would offer to
 		// undo whatever is actually true.
-		{"lookup failed", AdminAccessState{Unknown: true}, ">unavailable<", ""},
+		{"lookup failed", AdminAccessState{Unknown: true}, ">unavailable<", 0, 0},
 	}
 
 	for _, c := range cases {
@@ -45,19 +58,35 @@ func TestAdminAccessControl(t *testing.T) {
 				t.Errorf("should report %s", c.wantValue)
 			}
 
-			if c.wantAction != "" && !strings.Contains(html, c.wantAction) {
-				t.Errorf("should offer %s", c.wantAction)
+			if got := strings.Count(html, grant); got != c.wantGrants {
+				t.Errorf("offers %d grants, want %d", got, c.wantGrants)
 			}
 
-			if c.wantAction == "" {
-				if strings.Contains(html, promote) || strings.Contains(html, revoke) {
-					t.Error("should offer no control")
-				}
+			if got := strings.Count(html, revoke); got != c.wantRevokes {
+				t.Errorf("offers %d revokes, want %d", got, c.wantRevokes)
 			}
 		})
 	}
 }
 
+// Each button names its role. Without it the grant route cannot tell a
+// treasurer from an admin, and the one it guessed would be a privilege the
+// click never asked for.
+func TestAdminAccessButtonsNameTheirRole(t *testing.T) {
+	viewed := members.Member{ID: uuid.New(), BCOName: "michael"}
+
+	var out strings.Builder
+	if err := AdminAccess(viewed, AdminAccessState{Roles: rolesHeld()}).Render(context.Background(), &out); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	for _, role := range jwtauth.Roles {
+		if !strings.Contains(out.String(), role.Group) {
+			t.Errorf("no control names %s", role.Group)
+		}
+	}
+}
+
 func TestAdminAccessExplainsTheLoginDelay(t *testing.T) {
 	viewed := members.Member{ID: uuid.New(), BCOName: "michael"}
 
@@ -65,7 +94,7 @@ func TestAdminAccessExplainsTheLoginDelay(t *testing.T) {
 	// nothing to the member's current session. Without the notice the toggle
 	// looks like it failed.
 	var changed strings.Builder
-	if err := AdminAccess(viewed, AdminAccessState{IsAdmin: true, Changed: true}).Render(context.Background(), &changed); err != nil {
+	if err := AdminAccess(viewed, AdminAccessState{Roles: rolesHeld(jwtauth.AdminGroup), Changed: true}).Render(context.Background(), &changed); err != nil {
 		t.Fatalf("render: %v", err)
 	}
 
@@ -75,7 +104,7 @@ func TestAdminAccessExplainsTheLoginDelay(t *testing.T) {
 
 	// The page load is not a change, so it should not claim one just happened.
 	var loaded strings.Builder
-	if err := AdminAccess(viewed, AdminAccessState{IsAdmin: true}).Render(context.Background(), &loaded); err != nil {
+	if err := AdminAccess(viewed, AdminAccessState{Roles: rolesHeld(jwtauth.AdminGroup)}).Render(context.Background(), &loaded); err != nil {
 		t.Fatalf("render: %v", err)
 	}
 
@@ -118,3 +118,21 @@ func TestBothPartiesLinkToTheirMemberPage(t *testing.T) {
 		}
 	}
 }
+
+// A replay has no member for a subject, and from the command line no actor
+// either. The detail is what says how it was done.
+func TestAReplayNamesTheLetterAndHowItWasDone(t *testing.T) {
+	html := renderAudit(t, []adminevents.Event{{
+		ID:           uuid.New(),
+		Kind:         adminevents.KindWebhookReplayed,
+		OccurredAt:   time.Now(),
+		SubjectLabel: "PAYMENT.SALE.COMPLETED, dead letter 4",
+		Detail:       "from the command line",
+	}})
+
+	for _, want := range []string{"replayed webhook", "PAYMENT.SALE.COMPLETED, dead letter 4", "from the command line"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q on the page", want)
+		}
+	}
+}
@@ -99,15 +99,38 @@ func AdminAudit(events []adminevents.Event, member *members.Member, path string)
 							return templ_7745c5c3_Err
 						}
 						var templ_7745c5c3_Var5 string
-						templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(adminEventLabel(event.Kind))
+						templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(adminEventLabel(event))
 						if templ_7745c5c3_Err != nil {
-							return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 44, Col: 68}
+							return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 44, Col: 63}
 						}
 						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
 						if templ_7745c5c3_Err != nil {
 							return templ_7745c5c3_Err
 						}
-						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("</span></td><td class=\"p-2\">")
+						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("</span>")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						if event.Detail != "" {
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("<span class=\"block text-xs text-gray-600\">")
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+							var templ_7745c5c3_Var6 string
+							templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(event.Detail)
+							if templ_7745c5c3_Err != nil {
+								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 49, Col: 68}
+							}
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("</span>")
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+						}
+						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("</td><td class=\"p-2\">")
 						if templ_7745c5c3_Err != nil {
 							return templ_7745c5c3_Err
 						}
@@ -116,8 +139,8 @@ func AdminAudit(events []adminevents.Event, member *members.Member, path string)
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
-							var templ_7745c5c3_Var6 templ.SafeURL = templ.SafeURL("/admin/member/" + event.SubjectMemberID.String())
-							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var6)))
+							var templ_7745c5c3_Var7 templ.SafeURL = templ.SafeURL("/admin/member/" + event.SubjectMemberID.String())
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var7)))
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
@@ -125,12 +148,12 @@ func AdminAudit(events []adminevents.Event, member *members.Member, path string)
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
-							var templ_7745c5c3_Var7 string
-							templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(event.Subject())
+							var templ_7745c5c3_Var8 string
+							templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(event.Subject())
 							if templ_7745c5c3_Err != nil {
-								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 49, Col: 30}
+								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 55, Col: 30}
 							}
-							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
@@ -143,12 +166,12 @@ func AdminAudit(events []adminevents.Event, member *members.Member, path string)
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
-							var templ_7745c5c3_Var8 string
-							templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(event.Subject())
+							var templ_7745c5c3_Var9 string
+							templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(event.Subject())
 							if templ_7745c5c3_Err != nil {
-								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 55, Col: 29}
+								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 61, Col: 29}
 							}
-							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
@@ -167,12 +190,12 @@ func AdminAudit(events []adminevents.Event, member *members.Member, path string)
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
-							var templ_7745c5c3_Var9 string
-							templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(event.ActorName)
+							var templ_7745c5c3_Var10 string
+							templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(event.ActorName)
 							if templ_7745c5c3_Err != nil {
-								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 66, Col: 57}
+								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 72, Col: 57}
 							}
-							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
@@ -185,8 +208,8 @@ func AdminAudit(events []adminevents.Event, member *members.Member, path string)
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
-							var templ_7745c5c3_Var10 templ.SafeURL = templ.SafeURL("/admin/member/" + event.ActorMemberID.String())
-							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var10)))
+							var templ_7745c5c3_Var11 templ.SafeURL = templ.SafeURL("/admin/member/" + event.ActorMemberID.String())
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var11)))
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
@@ -194,12 +217,12 @@ func AdminAudit(events []adminevents.Event, member *members.Member, path string)
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
-							var templ_7745c5c3_Var11 string
-							templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(event.ActorName)
+							var templ_7745c5c3_Var12 string
+							templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(event.ActorName)
 							if templ_7745c5c3_Err != nil {
-								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 69, Col: 30}
+								return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/adminweb/audit.templ`, Line: 75, Col: 30}
 							}
-							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
 							if templ_7745c5c3_Err != nil {
 								return templ_7745c5c3_Err
 							}
@@ -247,8 +270,8 @@ func AdminAudit(events []adminevents.Event, member *members.Member, path string)
 // Not derived from Granted(), which only says which direction the change went:
 // two kinds grant and two revoke, and "granted admin" on an approved email
 // address would be wrong in the one place that exists to be trusted.
-func adminEventLabel(kind adminevents.Kind) string {
-	switch kind {
+func adminEventLabel(event adminevents.Event) string {
+	switch kind := event.Kind; kind {
 	case adminevents.KindAdminGranted:
 		return "granted admin"
 	case adminevents.KindAdminRevoked:
@@ -257,6 +280,34 @@ func adminEventLabel(kind adminevents.Kind) string {
 		return "approved to register"
 	case adminevents.KindEmailApprovalRemoved:
 		return "registration approval removed"
+	case adminevents.KindWebhookReplayed:
+		return "replayed webhook"
+	case adminevents.KindPaymentAssigned:
+		return "assigned a payment"
+	case adminevents.KindPasswordResetRequested:
+		return "asked for a password reset"
+	case adminevents.KindPasswordResetCompleted:
+		return "reset their password"
+	case adminevents.KindTwoFactorEnabled:
+		return "turned on two-factor"
+	case adminevents.KindTwoFactorDisabled:
+		return "turned off two-factor"
+	case adminevents.KindRecoveryCodeUsed:
+		return "used a recovery code"
+	case adminevents.KindRoleGranted:
+		return "granted " + event.Role
+	case adminevents.KindRoleRevoked:
+		return "revoked " + event.Role
+	case adminevents.KindFundManagerAssigned:
+		return "made fund manager"
+	case adminevents.KindFundManagerRemoved:
+		return "removed as fund manager"
+	case adminevents.KindInviteCreated:
+		return "invited to register"
+	case adminevents.KindInviteRevoked:
+		return "invite revoked"
+	case adminevents.KindInviteRedeemed:
+		return "registered by invite"
 	default:
 		// A kind added to the enum and not to this switch still reads as
 		// something rather than as a blank cell.
@@ -0,0 +1,60 @@
+package adminweb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"boardfund/service/donations"
+	"boardfund/service/finance"
+	"boardfund/service/members"
+
+	"github.com/google/uuid"
+)
+
+// A disputed payment says so on the audit page, with what is held, and the flag
+// says why it is raised -- "ok" under a red mark would contradict itself.
+func TestTheAuditPageShowsADisputeAndWhatItHolds(t *testing.T) {
+	var out strings.Builder
+	member := members.Member{ID: uuid.New(), BCOName: "michael"}
+	checked := time.Now()
+
+	audit := finance.Audit{
+		FundName: "rent",
+		Payments: []finance.AuditPayment{
+			{
+				PaymentID:           uuid.New(),
+				ProviderPaymentID:   "SALE-1",
+				DonorName:           "donor",
+				AmountCents:         2500,
+				ProviderStatus:      "COMPLETED",
+				ProviderAmountCents: 2500,
+				ReconciledAt:        &checked,
+				DisputeState:        donations.DisputeOpen,
+				DisputedCents:       2500,
+				DisputeReason:       "MERCHANDISE_OR_SERVICE_NOT_RECEIVED",
+				Created:             checked,
+			},
+			{
+				PaymentID:     uuid.New(),
+				AmountCents:   1000,
+				DisputeState:  donations.DisputeWon,
+				DisputedCents: 1000,
+				Created:       checked,
+			},
+		},
+	}
+
+	if err := FundPaymentsAudit(audit, &member, "/admin/fund/audit").Render(context.Background(), &out); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	html := out.String()
+
+	for _, want := range []string{"disputed, $25.00 held", "dispute won, $10.00 released", `title="disputed"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
@@ -108,6 +108,14 @@ func TestUrlencodedAdminFormsAreRead(t *testing.T) {
 			form:    url.Values{},
 			stopsAt: "email address",
 		},
+		{
+			name:    "invite",
+			pattern: "POST /admin/invite",
+			path:    "/admin/invite",
+			handler: func(h *AdminHandlers) http.HandlerFunc { return h.createInvite },
+			form:    url.Values{"email": {"new@example.com"}, "days": {"a week"}},
+			stopsAt: "days",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			_, records := formRig(t, tc.pattern, tc.path, tc.handler, tc.form)
@@ -1,6 +1,7 @@
 package adminweb
 
 import (
+	"boardfund/jwtauth"
 	"boardfund/messaging"
 	"boardfund/service/adminevents"
 	"boardfund/service/auth"
@@ -10,8 +11,10 @@ import (
 	"boardfund/service/fundevents"
 	"boardfund/service/members"
 	"boardfund/service/notices"
+	"boardfund/service/notifications"
 	"boardfund/service/payouts"
 	"boardfund/web/common"
+	hooksstore "boardfund/web/hooksweb/store"
 	"boardfund/web/mux"
 	"context"
 	"errors"
@@ -19,31 +22,46 @@ import (
 	"github.com/google/uuid"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 )
 
-// webhookBus is the durable bus, read-only. Narrow on purpose: the admin page
-// inspects delivery, it does not drive it.
+// webhookBus is the durable bus. Narrow on purpose: the admin page inspects
+// delivery, and the one thing it may drive is a dead letter back to where it
+// came from. Publishing anything else would be minting provider events.
 type webhookBus interface {
 	Status(ctx context.Context) (messaging.Status, error)
+	ReplayDeadLetter(ctx context.Context, seq uint64) (messaging.DeadLetter, error)
+}
+
+// webhookArchive is every verified webhook PayPal has sent us, kept. Read-only
+// here: it is the record of what arrived, and an admin page that could edit it
+// would make it a record of nothing.
+type webhookArchive interface {
+	SearchEvents(ctx context.Context, filter hooksstore.EventFilter) ([]hooksstore.ArchivedEvent, error)
+	GetEvent(ctx context.Context, transmissionID string) (*hooksstore.ArchivedEventDetail, error)
+	GetEventTypes(ctx context.Context) ([]string, error)
 }
 
 type AdminHandlers struct {
-	withAdmin         func(next http.HandlerFunc) http.HandlerFunc
-	memberService     *members.MemberService
-	donationService   *donations.DonationService
-	enrollmentService *enrollments.EnrollmentsService
-	authService       *auth.AuthService
-	financeService    *finance.FinanceService
-	payoutService     *payouts.PayoutService
-	fundEventsService *fundevents.Service
-	adminEvents       *adminevents.Service
-	noticeService     *notices.Service
-	sessionManager    *scs.SessionManager
-	logger            *slog.Logger
-	webhookBus        webhookBus
-	clientID          string
+	withAdmin           func(next http.HandlerFunc) http.HandlerFunc
+	memberService       *members.MemberService
+	donationService     *donations.DonationService
+	enrollmentService   *enrollments.EnrollmentsService
+	authService         *auth.AuthService
+	financeService      *finance.FinanceService
+	payoutService       *payouts.PayoutService
+	fundEventsService   *fundevents.Service
+	adminEvents         *adminevents.Service
+	noticeService       *notices.Service
+	notificationService *notifications.Service
+	sessionManager      *scs.SessionManager
+	logger              *slog.Logger
+	webhookBus          webhookBus
+	webhookArchive      webhookArchive
+	paypalHealth        paypalHealth
+	clientID            string
 }
 
 func NewAdminHandlers(
@@ -57,68 +75,87 @@ func NewAdminHandlers(
 	fundEventsService *fundevents.Service,
 	adminEvents *adminevents.Service,
 	noticeService *notices.Service,
+	notificationService *notifications.Service,
 	sessionManager *scs.SessionManager,
 	logger *slog.Logger,
 	webhookBus webhookBus,
+	webhookArchive webhookArchive,
 	clientID string,
 ) *AdminHandlers {
 	return &AdminHandlers{
-		withAdmin:         withAdmin,
-		memberService:     memberService,
-		donationService:   donationService,
-		authService:       authService,
-		financeService:    financeService,
-		enrollmentService: enrollmentsService,
-		payoutService:     payoutService,
-		fundEventsService: fundEventsService,
-		adminEvents:       adminEvents,
-		noticeService:     noticeService,
-		sessionManager:    sessionManager,
-		logger:            logger,
-		webhookBus:        webhookBus,
-		clientID:          clientID,
+		withAdmin:           withAdmin,
+		memberService:       memberService,
+		donationService:     donationService,
+		authService:         authService,
+		financeService:      financeService,
+		enrollmentService:   enrollmentsService,
+		payoutService:       payoutService,
+		fundEventsService:   fundEventsService,
+		adminEvents:         adminEvents,
+		noticeService:       noticeService,
+		notificationService: notificationService,
+		sessionManager:      sessionManager,
+		logger:              logger,
+		webhookBus:          webhookBus,
+		webhookArchive:      webhookArchive,
+		clientID:            clientID,
 	}
 }
 
+// Register puts every admin route behind the permission it needs -- see
+// jwtauth.Roles for which roles have which. Reading is open to every role;
+// each change is open only to the roles whose part it is.
 func (h *AdminHandlers) Register(r *mux.Router) {
-	r.HandleFunc("/admin", h.withAdmin(h.adminPage))
-	r.HandleFunc("GET /admin/funds", h.withAdmin(h.fundsPage))
-	r.HandleFunc("POST /admin/fund", h.withAdmin(h.createFund))
-	r.HandleFunc("POST /admin/fund/deactivate/{id}", h.withAdmin(h.deactivateFund))
-	r.HandleFunc("POST /admin/member/deactivate/{id}", h.withAdmin(h.deactivateMember))
-	r.HandleFunc("GET /admin/member/{id}", h.withAdmin(h.memberPage))
-	// verb/{id}, like deactivate above, and not /member/{id}/admin: a wildcard in
+	r.HandleFunc("/admin", h.require(jwtauth.ViewAdmin, h.adminPage))
+	r.HandleFunc("GET /admin/funds", h.require(jwtauth.ViewAdmin, h.fundsPage))
+	r.HandleFunc("POST /admin/fund", h.require(jwtauth.CreateFunds, h.createFund))
+	r.HandleFunc("POST /admin/fund/deactivate/{id}", h.require(jwtauth.CreateFunds, h.deactivateFund))
+	r.HandleFunc("POST /admin/member/deactivate/{id}", h.require(jwtauth.ManageMembers, h.deactivateMember))
+	r.HandleFunc("GET /admin/member/{id}", h.require(jwtauth.ViewAdmin, h.memberPage))
+	// verb/{id}, like deactivate above, and not /member/{id}/role: a wildcard in
 	// the third segment overlaps every literal one, and ServeMux rejects the
 	// ambiguity by panicking as it registers.
-	r.HandleFunc("POST /admin/member/promote/{id}", h.withAdmin(h.grantAdmin))
-	r.HandleFunc("POST /admin/member/demote/{id}", h.withAdmin(h.revokeAdmin))
-	r.HandleFunc("GET /admin/fund/audit", h.withAdmin(h.fundAudit))
-	r.HandleFunc("GET /admin/fund", h.withAdmin(h.fundPage))
-	r.HandleFunc("POST /admin/note/remove/{id}", h.withAdmin(h.removeFundNote))
+	r.HandleFunc("POST /admin/member/role/grant/{id}", h.require(jwtauth.ManageMembers, h.grantRole))
+	r.HandleFunc("POST /admin/member/role/revoke/{id}", h.require(jwtauth.ManageMembers, h.revokeRole))
+	r.HandleFunc("POST /admin/member/fund/assign/{id}", h.require(jwtauth.ManageMembers, h.assignFund))
+	r.HandleFunc("POST /admin/member/fund/remove/{id}", h.require(jwtauth.ManageMembers, h.removeFund))
+	r.HandleFunc("POST /admin/member/two-factor/reset/{id}", h.require(jwtauth.ManageMembers, h.resetTwoFactor))
+	r.HandleFunc("GET /admin/fund/audit", h.require(jwtauth.ViewAdmin, h.fundAudit))
+	r.HandleFunc("GET /admin/fund", h.require(jwtauth.ViewAdmin, h.fundPage))
+	r.HandleFunc("POST /admin/note/remove/{id}", h.require(jwtauth.ModerateNotes, h.removeFundNote))
 	// Verb-first, like deactivate/{id} beside it. "/admin/fund/{id}/image" reads
 	// better and cannot be registered: it collides with "/admin/fund/deactivate/{id}",
 	// because {id} matches "deactivate" just as happily as a uuid. The route
 	// conflict test caught it, which is what that test exists for.
-	r.HandleFunc("POST /admin/fund/details/{id}", h.withAdmin(h.saveFundDetails))
-	r.HandleFunc("POST /admin/fund/image/{id}", h.withAdmin(h.setFundImage))
-	r.HandleFunc("POST /admin/fund/image/remove/{id}", h.withAdmin(h.removeFundImage))
-	r.HandleFunc("GET /admin/members/search", h.withAdmin(h.searchMembers))
-	r.HandleFunc("POST /admin/enrollment", h.withAdmin(h.createEnrollment))
-	r.HandleFunc("GET /admin/enrollment/confirm", h.withAdmin(h.confirmEnrollment))
-	r.HandleFunc("POST /admin/enrollment/cancel/{id}", h.withAdmin(h.deactivateEnrollment))
-	r.HandleFunc("GET /admin/payouts", h.withAdmin(h.payoutsPage))
-	r.HandleFunc("GET /admin/webhooks", h.withAdmin(h.webhooksPage))
-	r.HandleFunc("GET /admin/audit", h.withAdmin(h.auditPage))
-	r.HandleFunc("GET /admin/payout/{id}", h.withAdmin(h.payoutPage))
-	r.HandleFunc("POST /admin/payout/approve/{id}", h.withAdmin(h.approvePayout))
-	r.HandleFunc("POST /admin/payout/reject/{id}", h.withAdmin(h.rejectPayout))
-	r.HandleFunc("DELETE /admin/approved/{email}", h.withAdmin(h.deleteApprovedEmail))
-	r.HandleFunc("POST /admin/approved", h.withAdmin(h.addApprovedEmail))
-	r.HandleFunc("POST /admin/notice", h.withAdmin(h.addNotice))
+	r.HandleFunc("POST /admin/fund/details/{id}", h.requireFund(fundInPath, h.saveFundDetails))
+	r.HandleFunc("POST /admin/fund/image/{id}", h.requireFund(fundInPath, h.setFundImage))
+	r.HandleFunc("POST /admin/fund/image/remove/{id}", h.requireFund(fundInPath, h.removeFundImage))
+	r.HandleFunc("GET /admin/members/search", h.require(jwtauth.ViewAdmin, h.searchMembers))
+	r.HandleFunc("POST /admin/enrollment", h.requireFund(fundInForm, h.createEnrollment))
+	r.HandleFunc("GET /admin/enrollment/confirm", h.requireFund(fundInQuery, h.confirmEnrollment))
+	r.HandleFunc("POST /admin/enrollment/cancel/{id}", h.requireFund(fundOfEnrollment, h.deactivateEnrollment))
+	r.HandleFunc("GET /admin/payouts", h.require(jwtauth.ViewAdmin, h.payoutsPage))
+	r.HandleFunc("GET /admin/webhooks", h.require(jwtauth.OperateWebhooks, h.webhooksPage))
+	r.HandleFunc("POST /admin/webhooks/replay/{seq}", h.require(jwtauth.OperateWebhooks, h.replayDeadLetter))
+	r.HandleFunc("GET /admin/webhooks/events", h.require(jwtauth.OperateWebhooks, h.webhookEventsPage))
+	r.HandleFunc("GET /admin/webhooks/event/{transmission}", h.require(jwtauth.OperateWebhooks, h.webhookEventPage))
+	r.HandleFunc("GET /admin/paypal", h.require(jwtauth.OperateWebhooks, h.paypalPage))
+	r.HandleFunc("GET /admin/unmatched", h.require(jwtauth.ApprovePayouts, h.unmatchedPage))
+	r.HandleFunc("POST /admin/unmatched/assign/{id}", h.require(jwtauth.ApprovePayouts, h.assignUnmatchedPayment))
+	r.HandleFunc("GET /admin/audit", h.require(jwtauth.ViewAdmin, h.auditPage))
+	r.HandleFunc("GET /admin/payout/{id}", h.require(jwtauth.ViewAdmin, h.payoutPage))
+	r.HandleFunc("POST /admin/payout/approve/{id}", h.require(jwtauth.ApprovePayouts, h.approvePayout))
+	r.HandleFunc("POST /admin/payout/reject/{id}", h.require(jwtauth.ApprovePayouts, h.rejectPayout))
+	r.HandleFunc("POST /admin/payout/strike/{id}/{payout}", h.require(jwtauth.ApprovePayouts, h.strikePayout))
+	r.HandleFunc("POST /admin/invite", h.require(jwtauth.ManageMembers, h.createInvite))
+	r.HandleFunc("POST /admin/invite/revoke/{id}", h.require(jwtauth.ManageMembers, h.revokeInvite))
+	r.HandleFunc("DELETE /admin/approved/{email}", h.require(jwtauth.ManageMembers, h.deleteApprovedEmail))
+	r.HandleFunc("POST /admin/approved", h.require(jwtauth.ManageMembers, h.addApprovedEmail))
+	r.HandleFunc("POST /admin/notice", h.require(jwtauth.ModerateNotes, h.addNotice))
 	// visibility/{id}, not {id}/visibility: a wildcard in the third segment would
 	// overlap the literal "notice" above it, which ServeMux refuses by panicking
 	// as it registers.
-	r.HandleFunc("POST /admin/notice/visibility/{id}", h.withAdmin(h.setNoticeVisibility))
+	r.HandleFunc("POST /admin/notice/visibility/{id}", h.require(jwtauth.ModerateNotes, h.setNoticeVisibility))
 }
 
 func (h *AdminHandlers) deactivateEnrollment(w http.ResponseWriter, r *http.Request) {
@@ -557,18 +594,24 @@ func (h *AdminHandlers) fundAudit(w http.ResponseWriter, r *http.Request) {
 	FundPaymentsAudit(*audit, &member, r.URL.Path).Render(ctx, w)
 }
 
-// AdminAccessState is what the member page knows about a member's admin rights.
-// Unknown is separate from IsAdmin because the answer lives in Cognito: when that
-// lookup fails, "not an admin" is a guess, and one that offers a button undoing
-// whatever is actually true.
+// RoleAccess is one role, and whether the viewed member holds it.
+type RoleAccess struct {
+	Role jwtauth.Role
+	Held bool
+}
+
+// AdminAccessState is what the member page knows about a member's roles.
+// Unknown is separate from every Held being false because the answer lives in
+// Cognito: when that lookup fails, "no roles" is a guess, and one that offers
+// buttons undoing whatever is actually true.
 type AdminAccessState struct {
-	IsAdmin bool
+	Roles   []RoleAccess
 	IsSelf  bool
 	Changed bool
 	Unknown bool
 }
 
-// adminAccess reads the viewed member's rights from Cognito. A failure is
+// adminAccess reads the viewed member's roles from Cognito. A failure is
 // reported as unknown rather than returned: the rest of the member page --
 // donations, contributions, dates -- does not depend on Cognito, and taking the
 // whole page down when the provider is slow is how a blip becomes an outage.
@@ -578,30 +621,36 @@ func (h *AdminHandlers) adminAccess(ctx context.Context, viewed, actor members.M
 		Changed: changed,
 	}
 
-	isAdmin, err := h.authService.IsAdmin(ctx, viewed.BCOName)
+	held, err := h.authService.Roles(ctx, viewed.BCOName)
 	if err != nil {
 		state.Unknown = true
 
 		return state
 	}
 
-	state.IsAdmin = isAdmin
+	for _, role := range jwtauth.Roles {
+		state.Roles = append(state.Roles, RoleAccess{
+			Role: role,
+			Held: slices.ContainsFunc(held, func(r jwtauth.Role) bool { return r.Group == role.Group }),
+		})
+	}
 
 	return state
 }
 
-// setAdmin backs both the promote and the revoke route. It re-reads the rights
-// from Cognito afterwards rather than assuming the write took, so the control
-// that comes back reflects the provider rather than the request.
-func (h *AdminHandlers) setAdmin(w http.ResponseWriter, r *http.Request, grant bool) {
+// setRole backs both the grant and the revoke route, for the role named by the
+// form's group. It re-reads the roles from Cognito afterwards rather than
+// assuming the write took, so the controls that come back reflect the provider
+// rather than the request.
+func (h *AdminHandlers) setRole(w http.ResponseWriter, r *http.Request, grant bool) {
 	ctx := r.Context()
 
 	actor, ok := h.sessionManager.Get(ctx, "member").(members.Member)
 	if !ok {
-		// Only ever reached by hx-post/hx-delete from the toggle, so a bare 302
-		// would be followed by the XHR and put the home page inside the admin
-		// access row. A session can expire while the token behind withAdmin is
-		// still valid, which is exactly when this fires.
+		// Only ever reached by hx-post from the toggles, so a bare 302 would be
+		// followed by the XHR and put the home page inside the roles row. A
+		// session can expire while the token behind withAdmin is still valid,
+		// which is exactly when this fires.
 		common.Redirect(w, r, "/")
 
 		return
@@ -614,6 +663,13 @@ func (h *AdminHandlers) setAdmin(w http.ResponseWriter, r *http.Request, grant b
 		return
 	}
 
+	role, ok := jwtauth.RoleByGroup(r.FormValue("role"))
+	if !ok {
+		h.badRequest(w, r, "that is not a role.")
+
+		return
+	}
+
 	viewed, err := h.memberService.GetMemberByID(ctx, idUUID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -622,25 +678,25 @@ func (h *AdminHandlers) setAdmin(w http.ResponseWriter, r *http.Request, grant b
 		return
 	}
 
-	// One admin revoking their own access locks everyone out, and the admin
-	// section is the only place this can be undone. The button is hidden for the
-	// same case, but the route is reachable without it.
+	// An admin revoking their own access locks everyone out, and the admin
+	// section is the only place this can be undone. The buttons are hidden for
+	// the same case, but the route is reachable without them.
 	if !grant && viewed.ID == actor.ID {
 		w.WriteHeader(http.StatusConflict)
-		common.ErrorMessage(&actor, "you cannot revoke your own admin access", r.URL.Path, r.URL.Path).Render(ctx, w)
+		common.ErrorMessage(&actor, "you cannot revoke your own roles", r.URL.Path, r.URL.Path).Render(ctx, w)
 
 		return
 	}
 
 	if grant {
-		err = h.authService.GrantAdmin(ctx, actor, *viewed)
+		err = h.authService.GrantRole(ctx, actor, *viewed, role)
 	} else {
-		err = h.authService.RevokeAdmin(ctx, actor, *viewed)
+		err = h.authService.RevokeRole(ctx, actor, *viewed, role)
 	}
 
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		common.ErrorMessage(&actor, "failed to change admin access", r.URL.Path, r.URL.Path).Render(ctx, w)
+		common.ErrorMessage(&actor, "failed to change "+role.Name+" access", r.URL.Path, r.URL.Path).Render(ctx, w)
 
 		return
 	}
@@ -648,16 +704,195 @@ func (h *AdminHandlers) setAdmin(w http.ResponseWriter, r *http.Request, grant b
 	AdminAccess(*viewed, h.adminAccess(ctx, *viewed, actor, true)).Render(ctx, w)
 }
 
-func (h *AdminHandlers) grantAdmin(w http.ResponseWriter, r *http.Request) {
-	h.setAdmin(w, r, true)
+func (h *AdminHandlers) grantRole(w http.ResponseWriter, r *http.Request) {
+	h.setRole(w, r, true)
+}
+
+func (h *AdminHandlers) revokeRole(w http.ResponseWriter, r *http.Request) {
+	h.setRole(w, r, false)
+}
+
+// FundManagerState is the funds a member manages, and the open funds they could
+// be given.
+type FundManagerState struct {
+	Managed   []auth.ManagedFund
+	Available []donations.Fund
+	Unknown   bool
+}
+
+// fundManagerAccess is read from the database, so unlike the roles it is only
+// unknown when the database is; it is reported so anyway, for the same reason.
+func (h *AdminHandlers) fundManagerAccess(ctx context.Context, viewed members.Member) FundManagerState {
+	var state FundManagerState
+
+	managed, err := h.authService.ManagedFunds(ctx, viewed.ID)
+	if err != nil {
+		state.Unknown = true
+
+		return state
+	}
+
+	funds, err := h.donationService.ListActiveFunds(ctx)
+	if err != nil {
+		state.Unknown = true
+
+		return state
+	}
+
+	state.Managed = managed
+
+	for _, fund := range funds {
+		if !slices.ContainsFunc(managed, func(m auth.ManagedFund) bool { return m.ID == fund.ID }) {
+			state.Available = append(state.Available, fund)
+		}
+	}
+
+	return state
+}
+
+// setFundManager backs both the assign and the remove route. An assignment is
+// kept whether or not the member has the fund manager role: it grants nothing
+// without the role, and the role can be given before or after.
+func (h *AdminHandlers) setFundManager(w http.ResponseWriter, r *http.Request, assign bool) {
+	ctx := r.Context()
+
+	actor, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	idUUID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.badRequest(w, r, "")
+
+		return
+	}
+
+	fundID, err := uuid.Parse(r.FormValue("fund"))
+	if err != nil {
+		h.badRequest(w, r, "that is not a fund id.")
+
+		return
+	}
+
+	viewed, err := h.memberService.GetMemberByID(ctx, idUUID)
+	if err != nil {
+		h.internalError(w, r)
+
+		return
+	}
+
+	fund, err := h.donationService.GetFundByID(ctx, fundID)
+	if err != nil {
+		h.internalError(w, r)
+
+		return
+	}
+
+	managed := auth.ManagedFund{ID: fund.ID, Name: fund.Name}
+
+	if assign {
+		err = h.authService.AssignFundManager(ctx, actor, *viewed, managed)
+	} else {
+		err = h.authService.RemoveFundManager(ctx, actor, *viewed, managed)
+	}
+
+	if err != nil {
+		h.internalError(w, r)
+
+		return
+	}
+
+	FundManagerAccess(*viewed, h.fundManagerAccess(ctx, *viewed)).Render(ctx, w)
+}
+
+func (h *AdminHandlers) assignFund(w http.ResponseWriter, r *http.Request) {
+	h.setFundManager(w, r, true)
 }
 
-func (h *AdminHandlers) revokeAdmin(w http.ResponseWriter, r *http.Request) {
-	h.setAdmin(w, r, false)
+func (h *AdminHandlers) removeFund(w http.ResponseWriter, r *http.Request) {
+	h.setFundManager(w, r, false)
 }
 
-// webhooksPage is the only window onto the durable bus. The embedded NATS server
-// opens no client port, so nothing outside this process can query it.
+// TwoFactorState is what the member page knows about a member's second factor.
+type TwoFactorState struct {
+	Enabled bool
+	IsSelf  bool
+	Reset   bool
+	Unknown bool
+}
+
+func (h *AdminHandlers) twoFactorAccess(ctx context.Context, viewed, actor members.Member, reset bool) TwoFactorState {
+	state := TwoFactorState{
+		IsSelf: viewed.ID == actor.ID,
+		Reset:  reset,
+	}
+
+	enabled, err := h.authService.HasTwoFactor(ctx, viewed.ID)
+	if err != nil {
+		state.Unknown = true
+
+		return state
+	}
+
+	state.Enabled = enabled
+
+	return state
+}
+
+// resetTwoFactor removes another member's authenticator and recovery codes, for
+// a member who has lost both. They set it up again at their next login to the
+// admin pages, or whenever they choose to if they are not an admin.
+//
+// Not for the admin's own: that goes through the account page, which asks for
+// a code first. Here the route is an admin's session alone, and a session left
+// open should not be able to strip its own second factor.
+func (h *AdminHandlers) resetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actor, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	idUUID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.badRequest(w, r, "")
+
+		return
+	}
+
+	viewed, err := h.memberService.GetMemberByID(ctx, idUUID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		common.ErrorMessage(&actor, "failed to get member", r.URL.Path, r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	if viewed.ID == actor.ID {
+		w.WriteHeader(http.StatusConflict)
+		common.ErrorMessage(&actor, "turn off your own two-factor from your account page", r.URL.Path, r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	if err = h.authService.DisableTwoFactor(ctx, actor, *viewed); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		common.ErrorMessage(&actor, "failed to reset two-factor", r.URL.Path, r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	TwoFactorAccess(*viewed, h.twoFactorAccess(ctx, *viewed, actor, true)).Render(ctx, w)
+}
+
+// webhooksPage is the window onto the durable bus. The embedded NATS server
+// listens on loopback only, so nothing off the host can query it.
 func (h *AdminHandlers) webhooksPage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -678,6 +913,52 @@ func (h *AdminHandlers) webhooksPage(w http.ResponseWriter, r *http.Request) {
 	Webhooks(status, &member, r.URL.Path).Render(ctx, w)
 }
 
+// replayDeadLetter sends a dead letter back to the consumer that gave up on it.
+//
+// Recorded in the admin log under the admin who pressed the button. A replay
+// re-runs whatever the event does to the money -- records a payment, applies a
+// refund, ends a subscription -- so "who did that, and when" has to have an
+// answer after the letter itself is gone.
+func (h *AdminHandlers) replayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actor, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	seq, err := strconv.ParseUint(r.PathValue("seq"), 10, 64)
+	if err != nil || seq == 0 {
+		h.badRequest(w, r, "that is not a dead letter.")
+
+		return
+	}
+
+	letter, err := h.webhookBus.ReplayDeadLetter(ctx, seq)
+	if errors.Is(err, messaging.ErrDeadLetterNotFound) {
+		h.renderError(w, r, http.StatusNotFound, "that dead letter is gone. it has most likely been replayed already.")
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to replay dead letter",
+			slog.Uint64("seq", seq),
+			slog.String("error", err.Error()),
+		)
+
+		h.internalError(w, r)
+
+		return
+	}
+
+	h.adminEvents.Record(ctx, adminevents.WebhookReplayed(&actor.ID, letter.Subject, letter.Seq, "from the admin page"))
+
+	DeadLetterReplayed(letter).Render(ctx, w)
+}
+
 // auditPage shows every recorded change to admin access.
 //
 // Guarded by withAdmin like the rest of the section. That is the right level:
@@ -736,7 +1017,12 @@ func (h *AdminHandlers) memberPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("HX-Redirect", r.URL.Path)
-	Member(*memberDetails, &member, r.URL.Path, h.adminAccess(ctx, *memberDetails, member, false)).Render(ctx, w)
+	Member(
+		*memberDetails, &member, r.URL.Path,
+		h.adminAccess(ctx, *memberDetails, member, false),
+		h.fundManagerAccess(ctx, *memberDetails),
+		h.twoFactorAccess(ctx, *memberDetails, member, false),
+	).Render(ctx, w)
 }
 
 func (h *AdminHandlers) deactivateFund(w http.ResponseWriter, r *http.Request) {
@@ -838,13 +1124,15 @@ func (h *AdminHandlers) createFund(w http.ResponseWriter, r *http.Request) {
 		// reading as the details card, and the safe direction: a form that lost
 		// the field creates a fund that does not name anybody.
 		EnrolleesVisible: checkboxOn(r, "show_recipients"),
+		CloseOnGoal:      checkboxOn(r, "close_on_goal"),
 	}
 
 	newFund, err := h.donationService.CreateFund(ctx, createFund, &actor.ID)
 	if err != nil {
-		// The admin can fix this, and it is the one refusal here that is about
+		// The admin can fix these, and they are the refusals here that are about
 		// what they typed rather than about something going wrong.
-		if errors.Is(err, donations.ErrOneTimeFundNeedsEndDate) {
+		if errors.Is(err, donations.ErrOneTimeFundNeedsEndDate) || errors.Is(err, donations.ErrUnknownFrequency) ||
+			errors.Is(err, donations.ErrCloseOnGoalNeedsGoal) {
 			h.badRequest(w, r, err.Error()+".")
 
 			return
@@ -979,14 +1267,21 @@ func (h *AdminHandlers) adminPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Not fatal: this page is members and approved emails, and it is still that
-	// with an empty notice panel.
+	invites, err := h.invites(r)
+	if err != nil {
+		h.internalError(w, r)
+
+		return
+	}
+
+	// Not fatal: this page is members and invites, and it is still that with an
+	// empty notice panel.
 	all, err := h.noticeService.All(ctx)
 	if err != nil {
 		all = nil
 	}
 
-	Members(currentMembers, emails, all, &member, r.URL.Path).Render(ctx, w)
+	Members(currentMembers, invites, emails, all, &member, r.URL.Path).Render(ctx, w)
 }
 
 func dollarStringToCents(dollars string) (int32, error) {
@@ -1185,6 +1480,7 @@ func (h *AdminHandlers) saveFundDetails(w http.ResponseWriter, r *http.Request)
 	// the safe direction: a form that lost the field turns recipient names off
 	// rather than on.
 	updated.EnrolleesVisible = checkboxOn(r, "show_recipients")
+	updated.CloseOnGoal = checkboxOn(r, "close_on_goal")
 
 	if goal := r.FormValue("goal"); goal != "" {
 		goalCents, errGoal := dollarStringToCents(goal)
@@ -1221,7 +1517,7 @@ func (h *AdminHandlers) saveFundDetails(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		if errors.Is(err, donations.ErrOneTimeFundNeedsEndDate) {
+		if errors.Is(err, donations.ErrOneTimeFundNeedsEndDate) || errors.Is(err, donations.ErrCloseOnGoalNeedsGoal) {
 			h.badDetails(w, r, fundID, err.Error()+".")
 
 			return
@@ -0,0 +1,199 @@
+package adminweb
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"boardfund/service/auth"
+	"boardfund/service/donations"
+	"boardfund/service/members"
+	"boardfund/web/common"
+
+	"github.com/google/uuid"
+)
+
+// inviteLifetimes are the choices the invite form offers, in days. A handful
+// rather than a free number: nobody needs an invite for eleven days, and the
+// service refuses anything past MaxInviteLifetime regardless.
+var inviteLifetimes = []int{1, 7, 30}
+
+// InvitesState is the invite panel on the members page.
+type InvitesState struct {
+	Invites []auth.Invite
+
+	// Funds are what an invite may enroll its member in.
+	Funds []donations.Fund
+
+	// Enabled is false with no signing key configured, and the form is replaced
+	// by a line saying so.
+	Enabled bool
+
+	// Created is the invite just made, shown above the list so its link can be
+	// copied without hunting for it.
+	Created *auth.Invite
+
+	Now time.Time
+}
+
+// invites gathers the panel. The funds failing to load only loses the choice of
+// one; the invites failing is an error, because a panel that showed none would
+// say there were none.
+func (h *AdminHandlers) invites(r *http.Request) (InvitesState, error) {
+	ctx := r.Context()
+
+	state := InvitesState{Enabled: h.authService.InvitesEnabled(), Now: time.Now()}
+
+	invites, err := h.authService.Invites(ctx)
+	if err != nil {
+		return state, err
+	}
+
+	state.Invites = invites
+
+	if funds, err := h.donationService.ListActiveFunds(ctx); err == nil {
+		state.Funds = funds
+	}
+
+	return state, nil
+}
+
+// createInvite makes an invite link from the form: an optional address it is
+// bound to, an optional fund to enroll in, and how many days it lasts.
+func (h *AdminHandlers) createInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actor, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormBytes)
+
+	if err := r.ParseForm(); err != nil {
+		h.badRequest(w, r, "we could not read that form.")
+
+		return
+	}
+
+	invite := auth.NewInvite{Email: r.PostFormValue("email")}
+
+	if fund := r.PostFormValue("fund"); fund != "" {
+		fundID, err := uuid.Parse(fund)
+		if err != nil {
+			h.badRequest(w, r, "that is not a fund id.")
+
+			return
+		}
+
+		invite.FundID = &fundID
+	}
+
+	if days := r.PostFormValue("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			h.badRequest(w, r, "choose how many days the invite lasts.")
+
+			return
+		}
+
+		invite.Lifetime = time.Duration(n) * 24 * time.Hour
+	}
+
+	created, err := h.authService.CreateInvite(ctx, actor, invite)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvitesUnavailable):
+			h.renderError(w, r, http.StatusServiceUnavailable, "invite links are off. set INVITE_SIGNING_KEY to turn them on.")
+		case errors.Is(err, auth.ErrInvalidInviteEmail), errors.Is(err, auth.ErrInviteLifetime):
+			h.badRequest(w, r, err.Error()+".")
+		default:
+			h.internalError(w, r)
+		}
+
+		return
+	}
+
+	state, err := h.invites(r)
+	if err != nil {
+		h.internalError(w, r)
+
+		return
+	}
+
+	state.Created = created
+
+	InvitePanel(state).Render(ctx, w)
+}
+
+// revokeInvite stops an unused invite working. One already used or revoked --
+// by another admin, in another tab -- is a conflict rather than a success: the
+// link did not stop working because of this click.
+func (h *AdminHandlers) revokeInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actor, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.badRequest(w, r, "that is not an invite.")
+
+		return
+	}
+
+	if err = h.authService.RevokeInvite(ctx, actor, id); err != nil {
+		if errors.Is(err, auth.ErrInviteInvalid) {
+			h.renderError(w, r, http.StatusConflict, "that invite has already been used or revoked.")
+
+			return
+		}
+
+		h.internalError(w, r)
+
+		return
+	}
+
+	state, err := h.invites(r)
+	if err != nil {
+		h.internalError(w, r)
+
+		return
+	}
+
+	InvitePanel(state).Render(ctx, w)
+}
+
+func inviteDays(days int) string {
+	if days == 1 {
+		return "1 day"
+	}
+
+	return strconv.Itoa(days) + " days"
+}
+
+// inviteDetail is the line under an invite: who made it, what it enrolls in,
+// and who it let in or until when it lasts.
+func inviteDetail(invite auth.Invite, state InvitesState) string {
+	detail := "by " + invite.InviterName + " on " + invite.Created.Format("Jan 02, 2006")
+
+	if invite.FundName != "" {
+		detail += ", enrolls in " + invite.FundName
+	}
+
+	switch {
+	case invite.MemberName != "":
+		detail += ", registered " + invite.MemberName
+	case invite.Open(state.Now):
+		detail += ", until " + invite.ExpiresAt.Format("Jan 02 15:04")
+	}
+
+	return detail
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"boardfund/service/notifications"
 	"boardfund/service/payouts"
 
 	"github.com/google/uuid"
@@ -187,3 +188,82 @@ func TestNothingUnhoverableSitsBetweenTheCountAndTheNames(t *testing.T) {
 	require.Contains(t, html, "top-full pt-1")
 	require.NotContains(t, html, "top-full mt-1")
 }
+
+// A refused address is why a batch can expire with nobody having decided
+// anything. The page has to say which one, and what the relay said about it.
+func TestAFailedNotificationShowsWhoItMissedAndWhy(t *testing.T) {
+	batchID := uuid.New()
+
+	html := renderAdmin(t, BatchNotifications([]notifications.Delivery{
+		{
+			BatchID: batchID, Kind: notifications.KindApprovalRequired, Channel: notifications.ChannelEmail,
+			Recipient: "typo@example.org", Error: "550 no such mailbox", Created: time.Now(),
+		},
+		{
+			BatchID: batchID, Kind: notifications.KindApprovalRequired, Channel: notifications.ChannelEmail,
+			Recipient: "treasurer@example.org", Created: time.Now(),
+		},
+	}))
+
+	require.Contains(t, html, "typo@example.org")
+	require.Contains(t, html, "550 no such mailbox")
+	require.Contains(t, html, "failed")
+	require.Contains(t, html, "treasurer@example.org")
+	require.Contains(t, html, "sent")
+}
+
+// One wrong address is struck on its own. The struck payout stays listed with
+// its reason, and cannot be struck again.
+func TestAStruckPayoutSaysWhyAndOnlyLivePayoutsCanBeStruck(t *testing.T) {
+	batch := awaitingBatch("human fund", []string{"ada", "bo", "cyd"}).Batch
+
+	struckAt := time.Now()
+	struckBy := uuid.New()
+	live := payouts.Payout{ID: uuid.New(), BatchID: batch.ID, AmountCents: 2000, Status: payouts.StatusAwaitingApproval, DestinationEmail: "ada@example.org"}
+	struck := payouts.Payout{
+		ID: uuid.New(), BatchID: batch.ID, AmountCents: 2000, Status: payouts.StatusStruck,
+		DestinationEmail: "bo@typo.example", FailureReason: "wrong paypal address",
+		StruckAt: &struckAt, StruckBy: &struckBy, StruckByName: "treasurer",
+	}
+
+	html := renderAdmin(t, BatchItems(batch, []payouts.Payout{live, struck}))
+
+	require.Contains(t, html, fmt.Sprintf("/admin/payout/strike/%s/%s", batch.ID, live.ID))
+	require.NotContains(t, html, fmt.Sprintf("/admin/payout/strike/%s/%s", batch.ID, struck.ID))
+	require.Contains(t, html, "wrong paypal address")
+	require.Contains(t, html, "by treasurer")
+	require.NotContains(t, html, struckBy.String(), "the audit line names the admin, not their id")
+}
+
+// Once the batch is decided there is nothing left to strike from.
+func TestAnApprovedBatchOffersNoStrike(t *testing.T) {
+	batch := awaitingBatch("human fund", []string{"ada", "bo"}).Batch
+	batch.Status = payouts.StatusReady
+
+	html := renderAdmin(t, BatchItems(batch, []payouts.Payout{{ID: uuid.New(), BatchID: batch.ID, AmountCents: 2000, Status: payouts.StatusAwaitingApproval}}))
+
+	require.NotContains(t, html, "/admin/payout/strike/")
+}
+
+// Under the two-person rule the first approval leaves the batch waiting. The
+// buttons stay up for the second admin, and the page says how far along it is
+// and who has already approved.
+func TestABatchAwaitingASecondApprovalSaysWhoApproved(t *testing.T) {
+	batch := awaitingBatch("human fund", []string{"ada", "bo"}).Batch
+	batch.ApprovalsRequired = 2
+	batch.ApprovalsGiven = 1
+
+	actions := renderAdmin(t, BatchActions(batch))
+
+	require.Contains(t, actions, "/admin/payout/approve/"+batch.ID.String())
+	require.Contains(t, actions, "1 of 2 approvals")
+
+	approver := uuid.New()
+	approvals := renderAdmin(t, BatchApprovals(batch, []payouts.Approval{
+		{ID: uuid.New(), BatchID: batch.ID, ApproverID: approver, ApproverName: "treasurer", Created: time.Now()},
+	}))
+
+	require.Contains(t, approvals, "approvals (1 of 2)")
+	require.Contains(t, approvals, "treasurer")
+	require.Contains(t, approvals, `href="/admin/member/`+approver.String()+`"`)
+}
@@ -1,6 +1,7 @@
 package adminweb
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,6 +10,8 @@ import (
 	"boardfund/service/enrollments"
 	"boardfund/service/fundevents"
 	"boardfund/service/members"
+	"boardfund/service/notifications"
+	"boardfund/service/payouts"
 
 	"github.com/google/uuid"
 )
@@ -99,7 +102,23 @@ func (h *AdminHandlers) payoutPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	PayoutDetail(*batch, items, &member, "/admin/payouts").Render(ctx, w)
+	// Who was told is context, not the page. A failed read leaves that section
+	// off rather than the batch unreachable, which is the one place to approve it.
+	var deliveries []notifications.Delivery
+	if h.notificationService != nil {
+		deliveries, err = h.notificationService.DeliveriesForBatch(ctx, batchID)
+		if err != nil {
+			deliveries = nil
+		}
+	}
+
+	// Like the deliveries: the record beside the batch, not the batch itself.
+	approvals, err := h.payoutService.GetApprovalsForBatch(ctx, batchID)
+	if err != nil {
+		approvals = nil
+	}
+
+	PayoutDetail(*batch, items, approvals, deliveries, &member, "/admin/payouts").Render(ctx, w)
 }
 
 // approvePayout records the approval against the member in session. The service
@@ -124,6 +143,17 @@ func (h *AdminHandlers) approvePayout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	batch, err := h.payoutService.ApproveBatch(ctx, batchID, member.ID)
+	switch {
+	case errors.Is(err, payouts.ErrApproverEnrolled):
+		h.renderError(w, r, http.StatusConflict, "you are enrolled in this fund, so another admin has to approve its payouts.")
+
+		return
+	case errors.Is(err, payouts.ErrAlreadyApproved):
+		h.renderError(w, r, http.StatusConflict, "you have already approved this batch. it needs a second admin.")
+
+		return
+	}
+
 	if err != nil {
 		// Most likely the batch is no longer awaiting approval. Re-render its
 		// current state so the page tells the truth rather than showing an error.
@@ -164,6 +194,56 @@ func (h *AdminHandlers) rejectPayout(w http.ResponseWriter, r *http.Request) {
 	BatchActions(*batch).Render(ctx, w)
 }
 
+// strikePayout takes one payee out of a batch awaiting approval. The whole page
+// is reloaded on success rather than the row swapped out: the batch's total and
+// count change with it, and the approve button's confirmation quotes both.
+func (h *AdminHandlers) strikePayout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		http.Redirect(w, r, "/", http.StatusFound)
+
+		return
+	}
+
+	batchID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.badRequest(w, r, "that is not a valid batch id.")
+
+		return
+	}
+
+	payoutID, err := uuid.Parse(r.PathValue("payout"))
+	if err != nil {
+		h.badRequest(w, r, "that is not a valid payout id.")
+
+		return
+	}
+
+	_, err = h.payoutService.StrikePayout(ctx, batchID, payoutID, member.ID, r.FormValue("reason"))
+	switch {
+	case errors.Is(err, payouts.ErrLastPayout):
+		h.renderError(w, r, http.StatusConflict, "that is the only payout left in the batch. reject the batch instead.")
+
+		return
+	case errors.Is(err, payouts.ErrNotApprovable):
+		h.renderError(w, r, http.StatusConflict, "this batch is no longer awaiting approval, so it can no longer be changed.")
+
+		return
+	case errors.Is(err, payouts.ErrPayoutNotFound):
+		h.renderError(w, r, http.StatusConflict, "that payout is not in this batch, or has already been struck.")
+
+		return
+	case err != nil:
+		h.internalError(w, r)
+
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/admin/payout/"+batchID.String())
+}
+
 // renderBatchActions re-renders a batch's action block from current state. Used when
 // an action was refused, so the operator sees why rather than a dead button.
 func (h *AdminHandlers) renderBatchActions(w http.ResponseWriter, r *http.Request, batchID uuid.UUID) {
@@ -179,6 +259,58 @@ func (h *AdminHandlers) renderBatchActions(w http.ResponseWriter, r *http.Reques
 	BatchActions(*batch).Render(ctx, w)
 }
 
+// approveConfirmation says what an approval will do, which for half of a
+// two-person approval is not to clear anything yet.
+func approveConfirmation(batch payouts.Batch) string {
+	if batch.ApprovalsRequired > 1 && batch.ApprovalsGiven+1 < batch.ApprovalsRequired {
+		return fmt.Sprintf("Approve %s to %d payees? It needs a second admin's approval before it can be submitted.",
+			payoutAmount(batch.AmountCents), batch.NumEnrollments)
+	}
+
+	return fmt.Sprintf("Approve %s to %d payees? This clears it for submission.", payoutAmount(batch.AmountCents), batch.NumEnrollments)
+}
+
+// strikable reports whether a payout can still be taken out of its batch: the
+// batch must be one a treasurer could still approve, or there is nothing left
+// to change.
+func strikable(batch payouts.Batch, item payouts.Payout) bool {
+	return batch.Status == payouts.StatusAwaitingApproval &&
+		!batch.ApprovalExpired(time.Now()) &&
+		!item.Struck() &&
+		batch.NumEnrollments > 1
+}
+
+// struckLine says why a payout was taken out, and who took it. The id is shown
+// only for a member who has since gone and left no name to show.
+func struckLine(item payouts.Payout) string {
+	line := "struck: " + item.FailureReason
+	if item.StruckAt != nil {
+		line += " (" + item.StruckAt.Format("01-02-2006 15:04")
+		switch {
+		case item.StruckByName != "":
+			line += " by " + item.StruckByName
+		case item.StruckBy != nil:
+			line += " by " + item.StruckBy.String()
+		}
+
+		line += ")"
+	}
+
+	return line
+}
+
+// notificationLabel says what a message was about.
+func notificationLabel(kind notifications.Kind) string {
+	switch kind {
+	case notifications.KindApprovalRequired:
+		return "approval requested"
+	case notifications.KindApprovalExpiring:
+		return "expiry reminder"
+	default:
+		return strings.ReplaceAll(string(kind), "_", " ")
+	}
+}
+
 // eventLabel renders an event kind as something a treasurer reads rather than a
 // database enum.
 func eventLabel(kind fundevents.Kind) string {
@@ -187,8 +319,14 @@ func eventLabel(kind fundevents.Kind) string {
 		return "donation started"
 	case fundevents.KindDonationCancelled:
 		return "donation cancelled"
+	case fundevents.KindDonationAmountChanged:
+		return "donation amount changed"
 	case fundevents.KindPaymentReceived:
 		return "payment received"
+	case fundevents.KindPaymentDisputed:
+		return "payment disputed, money held"
+	case fundevents.KindPaymentDisputeResolved:
+		return "payment dispute resolved"
 	case fundevents.KindMemberEnrolled:
 		return "member enrolled"
 	case fundevents.KindEnrollmentCancelled:
@@ -197,6 +335,8 @@ func eventLabel(kind fundevents.Kind) string {
 		return "payout batch planned"
 	case fundevents.KindBatchApproved:
 		return "payout batch approved"
+	case fundevents.KindBatchFirstApproval:
+		return "payout batch approved once, awaiting a second"
 	case fundevents.KindBatchRejected:
 		return "payout batch rejected"
 	case fundevents.KindBatchExpired:
@@ -213,6 +353,8 @@ func eventLabel(kind fundevents.Kind) string {
 		return "fund details changed"
 	case fundevents.KindFundNoteRemoved:
 		return "note removed"
+	case fundevents.KindPayoutStruck:
+		return "payout struck from batch"
 	default:
 		// A kind added to the enum but not here still renders legibly rather
 		// than as a blank row.
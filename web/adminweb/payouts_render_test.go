@@ -102,7 +102,7 @@ func TestPayoutTemplatesRender(t *testing.T) {
 
 	for _, batch := range batches {
 		var detail strings.Builder
-		if err := PayoutDetail(batch.Batch, items, &member, "/admin/payouts").Render(ctx, &detail); err != nil {
+		if err := PayoutDetail(batch.Batch, items, nil, nil, &member, "/admin/payouts").Render(ctx, &detail); err != nil {
 			t.Fatalf("PayoutDetail render (status %s): %v", batch.Status, err)
 		}
 
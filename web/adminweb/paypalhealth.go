@@ -0,0 +1,46 @@
+package adminweb
+
+import (
+	"net/http"
+
+	"boardfund/paypal"
+	"boardfund/service/members"
+	"boardfund/web/common"
+)
+
+// paypalHealth is the PayPal client's own view of how PayPal has been
+// answering: the breaker, and per-endpoint counts of requests, retries and
+// failures since the process started.
+type paypalHealth interface {
+	Health() paypal.Health
+}
+
+// WatchPayPal puts the PayPal client's health on the admin pages.
+//
+// A setter, as the webhook handlers take Stripe's secret: the counters live in
+// the one client the server makes its calls through, and handlers built without
+// it say so rather than show a client that has done nothing.
+func (h *AdminHandlers) WatchPayPal(client paypalHealth) {
+	h.paypalHealth = client
+}
+
+func (h *AdminHandlers) paypalPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	// Nothing has been attached: there are no numbers, which is different from
+	// numbers that are all zero.
+	var health *paypal.Health
+	if h.paypalHealth != nil {
+		current := h.paypalHealth.Health()
+		health = &current
+	}
+
+	PayPalHealth(health, &member, r.URL.Path).Render(ctx, w)
+}
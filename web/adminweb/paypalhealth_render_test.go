@@ -0,0 +1,58 @@
+package adminweb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"boardfund/paypal"
+	"boardfund/service/members"
+
+	"github.com/google/uuid"
+)
+
+func renderPayPalHealth(t *testing.T, health *paypal.Health) string {
+	t.Helper()
+
+	var out strings.Builder
+	member := members.Member{ID: uuid.New(), BCOName: "michael"}
+
+	if err := PayPalHealth(health, &member, "/admin/paypal").Render(context.Background(), &out); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	return out.String()
+}
+
+// An open breaker and a failing endpoint are what the page is for, so they
+// stand out; a healthy client has nothing in red.
+func TestPayPalTroubleIsCalledOut(t *testing.T) {
+	failing := renderPayPalHealth(t, &paypal.Health{
+		Breaker: "open",
+		Endpoints: []paypal.EndpointStats{
+			{Endpoint: "GET /v1/payments/payouts/{id}", Requests: 4, Errors: 2, Retries: 6, TotalLatency: 2 * time.Second, MaxLatency: 900 * time.Millisecond},
+		},
+	})
+
+	for _, want := range []string{"GET /v1/payments/payouts/{id}", "500 ms", "900 ms", "text-red-600"} {
+		if !strings.Contains(failing, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+
+	healthy := renderPayPalHealth(t, &paypal.Health{
+		Breaker:   "closed",
+		Endpoints: []paypal.EndpointStats{{Endpoint: "POST /v2/checkout/orders", Requests: 3}},
+	})
+
+	if strings.Contains(healthy, "text-red-600") {
+		t.Error("nothing is wrong, so nothing should stand out")
+	}
+}
+
+func TestNoPayPalClientIsNotAnIdleOne(t *testing.T) {
+	if !strings.Contains(renderPayPalHealth(t, nil), "no paypal client") {
+		t.Error("a page with no client should say so")
+	}
+}
@@ -0,0 +1,170 @@
+package adminweb
+
+import (
+	"boardfund/jwtauth"
+	"boardfund/web/middlewares"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// msgForbidden is the refusal for a role that does not cover the route. It
+// does not name the role that would: whoever has that one can be asked.
+const msgForbidden = "your role does not allow that."
+
+var errNoToken = errors.New("no token on the request")
+
+// fundOf finds the fund a request acts on, for requireFund.
+type fundOf func(h *AdminHandlers, w http.ResponseWriter, r *http.Request) (uuid.UUID, bool)
+
+// require is withAdmin for a route that needs one permission. withAdmin lets in
+// any role at all; this is what stops a moderator approving payouts.
+//
+// It is the token's groups that are checked, not the session's member.Roles:
+// those are a copy made at login for the menus, and the token is what the
+// request is actually authorised by.
+//
+// A per-fund grant is refused here. A route that acts on a fund says which with
+// requireFund, and one that does not cannot be checked against an assignment.
+func (h *AdminHandlers) require(permission jwtauth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return h.withAdmin(func(w http.ResponseWriter, r *http.Request) {
+		grant := tokenGrant(r, permission)
+		if !grant.Allowed || grant.PerFund {
+			h.renderError(w, r, http.StatusForbidden, msgForbidden)
+
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// requireFund is require(jwtauth.ManageFunds) for a route about one fund. A
+// member whose grant is per fund -- a fund manager -- is let through only for a
+// fund they are assigned, which the token cannot say and so is looked up on
+// every request. An assignment removed takes effect at once, not at next login.
+func (h *AdminHandlers) requireFund(fund fundOf, next http.HandlerFunc) http.HandlerFunc {
+	return h.withAdmin(func(w http.ResponseWriter, r *http.Request) {
+		grant := tokenGrant(r, jwtauth.ManageFunds)
+		if !grant.Allowed {
+			h.renderError(w, r, http.StatusForbidden, msgForbidden)
+
+			return
+		}
+
+		if !grant.PerFund {
+			next(w, r)
+
+			return
+		}
+
+		fundID, ok := fund(h, w, r)
+		if !ok {
+			return
+		}
+
+		memberID, err := tokenMemberID(r)
+		if err != nil {
+			h.renderError(w, r, http.StatusForbidden, msgForbidden)
+
+			return
+		}
+
+		manages, err := h.authService.ManagesFund(r.Context(), memberID, fundID)
+		if err != nil {
+			h.internalError(w, r)
+
+			return
+		}
+
+		if !manages {
+			h.renderError(w, r, http.StatusForbidden, "you do not manage that fund.")
+
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// fundInPath is a fund route's own {id}.
+func fundInPath(h *AdminHandlers, w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	return parseFundID(h, w, r, r.PathValue("id"))
+}
+
+// fundInQuery is the enrollment confirmation's ?fund=.
+func fundInQuery(h *AdminHandlers, w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	return parseFundID(h, w, r, r.URL.Query().Get("fund"))
+}
+
+// fundInForm is the enrollment form's fund field. The body is capped here as
+// the handler caps it, because reading the field is what parses the body and
+// the handler's own cap would come too late to apply.
+func fundInForm(h *AdminHandlers, w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormBytes)
+
+	if err := r.ParseForm(); err != nil {
+		h.badRequest(w, r, "we could not read that form.")
+
+		return uuid.Nil, false
+	}
+
+	return parseFundID(h, w, r, r.FormValue("fund"))
+}
+
+// fundOfEnrollment is the fund of the enrollment in {id}.
+func fundOfEnrollment(h *AdminHandlers, w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.badRequest(w, r, "that is not an enrollment id.")
+
+		return uuid.Nil, false
+	}
+
+	enrollment, err := h.enrollmentService.GetEnrollmentByID(r.Context(), id)
+	if err != nil {
+		h.notFound(w, r)
+
+		return uuid.Nil, false
+	}
+
+	return enrollment.FundID, true
+}
+
+func parseFundID(h *AdminHandlers, w http.ResponseWriter, r *http.Request, value string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		h.badRequest(w, r, "that is not a fund id.")
+
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// tokenGrant is what the request's token allows of permission. No token is no
+// grant: withAdmin has already turned that request away, so this is only ever
+// the belt to its braces.
+func tokenGrant(r *http.Request, permission jwtauth.Permission) jwtauth.Grant {
+	token, ok := middlewares.TokenFromContext(r.Context())
+	if !ok {
+		return jwtauth.Grant{}
+	}
+
+	return jwtauth.Allows(token.PrivateClaims(), permission)
+}
+
+// tokenMemberID is the member the token was issued to, which is who an
+// assignment is checked for. Not the session's member: the session outlives the
+// token it was made with, and may have been made with somebody else's.
+func tokenMemberID(r *http.Request) (uuid.UUID, error) {
+	token, ok := middlewares.TokenFromContext(r.Context())
+	if !ok {
+		return uuid.Nil, errNoToken
+	}
+
+	memberID, _ := token.PrivateClaims()["custom:member_id"].(string)
+
+	return uuid.Parse(memberID)
+}
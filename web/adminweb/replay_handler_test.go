@@ -0,0 +1,117 @@
+package adminweb
+
+import (
+	"context"
+	"encoding/gob"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"boardfund/messaging"
+	"boardfund/service/adminevents"
+	"boardfund/service/members"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBus struct {
+	letters map[uint64]messaging.DeadLetter
+}
+
+func (f fakeBus) Status(context.Context) (messaging.Status, error) { return messaging.Status{}, nil }
+
+func (f fakeBus) ReplayDeadLetter(_ context.Context, seq uint64) (messaging.DeadLetter, error) {
+	letter, ok := f.letters[seq]
+	if !ok {
+		return messaging.DeadLetter{}, messaging.ErrDeadLetterNotFound
+	}
+
+	delete(f.letters, seq)
+
+	return letter, nil
+}
+
+type recordedEvents struct{ records []adminevents.Record }
+
+func (r *recordedEvents) InsertAdminEvent(_ context.Context, record adminevents.Record) (*adminevents.Event, error) {
+	r.records = append(r.records, record)
+
+	return &adminevents.Event{Kind: record.Kind}, nil
+}
+
+func (r *recordedEvents) GetAdminEvents(context.Context, int32) ([]adminevents.Event, error) {
+	return nil, nil
+}
+
+func replayRig(t *testing.T, actor members.Member) (func(seq string) *httptest.ResponseRecorder, *recordedEvents) {
+	t.Helper()
+
+	gob.Register(members.Member{})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	events := &recordedEvents{}
+	sessions := scs.New()
+
+	handlers := &AdminHandlers{
+		sessionManager: sessions,
+		logger:         logger,
+		adminEvents:    adminevents.NewService(events, logger),
+		webhookBus: fakeBus{letters: map[uint64]messaging.DeadLetter{
+			3: {Seq: 3, Subject: messaging.PaymentRefunded, Data: []byte(`{}`)},
+		}},
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("POST /admin/webhooks/replay/{seq}", func(w http.ResponseWriter, r *http.Request) {
+		sessions.Put(r.Context(), "member", actor)
+
+		handlers.replayDeadLetter(w, r)
+	})
+
+	return func(seq string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/admin/webhooks/replay/"+seq, nil)
+		request.Header.Set("HX-Request", "true")
+
+		recorder := httptest.NewRecorder()
+		sessions.LoadAndSave(router).ServeHTTP(recorder, request)
+
+		return recorder
+	}, events
+}
+
+// A replay re-runs what the event does to the money, so it is written down
+// under whoever asked for it -- and a second press finds nothing, rather than
+// replaying twice.
+func TestAReplayIsRecordedUnderTheAdminWhoAskedForIt(t *testing.T) {
+	actor := members.Member{ID: uuid.New(), BCOName: "treasurer"}
+	post, events := replayRig(t, actor)
+
+	recorder := post("3")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, strings.Contains(recorder.Body.String(), "replayed"), recorder.Body.String())
+
+	require.Len(t, events.records, 1)
+	record := events.records[0]
+	require.Equal(t, adminevents.KindWebhookReplayed, record.Kind)
+	require.Equal(t, actor.ID, *record.ActorMemberID)
+	require.Equal(t, messaging.PaymentRefunded+", dead letter 3", record.SubjectLabel)
+
+	recorder = post("3")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Len(t, events.records, 1, "a replay that did not happen is not recorded")
+}
+
+func TestReplayRefusesWhatIsNotASequence(t *testing.T) {
+	post, events := replayRig(t, members.Member{ID: uuid.New()})
+
+	for _, seq := range []string{"0", "-1", "abc"} {
+		require.Equal(t, http.StatusBadRequest, post(seq).Code, seq)
+	}
+
+	require.Empty(t, events.records)
+}
@@ -0,0 +1,121 @@
+package adminweb
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"boardfund/service/adminevents"
+	"boardfund/service/donations"
+	"boardfund/service/finance"
+	"boardfund/service/members"
+	"boardfund/web/common"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// unmatchedPage lists the money the import job found at a provider and could
+// not tie to a fund and a member. Until somebody assigns it, it is in the fund's
+// account and not in its balance.
+func (h *AdminHandlers) unmatchedPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	payments, err := h.financeService.GetUnmatchedPayments(ctx)
+	if err != nil {
+		h.internalError(w, r)
+
+		return
+	}
+
+	funds, err := h.donationService.ListAllFunds(ctx)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to list funds", slog.String("error", err.Error()))
+		h.internalError(w, r)
+
+		return
+	}
+
+	UnmatchedPayments(payments, funds, &member, r.URL.Path).Render(ctx, w)
+}
+
+// assignUnmatchedPayment records an unmatched payment as a donation by the
+// member the admin names, to the fund they choose.
+func (h *AdminHandlers) assignUnmatchedPayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actor, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.badRequest(w, r, "that is not a payment.")
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormBytes)
+
+	if err = r.ParseForm(); err != nil {
+		h.badRequest(w, r, msgBadRequest)
+
+		return
+	}
+
+	fundID, err := uuid.Parse(r.PostFormValue("fund"))
+	if err != nil {
+		h.badRequest(w, r, "choose the fund the payment was for.")
+
+		return
+	}
+
+	donorEmail := strings.TrimSpace(r.PostFormValue("email"))
+	if donorEmail == "" {
+		h.badRequest(w, r, "give the email of the member who paid.")
+
+		return
+	}
+
+	donation, payment, err := h.financeService.AssignUnmatchedPayment(ctx, id, fundID, donorEmail, actor.ID)
+
+	switch {
+	case errors.Is(err, finance.ErrNoSuchDonor):
+		h.badRequest(w, r, "no member has that email. they need an account before the payment can be theirs.")
+
+		return
+	case errors.Is(err, finance.ErrUnmatchedPaymentAssigned), errors.Is(err, donations.ErrPaymentAlreadyRecorded):
+		h.renderError(w, r, http.StatusConflict, "that payment is already recorded. reload to see where it went.")
+
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		h.renderError(w, r, http.StatusNotFound, msgNotFound)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to assign unmatched payment",
+			slog.String("unmatched_payment_id", id.String()),
+			slog.String("error", err.Error()),
+		)
+
+		h.internalError(w, r)
+
+		return
+	}
+
+	h.adminEvents.Record(ctx, adminevents.PaymentAssigned(actor.ID, donation.DonorID,
+		payment.ProviderPaymentID, payment.AmountCents, payment.FundName))
+
+	UnmatchedPaymentAssigned(*payment, donorEmail).Render(ctx, w)
+}
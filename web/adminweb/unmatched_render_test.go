@@ -0,0 +1,75 @@
+package adminweb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"boardfund/service/donations"
+	"boardfund/service/finance"
+	"boardfund/service/members"
+
+	"github.com/google/uuid"
+)
+
+// The form starts from what the import knew: the fund the order named, and the
+// payer's email, which is the likeliest member to credit.
+func TestAnUnmatchedPaymentStartsFromWhatTheOrderSaid(t *testing.T) {
+	rent := donations.Fund{ID: uuid.New(), Name: "rent"}
+	food := donations.Fund{ID: uuid.New(), Name: "food"}
+
+	payment := finance.UnmatchedPayment{
+		ID:                uuid.New(),
+		ProviderName:      "paypal",
+		ProviderPaymentID: "SALE-1",
+		AmountCents:       2500,
+		PayerEmail:        "donor@example.com",
+		PayerName:         "Ada Lovelace",
+		FundID:            &food.ID,
+		Reason:            "the payer is not a member",
+		OccurredAt:        time.Date(2026, time.August, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	var out strings.Builder
+	member := members.Member{ID: uuid.New(), BCOName: "michael"}
+
+	err := UnmatchedPayments([]finance.UnmatchedPayment{payment}, []donations.Fund{rent, food}, &member, "/admin/unmatched").
+		Render(context.Background(), &out)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	page := out.String()
+
+	for _, want := range []string{
+		"$25.00",
+		"SALE-1",
+		"the payer is not a member",
+		"Ada Lovelace &lt;donor@example.com&gt;",
+		`value="donor@example.com"`,
+		`hx-post="/admin/unmatched/assign/` + payment.ID.String() + `"`,
+		`<option value="` + food.ID.String() + `" selected>food</option>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+
+	if strings.Contains(page, `<option value="`+rent.ID.String()+`" selected>`) {
+		t.Error("only the fund the order named should be chosen")
+	}
+}
+
+func TestNothingUnmatchedSaysSo(t *testing.T) {
+	var out strings.Builder
+	member := members.Member{ID: uuid.New(), BCOName: "michael"}
+
+	if err := UnmatchedPayments(nil, nil, &member, "/admin/unmatched").Render(context.Background(), &out); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "accounted for") {
+		t.Error("an empty page should say there is nothing to do")
+	}
+}
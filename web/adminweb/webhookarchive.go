@@ -0,0 +1,143 @@
+package adminweb
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"boardfund/service/members"
+	"boardfund/web/common"
+	hooksstore "boardfund/web/hooksweb/store"
+)
+
+// archiveLimit bounds one page of the archive. A search that hits it says so,
+// and the answer is a narrower search rather than a longer page: nobody reads a
+// thousand webhooks looking for one.
+const archiveLimit = 100
+
+// archiveDate is how the filter form sends a day.
+const archiveDate = "2006-01-02"
+
+// archiveSearch is the filter form as submitted, kept as typed so the page can
+// put it back in the form.
+type archiveSearch struct {
+	EventType  string
+	ResourceID string
+	From       string
+	Until      string
+}
+
+// filter reads the form into a search of the archive. Until names a day, and
+// the day is included, so it becomes midnight at the end of it.
+func (s archiveSearch) filter() (hooksstore.EventFilter, error) {
+	filter := hooksstore.EventFilter{
+		EventType:  s.EventType,
+		ResourceID: s.ResourceID,
+		Limit:      archiveLimit,
+	}
+
+	if s.From != "" {
+		from, err := time.Parse(archiveDate, s.From)
+		if err != nil {
+			return hooksstore.EventFilter{}, err
+		}
+
+		filter.From = from
+	}
+
+	if s.Until != "" {
+		until, err := time.Parse(archiveDate, s.Until)
+		if err != nil {
+			return hooksstore.EventFilter{}, err
+		}
+
+		filter.Until = until.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
+
+func readArchiveSearch(query url.Values) archiveSearch {
+	return archiveSearch{
+		EventType:  strings.TrimSpace(query.Get("type")),
+		ResourceID: strings.TrimSpace(query.Get("resource")),
+		From:       strings.TrimSpace(query.Get("from")),
+		Until:      strings.TrimSpace(query.Get("until")),
+	}
+}
+
+// webhookEventsPage searches the webhook archive.
+//
+// The stream keeps a week, and the question that needs the archive is usually
+// about something older: a donor disputing a charge, an accountant asking what
+// a payment in March actually was. They arrive with a payment or subscription
+// id, or a date, so those are what it filters on.
+func (h *AdminHandlers) webhookEventsPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	search := readArchiveSearch(r.URL.Query())
+
+	filter, err := search.filter()
+	if err != nil {
+		h.badRequest(w, r, "dates are year-month-day, like 2026-03-01.")
+
+		return
+	}
+
+	types, err := h.webhookArchive.GetEventTypes(ctx)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to read archived webhook types", slog.String("error", err.Error()))
+		h.internalError(w, r)
+
+		return
+	}
+
+	events, err := h.webhookArchive.SearchEvents(ctx, filter)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to search the webhook archive", slog.String("error", err.Error()))
+		h.internalError(w, r)
+
+		return
+	}
+
+	WebhookEvents(events, types, search, &member, r.URL.Path).Render(ctx, w)
+}
+
+// webhookEventPage is one archived webhook: what PayPal sent, how its signature
+// checked out, and every attempt the consumers made at it.
+func (h *AdminHandlers) webhookEventPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/")
+
+		return
+	}
+
+	event, err := h.webhookArchive.GetEvent(ctx, r.PathValue("transmission"))
+	if errors.Is(err, hooksstore.ErrEventNotFound) {
+		h.notFound(w, r)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to read archived webhook", slog.String("error", err.Error()))
+		h.internalError(w, r)
+
+		return
+	}
+
+	WebhookEvent(*event, &member, r.URL.Path).Render(ctx, w)
+}
@@ -0,0 +1,101 @@
+package adminweb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"boardfund/service/members"
+	hooksstore "boardfund/web/hooksweb/store"
+
+	"github.com/google/uuid"
+)
+
+// An event that was handled in the end, after failing, is still worth noticing
+// from the list. So is one nothing ever handled.
+func TestTheArchiveListCallsOutFailures(t *testing.T) {
+	var out strings.Builder
+	member := members.Member{ID: uuid.New(), BCOName: "michael"}
+
+	events := []hooksstore.ArchivedEvent{
+		{TransmissionID: "tx-clean", EventType: "PAYMENT.SALE.COMPLETED", ResourceID: "SALE-1", Handled: 1, ReceivedAt: time.Now()},
+		{TransmissionID: "tx-bumpy", EventType: "PAYMENT.SALE.COMPLETED", ResourceID: "SALE-2", Handled: 1, Failed: 2, ReceivedAt: time.Now()},
+		{TransmissionID: "tx/odd", EventType: "PAYMENT.SALE.REFUNDED", ResourceID: "SALE-3", Failed: 5, ReceivedAt: time.Now()},
+	}
+
+	search := archiveSearch{EventType: "PAYMENT.SALE.REFUNDED", ResourceID: "SALE-3"}
+
+	err := WebhookEvents(events, []string{"PAYMENT.SALE.COMPLETED", "PAYMENT.SALE.REFUNDED"}, search, &member, "/admin/webhooks/events").
+		Render(context.Background(), &out)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	html := out.String()
+
+	for _, want := range []string{"handled after 2 failed", "failed 5", `value="SALE-3"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+
+	if !strings.Contains(html, `<option value="PAYMENT.SALE.REFUNDED" selected`) {
+		t.Error("the type searched for should stay selected")
+	}
+
+	// A transmission id is PayPal's, and nothing promises it is path-safe.
+	if !strings.Contains(html, `href="/admin/webhooks/event/tx%2Fodd"`) {
+		t.Error("the link to an event should escape its id")
+	}
+}
+
+func TestAnArchivedEventShowsItsPayloadAndEveryAttempt(t *testing.T) {
+	var out strings.Builder
+	member := members.Member{ID: uuid.New(), BCOName: "michael"}
+
+	event := hooksstore.ArchivedEventDetail{
+		TransmissionID: "tx-1",
+		EventType:      "PAYMENT.SALE.COMPLETED",
+		ResourceID:     "SALE-1",
+		Payload:        []byte(`{"id":"WH-1","resource":{"id":"SALE-1"}}`),
+		ChainError:     "x509: certificate signed by unknown authority",
+		Outcomes: []hooksstore.EventOutcome{
+			{Consumer: "PAYMENT.SALE.COMPLETED", StreamSeq: 7, Delivery: 1, Outcome: hooksstore.OutcomeFailed, Error: "connection refused"},
+			{Consumer: "PAYMENT.SALE.COMPLETED", StreamSeq: 7, Delivery: 2, Outcome: hooksstore.OutcomeHandled},
+		},
+	}
+
+	if err := WebhookEvent(event, &member, "/admin/webhooks/event/tx-1").Render(context.Background(), &out); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	html := out.String()
+
+	if !strings.Contains(html, "&#34;resource&#34;: {") {
+		t.Error("the payload should be pretty-printed")
+	}
+
+	for _, want := range []string{"connection refused", "failed, retried", "#2 of message 7", "did not chain"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
+
+// Until names a day, and means the whole of it: an admin asking for the first
+// of March wants what arrived that afternoon.
+func TestTheArchiveSearchIncludesItsLastDay(t *testing.T) {
+	filter, err := archiveSearch{From: "2026-03-01", Until: "2026-03-01"}.filter()
+	if err != nil {
+		t.Fatalf("filter: %v", err)
+	}
+
+	if got := filter.Until.Sub(filter.From); got != 24*time.Hour {
+		t.Errorf("a one-day search spans %s", got)
+	}
+
+	if _, err = (archiveSearch{From: "03/01/2026"}).filter(); err == nil {
+		t.Error("a date in another format should be refused rather than ignored")
+	}
+}
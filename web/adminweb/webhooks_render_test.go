@@ -65,21 +65,48 @@ func TestNoConsumersSaysSo(t *testing.T) {
 	}
 }
 
-// The exhausted list lives in memory. An empty table after a restart would read
-// as an all clear when it is really an absence of evidence.
-func TestExhaustedListAdmitsItIsCleared(t *testing.T) {
+// A dead letter is only worth listing with what it held: the sequence is how
+// it is replayed, and the payload is how anybody works out why it failed.
+func TestDeadLettersShowTheirPayloadAndAReplayButton(t *testing.T) {
 	html := renderWebhooks(t, messaging.Status{
-		Exhausted: []messaging.Exhausted{
-			{Consumer: "PAYMENT_SALE_COMPLETED", StreamSeq: 42, Deliveries: 5, At: time.Now()},
-		},
+		DeadLetters: []messaging.DeadLetter{{
+			Seq: 42, Subject: "PAYMENT.SALE.COMPLETED", Consumer: "PAYMENT_SALE_COMPLETED",
+			StreamSeq: 7, Deliveries: 5, At: time.Now(), Received: time.Now(),
+			Data: []byte(`{"id":"SALE-1","amount":{"total":"5.00"}}`),
+		}},
+		DeadLetterCount: 1,
 	})
 
-	if !strings.Contains(html, "42") {
-		t.Error("the stream sequence is how the message is found, so it has to be shown")
+	if !strings.Contains(html, `hx-post="/admin/webhooks/replay/42"`) {
+		t.Error("each letter should offer a replay by its dead-letter sequence")
+	}
+
+	// Indented, so a nested amount is readable rather than one long line.
+	if !strings.Contains(html, "&#34;amount&#34;: {") {
+		t.Errorf("the payload should be shown, indented: %s", html)
 	}
 
-	if !strings.Contains(html, "cleared when the service restarts") {
-		t.Error("the page should say the list does not survive a restart")
+	if strings.Contains(html, "cleared when the service restarts") {
+		t.Error("dead letters are on disk now; the page should not say otherwise")
+	}
+}
+
+// Fifty are read. Past that, the page has to say there are more, or a full list
+// reads as the whole story.
+func TestATruncatedDeadLetterListSaysSo(t *testing.T) {
+	html := renderWebhooks(t, messaging.Status{
+		DeadLetters:     []messaging.DeadLetter{{Seq: 1, Subject: "PAYMENT.SALE.COMPLETED"}},
+		DeadLetterCount: 80,
+	})
+
+	if !strings.Contains(html, "showing the newest 1 of 80") {
+		t.Error("a truncated list should say how many there are in all")
+	}
+}
+
+func TestPrettyPayloadKeepsWhatIsNotJSON(t *testing.T) {
+	if got := prettyPayload([]byte("not json")); got != "not json" {
+		t.Errorf("prettyPayload = %q, want the raw bytes", got)
 	}
 }
 
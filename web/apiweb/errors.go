@@ -0,0 +1,141 @@
+package apiweb
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"boardfund/service/donations"
+	"boardfund/service/payouts"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Error codes a client can switch on. The message is for a person; the code is
+// for the script, and stays the same when the wording of a message changes.
+const (
+	codeUnauthorized = "unauthorized"
+	codeForbidden    = "forbidden"
+	codeSecondFactor = "second_factor_required"
+	codeNotFound     = "not_found"
+	codeConflict     = "conflict"
+	codeInvalid      = "invalid"
+	codeInternal     = "internal"
+)
+
+const msgInternal = "something went wrong. try again, and check the logs if it persists."
+
+// errorBody is the one shape every failure takes, so a client reads an error
+// the same way whichever route produced it.
+type errorBody struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// refusal is how one service sentinel is answered.
+type refusal struct {
+	err    error
+	status int
+	code   string
+}
+
+// refusals maps the service sentinels to a status and a code.
+//
+// A table rather than a switch in each handler, so a sentinel answers the same
+// way on every route -- "fund is closed" is a 409 whether it came from reading
+// a fund or, later, from changing one. The sentinels' own messages are written
+// to be shown, so they are what the client is told; the wrapping around them
+// is not, because it can carry anything the layers below it said.
+var refusals = []refusal{
+	{pgx.ErrNoRows, http.StatusNotFound, codeNotFound},
+	// Not-found rather than forbidden, as on the pages: a member has no business
+	// learning whether somebody else's donation exists.
+	{donations.ErrDonationNotYours, http.StatusNotFound, codeNotFound},
+	{payouts.ErrFundNotFound, http.StatusNotFound, codeNotFound},
+	{payouts.ErrEnrollmentNotFound, http.StatusNotFound, codeNotFound},
+	{payouts.ErrPayoutNotFound, http.StatusNotFound, codeNotFound},
+
+	{donations.ErrFundClosed, http.StatusConflict, codeConflict},
+	{donations.ErrDonationNotCancellable, http.StatusConflict, codeConflict},
+	{payouts.ErrFundInactive, http.StatusConflict, codeConflict},
+	{payouts.ErrNotApprovable, http.StatusConflict, codeConflict},
+	{payouts.ErrNotSubmittable, http.StatusConflict, codeConflict},
+	{payouts.ErrAlreadyApproved, http.StatusConflict, codeConflict},
+	{payouts.ErrLastPayout, http.StatusConflict, codeConflict},
+
+	{payouts.ErrApproverEnrolled, http.StatusForbidden, codeForbidden},
+
+	{donations.ErrInvalidInterval, http.StatusBadRequest, codeInvalid},
+	{donations.ErrUnknownFrequency, http.StatusBadRequest, codeInvalid},
+	{donations.ErrOneTimeFundNeedsEndDate, http.StatusBadRequest, codeInvalid},
+	{donations.ErrCloseOnGoalNeedsGoal, http.StatusBadRequest, codeInvalid},
+	{payouts.ErrInvalidAllocation, http.StatusBadRequest, codeInvalid},
+	{payouts.ErrInvalidThreshold, http.StatusBadRequest, codeInvalid},
+}
+
+// errorFor answers a service error. Anything not in the table is a 500 with a
+// message that says nothing about why, since the why is in the logs and may be
+// a query or a provider response nobody outside should read.
+func errorFor(err error) (int, apiError) {
+	for _, known := range refusals {
+		if !errors.Is(err, known.err) {
+			continue
+		}
+
+		message := known.err.Error()
+		if known.code == codeNotFound {
+			// pgx says "no rows in result set", which is true and useless.
+			message = "not found"
+		}
+
+		return known.status, apiError{Code: known.code, Message: message}
+	}
+
+	return http.StatusInternalServerError, apiError{Code: codeInternal, Message: msgInternal}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorBody{Error: apiError{Code: code, Message: message}})
+}
+
+// serviceError answers an error from a service call, logging the ones that are
+// our fault. A 404 for an id somebody guessed is not worth a line.
+func (h *APIHandlers) serviceError(w http.ResponseWriter, r *http.Request, err error) {
+	status, body := errorFor(err)
+
+	if status >= http.StatusInternalServerError {
+		h.logger.ErrorContext(r.Context(), "api request failed",
+			slog.String("path", r.URL.Path),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	writeJSON(w, status, errorBody{Error: body})
+}
+
+// Unauthorized is the API's refusal for a missing or invalid token, for
+// middlewares.VerifyOr. The pages redirect to the login form; a script wants a
+// status it can act on.
+func Unauthorized(w http.ResponseWriter, _ *http.Request) {
+	writeError(w, http.StatusUnauthorized, codeUnauthorized, "a valid bearer token is required")
+}
+
+// SecondFactorRequired is the API's refusal for a role that must give a second
+// factor and has not, for middlewares.RequireTwoFactorOr. A code of its own,
+// because what to do about it is not what to do about the wrong role: log in on
+// the site with two-factor, and send the token with that session.
+func SecondFactorRequired(w http.ResponseWriter, _ *http.Request) {
+	writeError(w, http.StatusForbidden, codeSecondFactor, "give a second factor on the site before using this route")
+}
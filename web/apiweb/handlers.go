@@ -0,0 +1,362 @@
+// Package apiweb is the versioned JSON API, for scripts and the mobile view.
+//
+// It reads the same services the pages do and answers in JSON. Everything is
+// behind a bearer token; the batch and payout routes are for admins and
+// treasurers only.
+package apiweb
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"boardfund/jwtauth"
+	"boardfund/service/donations"
+	"boardfund/service/fundevents"
+	"boardfund/service/members"
+	"boardfund/service/payouts"
+	"boardfund/web/middlewares"
+	"boardfund/web/mux"
+
+	"github.com/google/uuid"
+)
+
+// Prefix is where the API lives. Versioned in the path so a v2 can sit beside
+// it while the scripts written against v1 keep working.
+const Prefix = "/api/v1"
+
+// maxTimelineLimit bounds ?limit on the timeline. The page shows
+// fundevents.DefaultLimit, and a script asking for a million should get a lot
+// rather than a query that reads the whole table.
+const maxTimelineLimit = 500
+
+// The services are narrowed to what the API reads. Like homeweb's publicEvents,
+// the point is what does not compile: nothing here can reach the full event
+// feed, which names the donor behind every payment.
+
+type fundReader interface {
+	ListActiveFunds(ctx context.Context) ([]donations.Fund, error)
+	GetFundByID(ctx context.Context, id uuid.UUID) (*donations.Fund, error)
+	ListDonationsForMember(ctx context.Context, memberID uuid.UUID) ([]donations.MemberDonation, error)
+}
+
+type publicEvents interface {
+	GetPublicFundEvents(ctx context.Context, fundID uuid.UUID, limit int32) ([]fundevents.PublicEvent, error)
+}
+
+type batchReader interface {
+	GetBatchesForFund(ctx context.Context, fundID uuid.UUID) ([]payouts.Batch, error)
+	GetBatchesAwaitingApproval(ctx context.Context) ([]payouts.Batch, error)
+	GetBatchByID(ctx context.Context, id uuid.UUID) (*payouts.Batch, error)
+	GetPayoutsForBatch(ctx context.Context, batchID uuid.UUID) ([]payouts.Payout, error)
+}
+
+type memberLookup interface {
+	GetMemberByUsername(ctx context.Context, username string) (*members.Member, error)
+}
+
+type APIHandlers struct {
+	withToken func(http.HandlerFunc) http.HandlerFunc
+
+	// withSecondFactor is the check the admin pages make, for the admin routes.
+	withSecondFactor func(http.HandlerFunc) http.HandlerFunc
+
+	funds      fundReader
+	fundEvents publicEvents
+	batches    batchReader
+	members    memberLookup
+	logger     *slog.Logger
+}
+
+// NewAPIHandlers takes the token check as a middleware, like the page
+// handlers. It should be middlewares.VerifyOr with Unauthorized, so a refusal
+// is a JSON 401 rather than a redirect to the login page. withSecondFactor is
+// middlewares.RequireTwoFactorOr with SecondFactorRequired, for the same reason.
+func NewAPIHandlers(
+	withToken func(http.HandlerFunc) http.HandlerFunc,
+	withSecondFactor func(http.HandlerFunc) http.HandlerFunc,
+	funds fundReader,
+	fundEvents publicEvents,
+	batches batchReader,
+	members memberLookup,
+	logger *slog.Logger,
+) *APIHandlers {
+	return &APIHandlers{
+		withToken:        withToken,
+		withSecondFactor: withSecondFactor,
+		funds:            funds,
+		fundEvents:       fundEvents,
+		batches:          batches,
+		members:          members,
+		logger:           logger,
+	}
+}
+
+// Register adds the routes. Each one is also described in operations, in
+// openapi.go; the contract test fails when the two disagree.
+func (h *APIHandlers) Register(r *mux.Router) {
+	// Unauthenticated. It describes the API, and a client generator has to be
+	// able to read it before it has a token to send.
+	r.HandleFunc("GET "+SpecPath, h.openAPI)
+
+	r.HandleFunc("GET "+Prefix+"/me", h.withMember(h.me))
+	r.HandleFunc("GET "+Prefix+"/me/donations", h.withMember(h.myDonations))
+	r.HandleFunc("GET "+Prefix+"/funds", h.withMember(h.listFunds))
+	r.HandleFunc("GET "+Prefix+"/funds/{id}", h.withMember(h.getFund))
+	r.HandleFunc("GET "+Prefix+"/funds/{id}/timeline", h.withMember(h.fundTimeline))
+	r.HandleFunc("GET "+Prefix+"/funds/{id}/batches", h.withAdmin(h.fundBatches))
+	r.HandleFunc("GET "+Prefix+"/batches/awaiting-approval", h.withAdmin(h.batchesAwaitingApproval))
+	r.HandleFunc("GET "+Prefix+"/batches/{id}", h.withAdmin(h.getBatch))
+	r.HandleFunc("GET "+Prefix+"/batches/{id}/payouts", h.withAdmin(h.batchPayouts))
+}
+
+// caller is who a request is from, resolved from its token.
+type caller struct {
+	member members.Member
+	admin  bool
+
+	// payouts is whether the token's roles may see payout batches: admins and
+	// treasurers, who approve them.
+	payouts bool
+}
+
+type callerHandler func(w http.ResponseWriter, r *http.Request, who caller)
+
+// withMember verifies the token and finds the member it was issued to.
+//
+// The pages take the member from the session, which the API has none of; the
+// username on the token is the member's bco name, the same lookup login does.
+// A valid token for somebody with no member row is refused rather than served
+// as an anonymous caller, because every route here is about a member.
+func (h *APIHandlers) withMember(next callerHandler) http.HandlerFunc {
+	return h.withToken(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := middlewares.TokenFromContext(r.Context())
+		if !ok {
+			Unauthorized(w, r)
+
+			return
+		}
+
+		claims := token.PrivateClaims()
+
+		username := jwtauth.Username(claims)
+		if username == "" {
+			Unauthorized(w, r)
+
+			return
+		}
+
+		member, err := h.members.GetMemberByUsername(r.Context(), username)
+		if err != nil || member == nil || !member.Active {
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "this token does not belong to an active member")
+
+			return
+		}
+
+		next(w, r, caller{
+			member:  *member,
+			admin:   jwtauth.HasGroup(claims, jwtauth.AdminGroup),
+			payouts: jwtauth.Allows(claims, jwtauth.ApprovePayouts).Allowed,
+		})
+	})
+}
+
+// withAdmin is withMember for the routes that show where money went and to
+// whom, which are for whoever approves payouts. A member who may not gets a
+// 403, not the 401 a bad token gets: the token is fine, and asking for a new
+// one will not help.
+//
+// They are what the admin pages show, so they ask what those pages ask: a
+// second factor from the roles that must give one.
+func (h *APIHandlers) withAdmin(next callerHandler) http.HandlerFunc {
+	return h.withMember(func(w http.ResponseWriter, r *http.Request, who caller) {
+		if !who.payouts {
+			writeError(w, http.StatusForbidden, codeForbidden, "admins and treasurers only")
+
+			return
+		}
+
+		h.withSecondFactor(func(w http.ResponseWriter, r *http.Request) {
+			next(w, r, who)
+		})(w, r)
+	})
+}
+
+// pathID reads {id}, answering a 400 itself when it is not one.
+func pathID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalid, "that is not an id")
+
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+func (h *APIHandlers) me(w http.ResponseWriter, _ *http.Request, who caller) {
+	writeJSON(w, http.StatusOK, envelope{Data: toMember(who.member, who.admin)})
+}
+
+func (h *APIHandlers) myDonations(w http.ResponseWriter, r *http.Request, who caller) {
+	mine, err := h.funds.ListDonationsForMember(r.Context(), who.member.ID)
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, envelope{Data: mapSlice(mine, toDonation)})
+}
+
+// listFunds is the funds the home page lists: open, and taking donations.
+func (h *APIHandlers) listFunds(w http.ResponseWriter, r *http.Request, _ caller) {
+	funds, err := h.funds.ListActiveFunds(r.Context())
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	now := time.Now()
+
+	writeJSON(w, http.StatusOK, envelope{Data: mapSlice(funds, func(fund donations.Fund) fundJSON {
+		return toFund(fund, now)
+	})})
+}
+
+// getFund answers for closed funds as well. The archive page shows them to
+// every member, so there is nothing here to hide.
+func (h *APIHandlers) getFund(w http.ResponseWriter, r *http.Request, _ caller) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	fund, err := h.funds.GetFundByID(r.Context(), id)
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, envelope{Data: toFund(*fund, time.Now())})
+}
+
+// fundTimeline is the public projection of the fund's history, newest first,
+// the same events the fund pages show.
+func (h *APIHandlers) fundTimeline(w http.ResponseWriter, r *http.Request, _ caller) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := int32(fundevents.DefaultLimit)
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, codeInvalid, "limit must be a positive number")
+
+			return
+		}
+
+		limit = int32(min(parsed, maxTimelineLimit))
+	}
+
+	// Read first, so a fund that does not exist is a 404 rather than an empty
+	// history that looks like a quiet one.
+	if _, err := h.funds.GetFundByID(r.Context(), id); err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	events, err := h.fundEvents.GetPublicFundEvents(r.Context(), id, limit)
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, envelope{Data: mapSlice(events, toEvent)})
+}
+
+func (h *APIHandlers) fundBatches(w http.ResponseWriter, r *http.Request, _ caller) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	batches, err := h.batches.GetBatchesForFund(r.Context(), id)
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, envelope{Data: mapSlice(batches, toBatch)})
+}
+
+func (h *APIHandlers) batchesAwaitingApproval(w http.ResponseWriter, r *http.Request, _ caller) {
+	batches, err := h.batches.GetBatchesAwaitingApproval(r.Context())
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, envelope{Data: mapSlice(batches, toBatch)})
+}
+
+// getBatch is the batch with its payouts, which is what anybody asking about
+// one batch goes on to ask.
+func (h *APIHandlers) getBatch(w http.ResponseWriter, r *http.Request, _ caller) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	batch, err := h.batches.GetBatchByID(r.Context(), id)
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	items, err := h.batches.GetPayoutsForBatch(r.Context(), id)
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	out := toBatch(*batch)
+	out.Items = mapSlice(items, toPayout)
+
+	writeJSON(w, http.StatusOK, envelope{Data: out})
+}
+
+func (h *APIHandlers) batchPayouts(w http.ResponseWriter, r *http.Request, _ caller) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// As with the timeline: an unknown batch is a 404, not an empty list.
+	if _, err := h.batches.GetBatchByID(r.Context(), id); err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	items, err := h.batches.GetPayoutsForBatch(r.Context(), id)
+	if err != nil {
+		h.serviceError(w, r, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, envelope{Data: mapSlice(items, toPayout)})
+}
@@ -0,0 +1,335 @@
+package apiweb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boardfund/jwtauth"
+	"boardfund/service/donations"
+	"boardfund/service/fundevents"
+	"boardfund/service/members"
+	"boardfund/service/payouts"
+	"boardfund/web/middlewares"
+	"boardfund/web/mux"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFunds struct {
+	funds     []donations.Fund
+	donations map[uuid.UUID][]donations.MemberDonation
+}
+
+func (f fakeFunds) ListActiveFunds(context.Context) ([]donations.Fund, error) { return f.funds, nil }
+
+func (f fakeFunds) GetFundByID(_ context.Context, id uuid.UUID) (*donations.Fund, error) {
+	for _, fund := range f.funds {
+		if fund.ID == id {
+			return &fund, nil
+		}
+	}
+
+	return nil, fmt.Errorf("get fund: %w", pgx.ErrNoRows)
+}
+
+func (f fakeFunds) ListDonationsForMember(_ context.Context, memberID uuid.UUID) ([]donations.MemberDonation, error) {
+	return f.donations[memberID], nil
+}
+
+type fakeEvents struct{}
+
+func (fakeEvents) GetPublicFundEvents(context.Context, uuid.UUID, int32) ([]fundevents.PublicEvent, error) {
+	return []fundevents.PublicEvent{{Kind: fundevents.KindFundCreated, Automatic: true}}, nil
+}
+
+type fakeBatches struct{ batch payouts.Batch }
+
+func (f fakeBatches) GetBatchesForFund(context.Context, uuid.UUID) ([]payouts.Batch, error) {
+	return []payouts.Batch{f.batch}, nil
+}
+
+func (f fakeBatches) GetBatchesAwaitingApproval(context.Context) ([]payouts.Batch, error) {
+	return nil, nil
+}
+
+func (f fakeBatches) GetBatchByID(_ context.Context, id uuid.UUID) (*payouts.Batch, error) {
+	if id != f.batch.ID {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &f.batch, nil
+}
+
+func (f fakeBatches) GetPayoutsForBatch(context.Context, uuid.UUID) ([]payouts.Payout, error) {
+	return []payouts.Payout{{ID: uuid.New(), BatchID: f.batch.ID, AmountCents: 500}}, nil
+}
+
+type fakeMembers map[string]members.Member
+
+func (f fakeMembers) GetMemberByUsername(_ context.Context, username string) (*members.Member, error) {
+	member, ok := f[username]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return &member, nil
+}
+
+// tokens stands in for the JWKS check: the bearer value is the username and
+// the member id, and "admin" and "treasurer" carry their groups.
+func tokens(tokenStr string) (jwt.Token, error) {
+	if tokenStr == "bad" {
+		return nil, errors.New("failed to parse token")
+	}
+
+	builder := jwt.NewBuilder().
+		Claim(jwtauth.UsernameClaim, tokenStr).
+		Claim("custom:member_id", tokenStr)
+
+	switch tokenStr {
+	case "admin":
+		builder = builder.Claim(jwtauth.GroupsClaim, []any{jwtauth.AdminGroup})
+	case "treasurer":
+		builder = builder.Claim(jwtauth.GroupsClaim, []any{jwtauth.TreasurerGroup})
+	}
+
+	return builder.Build()
+}
+
+type apiFixture struct {
+	handler http.Handler
+	fund    donations.Fund
+	batch   payouts.Batch
+	donor   members.Member
+
+	// sessions holds the session cookie each bearer is sent with. The admin's
+	// has had a second factor; the treasurer has none.
+	sessions map[string]*http.Cookie
+}
+
+func newFixture(t *testing.T) apiFixture {
+	t.Helper()
+
+	fixture := apiFixture{
+		fund:  donations.Fund{ID: uuid.New(), Name: "human fund", Active: true, GoalCents: 10000, Stats: donations.FundStats{TotalDonated: 5000}},
+		batch: payouts.Batch{ID: uuid.New(), FundID: uuid.New(), AmountCents: 500, Status: payouts.StatusAwaitingApproval},
+		donor: members.Member{ID: uuid.New(), BCOName: "donor", Active: true},
+	}
+
+	admin := members.Member{ID: uuid.New(), BCOName: "admin", Active: true}
+	treasurer := members.Member{ID: uuid.New(), BCOName: "treasurer", Active: true}
+	gone := members.Member{ID: uuid.New(), BCOName: "gone", Active: false}
+
+	sessions := scs.New()
+
+	ctx, err := sessions.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	sessions.Put(ctx, middlewares.TwoFactorSessionKey, "admin")
+
+	verified, _, err := sessions.Commit(ctx)
+	require.NoError(t, err)
+
+	fixture.sessions = map[string]*http.Cookie{"admin": {Name: sessions.Cookie.Name, Value: verified}}
+
+	h := NewAPIHandlers(
+		middlewares.VerifyOr(Unauthorized, tokens, middlewares.TokenFromHeader),
+		middlewares.RequireTwoFactorOr(SecondFactorRequired, sessions, []string{jwtauth.AdminGroup, jwtauth.TreasurerGroup}),
+		fakeFunds{
+			funds: []donations.Fund{fixture.fund},
+			donations: map[uuid.UUID][]donations.MemberDonation{
+				fixture.donor.ID: {{ID: uuid.New(), FundID: fixture.fund.ID, FundName: "human fund", TotalGivenCents: 2500}},
+			},
+		},
+		fakeEvents{},
+		fakeBatches{batch: fixture.batch},
+		fakeMembers{"donor": fixture.donor, "admin": admin, "treasurer": treasurer, "gone": gone},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+
+	router := mux.NewRouter(http.NewServeMux())
+	h.Register(router)
+
+	fixture.handler = sessions.LoadAndSave(router)
+
+	return fixture
+}
+
+func (f apiFixture) get(t *testing.T, path, bearer string) (int, map[string]any) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	if cookie, ok := f.sessions[bearer]; ok {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, req)
+
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"), "every answer is JSON, refusals included")
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+	return rec.Code, body
+}
+
+func errorCode(t *testing.T, body map[string]any) string {
+	t.Helper()
+
+	apiErr, ok := body["error"].(map[string]any)
+	require.True(t, ok, "no error object in %v", body)
+
+	return apiErr["code"].(string)
+}
+
+// A script has no use for the 302 to the login page the pages answer with.
+func TestAMissingOrBadTokenIsAJSON401(t *testing.T) {
+	f := newFixture(t)
+
+	for _, bearer := range []string{"", "bad", "nobody", "gone"} {
+		status, body := f.get(t, Prefix+"/funds", bearer)
+
+		require.Equal(t, http.StatusUnauthorized, status, "bearer %q", bearer)
+		require.Equal(t, codeUnauthorized, errorCode(t, body))
+	}
+}
+
+// The token is fine; the member is not an admin. A 403 says asking again will
+// not help.
+func TestBatchesAreForAdminsOnly(t *testing.T) {
+	f := newFixture(t)
+
+	status, body := f.get(t, Prefix+"/batches/"+f.batch.ID.String(), "donor")
+	require.Equal(t, http.StatusForbidden, status)
+	require.Equal(t, codeForbidden, errorCode(t, body))
+
+	status, body = f.get(t, Prefix+"/batches/"+f.batch.ID.String(), "admin")
+	require.Equal(t, http.StatusOK, status)
+
+	batch := body["data"].(map[string]any)
+	require.Equal(t, f.batch.ID.String(), batch["id"])
+	require.Len(t, batch["items"], 1, "a single batch comes with its payouts")
+}
+
+// A treasurer's token from the password alone is refused what the admin pages
+// would refuse them, and told why in a code of its own.
+func TestBatchesAskForASecondFactor(t *testing.T) {
+	f := newFixture(t)
+
+	for _, path := range []string{
+		Prefix + "/funds/" + f.batch.FundID.String() + "/batches",
+		Prefix + "/batches/awaiting-approval",
+		Prefix + "/batches/" + f.batch.ID.String(),
+		Prefix + "/batches/" + f.batch.ID.String() + "/payouts",
+	} {
+		status, body := f.get(t, path, "treasurer")
+		require.Equal(t, http.StatusForbidden, status, path)
+		require.Equal(t, codeSecondFactor, errorCode(t, body), path)
+
+		status, _ = f.get(t, path, "admin")
+		require.Equal(t, http.StatusOK, status, path)
+	}
+
+	// The member routes ask nothing more of them than of anybody.
+	status, _ := f.get(t, Prefix+"/funds", "treasurer")
+	require.Equal(t, http.StatusOK, status)
+}
+
+func TestAMemberReadsFundsAndTheirOwnDonations(t *testing.T) {
+	f := newFixture(t)
+
+	status, body := f.get(t, Prefix+"/funds", "donor")
+	require.Equal(t, http.StatusOK, status)
+
+	funds := body["data"].([]any)
+	require.Len(t, funds, 1)
+	require.Equal(t, float64(50), funds[0].(map[string]any)["goal_progress"])
+
+	status, body = f.get(t, Prefix+"/me/donations", "donor")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, body["data"], 1)
+
+	// Nobody else's: the admin has given nothing, and gets an empty list rather
+	// than null.
+	status, body = f.get(t, Prefix+"/me/donations", "admin")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []any{}, body["data"])
+}
+
+// An unknown fund is a 404 from the sentinel under the wrapping, and an id that
+// is not one is a 400 before anything is read.
+func TestLookupsAnswerWithTheirStatus(t *testing.T) {
+	f := newFixture(t)
+
+	status, body := f.get(t, Prefix+"/funds/"+uuid.NewString(), "donor")
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, codeNotFound, errorCode(t, body))
+
+	status, body = f.get(t, Prefix+"/funds/"+uuid.NewString()+"/timeline", "donor")
+	require.Equal(t, http.StatusNotFound, status, "an unknown fund is not an empty history")
+	require.Equal(t, codeNotFound, errorCode(t, body))
+
+	status, body = f.get(t, Prefix+"/funds/not-an-id", "donor")
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, codeInvalid, errorCode(t, body))
+
+	status, _ = f.get(t, Prefix+"/funds/"+f.fund.ID.String()+"/timeline?limit=0", "donor")
+	require.Equal(t, http.StatusBadRequest, status)
+
+	status, body = f.get(t, Prefix+"/funds/"+f.fund.ID.String()+"/timeline", "donor")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, body["data"], 1)
+}
+
+func TestServiceErrorsMapToStatuses(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("wrapped: %w", pgx.ErrNoRows), http.StatusNotFound, codeNotFound},
+		{donations.ErrDonationNotYours, http.StatusNotFound, codeNotFound},
+		{donations.ErrFundClosed, http.StatusConflict, codeConflict},
+		{payouts.ErrNotApprovable, http.StatusConflict, codeConflict},
+		{payouts.ErrApproverEnrolled, http.StatusForbidden, codeForbidden},
+		{donations.ErrInvalidInterval, http.StatusBadRequest, codeInvalid},
+		{errors.New("connection refused to 10.0.0.5"), http.StatusInternalServerError, codeInternal},
+	}
+
+	for _, c := range cases {
+		status, body := errorFor(c.err)
+
+		require.Equal(t, c.status, status, c.err.Error())
+		require.Equal(t, c.code, body.Code, c.err.Error())
+		require.NotContains(t, body.Message, "10.0.0.5", "an unexpected error says nothing about why")
+		require.NotContains(t, body.Message, "no rows", "pgx's wording is not the client's business")
+	}
+}
+
+// The same check the admin routes have: ServeMux rejects an ambiguous pattern
+// by panicking as it registers, which is at boot.
+func TestAPIRoutesRegisterWithoutConflict(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("api routes conflict: %v", r)
+		}
+	}()
+
+	newFixture(t)
+}
@@ -0,0 +1,268 @@
+package apiweb
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SpecPath is where the OpenAPI document is served. Outside Prefix, because it
+// describes every version rather than belonging to one.
+const SpecPath = "/api/openapi.json"
+
+// operation is one route as the spec describes it.
+//
+// The response is an example value of the wire type rather than a schema
+// written out by hand, so the schema is generated from the same struct the
+// handler encodes and cannot drift from it. What cannot be generated -- which
+// routes exist -- is checked against the router by TestTheSpecMatchesTheRoutes.
+type operation struct {
+	method   string
+	path     string
+	summary  string
+	admin    bool
+	query    []queryParam
+	response any
+}
+
+type queryParam struct {
+	name        string
+	description string
+}
+
+var operations = []operation{
+	{method: http.MethodGet, path: Prefix + "/me", summary: "The member the token belongs to.", response: memberJSON{}},
+	{method: http.MethodGet, path: Prefix + "/me/donations", summary: "The caller's own donations.", response: []donationJSON{}},
+	{method: http.MethodGet, path: Prefix + "/funds", summary: "Open funds taking donations.", response: []fundJSON{}},
+	{method: http.MethodGet, path: Prefix + "/funds/{id}", summary: "One fund, open or closed, with its monthly totals.", response: fundJSON{}},
+	{
+		method: http.MethodGet, path: Prefix + "/funds/{id}/timeline", summary: "The fund's public history, newest first.",
+		query:    []queryParam{{name: "limit", description: "how many events, at most 500"}},
+		response: []eventJSON{},
+	},
+	{method: http.MethodGet, path: Prefix + "/funds/{id}/batches", summary: "Every payout batch for a fund.", admin: true, response: []batchJSON{}},
+	{method: http.MethodGet, path: Prefix + "/batches/awaiting-approval", summary: "Batches waiting on a treasurer.", admin: true, response: []batchJSON{}},
+	{method: http.MethodGet, path: Prefix + "/batches/{id}", summary: "One batch, with its payouts.", admin: true, response: batchJSON{}},
+	{method: http.MethodGet, path: Prefix + "/batches/{id}/payouts", summary: "The payouts in one batch.", admin: true, response: []payoutJSON{}},
+}
+
+var (
+	specOnce  sync.Once
+	specBytes []byte
+	specErr   error
+)
+
+// Spec is the OpenAPI document, built once. The types it reflects over do not
+// change while the process runs.
+func Spec() ([]byte, error) {
+	specOnce.Do(func() {
+		specBytes, specErr = json.MarshalIndent(buildSpec(), "", "  ")
+	})
+
+	return specBytes, specErr
+}
+
+func (h *APIHandlers) openAPI(w http.ResponseWriter, _ *http.Request) {
+	spec, err := Spec()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, msgInternal)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(spec)
+}
+
+type object = map[string]any
+
+func buildSpec() object {
+	paths := object{}
+
+	for _, op := range operations {
+		item, _ := paths[op.path].(object)
+		if item == nil {
+			item = object{}
+			paths[op.path] = item
+		}
+
+		item[strings.ToLower(op.method)] = op.describe()
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "boardfund",
+			"version": "v1",
+			"description": "Funds, donations and payouts. Every route needs a bearer token; " +
+				"batches and payouts need one from an admin or a treasurer.",
+		},
+		"paths": paths,
+		"components": object{
+			"securitySchemes": object{
+				"bearer": object{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+			"schemas": object{
+				"Error": schemaOf(reflect.TypeOf(errorBody{})),
+			},
+		},
+		"security": []any{object{"bearer": []any{}}},
+	}
+}
+
+func (op operation) describe() object {
+	var params []any
+
+	// Every wildcard in the path, found in the path itself so a renamed one
+	// cannot be described under its old name.
+	for _, segment := range strings.Split(op.path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, object{
+				"name":     strings.Trim(segment, "{}"),
+				"in":       "path",
+				"required": true,
+				"schema":   object{"type": "string", "format": "uuid"},
+			})
+		}
+	}
+
+	for _, param := range op.query {
+		params = append(params, object{
+			"name":        param.name,
+			"in":          "query",
+			"description": param.description,
+			"schema":      object{"type": "integer"},
+		})
+	}
+
+	errorResponse := func(description string) object {
+		return object{
+			"description": description,
+			"content": object{"application/json": object{
+				"schema": object{"$ref": "#/components/schemas/Error"},
+			}},
+		}
+	}
+
+	responses := object{
+		"200": object{
+			"description": "OK",
+			"content": object{"application/json": object{
+				"schema": object{
+					"type":       "object",
+					"properties": object{"data": schemaOf(reflect.TypeOf(op.response))},
+					"required":   []string{"data"},
+				},
+			}},
+		},
+		"401": errorResponse("no token, or one that is not valid for an active member"),
+		"500": errorResponse("something went wrong on our side"),
+	}
+
+	if len(params) > 0 {
+		responses["400"] = errorResponse("a malformed id or parameter")
+	}
+
+	if strings.Contains(op.path, "{") {
+		responses["404"] = errorResponse("nothing with that id")
+	}
+
+	if op.admin {
+		responses["403"] = errorResponse("the token is not an admin's or a treasurer's, or no second factor has been given with it")
+	}
+
+	described := object{
+		"summary":   op.summary,
+		"responses": responses,
+	}
+
+	if len(params) > 0 {
+		described["parameters"] = params
+	}
+
+	return described
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// schemaOf is the JSON Schema for what encoding/json makes of t, read from the
+// same json tags the encoder reads.
+func schemaOf(t reflect.Type) object {
+	switch t {
+	case timeType:
+		return object{"type": "string", "format": "date-time"}
+	case uuidType:
+		return object{"type": "string", "format": "uuid"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := schemaOf(t.Elem())
+		schema["nullable"] = true
+
+		return schema
+	case reflect.Slice, reflect.Array:
+		return object{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.String:
+		return object{"type": "string"}
+	default:
+		// An interface or a map. Said as "anything" rather than guessed at.
+		return object{}
+	}
+}
+
+func structSchema(t reflect.Type) object {
+	properties := object{}
+	var required []string
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaOf(field.Type)
+
+		// omitempty leaves the field out when it is empty, so a client cannot
+		// count on it being there. Everything else is always sent, null or not.
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	sort.Strings(required)
+
+	schema := object{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
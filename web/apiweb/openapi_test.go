@@ -0,0 +1,49 @@
+package apiweb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The schema is read off the wire types, so it says what the encoder writes:
+// the json names, a nullable pointer, an optional omitempty.
+func TestSchemasFollowTheWireTypes(t *testing.T) {
+	schema := schemaOf(reflect.TypeOf(fundJSON{}))
+
+	properties := schema["properties"].(object)
+	require.Contains(t, properties, "goal_cents")
+	require.NotContains(t, properties, "GoalCents")
+
+	require.Equal(t, object{"type": "string", "format": "uuid"}, properties["id"])
+	require.Equal(t, true, properties["expires"].(object)["nullable"])
+	require.Equal(t, "array", properties["monthly"].(object)["type"])
+
+	required := schema["required"].([]string)
+	require.Contains(t, required, "name")
+	require.NotContains(t, required, "monthly", "omitempty is not always sent")
+}
+
+// Served without a token: a client generator reads it before it has one.
+func TestTheSpecIsServedWithoutAToken(t *testing.T) {
+	f := newFixture(t)
+
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, SpecPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var document map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &document))
+	require.Equal(t, "3.0.3", document["openapi"])
+
+	paths := document["paths"].(map[string]any)
+	batch := paths[Prefix+"/batches/{id}"].(map[string]any)["get"].(map[string]any)
+	require.Contains(t, batch["responses"], "403", "an admin route says who it refuses")
+	require.Len(t, batch["parameters"], 1, "the path wildcard is described")
+}
@@ -0,0 +1,251 @@
+package apiweb
+
+import (
+	"time"
+
+	"boardfund/service/donations"
+	"boardfund/service/fundevents"
+	"boardfund/service/members"
+	"boardfund/service/payouts"
+
+	"github.com/google/uuid"
+)
+
+// The wire types are declared here rather than by tagging the service structs.
+// Those change whenever a page needs something, and a field renamed for a
+// template would otherwise be a breaking change to every script holding a v1
+// client -- with nothing in the diff saying so.
+
+// envelope wraps every successful answer, so a list can grow paging alongside
+// "data" later without changing what "data" is.
+type envelope struct {
+	Data any `json:"data"`
+}
+
+type fundJSON struct {
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Active          bool       `json:"active"`
+	Closed          bool       `json:"closed"`
+	PayoutFrequency string     `json:"payout_frequency"`
+	GoalCents       int32      `json:"goal_cents"`
+	GoalProgress    int        `json:"goal_progress"`
+	CloseOnGoal     bool       `json:"close_on_goal"`
+	DonatedCents    int32      `json:"donated_cents"`
+	Donations       int32      `json:"donations"`
+	Donors          int32      `json:"donors"`
+	Expires         *time.Time `json:"expires"`
+	NextPayment     *time.Time `json:"next_payment"`
+	Created         time.Time  `json:"created"`
+
+	// Only on a single fund. The list does not load it -- see ListActiveFunds.
+	Monthly []monthJSON `json:"monthly,omitempty"`
+}
+
+type monthJSON struct {
+	Month        string `json:"month"`
+	AmountCents  int32  `json:"amount_cents"`
+	UniqueDonors int32  `json:"unique_donors"`
+}
+
+func toFund(fund donations.Fund, now time.Time) fundJSON {
+	out := fundJSON{
+		ID:              fund.ID,
+		Name:            fund.Name,
+		Description:     fund.Description,
+		Active:          fund.Active,
+		Closed:          fund.Closed(),
+		PayoutFrequency: string(fund.PayoutFrequency),
+		GoalCents:       fund.GoalCents,
+		GoalProgress:    fund.GoalProgress(),
+		CloseOnGoal:     fund.CloseOnGoal,
+		DonatedCents:    fund.Stats.TotalDonated,
+		Donations:       fund.Stats.TotalDonations,
+		Donors:          fund.Stats.TotalDonors,
+		Expires:         fund.Expires,
+		Created:         fund.Created,
+	}
+
+	// The rolled-forward date the fund page shows, not the stored anchor, which
+	// is in the past for every fund that has paid once.
+	if next := fund.NextPaymentAfter(now); !next.IsZero() {
+		out.NextPayment = &next
+	}
+
+	for _, month := range fund.Stats.Monthly {
+		out.Monthly = append(out.Monthly, monthJSON{
+			Month:        month.MonthYear,
+			AmountCents:  month.TotalCents,
+			UniqueDonors: month.UniqueDonors,
+		})
+	}
+
+	return out
+}
+
+type donationJSON struct {
+	ID              uuid.UUID  `json:"id"`
+	FundID          uuid.UUID  `json:"fund_id"`
+	FundName        string     `json:"fund_name"`
+	FundActive      bool       `json:"fund_active"`
+	Recurring       bool       `json:"recurring"`
+	Active          bool       `json:"active"`
+	InactiveReason  string     `json:"inactive_reason,omitempty"`
+	TotalGivenCents int64      `json:"total_given_cents"`
+	PlanAmountCents int32      `json:"plan_amount_cents,omitempty"`
+	PlanInterval    string     `json:"plan_interval,omitempty"`
+	Cancellable     bool       `json:"cancellable"`
+	Started         time.Time  `json:"started"`
+	LastPayment     *time.Time `json:"last_payment"`
+}
+
+func toDonation(donation donations.MemberDonation) donationJSON {
+	out := donationJSON{
+		ID:              donation.ID,
+		FundID:          donation.FundID,
+		FundName:        donation.FundName,
+		FundActive:      donation.FundActive,
+		Recurring:       donation.Recurring,
+		Active:          donation.Active,
+		InactiveReason:  donation.InactiveReason,
+		TotalGivenCents: donation.TotalGivenCents,
+		Cancellable:     donation.Cancellable(),
+		Started:         donation.Started,
+		LastPayment:     donation.LastPayment,
+	}
+
+	if donation.Recurring {
+		out.PlanAmountCents = donation.PlanAmountCents
+		out.PlanInterval = donations.IntervalLabel(donation.PlanIntervalUnit, donation.PlanIntervalCount)
+	}
+
+	return out
+}
+
+type eventJSON struct {
+	Kind        string    `json:"kind"`
+	OccurredAt  time.Time `json:"occurred_at"`
+	Actor       string    `json:"actor,omitempty"`
+	Automatic   bool      `json:"automatic"`
+	AmountCents *int32    `json:"amount_cents,omitempty"`
+	Detail      string    `json:"detail,omitempty"`
+}
+
+// toEvent takes the public event only. The full one names subjects and carries
+// member ids, and the timeline is the view of a fund that anyone signed in may
+// read.
+func toEvent(event fundevents.PublicEvent) eventJSON {
+	return eventJSON{
+		Kind:        string(event.Kind),
+		OccurredAt:  event.OccurredAt,
+		Actor:       event.ActorName,
+		Automatic:   event.Automatic,
+		AmountCents: event.AmountCents,
+		Detail:      event.Detail,
+	}
+}
+
+type memberJSON struct {
+	ID          uuid.UUID `json:"id"`
+	BCOName     string    `json:"bco_name"`
+	Email       string    `json:"email"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	PaypalEmail string    `json:"paypal_email"`
+	Admin       bool      `json:"admin"`
+	Created     time.Time `json:"created"`
+}
+
+func toMember(member members.Member, admin bool) memberJSON {
+	return memberJSON{
+		ID:          member.ID,
+		BCOName:     member.BCOName,
+		Email:       member.Email,
+		FirstName:   member.FirstName,
+		LastName:    member.LastName,
+		PaypalEmail: member.PaypalEmail,
+		Admin:       admin,
+		Created:     member.Created,
+	}
+}
+
+type batchJSON struct {
+	ID                uuid.UUID  `json:"id"`
+	FundID            uuid.UUID  `json:"fund_id"`
+	AmountCents       int32      `json:"amount_cents"`
+	Payouts           int32      `json:"payouts"`
+	Status            string     `json:"status"`
+	FailureReason     string     `json:"failure_reason,omitempty"`
+	Description       string     `json:"description,omitempty"`
+	PayoutDate        time.Time  `json:"payout_date"`
+	ApprovalDeadline  *time.Time `json:"approval_deadline"`
+	ApprovalsRequired int32      `json:"approvals_required"`
+	ApprovalsGiven    int32      `json:"approvals_given"`
+	ApprovedAt        *time.Time `json:"approved_at"`
+	Created           time.Time  `json:"created"`
+	Updated           time.Time  `json:"updated"`
+
+	// Only on a single batch.
+	Items []payoutJSON `json:"items,omitempty"`
+}
+
+func toBatch(batch payouts.Batch) batchJSON {
+	return batchJSON{
+		ID:                batch.ID,
+		FundID:            batch.FundID,
+		AmountCents:       batch.AmountCents,
+		Payouts:           batch.NumEnrollments,
+		Status:            string(batch.Status),
+		FailureReason:     batch.FailureReason,
+		Description:       batch.Description,
+		PayoutDate:        batch.PayoutDate,
+		ApprovalDeadline:  batch.ApprovalDeadline,
+		ApprovalsRequired: batch.ApprovalsRequired,
+		ApprovalsGiven:    batch.ApprovalsGiven,
+		ApprovedAt:        batch.ApprovedAt,
+		Created:           batch.Created,
+		Updated:           batch.Updated,
+	}
+}
+
+type payoutJSON struct {
+	ID               uuid.UUID  `json:"id"`
+	BatchID          uuid.UUID  `json:"batch_id"`
+	EnrollmentID     uuid.UUID  `json:"enrollment_id"`
+	AmountCents      int32      `json:"amount_cents"`
+	FeeCents         int32      `json:"fee_cents"`
+	DestinationEmail string     `json:"destination_email"`
+	Status           string     `json:"status"`
+	FailureReason    string     `json:"failure_reason,omitempty"`
+	PayoutDate       time.Time  `json:"payout_date"`
+	StruckAt         *time.Time `json:"struck_at,omitempty"`
+	StruckBy         *uuid.UUID `json:"struck_by,omitempty"`
+}
+
+func toPayout(payout payouts.Payout) payoutJSON {
+	return payoutJSON{
+		ID:               payout.ID,
+		BatchID:          payout.BatchID,
+		EnrollmentID:     payout.FundEnrollmentID,
+		AmountCents:      payout.AmountCents,
+		FeeCents:         payout.ProviderFeeCents,
+		DestinationEmail: payout.DestinationEmail,
+		Status:           string(payout.Status),
+		FailureReason:    payout.FailureReason,
+		PayoutDate:       payout.PayoutDate,
+		StruckAt:         payout.StruckAt,
+		StruckBy:         payout.StruckBy,
+	}
+}
+
+// mapSlice converts a list, and answers an empty one as [] rather than null --
+// a client ranging over "data" should not have to check for null first.
+func mapSlice[In, Out any](in []In, convert func(In) Out) []Out {
+	out := make([]Out, 0, len(in))
+	for _, item := range in {
+		out = append(out, convert(item))
+	}
+
+	return out
+}
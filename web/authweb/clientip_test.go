@@ -0,0 +1,87 @@
+package authweb
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// Reset requests are limited per IP, and every request arrives from a proxy.
+// Counting the proxy would put every visitor in one bucket, so that one busy
+// hour locks everybody out.
+func TestTheIPCountedIsTheVisitors(t *testing.T) {
+	var h AuthHandlers
+	if err := h.TrustProxies([]string{"192.0.2.1", "172.70.0.0/15"}); err != nil {
+		t.Fatalf("trust: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"through cloudflare", map[string]string{"CF-Connecting-IP": "198.51.100.7", "X-Forwarded-For": "198.51.100.7, 172.70.1.1"}, "198.51.100.7"},
+		{"through one proxy", map[string]string{"X-Forwarded-For": "203.0.113.5, 198.51.100.7"}, "198.51.100.7"},
+		// The trusted hops are walked past, so the one counted is the one a
+		// trusted proxy said it was forwarding for.
+		{"through two proxies", map[string]string{"X-Forwarded-For": "203.0.113.5, 198.51.100.7, 172.70.1.1"}, "198.51.100.7"},
+		{"directly", nil, "192.0.2.1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/password/forgot", nil)
+			r.RemoteAddr = "192.0.2.1:54321"
+
+			for name, value := range c.headers {
+				r.Header.Set(name, value)
+			}
+
+			if got := clientIP(r, h.trustedProxies); got != c.want {
+				t.Errorf("clientIP = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// A header from a connection no trusted proxy made is whatever the client
+// chose. Believing it would give every request a fresh count, so it is ignored
+// and the address that connected is counted.
+func TestASpoofedHeaderOnADirectConnectionIsIgnored(t *testing.T) {
+	var h AuthHandlers
+	if err := h.TrustProxies([]string{"192.0.2.1"}); err != nil {
+		t.Fatalf("trust: %v", err)
+	}
+
+	for _, headers := range []map[string]string{
+		{"CF-Connecting-IP": "198.51.100.7"},
+		{"X-Forwarded-For": "198.51.100.7"},
+		{"CF-Connecting-IP": "198.51.100.8", "X-Forwarded-For": "203.0.113.5, 198.51.100.9"},
+	} {
+		r := httptest.NewRequest("POST", "/password/forgot", nil)
+		r.RemoteAddr = "203.0.113.66:40000"
+
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+
+		if got := clientIP(r, h.trustedProxies); got != "203.0.113.66" {
+			t.Errorf("headers %v: clientIP = %q, want the address that connected", headers, got)
+		}
+	}
+
+	// And with no proxies trusted at all, which is how it starts.
+	r := httptest.NewRequest("POST", "/password/forgot", nil)
+	r.RemoteAddr = "192.0.2.1:54321"
+	r.Header.Set("CF-Connecting-IP", "198.51.100.7")
+
+	if got := clientIP(r, nil); got != "192.0.2.1" {
+		t.Errorf("nothing trusted: clientIP = %q, want the address that connected", got)
+	}
+}
+
+func TestAProxyThatIsNotAnAddressIsRefused(t *testing.T) {
+	var h AuthHandlers
+	if err := h.TrustProxies([]string{"railway"}); err == nil {
+		t.Error("a name was accepted as a proxy")
+	}
+}
@@ -2,12 +2,16 @@ package authweb
 
 import (
 	"boardfund/service/auth"
+	"boardfund/service/enrollments"
 	"boardfund/service/members"
 	"boardfund/web/common"
+	"boardfund/web/middlewares"
 	"boardfund/web/mux"
 	"errors"
 	"github.com/alexedwards/scs/v2"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"time"
 )
@@ -17,39 +21,93 @@ type AuthHandlers struct {
 	memberService  *members.MemberService
 	sessionManager *scs.SessionManager
 
+	// enrollmentService makes the enrollment an invite can carry, as the member
+	// it invited registers.
+	enrollmentService *enrollments.EnrollmentsService
+
+	// withAuth guards the account pages, which are for a member already logged
+	// in. The login pages are outside it, for the obvious reason.
+	withAuth func(http.HandlerFunc) http.HandlerFunc
+
+	// logger records the failures a visitor is only shown a generic sentence
+	// for.
+	logger *slog.Logger
+
 	clientID string
 
 	// secureCookies marks the token cookie Secure, which a browser honours by
 	// refusing to send it over plain HTTP. Off in local development, where there
 	// is no certificate and marking it would send no cookie at all.
 	secureCookies bool
+
+	// trustedProxies are the proxies whose forwarding headers are believed, set
+	// by TrustProxies. Empty means none are.
+	trustedProxies []netip.Prefix
+
+	// serveKeys answers JWKSPath, set by PublishKeysWith. Nil means the tokens
+	// are Cognito's, which publishes its own keys.
+	serveKeys http.HandlerFunc
 }
 
-func NewAuthHandlers(authService *auth.AuthService, memberService *members.MemberService, sessionManager *scs.SessionManager, clientID string, secureCookies bool) *AuthHandlers {
+// JWKSPath is where the keys that sign local tokens are published, at the path
+// Cognito uses for its own.
+const JWKSPath = "/.well-known/jwks.json"
+
+func NewAuthHandlers(authService *auth.AuthService, memberService *members.MemberService, enrollmentService *enrollments.EnrollmentsService, sessionManager *scs.SessionManager, withAuth func(http.HandlerFunc) http.HandlerFunc, logger *slog.Logger, clientID string, secureCookies bool) *AuthHandlers {
 
 	return &AuthHandlers{
-		authService:    authService,
-		memberService:  memberService,
-		sessionManager: sessionManager,
-		clientID:       clientID,
-		secureCookies:  secureCookies,
+		authService:       authService,
+		memberService:     memberService,
+		enrollmentService: enrollmentService,
+		sessionManager:    sessionManager,
+		withAuth:          withAuth,
+		logger:            logger,
+		clientID:          clientID,
+		secureCookies:     secureCookies,
 	}
 }
 
+// PublishKeysWith serves the key set at JWKSPath. A setter, like
+// hooksweb's AcceptStripeWebhooks: only a deployment issuing its own tokens has
+// keys to publish.
+func (h *AuthHandlers) PublishKeysWith(serve http.HandlerFunc) {
+	h.serveKeys = serve
+}
+
 func (h AuthHandlers) Register(r *mux.Router) {
 	r.HandleFunc("GET /login", h.loginPage)
 	r.HandleFunc("POST /login", h.login)
+	r.HandleFunc("GET /login/two-factor", h.loginTwoFactorPage)
+	r.HandleFunc("POST /login/two-factor", h.loginTwoFactor)
 	r.HandleFunc("GET /register", h.passwordRegistrationPage)
 	r.HandleFunc("POST /register", h.register)
 	r.HandleFunc("/logout", h.logout)
 	r.HandleFunc("GET /password", h.passwordPage)
 	r.HandleFunc("GET /auth/error", h.errorPage)
 	r.HandleFunc("POST /password", h.resetPassword)
+	r.HandleFunc("GET /password/forgot", h.forgotPasswordPage)
+	r.HandleFunc("POST /password/forgot", h.forgotPassword)
+	r.HandleFunc("GET /password/reset", h.resetPasswordPage)
+	r.HandleFunc("POST /password/reset", h.resetForgottenPassword)
+	r.HandleFunc("GET /account/two-factor", h.withAuth(h.twoFactorPage))
+	r.HandleFunc("POST /account/two-factor", h.withAuth(h.confirmTwoFactor))
+	r.HandleFunc("POST /account/two-factor/verify", h.withAuth(h.verifyTwoFactor))
+	r.HandleFunc("POST /account/two-factor/disable", h.withAuth(h.disableTwoFactor))
+
+	if h.serveKeys != nil {
+		r.HandleFunc("GET "+JWKSPath, h.serveKeys)
+	}
 }
 
 func (h AuthHandlers) register(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if token := r.FormValue("invite"); token != "" {
+		h.registerWithInvite(w, r, token)
+
+		return
+	}
+
 	username := r.FormValue("username")
 	email := r.FormValue("email")
 
@@ -88,12 +146,18 @@ func (h AuthHandlers) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	RegistrationSuccess().Render(ctx, w)
+	RegistrationSuccess("").Render(ctx, w)
 }
 
 func (h AuthHandlers) passwordRegistrationPage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if token := r.URL.Query().Get("invite"); token != "" {
+		h.inviteRegistrationPage(w, r, token)
+
+		return
+	}
+
 	PasswordRegistration().Render(ctx, w)
 }
 
@@ -124,10 +188,7 @@ func (h AuthHandlers) resetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.setTokenCookie("access-token", authResp.Token.IDTokenStr, authResp.Token.Expires, w)
-	h.sessionManager.Put(ctx, "member", member)
-
-	http.Redirect(w, r, "/", http.StatusFound)
+	h.finishLogin(w, r, member, authResp.Token)
 }
 
 func (h AuthHandlers) login(w http.ResponseWriter, r *http.Request) {
@@ -155,10 +216,7 @@ func (h AuthHandlers) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.setTokenCookie("access-token", authResp.Token.IDTokenStr, authResp.Token.Expires, w)
-	h.sessionManager.Put(ctx, "member", member)
-
-	http.Redirect(w, r, "/", http.StatusFound)
+	h.finishLogin(w, r, member, authResp.Token)
 }
 
 func (h AuthHandlers) errorPage(w http.ResponseWriter, r *http.Request) {
@@ -177,11 +235,21 @@ func (h AuthHandlers) passwordPage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h AuthHandlers) logout(w http.ResponseWriter, r *http.Request) {
-	h.setTokenCookie("access-token", "", time.Now(), w)
+	h.endSession(w, r)
 
 	http.Redirect(w, r, "/login", http.StatusFound)
 }
 
+// endSession logs the browser out. The session outlives the cookie, so the
+// second factor given in it has to be taken back here, or the next login with
+// the password alone would inherit it.
+func (h AuthHandlers) endSession(w http.ResponseWriter, r *http.Request) {
+	h.sessionManager.Remove(r.Context(), middlewares.TwoFactorSessionKey)
+	h.sessionManager.Remove(r.Context(), pendingLoginKey)
+
+	h.setTokenCookie("access-token", "", time.Now(), w)
+}
+
 func (h AuthHandlers) loginPage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -0,0 +1,109 @@
+package authweb
+
+import (
+	"boardfund/service/auth"
+	"boardfund/service/enrollments"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// inviteRegistrationPage is where an invite link lands. The invite is checked
+// so the form can ask for what it needs -- an address if none was bound, a
+// PayPal address if there is a fund to enroll in -- but nothing is spent by
+// opening it.
+func (h AuthHandlers) inviteRegistrationPage(w http.ResponseWriter, r *http.Request, token string) {
+	ctx := r.Context()
+
+	invite, err := h.authService.CheckInvite(ctx, token)
+	if err != nil {
+		h.inviteErrRedirect(w, r, err, "/register")
+
+		return
+	}
+
+	// The token is in this page's URL. Without this, any link followed from the
+	// page would hand it to another site in the Referer header.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	InviteRegistration(*invite, token).Render(ctx, w)
+}
+
+// registerWithInvite registers the member an invite was made for, and enrolls
+// them in its fund if it has one.
+//
+// The PayPal address is checked before the account is made, because after it
+// the invite is spent and a typo would leave a member with no enrollment and no
+// link to try again with. The enrollment itself failing is reported rather than
+// undone: the account is the part that matters, and an admin can enroll them.
+func (h AuthHandlers) registerWithInvite(w http.ResponseWriter, r *http.Request, token string) {
+	ctx := r.Context()
+
+	// Back to the same link, which still works: nothing was spent.
+	retry := "/register?" + url.Values{"invite": {token}}.Encode()
+
+	invite, err := h.authService.CheckInvite(ctx, token)
+	if err != nil {
+		h.inviteErrRedirect(w, r, err, retry)
+
+		return
+	}
+
+	var paypalEmail string
+	if invite.FundID != nil {
+		paypalEmail, err = enrollments.ValidatePaypalEmail(r.FormValue("paypal"))
+		// The detail after the sentinel can quote the address back, so it is not
+		// repeated here.
+		if err != nil {
+			errRedirect(w, r, "that is not a valid paypal email address. give the address on its own.", retry)
+
+			return
+		}
+	}
+
+	member, invite, err := h.authService.RegisterWithInvite(ctx, token, r.FormValue("username"), r.FormValue("email"))
+	if err != nil {
+		h.inviteErrRedirect(w, r, err, retry)
+
+		return
+	}
+
+	if invite.FundID == nil {
+		RegistrationSuccess("").Render(ctx, w)
+
+		return
+	}
+
+	_, err = h.enrollmentService.CreateEnrollment(ctx, enrollments.CreateEnrollment{
+		MemberID:      member.ID,
+		FundID:        *invite.FundID,
+		PaypalEmail:   paypalEmail,
+		MemberBCOName: member.BCOName,
+	})
+	if err != nil {
+		RegistrationSuccess("you could not be enrolled in "+invite.FundName+" just now. ask an admin to enroll you.").Render(ctx, w)
+
+		return
+	}
+
+	RegistrationSuccess("you have been enrolled in "+invite.FundName+".").Render(ctx, w)
+}
+
+// inviteErrRedirect words an invite failure for the person holding the link.
+// Anything it does not recognise is a store or provider fault, which is logged
+// and not shown: the link is all it takes to see the page.
+func (h AuthHandlers) inviteErrRedirect(w http.ResponseWriter, r *http.Request, err error, retry string) {
+	switch {
+	case errors.Is(err, auth.ErrInvitesUnavailable), errors.Is(err, auth.ErrInviteInvalid):
+		errRedirect(w, r, "that invite link has expired or been used. ask whoever sent it for another.", "/")
+	case errors.Is(err, auth.ErrInviteEmailMismatch):
+		errRedirect(w, r, "that invite is for a different email address. use the one it was sent to.", retry)
+	case errors.Is(err, auth.ErrUsernameExists):
+		errRedirect(w, r, "that username is taken. choose another.", retry)
+	default:
+		h.logger.ErrorContext(r.Context(), "failed to register with an invite", slog.String("error", err.Error()))
+
+		errRedirect(w, r, "could not register you. please try again.", retry)
+	}
+}
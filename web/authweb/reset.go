@@ -0,0 +1,184 @@
+package authweb
+
+import (
+	"boardfund/service/auth"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// forgotPasswordPage asks for the address a reset link should go to.
+func (h AuthHandlers) forgotPasswordPage(w http.ResponseWriter, r *http.Request) {
+	ForgotPassword().Render(r.Context(), w)
+}
+
+// forgotPassword sends a reset link, if the address has an account. The page
+// that follows says the same thing either way.
+func (h AuthHandlers) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	email := r.FormValue("email")
+	if strings.TrimSpace(email) == "" {
+		errRedirect(w, r, "give the email your account was registered with.", "/password/forgot")
+
+		return
+	}
+
+	err := h.authService.RequestPasswordReset(ctx, email, clientIP(r, h.trustedProxies))
+
+	switch {
+	case errors.Is(err, auth.ErrPasswordResetUnavailable):
+		errRedirect(w, r, "password reset by email is not set up here. ask an admin to reset it for you.", "/login")
+
+		return
+	case errors.Is(err, auth.ErrTooManyResetRequests):
+		errRedirect(w, r, "too many reset requests. try again in an hour.", "/login")
+
+		return
+	case err != nil:
+		errRedirect(w, r, "could not send a reset link. please try again.", "/password/forgot")
+
+		return
+	}
+
+	ForgotPasswordSent().Render(ctx, w)
+}
+
+// resetPasswordPage is where the emailed link lands. The token is carried into
+// the form rather than checked here, so opening the link spends nothing.
+func (h AuthHandlers) resetPasswordPage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Redirect(w, r, "/password/forgot", http.StatusFound)
+
+		return
+	}
+
+	// The token is in this page's URL. Without this, any link followed from the
+	// page would hand it to another site in the Referer header.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	ResetPassword(token).Render(r.Context(), w)
+}
+
+func (h AuthHandlers) resetForgottenPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token := r.FormValue("token")
+	newPassword := r.FormValue("new")
+
+	// Back to the same link, which still works: nothing was spent.
+	retry := "/password/reset?" + url.Values{"token": {token}}.Encode()
+
+	if newPassword != r.FormValue("confirm") {
+		errRedirect(w, r, "passwords do not match", retry)
+
+		return
+	}
+
+	err := h.authService.ResetForgottenPassword(ctx, token, newPassword)
+
+	switch {
+	case errors.Is(err, auth.ErrResetLinkInvalid):
+		errRedirect(w, r, "that link has expired or been used. ask for another.", "/password/forgot")
+
+		return
+	case errors.Is(err, auth.ErrInvalidPassword):
+		errRedirect(w, r, "that password was not accepted. choose a longer one.", retry)
+
+		return
+	case err != nil:
+		errRedirect(w, r, "could not reset your password. please try again.", retry)
+
+		return
+	}
+
+	PasswordResetDone().Render(ctx, w)
+}
+
+// TrustProxies names the proxies whose forwarding headers clientIP believes,
+// as addresses or CIDR ranges. Without it the connection's own address is the
+// only one counted, because a header is whatever the client sent.
+func (h *AuthHandlers) TrustProxies(proxies []string) error {
+	trusted := make([]netip.Prefix, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(proxy); err == nil {
+			trusted = append(trusted, netip.PrefixFrom(addr, addr.BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return fmt.Errorf("%q is not an address or a CIDR range", proxy)
+		}
+
+		trusted = append(trusted, prefix.Masked())
+	}
+
+	h.trustedProxies = trusted
+
+	return nil
+}
+
+// clientIP is who to count a reset request against.
+//
+// The site is reached through Cloudflare and then Railway's proxy, so the
+// connection's own address is the proxy's, and counting by it would put every
+// visitor in one bucket. The proxies say who they forwarded for in
+// CF-Connecting-IP and X-Forwarded-For -- but so can anybody connecting
+// directly, and a header believed from them would be a fresh count per request.
+// So they are read only from a connection a trusted proxy made, and
+// X-Forwarded-For is walked back past the trusted hops to the first that is
+// not one.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	if !isTrusted(remote, trusted) {
+		return remote
+	}
+
+	if ip := strings.TrimSpace(r.Header.Get("CF-Connecting-IP")); ip != "" {
+		return ip
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop != "" && !isTrusted(hop, trusted) {
+			return hop
+		}
+	}
+
+	return remote
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
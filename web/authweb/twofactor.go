@@ -0,0 +1,345 @@
+package authweb
+
+import (
+	"boardfund/service/auth"
+	"boardfund/service/members"
+	"boardfund/web/middlewares"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pendingLoginKey holds a login whose password was right and whose second
+// factor has not been given yet.
+const pendingLoginKey = "pending_login"
+
+// pendingLoginLifetime is how long the code form waits after the password.
+const pendingLoginLifetime = 5 * time.Minute
+
+// maxTwoFactorAttempts is how many wrong codes end a pending login. Three
+// working codes are valid at any moment; a few tries cover a fumble, and going
+// back to the password for more makes guessing a million codes cost a million
+// password checks.
+//
+// That is per session. The service counts per member as well, across every form
+// that takes a code, and locks them out however many sessions are guessing.
+const maxTwoFactorAttempts = 5
+
+// lockedMessage is what a member is told once the service has locked them out.
+const lockedMessage = "too many wrong codes. wait a quarter of an hour and log in again."
+
+// pendingLogin is what login keeps between the password and the code. The token
+// is issued already, but it reaches the browser only once the code is right:
+// a cookie set at the password step would be a login with one factor.
+type pendingLogin struct {
+	Member   members.Member
+	Token    auth.Token
+	Expires  time.Time
+	Attempts int
+}
+
+func init() {
+	gob.Register(pendingLogin{})
+}
+
+// finishLogin is what follows a password accepted: straight in for a member
+// with no second factor, the code form for one who has.
+func (h AuthHandlers) finishLogin(w http.ResponseWriter, r *http.Request, member *members.Member, token *auth.Token) {
+	ctx := r.Context()
+
+	hasTwoFactor, err := h.authService.HasTwoFactor(ctx, member.ID)
+	if err != nil {
+		errRedirect(w, r, "could not log you in. please try again.", "/login")
+
+		return
+	}
+
+	if !hasTwoFactor {
+		h.completeLogin(w, r, *member, *token, false)
+
+		return
+	}
+
+	h.sessionManager.Put(ctx, pendingLoginKey, pendingLogin{
+		Member:  *member,
+		Token:   *token,
+		Expires: time.Now().Add(pendingLoginLifetime),
+	})
+
+	http.Redirect(w, r, "/login/two-factor", http.StatusFound)
+}
+
+// completeLogin hands the browser its token. The session is given a new id
+// first: it is about to say who is logged in, and an id somebody else planted
+// before the login would let them read it.
+func (h AuthHandlers) completeLogin(w http.ResponseWriter, r *http.Request, member members.Member, token auth.Token, secondFactor bool) {
+	ctx := r.Context()
+
+	if err := h.sessionManager.RenewToken(ctx); err != nil {
+		errRedirect(w, r, "could not log you in. please try again.", "/login")
+
+		return
+	}
+
+	h.sessionManager.Remove(ctx, pendingLoginKey)
+
+	if secondFactor {
+		h.sessionManager.Put(ctx, middlewares.TwoFactorSessionKey, member.ID.String())
+	} else {
+		h.sessionManager.Remove(ctx, middlewares.TwoFactorSessionKey)
+	}
+
+	h.setTokenCookie("access-token", token.IDTokenStr, token.Expires, w)
+	h.sessionManager.Put(ctx, "member", member)
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (h AuthHandlers) loginTwoFactorPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if _, ok := h.sessionManager.Get(ctx, pendingLoginKey).(pendingLogin); !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+
+		return
+	}
+
+	LoginTwoFactor().Render(ctx, w)
+}
+
+func (h AuthHandlers) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	pending, ok := h.sessionManager.Get(ctx, pendingLoginKey).(pendingLogin)
+	if !ok || time.Now().After(pending.Expires) {
+		h.sessionManager.Remove(ctx, pendingLoginKey)
+		errRedirect(w, r, "that login has expired. log in again.", "/login")
+
+		return
+	}
+
+	err := h.authService.VerifySecondFactor(ctx, pending.Member, r.FormValue("code"))
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		pending.Attempts++
+
+		if pending.Attempts >= maxTwoFactorAttempts {
+			h.sessionManager.Remove(ctx, pendingLoginKey)
+			errRedirect(w, r, "too many wrong codes. log in again.", "/login")
+
+			return
+		}
+
+		h.sessionManager.Put(ctx, pendingLoginKey, pending)
+		errRedirect(w, r, "that code is not valid. try the next one your app shows.", "/login/two-factor")
+
+		return
+	case errors.Is(err, auth.ErrTwoFactorLocked):
+		h.sessionManager.Remove(ctx, pendingLoginKey)
+		errRedirect(w, r, lockedMessage, "/login")
+
+		return
+	case err != nil:
+		errRedirect(w, r, "could not check that code. please try again.", "/login/two-factor")
+
+		return
+	}
+
+	h.completeLogin(w, r, pending.Member, pending.Token, true)
+}
+
+// twoFactorPage is where RequireTwoFactor sends a member: to set two-factor up
+// if they have none, and to give a code if this session has not had one.
+func (h AuthHandlers) twoFactorPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, err := h.tokenMember(ctx)
+	if err != nil {
+		errRedirect(w, r, "could not find your account. log in again.", "/login")
+
+		return
+	}
+
+	enrolled, err := h.authService.HasTwoFactor(ctx, member.ID)
+	if err != nil {
+		errRedirect(w, r, "could not check your two-factor settings. please try again.", "/")
+
+		return
+	}
+
+	if enrolled {
+		verified := h.sessionManager.GetString(ctx, middlewares.TwoFactorSessionKey) == member.ID.String()
+
+		TwoFactorSettings(*member, verified).Render(ctx, w)
+
+		return
+	}
+
+	enrolment, err := h.authService.BeginTOTPEnrolment(ctx, *member)
+	if err != nil {
+		errRedirect(w, r, "could not start two-factor setup. please try again.", "/")
+
+		return
+	}
+
+	qr := "data:image/png;base64," + base64.StdEncoding.EncodeToString(enrolment.QRCode)
+
+	// The secret is on the page in plain text, for a device that cannot scan.
+	w.Header().Set("Cache-Control", "no-store")
+
+	TwoFactorEnrol(*member, qr, enrolment.Secret).Render(ctx, w)
+}
+
+// confirmTwoFactor turns two-factor on and shows the recovery codes, the only
+// time they are ever shown. The code just given counts as this session's second
+// factor.
+func (h AuthHandlers) confirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, err := h.tokenMember(ctx)
+	if err != nil {
+		errRedirect(w, r, "could not find your account. log in again.", "/login")
+
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTPEnrolment(ctx, *member, r.FormValue("code"))
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		// Back to the same page, which now shows a new secret: scanning again is
+		// the fix for a code that will not match.
+		errRedirect(w, r, "that code did not match. scan the new code and try again.", middlewares.TwoFactorPath)
+
+		return
+	case errors.Is(err, auth.ErrTwoFactorNotEnrolled), errors.Is(err, auth.ErrTwoFactorAlreadyEnrolled):
+		http.Redirect(w, r, middlewares.TwoFactorPath, http.StatusFound)
+
+		return
+	case err != nil:
+		errRedirect(w, r, "could not turn on two-factor. please try again.", middlewares.TwoFactorPath)
+
+		return
+	}
+
+	if err = h.sessionManager.RenewToken(ctx); err != nil {
+		errRedirect(w, r, "two-factor is on, but could not update this session. log in again.", "/login")
+
+		return
+	}
+
+	h.sessionManager.Put(ctx, middlewares.TwoFactorSessionKey, member.ID.String())
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	TwoFactorRecoveryCodes(*member, codes).Render(ctx, w)
+}
+
+// verifyTwoFactor is the step up for a session that logged in before the member
+// had two-factor, or before it was asked of them.
+func (h AuthHandlers) verifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, err := h.tokenMember(ctx)
+	if err != nil {
+		errRedirect(w, r, "could not find your account. log in again.", "/login")
+
+		return
+	}
+
+	err = h.authService.VerifySecondFactor(ctx, *member, r.FormValue("code"))
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		errRedirect(w, r, "that code is not valid. try the next one your app shows.", middlewares.TwoFactorPath)
+
+		return
+	case errors.Is(err, auth.ErrTwoFactorLocked):
+		h.endSession(w, r)
+		errRedirect(w, r, lockedMessage, "/login")
+
+		return
+	case err != nil:
+		errRedirect(w, r, "could not check that code. please try again.", middlewares.TwoFactorPath)
+
+		return
+	}
+
+	if err = h.sessionManager.RenewToken(ctx); err != nil {
+		errRedirect(w, r, "could not update this session. log in again.", "/login")
+
+		return
+	}
+
+	h.sessionManager.Put(ctx, middlewares.TwoFactorSessionKey, member.ID.String())
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// disableTwoFactor turns it off, given a code: a session left open on a shared
+// computer should not be enough to take the second factor away.
+func (h AuthHandlers) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, err := h.tokenMember(ctx)
+	if err != nil {
+		errRedirect(w, r, "could not find your account. log in again.", "/login")
+
+		return
+	}
+
+	err = h.authService.VerifySecondFactor(ctx, *member, r.FormValue("code"))
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		errRedirect(w, r, "that code is not valid, so two-factor is still on.", middlewares.TwoFactorPath)
+
+		return
+	case errors.Is(err, auth.ErrTwoFactorLocked):
+		// Somebody guessing from an open session is the case this form exists for,
+		// so the session goes too, not only the chance to guess.
+		h.endSession(w, r)
+		errRedirect(w, r, lockedMessage, "/login")
+
+		return
+	case err != nil:
+		errRedirect(w, r, "could not check that code. please try again.", middlewares.TwoFactorPath)
+
+		return
+	}
+
+	if err = h.authService.DisableTwoFactor(ctx, *member, *member); err != nil {
+		errRedirect(w, r, "could not turn off two-factor. please try again.", middlewares.TwoFactorPath)
+
+		return
+	}
+
+	h.sessionManager.Remove(ctx, middlewares.TwoFactorSessionKey)
+
+	http.Redirect(w, r, middlewares.TwoFactorPath, http.StatusFound)
+}
+
+// tokenMember is the member the verified token was issued to. The token rather
+// than the session's copy, because the session outlives a logout and the token
+// is what RequireTwoFactor compares against.
+func (h AuthHandlers) tokenMember(ctx context.Context) (*members.Member, error) {
+	token, ok := middlewares.TokenFromContext(ctx)
+	if !ok {
+		return nil, errors.New("no token on the request")
+	}
+
+	memberID, _ := token.PrivateClaims()["custom:member_id"].(string)
+
+	id, err := uuid.Parse(memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.memberService.GetMemberByID(ctx, id)
+}
@@ -0,0 +1,140 @@
+package authweb
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"boardfund/pg"
+	"boardfund/service/auth"
+	authstore "boardfund/service/auth/store"
+	"boardfund/service/members"
+	memberstore "boardfund/service/members/store"
+	"boardfund/web/middlewares"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// twoFactorRig serves the step-up and disable forms against a real database,
+// each request in a session of its own: the guesses being counted are the ones
+// no single session would see.
+func twoFactorRig(t *testing.T) (func(path string, memberID uuid.UUID, code string) *httptest.ResponseRecorder, *auth.AuthService, *pgxpool.Pool) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	sessions := scs.New()
+
+	authService := auth.NewAuthService(memberstore.NewMemberStore(pool), authstore.NewAuthStore(pool), nil, nil, logger)
+
+	handlers := AuthHandlers{
+		authService:    authService,
+		memberService:  members.NewMemberService(memberstore.NewMemberStore(pool), nil, nil, nil, logger),
+		sessionManager: sessions,
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("POST /account/two-factor/verify", handlers.verifyTwoFactor)
+	router.HandleFunc("POST /account/two-factor/disable", handlers.disableTwoFactor)
+
+	return func(path string, memberID uuid.UUID, code string) *httptest.ResponseRecorder {
+		verify := func(string) (jwt.Token, error) {
+			return jwt.NewBuilder().Claim("custom:member_id", memberID.String()).Build()
+		}
+
+		withToken := middlewares.Verify(verify, func(*http.Request) string { return "token" })
+
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(url.Values{"code": {code}}.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		recorder := httptest.NewRecorder()
+		sessions.LoadAndSave(withToken(router.ServeHTTP)).ServeHTTP(recorder, request)
+
+		return recorder
+	}, authService, pool
+}
+
+// enrolledMember is a member with two-factor on, and the secret to make codes
+// from.
+func enrolledMember(t *testing.T, pool *pgxpool.Pool, authService *auth.AuthService) (members.Member, string) {
+	t.Helper()
+
+	ctx := context.Background()
+	member := members.Member{ID: uuid.New(), BCOName: "member-" + uuid.NewString()[:8]}
+
+	_, err := pool.Exec(ctx, `INSERT INTO member (id, email, bco_name, active) VALUES ($1, $2, $3, true)`,
+		member.ID, member.BCOName+"@example.org", member.BCOName)
+	require.NoError(t, err)
+
+	enrolment, err := authService.BeginTOTPEnrolment(ctx, member)
+	require.NoError(t, err)
+
+	// The previous step, so that the current one is left for the test to use.
+	code, err := totp.GenerateCode(enrolment.Secret, time.Now().Add(-30*time.Second))
+	require.NoError(t, err)
+
+	_, err = authService.ConfirmTOTPEnrolment(ctx, member, code)
+	require.NoError(t, err)
+
+	return member, enrolment.Secret
+}
+
+// errorMessage is the message a redirect to the error page carries.
+func errorMessage(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	require.NoError(t, err)
+
+	return location.Query().Get("msg")
+}
+
+// A session is all it takes to reach either form, so a guess at either counts
+// against the member. The guess that reaches the limit is refused and logs the
+// browser out, and after it not even the right code is taken.
+func TestWrongCodesAtTheStepUpAndDisableFormsRunOut(t *testing.T) {
+	post, authService, pool := twoFactorRig(t)
+
+	for _, path := range []string{"/account/two-factor/verify", "/account/two-factor/disable"} {
+		t.Run(path, func(t *testing.T) {
+			member, secret := enrolledMember(t, pool, authService)
+
+			for i := 1; i < 5; i++ {
+				recorder := post(path, member.ID, "000000")
+				require.Equal(t, http.StatusFound, recorder.Code)
+				require.Contains(t, errorMessage(t, recorder), "not valid", "wrong code %d", i)
+			}
+
+			recorder := post(path, member.ID, "000000")
+			require.Equal(t, lockedMessage, errorMessage(t, recorder), "the fifth wrong code should lock the member out")
+			require.Contains(t, recorder.Header().Get("Set-Cookie"), "access-token=;", "and end the session it came from")
+
+			code, err := totp.GenerateCode(secret, time.Now())
+			require.NoError(t, err)
+
+			recorder = post(path, member.ID, code)
+			require.Equal(t, lockedMessage, errorMessage(t, recorder), "the right code is not checked while locked")
+
+			enrolled, err := authService.HasTwoFactor(context.Background(), member.ID)
+			require.NoError(t, err)
+			require.True(t, enrolled, "a locked member's two-factor stays on")
+		})
+	}
+}
@@ -143,3 +143,24 @@ func TestTileColourFollowsState(t *testing.T) {
 		t.Error("tiles still alternate by position")
 	}
 }
+
+// The amount form goes where cancel goes, and for the same reason only there: a
+// donation with no subscription has nothing to revise. A closed fund makes no new
+// plans, so its donors are not offered a change it would refuse.
+func TestOnlyAnOpenFundsLiveDonationOffersANewAmount(t *testing.T) {
+	live := donations.NewMemberDonation(donations.MemberDonationRow{
+		ID: uuid.New(), FundName: "human fund", FundActive: true,
+		Active: true, Recurring: true, HasSubscription: true,
+	})
+
+	if html := renderRow(t, live, ""); !strings.Contains(html, `action="/donation/amount/`+live.ID.String()+`"`) {
+		t.Error("a live recurring donation should offer a new amount")
+	}
+
+	closed := live
+	closed.FundActive = false
+
+	if strings.Contains(renderRow(t, closed, ""), "change amount") {
+		t.Error("a closed fund's donation should not offer a new amount")
+	}
+}
@@ -0,0 +1,38 @@
+package homeweb
+
+import (
+	"strings"
+	"testing"
+
+	"boardfund/service/donations"
+	"boardfund/service/members"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Both layouts carry the bar, like the picture: the table for wide screens and
+// the cards for narrow ones are both in the markup.
+func TestTheFundListShowsHowFarAFundIsTowardsItsGoal(t *testing.T) {
+	fund := donations.Fund{
+		ID: uuid.New(), Name: "human fund", GoalCents: 10000, CloseOnGoal: true,
+		Stats: donations.FundStats{TotalDonated: 2500},
+	}
+
+	html := render(t, Funds([]donations.Fund{fund}, nil, nil, nil, &members.Member{}, "/"))
+
+	require.Equal(t, 2, strings.Count(html, `<progress`))
+	require.Contains(t, html, `value="25"`)
+	require.Contains(t, html, "25% of goal")
+	require.Contains(t, html, "closes when reached")
+}
+
+// An empty bar under a fund with no goal would read as a fund nobody has given
+// to.
+func TestAFundWithNoGoalHasNoBar(t *testing.T) {
+	fund := donations.Fund{ID: uuid.New(), Name: "human fund", Stats: donations.FundStats{TotalDonated: 2500}}
+
+	html := render(t, Funds([]donations.Fund{fund}, nil, nil, nil, &members.Member{}, "/"))
+
+	require.NotContains(t, html, "<progress")
+}
@@ -1,6 +1,7 @@
 package homeweb
 
 import (
+	"boardfund/providers"
 	"boardfund/service/donations"
 	"boardfund/service/enrollments"
 	"boardfund/service/fundevents"
@@ -17,6 +18,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -24,6 +26,8 @@ import (
 
 const internalErrMessage = "internal error"
 
+const declinedMessage = "your payment was declined and nothing was taken. please try again with another card or account."
+
 // publicEvents is the fund timeline, public projection only.
 //
 // Narrowed to the one method deliberately. Everything in this package renders
@@ -58,6 +62,9 @@ type FundHandlers struct {
 	withAuth        func(http.HandlerFunc) http.HandlerFunc
 	logger          *slog.Logger
 	clientID        string
+	// publicURL is where PayPal sends a donor back to after approving a new
+	// amount. Absolute, because the donor is returning from another site.
+	publicURL string
 }
 
 func NewFundHandlers(
@@ -69,6 +76,7 @@ func NewFundHandlers(
 	withAuth func(http.HandlerFunc) http.HandlerFunc,
 	logger *slog.Logger,
 	clientID string,
+	publicURL string,
 ) *FundHandlers {
 	return &FundHandlers{
 		donationService: donationService,
@@ -79,6 +87,7 @@ func NewFundHandlers(
 		withAuth:        withAuth,
 		logger:          logger,
 		clientID:        clientID,
+		publicURL:       strings.TrimSuffix(publicURL, "/"),
 	}
 }
 
@@ -88,6 +97,8 @@ func (h *FundHandlers) Register(r *mux.Router) {
 	r.HandleFunc("/donation/plan/complete", h.withAuth(h.completeRecurringDonation))
 	r.HandleFunc("/donation/once/complete", h.withAuth(h.completeOneTimeDonation))
 	r.HandleFunc("/donation/once/initiate", h.withAuth(h.initiateOneTimeDonation))
+	r.HandleFunc("GET /donation/stripe/once", h.withAuth(h.completeStripeDonation))
+	r.HandleFunc("GET /donation/stripe/plan", h.withAuth(h.completeStripeSubscription))
 	r.HandleFunc("/donation/success", h.withAuth(h.donationSuccess))
 	r.HandleFunc("GET /donations", h.withAuth(h.myDonations))
 	// No auth: it is shown on pages that have it, and would be a broken box on any
@@ -96,6 +107,8 @@ func (h *FundHandlers) Register(r *mux.Router) {
 	r.HandleFunc("POST /fund/{fundId}/note", h.withAuth(h.saveFundNote))
 	r.HandleFunc("POST /fund/{fundId}/note/remove", h.withAuth(h.removeOwnFundNote))
 	r.HandleFunc("POST /donation/cancel/{id}", h.withAuth(h.cancelMyDonation))
+	r.HandleFunc("POST /donation/amount/{id}", h.withAuth(h.changeMyDonationAmount))
+	r.HandleFunc("GET /donation/amount/{id}/confirm", h.withAuth(h.confirmMyDonationAmount))
 	r.HandleFunc("/donate/{fundId}", h.withAuth(h.donate))
 	r.HandleFunc("/error", h.error)
 	r.HandleFunc("/ping", h.ping)
@@ -185,7 +198,13 @@ func (h *FundHandlers) initiateOneTimeDonation(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	providerPaymentID, err := h.donationService.InitiateDonation(ctx, fundUUID, int32(amountCents))
+	// Only PayPal's button calls this: a provider with its own checkout page was
+	// sent there by createOneTimeDonation instead.
+	checkout, err := h.donationService.InitiateDonation(ctx, donations.DonationStart{
+		FundID:       fundUUID,
+		AmountCents:  int32(amountCents),
+		ProviderName: providers.PayPal,
+	})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
@@ -193,7 +212,7 @@ func (h *FundHandlers) initiateOneTimeDonation(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	sendJSON(w, http.StatusOK, initDonationResponse{ProviderOrderID: providerPaymentID})
+	sendJSON(w, http.StatusOK, initDonationResponse{ProviderOrderID: checkout.ID})
 }
 
 type initDonationResponse struct {
@@ -252,6 +271,26 @@ func (h *FundHandlers) createOneTimeDonation(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if provider := r.FormValue("provider"); provider != "" && provider != providers.PayPal {
+		checkout, errCheckout := h.donationService.InitiateDonation(ctx, donations.DonationStart{
+			FundID:       fundUUID,
+			AmountCents:  amountCents,
+			ProviderName: provider,
+			ReturnURL:    h.publicURL + "/donation/stripe/once?fund=" + fundUUID.String(),
+			CancelURL:    h.publicURL + "/donate/" + fundUUID.String(),
+		})
+		if errCheckout != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
+
+			return
+		}
+
+		w.Header().Set("HX-Redirect", checkout.RedirectURL)
+
+		return
+	}
+
 	fund, err := h.donationService.GetFundByID(ctx, fundUUID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -309,7 +348,7 @@ func (h *FundHandlers) donate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("HX-Redirect", r.URL.Path)
-	Fund(*fund, fund.Stats, notes, h.recipientsFor(ctx, *fund), image, &member, r.URL.Path).Render(ctx, w)
+	Fund(*fund, fund.Stats, notes, h.recipientsFor(ctx, *fund), image, h.donationService.PaymentProviders(), &member, r.URL.Path).Render(ctx, w)
 }
 
 // saveFundNote writes or replaces the signed-in donor's note on a fund.
@@ -555,6 +594,116 @@ func (h *FundHandlers) renderCancelError(ctx context.Context, w http.ResponseWri
 	h.renderDonationRow(ctx, w, member, donationID, message)
 }
 
+// changeMyDonationAmount sends the donor to PayPal to approve a new amount for
+// their subscription.
+//
+// A plain form post and a full redirect rather than htmx: the next page is
+// PayPal's, and a swap has nowhere to put it.
+func (h *FundHandlers) changeMyDonationAmount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/login")
+
+		return
+	}
+
+	donationID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		common.ErrorMessage(&member, "that is not a donation id", "/donations", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	// Whole dollars, as the donate form takes them. A plan's name is built from
+	// the dollar figure, and the catalogue has no plan for $12.50.
+	amountCents, err := wholeDollarsToCents(r.FormValue("amount"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		common.ErrorMessage(&member, "the amount must be a whole number of dollars", "/donations", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	amountPath := h.publicURL + "/donation/amount/" + donationID.String()
+
+	next, err := h.donationService.ChangeDonationAmount(ctx, donations.AmountChange{
+		DonationID:  donationID,
+		MemberID:    member.ID,
+		AmountCents: amountCents,
+		ReturnURL:   amountPath + "/confirm",
+		CancelURL:   h.publicURL + "/donations",
+	})
+	if err != nil {
+		h.amountFailed(ctx, w, r, member, donationID, err)
+
+		return
+	}
+
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// confirmMyDonationAmount is where PayPal returns a donor who approved a new
+// amount. The donation is moved to whatever plan PayPal now says the
+// subscription is on, and the donor is shown their donations.
+func (h *FundHandlers) confirmMyDonationAmount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		common.Redirect(w, r, "/login")
+
+		return
+	}
+
+	donationID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		common.ErrorMessage(&member, "that is not a donation id", "/donations", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	if err = h.donationService.ConfirmDonationAmount(ctx, donationID, member.ID); err != nil {
+		h.amountFailed(ctx, w, r, member, donationID, err)
+
+		return
+	}
+
+	http.Redirect(w, r, "/donations", http.StatusSeeOther)
+}
+
+// amountFailed reports a failed amount change. The same rule as cancelFailed:
+// each case in its own words, and a PayPal failure says plainly what the donor is
+// still paying.
+func (h *FundHandlers) amountFailed(ctx context.Context, w http.ResponseWriter, r *http.Request,
+	member members.Member, donationID uuid.UUID, err error) {
+	status, message := http.StatusInternalServerError,
+		"we could not change this donation with PayPal, so it is still running at the old amount. please try again."
+
+	switch {
+	case errors.Is(err, donations.ErrDonationNotYours):
+		status, message = http.StatusNotFound, "no such donation"
+	case errors.Is(err, donations.ErrDonationNotChangeable):
+		status, message = http.StatusBadRequest, "the amount of that donation cannot be changed"
+	case errors.Is(err, donations.ErrInvalidAmount),
+		errors.Is(err, donations.ErrAmountUnchanged),
+		errors.Is(err, donations.ErrFundClosed),
+		errors.Is(err, donations.ErrSubscriptionNotActive):
+		status, message = http.StatusBadRequest, err.Error()
+	default:
+		h.logger.ErrorContext(ctx, "failed to change donation amount",
+			slog.String("donation_id", donationID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	w.WriteHeader(status)
+	common.ErrorMessage(&member, message, "/donations", r.URL.Path).Render(ctx, w)
+}
+
 // renderDonationRow redraws one donation, optionally carrying a failure.
 //
 // Re-read rather than reused, so the row reflects what the server did. When the
@@ -708,9 +857,19 @@ func (h *FundHandlers) completeOneTimeDonation(w http.ResponseWriter, r *http.Re
 		FundID:            fundUUID,
 		ProviderOrderID:   orderID,
 		ProviderPaymentID: paymentID,
+		ProviderName:      providers.PayPal,
 	}
 
 	err = h.donationService.CompleteDonation(ctx, member.ID, completion)
+	if errors.Is(err, donations.ErrPaymentDeclined) {
+		// Nothing was taken, and another card may well work. The page shows this
+		// as it is, so it has to read as an invitation to try again.
+		w.WriteHeader(http.StatusPaymentRequired)
+		w.Write([]byte(declinedMessage))
+
+		return
+	}
+
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
@@ -825,6 +984,7 @@ func (h *FundHandlers) completeRecurringDonation(w http.ResponseWriter, r *http.
 		FundID:                 fundUUID,
 		ProviderOrderID:        orderID,
 		ProviderSubscriptionID: providerSubscriptionID,
+		ProviderName:           providers.PayPal,
 	}
 
 	err = h.donationService.CompleteRecurringDonation(ctx, member.ID, completion)
@@ -909,11 +1069,21 @@ func (h *FundHandlers) createDonationPlan(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	count, err := parseIntervalCount(r.FormValue("interval_count"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		common.ErrorMessage(&member, donations.ErrInvalidInterval.Error(), "/", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
 	plan := donations.CreatePlan{
-		FundID:       fundUUID,
-		Name:         fmt.Sprintf("%d-%s", amountInt, interval),
-		AmountCents:  int32(amountInt * 100),
-		IntervalUnit: donations.IntervalUnit(interval),
+		FundID:        fundUUID,
+		Name:          fmt.Sprintf("%d-%d-%s", amountInt, count, interval),
+		AmountCents:   int32(amountInt * 100),
+		IntervalUnit:  donations.IntervalUnit(interval),
+		IntervalCount: count,
+		ProviderName:  r.FormValue("provider"),
 	}
 
 	fund, err := h.donationService.GetFundByID(ctx, fundUUID)
@@ -924,19 +1094,139 @@ func (h *FundHandlers) createDonationPlan(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	plan.ProviderFundID = fund.ProviderID
-
 	newPlan, err := h.donationService.CreateDonationPlan(ctx, plan)
 	if err != nil {
+		if errors.Is(err, donations.ErrInvalidInterval) || errors.Is(err, donations.ErrFundClosed) {
+			w.WriteHeader(http.StatusBadRequest)
+			common.ErrorMessage(&member, err.Error(), "/", r.URL.Path).Render(ctx, w)
+
+			return
+		}
+
 		w.WriteHeader(http.StatusInternalServerError)
 		common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
 
 		return
 	}
 
+	if newPlan.ProviderName != "" && newPlan.ProviderName != providers.PayPal {
+		checkout, errCheckout := h.donationService.InitiateSubscription(ctx, *newPlan,
+			h.publicURL+"/donation/stripe/plan?plan="+newPlan.ID.String()+"&fund="+fundUUID.String(),
+			h.publicURL+"/donate/"+fundUUID.String())
+		if errCheckout != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
+
+			return
+		}
+
+		w.Header().Set("HX-Redirect", checkout.RedirectURL)
+
+		return
+	}
+
 	PaypalSubscription(*newPlan, h.clientID, fund.Name).Render(ctx, w)
 }
 
+// completeStripeDonation is where Stripe's checkout sends a donor back after a
+// one-time payment.
+//
+// The session id is all the request carries, and all that is needed: the
+// amount, the payment and the fund it was for are read back from Stripe, and a
+// session for another fund is refused. A donor who reloads this page finds the
+// donation already recorded, which is not an error.
+func (h *FundHandlers) completeStripeDonation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		common.ErrorMessage(nil, "unauthorized", "/", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	fundUUID, err := uuid.Parse(r.URL.Query().Get("fund"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		h.logger.ErrorContext(ctx, "missing session id")
+
+		w.WriteHeader(http.StatusBadRequest)
+		common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	err = h.donationService.CompleteDonation(ctx, member.ID, donations.OneTimeCompletion{
+		FundID:          fundUUID,
+		ProviderOrderID: sessionID,
+		ProviderName:    providers.Stripe,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	http.Redirect(w, r, "/donation/success?fund="+fundUUID.String(), http.StatusSeeOther)
+}
+
+// completeStripeSubscription is where Stripe's checkout sends a donor back
+// after subscribing. The subscription is found through the session, and checked
+// against the plan and fund named here as PayPal's is.
+func (h *FundHandlers) completeStripeSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	member, ok := h.sessionManager.Get(ctx, "member").(members.Member)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		common.ErrorMessage(nil, "unauthorized", "/", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	planUUID, errPlan := uuid.Parse(query.Get("plan"))
+	fundUUID, errFund := uuid.Parse(query.Get("fund"))
+	sessionID := query.Get("session_id")
+
+	if errPlan != nil || errFund != nil || sessionID == "" {
+		h.logger.ErrorContext(ctx, "incomplete return from stripe checkout",
+			slog.String("plan", query.Get("plan")),
+			slog.String("fund", query.Get("fund")),
+		)
+
+		w.WriteHeader(http.StatusBadRequest)
+		common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	err := h.donationService.CompleteRecurringDonation(ctx, member.ID, donations.RecurringCompletion{
+		PlanID:          uuid.NullUUID{UUID: planUUID, Valid: true},
+		FundID:          fundUUID,
+		ProviderOrderID: sessionID,
+		ProviderName:    providers.Stripe,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		common.ErrorMessage(&member, internalErrMessage, "/", r.URL.Path).Render(ctx, w)
+
+		return
+	}
+
+	http.Redirect(w, r, "/donation/success?fund="+fundUUID.String(), http.StatusSeeOther)
+}
+
 func (h *FundHandlers) home(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -1076,6 +1366,22 @@ func sendJSON(w http.ResponseWriter, status int, v any) {
 	json.NewEncoder(w).Encode(v)
 }
 
+// parseIntervalCount reads how many of the chosen unit the donor is billed
+// every. Left empty it is one, which is what the form always meant before it
+// asked. Whether the count suits the unit is the service's to say.
+func parseIntervalCount(value string) (int32, error) {
+	if value == "" {
+		return 1, nil
+	}
+
+	count, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(count), nil
+}
+
 func dollarStringToCents(dollars string) (int32, error) {
 	dollars = strings.TrimSpace(dollars)
 
@@ -1095,8 +1401,10 @@ func dollarStringToCents(dollars string) (int32, error) {
 		fractionalPart = fractionalPart[:2]
 	}
 
+	// Parsed at 32 bits so a figure too large for the column is refused here
+	// rather than wrapping to a negative one.
 	combinedAmount := integerPart + fractionalPart
-	amountInCents, err := strconv.Atoi(combinedAmount)
+	amountInCents, err := strconv.ParseInt(combinedAmount, 10, 32)
 	if err != nil {
 		return 0, fmt.Errorf("invalid dollar amount format: %s", dollars)
 	}
@@ -1104,6 +1412,22 @@ func dollarStringToCents(dollars string) (int32, error) {
 	return int32(amountInCents), nil
 }
 
+// wholeDollarsToCents is dollarStringToCents for a form that takes whole
+// dollars only, with the same bound: anything that would not fit in cents is
+// refused rather than wrapped.
+func wholeDollarsToCents(dollars string) (int32, error) {
+	amount, err := strconv.ParseInt(strings.TrimSpace(dollars), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid dollar amount format: %s", dollars)
+	}
+
+	if amount > math.MaxInt32/100 || amount < math.MinInt32/100 {
+		return 0, fmt.Errorf("dollar amount out of range: %s", dollars)
+	}
+
+	return int32(amount * 100), nil
+}
+
 // fundImage serves a fund's picture.
 //
 // Public and unauthenticated, like the pages that show it: an image behind a
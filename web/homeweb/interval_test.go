@@ -0,0 +1,70 @@
+package homeweb
+
+import (
+	"strings"
+	"testing"
+
+	"boardfund/service/donations"
+)
+
+// A donor to a biweekly fund giving once per payout is billed every two weeks,
+// and the form starts there rather than leaving it to be worked out.
+func TestTheFormStartsOnTheFundsOwnCycle(t *testing.T) {
+	html := render(t, Frequency(donations.PayoutFrequencyBiweekly))
+
+	if !strings.Contains(html, `value="2"`) {
+		t.Errorf("the count should start at the fund's two weeks:\n%s", html)
+	}
+
+	if !strings.Contains(html, `value="WEEK" selected`) {
+		t.Errorf("weeks should be the unit chosen:\n%s", html)
+	}
+}
+
+// Yearly giving is offered alongside weeks and months, whatever the fund's own
+// cycle.
+func TestEveryUnitIsOffered(t *testing.T) {
+	html := render(t, Frequency(donations.PayoutFrequencyMonthly))
+
+	for _, unit := range []string{"WEEK", "MONTH", "YEAR"} {
+		if !strings.Contains(html, `value="`+unit+`"`) {
+			t.Errorf("%s is not offered", unit)
+		}
+	}
+}
+
+// A fund paying out every day has no cycle a donor could be billed on, and the
+// form falls back to a month.
+func TestADailyFundStartsOnAMonth(t *testing.T) {
+	unit, count := defaultInterval(donations.PayoutFrequencyDaily)
+	if unit != donations.IntervalUnitMonth || count != 1 {
+		t.Errorf("got %s x %d, want MONTH x 1", unit, count)
+	}
+}
+
+func TestParseIntervalCount(t *testing.T) {
+	cases := []struct {
+		value string
+		count int32
+		ok    bool
+	}{
+		{"", 1, true},
+		{"1", 1, true},
+		{"3", 3, true},
+		{"two", 0, false},
+		{"99999999999", 0, false},
+	}
+
+	for _, c := range cases {
+		count, err := parseIntervalCount(c.value)
+		if (err == nil) != c.ok {
+			t.Errorf("parseIntervalCount(%q) error = %v, want ok %v", c.value, err, c.ok)
+
+			continue
+		}
+
+		if count != c.count {
+			t.Errorf("parseIntervalCount(%q) = %d, want %d", c.value, count, c.count)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package homeweb
 
 import (
 	"math"
+	"strconv"
 	"testing"
 )
 
@@ -49,3 +50,29 @@ func TestFormattersAgreeOnSharedRange(t *testing.T) {
 		}
 	}
 }
+
+// A new amount is multiplied into cents, and a large enough one used to wrap to
+// a negative figure on its way to PayPal. Past the largest whole dollar amount
+// that fits, it is refused instead.
+func TestAWholeDollarAmountTooLargeForCentsIsRefused(t *testing.T) {
+	largest := strconv.Itoa(math.MaxInt32 / 100)
+
+	cents, err := wholeDollarsToCents(largest)
+	if err != nil {
+		t.Fatalf("%s dollars should fit: %v", largest, err)
+	}
+
+	if cents != math.MaxInt32/100*100 {
+		t.Errorf("%s dollars = %d cents", largest, cents)
+	}
+
+	for _, amount := range []string{strconv.Itoa(math.MaxInt32/100 + 1), "50000000", "99999999999", "12.50", ""} {
+		if cents, err := wholeDollarsToCents(amount); err == nil {
+			t.Errorf("%q was accepted as %d cents", amount, cents)
+		}
+	}
+
+	if cents, err := dollarStringToCents("99999999.99"); err == nil {
+		t.Errorf("99999999.99 was accepted as %d cents", cents)
+	}
+}
@@ -72,7 +72,7 @@ func noteRig(t *testing.T) (func(fundID, form string) *httptest.ResponseRecorder
 		nil,
 		sessions,
 		func(next http.HandlerFunc) http.HandlerFunc { return next },
-		logger, "client",
+		logger, "client", "",
 	)
 
 	memberID := uuid.New()
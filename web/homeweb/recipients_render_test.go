@@ -24,7 +24,7 @@ func fundPage(t *testing.T, visible bool, recipients []enrollments.Recipient, no
 		Active: true, EnrolleesVisible: visible,
 	}
 
-	require.NoError(t, Fund(fund, donations.FundStats{}, notes, recipients, nil,
+	require.NoError(t, Fund(fund, donations.FundStats{}, notes, recipients, nil, nil,
 		&members.Member{}, "/donate/x").Render(context.Background(), &out))
 
 	return out.String()
@@ -3,22 +3,25 @@ package hooksweb
 import (
 	"boardfund/service/donations"
 	"boardfund/service/members"
+	"boardfund/web/hooksweb/store"
 	"boardfund/web/mux"
 	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
 type publisher interface {
-	Publish(event string, data []byte) error
+	PublishWebhook(event, transmissionID string, data []byte) error
 }
 
 // deliveries records which transmissions have already been accepted, so a replay
-// of a genuinely signed request is not handled twice.
+// of a genuinely signed request is not handled twice, and archives the ones that
+// are new.
 type deliveries interface {
-	RecordDelivery(ctx context.Context, transmissionID, eventType string) (bool, error)
+	RecordDelivery(ctx context.Context, delivery store.Delivery) (bool, error)
 }
 
 type WebhooksHandlers struct {
@@ -30,6 +33,12 @@ type WebhooksHandlers struct {
 	logger *slog.Logger
 
 	webhookID string
+	// stripeSecret is the Stripe endpoint's signing secret, set by
+	// AcceptStripeWebhooks. Empty means there is no Stripe endpoint at all.
+	stripeSecret string
+	// certs is the zero value, meaning PayPal, unless TrustCertificatesFrom said
+	// otherwise.
+	certs certSource
 }
 
 func NewWebhooksHandlers(donationService *donations.DonationService, memberService *members.MemberService, publisher publisher, deliveries deliveries, logger *slog.Logger, webhookID string) *WebhooksHandlers {
@@ -45,10 +54,19 @@ func NewWebhooksHandlers(donationService *donations.DonationService, memberServi
 
 func (h WebhooksHandlers) Register(r *mux.Router) {
 	r.HandleFunc("POST /webhooks", h.webhooks)
+
+	if h.stripeSecret != "" {
+		r.HandleFunc("POST /webhooks/stripe", h.stripeWebhooks)
+	}
 }
 
 func (h WebhooksHandlers) webhooks(w http.ResponseWriter, r *http.Request) {
-	bodyBytes, err := verifySignature(r, h.webhookID, h.logger)
+	certs := h.certs
+	if certs.hosts == nil {
+		certs = paypalCerts
+	}
+
+	bodyBytes, checked, err := verifySignature(r, h.webhookID, certs, h.logger)
 	if err != nil {
 		// Being unable to check is not the same as checking and finding it invalid.
 		// A bad signature is settled and a retry would fail identically, so it is
@@ -73,7 +91,7 @@ func (h WebhooksHandlers) webhooks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.accept(r.Context(), w, r.Header.Get("paypal-transmission-id"), bodyBytes)
+	h.accept(r.Context(), w, r.Header.Get("paypal-transmission-id"), bodyBytes, checked)
 }
 
 // accept handles a request whose signature has already been checked.
@@ -82,7 +100,7 @@ func (h WebhooksHandlers) webhooks(w http.ResponseWriter, r *http.Request) {
 // a test would mean producing a signature that chains to a public root, which is
 // not something a test can do, and the decisions below -- replay, publish, what
 // to tell PayPal -- are the ones worth checking.
-func (h WebhooksHandlers) accept(ctx context.Context, w http.ResponseWriter, transmissionID string, body []byte) {
+func (h WebhooksHandlers) accept(ctx context.Context, w http.ResponseWriter, transmissionID string, body []byte, checked verification) {
 	var event webhookEvent
 
 	err := json.Unmarshal(body, &event)
@@ -109,19 +127,27 @@ func (h WebhooksHandlers) accept(ctx context.Context, w http.ResponseWriter, tra
 		return
 	}
 
-	// A valid signature says PayPal sent this. It does not say we have not
+	h.publish(ctx, w, event.delivery(transmissionID, body, checked), event.EventType, event.Resource)
+}
+
+// publish archives a verified delivery and puts it on the stream under subject,
+// answering the provider according to how that went. Shared by every provider:
+// how a request is verified and unwrapped differs, and what happens to it after
+// does not.
+func (h WebhooksHandlers) publish(ctx context.Context, w http.ResponseWriter, delivery store.Delivery, subject string, data []byte) {
+	// A valid signature says the provider sent this. It does not say we have not
 	// already handled it: a captured request replays for as long as its
-	// certificate verifies, and PayPal itself redelivers anything answered with a
-	// 5xx. Recorded before publishing, so a replay never reaches the stream or the
-	// handlers behind it.
+	// signature verifies, and the provider itself redelivers anything answered
+	// with a 5xx. Recorded before publishing, so a replay never reaches the stream
+	// or the handlers behind it.
 	//
 	// A failure here is not a reason to drop the event. It is a reason to be asked
 	// again, when the database is back.
-	fresh, err := h.deliveries.RecordDelivery(ctx, transmissionID, event.EventType)
+	fresh, err := h.deliveries.RecordDelivery(ctx, delivery)
 	if err != nil {
 		h.logger.ErrorContext(ctx, "failed to record webhook delivery, asking for redelivery",
 			slog.String("error", err.Error()),
-			slog.String("event_type", event.EventType),
+			slog.String("event_type", subject),
 		)
 
 		w.WriteHeader(http.StatusInternalServerError)
@@ -131,8 +157,8 @@ func (h WebhooksHandlers) accept(ctx context.Context, w http.ResponseWriter, tra
 
 	if !fresh {
 		h.logger.InfoContext(ctx, "ignoring a webhook we have already accepted",
-			slog.String("event_type", event.EventType),
-			slog.String("transmission_id", transmissionID),
+			slog.String("event_type", subject),
+			slog.String("transmission_id", delivery.TransmissionID),
 		)
 
 		w.WriteHeader(http.StatusOK)
@@ -140,13 +166,13 @@ func (h WebhooksHandlers) accept(ctx context.Context, w http.ResponseWriter, tra
 		return
 	}
 
-	err = h.publisher.Publish(event.EventType, []byte(event.Resource))
+	err = h.publisher.PublishWebhook(subject, delivery.TransmissionID, data)
 	if err != nil {
 		// Fail loudly so the provider redelivers. Answering 200 here drops the event
 		// permanently, and nothing replays it.
 		h.logger.ErrorContext(ctx, "failed to publish event",
 			slog.String("error", err.Error()),
-			slog.String("event_type", event.EventType),
+			slog.String("event_type", subject),
 		)
 
 		w.WriteHeader(http.StatusInternalServerError)
@@ -154,12 +180,52 @@ func (h WebhooksHandlers) accept(ctx context.Context, w http.ResponseWriter, tra
 		return
 	}
 
-	h.logger.InfoContext(ctx, "received webhook event", slog.String("event_type", event.EventType))
+	h.logger.InfoContext(ctx, "received webhook event", slog.String("event_type", subject))
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// webhookEvent is PayPal's envelope. Only the resource is published; the rest is
+// kept in the archive, where it is what a search is run against.
 type webhookEvent struct {
-	EventType string          `json:"event_type"`
-	Resource  json.RawMessage `json:"resource"`
+	ID           string          `json:"id"`
+	EventType    string          `json:"event_type"`
+	ResourceType string          `json:"resource_type"`
+	Summary      string          `json:"summary"`
+	CreateTime   string          `json:"create_time"`
+	Resource     json.RawMessage `json:"resource"`
+}
+
+// delivery is the event as the archive keeps it.
+//
+// The resource id and the creation time are read leniently. Neither decides
+// anything -- they are ways to find the event again -- so a resource that is not
+// an object, or a time in a format PayPal has not used before, costs the search
+// a field and not the archive an event.
+func (e webhookEvent) delivery(transmissionID string, body []byte, checked verification) store.Delivery {
+	var resource struct {
+		ID string `json:"id"`
+	}
+
+	_ = json.Unmarshal(e.Resource, &resource)
+
+	createdAt, _ := time.Parse(time.RFC3339Nano, e.CreateTime)
+
+	delivery := store.Delivery{
+		TransmissionID: transmissionID,
+		EventID:        e.ID,
+		EventType:      e.EventType,
+		ResourceType:   e.ResourceType,
+		ResourceID:     resource.ID,
+		Summary:        e.Summary,
+		Payload:        body,
+		CertURL:        checked.certURL,
+		CreatedAt:      createdAt,
+	}
+
+	if checked.chainErr != nil {
+		delivery.ChainError = checked.chainErr.Error()
+	}
+
+	return delivery
 }
@@ -0,0 +1,67 @@
+package hooksweb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boardfund/paypal/paypaltest"
+)
+
+// The whole of verification, end to end, for once: a signature over the real
+// message, a certificate fetched over TLS from the host the header names, and
+// the event published at the end of it.
+func TestAWebhookFromTheStandInIsVerifiedAndPublished(t *testing.T) {
+	paypal := paypaltest.NewServer(t)
+
+	pub := &stubPublisher{}
+	handlers := newTestHandlers(pub)
+	handlers.webhookID = paypaltest.WebhookID
+	handlers.TrustCertificatesFrom(CertSource{
+		Host:     paypal.CertHost(),
+		Client:   paypal.CertClient(),
+		CacheDir: t.TempDir(),
+	})
+
+	target := httptest.NewServer(http.HandlerFunc(handlers.webhooks))
+	defer target.Close()
+
+	sale := paypaltest.Sale{ID: "SALE-1", SubscriptionID: "I-1", AmountCents: 2500, FeeCents: 136}
+
+	status, err := paypal.SendWebhook(context.Background(), target.URL, "PAYMENT.SALE.COMPLETED", sale.CompletedResource())
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if status != http.StatusOK {
+		t.Errorf("answered %d", status)
+	}
+
+	if len(pub.published) != 1 || pub.published[0] != "PAYMENT.SALE.COMPLETED" {
+		t.Errorf("published %v, want the sale", pub.published)
+	}
+}
+
+// The stand-in signs properly, and that must not be enough. A handler that has
+// not been told to trust it sees a certificate URL that is not PayPal's.
+func TestAHandlerThatTrustsOnlyPayPalRefusesTheStandIn(t *testing.T) {
+	paypal := paypaltest.NewServer(t)
+
+	pub := &stubPublisher{}
+	handlers := newTestHandlers(pub)
+	handlers.webhookID = paypaltest.WebhookID
+
+	target := httptest.NewServer(http.HandlerFunc(handlers.webhooks))
+	defer target.Close()
+
+	sale := paypaltest.Sale{ID: "SALE-1", AmountCents: 2500}
+
+	if _, err := paypal.SendWebhook(context.Background(), target.URL, "PAYMENT.SALE.COMPLETED", sale.CompletedResource()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if len(pub.published) != 0 {
+		t.Errorf("published %v from a certificate host that is not PayPal's", pub.published)
+	}
+}
@@ -1,35 +1,281 @@
-// Package store records which provider webhooks have already been accepted.
+// Package store records which provider webhooks have already been accepted, and
+// keeps what each of them carried.
 package store
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"boardfund/db"
+	"boardfund/messaging"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrEventNotFound is a transmission id with nothing archived under it.
+var ErrEventNotFound = errors.New("no archived webhook with that transmission id")
+
+// Delivery is a webhook whose signature has been checked, as it is to be kept.
+//
+// The envelope fields are copies out of Payload, so the archive can be searched
+// without reaching into it. Any of them may be empty: PayPal's envelope is not
+// the same shape for every event, and the payload is kept whatever it leaves out.
+type Delivery struct {
+	TransmissionID string
+	EventID        string
+	EventType      string
+	ResourceType   string
+	ResourceID     string
+	Summary        string
+	Payload        []byte
+
+	// CertURL is the certificate the signature was checked against, and
+	// ChainError why it did not chain to a trusted root -- empty when it did.
+	CertURL    string
+	ChainError string
+
+	// CreatedAt is when PayPal says the event happened. Zero when it did not say
+	// in a form we could read.
+	CreatedAt time.Time
+}
+
+// Outcome is what a consumer's attempt at an archived event came to.
+type Outcome string
+
+const (
+	OutcomeHandled Outcome = "handled"
+	// OutcomeFailed will be retried.
+	OutcomeFailed Outcome = "failed"
+	// OutcomeAbandoned was the last attempt, and the event is a dead letter now.
+	OutcomeAbandoned Outcome = "abandoned"
+)
+
+// EventFilter narrows a search of the archive. Empty fields match everything;
+// Until is exclusive.
+type EventFilter struct {
+	EventType  string
+	ResourceID string
+	From       time.Time
+	Until      time.Time
+	Limit      int32
+}
+
+// ArchivedEvent is one line of a search: the envelope, and how many attempts at
+// it went each way.
+type ArchivedEvent struct {
+	TransmissionID     string
+	EventType          string
+	ResourceType       string
+	ResourceID         string
+	Summary            string
+	CertificateChained bool
+	ReceivedAt         time.Time
+	Handled            int
+	Failed             int
+}
+
+// ArchivedEventDetail is one archived event in full, with every attempt the
+// consumers made at it in the order they happened.
+type ArchivedEventDetail struct {
+	TransmissionID     string
+	EventID            string
+	EventType          string
+	ResourceType       string
+	ResourceID         string
+	Summary            string
+	Payload            []byte
+	CertURL            string
+	CertificateChained bool
+	ChainError         string
+	CreatedAt          time.Time
+	ReceivedAt         time.Time
+	Outcomes           []EventOutcome
+}
+
+type EventOutcome struct {
+	Consumer   string
+	StreamSeq  int64
+	Delivery   int
+	Outcome    Outcome
+	Error      string
+	OccurredAt time.Time
+}
+
 type DeliveryStore struct {
 	queries *db.Queries
+	conn    *pgxpool.Pool
 }
 
 func NewDeliveryStore(conn *pgxpool.Pool) DeliveryStore {
-	return DeliveryStore{queries: db.New(conn)}
+	return DeliveryStore{queries: db.New(conn), conn: conn}
 }
 
-// RecordDelivery reports whether this transmission is one we have not seen.
+// RecordDelivery reports whether this transmission is one we have not seen, and
+// archives it when it is.
 //
 // False means a replay, or PayPal redelivering something we already accepted --
 // which is the same thing from here, and in both cases the event has already been
-// published to the stream and does not need publishing again.
-func (s DeliveryStore) RecordDelivery(ctx context.Context, transmissionID, eventType string) (bool, error) {
-	rows, err := s.queries.RecordWebhookDelivery(ctx, db.RecordWebhookDeliveryParams{
-		TransmissionID: transmissionID,
-		EventType:      eventType,
+// published to the stream and does not need publishing again. Nor archiving:
+// what is kept is the first copy, which is the one that was published.
+//
+// One transaction, so an event is never accepted without being archived. A
+// failure to archive fails the delivery, and PayPal is asked to send it again.
+func (s DeliveryStore) RecordDelivery(ctx context.Context, delivery Delivery) (bool, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	txQueries := s.queries.WithTx(tx)
+
+	rows, err := txQueries.RecordWebhookDelivery(ctx, db.RecordWebhookDeliveryParams{
+		TransmissionID: delivery.TransmissionID,
+		EventType:      delivery.EventType,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(rows) == 0 {
+		return false, nil
+	}
+
+	err = txQueries.ArchiveWebhookEvent(ctx, db.ArchiveWebhookEventParams{
+		TransmissionID:     delivery.TransmissionID,
+		EventID:            delivery.EventID,
+		EventType:          delivery.EventType,
+		ResourceType:       delivery.ResourceType,
+		ResourceID:         delivery.ResourceID,
+		Summary:            delivery.Summary,
+		Payload:            delivery.Payload,
+		CertUrl:            delivery.CertURL,
+		CertificateChained: delivery.ChainError == "",
+		ChainError:         pgtype.Text{String: delivery.ChainError, Valid: delivery.ChainError != ""},
+		CreatedAt:          pgtype.Timestamptz{Time: delivery.CreatedAt, Valid: !delivery.CreatedAt.IsZero()},
 	})
 	if err != nil {
 		return false, err
 	}
 
-	return len(rows) > 0, nil
+	if err = tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RecordOutcome files one consumer's attempt against the archived event it was
+// about. An attempt at an event from before the archive existed is dropped by
+// the query, not refused.
+func (s DeliveryStore) RecordOutcome(ctx context.Context, outcome messaging.Outcome) error {
+	kind := OutcomeHandled
+
+	switch {
+	case outcome.Err != nil && outcome.Final:
+		kind = OutcomeAbandoned
+	case outcome.Err != nil:
+		kind = OutcomeFailed
+	}
+
+	var errText pgtype.Text
+	if outcome.Err != nil {
+		errText = pgtype.Text{String: outcome.Err.Error(), Valid: true}
+	}
+
+	return s.queries.RecordWebhookOutcome(ctx, db.RecordWebhookOutcomeParams{
+		ID:             uuid.New(),
+		TransmissionID: outcome.TransmissionID,
+		Consumer:       outcome.Consumer,
+		StreamSeq:      int64(outcome.StreamSeq),
+		Delivery:       int32(outcome.Delivery),
+		Outcome:        db.WebhookOutcome(kind),
+		Error:          errText,
+	})
+}
+
+// SearchEvents is the archive newest first, narrowed by filter.
+func (s DeliveryStore) SearchEvents(ctx context.Context, filter EventFilter) ([]ArchivedEvent, error) {
+	rows, err := s.queries.SearchWebhookEvents(ctx, db.SearchWebhookEventsParams{
+		EventType:     pgtype.Text{String: filter.EventType, Valid: filter.EventType != ""},
+		ResourceID:    pgtype.Text{String: filter.ResourceID, Valid: filter.ResourceID != ""},
+		ReceivedFrom:  pgtype.Timestamptz{Time: filter.From, Valid: !filter.From.IsZero()},
+		ReceivedUntil: pgtype.Timestamptz{Time: filter.Until, Valid: !filter.Until.IsZero()},
+		RowLimit:      filter.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]ArchivedEvent, len(rows))
+	for i, row := range rows {
+		events[i] = ArchivedEvent{
+			TransmissionID:     row.TransmissionID,
+			EventType:          row.EventType,
+			ResourceType:       row.ResourceType,
+			ResourceID:         row.ResourceID,
+			Summary:            row.Summary,
+			CertificateChained: row.CertificateChained,
+			ReceivedAt:         row.ReceivedAt.Time,
+			Handled:            int(row.Handled),
+			Failed:             int(row.Failed),
+		}
+	}
+
+	return events, nil
+}
+
+// GetEvent is one archived event and its outcomes, or ErrEventNotFound.
+func (s DeliveryStore) GetEvent(ctx context.Context, transmissionID string) (*ArchivedEventDetail, error) {
+	row, err := s.queries.GetWebhookEvent(ctx, transmissionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes, err := s.queries.GetWebhookEventOutcomes(ctx, transmissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &ArchivedEventDetail{
+		TransmissionID:     row.TransmissionID,
+		EventID:            row.EventID,
+		EventType:          row.EventType,
+		ResourceType:       row.ResourceType,
+		ResourceID:         row.ResourceID,
+		Summary:            row.Summary,
+		Payload:            row.Payload,
+		CertURL:            row.CertUrl,
+		CertificateChained: row.CertificateChained,
+		ChainError:         row.ChainError.String,
+		CreatedAt:          row.CreatedAt.Time,
+		ReceivedAt:         row.ReceivedAt.Time,
+	}
+
+	for _, outcome := range outcomes {
+		event.Outcomes = append(event.Outcomes, EventOutcome{
+			Consumer:   outcome.Consumer,
+			StreamSeq:  outcome.StreamSeq,
+			Delivery:   int(outcome.Delivery),
+			Outcome:    Outcome(outcome.Outcome),
+			Error:      outcome.Error.String,
+			OccurredAt: outcome.OccurredAt.Time,
+		})
+	}
+
+	return event, nil
+}
+
+// GetEventTypes is every event type the archive holds, for the type filter.
+func (s DeliveryStore) GetEventTypes(ctx context.Context) ([]string, error) {
+	return s.queries.GetWebhookEventTypes(ctx)
 }
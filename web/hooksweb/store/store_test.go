@@ -2,8 +2,11 @@ package store_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"boardfund/messaging"
 	"boardfund/pg"
 	hooksstore "boardfund/web/hooksweb/store"
 
@@ -24,19 +27,27 @@ func TestRecordDelivery(t *testing.T) {
 
 	store := hooksstore.NewDeliveryStore(pool)
 
+	sale := func(transmissionID string) hooksstore.Delivery {
+		return hooksstore.Delivery{
+			TransmissionID: transmissionID,
+			EventType:      "PAYMENT.SALE.COMPLETED",
+			Payload:        []byte(`{"event_type":"PAYMENT.SALE.COMPLETED"}`),
+		}
+	}
+
 	t.Run("a transmission we have not seen is new", func(t *testing.T) {
-		fresh, errRecord := store.RecordDelivery(ctx, "tx-1", "PAYMENT.SALE.COMPLETED")
+		fresh, errRecord := store.RecordDelivery(ctx, sale("tx-1"))
 		require.NoError(t, errRecord)
 		assert.True(t, fresh)
 	})
 
 	t.Run("the same transmission again is not", func(t *testing.T) {
-		_, errFirst := store.RecordDelivery(ctx, "tx-2", "PAYMENT.SALE.COMPLETED")
+		_, errFirst := store.RecordDelivery(ctx, sale("tx-2"))
 		require.NoError(t, errFirst)
 
 		// A replay, or PayPal redelivering something we already accepted. The same
 		// thing from here, and neither needs publishing again.
-		fresh, errSecond := store.RecordDelivery(ctx, "tx-2", "PAYMENT.SALE.COMPLETED")
+		fresh, errSecond := store.RecordDelivery(ctx, sale("tx-2"))
 		require.NoError(t, errSecond, "a replay is a thing to ignore, not a fault")
 		assert.False(t, fresh)
 	})
@@ -44,13 +55,110 @@ func TestRecordDelivery(t *testing.T) {
 	t.Run("distinct transmissions of the same event type are independent", func(t *testing.T) {
 		// PayPal issues a distinct transmission id per event, so two real payments
 		// must not collapse into one.
-		first, errFirst := store.RecordDelivery(ctx, "tx-3", "PAYMENT.SALE.COMPLETED")
+		first, errFirst := store.RecordDelivery(ctx, sale("tx-3"))
 		require.NoError(t, errFirst)
 
-		second, errSecond := store.RecordDelivery(ctx, "tx-4", "PAYMENT.SALE.COMPLETED")
+		second, errSecond := store.RecordDelivery(ctx, sale("tx-4"))
 		require.NoError(t, errSecond)
 
 		assert.True(t, first)
 		assert.True(t, second)
 	})
 }
+
+// The archive is the answer to "what exactly did PayPal send us" long after the
+// stream has let it go, and to "what did we do with it". Both have to come back
+// out the way they went in, against a real database.
+func TestArchivedEventsAreSearchableWithTheirOutcomes(t *testing.T) {
+	ctx := context.Background()
+
+	container, pool, err := pg.SetupTestDatabase()
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	store := hooksstore.NewDeliveryStore(pool)
+
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	fresh, err := store.RecordDelivery(ctx, hooksstore.Delivery{
+		TransmissionID: "tx-sale",
+		EventID:        "WH-1",
+		EventType:      "PAYMENT.SALE.COMPLETED",
+		ResourceType:   "sale",
+		ResourceID:     "SALE-1",
+		Summary:        "Payment completed for $ 5.0 USD",
+		Payload:        []byte(`{"id":"WH-1","resource":{"id":"SALE-1"}}`),
+		CertURL:        "https://api.paypal.com/v1/notifications/certs/CERT-1",
+		ChainError:     "x509: certificate signed by unknown authority",
+		CreatedAt:      created,
+	})
+	require.NoError(t, err)
+	require.True(t, fresh)
+
+	_, err = store.RecordDelivery(ctx, hooksstore.Delivery{
+		TransmissionID: "tx-cancel",
+		EventType:      "BILLING.SUBSCRIPTION.CANCELLED",
+		ResourceID:     "I-SUB",
+		Payload:        []byte(`{"resource":{"id":"I-SUB"}}`),
+	})
+	require.NoError(t, err)
+
+	for _, outcome := range []messaging.Outcome{
+		{TransmissionID: "tx-sale", Consumer: "PAYMENT.SALE.COMPLETED", StreamSeq: 1, Delivery: 1, Err: errors.New("connection refused")},
+		{TransmissionID: "tx-sale", Consumer: "PAYMENT.SALE.COMPLETED", StreamSeq: 1, Delivery: 2},
+		// The same report again, as a lost ack would make it.
+		{TransmissionID: "tx-sale", Consumer: "PAYMENT.SALE.COMPLETED", StreamSeq: 1, Delivery: 2},
+		// From before the archive: nothing to file it against, and not an error.
+		{TransmissionID: "tx-older", Consumer: "PAYMENT.SALE.COMPLETED", StreamSeq: 9, Delivery: 1},
+	} {
+		require.NoError(t, store.RecordOutcome(ctx, outcome))
+	}
+
+	t.Run("filters narrow the list", func(t *testing.T) {
+		all, errSearch := store.SearchEvents(ctx, hooksstore.EventFilter{Limit: 10})
+		require.NoError(t, errSearch)
+		assert.Len(t, all, 2)
+
+		byResource, errSearch := store.SearchEvents(ctx, hooksstore.EventFilter{ResourceID: "SALE-1", Limit: 10})
+		require.NoError(t, errSearch)
+		require.Len(t, byResource, 1)
+		assert.Equal(t, 1, byResource[0].Handled)
+		assert.Equal(t, 1, byResource[0].Failed)
+		assert.False(t, byResource[0].CertificateChained)
+
+		byType, errSearch := store.SearchEvents(ctx, hooksstore.EventFilter{EventType: "BILLING.SUBSCRIPTION.CANCELLED", Limit: 10})
+		require.NoError(t, errSearch)
+		require.Len(t, byType, 1)
+		assert.Equal(t, "tx-cancel", byType[0].TransmissionID)
+
+		future, errSearch := store.SearchEvents(ctx, hooksstore.EventFilter{From: time.Now().Add(time.Hour), Limit: 10})
+		require.NoError(t, errSearch)
+		assert.Empty(t, future)
+	})
+
+	t.Run("an event comes back whole", func(t *testing.T) {
+		event, errGet := store.GetEvent(ctx, "tx-sale")
+		require.NoError(t, errGet)
+
+		assert.JSONEq(t, `{"id":"WH-1","resource":{"id":"SALE-1"}}`, string(event.Payload))
+		assert.True(t, event.CreatedAt.Equal(created))
+		assert.Equal(t, "x509: certificate signed by unknown authority", event.ChainError)
+
+		require.Len(t, event.Outcomes, 2)
+		assert.Equal(t, hooksstore.OutcomeFailed, event.Outcomes[0].Outcome)
+		assert.Equal(t, "connection refused", event.Outcomes[0].Error)
+		assert.Equal(t, hooksstore.OutcomeHandled, event.Outcomes[1].Outcome)
+	})
+
+	t.Run("an unknown transmission is not found", func(t *testing.T) {
+		_, errGet := store.GetEvent(ctx, "tx-nobody")
+		assert.ErrorIs(t, errGet, hooksstore.ErrEventNotFound)
+	})
+
+	t.Run("the type filter offers what has arrived", func(t *testing.T) {
+		types, errTypes := store.GetEventTypes(ctx)
+		require.NoError(t, errTypes)
+		assert.Equal(t, []string{"BILLING.SUBSCRIPTION.CANCELLED", "PAYMENT.SALE.COMPLETED"}, types)
+	})
+}
@@ -0,0 +1,145 @@
+package hooksweb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"boardfund/messaging"
+	"boardfund/web/mux"
+)
+
+func stripeSignature(secret string, signedAt time.Time, body []byte) string {
+	timestamp := fmt.Sprint(signedAt.Unix())
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAStripeSignatureIsCheckedAgainstTheSecretAndTheClock(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1_800_000_000, 0)
+
+	if err := verifyStripeSignature(body, stripeSignature("whsec_1", now, body), "whsec_1", now); err != nil {
+		t.Fatalf("a correct signature was refused: %v", err)
+	}
+
+	// While a secret is being rolled, Stripe signs with both.
+	rolled := stripeSignature("whsec_old", now, body) + "," + strings.SplitN(stripeSignature("whsec_1", now, body), ",", 2)[1]
+	if err := verifyStripeSignature(body, rolled, "whsec_1", now); err != nil {
+		t.Errorf("a header with the right signature second was refused: %v", err)
+	}
+
+	refused := []struct {
+		name   string
+		header string
+		body   []byte
+	}{
+		{"another secret", stripeSignature("whsec_2", now, body), body},
+		{"a changed body", stripeSignature("whsec_1", now, body), []byte(`{"id":"evt_2"}`)},
+		{"signed too long ago", stripeSignature("whsec_1", now.Add(-10*time.Minute), body), body},
+		{"signed in the future", stripeSignature("whsec_1", now.Add(10*time.Minute), body), body},
+		{"no signature", "t=1800000000", body},
+		{"empty", "", body},
+	}
+
+	for _, c := range refused {
+		t.Run(c.name, func(t *testing.T) {
+			if err := verifyStripeSignature(c.body, c.header, "whsec_1", now); err == nil {
+				t.Error("expected the signature to be refused")
+			}
+		})
+	}
+}
+
+// The object is what is published, under the Stripe subject space, and the
+// envelope is what is archived -- as PayPal's are.
+func TestAStripeEventIsPublishedUnderItsOwnSubject(t *testing.T) {
+	pub := &stubPublisher{}
+	seen := &stubDeliveries{}
+
+	h := newTestHandlersWith(pub, seen)
+	h.AcceptStripeWebhooks("whsec_1")
+
+	r := mux.NewRouter(http.NewServeMux())
+	h.Register(r)
+
+	body := []byte(`{"id":"evt_1","type":"invoice.paid","created":1800000000,` +
+		`"data":{"object":{"id":"in_1","object":"invoice"}}}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", strings.NewReader(string(body)))
+	req.Header.Set("Stripe-Signature", stripeSignature("whsec_1", time.Now(), body))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	if len(pub.published) != 1 || pub.published[0] != messaging.StripeInvoicePaid {
+		t.Fatalf("published %v, want %s", pub.published, messaging.StripeInvoicePaid)
+	}
+
+	got := seen.archived[0]
+	if got.TransmissionID != "evt_1" || got.ResourceType != "invoice" || got.ResourceID != "in_1" || got.CreatedAt.IsZero() {
+		t.Errorf("archived %+v", got)
+	}
+}
+
+// Stripe retries with the same event id, so the second copy is a redelivery.
+func TestARedeliveredStripeEventIsNotPublishedTwice(t *testing.T) {
+	pub := &stubPublisher{}
+	h := newTestHandlersWith(pub, &stubDeliveries{})
+
+	body := []byte(`{"id":"evt_2","type":"charge.refunded","data":{"object":{"id":"ch_1"}}}`)
+
+	h.acceptStripe(context.Background(), httptest.NewRecorder(), body)
+	h.acceptStripe(context.Background(), httptest.NewRecorder(), body)
+
+	if len(pub.published) != 1 {
+		t.Errorf("published %d times, want once", len(pub.published))
+	}
+}
+
+func TestAForgedStripeEventIsDropped(t *testing.T) {
+	pub := &stubPublisher{}
+
+	h := newTestHandlersWith(pub, &stubDeliveries{})
+	h.AcceptStripeWebhooks("whsec_1")
+
+	body := []byte(`{"id":"evt_3","type":"invoice.paid","data":{"object":{}}}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", strings.NewReader(string(body)))
+	req.Header.Set("Stripe-Signature", stripeSignature("guessed", time.Now(), body))
+
+	rec := httptest.NewRecorder()
+	h.stripeWebhooks(rec, req)
+
+	if rec.Code != http.StatusOK || len(pub.published) != 0 {
+		t.Errorf("status %d and published %v, want 200 and nothing", rec.Code, pub.published)
+	}
+}
+
+// Without a secret there is nothing to verify against, so there is no endpoint.
+func TestNoStripeEndpointWithoutASecret(t *testing.T) {
+	r := mux.NewRouter(http.NewServeMux())
+	newTestHandlers(&stubPublisher{}).Register(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/stripe", strings.NewReader(`{}`)))
+
+	if rec.Code == http.StatusOK {
+		t.Error("a handler with no stripe secret answered for stripe")
+	}
+}
@@ -56,7 +56,48 @@ var certHosts = map[string]bool{
 	"api-m.sandbox.paypal.com": true,
 }
 
+// certSource is where signing certificates may come from, how they are fetched,
+// and where they are kept once they have been.
+type certSource struct {
+	hosts map[string]bool
+	// client is nil for certFetchClient, read at the time of the fetch.
+	client   *http.Client
+	cacheDir string
+}
+
+// paypalCerts is the only source a production handler ever uses.
+var paypalCerts = certSource{hosts: certHosts, cacheDir: "tmp"}
+
+// CertSource is a host other than PayPal's to accept signing certificates from,
+// for a handler receiving webhooks from paypaltest rather than from PayPal.
+//
+// It replaces PayPal's hosts rather than joining them, and the URL must still be
+// https: the transport is what establishes trust, so Client has to be one that
+// trusts the stand-in's server certificate and nothing it should not.
+type CertSource struct {
+	Host     string
+	Client   *http.Client
+	CacheDir string
+}
+
+// TrustCertificatesFrom makes the handler fetch signing certificates from
+// source instead of from PayPal.
+//
+// Nothing in cmd calls it, and nothing should: a handler that trusts another
+// host verifies whatever that host is willing to sign.
+func (h *WebhooksHandlers) TrustCertificatesFrom(source CertSource) {
+	h.certs = certSource{
+		hosts:    map[string]bool{source.Host: true},
+		client:   source.Client,
+		cacheDir: source.CacheDir,
+	}
+}
+
 func checkCertURL(raw string) error {
+	return paypalCerts.check(raw)
+}
+
+func (s certSource) check(raw string) error {
 	parsed, err := url.Parse(raw)
 	if err != nil {
 		return fmt.Errorf("unparseable certificate url: %w", err)
@@ -66,7 +107,7 @@ func checkCertURL(raw string) error {
 		return fmt.Errorf("certificate url is not https: %q", parsed.Scheme)
 	}
 
-	if !certHosts[parsed.Hostname()] {
+	if !s.hosts[parsed.Hostname()] {
 		return fmt.Errorf("certificate url host %q is not PayPal", parsed.Hostname())
 	}
 
@@ -147,8 +188,8 @@ func parseSigningCert(certPem string) (*signingCert, error) {
 // certificate fetched after a restart answered every later request whatever URL
 // it named, so a rotation at PayPal broke verification until the container was
 // replaced -- and a single request naming another URL poisoned it for everyone.
-func downloadAndCache(ctx context.Context, certURL, cacheKey string) (string, error) {
-	filePath := filepath.Join("tmp", cacheKey)
+func (s certSource) downloadAndCache(ctx context.Context, certURL, cacheKey string) (string, error) {
+	filePath := filepath.Join(s.cacheDir, cacheKey)
 
 	var data []byte
 	var err error
@@ -162,8 +203,8 @@ func downloadAndCache(ctx context.Context, certURL, cacheKey string) (string, er
 		return "", err
 	}
 
-	if err = os.MkdirAll("tmp", 0755); err != nil {
-		return "", fmt.Errorf("failed to create tmp directory: %w", err)
+	if err = os.MkdirAll(s.cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s directory: %w", s.cacheDir, err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
@@ -171,7 +212,12 @@ func downloadAndCache(ctx context.Context, certURL, cacheKey string) (string, er
 		return "", fmt.Errorf("failed to build certificate request: %w", err)
 	}
 
-	resp, err := certFetchClient.Do(req)
+	client := s.client
+	if client == nil {
+		client = certFetchClient
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to download from URL: %w", err)
 	}
@@ -193,14 +239,22 @@ func downloadAndCache(ctx context.Context, certURL, cacheKey string) (string, er
 	return string(body), nil
 }
 
-func verifySignature(r *http.Request, webhookID string, logger *slog.Logger) ([]byte, error) {
+// verification is what checking a request established beyond the signature
+// being good, kept with the event in the archive.
+type verification struct {
+	certURL string
+	// chainErr is the signing certificate's, as parseSigningCert reported it.
+	chainErr error
+}
+
+func verifySignature(r *http.Request, webhookID string, certs certSource, logger *slog.Logger) ([]byte, verification, error) {
 	transmissionID := r.Header.Get("paypal-transmission-id")
 	timestamp := r.Header.Get("paypal-transmission-time")
 	certURL := r.Header.Get("paypal-cert-url")
 	sig := r.Header.Get("paypal-transmission-sig")
 
 	if transmissionID == "" || timestamp == "" {
-		return nil, fmt.Errorf("missing required PayPal headers")
+		return nil, verification{}, fmt.Errorf("missing required PayPal headers")
 	}
 
 	body := r.Body
@@ -208,7 +262,7 @@ func verifySignature(r *http.Request, webhookID string, logger *slog.Logger) ([]
 
 	bodyBytes, err := io.ReadAll(body)
 	if err != nil {
-		return nil, unverifiable(err)
+		return nil, verification{}, unverifiable(err)
 	}
 
 	crc := crc32.ChecksumIEEE(bodyBytes)
@@ -217,8 +271,8 @@ func verifySignature(r *http.Request, webhookID string, logger *slog.Logger) ([]
 
 	// Before anything is downloaded. The URL decides which key will verify this
 	// request's signature, so an unchecked one makes the signature meaningless.
-	if err = checkCertURL(certURL); err != nil {
-		return nil, err
+	if err = certs.check(certURL); err != nil {
+		return nil, verification{}, err
 	}
 
 	// Cache key derived from the URL, so a rotated certificate is a new entry
@@ -229,20 +283,20 @@ func verifySignature(r *http.Request, webhookID string, logger *slog.Logger) ([]
 	// reason to spend a request to PayPal establishing that.
 	sigBytes, err := base64.StdEncoding.DecodeString(sig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode signature: %w", err)
+		return nil, verification{}, fmt.Errorf("failed to decode signature: %w", err)
 	}
 
 	// Past the host check, so the certificate is coming from PayPal. Failing to
 	// fetch or trust it from here is our problem or theirs, not the caller's, and
 	// the event deserves another delivery rather than being dropped.
-	certPem, err := downloadAndCache(r.Context(), certURL, "pp-cert-"+hex.EncodeToString(sum[:8])+".pem")
+	certPem, err := certs.downloadAndCache(r.Context(), certURL, "pp-cert-"+hex.EncodeToString(sum[:8])+".pem")
 	if err != nil {
-		return nil, unverifiable(fmt.Errorf("failed to fetch certificate: %w", err))
+		return nil, verification{}, unverifiable(fmt.Errorf("failed to fetch certificate: %w", err))
 	}
 
 	cert, err := parseSigningCert(certPem)
 	if err != nil {
-		return nil, unverifiable(err)
+		return nil, verification{}, unverifiable(err)
 	}
 
 	// Reported, not enforced. PayPal serves this certificate without the
@@ -255,5 +309,7 @@ func verifySignature(r *http.Request, webhookID string, logger *slog.Logger) ([]
 		)
 	}
 
-	return bodyBytes, cert.leaf.CheckSignature(x509.SHA256WithRSA, []byte(message)[:], sigBytes)
+	checked := verification{certURL: certURL, chainErr: cert.chainErr}
+
+	return bodyBytes, checked, cert.leaf.CheckSignature(x509.SHA256WithRSA, []byte(message)[:], sigBytes)
 }
@@ -16,6 +16,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"boardfund/web/hooksweb/store"
 )
 
 // The header names the URL, so an unchecked one lets the caller choose the key
@@ -138,24 +140,27 @@ type stubPublisher struct {
 	err       error
 }
 
-func (s *stubPublisher) Publish(event string, _ []byte) error {
+func (s *stubPublisher) PublishWebhook(event, _ string, _ []byte) error {
 	s.published = append(s.published, event)
 
 	return s.err
 }
 
 // stubDeliveries reports every transmission as new unless told otherwise, which
-// is the ordinary case.
+// is the ordinary case, and keeps what it was asked to archive.
 type stubDeliveries struct {
-	seen map[string]bool
-	err  error
+	seen     map[string]bool
+	err      error
+	archived []store.Delivery
 }
 
-func (s *stubDeliveries) RecordDelivery(_ context.Context, transmissionID, _ string) (bool, error) {
+func (s *stubDeliveries) RecordDelivery(_ context.Context, delivery store.Delivery) (bool, error) {
 	if s.err != nil {
 		return false, s.err
 	}
 
+	transmissionID := delivery.TransmissionID
+
 	if s.seen == nil {
 		s.seen = map[string]bool{}
 	}
@@ -165,6 +170,7 @@ func (s *stubDeliveries) RecordDelivery(_ context.Context, transmissionID, _ str
 	}
 
 	s.seen[transmissionID] = true
+	s.archived = append(s.archived, delivery)
 
 	return true, nil
 }
@@ -294,7 +300,7 @@ func TestAcceptRefusesToPublishAReplay(t *testing.T) {
 		rec := httptest.NewRecorder()
 
 		newTestHandlersWith(pub, &stubDeliveries{}).
-			accept(context.Background(), rec, "tx-1", body)
+			accept(context.Background(), rec, "tx-1", body, verification{})
 
 		if rec.Code != http.StatusOK {
 			t.Errorf("status = %d, want 200", rec.Code)
@@ -310,10 +316,10 @@ func TestAcceptRefusesToPublishAReplay(t *testing.T) {
 		pub := &stubPublisher{}
 
 		first := httptest.NewRecorder()
-		newTestHandlersWith(pub, seen).accept(context.Background(), first, "tx-2", body)
+		newTestHandlersWith(pub, seen).accept(context.Background(), first, "tx-2", body, verification{})
 
 		second := httptest.NewRecorder()
-		newTestHandlersWith(pub, seen).accept(context.Background(), second, "tx-2", body)
+		newTestHandlersWith(pub, seen).accept(context.Background(), second, "tx-2", body, verification{})
 
 		// 200, because a replay is not something PayPal should send again.
 		if second.Code != http.StatusOK {
@@ -331,8 +337,8 @@ func TestAcceptRefusesToPublishAReplay(t *testing.T) {
 		seen := &stubDeliveries{}
 		pub := &stubPublisher{}
 
-		newTestHandlersWith(pub, seen).accept(context.Background(), httptest.NewRecorder(), "tx-3", body)
-		newTestHandlersWith(pub, seen).accept(context.Background(), httptest.NewRecorder(), "tx-4", body)
+		newTestHandlersWith(pub, seen).accept(context.Background(), httptest.NewRecorder(), "tx-3", body, verification{})
+		newTestHandlersWith(pub, seen).accept(context.Background(), httptest.NewRecorder(), "tx-4", body, verification{})
 
 		if len(pub.published) != 2 {
 			t.Errorf("published %d, want 2", len(pub.published))
@@ -347,7 +353,7 @@ func TestAcceptAsksForRedeliveryWhenItCannotRecord(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	newTestHandlersWith(pub, &stubDeliveries{err: errors.New("connection refused")}).
-		accept(context.Background(), rec, "tx-5", []byte(`{"event_type":"PAYMENT.SALE.COMPLETED"}`))
+		accept(context.Background(), rec, "tx-5", []byte(`{"event_type":"PAYMENT.SALE.COMPLETED"}`), verification{})
 
 	if rec.Code != http.StatusInternalServerError {
 		t.Errorf("status = %d, want 500", rec.Code)
@@ -369,7 +375,7 @@ func TestAcceptRefusesAnEmptyTransmissionID(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	newTestHandlersWith(pub, &stubDeliveries{}).
-		accept(context.Background(), rec, "", []byte(`{"event_type":"PAYMENT.SALE.COMPLETED"}`))
+		accept(context.Background(), rec, "", []byte(`{"event_type":"PAYMENT.SALE.COMPLETED"}`), verification{})
 
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("status = %d, want 400", rec.Code)
@@ -379,3 +385,59 @@ func TestAcceptRefusesAnEmptyTransmissionID(t *testing.T) {
 		t.Error("nothing should be published under an empty dedupe key")
 	}
 }
+
+// What is archived is the whole body and the envelope read out of it, along with
+// how verification went. The stream only ever had the resource, and only for a
+// week.
+func TestAcceptArchivesTheEnvelope(t *testing.T) {
+	pub := &stubPublisher{}
+	seen := &stubDeliveries{}
+
+	body := []byte(`{"id":"WH-1","event_type":"PAYMENT.SALE.COMPLETED","resource_type":"sale",` +
+		`"summary":"Payment completed","create_time":"2026-03-01T12:00:00.000Z","resource":{"id":"SALE-1"}}`)
+
+	newTestHandlersWith(pub, seen).accept(context.Background(), httptest.NewRecorder(), "tx-6", body, verification{
+		certURL:  "https://api.paypal.com/v1/notifications/certs/CERT-abc",
+		chainErr: errors.New("x509: certificate signed by unknown authority"),
+	})
+
+	if len(seen.archived) != 1 {
+		t.Fatalf("archived %d, want 1", len(seen.archived))
+	}
+
+	got := seen.archived[0]
+
+	if got.EventID != "WH-1" || got.ResourceType != "sale" || got.ResourceID != "SALE-1" || got.Summary != "Payment completed" {
+		t.Errorf("envelope = %+v", got)
+	}
+
+	if string(got.Payload) != string(body) {
+		t.Error("the archive should keep the body PayPal sent, not just the resource")
+	}
+
+	if got.CreatedAt.IsZero() {
+		t.Error("the event's own time should be kept")
+	}
+
+	if got.CertURL == "" || got.ChainError == "" {
+		t.Error("how verification went should be kept with the event")
+	}
+}
+
+// A resource that is not an object has no id to search by. The event is still
+// kept, and still published.
+func TestAnOddEnvelopeIsStillArchived(t *testing.T) {
+	pub := &stubPublisher{}
+	seen := &stubDeliveries{}
+
+	newTestHandlersWith(pub, seen).accept(context.Background(), httptest.NewRecorder(), "tx-7",
+		[]byte(`{"event_type":"PAYMENT.SALE.COMPLETED","create_time":"last tuesday","resource":[1,2]}`), verification{})
+
+	if len(seen.archived) != 1 || len(pub.published) != 1 {
+		t.Fatalf("archived %d and published %d, want 1 and 1", len(seen.archived), len(pub.published))
+	}
+
+	if seen.archived[0].ResourceID != "" || !seen.archived[0].CreatedAt.IsZero() {
+		t.Errorf("nothing readable should come out of it: %+v", seen.archived[0])
+	}
+}
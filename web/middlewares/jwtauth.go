@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,6 +10,17 @@ import (
 )
 
 func Verify(verifyFunc func(tokenStr string) (jwt.Token, error), findTokenFns ...func(r *http.Request) string) func(http.HandlerFunc) http.HandlerFunc {
+	return VerifyOr(redirectToLogin, verifyFunc, findTokenFns...)
+}
+
+// VerifyOr is Verify with the refusal left to the caller. A page sends the
+// browser to the login form; a script asking the API for JSON has no use for a
+// 302 to an HTML page, and following it would report a login form as the
+// answer to its question.
+//
+// The verified token is kept on the request context for the handler, which
+// otherwise has to verify it a second time to learn who is asking.
+func VerifyOr(refuse http.HandlerFunc, verifyFunc func(tokenStr string) (jwt.Token, error), findTokenFns ...func(r *http.Request) string) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		hfn := func(w http.ResponseWriter, r *http.Request) {
 			if r.URL.Path == "/static" {
@@ -16,15 +28,15 @@ func Verify(verifyFunc func(tokenStr string) (jwt.Token, error), findTokenFns ..
 				return
 			}
 
-			ctx := r.Context()
-			_, err := VerifyRequest(r, verifyFunc, findTokenFns...)
+			token, err := VerifyRequest(r, verifyFunc, findTokenFns...)
 			if err != nil {
-				w.Header().Set("HX-Redirect", "/login")
-				http.Redirect(w, r, "/login", http.StatusFound)
+				refuse(w, r)
 
 				return
 			}
 
+			ctx := context.WithValue(r.Context(), tokenKey{}, token)
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 
@@ -32,6 +44,20 @@ func Verify(verifyFunc func(tokenStr string) (jwt.Token, error), findTokenFns ..
 	}
 }
 
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("HX-Redirect", "/login")
+	http.Redirect(w, r, "/login", http.StatusFound)
+}
+
+type tokenKey struct{}
+
+// TokenFromContext is the token Verify accepted for this request.
+func TokenFromContext(ctx context.Context) (jwt.Token, bool) {
+	token, ok := ctx.Value(tokenKey{}).(jwt.Token)
+
+	return token, ok
+}
+
 func VerifyRequest(r *http.Request, verifyFunc func(tokenStr string) (jwt.Token, error), findTokenFns ...func(r *http.Request) string) (jwt.Token, error) {
 	var tokenString string
 
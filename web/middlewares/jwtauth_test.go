@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+func acceptOnly(good string) func(string) (jwt.Token, error) {
+	return func(tokenStr string) (jwt.Token, error) {
+		if tokenStr != good {
+			return nil, errors.New("failed to parse token")
+		}
+
+		return jwt.NewBuilder().Subject("member").Build()
+	}
+}
+
+// The pages still send a refused request to the login form.
+func TestVerifyRedirectsToLogin(t *testing.T) {
+	handler := Verify(acceptOnly("good"), TokenFromHeader)(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a refused request reached the handler")
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/login" {
+		t.Errorf("got %d to %q, want a redirect to /login", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+// The handler behind it sees the token that was accepted, and a refusal is
+// whatever the caller asked for.
+func TestVerifyOrKeepsTheTokenAndRefusesAsAsked(t *testing.T) {
+	var seen jwt.Token
+
+	handler := VerifyOr(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}, acceptOnly("good"), TokenFromHeader)(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = TokenFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	handler(httptest.NewRecorder(), req)
+
+	if seen == nil || seen.Subject() != "member" {
+		t.Fatalf("the handler did not get the token: %v", seen)
+	}
+
+	rec := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer forged")
+	handler(rec, req)
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("got %d, want the caller's refusal", rec.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"boardfund/jwtauth"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// TwoFactorSessionKey holds the id of the member who gave a second factor in
+// this session. The id rather than a flag: the session and the token cookie are
+// separate things, and a flag set for one member must not vouch for a token
+// issued to another.
+const TwoFactorSessionKey = "two_factor"
+
+// TwoFactorPath is where a member is sent to give a second factor, or to set
+// one up if they have none.
+const TwoFactorPath = "/account/two-factor"
+
+// RequireTwoFactor refuses a member in any of groups whose session has not had a
+// second factor, and sends them to TwoFactorPath. Members in none of the groups
+// pass as they are.
+//
+// It reads the token Verify put on the context, so it goes inside Verify: it is
+// the token's groups that decide what a request may do, and so it is the
+// token's groups that decide what it must prove first.
+func RequireTwoFactor(sessions *scs.SessionManager, groups []string) func(http.HandlerFunc) http.HandlerFunc {
+	return RequireTwoFactorOr(sendToTwoFactor, sessions, groups)
+}
+
+// RequireTwoFactorOr is RequireTwoFactor with the refusal left to the caller,
+// as VerifyOr is to Verify. The API answers a JSON 403: a script cannot give a
+// code, and a redirect to the form would read as the answer to its question.
+//
+// The check is the same, so a bearer token is only as good as the session it
+// arrives with. A token issued on the password alone carries its roles all the
+// same, and without this the API would show what the pages refuse to.
+func RequireTwoFactorOr(refuse http.HandlerFunc, sessions *scs.SessionManager, groups []string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := TokenFromContext(r.Context())
+			if !ok {
+				redirectToLogin(w, r)
+
+				return
+			}
+
+			claims := token.PrivateClaims()
+
+			required := false
+			for _, group := range groups {
+				if jwtauth.HasGroup(claims, group) {
+					required = true
+
+					break
+				}
+			}
+
+			memberID, _ := claims["custom:member_id"].(string)
+
+			if required && (memberID == "" || sessions.GetString(r.Context(), TwoFactorSessionKey) != memberID) {
+				refuse(w, r)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+func sendToTwoFactor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("HX-Redirect", TwoFactorPath)
+	http.Redirect(w, r, TwoFactorPath, http.StatusFound)
+}
@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boardfund/jwtauth"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+func tokenFor(t *testing.T, memberID string, groups ...any) jwt.Token {
+	t.Helper()
+
+	token, err := jwt.NewBuilder().
+		Claim("custom:member_id", memberID).
+		Claim(jwtauth.GroupsClaim, groups).
+		Build()
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	return token
+}
+
+// A request as it arrives from Verify, with a session that has had a second
+// factor from verifiedBy, or from nobody when that is empty.
+func verifiedRequest(t *testing.T, sessions *scs.SessionManager, token jwt.Token, verifiedBy string) *http.Request {
+	t.Helper()
+
+	ctx, err := sessions.Load(context.Background(), "")
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+
+	if verifiedBy != "" {
+		sessions.Put(ctx, TwoFactorSessionKey, verifiedBy)
+	}
+
+	ctx = context.WithValue(ctx, tokenKey{}, token)
+
+	return httptest.NewRequest(http.MethodGet, "/admin", nil).WithContext(ctx)
+}
+
+func TestTwoFactorIsAskedOfTheGroupsThatNeedIt(t *testing.T) {
+	sessions := scs.New()
+	require := RequireTwoFactor(sessions, []string{jwtauth.AdminGroup})
+
+	cases := []struct {
+		name       string
+		token      jwt.Token
+		verifiedBy string
+		allowed    bool
+	}{
+		{"an admin with a second factor", tokenFor(t, "ada", jwtauth.AdminGroup), "ada", true},
+		{"an admin without one", tokenFor(t, "ada", jwtauth.AdminGroup), "", false},
+		// The session and the cookie are separate, so one member's code must not
+		// vouch for another's token.
+		{"an admin on somebody else's", tokenFor(t, "ada", jwtauth.AdminGroup), "grace", false},
+		{"a member in no such group", tokenFor(t, "grace"), "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reached := false
+			handler := require(func(w http.ResponseWriter, r *http.Request) { reached = true })
+
+			rec := httptest.NewRecorder()
+			handler(rec, verifiedRequest(t, sessions, c.token, c.verifiedBy))
+
+			if reached != c.allowed {
+				t.Fatalf("reached the handler: %v, want %v", reached, c.allowed)
+			}
+
+			if !c.allowed && rec.Header().Get("Location") != TwoFactorPath {
+				t.Errorf("sent to %q, want %q", rec.Header().Get("Location"), TwoFactorPath)
+			}
+		})
+	}
+}
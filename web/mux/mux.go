@@ -13,6 +13,7 @@ type Mux interface {
 type Router struct {
 	mux         Mux
 	middlewares []Middleware
+	patterns    []string
 }
 
 func NewRouter(mux Mux, middlewares ...Middleware) *Router {
@@ -54,10 +55,22 @@ func (r *Router) UseHandler(middlewares ...func(http.Handler) http.Handler) {
 
 func (r *Router) Handle(pattern string, handler http.Handler) {
 	r.mux.Handle(pattern, compileHandlerWithMiddleware(r.middlewares, handler.ServeHTTP))
+	r.patterns = append(r.patterns, pattern)
 }
 
 func (r *Router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
 	r.mux.HandleFunc(pattern, compileHandlerWithMiddleware(r.middlewares, handler))
+	r.patterns = append(r.patterns, pattern)
+}
+
+// Patterns is every pattern registered so far, in order, as it was written --
+// "GET /api/v1/funds/{id}", or "/about" for one that takes any method.
+//
+// ServeMux will not say what it holds, and the API's description has to be
+// checked against what is actually served rather than against a second list
+// somebody keeps by hand.
+func (r *Router) Patterns() []string {
+	return append([]string(nil), r.patterns...)
 }
 
 func (r *Router) ListenAndServe(addr string, handler http.Handler) error {